@@ -2,13 +2,25 @@ package api
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"nofx/auth"
 	"nofx/config"
+	nofxcrypto "nofx/crypto"
 
 	"github.com/gin-gonic/gin"
 )
@@ -131,6 +143,38 @@ func TestHandleTraderList(t *testing.T) {
 	t.Logf("✅ handleTraderList test passed")
 }
 
+// TestHandleTraderList_FilterErrors tests status codes for invalid filters and database failures
+func TestHandleTraderList_FilterErrors(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	userID, _, _ := setupTestEnv(t, db)
+
+	router := gin.New()
+	router.GET("/traders", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		server.handleTraderList(c)
+	})
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	if w := get("/traders?sort=bogus"); w.Code != http.StatusBadRequest {
+		t.Errorf("Invalid sort: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("/traders?running=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("Invalid running: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 数据库故障属于内部错误
+	db.Close()
+	if w := get("/traders?sort=name"); w.Code != http.StatusInternalServerError {
+		t.Errorf("Database failure: expected 500, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 // TestHandleDeleteTrader tests the delete trader endpoint
 func TestHandleDeleteTrader(t *testing.T) {
 	server, db, cleanup := setupTestServer(t)
@@ -643,6 +687,16 @@ func TestHandleStopTrader(t *testing.T) {
 		t.Fatalf("Failed to start trader: %s", startW.Body.String())
 	}
 
+	// handleStartTrader 在 goroutine 中启动，等待运行状态生效
+	if at, err := server.traderManager.GetTrader(traderID); err == nil {
+		for i := 0; i < 50; i++ {
+			if running, ok := at.GetStatus()["is_running"].(bool); ok && running {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
 	// Now create test request to stop the trader
 	router := gin.New()
 	router.POST("/traders/:id/stop", func(c *gin.Context) {
@@ -682,6 +736,303 @@ func TestHandleStopTrader(t *testing.T) {
 	if stoppedTrader.IsRunning {
 		t.Error("Trader should be marked as stopped after stop")
 	}
+	if stoppedTrader.StopReason != config.StopReasonUser {
+		t.Errorf("Expected stop_reason %q, got %q", config.StopReasonUser, stoppedTrader.StopReason)
+	}
 
 	t.Logf("✅ handleStopTrader test passed")
 }
+
+// TestHandleTestExchangeConnection tests the exchange connectivity check without hitting the network
+func TestHandleTestExchangeConnection(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	userID, _, _ := setupTestEnv(t, db)
+
+	// Aster exchange without credentials should fail before any network call
+	if err := db.CreateExchange(userID, "aster", "Aster", "dex", true, "", "", false, "", "", "", ""); err != nil {
+		t.Fatalf("Failed to create exchange: %v", err)
+	}
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil {
+		t.Fatalf("Failed to get exchanges: %v", err)
+	}
+	asterID := 0
+	for _, ex := range exchanges {
+		if ex.ExchangeID == "aster" {
+			asterID = ex.ID
+		}
+	}
+	if asterID == 0 {
+		t.Fatal("Aster exchange not found")
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/exchanges/:id/test", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		server.handleTestExchangeConnection(c)
+	})
+
+	tests := []struct {
+		name      string
+		id        string
+		wantError string
+	}{
+		{"invalid id", "abc", "无效的交易所ID"},
+		{"unknown exchange", "99999", "交易所配置不存在"},
+		{"missing credentials", fmt.Sprintf("%d", asterID), "未配置"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/exchanges/"+tt.id+"/test", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if msg, _ := resp["error"].(string); !strings.Contains(msg, tt.wantError) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantError, msg)
+			}
+		})
+	}
+}
+
+// TestHandleWebhook_StopWritesAudit 签名通过的 stop 告警停止运行中的交易员并写入审计日志
+func TestHandleWebhook_StopWritesAudit(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	t.Setenv("WEBHOOK_SECRET", "s3cret")
+
+	userID, aiModelIntID, exchangeIntID := setupTestEnv(t, db)
+	trader := &config.TraderRecord{
+		ID:                  "test-trader-webhook-stop",
+		UserID:              userID,
+		Name:                "Webhook Stop",
+		AIModelID:           aiModelIntID,
+		ExchangeID:          exchangeIntID,
+		InitialBalance:      1000.0,
+		ScanIntervalMinutes: 3,
+		BTCETHLeverage:      5,
+		AltcoinLeverage:     5,
+		TradingSymbols:      "BTCUSDT",
+	}
+	if err := db.CreateTrader(trader); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+	if err := server.traderManager.LoadUserTraders(db, userID); err != nil {
+		t.Fatalf("Failed to load trader into manager: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	startRouter := gin.New()
+	startRouter.POST("/traders/:id/start", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		server.handleStartTrader(c)
+	})
+	startW := httptest.NewRecorder()
+	startRouter.ServeHTTP(startW, httptest.NewRequest("POST", "/traders/"+trader.ID+"/start", nil))
+	if startW.Code != http.StatusOK {
+		t.Fatalf("Failed to start trader: %s", startW.Body.String())
+	}
+	at, err := server.traderManager.GetTrader(trader.ID)
+	if err != nil {
+		t.Fatalf("GetTrader failed: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if running, ok := at.GetStatus()["is_running"].(bool); ok && running {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	body := `{"action":"stop","type":"drawdown","content":"回撤过大"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/webhook/"+trader.ID, strings.NewReader(body))
+	req.Header.Set(webhookSignatureHeader, webhookSignature("s3cret", []byte(body)))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	entries, err := db.GetAuditLogByEntity(config.AuditEntityTrader, trader.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("GetAuditLogByEntity failed: %v", err)
+	}
+	found := false
+	for _, entry := range entries {
+		if entry.Action == "webhook_stop" && strings.Contains(entry.Detail, "drawdown") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected webhook_stop audit entry, got %+v", entries)
+	}
+}
+
+// encryptTestPayload 按前端的方式（RSA-OAEP 包装 AES-GCM 密钥）加密请求体
+func encryptTestPayload(t *testing.T, server *Server, v interface{}) []byte {
+	t.Helper()
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	block, _ := pem.Decode([]byte(server.cryptoHandler.cryptoService.GetPublicKeyPEM()))
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse public key: %v", err)
+	}
+	aesKey := make([]byte, 32)
+	iv := make([]byte, 12)
+	rand.Read(aesKey)
+	rand.Read(iv)
+	aesBlock, _ := aes.NewCipher(aesKey)
+	gcm, _ := cipher.NewGCM(aesBlock)
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub.(*rsa.PublicKey), aesKey, nil)
+	if err != nil {
+		t.Fatalf("Failed to wrap key: %v", err)
+	}
+	body, _ := json.Marshal(nofxcrypto.EncryptedPayload{
+		WrappedKey: base64.RawURLEncoding.EncodeToString(wrappedKey),
+		IV:         base64.RawURLEncoding.EncodeToString(iv),
+		Ciphertext: base64.RawURLEncoding.EncodeToString(gcm.Seal(nil, iv, plaintext, nil)),
+	})
+	return body
+}
+
+// TestHandleCreateExchangeAccount 同一用户可以新增第二个币安账户，按类型更新多个同类型账户时返回 409
+func TestHandleCreateExchangeAccount(t *testing.T) {
+	t.Setenv("DATA_ENCRYPTION_KEY", "unit-test-key")
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	if server.cryptoHandler.cryptoService == nil {
+		t.Fatal("crypto service not available")
+	}
+
+	userID, _, _ := setupTestEnv(t, db)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/exchanges", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		server.handleCreateExchangeAccount(c)
+	})
+	router.PUT("/exchanges", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		server.handleUpdateExchangeConfigs(c)
+	})
+	send := func(method string, payload interface{}) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/exchanges", bytes.NewReader(encryptTestPayload(t, server, payload)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	second := CreateExchangeAccountRequest{ExchangeID: "binance", DisplayName: "Sub account", Enabled: true, APIKey: "key-2", SecretKey: "secret-2"}
+	w := send("POST", second)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	newID := int(resp["id"].(float64))
+
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil {
+		t.Fatalf("Failed to get exchanges: %v", err)
+	}
+	found := false
+	binanceCount := 0
+	for _, ex := range exchanges {
+		if ex.ExchangeID == "binance" {
+			binanceCount++
+		}
+		if ex.ID == newID {
+			found = true
+			if ex.DisplayName != "Sub account" || ex.Name != "Binance Futures" || ex.APIKey != "key-2" {
+				t.Errorf("Unexpected new account: %+v", ex)
+			}
+		}
+	}
+	if !found || binanceCount != 2 {
+		t.Fatalf("Expected a second binance account with id %d, found=%v count=%d", newID, found, binanceCount)
+	}
+
+	if w := send("POST", second); w.Code != http.StatusConflict {
+		t.Errorf("Duplicate display name: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("POST", CreateExchangeAccountRequest{ExchangeID: "nope"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Unsupported exchange: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	update := map[string]interface{}{"exchanges": map[string]interface{}{"binance": map[string]interface{}{"enabled": true}}}
+	if w := send("PUT", update); w.Code != http.StatusConflict {
+		t.Errorf("Ambiguous update: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	update = map[string]interface{}{"exchanges": map[string]interface{}{fmt.Sprintf("%d", newID): map[string]interface{}{"enabled": false}}}
+	if w := send("PUT", update); w.Code != http.StatusOK {
+		t.Errorf("Update by numeric id: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestHandleRotateExchangeKeys tests exchange key rotation through the API
+func TestHandleRotateExchangeKeys(t *testing.T) {
+	t.Setenv("DATA_ENCRYPTION_KEY", "unit-test-key")
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	if server.cryptoHandler.cryptoService == nil {
+		t.Fatal("crypto service not available")
+	}
+
+	userID, _, exchangeIntID := setupTestEnv(t, db)
+	db.SetExchangeKeyTester(func(ex *config.ExchangeConfig) error {
+		if ex.APIKey == "bad-key" {
+			return fmt.Errorf("invalid api key")
+		}
+		return nil
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/exchanges/:id/rotate-keys", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		server.handleRotateExchangeKeys(c)
+	})
+	send := func(id int, payload RotateExchangeKeysRequest) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/exchanges/%d/rotate-keys", id), bytes.NewReader(encryptTestPayload(t, server, payload)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(exchangeIntID, RotateExchangeKeysRequest{APIKey: "bad-key", SecretKey: "bad-secret"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Rejected key: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(exchangeIntID+1000, RotateExchangeKeysRequest{APIKey: "new-key"}); w.Code != http.StatusNotFound {
+		t.Errorf("Unknown exchange: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := send(exchangeIntID, RotateExchangeKeysRequest{APIKey: "new-key", SecretKey: "new-secret"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil {
+		t.Fatalf("Failed to get exchanges: %v", err)
+	}
+	for _, ex := range exchanges {
+		if ex.ID == exchangeIntID && (ex.APIKey != "new-key" || ex.SecretKey != "new-secret") {
+			t.Errorf("Expected rotated keys, got %s/%s", ex.APIKey, ex.SecretKey)
+		}
+	}
+}
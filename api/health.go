@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"nofx/market"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthCheckTimeout 单项检查超时时间，保证汇总接口整体响应快
+const healthCheckTimeout = 2 * time.Second
+
+// 健康状态
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+	HealthDisabled = "disabled" // 未配置该组件
+)
+
+// HealthCheckFunc 组件健康检查函数
+type HealthCheckFunc func(ctx context.Context) error
+
+// ComponentHealth 单个组件的健康状态
+type ComponentHealth struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Critical    bool       `json:"critical"` // 关键组件故障时整体判定为 down
+	LatencyMs   int64      `json:"latency_ms"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// HealthReport 系统健康汇总
+type HealthReport struct {
+	Status     string             `json:"status"` // ok / degraded / down
+	CheckedAt  time.Time          `json:"checked_at"`
+	Components []*ComponentHealth `json:"components"`
+}
+
+// namedHealthCheck 额外注册的健康检查（如 Redis）
+type namedHealthCheck struct {
+	name     string
+	critical bool
+	check    HealthCheckFunc
+}
+
+var (
+	extraHealthChecksMu sync.RWMutex
+	extraHealthChecks   []namedHealthCheck
+)
+
+// RegisterHealthCheck 注册额外的组件健康检查，例如启用 Redis 后注册其 Ping
+func RegisterHealthCheck(name string, critical bool, check HealthCheckFunc) {
+	extraHealthChecksMu.Lock()
+	defer extraHealthChecksMu.Unlock()
+	extraHealthChecks = append(extraHealthChecks, namedHealthCheck{name: name, critical: critical, check: check})
+}
+
+// ClockSkewHealthCheck 检查本地时钟与交易所服务器时间的偏差，超过 market.TimeSkewThreshold 时判定为异常
+func ClockSkewHealthCheck(exchangeType string) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		skew, err := market.CheckTimeSkew(exchangeType)
+		if err != nil {
+			return err
+		}
+		if skew > market.TimeSkewThreshold || skew < -market.TimeSkewThreshold {
+			return fmt.Errorf("本地时钟与 %s 服务器相差 %v，请同步系统时间", exchangeType, skew.Round(time.Millisecond))
+		}
+		return nil
+	}
+}
+
+// runHealthCheck 独立计时并限时执行一次检查
+func runHealthCheck(name string, critical bool, check HealthCheckFunc) *ComponentHealth {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() { errCh <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+	}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	result := &ComponentHealth{Name: name, Critical: critical, Status: HealthOK, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = HealthDown
+		result.Error = err.Error()
+	} else {
+		now := time.Now()
+		result.LastSuccess = &now
+	}
+	return result
+}
+
+// marketSourceHealth 从数据源管理器读取各行情源的最近状态（不发起请求）
+func marketSourceHealth() []*ComponentHealth {
+	if market.WSMonitorCli == nil || market.WSMonitorCli.GetDSManager() == nil {
+		return nil
+	}
+
+	statuses := market.WSMonitorCli.GetDSManager().GetStatus()
+	components := make([]*ComponentHealth, 0, len(statuses))
+	for name, status := range statuses {
+		component := &ComponentHealth{
+			Name:      "market:" + name,
+			Status:    HealthOK,
+			LatencyMs: status.Latency.Milliseconds(),
+		}
+		if !status.LastSuccess.IsZero() {
+			lastSuccess := status.LastSuccess
+			component.LastSuccess = &lastSuccess
+		}
+		if !status.Healthy {
+			component.Status = HealthDown
+			if status.FailureCount > 0 {
+				component.Error = "连续健康检查失败"
+			} else {
+				component.Error = "尚未通过健康检查"
+			}
+		}
+		components = append(components, component)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components
+}
+
+// GetSystemHealth 汇总数据库、额外注册组件（如 Redis）和行情源的健康状态
+// 数据库或其他关键组件不可用时为 down；非关键组件异常时为 degraded
+func (s *Server) GetSystemHealth() (*HealthReport, error) {
+	extraHealthChecksMu.RLock()
+	checks := append([]namedHealthCheck(nil), extraHealthChecks...)
+	extraHealthChecksMu.RUnlock()
+	if s.database != nil {
+		checks = append([]namedHealthCheck{{name: "database", critical: true, check: s.database.Ping}}, checks...)
+	}
+
+	components := make([]*ComponentHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedHealthCheck) {
+			defer wg.Done()
+			components[i] = runHealthCheck(c.name, c.critical, c.check)
+		}(i, c)
+	}
+	wg.Wait()
+
+	if !hasComponent(components, "redis") {
+		components = append(components, &ComponentHealth{Name: "redis", Status: HealthDisabled})
+	}
+	components = append(components, marketSourceHealth()...)
+
+	report := &HealthReport{Status: HealthOK, CheckedAt: time.Now(), Components: components}
+	for _, component := range components {
+		if component.Status != HealthDown {
+			continue
+		}
+		if component.Critical {
+			report.Status = HealthDown
+			break
+		}
+		report.Status = HealthDegraded
+	}
+	return report, nil
+}
+
+func hasComponent(components []*ComponentHealth, name string) bool {
+	for _, c := range components {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSystemHealth 系统健康汇总（供状态页使用），down 时返回 503
+func (s *Server) handleSystemHealth(c *gin.Context) {
+	report, err := s.GetSystemHealth()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status := http.StatusOK
+	if report.Status == HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// handleDatabaseIntegrity 按需执行数据库完整性检查（PRAGMA integrity_check）
+func (s *Server) handleDatabaseIntegrity(c *gin.Context) {
+	start := time.Now()
+	problems, err := s.database.CheckIntegrity()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	status := HealthOK
+	if len(problems) > 0 {
+		status = HealthDown
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":      status,
+		"problems":    problems,
+		"duration_ms": time.Since(start).Milliseconds(),
+		"checked_at":  time.Now(),
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nofx/auth"
+)
+
+func TestGetSystemHealth(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	saved := extraHealthChecks
+	t.Cleanup(func() { extraHealthChecks = saved })
+	extraHealthChecks = nil
+
+	report, err := server.GetSystemHealth()
+	if err != nil {
+		t.Fatalf("GetSystemHealth failed: %v", err)
+	}
+	if report.Status != HealthOK {
+		t.Fatalf("expected ok, got %s: %+v", report.Status, report.Components)
+	}
+	if report.Components[0].Name != "database" || report.Components[0].LastSuccess == nil {
+		t.Fatalf("unexpected database component: %+v", report.Components[0])
+	}
+	if !hasComponent(report.Components, "redis") {
+		t.Fatal("expected redis to be reported as disabled")
+	}
+
+	// 非关键组件故障 → degraded
+	RegisterHealthCheck("sentiment", false, func(ctx context.Context) error { return errors.New("timeout") })
+	report, _ = server.GetSystemHealth()
+	if report.Status != HealthDegraded {
+		t.Fatalf("expected degraded, got %s", report.Status)
+	}
+
+	// 数据库不可用 → down
+	db.Close()
+	report, _ = server.GetSystemHealth()
+	if report.Status != HealthDown {
+		t.Fatalf("expected down, got %s", report.Status)
+	}
+}
+
+func TestRunHealthCheckTimeout(t *testing.T) {
+	result := runHealthCheck("slow", false, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if result.Status != HealthDown || result.Error == "" {
+		t.Fatalf("expected timeout to mark component down, got %+v", result)
+	}
+}
+
+func TestGetSystemHealth_RegisteredRedis(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	saved := extraHealthChecks
+	t.Cleanup(func() { extraHealthChecks = saved })
+	extraHealthChecks = nil
+
+	RegisterHealthCheck("redis", false, func(ctx context.Context) error { return nil })
+	report, err := server.GetSystemHealth()
+	if err != nil {
+		t.Fatalf("GetSystemHealth failed: %v", err)
+	}
+	redisCount := 0
+	for _, component := range report.Components {
+		if component.Name != "redis" {
+			continue
+		}
+		redisCount++
+		if component.Status != HealthOK {
+			t.Fatalf("registered redis check should be reported as ok, got %+v", component)
+		}
+	}
+	if redisCount != 1 {
+		t.Fatalf("expected exactly one redis component, got %d", redisCount)
+	}
+}
+
+// TestDatabaseIntegrityRequiresAdmin 完整性检查只对管理员开放
+func TestDatabaseIntegrityRequiresAdmin(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	auth.SetJWTSecret("test-secret-for-integrity")
+	t.Setenv("ADMIN_EMAILS", "ops@example.com, root@example.com")
+
+	check := func(userID, email string) int {
+		token, err := auth.GenerateJWT(userID, email)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/health/integrity", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := check("user-1", "user@example.com"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a regular user, got %d", code)
+	}
+	if code := check("user-2", "ROOT@example.com"); code != http.StatusOK {
+		t.Fatalf("expected 200 for a listed admin email, got %d", code)
+	}
+	if code := check("admin", "admin@localhost"); code != http.StatusOK {
+		t.Fatalf("expected 200 for the system admin user, got %d", code)
+	}
+}
@@ -681,3 +681,60 @@ func TestConcurrentAuthenticationRequests(t *testing.T) {
 		t.Error("No successful logins in concurrent test")
 	}
 }
+
+// TestDisabledUserRejected 已禁用用户的有效 token 和登录请求都应被拒绝
+func TestDisabledUserRejected(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+	auth.SetJWTSecret("test-secret-for-disabled-user")
+
+	hashedPassword, err := auth.HashPassword("ValidPass123!")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	user := &config.User{ID: "disabled-user", Email: "disabled@example.com", PasswordHash: hashedPassword, OTPVerified: true}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	token, err := auth.GenerateJWT(user.ID, user.Email)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	getTraders := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/my-traders", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	login := func() int {
+		body, _ := json.Marshal(map[string]string{"email": user.Email, "password": "ValidPass123!"})
+		req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := getTraders(); code == http.StatusForbidden || code == http.StatusUnauthorized {
+		t.Fatalf("expected enabled user to pass auth, got %d", code)
+	}
+
+	if err := server.traderManager.SetUserDisabled(db, user.ID, true); err != nil {
+		t.Fatalf("SetUserDisabled failed: %v", err)
+	}
+	if code := getTraders(); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for disabled user, got %d", code)
+	}
+	if code := login(); code != http.StatusForbidden {
+		t.Fatalf("expected login of disabled user to return 403, got %d", code)
+	}
+
+	if err := db.SetUserDisabled(user.ID, false); err != nil {
+		t.Fatalf("SetUserDisabled failed: %v", err)
+	}
+	if code := getTraders(); code == http.StatusForbidden {
+		t.Fatal("expected re-enabled user to pass auth")
+	}
+}
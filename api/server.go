@@ -1,8 +1,10 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -151,6 +153,14 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 		port:          port,
 	}
 
+	// 密钥轮换时用余额查询验证新密钥
+	if database != nil {
+		database.SetExchangeKeyTester(func(exchange *config.ExchangeConfig) error {
+			_, err := s.queryExchangeBalance(exchange.UserID, exchange.ExchangeID, exchange)
+			return err
+		})
+	}
+
 	// 设置路由
 	s.setupRoutes()
 
@@ -308,6 +318,7 @@ func (s *Server) setupRoutes() {
 	{
 		// 健康检查
 		api.Any("/health", s.handleHealth)
+		api.GET("/health/summary", s.handleSystemHealth)
 
 		// 管理员登录（管理员模式下使用，公共）
 
@@ -336,6 +347,10 @@ func (s *Server) setupRoutes() {
 		api.POST("/equity-history-batch", s.handleEquityHistoryBatch)
 		api.GET("/traders/:id/public-config", s.handleGetPublicTraderConfig)
 
+		// 外部告警 webhook（必须配置 WEBHOOK_SECRET 并校验 HMAC 签名，无需JWT）
+		api.POST("/webhook", s.handleWebhook)
+		api.POST("/webhook/:traderID", s.handleWebhook)
+
 		// 认证相关路由（应用严格速率限制，防止暴力破解）
 		authGroup := api.Group("/", middleware.AuthRateLimitMiddleware())
 		{
@@ -360,23 +375,53 @@ func (s *Server) setupRoutes() {
 			// 服务器IP查询（需要认证，用于白名单配置）
 			protected.GET("/server-ip", s.handleGetServerIP)
 
+			// 管理员接口（需要认证 + 管理员权限）
+			admin := protected.Group("/", s.adminMiddleware())
+			// 数据库完整性检查（按需执行，耗时较长，且暴露数据库内部信息）
+			admin.GET("/health/integrity", s.handleDatabaseIntegrity)
+
 			// AI交易员管理
 			protected.GET("/my-traders", s.handleTraderList)
 			protected.GET("/traders/:id/config", s.handleGetTraderConfig)
 			protected.POST("/traders", s.handleCreateTrader)
 			protected.PUT("/traders/:id", s.handleUpdateTrader)
 			protected.DELETE("/traders/:id", s.handleDeleteTrader)
+			protected.GET("/traders/duplicates", s.handleFindDuplicateTraders)
+			protected.POST("/traders/:id/merge", s.handleMergeTraders)
 			protected.POST("/traders/:id/start", s.handleStartTrader)
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
+			protected.POST("/traders/:id/sync-balance", s.handleSyncBalance)
+			protected.GET("/traders/:id/stats", s.handleTraderStats)
+			protected.GET("/traders/:id/daily-pnl", s.handleTraderDailyPnL)
+			protected.GET("/traders/:id/fees", s.handleTraderFees)
+			protected.GET("/traders/:id/pnl-breakdown", s.handleTraderPnLBreakdown)
+			protected.GET("/traders/:id/drawdown", s.handleTraderDrawdown)
+			protected.GET("/traders/:id/balance-history", s.handleTraderBalanceHistory)
+			protected.GET("/traders/:id/logs", s.handleTraderLogs)
+			protected.GET("/audit-log", s.handleAuditLog)
+			protected.GET("/exposure", s.handleAggregateExposure)
+			protected.GET("/traders/:id/decisions/current", s.handleCurrentDecisions)
+			protected.POST("/traders/:id/decisions/:decision_id/replay", s.handleReplayDecision)
+			protected.GET("/traders/:id/effective-prompt", s.handleEffectivePrompt)
+			protected.GET("/traders/:id/config-history", s.handleTraderConfigHistory)
+
+			// webhook 失败记录
+			protected.GET("/webhook-failures", s.handleGetWebhookFailures)
+			protected.POST("/webhook-failures/:id/retry", s.handleRetryWebhookFailure)
 
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
 			protected.PUT("/models", s.handleUpdateModelConfigs)
+			protected.PUT("/models/providers/:provider/enabled", s.handleSetProviderEnabled)
 
 			// 交易所配置
 			protected.GET("/exchanges", s.handleGetExchangeConfigs)
 			protected.PUT("/exchanges", s.handleUpdateExchangeConfigs)
+			protected.POST("/exchanges", s.handleCreateExchangeAccount)
+			protected.POST("/exchanges/:id/test", s.handleTestExchangeConnection)
+			protected.POST("/exchanges/:id/rotate-keys", s.handleRotateExchangeKeys)
+			protected.PUT("/exchanges/types/:type/enabled", s.handleSetExchangeTypeEnabled)
 
 			// 用户信号源配置
 			protected.GET("/user/signal-sources", s.handleGetUserSignalSource)
@@ -387,6 +432,12 @@ func (s *Server) setupRoutes() {
 			protected.PUT("/prompt-templates/:name", s.handleUpdatePromptTemplate)
 			protected.DELETE("/prompt-templates/:name", s.handleDeletePromptTemplate)
 			protected.POST("/prompt-templates/reload", s.handleReloadPromptTemplates)
+
+			// 用户提示词模板（数据库，交易员通过 prompt_template_name 引用）
+			protected.GET("/user-prompt-templates", s.handleGetUserPromptTemplates)
+			protected.POST("/user-prompt-templates", s.handleCreateUserPromptTemplate)
+			protected.PUT("/user-prompt-templates/:name", s.handleUpdateUserPromptTemplate)
+			protected.DELETE("/user-prompt-templates/:name", s.handleDeleteUserPromptTemplate)
 			// 指定trader的数据（使用query参数 ?trader_id=xxx）
 			protected.GET("/status", s.handleStatus)
 			protected.GET("/account", s.handleAccount)
@@ -401,10 +452,16 @@ func (s *Server) setupRoutes() {
 
 // handleHealth 健康检查
 func (s *Server) handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"status": "ok",
 		"time":   c.Request.Context().Value("time"),
-	})
+	}
+	if s.database != nil {
+		resp["metrics"] = gin.H{
+			"decrypt_failures": s.database.GetDecryptFailureStats(),
+		}
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // handleGetCSRFToken 获取 CSRF Token
@@ -641,6 +698,7 @@ type CreateTraderRequest struct {
 	ExchangeID           string  `json:"exchange_id" binding:"required"`
 	InitialBalance       float64 `json:"initial_balance"`
 	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
+	ScanIntervalSeconds  int     `json:"scan_interval_seconds"` // 秒级扫描间隔，优先于 scan_interval_minutes
 	BTCETHLeverage       int     `json:"btc_eth_leverage"`
 	AltcoinLeverage      int     `json:"altcoin_leverage"`
 	TradingSymbols       string  `json:"trading_symbols"`
@@ -650,12 +708,33 @@ type CreateTraderRequest struct {
 	IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
 	UseCoinPool          bool    `json:"use_coin_pool"`
 	UseOITop             bool    `json:"use_oi_top"`
-	TakerFeeRate         float64 `json:"taker_fee_rate"`        // Taker fee rate, default 0.0004 (0.04%)
-	MakerFeeRate         float64 `json:"maker_fee_rate"`        // Maker fee rate, default 0.0002 (0.02%)
-	OrderStrategy        string  `json:"order_strategy"`        // Order strategy: market_only, conservative_hybrid, limit_only
-	LimitPriceOffset     float64 `json:"limit_price_offset"`    // Limit price offset percentage, default -0.03 (-0.03%)
-	LimitTimeoutSeconds  int     `json:"limit_timeout_seconds"` // Limit order timeout in seconds, default 60
-	Timeframes           string  `json:"timeframes"`            // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+	TakerFeeRate         float64 `json:"taker_fee_rate"`         // Taker fee rate, defaults to the exchange's rate (Binance 0.0004)
+	MakerFeeRate         float64 `json:"maker_fee_rate"`         // Maker fee rate, defaults to the exchange's rate (Binance 0.0002)
+	OrderStrategy        string  `json:"order_strategy"`         // Order strategy: market_only, conservative_hybrid, limit_only
+	BTCETHOrderStrategy  string  `json:"btc_eth_order_strategy"` // BTC/ETH订单策略，为空时使用 order_strategy
+	AltcoinOrderStrategy string  `json:"altcoin_order_strategy"` // 山寨币订单策略，为空时使用 order_strategy
+	LimitPriceOffset     float64 `json:"limit_price_offset"`     // Limit price offset percentage, default -0.03 (-0.03%)
+	LimitTimeoutSeconds  int     `json:"limit_timeout_seconds"`  // Limit order timeout in seconds, default 60
+	Timeframes           string  `json:"timeframes"`             // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+	FallbackAIModelIDs   []int   `json:"fallback_ai_model_ids"`  // 备用AI模型ID（ai_models.id），主模型失败时按顺序尝试
+	LossStreakThreshold  int     `json:"loss_streak_threshold"`  // 连续亏损N笔后暂停交易，0表示关闭
+	CooldownMinutes      int     `json:"cooldown_minutes"`       // 连续亏损冷却时长（分钟），默认60
+	PromptTemplateName   string  `json:"prompt_template_name"`   // 引用的数据库提示词模板名称，为空表示使用 custom_prompt
+	MaxOrdersPerCycle    int     `json:"max_orders_per_cycle"`   // 单个决策周期最多开仓数（平仓不计入），0表示不限制
+
+	// 移动止损（按价格百分比，0表示关闭）
+	TrailingStopPercent       float64 `json:"trailing_stop_percent"`       // 距最优价的回撤百分比
+	TrailingActivationPercent float64 `json:"trailing_activation_percent"` // 浮盈达到该百分比后激活
+
+	CandleLookback map[string]int `json:"candle_lookback"` // 各时间线传给AI的K线数量，例如 {"4h":30,"1m":5}，未配置的时间线使用默认值
+
+	SymbolWeights map[string]float64 `json:"symbol_weights"` // 币种仓位权重，例如 {"BTCUSDT":2,"DOGEUSDT":0.5}，未列出的币种权重为1
+
+	// OCO 止盈止损（按开仓价百分比，0表示使用AI给出的止盈止损价）
+	TakeProfitPercent float64 `json:"tp_percent"`
+	StopLossPercent   float64 `json:"sl_percent"`
+
+	Timezone string `json:"timezone"` // 交易员时区（IANA 名称），用于切分交易日，为空表示 UTC
 }
 
 type ModelConfig struct {
@@ -669,12 +748,13 @@ type ModelConfig struct {
 
 // SafeModelConfig 安全的模型配置结构（不包含敏感信息）
 type SafeModelConfig struct {
-	ID              string `json:"id"`
-	Name            string `json:"name"`
-	Provider        string `json:"provider"`
-	Enabled         bool   `json:"enabled"`
-	CustomAPIURL    string `json:"customApiUrl"`    // 自定义API URL（通常不敏感）
-	CustomModelName string `json:"customModelName"` // 自定义模型名（不敏感）
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	Provider          string `json:"provider"`
+	Enabled           bool   `json:"enabled"`
+	CustomAPIURL      string `json:"customApiUrl"`      // 自定义API URL（通常不敏感）
+	CustomModelName   string `json:"customModelName"`   // 自定义模型名（不敏感）
+	RequestsPerMinute int    `json:"requestsPerMinute"` // 每分钟请求上限，0表示使用全局默认值
 }
 
 type ExchangeConfig struct {
@@ -705,7 +785,11 @@ type UpdateModelConfigRequest struct {
 		APIKey          string `json:"api_key"`
 		CustomAPIURL    string `json:"custom_api_url"`
 		CustomModelName string `json:"custom_model_name"`
+		// RequestsPerMinute 该模型每分钟请求上限（0表示使用全局 ai_model_rpm），未传入时保持不变
+		RequestsPerMinute *int `json:"requests_per_minute,omitempty"`
 	} `json:"models"`
+	// CustomHeaders 按模型ID设置自定义请求头；未出现的模型保持不变，空对象表示清除
+	CustomHeaders map[string]map[string]string `json:"custom_headers,omitempty"`
 }
 
 type UpdateExchangeConfigRequest struct {
@@ -718,9 +802,24 @@ type UpdateExchangeConfigRequest struct {
 		AsterUser             string `json:"aster_user"`
 		AsterSigner           string `json:"aster_signer"`
 		AsterPrivateKey       string `json:"aster_private_key"`
+		AccountMode           string `json:"account_mode"` // 币安账户类型，空值表示保持不变
 	} `json:"exchanges"`
 }
 
+// resolveExchangeType 将交易员引用的交易所（类型或数字账户ID）解析为交易所类型，未找到时原样返回
+func (s *Server) resolveExchangeType(userID, exchangeID string) string {
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		return exchangeID
+	}
+	for _, ex := range exchanges {
+		if ex.ExchangeID == exchangeID || strconv.Itoa(ex.ID) == exchangeID {
+			return ex.ExchangeID
+		}
+	}
+	return exchangeID
+}
+
 // queryExchangeBalance 查詢交易所實際餘額
 // 根據交易所類型創建臨時 trader 並查詢當前總資產
 func (s *Server) queryExchangeBalance(userID, exchangeID string, exchangeCfg *config.ExchangeConfig) (float64, error) {
@@ -731,7 +830,11 @@ func (s *Server) queryExchangeBalance(userID, exchangeID string, exchangeCfg *co
 	switch exchangeID {
 	case "binance":
 		// 使用默认订单策略（查询余额不需要实际下单）
-		tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID, "market_only", -0.03, 60)
+		if exchangeCfg.AccountMode == config.AccountModePortfolioMargin {
+			tempTrader = trader.NewPortfolioMarginTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey)
+		} else {
+			tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID, "market_only", -0.03, 60)
+		}
 	case "hyperliquid":
 		tempTrader, err = trader.NewHyperliquidTrader(
 			exchangeCfg.APIKey, // private key
@@ -856,13 +959,8 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		systemPromptTemplate = req.SystemPromptTemplate
 	}
 
-	// 设置扫描间隔默认值
-	scanIntervalMinutes := req.ScanIntervalMinutes
-	if scanIntervalMinutes <= 0 {
-		scanIntervalMinutes = 2 // 默认2分钟
-	} else if scanIntervalMinutes < 1 {
-		scanIntervalMinutes = 1 // 最低1分钟，不允许小于1分钟
-	}
+	// 设置扫描间隔默认值（默认2分钟，秒级配置不低于 config.MinScanIntervalSeconds）
+	scanIntervalSeconds, scanIntervalMinutes := resolveScanInterval(req.ScanIntervalSeconds, req.ScanIntervalMinutes, 2*60)
 
 	// ✅ Fix #787, #807, #790: Respect user-specified initial balance
 	// ✅ Fix P&L calculation: Use total equity instead of available balance when auto-querying
@@ -915,12 +1013,15 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	takerFeeRate := req.TakerFeeRate
 	makerFeeRate := req.MakerFeeRate
 
-	// 如果用户未设置，使用默认值
-	if takerFeeRate == 0 {
-		takerFeeRate = 0.0004 // Binance 标准 Taker 费率
-	}
-	if makerFeeRate == 0 {
-		makerFeeRate = 0.0002 // Binance 标准 Maker 费率
+	// 如果用户未设置，使用该交易所类型的默认费率
+	if takerFeeRate == 0 || makerFeeRate == 0 {
+		defaultRates := s.database.GetExchangeFeeRates(s.resolveExchangeType(userID, req.ExchangeID))
+		if takerFeeRate == 0 {
+			takerFeeRate = defaultRates.Taker
+		}
+		if makerFeeRate == 0 {
+			makerFeeRate = defaultRates.Maker
+		}
 	}
 
 	// 添加费率范围验证
@@ -939,7 +1040,7 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	// 设置时间线默认值
 	timeframes := req.Timeframes
 	if timeframes == "" {
-		timeframes = "4h" // 默认只勾选4小时线
+		timeframes = strings.Join(s.database.GetDefaultTimeframes(), ",") // 默认时间线（system_config: default_timeframes）
 	}
 
 	// 设置订单策略默认值
@@ -947,6 +1048,10 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	if orderStrategy == "" {
 		orderStrategy = "conservative_hybrid" // 默认使用保守混合策略
 	}
+	if err := validateOrderStrategies(orderStrategy, req.BTCETHOrderStrategy, req.AltcoinOrderStrategy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 设置限价偏移默认值
 	limitPriceOffset := req.LimitPriceOffset
@@ -989,9 +1094,23 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("AI模型 %s 不存在", req.AIModelID)})
 		return
 	}
+	if err := s.database.ValidateFallbackAIModels(userID, aiModelIntID, req.FallbackAIModelIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 连续亏损冷却配置
+	if req.LossStreakThreshold < 0 || req.CooldownMinutes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "连续亏损阈值和冷却时长不能为负数"})
+		return
+	}
+	cooldownMinutes := req.CooldownMinutes
+	if cooldownMinutes == 0 {
+		cooldownMinutes = 60
+	}
 
 	log.Printf("🔍 [DEBUG] 步骤8: 查询用户 %s 的交易所配置 (请求的交易所: %s)...", userID, req.ExchangeID)
-	exchanges, err := s.database.GetExchanges(userID)
+	exchanges, err := s.database.GetExchangesMetadata(userID)
 	if err != nil {
 		log.Printf("❌ [DEBUG] 查询交易所失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
@@ -1038,12 +1157,27 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		SystemPromptTemplate: systemPromptTemplate,
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
-		TakerFeeRate:         takerFeeRate,        // 添加 Taker 费率
-		MakerFeeRate:         makerFeeRate,        // 添加 Maker 费率
-		OrderStrategy:        orderStrategy,       // 添加订单策略
-		LimitPriceOffset:     limitPriceOffset,    // 添加限价偏移
-		LimitTimeoutSeconds:  limitTimeoutSeconds, // 添加限价超时
-		Timeframes:           timeframes,          // 添加时间线选择
+		ScanIntervalSeconds:  scanIntervalSeconds,
+		TakerFeeRate:         takerFeeRate,             // 添加 Taker 费率
+		MakerFeeRate:         makerFeeRate,             // 添加 Maker 费率
+		OrderStrategy:        orderStrategy,            // 添加订单策略
+		BTCETHOrderStrategy:  req.BTCETHOrderStrategy,  // BTC/ETH订单策略
+		AltcoinOrderStrategy: req.AltcoinOrderStrategy, // 山寨币订单策略
+		LimitPriceOffset:     limitPriceOffset,         // 添加限价偏移
+		LimitTimeoutSeconds:  limitTimeoutSeconds,      // 添加限价超时
+		Timeframes:           timeframes,               // 添加时间线选择
+		FallbackAIModelIDs:   config.EncodeFallbackAIModelIDs(req.FallbackAIModelIDs),
+		LossStreakThreshold:  req.LossStreakThreshold,
+		CooldownMinutes:      cooldownMinutes,
+		PromptTemplateName:   strings.TrimSpace(req.PromptTemplateName),
+		MaxOrdersPerCycle:    req.MaxOrdersPerCycle,
+		TrailingStopPercent:  req.TrailingStopPercent,
+		TrailingActivation:   req.TrailingActivationPercent,
+		CandleLookback:       config.EncodeCandleLookback(req.CandleLookback),
+		SymbolWeights:        config.EncodeSymbolWeights(req.SymbolWeights),
+		TakeProfitPercent:    req.TakeProfitPercent,
+		StopLossPercent:      req.StopLossPercent,
+		Timezone:             strings.TrimSpace(req.Timezone),
 		IsRunning:            false,
 	}
 	log.Printf("✅ [DEBUG] 交易员配置对象已构建: ID=%s, AIModelID=%d, ExchangeID=%d", traderID, aiModelIntID, exchangeIntID)
@@ -1051,6 +1185,13 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	// 保存到数据库
 	log.Printf("🔍 [DEBUG] 步骤10: 保存交易员到数据库...")
 	err = s.database.CreateTrader(trader)
+	if errors.Is(err, config.ErrTooManySymbols) || errors.Is(err, config.ErrPromptTemplateNotFound) || errors.Is(err, config.ErrUnsupportedSymbols) ||
+		errors.Is(err, config.ErrInvalidMaxOrders) || errors.Is(err, config.ErrInvalidTrailingStop) ||
+		errors.Is(err, config.ErrInvalidCandleLookback) || errors.Is(err, config.ErrInvalidSymbolWeights) ||
+		errors.Is(err, config.ErrInvalidExitBracket) || errors.Is(err, config.ErrInvalidTimezone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("❌ [DEBUG] 数据库 CreateTrader 失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建交易员失败: %v", err)})
@@ -1082,6 +1223,7 @@ type UpdateTraderRequest struct {
 	ExchangeID           string  `json:"exchange_id" binding:"required"`
 	InitialBalance       float64 `json:"initial_balance"`
 	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
+	ScanIntervalSeconds  int     `json:"scan_interval_seconds"` // 秒级扫描间隔，优先于 scan_interval_minutes
 	BTCETHLeverage       int     `json:"btc_eth_leverage"`
 	AltcoinLeverage      int     `json:"altcoin_leverage"`
 	TradingSymbols       string  `json:"trading_symbols"`
@@ -1091,12 +1233,57 @@ type UpdateTraderRequest struct {
 	IsCrossMargin        *bool   `json:"is_cross_margin"`
 	UseCoinPool          *bool   `json:"use_coin_pool"`
 	UseOITop             *bool   `json:"use_oi_top"`
-	TakerFeeRate         float64 `json:"taker_fee_rate"`        // Taker fee rate
-	MakerFeeRate         float64 `json:"maker_fee_rate"`        // Maker fee rate
-	OrderStrategy        string  `json:"order_strategy"`        // Order strategy
-	LimitPriceOffset     float64 `json:"limit_price_offset"`    // Limit price offset
-	LimitTimeoutSeconds  int     `json:"limit_timeout_seconds"` // Limit timeout in seconds
-	Timeframes           string  `json:"timeframes"`            // Timeframes selection
+	TakerFeeRate         float64 `json:"taker_fee_rate"`         // Taker fee rate
+	MakerFeeRate         float64 `json:"maker_fee_rate"`         // Maker fee rate
+	OrderStrategy        string  `json:"order_strategy"`         // Order strategy
+	BTCETHOrderStrategy  *string `json:"btc_eth_order_strategy"` // BTC/ETH订单策略，nil表示保持原值，空字符串表示使用 order_strategy
+	AltcoinOrderStrategy *string `json:"altcoin_order_strategy"` // 山寨币订单策略，nil表示保持原值，空字符串表示使用 order_strategy
+	LimitPriceOffset     float64 `json:"limit_price_offset"`     // Limit price offset
+	LimitTimeoutSeconds  int     `json:"limit_timeout_seconds"`  // Limit timeout in seconds
+	Timeframes           string  `json:"timeframes"`             // Timeframes selection
+	FallbackAIModelIDs   *[]int  `json:"fallback_ai_model_ids"`  // 备用AI模型ID，nil表示保持原值，空数组表示清除
+	LossStreakThreshold  *int    `json:"loss_streak_threshold"`  // 连续亏损冷却阈值，nil表示保持原值
+	CooldownMinutes      *int    `json:"cooldown_minutes"`       // 连续亏损冷却时长（分钟），nil表示保持原值
+	PromptTemplateName   *string `json:"prompt_template_name"`   // 引用的数据库提示词模板名称，nil表示保持原值，空字符串表示取消引用
+	MaxOrdersPerCycle    *int    `json:"max_orders_per_cycle"`   // 单个决策周期最多开仓数，nil表示保持原值
+
+	// 移动止损，nil表示保持原值，0表示关闭
+	TrailingStopPercent       *float64 `json:"trailing_stop_percent"`
+	TrailingActivationPercent *float64 `json:"trailing_activation_percent"`
+
+	CandleLookback *map[string]int `json:"candle_lookback"` // 各时间线K线数量，nil表示保持原值，空对象表示恢复默认
+
+	SymbolWeights *map[string]float64 `json:"symbol_weights"` // 币种仓位权重，nil表示保持原值，空对象表示恢复等权
+
+	TakeProfitPercent *float64 `json:"tp_percent"`
+	StopLossPercent   *float64 `json:"sl_percent"`
+
+	Timezone *string `json:"timezone"` // 交易员时区，nil表示保持原值，空字符串表示恢复 UTC
+}
+
+// resolveScanInterval 计算扫描间隔，返回 (秒, 分钟)
+// 秒级配置优先；分钟字段保留用于向后兼容（至少为1分钟）
+func resolveScanInterval(seconds, minutes, defaultSeconds int) (int, int) {
+	seconds = config.NormalizeScanInterval(seconds, minutes, defaultSeconds)
+	minutes = seconds / 60
+	if minutes < 1 {
+		minutes = 1
+	}
+	return seconds, minutes
+}
+
+// validateOrderStrategies 校验全局订单策略以及按币种类别的覆盖策略（覆盖策略允许为空）
+func validateOrderStrategies(orderStrategy, btcEthOrderStrategy, altcoinOrderStrategy string) error {
+	if !trader.IsValidOrderStrategy(orderStrategy) {
+		return fmt.Errorf("无效的订单策略: %s（可选: market_only, conservative_hybrid, limit_only）", orderStrategy)
+	}
+	if btcEthOrderStrategy != "" && !trader.IsValidOrderStrategy(btcEthOrderStrategy) {
+		return fmt.Errorf("无效的BTC/ETH订单策略: %s", btcEthOrderStrategy)
+	}
+	if altcoinOrderStrategy != "" && !trader.IsValidOrderStrategy(altcoinOrderStrategy) {
+		return fmt.Errorf("无效的山寨币订单策略: %s", altcoinOrderStrategy)
+	}
+	return nil
 }
 
 // handleUpdateTrader 更新交易员配置
@@ -1152,12 +1339,10 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		altcoinLeverage = existingTrader.AltcoinLeverage // 保持原值
 	}
 
-	// 设置扫描间隔，允许更新
-	scanIntervalMinutes := req.ScanIntervalMinutes
-	if scanIntervalMinutes <= 0 {
-		scanIntervalMinutes = existingTrader.ScanIntervalMinutes // 保持原值
-	} else if scanIntervalMinutes < 1 {
-		scanIntervalMinutes = 1 // 最低1分钟，不允许小于1分钟
+	// 设置扫描间隔，允许更新（都未提供时保持原值）
+	scanIntervalSeconds, scanIntervalMinutes := existingTrader.ScanIntervalSeconds, existingTrader.ScanIntervalMinutes
+	if req.ScanIntervalSeconds > 0 || req.ScanIntervalMinutes > 0 {
+		scanIntervalSeconds, scanIntervalMinutes = resolveScanInterval(req.ScanIntervalSeconds, req.ScanIntervalMinutes, 2*60)
 	}
 
 	// 设置提示词模板，允许更新
@@ -1166,6 +1351,11 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		systemPromptTemplate = existingTrader.SystemPromptTemplate // 如果请求中没有提供，保持原值
 	}
 
+	promptTemplateName := existingTrader.PromptTemplateName
+	if req.PromptTemplateName != nil {
+		promptTemplateName = strings.TrimSpace(*req.PromptTemplateName)
+	}
+
 	// 设置信号源开关
 	useCoinPool := existingTrader.UseCoinPool
 	if req.UseCoinPool != nil {
@@ -1181,19 +1371,20 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 	takerFeeRate := req.TakerFeeRate
 	makerFeeRate := req.MakerFeeRate
 
-	// 如果用户未提供或为0，保持原有配置
-	if takerFeeRate == 0 {
-		if existingTrader.TakerFeeRate > 0 {
-			takerFeeRate = existingTrader.TakerFeeRate // 保持原值
-		} else {
-			takerFeeRate = 0.0004 // 使用默认值
-		}
+	// 如果用户未提供或为0，保持原有配置；原配置也没有时使用该交易所类型的默认费率
+	if takerFeeRate == 0 && existingTrader.TakerFeeRate > 0 {
+		takerFeeRate = existingTrader.TakerFeeRate // 保持原值
 	}
-	if makerFeeRate == 0 {
-		if existingTrader.MakerFeeRate > 0 {
-			makerFeeRate = existingTrader.MakerFeeRate // 保持原值
-		} else {
-			makerFeeRate = 0.0002 // 使用默认值
+	if makerFeeRate == 0 && existingTrader.MakerFeeRate > 0 {
+		makerFeeRate = existingTrader.MakerFeeRate // 保持原值
+	}
+	if takerFeeRate == 0 || makerFeeRate == 0 {
+		defaultRates := s.database.GetExchangeFeeRates(s.resolveExchangeType(userID, req.ExchangeID))
+		if takerFeeRate == 0 {
+			takerFeeRate = defaultRates.Taker
+		}
+		if makerFeeRate == 0 {
+			makerFeeRate = defaultRates.Maker
 		}
 	}
 
@@ -1224,6 +1415,20 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		}
 	}
 
+	// 按币种类别的订单策略，未传入时保持原值
+	btcEthOrderStrategy := existingTrader.BTCETHOrderStrategy
+	if req.BTCETHOrderStrategy != nil {
+		btcEthOrderStrategy = *req.BTCETHOrderStrategy
+	}
+	altcoinOrderStrategy := existingTrader.AltcoinOrderStrategy
+	if req.AltcoinOrderStrategy != nil {
+		altcoinOrderStrategy = *req.AltcoinOrderStrategy
+	}
+	if err := validateOrderStrategies(orderStrategy, btcEthOrderStrategy, altcoinOrderStrategy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 设置限价偏移，允许更新
 	limitPriceOffset := req.LimitPriceOffset
 	if limitPriceOffset == 0 {
@@ -1250,10 +1455,59 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		if existingTrader.Timeframes != "" {
 			timeframes = existingTrader.Timeframes // 保持原值
 		} else {
-			timeframes = "4h" // 使用默认值
+			timeframes = strings.Join(s.database.GetDefaultTimeframes(), ",") // 使用默认值
 		}
 	}
 
+	// 连续亏损冷却配置，未传入时保持原值
+	lossStreakThreshold := existingTrader.LossStreakThreshold
+	if req.LossStreakThreshold != nil {
+		lossStreakThreshold = *req.LossStreakThreshold
+	}
+	cooldownMinutes := existingTrader.CooldownMinutes
+	if req.CooldownMinutes != nil {
+		cooldownMinutes = *req.CooldownMinutes
+	}
+	if lossStreakThreshold < 0 || cooldownMinutes < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "连续亏损阈值和冷却时长不能为负数"})
+		return
+	}
+	if cooldownMinutes == 0 {
+		cooldownMinutes = 60
+	}
+	maxOrdersPerCycle := existingTrader.MaxOrdersPerCycle
+	if req.MaxOrdersPerCycle != nil {
+		maxOrdersPerCycle = *req.MaxOrdersPerCycle
+	}
+	trailingStopPercent := existingTrader.TrailingStopPercent
+	if req.TrailingStopPercent != nil {
+		trailingStopPercent = *req.TrailingStopPercent
+	}
+	trailingActivation := existingTrader.TrailingActivation
+	if req.TrailingActivationPercent != nil {
+		trailingActivation = *req.TrailingActivationPercent
+	}
+	candleLookback := existingTrader.CandleLookback
+	if req.CandleLookback != nil {
+		candleLookback = config.EncodeCandleLookback(*req.CandleLookback)
+	}
+	symbolWeights := existingTrader.SymbolWeights
+	if req.SymbolWeights != nil {
+		symbolWeights = config.EncodeSymbolWeights(*req.SymbolWeights)
+	}
+	tpPercent := existingTrader.TakeProfitPercent
+	if req.TakeProfitPercent != nil {
+		tpPercent = *req.TakeProfitPercent
+	}
+	slPercent := existingTrader.StopLossPercent
+	if req.StopLossPercent != nil {
+		slPercent = *req.StopLossPercent
+	}
+	timezone := existingTrader.Timezone
+	if req.Timezone != nil {
+		timezone = strings.TrimSpace(*req.Timezone)
+	}
+
 	// 查询 AI Model 和 Exchange 的自增 ID
 	aiModels, err := s.database.GetAIModels(userID)
 	if err != nil {
@@ -1275,7 +1529,21 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		return
 	}
 
-	exchanges, err := s.database.GetExchanges(userID)
+	// 备用AI模型：未提供时保持原值，但仍需重新校验（主模型可能已变更）
+	fallbackAIModelIDs, err := config.ParseFallbackAIModelIDs(existingTrader.FallbackAIModelIDs)
+	if err != nil {
+		fallbackAIModelIDs = nil
+	}
+	if req.FallbackAIModelIDs != nil {
+		fallbackAIModelIDs = *req.FallbackAIModelIDs
+	}
+	if err := s.database.ValidateFallbackAIModels(userID, aiModelIntID, fallbackAIModelIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fallbackAIModelsJSON := config.EncodeFallbackAIModelIDs(fallbackAIModelIDs)
+
+	exchanges, err := s.database.GetExchangesMetadata(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
 		return
@@ -1313,17 +1581,39 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		SystemPromptTemplate: systemPromptTemplate,
 		IsCrossMargin:        isCrossMargin,
 		ScanIntervalMinutes:  scanIntervalMinutes,
+		ScanIntervalSeconds:  scanIntervalSeconds,
 		TakerFeeRate:         takerFeeRate,             // 添加 Taker 费率
 		MakerFeeRate:         makerFeeRate,             // 添加 Maker 费率
 		OrderStrategy:        orderStrategy,            // 添加订单策略
+		BTCETHOrderStrategy:  btcEthOrderStrategy,      // BTC/ETH订单策略
+		AltcoinOrderStrategy: altcoinOrderStrategy,     // 山寨币订单策略
 		LimitPriceOffset:     limitPriceOffset,         // 添加限价偏移
 		LimitTimeoutSeconds:  limitTimeoutSeconds,      // 添加限价超时
 		Timeframes:           timeframes,               // 添加时间线选择
+		FallbackAIModelIDs:   fallbackAIModelsJSON,     // 备用AI模型
+		LossStreakThreshold:  lossStreakThreshold,      // 连续亏损冷却阈值
+		CooldownMinutes:      cooldownMinutes,          // 连续亏损冷却时长
+		PromptTemplateName:   promptTemplateName,       // 引用的提示词模板
+		MaxOrdersPerCycle:    maxOrdersPerCycle,        // 单周期最大下单数
+		TrailingStopPercent:  trailingStopPercent,      // 移动止损回撤百分比
+		TrailingActivation:   trailingActivation,       // 移动止损激活阈值
+		CandleLookback:       candleLookback,           // K线回看数量
+		SymbolWeights:        symbolWeights,            // 币种仓位权重
+		TakeProfitPercent:    tpPercent,                // OCO 止盈百分比
+		StopLossPercent:      slPercent,                // OCO 止损百分比
+		Timezone:             timezone,                 // 交易员时区
 		IsRunning:            existingTrader.IsRunning, // 保持原值
 	}
 
 	// 更新数据库
 	err = s.database.UpdateTrader(trader)
+	if errors.Is(err, config.ErrTooManySymbols) || errors.Is(err, config.ErrPromptTemplateNotFound) || errors.Is(err, config.ErrUnsupportedSymbols) ||
+		errors.Is(err, config.ErrInvalidMaxOrders) || errors.Is(err, config.ErrInvalidTrailingStop) ||
+		errors.Is(err, config.ErrInvalidCandleLookback) || errors.Is(err, config.ErrInvalidSymbolWeights) ||
+		errors.Is(err, config.ErrInvalidExitBracket) || errors.Is(err, config.ErrInvalidTimezone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易员失败: %v", err)})
 		return
@@ -1388,6 +1678,48 @@ func (s *Server) handleDeleteTrader(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已删除"})
 }
 
+// handleFindDuplicateTraders 查找名称、交易所、AI模型完全相同的重复交易员
+func (s *Server) handleFindDuplicateTraders(c *gin.Context) {
+	groups, err := s.database.FindDuplicateTraders(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("查询重复交易员失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+// handleMergeTraders 将重复交易员合并到路径中的交易员（迁移历史记录后删除重复项）
+func (s *Server) handleMergeTraders(c *gin.Context) {
+	userID := c.GetString("user_id")
+	keepID := c.Param("id")
+
+	var req struct {
+		DuplicateIDs []string `json:"duplicate_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.DuplicateIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate_ids 不能为空"})
+		return
+	}
+
+	if err := s.database.MergeTraders(userID, keepID, req.DuplicateIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("合并交易员失败: %v", err)})
+		return
+	}
+
+	// 数据库已删除重复交易员，同步移除内存中的实例
+	for _, dupID := range req.DuplicateIDs {
+		if dupID == keepID {
+			continue
+		}
+		if err := s.traderManager.RemoveTrader(dupID); err != nil {
+			log.Printf("⚠️ 从内存中移除交易员时出现警告: %v", err)
+		}
+	}
+
+	log.Printf("🔀 交易员 %s 已合并 %d 个重复交易员", keepID, len(req.DuplicateIDs))
+	c.JSON(http.StatusOK, gin.H{"message": "交易员已合并", "trader_id": keepID})
+}
+
 // handleStartTrader 启动交易员
 func (s *Server) handleStartTrader(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -1430,11 +1762,15 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 		log.Printf("▶️  启动交易员 %s (%s)", traderID, trader.GetName())
 		if err := trader.Run(); err != nil {
 			log.Printf("❌ 交易员 %s 运行错误: %v", trader.GetName(), err)
+			reason := fmt.Sprintf("%s: %v", config.StopReasonError, err)
+			if err := s.database.UpdateTraderStatus(userID, traderID, false, reason); err != nil {
+				log.Printf("⚠️  更新交易员状态失败: %v", err)
+			}
 		}
 	}()
 
 	// 更新数据库中的运行状态
-	err = s.database.UpdateTraderStatus(userID, traderID, true)
+	err = s.database.UpdateTraderStatus(userID, traderID, true, "")
 	if err != nil {
 		log.Printf("⚠️  更新交易员状态失败: %v", err)
 	}
@@ -1478,7 +1814,7 @@ func (s *Server) handleStopTrader(c *gin.Context) {
 	trader.Stop()
 
 	// 更新数据库中的运行状态
-	err = s.database.UpdateTraderStatus(userID, traderID, false)
+	err = s.database.UpdateTraderStatus(userID, traderID, false, config.StopReasonUser)
 	if err != nil {
 		log.Printf("⚠️  更新交易员状态失败: %v", err)
 	}
@@ -1487,6 +1823,432 @@ func (s *Server) handleStopTrader(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已停止"})
 }
 
+// handleTraderStats 获取交易员成交统计（胜率、平均盈亏、盈亏比、手续费、已实现盈亏）
+func (s *Server) handleTraderStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since 参数格式错误，应为 RFC3339"})
+			return
+		}
+		since = parsed
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	stats, err := s.database.GetTraderStats(userID, traderID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易统计失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// handleTraderLogs 获取交易员最近的周期日志（?n= 行数，默认 100）
+// 交易员未加载到内存时返回数据库中持久化的警告/错误日志
+func (s *Server) handleTraderLogs(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	n := 100
+	if raw := c.Query("n"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "n 参数必须为正整数"})
+			return
+		}
+		n = parsed
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	if lines, err := s.traderManager.GetTraderLogs(traderID, n); err == nil {
+		c.JSON(http.StatusOK, lines)
+		return
+	}
+
+	persisted, err := s.database.GetTraderLogs(traderID, n)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员日志失败: %v", err)})
+		return
+	}
+	lines := make([]trader.TraderLogLine, 0, len(persisted))
+	for _, l := range persisted {
+		lines = append(lines, trader.TraderLogLine{Time: l.CreatedAt, Level: l.Level, Message: l.Message})
+	}
+	c.JSON(http.StatusOK, lines)
+}
+
+// handleTraderBalanceHistory 获取交易员的交易所余额快照（since/until 为可选的 RFC3339 时间），用于对账
+func (s *Server) handleTraderBalanceHistory(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	var since, until time.Time
+	for name, target := range map[string]*time.Time{"since": &since, "until": &until} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s 参数格式错误，应为 RFC3339", name)})
+			return
+		}
+		*target = parsed
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	history, err := s.database.GetBalanceHistory(userID, traderID, since, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取余额历史失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// handleTraderDrawdown 获取交易员当前回撤和历史最大回撤（百分比）
+func (s *Server) handleTraderDrawdown(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	current, max, err := s.database.GetTraderDrawdown(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取回撤失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"current_drawdown_pct": current, "max_drawdown_pct": max})
+}
+
+// handleAggregateExposure 获取当前用户所有交易员按币种汇总的净敞口（用于集中度风险提示）
+// 已加载的交易员使用交易所实际持仓，其余交易员按成交记录估算
+func (s *Server) handleAggregateExposure(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	exposures, err := s.database.GetAggregateExposure(userID, s.liveExposurePositions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取持仓敞口失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, exposures)
+}
+
+// handleAuditLog 导出某个交易员、交易所或当前用户在时间范围内的审计日志（JSON 或 CSV）
+func (s *Server) handleAuditLog(c *gin.Context) {
+	userID := c.GetString("user_id")
+	entityType := c.Query("entity_type")
+	entityID := c.Query("entity_id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format 参数只支持 json 或 csv"})
+		return
+	}
+
+	// 只允许查询自己名下的实体
+	owned := false
+	switch entityType {
+	case config.AuditEntityTrader:
+		_, _, _, err := s.database.GetTraderConfig(userID, entityID)
+		owned = err == nil
+	case config.AuditEntityExchange:
+		if exchanges, err := s.database.GetExchangesMetadata(userID); err == nil {
+			for _, ex := range exchanges {
+				if strconv.Itoa(ex.ID) == entityID {
+					owned = true
+					break
+				}
+			}
+		}
+	case config.AuditEntityUser:
+		owned = entityID == userID
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entity_type 参数只支持 trader、exchange 或 user"})
+		return
+	}
+	if !owned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "实体不存在或无访问权限"})
+		return
+	}
+
+	var since, until time.Time
+	for name, target := range map[string]*time.Time{"since": &since, "until": &until} {
+		if raw := c.Query(name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " 参数格式错误，应为 RFC3339"})
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.database.ExportAuditLog(&buf, format, entityType, entityID, since, until); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("导出审计日志失败: %v", err)})
+		return
+	}
+	contentType := "application/json; charset=utf-8"
+	if format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit_%s_%s.csv", entityType, entityID))
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// handleTraderDailyPnL 获取交易员按自然日（交易员时区，默认 UTC）汇总的盈亏（用于图表，无成交的日期不返回）
+func (s *Server) handleTraderDailyPnL(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since 参数格式错误，应为 RFC3339"})
+			return
+		}
+		since = parsed
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	days, err := s.database.GetDailyPnL(userID, traderID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取每日盈亏失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, days)
+}
+
+// handleTraderPnLBreakdown 获取交易员已实现盈亏与未实现盈亏的拆分
+// 已加载的交易员直接取交易所持仓的未实现盈亏；未加载时按成交记录和标记价格估算（unrealized_source=estimate）
+func (s *Server) handleTraderPnLBreakdown(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	realized, err := s.database.GetRealizedPnL(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取盈亏拆分失败: %v", err)})
+		return
+	}
+
+	var unrealized float64
+	source := "exchange"
+	positions, err := s.livePositions(traderID)
+	if err == nil {
+		for _, pos := range positions {
+			pnl, _ := pos["unrealized_pnl"].(float64)
+			unrealized += pnl
+		}
+	} else {
+		log.Printf("⚠️ 交易员 %s 无法获取交易所持仓，按成交记录估算未实现盈亏: %v", traderID, err)
+		source = "estimate"
+		if _, unrealized, err = s.database.GetPnLBreakdown(userID, traderID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取盈亏拆分失败: %v", err)})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"realized_pnl":      realized,
+		"unrealized_pnl":    unrealized,
+		"total_pnl":         realized + unrealized,
+		"unrealized_source": source,
+	})
+}
+
+// livePositions 从已加载的交易员获取交易所当前持仓
+func (s *Server) livePositions(traderID string) ([]map[string]interface{}, error) {
+	if s.traderManager == nil {
+		return nil, fmt.Errorf("交易员未加载")
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		return nil, err
+	}
+	return at.GetPositions()
+}
+
+// liveExposurePositions 已加载交易员的交易所持仓（按标记价格计算名义价值），供敞口汇总使用
+func (s *Server) liveExposurePositions(traderID string) ([]config.TraderPosition, bool) {
+	positions, err := s.livePositions(traderID)
+	if err != nil {
+		return nil, false
+	}
+	result := make([]config.TraderPosition, 0, len(positions))
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["quantity"].(float64)
+		price, _ := pos["mark_price"].(float64)
+		if price <= 0 {
+			price, _ = pos["entry_price"].(float64)
+		}
+		result = append(result, config.TraderPosition{Symbol: symbol, Side: side, Quantity: quantity, Notional: quantity * price})
+	}
+	return result, true
+}
+
+// handleTraderFees 获取交易员 Maker/Taker 手续费拆分（成交笔数、成交额、手续费）
+func (s *Server) handleTraderFees(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	var since time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since 参数格式错误，应为 RFC3339"})
+			return
+		}
+		since = parsed
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	breakdown, err := s.database.GetFeeBreakdown(userID, traderID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取手续费统计失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// handleTraderConfigHistory 获取交易员配置变更历史
+// 同时传入 from/to（RFC3339）时返回两个时间点之间变化的字段
+func (s *Server) handleTraderConfigHistory(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	if rawFrom, rawTo := c.Query("from"), c.Query("to"); rawFrom != "" || rawTo != "" {
+		from, errFrom := time.Parse(time.RFC3339, rawFrom)
+		to, errTo := time.Parse(time.RFC3339, rawTo)
+		if errFrom != nil || errTo != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from/to 参数格式错误，应为 RFC3339"})
+			return
+		}
+		changes, err := s.database.DiffTraderConfig(traderID, from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("对比配置失败: %v", err)})
+			return
+		}
+		c.JSON(http.StatusOK, changes)
+		return
+	}
+
+	history, err := s.database.GetTraderConfigHistory(traderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取配置历史失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// handleCurrentDecisions 获取交易员每个币种的最新决策（用于当前视图面板）
+func (s *Server) handleCurrentDecisions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	latest, err := s.database.GetLatestDecisions(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取最新决策失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, latest)
+}
+
+// handleReplayDecision 用当前AI配置重放历史决策的输入prompt，返回新旧决策对比（不执行交易）
+func (s *Server) handleReplayDecision(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+	}
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil || trader.GetUserID() != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	result, err := trader.ReplayDecision(userID, c.Param("decision_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("重放决策失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// errTraderNotAccessible 交易员不存在或不属于当前用户
+var errTraderNotAccessible = errors.New("交易员不存在或无访问权限")
+
+// BuildEffectivePrompt 组装交易员实际发送给AI的系统提示词（不含实时行情），用于预览和排查prompt配置
+// 只读取配置和模板，不调用AI、不下单
+func (s *Server) BuildEffectivePrompt(userID, traderID string) (string, error) {
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
+	}
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil || trader.GetUserID() != userID {
+		return "", errTraderNotAccessible
+	}
+	return trader.EffectivePrompt()
+}
+
+// handleEffectivePrompt 预览交易员实际生效的系统提示词
+func (s *Server) handleEffectivePrompt(c *gin.Context) {
+	prompt, err := s.BuildEffectivePrompt(c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, errTraderNotAccessible) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("组装提示词失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"system_prompt": prompt})
+}
+
 // handleUpdateTraderPrompt 更新交易员自定义Prompt
 func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	traderID := c.Param("id")
@@ -1526,121 +2288,86 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "自定义prompt已更新"})
 }
 
-// handleSyncBalance 同步交易所余额到initial_balance（选项B：手动同步 + 选项C：智能检测）
-func (s *Server) handleSyncBalance(c *gin.Context) {
-	userID := c.GetString("user_id")
-	traderID := c.Param("id")
-
-	// 确保用户的交易员已加载到内存中（修复 404 问题）
-	err := s.traderManager.LoadUserTraders(s.database, userID)
-	if err != nil {
-		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
-	}
-
-	log.Printf("🔄 用户 %s 请求同步交易员 %s 的余额", userID, traderID)
+// SyncTraderInitialBalance 将交易员的 initial_balance 重置为交易所当前总资产
+// 交易员正在执行交易周期时返回 trader.ErrCycleInProgress，避免在开平仓过程中重置盈亏基准
+func (s *Server) SyncTraderInitialBalance(userID, traderID string) error {
+	_, _, err := s.syncTraderInitialBalance(userID, traderID)
+	return err
+}
 
-	// 从数据库获取交易员配置（包含交易所信息）
+func (s *Server) syncTraderInitialBalance(userID, traderID string) (oldBalance, newBalance float64, err error) {
 	traderConfig, _, exchangeCfg, err := s.database.GetTraderConfig(userID, traderID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
-		return
+		return 0, 0, fmt.Errorf("交易员不存在: %w", err)
 	}
-
 	if exchangeCfg == nil || !exchangeCfg.Enabled {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "交易所未配置或未启用"})
-		return
-	}
-
-	// 创建临时 trader 查询余额
-	var tempTrader trader.Trader
-	var createErr error
-
-	switch exchangeCfg.ExchangeID {
-	case "binance":
-		// 使用默认订单策略（查询余额不需要实际下单）
-		tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID, "market_only", -0.03, 60)
-	case "hyperliquid":
-		tempTrader, createErr = trader.NewHyperliquidTrader(
-			exchangeCfg.APIKey,
-			exchangeCfg.HyperliquidWalletAddr,
-			exchangeCfg.Testnet,
-		)
-	case "aster":
-		tempTrader, createErr = trader.NewAsterTrader(
-			exchangeCfg.AsterUser,
-			exchangeCfg.AsterSigner,
-			exchangeCfg.AsterPrivateKey,
-		)
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的交易所类型"})
-		return
+		return 0, 0, fmt.Errorf("交易所未配置或未启用")
 	}
+	oldBalance = traderConfig.InitialBalance
 
-	if createErr != nil {
-		log.Printf("⚠️ 创建临时 trader 失败: %v", createErr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("连接交易所失败: %v", createErr)})
-		return
+	doSync := func() error {
+		balance, err := s.queryExchangeBalance(userID, exchangeCfg.ExchangeID, exchangeCfg)
+		if err != nil {
+			return err
+		}
+		if balance <= 0 {
+			return fmt.Errorf("无法获取总资产余额")
+		}
+		if err := s.database.UpdateTraderInitialBalance(userID, traderID, balance); err != nil {
+			return fmt.Errorf("更新initial_balance失败: %w", err)
+		}
+		newBalance = balance
+		return nil
 	}
 
-	// 查询实际余额
-	balanceInfo, balanceErr := tempTrader.GetBalance()
-	if balanceErr != nil {
-		log.Printf("⚠️ 查询交易所余额失败: %v", balanceErr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("查询余额失败: %v", balanceErr)})
-		return
+	// 已加载到内存的交易员需在周期空闲时同步，并刷新内存中的基准
+	if at, getErr := s.traderManager.GetTrader(traderID); getErr == nil {
+		err = at.WithCycleIdle(func() error {
+			if err := doSync(); err != nil {
+				return err
+			}
+			at.SetInitialBalance(newBalance)
+			return nil
+		})
+	} else {
+		err = doSync()
 	}
+	return oldBalance, newBalance, err
+}
 
-	// ✅ 使用总资产（total equity）而不是可用余额
-	// 总资产 = 钱包余额 + 未实现盈亏，这样才能正确计算总盈亏
-	var actualBalance float64
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
+// handleSyncBalance 同步交易所余额到initial_balance（选项B：手动同步 + 选项C：智能检测）
+func (s *Server) handleSyncBalance(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
 
-	if wallet, ok := balanceInfo["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
-	}
-	if unrealized, ok := balanceInfo["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
+	// 确保用户的交易员已加载到内存中（修复 404 问题）
+	if err := s.traderManager.LoadUserTraders(s.database, userID); err != nil {
+		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
 	}
 
-	// 总资产 = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	log.Printf("🔄 用户 %s 请求同步交易员 %s 的余额", userID, traderID)
 
-	if totalEquity > 0 {
-		actualBalance = totalEquity
-		log.Printf("✓ 查询到交易所总资产余额: %.2f USDT (钱包: %.2f + 未实现: %.2f)",
-			actualBalance, totalWalletBalance, totalUnrealizedProfit)
-	} else {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法获取总资产余额"})
+	oldBalance, actualBalance, err := s.syncTraderInitialBalance(userID, traderID)
+	if errors.Is(err, trader.ErrCycleInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ 同步余额失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("同步余额失败: %v", err)})
 		return
 	}
-
-	oldBalance := traderConfig.InitialBalance
 
 	// ✅ 选项C：智能检测余额变化
-	changePercent := ((actualBalance - oldBalance) / oldBalance) * 100
+	changePercent := 0.0
+	if oldBalance > 0 {
+		changePercent = ((actualBalance - oldBalance) / oldBalance) * 100
+	}
 	changeType := "增加"
 	if changePercent < 0 {
 		changeType = "减少"
 	}
 
-	log.Printf("✓ 查询到交易所实际余额: %.2f USDT (当前配置: %.2f USDT, 变化: %.2f%%)",
-		actualBalance, oldBalance, changePercent)
-
-	// 更新数据库中的 initial_balance
-	err = s.database.UpdateTraderInitialBalance(userID, traderID, actualBalance)
-	if err != nil {
-		log.Printf("❌ 更新initial_balance失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新余额失败"})
-		return
-	}
-
-	// 重新加载交易员到内存
-	err = s.traderManager.LoadTraderByID(s.database, userID, traderID)
-	if err != nil {
-		log.Printf("⚠️ 重新加载交易员到内存失败: %v", err)
-	}
-
 	log.Printf("✅ 已同步余额: %.2f → %.2f USDT (%s %.2f%%)", oldBalance, actualBalance, changeType, changePercent)
 
 	c.JSON(http.StatusOK, gin.H{
@@ -1666,55 +2393,27 @@ func (s *Server) handleGetModelConfigs(c *gin.Context) {
 
 	// 转换为安全的响应结构，移除敏感信息
 	safeModels := make([]SafeModelConfig, len(models))
-	for i, model := range models {
-		safeModels[i] = SafeModelConfig{
-			ID:              model.ModelID, // 返回 model_id（例如 "deepseek"）而不是自增 ID
-			Name:            model.Name,
-			Provider:        model.Provider,
-			Enabled:         model.Enabled,
-			CustomAPIURL:    model.CustomAPIURL,
-			CustomModelName: model.CustomModelName,
-		}
-	}
-
-	c.JSON(http.StatusOK, safeModels)
-}
-
-// handleUpdateModelConfigs 更新AI模型配置（仅支持加密数据）
-func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
-	userID := c.GetString("user_id")
-
-	// 读取原始请求体
-	bodyBytes, err := c.GetRawData()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
-		return
-	}
-
-	// 解析加密的 payload
-	var encryptedPayload crypto.EncryptedPayload
-	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err != nil {
-		log.Printf("❌ 解析加密载荷失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误，必须使用加密传输"})
-		return
-	}
-
-	// 验证是否为加密数据
-	if encryptedPayload.WrappedKey == "" {
-		log.Printf("❌ 检测到非加密请求 (UserID: %s)", userID)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "此接口仅支持加密传输，请使用加密客户端",
-			"code":    "ENCRYPTION_REQUIRED",
-			"message": "Encrypted transmission is required for security reasons",
-		})
-		return
+	for i, model := range models {
+		safeModels[i] = SafeModelConfig{
+			ID:                model.ModelID, // 返回 model_id（例如 "deepseek"）而不是自增 ID
+			Name:              model.Name,
+			Provider:          model.Provider,
+			Enabled:           model.Enabled,
+			CustomAPIURL:      model.CustomAPIURL,
+			CustomModelName:   model.CustomModelName,
+			RequestsPerMinute: model.RequestsPerMinute,
+		}
 	}
 
-	// 解密数据
-	decrypted, err := s.cryptoHandler.cryptoService.DecryptSensitiveData(&encryptedPayload)
-	if err != nil {
-		log.Printf("❌ 解密模型配置失败 (UserID: %s): %v", userID, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "解密数据失败"})
+	c.JSON(http.StatusOK, safeModels)
+}
+
+// handleUpdateModelConfigs 更新AI模型配置（仅支持加密数据）
+func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	decrypted, ok := s.decryptRequestBody(c, userID, "模型配置")
+	if !ok {
 		return
 	}
 
@@ -1725,7 +2424,6 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
 		return
 	}
-	log.Printf("🔓 已解密模型配置数据 (UserID: %s)", userID)
 
 	// 更新每个模型的配置
 	for modelID, modelData := range req.Models {
@@ -1734,10 +2432,24 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
 			return
 		}
+		if modelData.RequestsPerMinute != nil {
+			if err := s.database.SetAIModelRequestsPerMinute(userID, modelID, *modelData.RequestsPerMinute); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("更新模型 %s 每分钟请求上限失败: %v", modelID, err)})
+				return
+			}
+		}
+	}
+	for modelID, headers := range req.CustomHeaders {
+		if err := s.database.SetAIModelCustomHeaders(userID, modelID, headers); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("更新模型 %s 的自定义请求头失败: %v", modelID, err)})
+			return
+		}
+		// 请求头的值可能包含密钥，日志只记录数量
+		log.Printf("✓ 模型 %s 已设置 %d 个自定义请求头", modelID, len(headers))
 	}
 
 	// 重新加载该用户的所有交易员，使新配置立即生效
-	err = s.traderManager.LoadUserTraders(s.database, userID)
+	err := s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
 		log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
 		// 这里不返回错误，因为模型配置已经成功更新到数据库
@@ -1747,11 +2459,57 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "模型配置已更新"})
 }
 
+// setEnabledRequest 批量启用/禁用请求
+type setEnabledRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// handleSetProviderEnabled 批量启用/禁用当前用户某AI提供商的全部模型配置（提供商故障时快速切换）
+func (s *Server) handleSetProviderEnabled(c *gin.Context) {
+	var req setEnabledRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: 需要 enabled 字段"})
+		return
+	}
+	provider := c.Param("provider")
+	affected, err := s.database.SetProviderEnabled(c.GetString("user_id"), provider, *req.Enabled)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🔁 用户 %s 批量%s AI提供商 %s 的模型配置: %d 个", c.GetString("user_id"), enabledVerb(*req.Enabled), provider, affected)
+	c.JSON(http.StatusOK, gin.H{"provider": provider, "enabled": *req.Enabled, "affected": affected})
+}
+
+// handleSetExchangeTypeEnabled 批量启用/禁用当前用户某交易所类型的全部配置
+func (s *Server) handleSetExchangeTypeEnabled(c *gin.Context) {
+	var req setEnabledRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: 需要 enabled 字段"})
+		return
+	}
+	exchangeType := c.Param("type")
+	affected, err := s.database.SetExchangeTypeEnabled(c.GetString("user_id"), exchangeType, *req.Enabled)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🔁 用户 %s 批量%s交易所 %s 的配置: %d 个", c.GetString("user_id"), enabledVerb(*req.Enabled), exchangeType, affected)
+	c.JSON(http.StatusOK, gin.H{"exchange_type": exchangeType, "enabled": *req.Enabled, "affected": affected})
+}
+
+func enabledVerb(enabled bool) string {
+	if enabled {
+		return "启用"
+	}
+	return "禁用"
+}
+
 // handleGetExchangeConfigs 获取交易所配置
 func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
 	log.Printf("🔍 查询用户 %s 的交易所配置", userID)
-	exchanges, err := s.database.GetExchanges(userID)
+	exchanges, err := s.database.GetExchangesMetadata(userID)
 	if err != nil {
 		log.Printf("❌ 获取交易所配置失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易所配置失败: %v", err)})
@@ -1777,15 +2535,184 @@ func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 	c.JSON(http.StatusOK, safeExchanges)
 }
 
-// handleUpdateExchangeConfigs 更新交易所配置（仅支持加密数据）
-func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
+// TestExchangeConnection 使用已保存（解密后）的密钥测试交易所连接
+// 仅执行一次需要鉴权的余额查询，不会下单
+func (s *Server) TestExchangeConnection(userID string, exchangeID int) error {
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		return fmt.Errorf("获取交易所配置失败: %w", err)
+	}
+
+	var exchangeCfg *config.ExchangeConfig
+	for _, ex := range exchanges {
+		if ex.ID == exchangeID {
+			exchangeCfg = ex
+			break
+		}
+	}
+	if exchangeCfg == nil {
+		return fmt.Errorf("交易所配置不存在: %d", exchangeID)
+	}
+
+	switch exchangeCfg.ExchangeID {
+	case "binance":
+		if exchangeCfg.APIKey == "" || exchangeCfg.SecretKey == "" {
+			return fmt.Errorf("未配置 API Key 或 Secret Key")
+		}
+	case "hyperliquid":
+		if exchangeCfg.APIKey == "" {
+			return fmt.Errorf("未配置私钥")
+		}
+	case "aster":
+		if exchangeCfg.AsterUser == "" || exchangeCfg.AsterSigner == "" || exchangeCfg.AsterPrivateKey == "" {
+			return fmt.Errorf("未配置 Aster 用户地址、签名地址或私钥")
+		}
+	}
+
+	if _, err := s.queryExchangeBalance(userID, exchangeCfg.ExchangeID, exchangeCfg); err != nil {
+		return fmt.Errorf("连接测试失败: %w", err)
+	}
+	return nil
+}
+
+// handleTestExchangeConnection 测试交易所连接
+func (s *Server) handleTestExchangeConnection(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exchangeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的交易所ID"})
+		return
+	}
+
+	if err := s.TestExchangeConnection(userID, exchangeID); err != nil {
+		log.Printf("❌ 交易所 %d 连接测试失败: %v", exchangeID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	log.Printf("✅ 交易所 %d 连接测试成功", exchangeID)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "连接成功"})
+}
+
+// RotateExchangeKeysRequest 交易所密钥轮换请求（解密后的数据）
+type RotateExchangeKeysRequest struct {
+	APIKey    string `json:"api_key"`
+	SecretKey string `json:"secret_key"` // 为空时保留原 Secret Key
+}
+
+// handleRotateExchangeKeys 轮换交易所 API 密钥（仅支持加密数据）
+// 新密钥通过连接测试后才替换旧密钥，随后重新加载使用该交易所账户的交易员
+func (s *Server) handleRotateExchangeKeys(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exchangeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的交易所ID"})
+		return
+	}
+
+	decrypted, ok := s.decryptRequestBody(c, userID, "交易所密钥")
+	if !ok {
+		return
+	}
+	var req RotateExchangeKeysRequest
+	if err := json.Unmarshal([]byte(decrypted), &req); err != nil {
+		log.Printf("❌ 解析解密数据失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
+		return
+	}
+
+	if err := s.database.RotateExchangeKeys(userID, exchangeID, req.APIKey, req.SecretKey); err != nil {
+		log.Printf("❌ 交易所 %d 密钥轮换失败: %v", exchangeID, err)
+		switch {
+		case errors.Is(err, config.ErrExchangeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, config.ErrExchangeKeyRejected):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("轮换密钥失败: %v", err)})
+		}
+		return
+	}
+
+	// 运行中的交易员持有旧密钥创建的客户端，重新加载后才会使用新密钥
+	reloaded, err := s.traderManager.ReloadTradersByExchange(s.database, userID, exchangeID)
+	if err != nil {
+		log.Printf("⚠️ 重新加载交易所 %d 的交易员失败: %v", exchangeID, err)
+		// 这里不返回错误，因为新密钥已经生效
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "密钥已轮换", "reloaded_traders": reloaded})
+}
+
+// CreateExchangeAccountRequest 新增交易所账户请求（解密后的数据）
+type CreateExchangeAccountRequest struct {
+	ExchangeID            string `json:"exchange_id"`  // 交易所类型，例如 "binance"
+	DisplayName           string `json:"display_name"` // 同类型多个账户以显示名称区分
+	Enabled               bool   `json:"enabled"`
+	APIKey                string `json:"api_key"`
+	SecretKey             string `json:"secret_key"`
+	Testnet               bool   `json:"testnet"`
+	HyperliquidWalletAddr string `json:"hyperliquid_wallet_addr"`
+	AsterUser             string `json:"aster_user"`
+	AsterSigner           string `json:"aster_signer"`
+	AsterPrivateKey       string `json:"aster_private_key"`
+}
+
+// handleCreateExchangeAccount 新增交易所账户（仅支持加密数据），同一交易所类型可以有多个账户
+func (s *Server) handleCreateExchangeAccount(c *gin.Context) {
 	userID := c.GetString("user_id")
 
+	decrypted, ok := s.decryptRequestBody(c, userID, "交易所账户")
+	if !ok {
+		return
+	}
+	var req CreateExchangeAccountRequest
+	if err := json.Unmarshal([]byte(decrypted), &req); err != nil {
+		log.Printf("❌ 解析解密数据失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
+		return
+	}
+
+	// 名称和类型取自系统支持的交易所（default 用户的配置）
+	supported, err := s.database.GetExchangesMetadata("default")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取支持的交易所失败: %v", err)})
+		return
+	}
+	var template *config.ExchangeConfig
+	for _, ex := range supported {
+		if ex.ExchangeID == req.ExchangeID {
+			template = ex
+			break
+		}
+	}
+	if template == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("不支持的交易所: %s", req.ExchangeID)})
+		return
+	}
+
+	id, err := s.database.CreateExchangeAccount(userID, req.ExchangeID, req.DisplayName, template.Name, template.Type, req.Enabled,
+		req.APIKey, req.SecretKey, req.Testnet, req.HyperliquidWalletAddr, req.AsterUser, req.AsterSigner, req.AsterPrivateKey)
+	if errors.Is(err, config.ErrExchangeAccountExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建交易所账户失败: %v", err)})
+		return
+	}
+
+	log.Printf("✓ 用户 %s 新增交易所账户: %s (%s), id=%d", userID, req.ExchangeID, req.DisplayName, id)
+	c.JSON(http.StatusOK, gin.H{"id": id, "message": "交易所账户已创建"})
+}
+
+// decryptRequestBody 读取并解密客户端加密传输的请求体，失败时已写入错误响应并返回 false
+func (s *Server) decryptRequestBody(c *gin.Context, userID, resource string) (string, bool) {
 	// 读取原始请求体
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
-		return
+		return "", false
 	}
 
 	// 解析加密的 payload
@@ -1793,7 +2720,7 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err != nil {
 		log.Printf("❌ 解析加密载荷失败: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误，必须使用加密传输"})
-		return
+		return "", false
 	}
 
 	// 验证是否为加密数据
@@ -1804,14 +2731,26 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 			"code":    "ENCRYPTION_REQUIRED",
 			"message": "Encrypted transmission is required for security reasons",
 		})
-		return
+		return "", false
 	}
 
 	// 解密数据
 	decrypted, err := s.cryptoHandler.cryptoService.DecryptSensitiveData(&encryptedPayload)
 	if err != nil {
-		log.Printf("❌ 解密交易所配置失败 (UserID: %s): %v", userID, err)
+		log.Printf("❌ 解密%s失败 (UserID: %s): %v", resource, userID, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "解密数据失败"})
+		return "", false
+	}
+	log.Printf("🔓 已解密%s数据 (UserID: %s)", resource, userID)
+	return decrypted, true
+}
+
+// handleUpdateExchangeConfigs 更新交易所配置（仅支持加密数据）
+func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	decrypted, ok := s.decryptRequestBody(c, userID, "交易所配置")
+	if !ok {
 		return
 	}
 
@@ -1822,19 +2761,28 @@ func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
 		return
 	}
-	log.Printf("🔓 已解密交易所配置数据 (UserID: %s)", userID)
 
 	// 更新每个交易所的配置
 	for exchangeID, exchangeData := range req.Exchanges {
 		err := s.database.UpdateExchange(userID, exchangeID, exchangeData.Enabled, exchangeData.APIKey, exchangeData.SecretKey, exchangeData.Testnet, exchangeData.HyperliquidWalletAddr, exchangeData.AsterUser, exchangeData.AsterSigner, exchangeData.AsterPrivateKey)
+		if errors.Is(err, config.ErrAmbiguousExchange) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("更新交易所 %s 失败: %v", exchangeID, err)})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所 %s 失败: %v", exchangeID, err)})
 			return
 		}
+		if exchangeData.AccountMode != "" {
+			if err := s.database.SetExchangeAccountMode(userID, exchangeID, exchangeData.AccountMode); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("更新交易所 %s 账户类型失败: %v", exchangeID, err)})
+				return
+			}
+		}
 	}
 
 	// 重新加载该用户的所有交易员，使新配置立即生效
-	err = s.traderManager.LoadUserTraders(s.database, userID)
+	err := s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
 		log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
 		// 这里不返回错误，因为交易所配置已经成功更新到数据库
@@ -1889,7 +2837,32 @@ func (s *Server) handleSaveUserSignalSource(c *gin.Context) {
 // handleTraderList trader列表
 func (s *Server) handleTraderList(c *gin.Context) {
 	userID := c.GetString("user_id")
-	traders, err := s.database.GetTraders(userID)
+
+	// 可选的过滤/排序参数：?running=true&name=btc&sort=name&order=desc
+	var traders []*config.TraderRecord
+	var err error
+	if c.Query("running") == "" && c.Query("name") == "" && c.Query("sort") == "" {
+		traders, err = s.database.GetTraders(userID)
+	} else {
+		opts := config.TraderQueryOptions{
+			NameContains: c.Query("name"),
+			SortBy:       c.Query("sort"),
+			SortDesc:     strings.EqualFold(c.Query("order"), "desc"),
+		}
+		if runningStr := c.Query("running"); runningStr != "" {
+			running, parseErr := strconv.ParseBool(runningStr)
+			if parseErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "running 参数必须为 true 或 false"})
+				return
+			}
+			opts.IsRunning = &running
+		}
+		traders, err = s.database.GetTradersFiltered(userID, opts)
+		if errors.Is(err, config.ErrInvalidTraderSort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员列表失败: %v", err)})
 		return
@@ -1902,7 +2875,7 @@ func (s *Server) handleTraderList(c *gin.Context) {
 		return
 	}
 
-	exchanges, err := s.database.GetExchanges(userID)
+	exchanges, err := s.database.GetExchangesMetadata(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
 		return
@@ -1952,6 +2925,7 @@ func (s *Server) handleTraderList(c *gin.Context) {
 			"initial_balance":        trader.InitialBalance,
 			"system_prompt_template": trader.SystemPromptTemplate,
 			"scan_interval_minutes":  trader.ScanIntervalMinutes,
+			"scan_interval_seconds":  int(trader.ScanInterval() / time.Second),
 			"btc_eth_leverage":       trader.BTCETHLeverage,
 			"altcoin_leverage":       trader.AltcoinLeverage,
 			"trading_symbols":        trader.TradingSymbols,
@@ -1963,9 +2937,24 @@ func (s *Server) handleTraderList(c *gin.Context) {
 			"taker_fee_rate":         trader.TakerFeeRate,
 			"maker_fee_rate":         trader.MakerFeeRate,
 			"order_strategy":         trader.OrderStrategy,
+			"btc_eth_order_strategy": trader.BTCETHOrderStrategy,
+			"altcoin_order_strategy": trader.AltcoinOrderStrategy,
 			"limit_price_offset":     trader.LimitPriceOffset,
 			"limit_timeout_seconds":  trader.LimitTimeoutSeconds,
 			"timeframes":             trader.Timeframes,
+			"stop_reason":            trader.StopReason,
+			"fallback_ai_model_ids":  trader.FallbackModelIDs(),
+			"loss_streak_threshold":  trader.LossStreakThreshold,
+			"cooldown_minutes":       trader.CooldownMinutes,
+			"max_orders_per_cycle":   trader.MaxOrdersPerCycle,
+
+			"trailing_stop_percent":       trader.TrailingStopPercent,
+			"trailing_activation_percent": trader.TrailingActivation,
+			"candle_lookback":             trader.CandleLookbackMap(),
+			"symbol_weights":              trader.SymbolWeightsMap(),
+			"tp_percent":                  trader.TakeProfitPercent,
+			"sl_percent":                  trader.StopLossPercent,
+			"timezone":                    trader.Timezone,
 		})
 	}
 
@@ -2017,6 +3006,7 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 		"exchange_id":            exchangeID,
 		"initial_balance":        traderConfig.InitialBalance,
 		"scan_interval_minutes":  traderConfig.ScanIntervalMinutes,
+		"scan_interval_seconds":  int(traderConfig.ScanInterval() / time.Second),
 		"btc_eth_leverage":       traderConfig.BTCETHLeverage,
 		"altcoin_leverage":       traderConfig.AltcoinLeverage,
 		"trading_symbols":        traderConfig.TradingSymbols,
@@ -2030,9 +3020,24 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 		"taker_fee_rate":         traderConfig.TakerFeeRate,
 		"maker_fee_rate":         traderConfig.MakerFeeRate,
 		"order_strategy":         traderConfig.OrderStrategy,
+		"btc_eth_order_strategy": traderConfig.BTCETHOrderStrategy,
+		"altcoin_order_strategy": traderConfig.AltcoinOrderStrategy,
 		"limit_price_offset":     traderConfig.LimitPriceOffset,
 		"limit_timeout_seconds":  traderConfig.LimitTimeoutSeconds,
 		"timeframes":             traderConfig.Timeframes,
+		"stop_reason":            traderConfig.StopReason,
+		"fallback_ai_model_ids":  traderConfig.FallbackModelIDs(),
+		"loss_streak_threshold":  traderConfig.LossStreakThreshold,
+		"cooldown_minutes":       traderConfig.CooldownMinutes,
+		"max_orders_per_cycle":   traderConfig.MaxOrdersPerCycle,
+
+		"trailing_stop_percent":       traderConfig.TrailingStopPercent,
+		"trailing_activation_percent": traderConfig.TrailingActivation,
+		"candle_lookback":             traderConfig.CandleLookbackMap(),
+		"symbol_weights":              traderConfig.SymbolWeightsMap(),
+		"tp_percent":                  traderConfig.TakeProfitPercent,
+		"sl_percent":                  traderConfig.StopLossPercent,
+		"timezone":                    traderConfig.Timezone,
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -2377,6 +3382,13 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 已禁用的用户即使持有有效 token 也拒绝访问
+		if s.isUserDisabled(claims.UserID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": errUserDisabled})
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
@@ -2384,6 +3396,55 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 	}
 }
 
+// adminMiddleware 管理员权限中间件（挂在 authMiddleware 之后），非管理员返回 403
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.isAdmin(c.GetString("user_id"), c.GetString("email")) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAdmin 系统 admin 用户或邮箱在管理员列表中的用户为管理员
+// 管理员列表优先级：环境变量 ADMIN_EMAILS > 数据库配置 admin_emails（逗号分隔）
+func (s *Server) isAdmin(userID, email string) bool {
+	if userID == "admin" {
+		return true
+	}
+	if email == "" {
+		return false
+	}
+	adminEmails := strings.TrimSpace(os.Getenv("ADMIN_EMAILS"))
+	if adminEmails == "" && s.database != nil {
+		adminEmails, _ = s.database.GetSystemConfig("admin_emails")
+	}
+	for _, adminEmail := range strings.Split(adminEmails, ",") {
+		if strings.EqualFold(strings.TrimSpace(adminEmail), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// errUserDisabled 已禁用用户登录或访问时返回的错误信息
+const errUserDisabled = "账户已被禁用，请联系管理员"
+
+// isUserDisabled 查询用户是否被禁用，查询失败时放行（避免数据库抖动导致全部请求被拒）
+func (s *Server) isUserDisabled(userID string) bool {
+	if s.database == nil {
+		return false
+	}
+	disabled, err := s.database.IsUserDisabled(userID)
+	if err != nil {
+		log.Printf("⚠️ [AUTH] 查询用户 %s 禁用状态失败: %v", userID, err)
+		return false
+	}
+	return disabled
+}
+
 // handleLogout 将当前token加入黑名单
 func (s *Server) handleLogout(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
@@ -2609,6 +3670,11 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": errUserDisabled})
+		return
+	}
+
 	// 检查OTP是否已验证
 	if !user.OTPVerified {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -2646,6 +3712,10 @@ func (s *Server) handleVerifyOTP(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
 		return
 	}
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": errUserDisabled})
+		return
+	}
 
 	// 验证OTP
 	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
@@ -2682,6 +3752,11 @@ func (s *Server) handleRefreshToken(c *gin.Context) {
 		return
 	}
 
+	if claims, err := auth.ValidateRefreshToken(req.RefreshToken); err == nil && s.isUserDisabled(claims.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errUserDisabled})
+		return
+	}
+
 	// 调用 auth.RefreshAccessToken 刷新令牌（自动进行 Token Rotation）
 	tokenPair, err := auth.RefreshAccessToken(req.RefreshToken)
 	if err != nil {
@@ -2770,7 +3845,7 @@ func (s *Server) handleGetSupportedModels(c *gin.Context) {
 // handleGetSupportedExchanges 获取系统支持的交易所列表
 func (s *Server) handleGetSupportedExchanges(c *gin.Context) {
 	// 返回系统支持的交易所（从default用户获取）
-	exchanges, err := s.database.GetExchanges("default")
+	exchanges, err := s.database.GetExchangesMetadata("default")
 	if err != nil {
 		log.Printf("❌ 获取支持的交易所失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取支持的交易所失败"})
@@ -2801,6 +3876,8 @@ func (s *Server) Start() error {
 	log.Printf("🌐 API服务器启动在 http://localhost%s", addr)
 	log.Printf("📊 API文档:")
 	log.Printf("  • GET  /api/health           - 健康检查")
+	log.Printf("  • GET  /api/health/summary   - 数据库/Redis/行情源健康汇总（ok/degraded/down）")
+	log.Printf("  • GET  /api/health/integrity - 数据库文件完整性检查（需要管理员权限）")
 	log.Printf("  • GET  /api/traders          - 公开的AI交易员排行榜前50名（无需认证）")
 	log.Printf("  • GET  /api/competition      - 公开的竞赛数据（无需认证）")
 	log.Printf("  • GET  /api/top-traders      - 前5名交易员数据（无需认证，表现对比用）")
@@ -2811,10 +3888,36 @@ func (s *Server) Start() error {
 	log.Printf("  • DELETE /api/traders/:id    - 删除AI交易员")
 	log.Printf("  • POST /api/traders/:id/start - 启动AI交易员")
 	log.Printf("  • POST /api/traders/:id/stop  - 停止AI交易员")
+	log.Printf("  • GET  /api/traders/duplicates - 查找重复交易员")
+	log.Printf("  • POST /api/traders/:id/merge - 合并重复交易员到该交易员")
+	log.Printf("  • GET  /api/traders/:id/stats?since=RFC3339 - 交易员胜率/盈亏统计")
+	log.Printf("  • GET  /api/traders/:id/daily-pnl?since=RFC3339 - 交易员每日盈亏（按交易员时区，默认 UTC）")
+	log.Printf("  • GET  /api/traders/:id/fees?since=RFC3339 - 交易员 Maker/Taker 手续费拆分")
+	log.Printf("  • GET  /api/traders/:id/pnl-breakdown - 交易员已实现/未实现盈亏拆分")
+	log.Printf("  • GET  /api/traders/:id/drawdown - 交易员当前/最大回撤")
+	log.Printf("  • GET  /api/traders/:id/balance-history?since=&until= - 交易所余额快照（对账）")
+	log.Printf("  • GET  /api/traders/:id/logs?n=100 - 交易员最近的周期日志")
+	log.Printf("  • GET  /api/traders/:id/effective-prompt - 预览交易员实际生效的系统提示词")
+	log.Printf("  • GET  /api/audit-log - 按实体导出审计日志（?entity_type=&entity_id=&since=&until=&format=json|csv）")
+	log.Printf("  • GET  /api/exposure - 各币种跨交易员的合计净敞口")
+	log.Printf("  • GET  /api/user-prompt-templates - 用户提示词模板（POST创建，PUT/DELETE /:name 更新/删除）")
+	log.Printf("  • POST /api/traders/:id/sync-balance - 将初始余额同步为交易所当前总资产")
+	log.Printf("  • GET  /api/traders/:id/decisions/current - 各币种最新决策")
+	log.Printf("  • POST /api/traders/:id/decisions/:decision_id/replay - 用当前AI配置重放历史决策（不下单）")
+	log.Printf("  • GET  /api/traders/:id/config-history?from=&to= - 交易员配置变更历史/对比")
+	log.Printf("  • POST /api/webhook          - 外部告警触发交易员立即运行一个周期（action=stop 时停止交易员）")
+	log.Printf("  • POST /api/webhook/:traderID - 按路径指定交易员的外部告警")
+	log.Printf("  • GET  /api/webhook-failures - webhook 失败记录")
+	log.Printf("  • POST /api/webhook-failures/:id/retry - 重试失败的 webhook")
 	log.Printf("  • GET  /api/models           - 获取AI模型配置")
 	log.Printf("  • PUT  /api/models           - 更新AI模型配置")
+	log.Printf("  • PUT  /api/models/providers/:provider/enabled - 批量启用/禁用某AI提供商的模型")
 	log.Printf("  • GET  /api/exchanges        - 获取交易所配置")
 	log.Printf("  • PUT  /api/exchanges        - 更新交易所配置")
+	log.Printf("  • POST /api/exchanges        - 新增交易所账户（同类型可有多个账户）")
+	log.Printf("  • POST /api/exchanges/:id/test - 测试交易所API连接（不下单）")
+	log.Printf("  • POST /api/exchanges/:id/rotate-keys - 轮换交易所API密钥（连接测试通过后替换并重新加载交易员）")
+	log.Printf("  • PUT  /api/exchanges/types/:type/enabled - 批量启用/禁用某类型交易所配置")
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
@@ -3206,3 +4309,86 @@ func (s *Server) handleReloadPromptTemplates(c *gin.Context) {
 		"count":   len(templates),
 	})
 }
+
+// handleGetUserPromptTemplates 获取当前用户的数据库提示词模板
+func (s *Server) handleGetUserPromptTemplates(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	templates, err := s.database.GetPromptTemplates(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取提示词模板失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// handleCreateUserPromptTemplate 创建用户提示词模板（内容支持 {{变量}} 占位符）
+func (s *Server) handleCreateUserPromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		Name string `json:"name" binding:"required"`
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	if existing, err := s.database.GetPromptTemplateByName(userID, req.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建模板失败: %v", err)})
+		return
+	} else if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("模板已存在: %s", req.Name)})
+		return
+	}
+
+	tpl := &config.PromptTemplate{UserID: userID, Name: req.Name, Body: req.Body}
+	if err := s.database.CreatePromptTemplate(tpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("创建模板失败: %v", err)})
+		return
+	}
+
+	log.Printf("✓ 用户 %s 创建提示词模板: %s", userID, tpl.Name)
+	c.JSON(http.StatusOK, gin.H{"success": true, "id": tpl.ID, "name": tpl.Name})
+}
+
+// handleUpdateUserPromptTemplate 更新用户提示词模板，引用它的交易员在下个周期生效
+func (s *Server) handleUpdateUserPromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	name := c.Param("name")
+
+	var req struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	if err := s.database.UpdatePromptTemplate(userID, name, req.Body); err != nil {
+		if errors.Is(err, config.ErrPromptTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("更新模板失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "name": name})
+}
+
+// handleDeleteUserPromptTemplate 删除用户提示词模板（仍被交易员引用时拒绝）
+func (s *Server) handleDeleteUserPromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	name := c.Param("name")
+
+	if err := s.database.DeletePromptTemplate(userID, name); err != nil {
+		if errors.Is(err, config.ErrPromptTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -21,15 +21,21 @@ func SanitizeModelConfigForLog(models map[string]struct {
 	APIKey          string `json:"api_key"`
 	CustomAPIURL    string `json:"custom_api_url"`
 	CustomModelName string `json:"custom_model_name"`
+	// RequestsPerMinute 该模型每分钟请求上限（0表示使用全局 ai_model_rpm），未传入时保持不变
+	RequestsPerMinute *int `json:"requests_per_minute,omitempty"`
 }) map[string]interface{} {
 	safe := make(map[string]interface{})
 	for modelID, cfg := range models {
-		safe[modelID] = map[string]interface{}{
+		safeModel := map[string]interface{}{
 			"enabled":           cfg.Enabled,
 			"api_key":           MaskSensitiveString(cfg.APIKey),
 			"custom_api_url":    cfg.CustomAPIURL,
 			"custom_model_name": cfg.CustomModelName,
 		}
+		if cfg.RequestsPerMinute != nil {
+			safeModel["requests_per_minute"] = *cfg.RequestsPerMinute
+		}
+		safe[modelID] = safeModel
 	}
 	return safe
 }
@@ -44,6 +50,7 @@ func SanitizeExchangeConfigForLog(exchanges map[string]struct {
 	AsterUser             string `json:"aster_user"`
 	AsterSigner           string `json:"aster_signer"`
 	AsterPrivateKey       string `json:"aster_private_key"`
+	AccountMode           string `json:"account_mode"`
 }) map[string]interface{} {
 	safe := make(map[string]interface{})
 	for exchangeID, cfg := range exchanges {
@@ -73,6 +80,9 @@ func SanitizeExchangeConfigForLog(exchanges map[string]struct {
 		if cfg.AsterSigner != "" {
 			safeExchange["aster_signer"] = cfg.AsterSigner
 		}
+		if cfg.AccountMode != "" {
+			safeExchange["account_mode"] = cfg.AccountMode
+		}
 
 		safe[exchangeID] = safeExchange
 	}
@@ -49,10 +49,11 @@ func TestMaskSensitiveString(t *testing.T) {
 
 func TestSanitizeModelConfigForLog(t *testing.T) {
 	models := map[string]struct {
-		Enabled         bool   `json:"enabled"`
-		APIKey          string `json:"api_key"`
-		CustomAPIURL    string `json:"custom_api_url"`
-		CustomModelName string `json:"custom_model_name"`
+		Enabled           bool   `json:"enabled"`
+		APIKey            string `json:"api_key"`
+		CustomAPIURL      string `json:"custom_api_url"`
+		CustomModelName   string `json:"custom_model_name"`
+		RequestsPerMinute *int   `json:"requests_per_minute,omitempty"`
 	}{
 		"deepseek": {
 			Enabled:         true,
@@ -97,6 +98,7 @@ func TestSanitizeExchangeConfigForLog(t *testing.T) {
 		AsterUser             string `json:"aster_user"`
 		AsterSigner           string `json:"aster_signer"`
 		AsterPrivateKey       string `json:"aster_private_key"`
+		AccountMode           string `json:"account_mode"`
 	}{
 		"binance": {
 			Enabled:   true,
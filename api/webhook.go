@@ -0,0 +1,442 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/notify"
+	"nofx/trader"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBodyBytes webhook 请求体上限
+const maxWebhookBodyBytes = 64 * 1024
+
+// webhookSignatureHeader 请求签名头：hex(HMAC-SHA256(WEBHOOK_SECRET, body))
+const webhookSignatureHeader = "X-Webhook-Signature"
+
+// webhook 动作
+const (
+	webhookActionCycle = "cycle" // 触发交易员立即运行一个周期（默认）
+	webhookActionStop  = "stop"  // 停止交易员，用于熔断类告警
+)
+
+// webhookResponseSignatureHeader 响应签名头：hex(HMAC-SHA256(WEBHOOK_SECRET, 响应体))，仅在配置密钥时返回
+const webhookResponseSignatureHeader = "X-Webhook-Response-Signature"
+
+// WebhookContent webhook 告警内容
+// 支持 JSON 或按空白分隔的位置格式：
+// <trader_id> <type> <symbol> <interval> <open> <high> <low> <close> <volume> [content...]
+// 通过 /webhook/:traderID 调用时交易员由路径指定，位置格式省略 <trader_id>
+// 指标值（如 RSI、MACD）只能通过 JSON 的 indicators 字段传入，位置格式不支持
+// action 只能通过 JSON 传入：为空或 "cycle" 时运行一个周期，"stop" 时停止交易员（此时 type 可省略）
+// side 只能通过 JSON 传入（long/short，也接受 buy/sell）：配置了加仓窗口时，窗口内的重复同向告警按加仓处理
+type WebhookContent struct {
+	TraderID string  `json:"trader_id"`
+	Action   string  `json:"action,omitempty"`
+	Type     string  `json:"type"`
+	Symbol   string  `json:"symbol"`
+	Side     string  `json:"side,omitempty"`
+	Interval string  `json:"interval"`
+	Open     float64 `json:"open"`
+	High     float64 `json:"high"`
+	Low      float64 `json:"low"`
+	Close    float64 `json:"close"`
+	Volume   float64 `json:"volume"`
+	Content  string  `json:"content"`
+
+	Indicators map[string]float64 `json:"indicators,omitempty"` // 告警计算的指标值，模板中以 ${RSI} 等形式引用
+}
+
+// parseWebhookPayload 解析 webhook 请求体
+// pathTraderID 非空时表示交易员来自 URL 路径：位置格式不再需要 trader_id 字段
+// （为兼容旧告警，首字段等于路径中的交易员ID时仍会被跳过），JSON 中的 trader_id 必须为空或与路径一致
+func parseWebhookPayload(body []byte, pathTraderID string) (*WebhookContent, error) {
+	text := strings.TrimSpace(string(body))
+	if text == "" {
+		return nil, fmt.Errorf("请求体为空")
+	}
+
+	var wc WebhookContent
+	if strings.HasPrefix(text, "{") {
+		if err := json.Unmarshal([]byte(text), &wc); err != nil {
+			return nil, fmt.Errorf("解析JSON失败: %w", err)
+		}
+		if pathTraderID != "" {
+			if wc.TraderID != "" && wc.TraderID != pathTraderID {
+				return nil, fmt.Errorf("trader_id 与路径不一致: %s", wc.TraderID)
+			}
+			wc.TraderID = pathTraderID
+		}
+	} else {
+		fields := strings.Fields(text)
+		if pathTraderID == "" {
+			if len(fields) < 9 {
+				return nil, fmt.Errorf("字段不足: 需要 trader_id type symbol interval open high low close volume")
+			}
+			wc.TraderID, fields = fields[0], fields[1:]
+		} else {
+			if len(fields) > 0 && fields[0] == pathTraderID {
+				fields = fields[1:]
+			}
+			if len(fields) < 8 {
+				return nil, fmt.Errorf("字段不足: 需要 type symbol interval open high low close volume")
+			}
+			wc.TraderID = pathTraderID
+		}
+		wc.Type, wc.Symbol, wc.Interval = fields[0], fields[1], fields[2]
+		for i, dst := range []*float64{&wc.Open, &wc.High, &wc.Low, &wc.Close, &wc.Volume} {
+			v, err := strconv.ParseFloat(fields[3+i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s 字段不是有效数字: %s", []string{"open", "high", "low", "close", "volume"}[i], fields[3+i])
+			}
+			*dst = v
+		}
+		wc.Content = strings.Join(fields[8:], " ")
+	}
+
+	switch wc.Action = strings.ToLower(strings.TrimSpace(wc.Action)); wc.Action {
+	case "", webhookActionCycle:
+		wc.Action = webhookActionCycle
+	case webhookActionStop:
+		if wc.TraderID == "" {
+			return nil, fmt.Errorf("缺少 trader_id")
+		}
+		return &wc, nil
+	default:
+		return nil, fmt.Errorf("不支持的 action: %s", wc.Action)
+	}
+
+	if wc.TraderID == "" || wc.Type == "" {
+		return nil, fmt.Errorf("缺少 trader_id 或 type")
+	}
+
+	switch wc.Side = strings.ToLower(strings.TrimSpace(wc.Side)); wc.Side {
+	case "", "long", "short":
+	case "buy":
+		wc.Side = "long"
+	case "sell":
+		wc.Side = "short"
+	default:
+		return nil, fmt.Errorf("不支持的 side: %s", wc.Side)
+	}
+	if wc.Side != "" && wc.Symbol == "" {
+		return nil, fmt.Errorf("指定 side 时必须提供 symbol")
+	}
+	return &wc, nil
+}
+
+// webhookTemplate 读取告警类型对应的 prompt 模板（环境变量 TYPE_<type>）
+// 未配置时依次回退到环境变量 TYPE_DEFAULT 和 system_config 的 default_webhook_prompt，fallback 表示使用了默认模板
+func (s *Server) webhookTemplate(alertType string) (tpl string, fallback bool) {
+	if tpl = os.Getenv("TYPE_" + strings.ToUpper(alertType)); tpl != "" {
+		return tpl, false
+	}
+	if tpl = os.Getenv("TYPE_DEFAULT"); tpl != "" {
+		return tpl, true
+	}
+	if s.database != nil {
+		if tpl, _ = s.database.GetSystemConfig(config.DefaultWebhookPromptKey); strings.TrimSpace(tpl) != "" {
+			return tpl, true
+		}
+	}
+	return "", false
+}
+
+// CheckEnvWebhookTemplates 启动时检查环境变量中的告警模板（TYPE_<type>），记录发现的问题并返回（变量名 -> 问题列表）
+// environ 通常为 os.Environ()；只返回存在问题的模板
+func CheckEnvWebhookTemplates(environ []string) map[string][]config.PromptTemplateIssue {
+	result := make(map[string][]config.PromptTemplateIssue)
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, "TYPE_") || len(key) == len("TYPE_") {
+			continue
+		}
+		issues := config.ValidatePromptTemplate(value)
+		if len(issues) == 0 {
+			continue
+		}
+		result[key] = issues
+		for _, issue := range issues {
+			if issue.Warning {
+				log.Printf("ℹ️ [Webhook] 模板 %s: %s", key, issue)
+			} else {
+				log.Printf("⚠️ [Webhook] 模板 %s 存在错误: %s", key, issue)
+			}
+		}
+	}
+	return result
+}
+
+// renderWebhookPrompt 替换模板中的 ${...} 占位符
+// 指标按名称替换为 ${<名称>}（区分大小写），与内置字段同名时以内置字段为准；
+// ${Indicators} 替换为全部指标（按名称排序，例如 MACD=0.5, RSI=72.3）
+func renderWebhookPrompt(tpl string, wc *WebhookContent) string {
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	names := make([]string, 0, len(wc.Indicators))
+	for name := range wc.Indicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var indicatorPairs, summary []string
+	for _, name := range names {
+		v := num(wc.Indicators[name])
+		indicatorPairs = append(indicatorPairs, "${"+name+"}", v)
+		summary = append(summary, name+"="+v)
+	}
+
+	// strings.Replacer 在同一位置按参数顺序匹配，内置字段在前，保证不被同名指标覆盖
+	pairs := []string{
+		"${TraderID}", wc.TraderID,
+		"${Type}", wc.Type,
+		"${Symbol}", wc.Symbol,
+		"${Side}", wc.Side,
+		"${Interval}", wc.Interval,
+		"${Open}", num(wc.Open),
+		"${High}", num(wc.High),
+		"${Low}", num(wc.Low),
+		"${Close}", num(wc.Close),
+		"${Volume}", num(wc.Volume),
+		"${Content}", wc.Content,
+		"${Indicators}", strings.Join(summary, ", "),
+	}
+	return strings.NewReplacer(append(pairs, indicatorPairs...)...).Replace(tpl)
+}
+
+// webhookSecret 读取签名密钥：路径指定交易员时优先使用 WEBHOOK_SECRET_<交易员ID>
+// （大写，非字母数字字符替换为下划线），未配置时回退到全局 WEBHOOK_SECRET
+func webhookSecret(pathTraderID string) string {
+	if pathTraderID != "" {
+		key := strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return '_'
+		}, strings.ToUpper(pathTraderID))
+		if secret := os.Getenv("WEBHOOK_SECRET_" + key); secret != "" {
+			return secret
+		}
+	}
+	return os.Getenv("WEBHOOK_SECRET")
+}
+
+// verifyWebhookSignature 校验请求签名；未配置密钥时一律拒绝（webhook 无需登录即可触发实盘交易周期）
+func verifyWebhookSignature(body []byte, signature, pathTraderID string) bool {
+	secret := webhookSecret(pathTraderID)
+	if secret == "" {
+		return false
+	}
+	expected := webhookSignature(secret, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(strings.TrimSpace(signature))))
+}
+
+// webhookSignature 计算 hex(HMAC-SHA256(secret, body))
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// writeWebhookResponse 返回 JSON 响应；配置了密钥时用同一密钥对响应体签名，便于告警方验证回执来源
+func writeWebhookResponse(c *gin.Context, status int, payload gin.H, pathTraderID string) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "序列化响应失败"})
+		return
+	}
+	if secret := webhookSecret(pathTraderID); secret != "" {
+		c.Header(webhookResponseSignatureHeader, webhookSignature(secret, body))
+	}
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// prepareWebhookCycle 校验告警并生成本次周期的 prompt
+func (s *Server) prepareWebhookCycle(wc *WebhookContent) (cycle func() error, userID string, status int, err error) {
+	at, err := s.traderManager.GetTrader(wc.TraderID)
+	if err != nil {
+		return nil, "", http.StatusNotFound, fmt.Errorf("交易员不存在: %s", wc.TraderID)
+	}
+	if running, ok := at.GetStatus()["is_running"].(bool); ok && !running {
+		return nil, at.GetUserID(), http.StatusConflict, fmt.Errorf("交易员未运行: %s", wc.TraderID)
+	}
+	tpl, fallback := s.webhookTemplate(wc.Type)
+	if tpl == "" {
+		return nil, at.GetUserID(), http.StatusBadRequest, fmt.Errorf("未配置告警类型模板: TYPE_%s（也未配置 TYPE_DEFAULT）", strings.ToUpper(wc.Type))
+	}
+	if fallback {
+		log.Printf("ℹ️ [Webhook] 未配置 TYPE_%s，使用默认告警模板", strings.ToUpper(wc.Type))
+	}
+	prompt := renderWebhookPrompt(tpl, wc)
+	if wc.Side != "" && at.NoteWebhookAlert(wc.Symbol, wc.Side) {
+		log.Printf("📈 [Webhook] 交易员 %s 的 %s %s 告警在加仓窗口内，按加仓处理", wc.TraderID, wc.Symbol, wc.Side)
+		prompt += trader.WebhookScaleInPrompt(wc.Symbol, wc.Side)
+	}
+	return func() error { return at.RunCycle(prompt) }, at.GetUserID(), http.StatusOK, nil
+}
+
+// stopTraderFromWebhook 按告警停止交易员：记录停止原因、写入审计日志并发送通知（调用前已校验签名）
+// 交易员已停止时视为成功（告警可能重复触发），alreadyStopped 为 true
+func (s *Server) stopTraderFromWebhook(wc *WebhookContent) (alreadyStopped bool, status int, err error) {
+	at, err := s.traderManager.GetTrader(wc.TraderID)
+	if err != nil {
+		return false, http.StatusNotFound, fmt.Errorf("交易员不存在: %s", wc.TraderID)
+	}
+	if running, ok := at.GetStatus()["is_running"].(bool); ok && !running {
+		return true, http.StatusOK, nil
+	}
+
+	at.Stop()
+
+	reason := config.StopReasonWebhook
+	if wc.Type != "" {
+		reason = fmt.Sprintf("%s: %s", config.StopReasonWebhook, wc.Type)
+	}
+	if err := s.database.UpdateTraderStatus(at.GetUserID(), wc.TraderID, false, reason); err != nil {
+		log.Printf("⚠️ [Webhook] 更新交易员 %s 状态失败: %v", wc.TraderID, err)
+	}
+	s.database.RecordAuditEvent(at.GetUserID(), config.AuditEntityTrader, wc.TraderID, "webhook_stop", strings.TrimSpace(wc.Type+" "+wc.Content))
+
+	message := fmt.Sprintf("🛑 交易员 %s 已被外部告警停止", at.GetName())
+	if wc.Type != "" {
+		message += fmt.Sprintf("（%s）", wc.Type)
+	}
+	if wc.Content != "" {
+		message += ": " + wc.Content
+	}
+	notify.NotifyLevel(notify.LevelWarn, message)
+	return false, http.StatusOK, nil
+}
+
+// handleWebhook 接收外部告警（如 TradingView）并触发交易员立即运行一个周期，或按 action=stop 停止交易员
+// 交易员优先从路径 /webhook/:traderID 读取，缺省时使用请求体首字段（旧格式）
+// JSON 请求体先按字段映射（见 mapWebhookBody）转换为 WebhookContent 字段，签名针对原始请求体、按解析出的交易员的密钥校验
+// 停止动作同步执行；周期在后台执行，失败时写入 webhook_failures 以便排查和重试
+// 必须配置 WEBHOOK_SECRET（或 WEBHOOK_SECRET_<交易员ID>）并携带签名，未配置时返回 403；响应体带 X-Webhook-Response-Signature 签名
+func (s *Server) handleWebhook(c *gin.Context) {
+	pathTraderID := c.Param("traderID")
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		writeWebhookResponse(c, http.StatusBadRequest, gin.H{"error": "读取请求体失败"}, pathTraderID)
+		return
+	}
+
+	// 先解析出交易员再校验签名：旧路由 /webhook 的交易员来自请求体，必须用该交易员的 WEBHOOK_SECRET_<ID> 校验
+	wc, err := parseWebhookPayload(s.mapWebhookBody(body, pathTraderID), pathTraderID)
+	if err != nil {
+		writeWebhookResponse(c, http.StatusBadRequest, gin.H{"error": fmt.Sprintf("解析告警失败: %v", err)}, pathTraderID)
+		return
+	}
+	traderID := wc.TraderID
+	if webhookSecret(traderID) == "" {
+		writeWebhookResponse(c, http.StatusForbidden, gin.H{"error": "未配置 WEBHOOK_SECRET，webhook 已禁用"}, traderID)
+		return
+	}
+	if !verifyWebhookSignature(body, c.GetHeader(webhookSignatureHeader), traderID) {
+		writeWebhookResponse(c, http.StatusUnauthorized, gin.H{"error": "签名无效"}, traderID)
+		return
+	}
+
+	if wc.Action == webhookActionStop {
+		alreadyStopped, status, err := s.stopTraderFromWebhook(wc)
+		if err != nil {
+			writeWebhookResponse(c, status, gin.H{"error": err.Error()}, traderID)
+			return
+		}
+		log.Printf("🛑 [Webhook] 交易员 %s 收到停止告警 (%s)", wc.TraderID, wc.Type)
+		writeWebhookResponse(c, status, gin.H{"message": "交易员已停止", "trader_id": wc.TraderID, "already_stopped": alreadyStopped}, traderID)
+		return
+	}
+
+	cycle, userID, status, err := s.prepareWebhookCycle(wc)
+	if err != nil {
+		writeWebhookResponse(c, status, gin.H{"error": err.Error()}, traderID)
+		return
+	}
+
+	go func() {
+		if err := cycle(); err != nil {
+			log.Printf("❌ [Webhook] 交易员 %s 周期执行失败: %v", wc.TraderID, err)
+			failure := &config.WebhookFailure{TraderID: wc.TraderID, UserID: userID, Payload: string(body), Error: err.Error()}
+			if recordErr := s.database.RecordWebhookFailure(failure); recordErr != nil {
+				log.Printf("⚠️ [Webhook] 记录失败告警出错: %v", recordErr)
+			}
+		}
+	}()
+
+	log.Printf("📨 [Webhook] 交易员 %s 收到 %s 告警 (%s %s)", wc.TraderID, wc.Type, wc.Symbol, wc.Interval)
+	writeWebhookResponse(c, http.StatusAccepted, gin.H{"message": "已接收", "trader_id": wc.TraderID}, traderID)
+}
+
+// RetryWebhookFailure 同步重放一条失败的 webhook 记录
+// 成功后删除该记录；仍失败时更新错误信息并累加重试次数
+func (s *Server) RetryWebhookFailure(userID string, id int64) error {
+	failure, err := s.database.GetWebhookFailure(userID, id)
+	if err != nil {
+		return err
+	}
+	if failure == nil {
+		return fmt.Errorf("webhook失败记录不存在: %d", id)
+	}
+
+	runErr := func() error {
+		// 失败记录的交易员ID同时适用于旧格式（首字段即该ID）和路径格式的请求体
+		wc, err := parseWebhookPayload(s.mapWebhookBody([]byte(failure.Payload), failure.TraderID), failure.TraderID)
+		if err != nil {
+			return err
+		}
+		cycle, _, _, err := s.prepareWebhookCycle(wc)
+		if err != nil {
+			return err
+		}
+		return cycle()
+	}()
+	if runErr != nil {
+		if err := s.database.MarkWebhookFailureRetried(id, runErr.Error()); err != nil {
+			log.Printf("⚠️ [Webhook] 更新失败记录出错: %v", err)
+		}
+		return runErr
+	}
+	return s.database.ResolveWebhookFailure(id)
+}
+
+// handleGetWebhookFailures 获取当前用户的 webhook 失败记录
+func (s *Server) handleGetWebhookFailures(c *gin.Context) {
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		if l, err := strconv.Atoi(raw); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	failures, err := s.database.GetWebhookFailures(c.GetString("user_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取webhook失败记录失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, failures)
+}
+
+// handleRetryWebhookFailure 重试一条 webhook 失败记录
+func (s *Server) handleRetryWebhookFailure(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的记录ID"})
+		return
+	}
+	if err := s.RetryWebhookFailure(c.GetString("user_id"), id); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("重试失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "重试成功"})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+)
+
+// webhookFieldMappingKey system_config 中的全局字段映射（JSON 对象：{"告警字段名": "WebhookContent 字段名"}）
+// 按交易员覆盖时使用 webhook_field_mapping_<交易员ID>
+const webhookFieldMappingKey = "webhook_field_mapping"
+
+// webhookContentFields WebhookContent 的 JSON 字段名，即字段映射的合法目标
+var webhookContentFields = map[string]bool{
+	"trader_id": true, "action": true, "type": true, "symbol": true, "side": true, "interval": true,
+	"open": true, "high": true, "low": true, "close": true, "volume": true,
+	"content": true, "indicators": true,
+}
+
+// defaultWebhookFieldMapping 常见告警平台的字段别名，未配置映射时也会生效
+var defaultWebhookFieldMapping = map[string]string{
+	"ticker":     "symbol",
+	"pair":       "symbol",
+	"tf":         "interval",
+	"timeframe":  "interval",
+	"price":      "close",
+	"vol":        "volume",
+	"message":    "content",
+	"text":       "content",
+	"alert_type": "type",
+	"direction":  "side",
+}
+
+// loadWebhookFieldMapping 读取 system_config 中的字段映射并合并到 mapping，无效的配置或目标字段会被忽略
+func (s *Server) loadWebhookFieldMapping(key string, mapping map[string]string) {
+	if s.database == nil {
+		return
+	}
+	raw, _ := s.database.GetSystemConfig(key)
+	if strings.TrimSpace(raw) == "" {
+		return
+	}
+	var configured map[string]string
+	if err := json.Unmarshal([]byte(raw), &configured); err != nil {
+		log.Printf("⚠️ [Webhook] 字段映射 %s 不是有效的JSON对象，已忽略: %v", key, err)
+		return
+	}
+	for from, to := range configured {
+		if !webhookContentFields[to] {
+			log.Printf("⚠️ [Webhook] 字段映射 %s 的目标字段无效，已忽略: %s -> %s", key, from, to)
+			continue
+		}
+		mapping[from] = to
+	}
+}
+
+// applyWebhookFieldMapping 将告警 JSON 中的字段按映射重命名为 WebhookContent 字段
+// 请求体已包含目标字段时保留原值；非 JSON 对象的请求体（位置格式）原样返回
+func applyWebhookFieldMapping(body []byte, mapping map[string]string) []byte {
+	if len(mapping) == 0 || !strings.HasPrefix(strings.TrimSpace(string(body)), "{") {
+		return body
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+
+	// 按字段名排序，多个别名映射到同一字段时结果稳定
+	sources := make([]string, 0, len(mapping))
+	for from := range mapping {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+
+	changed := false
+	for _, from := range sources {
+		to := mapping[from]
+		value, ok := fields[from]
+		if !ok || from == to {
+			continue
+		}
+		if _, exists := fields[to]; exists {
+			continue
+		}
+		fields[to] = value
+		delete(fields, from)
+		changed = true
+	}
+	if !changed {
+		return body
+	}
+	mapped, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return mapped
+}
+
+// mapWebhookBody 按 默认别名 < 全局映射 < 交易员映射 的优先级合并映射后转换告警请求体
+// 交易员优先取自路径，否则取自（经全局映射后的）请求体 trader_id 字段
+func (s *Server) mapWebhookBody(body []byte, pathTraderID string) []byte {
+	mapping := make(map[string]string, len(defaultWebhookFieldMapping))
+	for from, to := range defaultWebhookFieldMapping {
+		mapping[from] = to
+	}
+	s.loadWebhookFieldMapping(webhookFieldMappingKey, mapping)
+
+	traderID := pathTraderID
+	if traderID == "" {
+		var probe struct {
+			TraderID string `json:"trader_id"`
+		}
+		if json.Unmarshal(applyWebhookFieldMapping(body, mapping), &probe) == nil {
+			traderID = probe.TraderID
+		}
+	}
+	if traderID != "" {
+		s.loadWebhookFieldMapping(webhookFieldMappingKey+"_"+traderID, mapping)
+	}
+	return applyWebhookFieldMapping(body, mapping)
+}
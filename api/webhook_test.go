@@ -0,0 +1,362 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"nofx/config"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseWebhookPayload(t *testing.T) {
+	wc, err := parseWebhookPayload([]byte("trader-1 breakout BTCUSDT 15m 100 110 95 105.5 1234 放量突破 前高"), "")
+	if err != nil {
+		t.Fatalf("parse positional payload failed: %v", err)
+	}
+	if wc.TraderID != "trader-1" || wc.Type != "breakout" || wc.Symbol != "BTCUSDT" || wc.Interval != "15m" {
+		t.Fatalf("unexpected header fields: %+v", wc)
+	}
+	if wc.Close != 105.5 || wc.Volume != 1234 || wc.Content != "放量突破 前高" {
+		t.Fatalf("unexpected values: %+v", wc)
+	}
+
+	wc, err = parseWebhookPayload([]byte(`{"trader_id":"trader-2","type":"rsi","symbol":"ETHUSDT","close":3000}`), "")
+	if err != nil {
+		t.Fatalf("parse JSON payload failed: %v", err)
+	}
+	if wc.TraderID != "trader-2" || wc.Close != 3000 {
+		t.Fatalf("unexpected JSON fields: %+v", wc)
+	}
+
+	for _, body := range []string{"", "trader-1 breakout BTCUSDT", "trader-1 breakout BTCUSDT 15m a b c d e", `{"symbol":"BTCUSDT"}`} {
+		if _, err := parseWebhookPayload([]byte(body), ""); err == nil {
+			t.Errorf("expected error for payload %q", body)
+		}
+	}
+}
+
+func TestParseWebhookPayload_PathTraderID(t *testing.T) {
+	wc, err := parseWebhookPayload([]byte("breakout BTCUSDT 15m 100 110 95 105.5 1234 突破"), "trader-9")
+	if err != nil {
+		t.Fatalf("parse path payload failed: %v", err)
+	}
+	if wc.TraderID != "trader-9" || wc.Type != "breakout" || wc.Close != 105.5 || wc.Content != "突破" {
+		t.Fatalf("unexpected fields: %+v", wc)
+	}
+
+	// 兼容旧格式：首字段与路径一致时跳过
+	wc, err = parseWebhookPayload([]byte("trader-9 breakout BTCUSDT 15m 100 110 95 105.5 1234"), "trader-9")
+	if err != nil || wc.Type != "breakout" || wc.Volume != 1234 {
+		t.Fatalf("legacy body on path route: %+v (%v)", wc, err)
+	}
+
+	wc, err = parseWebhookPayload([]byte(`{"type":"rsi","symbol":"ETHUSDT"}`), "trader-9")
+	if err != nil || wc.TraderID != "trader-9" {
+		t.Fatalf("JSON body on path route: %+v (%v)", wc, err)
+	}
+	if _, err := parseWebhookPayload([]byte(`{"trader_id":"other","type":"rsi"}`), "trader-9"); err == nil {
+		t.Fatal("expected error when JSON trader_id conflicts with path")
+	}
+}
+
+func TestParseWebhookPayload_Action(t *testing.T) {
+	wc, err := parseWebhookPayload([]byte(`{"trader_id":"trader-1","type":"rsi"}`), "")
+	if err != nil || wc.Action != webhookActionCycle {
+		t.Fatalf("missing action should default to cycle: %+v (%v)", wc, err)
+	}
+
+	// 停止动作不要求 type
+	wc, err = parseWebhookPayload([]byte(`{"action":"STOP","content":"熔断"}`), "trader-9")
+	if err != nil || wc.Action != webhookActionStop || wc.TraderID != "trader-9" {
+		t.Fatalf("stop action on path route: %+v (%v)", wc, err)
+	}
+
+	for _, body := range []string{`{"action":"stop"}`, `{"trader_id":"trader-1","type":"rsi","action":"close_all"}`} {
+		if _, err := parseWebhookPayload([]byte(body), ""); err == nil {
+			t.Errorf("expected error for payload %q", body)
+		}
+	}
+}
+
+func TestParseWebhookPayload_Side(t *testing.T) {
+	wc, err := parseWebhookPayload([]byte(`{"trader_id":"trader-1","type":"trend","symbol":"BTCUSDT","side":"Buy"}`), "")
+	if err != nil || wc.Side != "long" {
+		t.Fatalf("buy should map to long: %+v (%v)", wc, err)
+	}
+	wc, err = parseWebhookPayload([]byte(`{"type":"trend","symbol":"ETHUSDT","side":"short"}`), "trader-1")
+	if err != nil || wc.Side != "short" {
+		t.Fatalf("short side: %+v (%v)", wc, err)
+	}
+
+	for _, body := range []string{
+		`{"trader_id":"trader-1","type":"trend","symbol":"BTCUSDT","side":"flat"}`,
+		`{"trader_id":"trader-1","type":"trend","side":"long"}`,
+	} {
+		if _, err := parseWebhookPayload([]byte(body), ""); err == nil {
+			t.Errorf("expected error for payload %q", body)
+		}
+	}
+}
+
+func TestHandleWebhook_RequiresSecret(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	t.Setenv("WEBHOOK_SECRET", "")
+
+	for _, path := range []string{"/api/webhook", "/api/webhook/trader-1"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"trader_id":"trader-1","type":"rsi","symbol":"BTCUSDT"}`))
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected 403 without secret, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandleWebhook_StopUnknownTrader(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	t.Setenv("WEBHOOK_SECRET", "s3cret")
+
+	body := `{"action":"stop"}`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/webhook/missing-trader", strings.NewReader(body))
+	req.Header.Set(webhookSignatureHeader, webhookSignature("s3cret", []byte(body)))
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown trader, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWebhookTemplate_Fallback(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	t.Setenv("TYPE_BREAKOUT", "突破 ${Symbol}")
+	t.Setenv("TYPE_DEFAULT", "")
+	if tpl, fallback := server.webhookTemplate("breakout"); tpl != "突破 ${Symbol}" || fallback {
+		t.Fatalf("specific template: got %q fallback=%v", tpl, fallback)
+	}
+	if tpl, _ := server.webhookTemplate("newtype"); tpl != "" {
+		t.Fatalf("expected no template, got %q", tpl)
+	}
+
+	if err := db.SetSystemConfig("default_webhook_prompt", "配置默认 ${Type} ${Symbol}"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+	if tpl, fallback := server.webhookTemplate("newtype"); tpl != "配置默认 ${Type} ${Symbol}" || !fallback {
+		t.Fatalf("system_config fallback: got %q fallback=%v", tpl, fallback)
+	}
+
+	t.Setenv("TYPE_DEFAULT", "环境默认 ${Type} ${Close}")
+	tpl, fallback := server.webhookTemplate("newtype")
+	if tpl != "环境默认 ${Type} ${Close}" || !fallback {
+		t.Fatalf("TYPE_DEFAULT fallback: got %q fallback=%v", tpl, fallback)
+	}
+	if got := renderWebhookPrompt(tpl, &WebhookContent{Type: "newtype", Close: 42}); got != "环境默认 newtype 42" {
+		t.Fatalf("rendered fallback = %q", got)
+	}
+}
+
+func TestCheckEnvWebhookTemplates(t *testing.T) {
+	result := CheckEnvWebhookTemplates([]string{
+		"TYPE_BREAKOUT=突破 ${Symbol} ${Close}",
+		"TYPE_DUMP=暴跌 ${Close}",
+		"TYPE_RSI=${Symbol} RSI=${RSI}",
+		"PATH=/usr/bin",
+	})
+	if len(result) != 2 {
+		t.Fatalf("expected issues for TYPE_DUMP and TYPE_RSI, got %v", result)
+	}
+	if issues := result["TYPE_DUMP"]; len(issues) != 1 || issues[0].Warning || issues[0].Placeholder != "${Symbol}" {
+		t.Fatalf("TYPE_DUMP should miss ${Symbol}: %+v", issues)
+	}
+	if issues := result["TYPE_RSI"]; len(issues) != 1 || !issues[0].Warning {
+		t.Fatalf("indicator placeholder should only be a warning: %+v", issues)
+	}
+}
+
+// 所有内置占位符都应被替换
+func TestRenderWebhookPrompt_AllPlaceholders(t *testing.T) {
+	var tpl strings.Builder
+	for _, name := range config.WebhookPlaceholders {
+		tpl.WriteString("${" + name + "} ")
+	}
+	got := renderWebhookPrompt(tpl.String(), &WebhookContent{Symbol: "BTCUSDT"})
+	if strings.Contains(got, "${") {
+		t.Fatalf("unreplaced placeholder in %q", got)
+	}
+}
+
+func TestRenderWebhookPrompt(t *testing.T) {
+	wc := &WebhookContent{TraderID: "t1", Type: "breakout", Symbol: "BTCUSDT", Interval: "1h", Close: 65000.5, Content: "突破"}
+	got := renderWebhookPrompt("${Symbol} ${Interval} 收于 ${Close}: ${Content}", wc)
+	if want := "BTCUSDT 1h 收于 65000.5: 突破"; got != want {
+		t.Fatalf("renderWebhookPrompt = %q, want %q", got, want)
+	}
+}
+
+func TestRenderWebhookPrompt_Indicators(t *testing.T) {
+	wc, err := parseWebhookPayload([]byte(`{"trader_id":"t1","type":"rsi","symbol":"BTCUSDT","close":65000,"indicators":{"RSI":72.5,"MACD":-0.8,"Close":1}}`), "")
+	if err != nil {
+		t.Fatalf("parseWebhookPayload failed: %v", err)
+	}
+	if wc.Indicators["RSI"] != 72.5 {
+		t.Fatalf("expected RSI indicator, got %v", wc.Indicators)
+	}
+
+	got := renderWebhookPrompt("RSI=${RSI} MACD=${MACD} close=${Close} [${Indicators}] ${Missing}", wc)
+	if want := "RSI=72.5 MACD=-0.8 close=65000 [Close=1, MACD=-0.8, RSI=72.5] ${Missing}"; got != want {
+		t.Fatalf("renderWebhookPrompt = %q, want %q", got, want)
+	}
+
+	// 位置格式不解析指标
+	wc, err = parseWebhookPayload([]byte("t1 rsi BTCUSDT 1h 1 2 0.5 1.5 100 RSI=70"), "")
+	if err != nil {
+		t.Fatalf("parseWebhookPayload failed: %v", err)
+	}
+	if len(wc.Indicators) != 0 || wc.Content != "RSI=70" {
+		t.Fatalf("positional payload should keep content unchanged, got %+v", wc)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte("trader-1 breakout BTCUSDT 15m 1 2 3 4 5")
+
+	t.Setenv("WEBHOOK_SECRET", "")
+	if verifyWebhookSignature(body, "", "") {
+		t.Fatal("expected requests to be rejected without secret")
+	}
+
+	t.Setenv("WEBHOOK_SECRET", "s3cret")
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+	if !verifyWebhookSignature(body, sig, "") {
+		t.Fatal("expected valid signature to pass")
+	}
+	if verifyWebhookSignature(body, "deadbeef", "") || verifyWebhookSignature(body, "", "") {
+		t.Fatal("expected invalid signature to fail")
+	}
+}
+
+func TestVerifyWebhookSignature_PerTraderSecret(t *testing.T) {
+	body := []byte("breakout BTCUSDT 15m 1 2 3 4 5")
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	t.Setenv("WEBHOOK_SECRET", "global")
+	t.Setenv("WEBHOOK_SECRET_TRADER_9", "per-trader")
+	if !verifyWebhookSignature(body, sign("per-trader"), "trader-9") {
+		t.Fatal("expected per-trader secret to be used for path trader")
+	}
+	if verifyWebhookSignature(body, sign("global"), "trader-9") {
+		t.Fatal("global secret should not be accepted when a per-trader secret is set")
+	}
+	if !verifyWebhookSignature(body, sign("global"), "trader-10") {
+		t.Fatal("expected fallback to global secret")
+	}
+}
+
+// TestHandleWebhook_LegacyRoutePerTraderSecret 旧路由的交易员来自请求体，同样按 WEBHOOK_SECRET_<ID> 校验
+func TestHandleWebhook_LegacyRoutePerTraderSecret(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	t.Setenv("WEBHOOK_SECRET", "global")
+	t.Setenv("WEBHOOK_SECRET_TRADER_9", "per-trader")
+
+	body := `{"trader_id":"trader-9","action":"stop"}`
+	send := func(secret string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/webhook", strings.NewReader(body))
+		req.Header.Set(webhookSignatureHeader, webhookSignature(secret, []byte(body)))
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := send("global"); code != http.StatusUnauthorized {
+		t.Fatalf("global secret must not bypass the per-trader secret, got %d", code)
+	}
+	// 签名通过后才查找交易员（不存在返回 404）
+	if code := send("per-trader"); code != http.StatusNotFound {
+		t.Fatalf("expected per-trader signature to pass auth, got %d", code)
+	}
+}
+
+func TestWriteWebhookResponse_Signature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	respond := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		writeWebhookResponse(c, http.StatusAccepted, gin.H{"message": "已接收", "trader_id": "trader-1"}, "trader-1")
+		return w
+	}
+
+	t.Setenv("WEBHOOK_SECRET", "")
+	if w := respond(); w.Header().Get(webhookResponseSignatureHeader) != "" {
+		t.Fatal("expected no response signature without secret")
+	}
+
+	t.Setenv("WEBHOOK_SECRET", "s3cret")
+	w := respond()
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(w.Body.Bytes())
+	if got := w.Header().Get(webhookResponseSignatureHeader); got != hex.EncodeToString(mac.Sum(nil)) {
+		t.Fatalf("response signature does not match body: %q", got)
+	}
+}
+
+func TestMapWebhookBody(t *testing.T) {
+	server, db, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// 默认别名：ticker/tf/price
+	wc, err := parseWebhookPayload(server.mapWebhookBody([]byte(`{"trader_id":"trader-1","type":"rsi","ticker":"BTCUSDT","tf":"15m","price":105.5}`), ""), "")
+	if err != nil || wc.Symbol != "BTCUSDT" || wc.Interval != "15m" || wc.Close != 105.5 {
+		t.Fatalf("default aliases: %+v (%v)", wc, err)
+	}
+
+	// 请求体已有目标字段时保留原值
+	wc, err = parseWebhookPayload(server.mapWebhookBody([]byte(`{"trader_id":"trader-1","type":"rsi","symbol":"ETHUSDT","ticker":"BTCUSDT"}`), ""), "")
+	if err != nil || wc.Symbol != "ETHUSDT" {
+		t.Fatalf("existing field should win: %+v (%v)", wc, err)
+	}
+
+	if err := db.SetSystemConfig(webhookFieldMappingKey, `{"bot":"trader_id","signal":"type","coin":"symbol","bogus":"not_a_field"}`); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+	if err := db.SetSystemConfig(webhookFieldMappingKey+"_trader-2", `{"coin":"content"}`); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	wc, err = parseWebhookPayload(server.mapWebhookBody([]byte(`{"bot":"trader-1","signal":"breakout","coin":"SOLUSDT"}`), ""), "")
+	if err != nil || wc.TraderID != "trader-1" || wc.Type != "breakout" || wc.Symbol != "SOLUSDT" {
+		t.Fatalf("global mapping: %+v (%v)", wc, err)
+	}
+
+	// 交易员映射优先于全局映射；交易员ID经全局映射从请求体识别
+	wc, err = parseWebhookPayload(server.mapWebhookBody([]byte(`{"bot":"trader-2","signal":"breakout","coin":"SOLUSDT"}`), ""), "")
+	if err != nil || wc.Symbol != "" || wc.Content != "SOLUSDT" {
+		t.Fatalf("per-trader mapping: %+v (%v)", wc, err)
+	}
+	wc, err = parseWebhookPayload(server.mapWebhookBody([]byte(`{"signal":"breakout","coin":"SOLUSDT"}`), "trader-2"), "trader-2")
+	if err != nil || wc.Content != "SOLUSDT" {
+		t.Fatalf("per-trader mapping on path route: %+v (%v)", wc, err)
+	}
+
+	// 位置格式不受影响
+	positional := []byte("trader-1 breakout BTCUSDT 15m 100 110 95 105.5 1234")
+	if got := server.mapWebhookBody(positional, ""); string(got) != string(positional) {
+		t.Fatalf("positional payload should be unchanged, got %q", got)
+	}
+}
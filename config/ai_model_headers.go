@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// reservedCustomHeaders 由客户端自行设置的请求头，不允许通过自定义请求头覆盖
+var reservedCustomHeaders = map[string]struct{}{
+	"Authorization":  {},
+	"Content-Type":   {},
+	"Content-Length": {},
+	"Host":           {},
+}
+
+// ParseCustomHeaders 解析并校验 ai_models.custom_headers
+// 必须是扁平的 JSON 对象，键和值都为字符串；空字符串表示没有自定义请求头
+func ParseCustomHeaders(raw string) (map[string]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, fmt.Errorf("自定义请求头必须是JSON对象: %w", err)
+	}
+
+	headers := make(map[string]string, len(parsed))
+	for name, value := range parsed {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("自定义请求头 %s 的值必须是字符串", name)
+		}
+		if err := validateCustomHeader(name, str); err != nil {
+			return nil, err
+		}
+		headers[name] = str
+	}
+	return headers, nil
+}
+
+func validateCustomHeader(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("自定义请求头名称不能为空")
+	}
+	for _, r := range name {
+		// RFC 7230 token 字符
+		if r > 0x7e || r <= 0x20 || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return fmt.Errorf("自定义请求头名称无效: %q", name)
+		}
+	}
+	if _, reserved := reservedCustomHeaders[http.CanonicalHeaderKey(name)]; reserved {
+		return fmt.Errorf("不允许覆盖请求头: %s", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("自定义请求头 %s 的值不能包含换行", name)
+	}
+	return nil
+}
+
+// SetAIModelCustomHeaders 设置AI模型的自定义请求头（传入空 map 表示清除）
+// 请求头中通常带有网关鉴权令牌，与 API Key 一样加密存储
+func (d *Database) SetAIModelCustomHeaders(userID, modelID string, headers map[string]string) error {
+	for name, value := range headers {
+		if err := validateCustomHeader(name, value); err != nil {
+			return err
+		}
+	}
+
+	raw := ""
+	if len(headers) > 0 {
+		data, err := json.Marshal(headers)
+		if err != nil {
+			return fmt.Errorf("序列化自定义请求头失败: %w", err)
+		}
+		raw = d.encryptSensitiveData(string(data))
+	}
+
+	result, err := d.db.Exec(`
+		UPDATE ai_models SET custom_headers = ?, updated_at = datetime('now')
+		WHERE user_id = ? AND model_id = ?
+	`, raw, userID, modelID)
+	if err != nil {
+		return fmt.Errorf("更新自定义请求头失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("AI模型不存在: %s", modelID)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCustomHeaders(t *testing.T) {
+	headers, err := ParseCustomHeaders(`{"api-key":"azure-key","X-Tag":"nofx"}`)
+	if err != nil {
+		t.Fatalf("ParseCustomHeaders failed: %v", err)
+	}
+	if headers["api-key"] != "azure-key" || headers["X-Tag"] != "nofx" {
+		t.Fatalf("unexpected headers: %v", headers)
+	}
+
+	if headers, err := ParseCustomHeaders("  "); err != nil || headers != nil {
+		t.Fatalf("expected empty headers, got %v (%v)", headers, err)
+	}
+
+	invalid := []string{
+		`["api-key"]`,                     // 不是对象
+		`{"X-Retry": 3}`,                  // 值不是字符串
+		`{"X-Nested": {"a": "b"}}`,        // 嵌套对象
+		`{"authorization": "Bearer x"}`,   // 保留请求头
+		`{"Bad Header": "x"}`,             // 名称包含空格
+		`{"X-Inject": "a\r\nHost: evil"}`, // 值包含换行
+	}
+	for _, raw := range invalid {
+		if _, err := ParseCustomHeaders(raw); err == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestSetAIModelCustomHeaders(t *testing.T) {
+	t.Setenv("DATA_ENCRYPTION_KEY", "test-key-32-bytes-long-for-aes")
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	ensureTestAIModel(t, db, userID, "model-headers-1")
+
+	if err := db.SetAIModelCustomHeaders(userID, "model-headers-1", map[string]string{"api-key": "azure-key"}); err != nil {
+		t.Fatalf("SetAIModelCustomHeaders failed: %v", err)
+	}
+	if db.cryptoService != nil {
+		var raw string
+		if err := db.db.QueryRow(`SELECT custom_headers FROM ai_models WHERE user_id = ? AND model_id = ?`, userID, "model-headers-1").Scan(&raw); err != nil {
+			t.Fatalf("query custom_headers failed: %v", err)
+		}
+		if strings.Contains(raw, "azure-key") {
+			t.Fatalf("custom headers should be encrypted at rest, got %q", raw)
+		}
+	}
+	models, err := db.GetAIModels(userID)
+	if err != nil {
+		t.Fatalf("GetAIModels failed: %v", err)
+	}
+	var stored string
+	for _, m := range models {
+		if m.ModelID == "model-headers-1" {
+			stored = m.CustomHeaders
+		}
+	}
+	headers, err := ParseCustomHeaders(stored)
+	if err != nil || headers["api-key"] != "azure-key" {
+		t.Fatalf("unexpected stored headers %q: %v", stored, err)
+	}
+
+	if err := db.SetAIModelCustomHeaders(userID, "model-headers-1", map[string]string{"Content-Type": "text/plain"}); err == nil {
+		t.Fatal("expected reserved header to be rejected")
+	}
+	if err := db.SetAIModelCustomHeaders(userID, "missing-model", map[string]string{"X-Tag": "a"}); err == nil {
+		t.Fatal("expected error for unknown model")
+	}
+
+	// 空 map 清除请求头
+	if err := db.SetAIModelCustomHeaders(userID, "model-headers-1", nil); err != nil {
+		t.Fatalf("clear headers failed: %v", err)
+	}
+	models, _ = db.GetAIModels(userID)
+	for _, m := range models {
+		if m.ModelID == "model-headers-1" && m.CustomHeaders != "" {
+			t.Fatalf("expected headers to be cleared, got %q", m.CustomHeaders)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+import "fmt"
+
+// maxAIModelRequestsPerMinute 单个AI模型每分钟请求上限的最大可配置值
+const maxAIModelRequestsPerMinute = 10000
+
+// SetAIModelRequestsPerMinute 设置AI模型每分钟请求上限（0表示使用全局 ai_model_rpm）
+func (d *Database) SetAIModelRequestsPerMinute(userID, modelID string, rpm int) error {
+	if rpm < 0 || rpm > maxAIModelRequestsPerMinute {
+		return fmt.Errorf("每分钟请求上限必须在 0-%d 之间: %d", maxAIModelRequestsPerMinute, rpm)
+	}
+	result, err := d.db.Exec(`
+		UPDATE ai_models SET requests_per_minute = ?, updated_at = datetime('now')
+		WHERE user_id = ? AND model_id = ?
+	`, rpm, userID, modelID)
+	if err != nil {
+		return fmt.Errorf("更新每分钟请求上限失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("AI模型不存在: %s", modelID)
+	}
+	return nil
+}
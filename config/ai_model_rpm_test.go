@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+func TestSetAIModelRequestsPerMinute(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-rpm-1")
+	exID := ensureTestExchange(t, db, userID, "binance-rpm-1")
+	if err := db.CreateTrader(&TraderRecord{
+		ID: "tr-rpm", UserID: userID, Name: "rpm", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	if err := db.SetAIModelRequestsPerMinute(userID, "model-rpm-1", 12); err != nil {
+		t.Fatalf("SetAIModelRequestsPerMinute failed: %v", err)
+	}
+	models, err := db.GetAIModels(userID)
+	if err != nil {
+		t.Fatalf("GetAIModels failed: %v", err)
+	}
+	for _, m := range models {
+		if m.ModelID == "model-rpm-1" && m.RequestsPerMinute != 12 {
+			t.Fatalf("expected rpm 12, got %d", m.RequestsPerMinute)
+		}
+	}
+	_, aiModel, _, err := db.GetTraderConfig(userID, "tr-rpm")
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if aiModel.RequestsPerMinute != 12 {
+		t.Fatalf("trader config should carry the model rpm, got %d", aiModel.RequestsPerMinute)
+	}
+
+	if err := db.SetAIModelRequestsPerMinute(userID, "model-rpm-1", -1); err == nil {
+		t.Error("negative rpm should be rejected")
+	}
+	if err := db.SetAIModelRequestsPerMinute(userID, "missing-model", 5); err == nil {
+		t.Error("unknown model should be rejected")
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+)
+
+// 审计日志实体类型
+const (
+	AuditEntityTrader   = "trader"
+	AuditEntityExchange = "exchange"
+	AuditEntityUser     = "user"
+)
+
+// AuditLogEntry 审计日志记录（谁在何时对哪个配置做了什么）
+type AuditLogEntry struct {
+	ID         int64     `json:"id"`
+	UserID     string    `json:"user_id"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	Action     string    `json:"action"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// sqlExecer *sql.DB 与 *sql.Tx 共有的写入方法
+type sqlExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// recordAuditEvent 写入一条审计日志；可传入事务，使审计记录与配置变更一起提交
+func recordAuditEvent(exec sqlExecer, userID, entityType, entityID, action, detail string) error {
+	if _, err := exec.Exec(`
+		INSERT INTO audit_log (user_id, entity_type, entity_id, action, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, userID, entityType, entityID, action, detail, time.Now().UTC().Format(sqliteTimeLayout)); err != nil {
+		return fmt.Errorf("记录审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// RecordAuditEvent 记录审计日志（失败只输出警告，不影响业务操作）
+func (d *Database) RecordAuditEvent(userID, entityType, entityID, action, detail string) {
+	if err := recordAuditEvent(d.db, userID, entityType, entityID, action, detail); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+// GetAuditLogByEntity 获取某个实体（交易员、交易所、用户）自 since 起的全部审计记录，按时间升序
+// since 为零值时不限制起始时间
+func (d *Database) GetAuditLogByEntity(entityType, entityID string, since time.Time) ([]*AuditLogEntry, error) {
+	return d.queryAuditLog(entityType, entityID, since, time.Time{})
+}
+
+// queryAuditLog 按实体和时间范围 [since, until) 查询审计日志，零值表示不限制
+func (d *Database) queryAuditLog(entityType, entityID string, since, until time.Time) ([]*AuditLogEntry, error) {
+	query := `
+		SELECT id, user_id, entity_type, entity_id, action, detail, created_at
+		FROM audit_log WHERE entity_type = ? AND entity_id = ?`
+	args := []interface{}{entityType, entityID}
+	if !since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, since.UTC().Format(sqliteTimeLayout))
+	}
+	if !until.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, until.UTC().Format(sqliteTimeLayout))
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询审计日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditLogEntry, 0)
+	for rows.Next() {
+		var entry AuditLogEntry
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.EntityType, &entry.EntityID, &entry.Action, &entry.Detail, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取审计日志失败: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// ExportAuditLog 将某个实体在 [since, until) 内的审计日志导出为 CSV 或 JSON（format: "csv" / "json"）
+func (d *Database) ExportAuditLog(w io.Writer, format, entityType, entityID string, since, until time.Time) error {
+	entries, err := d.queryAuditLog(entityType, entityID, since, until)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return json.NewEncoder(w).Encode(entries)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "created_at", "user_id", "entity_type", "entity_id", "action", "detail"})
+		for _, entry := range entries {
+			cw.Write([]string{
+				strconv.FormatInt(entry.ID, 10), entry.CreatedAt.UTC().Format(time.RFC3339), entry.UserID,
+				entry.EntityType, entry.EntityID, entry.Action, entry.Detail,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
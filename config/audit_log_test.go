@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAuditLogByEntityAndExport(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-audit-1")
+	exID := ensureTestExchange(t, db, userID, "binance-audit-1")
+	tr := &TraderRecord{
+		ID: "tr-audit", UserID: userID, Name: "audit", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	tr.Name = "audit-renamed"
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	db.RecordAuditEvent(userID, AuditEntityTrader, "tr-other", "create", "")
+	if _, err := db.db.Exec(`
+		INSERT INTO audit_log (user_id, entity_type, entity_id, action, created_at)
+		VALUES (?, 'trader', 'tr-audit', 'old', '2020-01-01 00:00:00')
+	`, userID); err != nil {
+		t.Fatalf("insert old audit event failed: %v", err)
+	}
+
+	entries, err := db.GetAuditLogByEntity(AuditEntityTrader, "tr-audit", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetAuditLogByEntity failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "create" || entries[1].Action != "update" || entries[1].Detail != "audit-renamed" {
+		t.Fatalf("unexpected audit entries: %+v", entries)
+	}
+	if all, _ := db.GetAuditLogByEntity(AuditEntityTrader, "tr-audit", time.Time{}); len(all) != 3 || all[0].Action != "old" {
+		t.Fatalf("expected 3 entries without since, got %d", len(all))
+	}
+
+	// 导出：until 之前的记录
+	var buf bytes.Buffer
+	until := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.ExportAuditLog(&buf, "csv", AuditEntityTrader, "tr-audit", time.Time{}, until); err != nil {
+		t.Fatalf("ExportAuditLog csv failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 || records[1][5] != "old" {
+		t.Fatalf("unexpected csv export: %v (%v)", records, err)
+	}
+
+	buf.Reset()
+	if err := db.ExportAuditLog(&buf, "json", AuditEntityTrader, "tr-audit", time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("ExportAuditLog json failed: %v", err)
+	}
+	var exported []AuditLogEntry
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil || len(exported) != 2 {
+		t.Fatalf("unexpected json export: %s (%v)", buf.String(), err)
+	}
+
+	if err := db.ExportAuditLog(&buf, "xml", AuditEntityTrader, "tr-audit", time.Time{}, time.Time{}); err == nil {
+		t.Fatal("expected unsupported format error")
+	}
+
+	if err := db.DeleteTrader(userID, "tr-audit"); err != nil {
+		t.Fatalf("DeleteTrader failed: %v", err)
+	}
+	entries, _ = db.GetAuditLogByEntity(AuditEntityTrader, "tr-audit", time.Now().Add(-time.Hour))
+	if len(entries) != 3 || entries[2].Action != "delete" {
+		t.Fatalf("expected delete to be audited and kept after trader removal, got %+v", entries)
+	}
+}
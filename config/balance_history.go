@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// BalanceSnapshot 交易所返回的账户余额快照
+type BalanceSnapshot struct {
+	ID               int64     `json:"id"`
+	TraderID         string    `json:"trader_id"`
+	WalletBalance    float64   `json:"wallet_balance"`    // 钱包余额（交易所 totalWalletBalance）
+	AvailableBalance float64   `json:"available_balance"` // 可用余额
+	UnrealizedPnL    float64   `json:"unrealized_pnl"`    // 未实现盈亏
+	TotalEquity      float64   `json:"total_equity"`      // 钱包余额 + 未实现盈亏
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// RecordBalanceSnapshot 记录一条账户余额快照（CreatedAt 为零值时使用当前时间）
+func (d *Database) RecordBalanceSnapshot(snapshot *BalanceSnapshot) error {
+	if snapshot.TraderID == "" {
+		return fmt.Errorf("余额快照缺少交易员ID")
+	}
+	createdAt := snapshot.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	if _, err := d.db.Exec(`
+		INSERT INTO account_balance_history (trader_id, wallet_balance, available_balance, unrealized_pnl, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, snapshot.TraderID, snapshot.WalletBalance, snapshot.AvailableBalance, snapshot.UnrealizedPnL,
+		createdAt.UTC().Format(sqliteTimeLayout)); err != nil {
+		return fmt.Errorf("记录余额快照失败: %w", err)
+	}
+	return nil
+}
+
+// GetBalanceHistory 查询交易员在 [since, until] 区间内的余额快照，按时间升序
+// since/until 为零值时不限制对应方向
+func (d *Database) GetBalanceHistory(userID, traderID string, since, until time.Time) ([]BalanceSnapshot, error) {
+	query := `
+		SELECT b.id, b.trader_id, b.wallet_balance, b.available_balance, b.unrealized_pnl, b.created_at
+		FROM account_balance_history b JOIN traders t ON t.id = b.trader_id
+		WHERE b.trader_id = ? AND t.user_id = ?`
+	args := []interface{}{traderID, userID}
+	if !since.IsZero() {
+		query += ` AND b.created_at >= ?`
+		args = append(args, since.UTC().Format(sqliteTimeLayout))
+	}
+	if !until.IsZero() {
+		query += ` AND b.created_at <= ?`
+		args = append(args, until.UTC().Format(sqliteTimeLayout))
+	}
+	query += ` ORDER BY b.created_at, b.id`
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询余额历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]BalanceSnapshot, 0)
+	for rows.Next() {
+		var b BalanceSnapshot
+		if err := rows.Scan(&b.ID, &b.TraderID, &b.WalletBalance, &b.AvailableBalance, &b.UnrealizedPnL, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取余额历史失败: %w", err)
+		}
+		b.TotalEquity = b.WalletBalance + b.UnrealizedPnL
+		history = append(history, b)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBalanceHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-balance-1")
+	exID := ensureTestExchange(t, db, userID, "binance-balance-1")
+	tr := &TraderRecord{
+		ID: "tr-balance", UserID: userID, Name: "balance", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, wallet := range []float64{1000, 1010, 990} {
+		if err := db.RecordBalanceSnapshot(&BalanceSnapshot{
+			TraderID: tr.ID, WalletBalance: wallet, AvailableBalance: wallet - 100, UnrealizedPnL: 5,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}); err != nil {
+			t.Fatalf("RecordBalanceSnapshot failed: %v", err)
+		}
+	}
+	if err := db.RecordBalanceSnapshot(&BalanceSnapshot{}); err == nil {
+		t.Fatal("expected error for snapshot without trader id")
+	}
+
+	all, err := db.GetBalanceHistory(userID, tr.ID, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetBalanceHistory failed: %v", err)
+	}
+	if len(all) != 3 || all[0].WalletBalance != 1000 || all[2].WalletBalance != 990 {
+		t.Fatalf("unexpected history: %+v", all)
+	}
+	if all[1].TotalEquity != 1015 || all[1].AvailableBalance != 910 {
+		t.Fatalf("unexpected snapshot: %+v", all[1])
+	}
+
+	window, err := db.GetBalanceHistory(userID, tr.ID, base.Add(time.Hour), base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetBalanceHistory failed: %v", err)
+	}
+	if len(window) != 1 || window[0].WalletBalance != 1010 {
+		t.Fatalf("unexpected window: %+v", window)
+	}
+
+	// 其他用户不能查询
+	other, err := db.GetBalanceHistory("other-user", tr.ID, time.Time{}, time.Time{})
+	if err != nil || len(other) != 0 {
+		t.Fatalf("expected empty history for other user, got %v, %v", other, err)
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPreviewBetaCodeImport(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.db.Exec(`INSERT INTO beta_codes (code) VALUES ('abc234')`); err != nil {
+		t.Fatalf("insert beta code failed: %v", err)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "beta_codes.txt")
+	content := "# 注释\nabc234\nxyz789\n\nxyz789\nBAD-01\ntoolongcode\nmnp456\n"
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	newCodes, duplicate, invalid, err := db.PreviewBetaCodeImport(filePath)
+	if err != nil {
+		t.Fatalf("PreviewBetaCodeImport failed: %v", err)
+	}
+	if want := []string{"xyz789", "mnp456"}; !reflect.DeepEqual(newCodes, want) {
+		t.Errorf("new = %v, want %v", newCodes, want)
+	}
+	if want := []string{"abc234", "xyz789"}; !reflect.DeepEqual(duplicate, want) {
+		t.Errorf("duplicate = %v, want %v", duplicate, want)
+	}
+	if want := []string{"BAD-01", "toolongcode"}; !reflect.DeepEqual(invalid, want) {
+		t.Errorf("invalid = %v, want %v", invalid, want)
+	}
+
+	// 预览不应写入数据库
+	if total, _, _ := db.GetBetaCodeStats(); total != 1 {
+		t.Errorf("expected preview to insert nothing, total = %d", total)
+	}
+
+	if _, _, _, err := db.PreviewBetaCodeImport(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestLoadBetaCodesFromFile_SkipsInvalidCodes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	filePath := filepath.Join(t.TempDir(), "beta_codes.txt")
+	content := "# 注释\nxyz789\nBAD-01\ntoolongcode\nmnp456\n"
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	if err := db.LoadBetaCodesFromFile(filePath); err != nil {
+		t.Fatalf("LoadBetaCodesFromFile failed: %v", err)
+	}
+	if total, _, _ := db.GetBetaCodeStats(); total != 2 {
+		t.Errorf("expected only the 2 valid codes to be loaded, total = %d", total)
+	}
+	if ok, _ := db.ValidateBetaCode("BAD-01"); ok {
+		t.Error("malformed code should not be usable")
+	}
+}
+
+func TestBetaCodeUsesAllowed(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.db.Exec(`INSERT INTO beta_codes (code) VALUES ('one111'), ('multi2')`); err != nil {
+		t.Fatalf("insert beta codes failed: %v", err)
+	}
+
+	// 默认 uses_allowed=1，保持单次使用
+	if err := db.UseBetaCode("one111", "a@example.com"); err != nil {
+		t.Fatalf("UseBetaCode failed: %v", err)
+	}
+	if ok, _ := db.ValidateBetaCode("one111"); ok {
+		t.Error("single-use code should be exhausted")
+	}
+	if err := db.UseBetaCode("one111", "b@example.com"); err == nil {
+		t.Error("expected error reusing single-use code")
+	}
+
+	if err := db.SetBetaCodeUsesAllowed("multi2", 0); err == nil {
+		t.Error("expected error for uses_allowed=0")
+	}
+	if err := db.SetBetaCodeUsesAllowed("multi2", 5); err != nil {
+		t.Fatalf("SetBetaCodeUsesAllowed failed: %v", err)
+	}
+
+	// 并发注册不能超过上限
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if db.UseBetaCode("multi2", fmt.Sprintf("user%d@example.com", i)) == nil {
+				succeeded.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if succeeded.Load() != 5 {
+		t.Fatalf("expected 5 successful uses, got %d", succeeded.Load())
+	}
+	if ok, _ := db.ValidateBetaCode("multi2"); ok {
+		t.Error("code should be exhausted after 5 uses")
+	}
+	if _, used, _ := db.GetBetaCodeStats(); used != 2 {
+		t.Errorf("expected 2 exhausted codes, got %d", used)
+	}
+}
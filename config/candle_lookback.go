@@ -0,0 +1,37 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"nofx/market"
+)
+
+// ErrInvalidCandleLookback K线回看配置无效
+var ErrInvalidCandleLookback = errors.New("K线回看配置无效")
+
+// validateCandleLookback 校验 traders.candle_lookback（JSON 对象：时间线 -> K线数量，空字符串表示使用默认值）
+func validateCandleLookback(raw string) error {
+	if _, err := market.ParseCandleLookback(raw); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCandleLookback, err)
+	}
+	return nil
+}
+
+// CandleLookbackMap 返回交易员的K线回看配置（未配置或配置无效时返回 nil，使用默认值）
+func (t *TraderRecord) CandleLookbackMap() map[string]int {
+	lookback, err := market.ParseCandleLookback(t.CandleLookback)
+	if err != nil {
+		return nil
+	}
+	return lookback
+}
+
+// EncodeCandleLookback 序列化K线回看配置（空配置返回空字符串）
+func EncodeCandleLookback(lookback map[string]int) string {
+	if len(lookback) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(lookback)
+	return string(data)
+}
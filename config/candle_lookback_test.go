@@ -0,0 +1,48 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTraderCandleLookback(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-lookback")
+	exID := ensureTestExchange(t, db, userID, "binance-lookback")
+	tr := &TraderRecord{
+		ID: "tr-lookback", UserID: userID, Name: "lookback", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+	}
+
+	for _, raw := range []string{`{"2h":10}`, `{"4h":0}`, `{"4h":500}`, `not-json`} {
+		tr.CandleLookback = raw
+		if err := db.CreateTrader(tr); !errors.Is(err, ErrInvalidCandleLookback) {
+			t.Fatalf("%s: expected ErrInvalidCandleLookback, got %v", raw, err)
+		}
+	}
+
+	tr.CandleLookback = EncodeCandleLookback(map[string]int{"4h": 30, "1m": 5})
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	got, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	lookback := got.CandleLookbackMap()
+	if len(lookback) != 2 || lookback["4h"] != 30 || lookback["1m"] != 5 {
+		t.Fatalf("unexpected lookback: %v", lookback)
+	}
+
+	tr.CandleLookback = EncodeCandleLookback(nil)
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	got, _, _, _ = db.GetTraderConfig(userID, tr.ID)
+	if got.CandleLookbackMap() != nil {
+		t.Fatalf("cleared lookback should fall back to defaults, got %v", got.CandleLookbackMap())
+	}
+}
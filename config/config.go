@@ -16,6 +16,7 @@ type LeverageConfig struct {
 // LogConfig 日志配置
 type LogConfig struct {
 	Level    string          `json:"level"`    // 日志级别: debug, info, warn, error (默认: info)
+	Format   string          `json:"format"`   // 输出格式: text 或 json (默认: text)
 	Telegram *TelegramConfig `json:"telegram"` // Telegram推送配置（可选）
 }
 
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ConfigHistoryEntry 系统配置变更记录
+type ConfigHistoryEntry struct {
+	ID        int64     `json:"id"`
+	Key       string    `json:"key"`
+	OldValue  *string   `json:"old_value"` // nil 表示新建该配置
+	NewValue  *string   `json:"new_value"` // nil 表示删除该配置（例如被重命名）
+	ChangedBy string    `json:"changed_by"`
+	Note      string    `json:"note"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SetSystemConfigBy 设置系统配置并记录变更人
+// 值未变化时不写入历史，避免每次启动同步配置产生大量无意义记录
+func (d *Database) SetSystemConfigBy(key, value, changedBy string) error {
+	if err := validateSystemConfigValue(key, value); err != nil {
+		return err
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	oldValue, err := getSystemConfigTx(tx, key)
+	if err != nil {
+		return err
+	}
+	if oldValue != nil && *oldValue == value {
+		return nil
+	}
+
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO system_config (key, value) VALUES (?, ?)`, key, value); err != nil {
+		return fmt.Errorf("写入系统配置失败: %w", err)
+	}
+	if err := recordConfigHistoryTx(tx, key, oldValue, &value, changedBy, ""); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RenameSystemConfigKey 重命名系统配置键，保留原值并在新旧两个键下记录变更历史
+func (d *Database) RenameSystemConfigKey(oldKey, newKey string) error {
+	if oldKey == "" || newKey == "" {
+		return fmt.Errorf("配置键不能为空")
+	}
+	if oldKey == newKey {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	value, err := getSystemConfigTx(tx, oldKey)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return fmt.Errorf("配置键不存在: %s", oldKey)
+	}
+	existing, err := getSystemConfigTx(tx, newKey)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("目标配置键已存在: %s", newKey)
+	}
+
+	if _, err := tx.Exec(`INSERT INTO system_config (key, value) VALUES (?, ?)`, newKey, *value); err != nil {
+		return fmt.Errorf("写入新配置键失败: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM system_config WHERE key = ?`, oldKey); err != nil {
+		return fmt.Errorf("删除旧配置键失败: %w", err)
+	}
+	if err := recordConfigHistoryTx(tx, oldKey, value, nil, "", "renamed to "+newKey); err != nil {
+		return err
+	}
+	if err := recordConfigHistoryTx(tx, newKey, nil, value, "", "renamed from "+oldKey); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSystemConfigHistory 获取配置键的变更历史（最新的在前）
+func (d *Database) GetSystemConfigHistory(key string) ([]*ConfigHistoryEntry, error) {
+	rows, err := d.db.Query(`
+		SELECT id, key, old_value, new_value, COALESCE(changed_by, ''), COALESCE(note, ''), changed_at
+		FROM config_history WHERE key = ?
+		ORDER BY id DESC
+	`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*ConfigHistoryEntry
+	for rows.Next() {
+		var entry ConfigHistoryEntry
+		var oldValue, newValue sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Key, &oldValue, &newValue, &entry.ChangedBy, &entry.Note, &entry.ChangedAt); err != nil {
+			return nil, err
+		}
+		if oldValue.Valid {
+			entry.OldValue = &oldValue.String
+		}
+		if newValue.Valid {
+			entry.NewValue = &newValue.String
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// getSystemConfigTx 在事务中读取配置，不存在时返回 nil
+func getSystemConfigTx(tx *sql.Tx, key string) (*string, error) {
+	var value string
+	err := tx.QueryRow(`SELECT value FROM system_config WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取系统配置失败: %w", err)
+	}
+	return &value, nil
+}
+
+func recordConfigHistoryTx(tx *sql.Tx, key string, oldValue, newValue *string, changedBy, note string) error {
+	_, err := tx.Exec(`
+		INSERT INTO config_history (key, old_value, new_value, changed_by, note)
+		VALUES (?, ?, ?, ?, ?)
+	`, key, oldValue, newValue, changedBy, note)
+	if err != nil {
+		return fmt.Errorf("记录配置变更历史失败: %w", err)
+	}
+	return nil
+}
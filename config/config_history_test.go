@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+func TestSystemConfigHistoryAndRename(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSystemConfigBy("max_daily_loss", "8.0", "admin@example.com"); err != nil {
+		t.Fatalf("SetSystemConfigBy failed: %v", err)
+	}
+	// 相同的值不产生历史记录
+	if err := db.SetSystemConfig("max_daily_loss", "8.0"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	history, err := db.GetSystemConfigHistory("max_daily_loss")
+	if err != nil {
+		t.Fatalf("GetSystemConfigHistory failed: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("expected 1 history entry, got %d", len(history))
+	}
+	if h := history[0]; h.OldValue == nil || *h.OldValue != "10.0" || *h.NewValue != "8.0" || h.ChangedBy != "admin@example.com" {
+		t.Fatalf("unexpected history entry: %+v", h)
+	}
+
+	if err := db.RenameSystemConfigKey("max_daily_loss", "max_daily_loss_pct"); err != nil {
+		t.Fatalf("RenameSystemConfigKey failed: %v", err)
+	}
+	if val, err := db.GetSystemConfig("max_daily_loss_pct"); err != nil || val != "8.0" {
+		t.Fatalf("renamed key value = %q (%v), want 8.0", val, err)
+	}
+	if _, err := db.GetSystemConfig("max_daily_loss"); err == nil {
+		t.Fatal("old key should be removed after rename")
+	}
+
+	newHistory, _ := db.GetSystemConfigHistory("max_daily_loss_pct")
+	if len(newHistory) != 1 || newHistory[0].OldValue != nil || *newHistory[0].NewValue != "8.0" {
+		t.Fatalf("unexpected history for new key: %+v", newHistory)
+	}
+	oldHistory, _ := db.GetSystemConfigHistory("max_daily_loss")
+	if len(oldHistory) != 2 || oldHistory[0].NewValue != nil {
+		t.Fatalf("expected rename entry on old key, got %+v", oldHistory)
+	}
+
+	if err := db.RenameSystemConfigKey("missing_key", "other_key"); err == nil {
+		t.Fatal("renaming a missing key should fail")
+	}
+	if err := db.RenameSystemConfigKey("max_drawdown", "max_daily_loss_pct"); err == nil {
+		t.Fatal("renaming onto an existing key should fail")
+	}
+}
@@ -1,6 +1,14 @@
 package config
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"nofx/market"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestGetCustomCoinsFromRunningTraders(t *testing.T) {
 	db, cleanup := setupTestDB(t)
@@ -109,3 +117,208 @@ func ensureTestExchange(t *testing.T, db *Database, userID, exchangeID string) i
 	t.Fatalf("exchange %s not found", exchangeID)
 	return 0
 }
+
+func TestTraderCategoryOrderStrategiesRoundTrip(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-strategy-1")
+	exID := ensureTestExchange(t, db, userID, "binance-strategy-1")
+
+	tr := &TraderRecord{
+		ID:                   "tr-strategy",
+		UserID:               userID,
+		Name:                 "strategy",
+		AIModelID:            aiID,
+		ExchangeID:           exID,
+		InitialBalance:       1000,
+		ScanIntervalMinutes:  5,
+		OrderStrategy:        "conservative_hybrid",
+		BTCETHOrderStrategy:  "limit_only",
+		AltcoinOrderStrategy: "market_only",
+		SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	got, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if got.BTCETHOrderStrategy != "limit_only" || got.AltcoinOrderStrategy != "market_only" {
+		t.Fatalf("unexpected category strategies: %q / %q", got.BTCETHOrderStrategy, got.AltcoinOrderStrategy)
+	}
+
+	tr.AltcoinOrderStrategy = ""
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	traders, err := db.GetTraders(userID)
+	if err != nil || len(traders) != 1 {
+		t.Fatalf("GetTraders failed: %v (%d)", err, len(traders))
+	}
+	if traders[0].AltcoinOrderStrategy != "" || traders[0].BTCETHOrderStrategy != "limit_only" {
+		t.Fatalf("unexpected strategies after update: %q / %q", traders[0].BTCETHOrderStrategy, traders[0].AltcoinOrderStrategy)
+	}
+}
+
+// 回归测试：大量交易员时不能因为字符串拼接长度限制丢失币种
+func TestGetCustomCoinsManyTraders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-coins-many")
+	exID := ensureTestExchange(t, db, userID, "binance-coins-many")
+
+	const traderCount = 200
+	for i := 0; i < traderCount; i++ {
+		tr := &TraderRecord{
+			ID:                   fmt.Sprintf("tr-many-%03d", i),
+			UserID:               userID,
+			Name:                 fmt.Sprintf("many-%03d", i),
+			AIModelID:            aiID,
+			ExchangeID:           exID,
+			InitialBalance:       1000,
+			ScanIntervalMinutes:  5,
+			IsRunning:            true,
+			TradingSymbols:       fmt.Sprintf("COIN%03dUSDT, BTCUSDT", i),
+			SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	coins := db.GetCustomCoins()
+	if len(coins) != traderCount+1 {
+		t.Fatalf("expected %d coins, got %d", traderCount+1, len(coins))
+	}
+	if !slices.Contains(coins, "COIN199USDT") || !slices.Contains(coins, "BTCUSDT") {
+		t.Fatalf("missing expected coins in result")
+	}
+}
+
+func TestGetCustomCoinsAppliesSymbolFilter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-filter-1")
+	exID := ensureTestExchange(t, db, userID, "binance-filter-1")
+
+	tr := &TraderRecord{
+		ID: "tr-filter", UserID: userID, Name: "filter", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 5, IsRunning: true,
+		TradingSymbols: "BTCUSDT,ETHUSDT,LUNAUSDT", SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	if err := db.SetSystemConfig("symbol_denylist", "luna"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+	if coins := db.GetCustomCoins(); !slices.Equal(coins, []string{"BTCUSDT", "ETHUSDT"}) {
+		t.Fatalf("denylist not applied: %v", coins)
+	}
+
+	// 白名单只保留 BTC，黑名单依旧生效
+	if err := db.SetSystemConfig("symbol_allowlist", "BTCUSDT,LUNAUSDT"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+	if coins := db.GetCustomCoins(); !slices.Equal(coins, []string{"BTCUSDT"}) {
+		t.Fatalf("allowlist not applied: %v", coins)
+	}
+
+	filter := db.GetSymbolFilter()
+	if filter.Allowed("ETHUSDT") || filter.Allowed("LUNAUSDT") || !filter.Allowed("btc") {
+		t.Fatal("unexpected SymbolFilter.Allowed results")
+	}
+}
+
+func TestTraderSymbolLimit(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSystemConfig("max_trader_symbols", "3"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-limit-1")
+	exID := ensureTestExchange(t, db, userID, "binance-limit-1")
+
+	// 规范化去重后只有 3 个币种
+	tr := &TraderRecord{
+		ID:             "tr-limit",
+		UserID:         userID,
+		Name:           "limit",
+		AIModelID:      aiID,
+		ExchangeID:     exID,
+		TradingSymbols: "btc, BTCUSDT, eth,sol,,ETHUSDT",
+	}
+	if got := CountTraderSymbols(tr.TradingSymbols); got != 3 {
+		t.Fatalf("CountTraderSymbols = %d, want 3", got)
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	tr.TradingSymbols = "BTCUSDT,ETHUSDT,SOLUSDT,BNBUSDT"
+	err := db.UpdateTrader(tr)
+	if !errors.Is(err, ErrTooManySymbols) {
+		t.Fatalf("expected ErrTooManySymbols, got %v", err)
+	}
+
+	tr.ID = "tr-limit-2"
+	if err := db.CreateTrader(tr); !errors.Is(err, ErrTooManySymbols) {
+		t.Fatalf("expected ErrTooManySymbols on create, got %v", err)
+	}
+}
+
+func TestTraderExchangeSymbolValidation(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	catalog := market.NewSymbolCatalog(time.Hour)
+	fetchErr := error(nil)
+	catalog.Register("hl-sym-1", func(testnet bool) (map[string]struct{}, error) {
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return map[string]struct{}{"BTCUSDT": {}, "ETHUSDT": {}}, nil
+	})
+	db.SetSymbolChecker(catalog)
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-sym-1")
+	exID := ensureTestExchange(t, db, userID, "hl-sym-1")
+
+	tr := &TraderRecord{
+		ID: "tr-sym", UserID: userID, Name: "sym", AIModelID: aiID, ExchangeID: exID,
+		TradingSymbols: "btc,1000PEPEUSDT",
+	}
+	err := db.CreateTrader(tr)
+	if !errors.Is(err, ErrUnsupportedSymbols) || !strings.Contains(err.Error(), "1000PEPEUSDT") {
+		t.Fatalf("expected ErrUnsupportedSymbols naming 1000PEPEUSDT, got %v", err)
+	}
+
+	tr.TradingSymbols = "btc,ETHUSDT"
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	// 币种列表已缓存，获取失败也不影响；未注册的交易所不校验
+	fetchErr = errors.New("network down")
+	tr.TradingSymbols = "SOLUSDT"
+	if err := db.UpdateTrader(tr); !errors.Is(err, ErrUnsupportedSymbols) {
+		t.Fatalf("expected cached catalog to reject SOLUSDT, got %v", err)
+	}
+	tr.ExchangeID = ensureTestExchange(t, db, userID, "other-sym-1")
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("expected unregistered exchange to skip validation, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StopReasonConsecutiveFailures 交易周期连续失败达到上限时记录的停止原因前缀
+const StopReasonConsecutiveFailures = "consecutive_failures"
+
+// defaultMaxConsecutiveFailures 未配置 max_consecutive_failures 时的连续失败上限
+const defaultMaxConsecutiveFailures = 5
+
+// CycleFailureStatus 交易员交易周期的连续失败状态
+type CycleFailureStatus struct {
+	Failures     int  `json:"failures"`  // 当前连续失败次数（成功后清零）
+	Threshold    int  `json:"threshold"` // 连续失败上限（0 表示不自动停用）
+	JustDisabled bool `json:"-"`         // 本次记录是否触发了自动停用
+}
+
+// getMaxConsecutiveFailures 读取连续失败上限（system_config.max_consecutive_failures，0 表示不自动停用）
+func (d *Database) getMaxConsecutiveFailures() int {
+	val, err := d.GetSystemConfig("max_consecutive_failures")
+	if err != nil || strings.TrimSpace(val) == "" {
+		return defaultMaxConsecutiveFailures
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || n < 0 {
+		return defaultMaxConsecutiveFailures
+	}
+	return n
+}
+
+// RecordCycleResult 记录一次交易周期的结果：成功时清零连续失败计数，失败时计数加一
+// 连续失败达到上限且交易员仍在运行时，将其置为 is_running=0 并写入 stop_reason（只触发一次，重新启动后才会再次计数）
+func (d *Database) RecordCycleResult(traderID string, cycleErr error) (*CycleFailureStatus, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	status := &CycleFailureStatus{Threshold: d.getMaxConsecutiveFailures()}
+
+	if cycleErr == nil {
+		if _, err := d.db.Exec(`UPDATE traders SET consecutive_failures = 0 WHERE id = ? AND consecutive_failures <> 0`, traderID); err != nil {
+			return nil, fmt.Errorf("重置连续失败计数失败: %w", err)
+		}
+		return status, nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var running bool
+	err = tx.QueryRow(`
+		UPDATE traders SET consecutive_failures = COALESCE(consecutive_failures, 0) + 1
+		WHERE id = ?
+		RETURNING consecutive_failures, is_running
+	`, traderID).Scan(&status.Failures, &running)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("交易员不存在: %s", traderID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("更新连续失败计数失败: %w", err)
+	}
+
+	if status.Threshold > 0 && running && status.Failures >= status.Threshold {
+		reason := fmt.Sprintf("%s: 连续 %d 个周期失败，最近错误: %v", StopReasonConsecutiveFailures, status.Failures, cycleErr)
+		if _, err := tx.Exec(`UPDATE traders SET is_running = 0, stop_reason = ? WHERE id = ?`, reason, traderID); err != nil {
+			return nil, fmt.Errorf("停用交易员失败: %w", err)
+		}
+		status.JustDisabled = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecordCycleResult(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-fail-1")
+	exID := ensureTestExchange(t, db, userID, "binance-fail-1")
+	tr := &TraderRecord{
+		ID: "tr-fail", UserID: userID, Name: "fail", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default", IsRunning: true,
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	if err := db.SetSystemConfig("max_consecutive_failures", "3"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	cycleErr := errors.New("构建交易上下文失败")
+	record := func(err error) *CycleFailureStatus {
+		t.Helper()
+		status, recErr := db.RecordCycleResult(tr.ID, err)
+		if recErr != nil {
+			t.Fatalf("RecordCycleResult failed: %v", recErr)
+		}
+		return status
+	}
+
+	record(cycleErr)
+	record(cycleErr)
+	// 成功周期清零计数
+	if status := record(nil); status.Failures != 0 {
+		t.Fatalf("success should reset failures, got %+v", status)
+	}
+
+	for i := 1; i <= 2; i++ {
+		if status := record(cycleErr); status.Failures != i || status.JustDisabled {
+			t.Fatalf("failure #%d: unexpected status %+v", i, status)
+		}
+	}
+	if status := record(cycleErr); status.Failures != 3 || !status.JustDisabled {
+		t.Fatalf("third consecutive failure should disable the trader, got %+v", status)
+	}
+	trader, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if trader.IsRunning || !strings.HasPrefix(trader.StopReason, StopReasonConsecutiveFailures) {
+		t.Fatalf("trader should be stopped with stop_reason, got running=%v reason=%q", trader.IsRunning, trader.StopReason)
+	}
+
+	// 已停用后不再重复触发
+	if status := record(cycleErr); status.JustDisabled {
+		t.Fatalf("disable should only trigger once, got %+v", status)
+	}
+
+	// 重新启动后重新计数
+	if err := db.UpdateTraderStatus(userID, tr.ID, true, ""); err != nil {
+		t.Fatalf("UpdateTraderStatus failed: %v", err)
+	}
+	if status := record(cycleErr); status.Failures != 1 || status.JustDisabled {
+		t.Fatalf("restart should reset failures, got %+v", status)
+	}
+
+	if _, err := db.RecordCycleResult("missing", cycleErr); err == nil {
+		t.Fatal("expected error for unknown trader")
+	}
+}
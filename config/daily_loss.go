@@ -0,0 +1,137 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// StopReasonDailyLoss 触发日内最大亏损熔断时记录的停止原因前缀
+const StopReasonDailyLoss = "daily_loss_limit"
+
+// DailyLossStatus 交易员当日盈亏状态
+type DailyLossStatus struct {
+	TraderID      string  `json:"trader_id"`
+	UserID        string  `json:"user_id"`
+	TradingDay    string  `json:"trading_day"`    // 交易日（按交易员时区和 daily_reset_hour_utc 切分）
+	StartEquity   float64 `json:"start_equity"`   // 当日基准净值
+	CurrentEquity float64 `json:"current_equity"` // 最新净值
+	PnL           float64 `json:"pnl"`            // 当日盈亏（USDT）
+	PnLPct        float64 `json:"pnl_pct"`        // 当日盈亏百分比
+	LimitPct      float64 `json:"limit_pct"`      // max_daily_loss 配置
+	Breached      bool    `json:"breached"`       // 当日是否已触发熔断
+	JustBreached  bool    `json:"-"`              // 本次记录是否首次触发熔断
+}
+
+// DailyLossBreachFunc 日内最大亏损熔断回调（用于发送通知）
+type DailyLossBreachFunc func(status DailyLossStatus)
+
+// logDailyLossBreach 默认熔断通知：输出醒目日志
+func logDailyLossBreach(status DailyLossStatus) {
+	log.Printf("🚨 [熔断] 交易员 %s 当日亏损 %.2f%% (%.2f USDT) 超过上限 %.2f%%，已停止运行",
+		status.TraderID, -status.PnLPct, status.PnL, status.LimitPct)
+}
+
+// SetDailyLossBreachHandler 设置日内最大亏损熔断通知回调，nil 时恢复默认日志通知
+func (d *Database) SetDailyLossBreachHandler(fn DailyLossBreachFunc) {
+	if fn == nil {
+		fn = logDailyLossBreach
+	}
+	d.dailyLossBreach = fn
+}
+
+// tradingDay 计算交易日：每天在交易员时区的 resetHour 点切换（未配置时区时为 UTC）
+func tradingDay(now time.Time, resetHour int, loc *time.Location) string {
+	return now.In(loc).Add(-time.Duration(resetHour) * time.Hour).Format("2006-01-02")
+}
+
+// getDailyLossSettings 读取熔断配置（max_daily_loss 百分比、重置小时）
+func (d *Database) getDailyLossSettings() (limitPct float64, resetHour int) {
+	if val, err := d.GetSystemConfig("max_daily_loss"); err == nil {
+		limitPct, _ = strconv.ParseFloat(val, 64)
+	}
+	if val, err := d.GetSystemConfig("daily_reset_hour_utc"); err == nil {
+		if h, err := strconv.Atoi(val); err == nil && h >= 0 && h < 24 {
+			resetHour = h
+		}
+	}
+	return limitPct, resetHour
+}
+
+// RecordDailyEquity 记录交易员最新净值（同时写入净值快照）并检查日内最大亏损
+// 当日首次记录的净值作为基准；亏损百分比达到 max_daily_loss 时
+// 将交易员置为 is_running=0、写入 stop_reason 并触发熔断通知（每个交易日只触发一次）
+func (d *Database) RecordDailyEquity(traderID string, equity float64) (*DailyLossStatus, error) {
+	return d.recordDailyEquity(traderID, equity, time.Now())
+}
+
+func (d *Database) recordDailyEquity(traderID string, equity float64, now time.Time) (*DailyLossStatus, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	limitPct, resetHour := d.getDailyLossSettings()
+	day := tradingDay(now, resetHour, d.getTraderLocation(traderID))
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO trader_daily_pnl (trader_id, trading_day, start_equity, current_equity)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(trader_id, trading_day) DO UPDATE SET
+			current_equity = excluded.current_equity, updated_at = CURRENT_TIMESTAMP
+	`, traderID, day, equity, equity)
+	if err != nil {
+		return nil, fmt.Errorf("记录每日净值失败: %w", err)
+	}
+	if err := recordEquitySnapshotTx(tx, traderID, equity, now); err != nil {
+		return nil, err
+	}
+
+	status := &DailyLossStatus{TraderID: traderID, TradingDay: day, CurrentEquity: equity, LimitPct: limitPct}
+	err = tx.QueryRow(`
+		SELECT p.start_equity, p.breached, t.user_id
+		FROM trader_daily_pnl p JOIN traders t ON t.id = p.trader_id
+		WHERE p.trader_id = ? AND p.trading_day = ?
+	`, traderID, day).Scan(&status.StartEquity, &status.Breached, &status.UserID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("交易员不存在: %s", traderID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取每日盈亏失败: %w", err)
+	}
+
+	status.PnL = equity - status.StartEquity
+	if status.StartEquity > 0 {
+		status.PnLPct = status.PnL / status.StartEquity * 100
+	}
+
+	// 当日已熔断时（例如熔断后当天被手动重新启动）每次记录都再次停止交易员，只有首次触发时通知
+	if limitPct > 0 && (status.Breached || status.PnLPct <= -limitPct) {
+		reason := fmt.Sprintf("%s: 当日亏损 %.2f%% 超过上限 %.2f%%", StopReasonDailyLoss, -status.PnLPct, limitPct)
+		if !status.Breached {
+			if _, err := tx.Exec(`UPDATE trader_daily_pnl SET breached = 1 WHERE trader_id = ? AND trading_day = ?`, traderID, day); err != nil {
+				return nil, fmt.Errorf("更新熔断状态失败: %w", err)
+			}
+			status.Breached = true
+			status.JustBreached = true
+		}
+		if _, err := tx.Exec(`UPDATE traders SET is_running = 0, stop_reason = ? WHERE id = ?`, reason, traderID); err != nil {
+			return nil, fmt.Errorf("停止交易员失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	if status.JustBreached && d.dailyLossBreach != nil {
+		d.dailyLossBreach(*status)
+	}
+	return status, nil
+}
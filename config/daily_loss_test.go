@@ -0,0 +1,104 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTradingDayRespectsResetHour(t *testing.T) {
+	ts := time.Date(2025, 3, 10, 5, 30, 0, 0, time.UTC)
+	if got := tradingDay(ts, 0, time.UTC); got != "2025-03-10" {
+		t.Errorf("tradingDay(reset=0) = %s, want 2025-03-10", got)
+	}
+	if got := tradingDay(ts, 8, time.UTC); got != "2025-03-09" {
+		t.Errorf("tradingDay(reset=8) = %s, want 2025-03-09", got)
+	}
+}
+
+func TestTradingDayRespectsTimezone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	ts := time.Date(2025, 3, 10, 20, 0, 0, 0, time.UTC) // 上海时间 3月11日 04:00
+	if got := tradingDay(ts, 0, shanghai); got != "2025-03-11" {
+		t.Errorf("tradingDay(Asia/Shanghai) = %s, want 2025-03-11", got)
+	}
+	if got := tradingDay(ts, 8, shanghai); got != "2025-03-10" {
+		t.Errorf("tradingDay(Asia/Shanghai, reset=8) = %s, want 2025-03-10", got)
+	}
+}
+
+func TestRecordDailyEquity_BreachStopsTrader(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-dailyloss-1")
+	exID := ensureTestExchange(t, db, userID, "binance-dailyloss-1")
+	tr := &TraderRecord{
+		ID: "tr-dailyloss", UserID: userID, Name: "dailyloss", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, IsRunning: true, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	if err := db.SetSystemConfig("max_daily_loss", "5"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	var alerts []DailyLossStatus
+	db.SetDailyLossBreachHandler(func(status DailyLossStatus) {
+		alerts = append(alerts, status)
+	})
+
+	day1 := time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC)
+	if status, err := db.recordDailyEquity(tr.ID, 1000, day1); err != nil || status.Breached {
+		t.Fatalf("baseline record: status=%+v err=%v", status, err)
+	}
+	if status, err := db.recordDailyEquity(tr.ID, 960, day1.Add(time.Hour)); err != nil || status.Breached {
+		t.Fatalf("4%% loss should not breach: status=%+v err=%v", status, err)
+	}
+
+	status, err := db.recordDailyEquity(tr.ID, 940, day1.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("recordDailyEquity failed: %v", err)
+	}
+	if !status.JustBreached || status.PnL != -60 {
+		t.Fatalf("expected breach at -6%%, got %+v", status)
+	}
+
+	var isRunning bool
+	var stopReason string
+	if err := db.db.QueryRow(`SELECT is_running, stop_reason FROM traders WHERE id = ?`, tr.ID).Scan(&isRunning, &stopReason); err != nil {
+		t.Fatalf("query trader failed: %v", err)
+	}
+	if isRunning || !strings.HasPrefix(stopReason, StopReasonDailyLoss) {
+		t.Fatalf("trader should be stopped with daily loss reason, got running=%v reason=%q", isRunning, stopReason)
+	}
+
+	// 当天被重新启动后再次记录：仍然停止，但只通知一次
+	if _, err := db.db.Exec(`UPDATE traders SET is_running = 1, stop_reason = '' WHERE id = ?`, tr.ID); err != nil {
+		t.Fatalf("restart trader failed: %v", err)
+	}
+	status, err = db.recordDailyEquity(tr.ID, 900, day1.Add(3*time.Hour))
+	if err != nil || status.JustBreached || !status.Breached {
+		t.Fatalf("breach should only fire once per trading day but stay breached: status=%+v err=%v", status, err)
+	}
+	if err := db.db.QueryRow(`SELECT is_running, stop_reason FROM traders WHERE id = ?`, tr.ID).Scan(&isRunning, &stopReason); err != nil {
+		t.Fatalf("query trader failed: %v", err)
+	}
+	if isRunning || !strings.HasPrefix(stopReason, StopReasonDailyLoss) {
+		t.Fatalf("restarted trader should be stopped again, got running=%v reason=%q", isRunning, stopReason)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+
+	// 新交易日以新的净值为基准
+	status, err = db.recordDailyEquity(tr.ID, 900, day1.Add(24*time.Hour))
+	if err != nil || status.Breached || status.StartEquity != 900 {
+		t.Fatalf("new trading day should reset baseline: status=%+v err=%v", status, err)
+	}
+}
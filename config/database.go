@@ -1,16 +1,20 @@
 package config
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/base32"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"nofx/crypto"
 	"nofx/market"
 	"nofx/security"
 	"os"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
@@ -24,45 +28,133 @@ type DatabaseInterface interface {
 	CreateUser(user *User) error
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(userID string) (*User, error)
+	SetUserDisabled(userID string, disabled bool) error
+	IsUserDisabled(userID string) (bool, error)
 	GetAllUsers() ([]string, error)
 	UpdateUserOTPVerified(userID string, verified bool) error
+	DeleteUser(userID string) error
 	GetAIModels(userID string) ([]*AIModelConfig, error)
 	UpdateAIModel(userID, id string, enabled bool, apiKey, customAPIURL, customModelName string) error
+	SetAIModelCustomHeaders(userID, modelID string, headers map[string]string) error
+	SetAIModelRequestsPerMinute(userID, modelID string, rpm int) error
+	ValidateFallbackAIModels(userID string, primaryID int, ids []int) error
+	GetFallbackAIModels(userID string, ids []int) ([]*AIModelConfig, error)
 	GetExchanges(userID string) ([]*ExchangeConfig, error)
+	GetExchangesMetadata(userID string) ([]*ExchangeConfig, error)
 	UpdateExchange(userID, id string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error
+	RotateExchangeKeys(userID string, exchangeID int, newAPIKey, newSecret string) error
+	SeedDemoData() (*DemoCredentials, error)
+	RefreshSymbolUniverse() (map[string]int, error)
+	GetAuditLogByEntity(entityType, entityID string, since time.Time) ([]*AuditLogEntry, error)
+	ExportAuditLog(w io.Writer, format, entityType, entityID string, since, until time.Time) error
+	RecordAuditEvent(userID, entityType, entityID, action, detail string)
+	SetExchangeAccountMode(userID, exchangeID, mode string) error
 	CreateAIModel(userID, id, name, provider string, enabled bool, apiKey, customAPIURL string) error
 	CreateExchange(userID, id, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error
+	CreateExchangeAccount(userID, exchangeID, displayName, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) (int, error)
 	CreateTrader(trader *TraderRecord) error
 	GetTraders(userID string) ([]*TraderRecord, error)
-	UpdateTraderStatus(userID, id string, isRunning bool) error
+	GetAllRunningTraders() ([]*TraderRecord, error)
+	FindDuplicateTraders(userID string) ([][]*TraderRecord, error)
+	MergeTraders(userID, keepID string, duplicateIDs []string) error
+	CreatePromptTemplate(tpl *PromptTemplate) error
+	GetPromptTemplates(userID string) ([]*PromptTemplate, error)
+	GetPromptTemplateByName(userID, name string) (*PromptTemplate, error)
+	UpdatePromptTemplate(userID, name, body string) error
+	DeletePromptTemplate(userID, name string) error
+	GetTradersFiltered(userID string, opts TraderQueryOptions) ([]*TraderRecord, error)
+	ClaimNextDueTrader(instanceID string, leaseTTL time.Duration) (*TraderRecord, error)
+	GetDueTraders(limit int) ([]*TraderRecord, error)
+	ReleaseTraderLease(traderID, instanceID string) error
+	TouchTraderScan(traderID string) error
+	GetStalledTraders(threshold time.Duration) ([]*TraderRecord, error)
+	RecordDailyEquity(traderID string, equity float64) (*DailyLossStatus, error)
+	GetTraderDrawdown(userID, traderID string) (current, max float64, err error)
+	RecordTrade(trade *TradeRecord) error
+	GetTraderStats(userID, traderID string, since time.Time) (*TraderStats, error)
+	GetLossStreak(traderID string, since time.Time) (int, time.Time, error)
+	GetDailyPnL(userID, traderID string, since time.Time) ([]DailyPnL, error)
+	GetFeeBreakdown(userID, traderID string, since time.Time) (*FeeBreakdown, error)
+	GetRealizedPnL(userID, traderID string) (float64, error)
+	GetPnLBreakdown(userID, traderID string) (realized, unrealized float64, err error)
+	GetExchangeFeeRates(exchangeType string) ExchangeFeeRates
+	RecordCycleResult(traderID string, cycleErr error) (*CycleFailureStatus, error)
+	RecordTraderLog(traderID, level, message string) error
+	GetTraderLogs(traderID string, n int) ([]TraderLog, error)
+	RecordBalanceSnapshot(snapshot *BalanceSnapshot) error
+	GetBalanceHistory(userID, traderID string, since, until time.Time) ([]BalanceSnapshot, error)
+	GetAggregateExposure(userID string, live LivePositionFetcher) ([]*SymbolExposure, error)
+	GetPlatformStats() (*PlatformStats, error)
+	RecordLongShortHistory(symbol, period string, points []market.LongShortRatioPoint) (int, error)
+	GetLongShortHistory(symbol, period string, since time.Time) ([]market.LongShortRatioPoint, error)
+	RecordDecisionCycle(traderID, userID, userPrompt string, accountEquity float64) (int64, error)
+	RecordDecision(decision *Decision) error
+	GetLatestDecisions(userID, traderID string) (map[string]*Decision, error)
+	GetDecision(userID string, id int64) (*Decision, error)
+	RecordWebhookFailure(failure *WebhookFailure) error
+	GetWebhookFailures(userID string, limit int) ([]*WebhookFailure, error)
+	UpdateTraderStatus(userID, id string, isRunning bool, reason string) error
+	GetTraderIDsByStopReason(reason string) ([]string, error)
 	UpdateTrader(trader *TraderRecord) error
+	GetTraderConfigHistory(traderID string) ([]*TraderConfigSnapshot, error)
+	DiffTraderConfig(traderID string, fromTs, toTs time.Time) ([]TraderConfigChange, error)
+	GetProviderUsageStats() (*ProviderUsageStats, error)
+	SetProviderEnabled(userID, provider string, enabled bool) (int, error)
+	SetExchangeTypeEnabled(userID, exchangeID string, enabled bool) (int, error)
 	UpdateTraderInitialBalance(userID, id string, newBalance float64) error
 	UpdateTraderCustomPrompt(userID, id string, customPrompt string, overrideBase bool) error
 	DeleteTrader(userID, id string) error
 	GetTraderConfig(userID, traderID string) (*TraderRecord, *AIModelConfig, *ExchangeConfig, error)
 	GetSystemConfig(key string) (string, error)
 	SetSystemConfig(key, value string) error
+	SetSystemConfigBy(key, value, changedBy string) error
+	RenameSystemConfigKey(oldKey, newKey string) error
+	GetSystemConfigHistory(key string) ([]*ConfigHistoryEntry, error)
 	CreateUserSignalSource(userID, coinPoolURL, oiTopURL string) error
 	GetUserSignalSource(userID string) (*UserSignalSource, error)
 	UpdateUserSignalSource(userID, coinPoolURL, oiTopURL string) error
 	GetCustomCoins() []string
+	GetSymbolFilter() *SymbolFilter
 	GetAllTimeframes() []string
+	GetDefaultTimeframes() []string
 	LoadBetaCodesFromFile(filePath string) error
+	PreviewBetaCodeImport(filePath string) (newCodes []string, duplicate []string, invalid []string, err error)
 	ValidateBetaCode(code string) (bool, error)
 	UseBetaCode(code, userEmail string) error
+	SetBetaCodeUsesAllowed(code string, usesAllowed int) error
 	GetBetaCodeStats() (total, used int, err error)
+	GetDecryptFailureStats() DecryptFailureStats
 	Close() error
 }
 
 // Database 配置数据库
 type Database struct {
-	db            *sql.DB
-	dbPath        string // 數據庫文件路徑（用於備份等操作）
-	cryptoService *crypto.CryptoService
+	db               *sql.DB
+	dbPath           string // 數據庫文件路徑（用於備份等操作）
+	cryptoService    *crypto.CryptoService
+	decryptMonitor   *decryptFailureMonitor // 解密失败统计与告警
+	dailyLossBreach  DailyLossBreachFunc    // 日内最大亏损熔断通知
+	schemaChecks     schemaCheckCache       // 表结构检查结果缓存
+	symbolChecker    SymbolChecker          // 交易所币种校验（nil 表示不校验）
+	keyTester        ExchangeKeyTester      // 密钥轮换时的连接测试（nil 表示不允许轮换）
+	universeFetcher  SymbolUniverseFetcher  // 币种列表获取（nil 表示请求 Binance exchangeInfo）
+	markPriceFetcher MarkPriceFetcher       // 标记价格获取（nil 表示请求 Binance premiumIndex）
 }
 
-// NewDatabase 创建配置数据库
+// DatabaseOptions 数据库初始化选项
+type DatabaseOptions struct {
+	// SkipMigrations 仅连接数据库，不执行建表、遗留列清理、自增ID迁移和默认数据初始化
+	// 适用于生产环境：先手动备份，再显式调用 RunMigrations
+	SkipMigrations bool
+}
+
+// NewDatabase 创建配置数据库（自动执行迁移）
 func NewDatabase(dbPath string) (*Database, error) {
+	return NewDatabaseWithOptions(dbPath, DatabaseOptions{})
+}
+
+// NewDatabaseWithOptions 按选项创建配置数据库
+func NewDatabaseWithOptions(dbPath string, opts DatabaseOptions) (*Database, error) {
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("打开数据库失败: %w", err)
@@ -95,30 +187,48 @@ func NewDatabase(dbPath string) (*Database, error) {
 	}
 
 	database := &Database{
-		db:     db,
-		dbPath: dbPath,
+		db:              db,
+		dbPath:          dbPath,
+		decryptMonitor:  newDecryptFailureMonitor(),
+		dailyLossBreach: logDailyLossBreach,
+	}
+	if opts.SkipMigrations {
+		log.Printf("⚠️  已跳过数据库自动迁移，请在备份后手动调用 RunMigrations")
+	} else if err := database.RunMigrations(); err != nil {
+		return nil, err
 	}
-	if err := database.createTables(); err != nil {
-		return nil, fmt.Errorf("创建表失败: %w", err)
+
+	log.Printf("✅ 数据库已启用 WAL 模式、FULL 同步和外键约束,数据完整性得到保证")
+	return database, nil
+}
+
+// RunMigrations 执行建表、遗留列清理、自增ID迁移和默认数据初始化（可重复执行）
+func (d *Database) RunMigrations() error {
+	defer d.schemaChecks.reset()
+
+	if err := d.createTables(); err != nil {
+		return fmt.Errorf("创建表失败: %w", err)
 	}
 
 	// Automatically cleanup legacy _old columns for smooth upgrades
-	if err := database.cleanupLegacyColumns(); err != nil {
-		return nil, fmt.Errorf("清理遗留列失败: %w", err)
+	if err := d.cleanupLegacyColumns(); err != nil {
+		return fmt.Errorf("清理遗留列失败: %w", err)
 	}
 
 	// 檢查數據庫完整性（外鍵約束）
 	// 這個檢查不會中斷啟動，只記錄警告
-	if err := database.checkDataIntegrity(); err != nil {
+	if err := d.checkDataIntegrity(); err != nil {
 		log.Printf("⚠️  數據完整性檢查出現問題（不影響啟動）: %v", err)
 	}
 
-	if err := database.initDefaultData(); err != nil {
-		return nil, fmt.Errorf("初始化默认数据失败: %w", err)
+	if err := d.initDefaultData(); err != nil {
+		return fmt.Errorf("初始化默认数据失败: %w", err)
 	}
 
-	log.Printf("✅ 数据库已启用 WAL 模式、FULL 同步和外键约束,数据完整性得到保证")
-	return database, nil
+	if version, err := d.SchemaVersion(); err == nil && version < currentSchemaVersion {
+		log.Printf("⚠️  数据库结构版本 %d 低于当前版本 %d，部分迁移未完成", version, currentSchemaVersion)
+	}
+	return nil
 }
 
 // createTables 创建数据库表
@@ -136,6 +246,8 @@ func (d *Database) createTables() error {
 			api_key TEXT DEFAULT '',
 			custom_api_url TEXT DEFAULT '',
 			custom_model_name TEXT DEFAULT '',
+			custom_headers TEXT DEFAULT '',
+			requests_per_minute INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -159,6 +271,11 @@ func (d *Database) createTables() error {
 			aster_user TEXT DEFAULT '',
 			aster_signer TEXT DEFAULT '',
 			aster_private_key TEXT DEFAULT '',
+			-- 币安账户类型: standard / portfolio_margin
+			account_mode TEXT DEFAULT 'standard',
+			-- 密钥轮换暂存（测试通过后才替换 api_key/secret_key）
+			pending_api_key TEXT DEFAULT '',
+			pending_secret_key TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -185,6 +302,7 @@ func (d *Database) createTables() error {
 			exchange_id INTEGER NOT NULL,
 			initial_balance REAL NOT NULL,
 			scan_interval_minutes INTEGER DEFAULT 3,
+			scan_interval_seconds INTEGER DEFAULT 0,
 			is_running BOOLEAN DEFAULT 0,
 			btc_eth_leverage INTEGER DEFAULT 5,
 			altcoin_leverage INTEGER DEFAULT 5,
@@ -198,9 +316,28 @@ func (d *Database) createTables() error {
 			taker_fee_rate REAL DEFAULT 0.0004,
 			maker_fee_rate REAL DEFAULT 0.0002,
 			order_strategy TEXT DEFAULT 'conservative_hybrid',
+			btc_eth_order_strategy TEXT DEFAULT '',
+			altcoin_order_strategy TEXT DEFAULT '',
 			limit_price_offset REAL DEFAULT -0.03,
 			limit_timeout_seconds INTEGER DEFAULT 60,
 			timeframes TEXT DEFAULT '4h',
+			last_scanned_at DATETIME,
+			leased_until DATETIME,
+			leased_by TEXT DEFAULT '',
+			stop_reason TEXT DEFAULT '',
+			fallback_ai_model_ids TEXT DEFAULT '',
+			loss_streak_threshold INTEGER DEFAULT 0,
+			cooldown_minutes INTEGER DEFAULT 60,
+			prompt_template_name TEXT DEFAULT '',
+			max_orders_per_cycle INTEGER DEFAULT 0,
+			trailing_stop_percent REAL DEFAULT 0,
+			trailing_activation_percent REAL DEFAULT 0,
+			candle_lookback TEXT DEFAULT '',
+			symbol_weights TEXT DEFAULT '',
+			tp_percent REAL DEFAULT 0,
+			sl_percent REAL DEFAULT 0,
+			timezone TEXT DEFAULT '',
+			consecutive_failures INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -215,6 +352,7 @@ func (d *Database) createTables() error {
 			password_hash TEXT NOT NULL,
 			otp_secret TEXT,
 			otp_verified BOOLEAN DEFAULT 0,
+			disabled BOOLEAN DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
@@ -232,8 +370,198 @@ func (d *Database) createTables() error {
 			used BOOLEAN DEFAULT 0,
 			used_by TEXT DEFAULT '',
 			used_at DATETIME DEFAULT NULL,
+			uses_allowed INTEGER DEFAULT 1,
+			uses_count INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// 系统配置变更历史表
+		`CREATE TABLE IF NOT EXISTS config_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key TEXT NOT NULL,
+			old_value TEXT,
+			new_value TEXT,
+			changed_by TEXT DEFAULT '',
+			note TEXT DEFAULT '',
+			changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_config_history_key ON config_history(key)`,
+
+		// 成交记录表（开仓/平仓，用于统计胜率、盈亏、手续费）
+		`CREATE TABLE IF NOT EXISTS trades (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT 'default',
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,   -- 'long' or 'short'
+			action TEXT NOT NULL, -- 'open' or 'close'
+			quantity REAL DEFAULT 0,
+			price REAL DEFAULT 0,
+			realized_pnl REAL DEFAULT 0,
+			fee REAL DEFAULT 0,
+			order_id TEXT DEFAULT '',
+			client_order_id TEXT DEFAULT '', -- 幂等下单使用的 clientOrderId
+			liquidity TEXT DEFAULT '',       -- 'maker' or 'taker'（旧记录为空，按 taker 统计）
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trades_trader_created ON trades(trader_id, created_at)`,
+
+		// AI决策记录表（每个周期每个币种的决策及执行结果）
+		`CREATE TABLE IF NOT EXISTS decisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT 'default',
+			symbol TEXT NOT NULL,
+			action TEXT NOT NULL,
+			leverage INTEGER DEFAULT 0,
+			position_size_usd REAL DEFAULT 0,
+			stop_loss REAL DEFAULT 0,
+			take_profit REAL DEFAULT 0,
+			confidence INTEGER DEFAULT 0,
+			reasoning TEXT DEFAULT '',
+			success BOOLEAN DEFAULT 0,
+			error TEXT DEFAULT '',
+			client_order_id TEXT DEFAULT '',
+			cycle_id INTEGER DEFAULT 0, -- 所属决策周期（decision_cycles.id）
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_decisions_trader_symbol_created ON decisions(trader_id, symbol, created_at)`,
+
+		// 决策周期：每个周期发送给AI的输入只保存一份，该周期各币种的决策通过 cycle_id 引用（用于重放）
+		`CREATE TABLE IF NOT EXISTS decision_cycles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT 'default',
+			user_prompt TEXT DEFAULT '', -- 本周期发送给AI的输入prompt
+			account_equity REAL DEFAULT 0, -- 决策时的账户净值（重建系统prompt使用）
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+
+		// 交易员重要日志（警告/错误），用于重启后仍可查看最近的运行情况
+		`CREATE TABLE IF NOT EXISTS trader_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			level TEXT NOT NULL DEFAULT 'info', -- 'info', 'warn' or 'error'
+			message TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trader_logs_trader ON trader_logs(trader_id, id)`,
+
+		// 交易所返回的账户余额快照（用于与交易所账单对账，区别于 equity_snapshots 的净值计算）
+		`CREATE TABLE IF NOT EXISTS account_balance_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			wallet_balance REAL DEFAULT 0,
+			available_balance REAL DEFAULT 0,
+			unrealized_pnl REAL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_account_balance_history_trader ON account_balance_history(trader_id, created_at)`,
+
+		// 数据库结构版本（单行，迁移完成后更新）
+		`CREATE TABLE IF NOT EXISTS schema_version (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			version INTEGER NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// 交易员配置快照表（每次创建/更新时记录完整配置，用于追溯配置变更）
+		`CREATE TABLE IF NOT EXISTS trader_config_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT 'default',
+			snapshot TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trader_config_history_trader ON trader_config_history(trader_id, created_at)`,
+
+		// 审计日志表（按实体追溯交易员、交易所、用户的全部操作）
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL DEFAULT '',
+			entity_type TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			detail TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, created_at)`,
+
+		// webhook 失败记录表（死信日志，用于排查与重试）
+		`CREATE TABLE IF NOT EXISTS webhook_failures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL DEFAULT '',
+			user_id TEXT NOT NULL DEFAULT '',
+			payload TEXT NOT NULL,
+			error TEXT DEFAULT '',
+			retry_count INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_failures_user ON webhook_failures(user_id, id)`,
+
+		// 交易员每日盈亏表（用于日内最大亏损熔断）
+		`CREATE TABLE IF NOT EXISTS trader_daily_pnl (
+			trader_id TEXT NOT NULL,
+			trading_day TEXT NOT NULL,
+			start_equity REAL NOT NULL,
+			current_equity REAL NOT NULL,
+			breached BOOLEAN DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (trader_id, trading_day),
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+
+		// 提示词模板表（用户级可复用模板，交易员按名称引用，支持 {{变量}} 占位符）
+		`CREATE TABLE IF NOT EXISTS prompt_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			body TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)`,
+
+		// 交易所币种列表（RefreshSymbolUniverse 每日刷新，记录上架/暂停/下架状态）
+		`CREATE TABLE IF NOT EXISTS symbols (
+			exchange TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			status TEXT NOT NULL,
+			base_asset TEXT DEFAULT '',
+			quote_asset TEXT DEFAULT '',
+			first_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (exchange, symbol)
+		)`,
+
+		// 多空持仓人数比历史（RecordLongShortHistory 写入，用于回测情绪信号）
+		`CREATE TABLE IF NOT EXISTS long_short_history (
+			symbol TEXT NOT NULL,
+			period TEXT NOT NULL,
+			timestamp DATETIME NOT NULL,
+			long_short_ratio REAL NOT NULL,
+			long_account REAL DEFAULT 0,
+			short_account REAL DEFAULT 0,
+			PRIMARY KEY (symbol, period, timestamp)
+		)`,
+
+		// 交易员净值快照表（每个周期记录一次，用于计算回撤）
+		`CREATE TABLE IF NOT EXISTS equity_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trader_id TEXT NOT NULL,
+			equity REAL NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (trader_id) REFERENCES traders(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_equity_snapshots_trader_created ON equity_snapshots(trader_id, created_at)`,
 
 		// 触发器：自动更新 updated_at
 		`CREATE TRIGGER IF NOT EXISTS update_users_updated_at
@@ -285,6 +613,10 @@ func (d *Database) createTables() error {
 		`ALTER TABLE exchanges ADD COLUMN aster_user TEXT DEFAULT ''`,
 		`ALTER TABLE exchanges ADD COLUMN aster_signer TEXT DEFAULT ''`,
 		`ALTER TABLE exchanges ADD COLUMN aster_private_key TEXT DEFAULT ''`,
+		`ALTER TABLE exchanges ADD COLUMN account_mode TEXT DEFAULT 'standard'`,
+		`ALTER TABLE users ADD COLUMN disabled BOOLEAN DEFAULT 0`, // 禁用用户（保留数据，拒绝登录和访问）
+		`ALTER TABLE exchanges ADD COLUMN pending_api_key TEXT DEFAULT ''`,
+		`ALTER TABLE exchanges ADD COLUMN pending_secret_key TEXT DEFAULT ''`,
 		`ALTER TABLE traders ADD COLUMN custom_prompt TEXT DEFAULT ''`,
 		`ALTER TABLE traders ADD COLUMN override_base_prompt BOOLEAN DEFAULT 0`,
 		`ALTER TABLE traders ADD COLUMN is_cross_margin BOOLEAN DEFAULT 1`,                 // 默认为全仓模式
@@ -302,8 +634,36 @@ func (d *Database) createTables() error {
 		`ALTER TABLE traders ADD COLUMN limit_price_offset REAL DEFAULT -0.03`,             // Limit order price offset percentage (e.g., -0.03 for -0.03%)
 		`ALTER TABLE traders ADD COLUMN limit_timeout_seconds INTEGER DEFAULT 60`,          // Timeout in seconds before converting to market order
 		`ALTER TABLE traders ADD COLUMN timeframes TEXT DEFAULT '4h'`,                      // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+		`ALTER TABLE traders ADD COLUMN btc_eth_order_strategy TEXT DEFAULT ''`,            // BTC/ETH订单策略（为空时使用 order_strategy）
+		`ALTER TABLE traders ADD COLUMN altcoin_order_strategy TEXT DEFAULT ''`,            // 山寨币订单策略（为空时使用 order_strategy）
+		`ALTER TABLE traders ADD COLUMN scan_interval_seconds INTEGER DEFAULT 0`,           // 扫描间隔（秒），0表示使用 scan_interval_minutes
+		`ALTER TABLE traders ADD COLUMN last_scanned_at DATETIME`,                          // 最近一次被认领扫描的时间
+		`ALTER TABLE traders ADD COLUMN leased_until DATETIME`,                             // 扫描租约到期时间（多实例协同）
+		`ALTER TABLE traders ADD COLUMN leased_by TEXT DEFAULT ''`,                         // 持有扫描租约的实例ID
+		`ALTER TABLE traders ADD COLUMN stop_reason TEXT DEFAULT ''`,                       // 最近一次停止的原因
+		`ALTER TABLE traders ADD COLUMN fallback_ai_model_ids TEXT DEFAULT ''`,             // 备用AI模型ID列表（JSON数组，按顺序故障转移）
+		`ALTER TABLE traders ADD COLUMN loss_streak_threshold INTEGER DEFAULT 0`,           // 连续亏损N笔后进入冷却，0表示关闭
+		`ALTER TABLE traders ADD COLUMN cooldown_minutes INTEGER DEFAULT 60`,               // 连续亏损冷却时长（分钟）
+		`ALTER TABLE traders ADD COLUMN prompt_template_name TEXT DEFAULT ''`,              // 引用的数据库提示词模板名称（prompt_templates.name）
+		`ALTER TABLE traders ADD COLUMN max_orders_per_cycle INTEGER DEFAULT 0`,            // 单个决策周期最多下单数（0表示不限制）
+		`ALTER TABLE traders ADD COLUMN trailing_stop_percent REAL DEFAULT 0`,              // 移动止损回撤百分比（0表示关闭）
+		`ALTER TABLE traders ADD COLUMN trailing_activation_percent REAL DEFAULT 0`,        // 移动止损激活所需浮盈百分比
+		`ALTER TABLE traders ADD COLUMN candle_lookback TEXT DEFAULT ''`,                   // 各时间线传给AI的K线数量（JSON对象）
+		`ALTER TABLE traders ADD COLUMN symbol_weights TEXT DEFAULT ''`,                    // 币种仓位权重（JSON对象）
+		`ALTER TABLE traders ADD COLUMN tp_percent REAL DEFAULT 0`,                         // OCO 止盈百分比（0表示使用AI给出的止盈价）
+		`ALTER TABLE traders ADD COLUMN sl_percent REAL DEFAULT 0`,                         // OCO 止损百分比（0表示使用AI给出的止损价）
+		`ALTER TABLE traders ADD COLUMN timezone TEXT DEFAULT ''`,                          // 交易员时区（IANA 名称），用于切分交易日，为空表示 UTC
+		`ALTER TABLE traders ADD COLUMN consecutive_failures INTEGER DEFAULT 0`,            // 连续失败的交易周期数
 		`ALTER TABLE ai_models ADD COLUMN custom_api_url TEXT DEFAULT ''`,                  // 自定义API地址
 		`ALTER TABLE ai_models ADD COLUMN custom_model_name TEXT DEFAULT ''`,               // 自定义模型名称
+		`ALTER TABLE ai_models ADD COLUMN custom_headers TEXT DEFAULT ''`,                  // 自定义请求头（JSON对象）
+		`ALTER TABLE ai_models ADD COLUMN requests_per_minute INTEGER DEFAULT 0`,           // 该模型每分钟请求上限（0表示使用全局 ai_model_rpm）
+		`ALTER TABLE trades ADD COLUMN client_order_id TEXT DEFAULT ''`,                    // 幂等下单使用的 clientOrderId
+		`ALTER TABLE trades ADD COLUMN liquidity TEXT DEFAULT ''`,                          // 成交方式 maker/taker
+		`ALTER TABLE decisions ADD COLUMN client_order_id TEXT DEFAULT ''`,                 // 幂等下单使用的 clientOrderId
+		`ALTER TABLE decisions ADD COLUMN cycle_id INTEGER DEFAULT 0`,                      // 所属决策周期（decision_cycles.id）
+		`ALTER TABLE beta_codes ADD COLUMN uses_allowed INTEGER DEFAULT 1`,                 // 内测码可注册次数
+		`ALTER TABLE beta_codes ADD COLUMN uses_count INTEGER DEFAULT 0`,                   // 内测码已注册次数
 	}
 
 	for _, query := range alterQueries {
@@ -311,16 +671,27 @@ func (d *Database) createTables() error {
 		d.db.Exec(query)
 	}
 
+	// 回填秒级扫描间隔（保留原分钟配置: minutes*60）
+	d.db.Exec(`UPDATE traders SET scan_interval_seconds = scan_interval_minutes * 60
+		WHERE COALESCE(scan_interval_seconds, 0) = 0 AND COALESCE(scan_interval_minutes, 0) > 0`)
+
+	// 回填内测码使用次数（旧的单次内测码只有 used 标记）
+	d.db.Exec(`UPDATE beta_codes SET uses_count = 1 WHERE used = 1 AND COALESCE(uses_count, 0) = 0`)
+
 	// 检查是否需要迁移exchanges表的主键结构
 	err := d.migrateExchangesTable()
 	if err != nil {
 		log.Printf("⚠️ 迁移exchanges表失败: %v", err)
+	} else if err := d.setSchemaVersion(schemaVersionMultiUserExchanges); err != nil {
+		log.Printf("⚠️ %v", err)
 	}
 
 	// 迁移到自增ID结构（支持多配置）
 	err = d.migrateToAutoIncrementID()
 	if err != nil {
 		log.Printf("⚠️ 迁移自增ID失败: %v", err)
+	} else if err := d.setSchemaVersion(schemaVersionAutoIncrementID); err != nil {
+		log.Printf("⚠️ %v", err)
 	}
 
 	// 🔒 添加 UNIQUE 約束防止重複配置
@@ -329,9 +700,10 @@ func (d *Database) createTables() error {
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_ai_models_user_model
 		 ON ai_models(user_id, model_id)`,
 
-		// exchanges: 同一用戶不能有重複的 exchange_id
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_exchanges_user_exchange
-		 ON exchanges(user_id, exchange_id)`,
+		// exchanges: 同一用戶同類型可以有多個賬戶，以 display_name 區分（舊索引限制每種交易所一個賬戶，需先刪除）
+		`DROP INDEX IF EXISTS idx_exchanges_user_exchange`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_exchanges_user_exchange_name
+		 ON exchanges(user_id, exchange_id, display_name)`,
 	}
 
 	for _, query := range uniqueConstraints {
@@ -346,6 +718,14 @@ func (d *Database) createTables() error {
 
 // initDefaultData 初始化默认数据
 func (d *Database) initDefaultData() error {
+	// 确保default用户存在（ai_models/exchanges 的 user_id 外键引用 users 表）
+	if _, err := d.db.Exec(`
+		INSERT OR IGNORE INTO users (id, email, password_hash, otp_secret, otp_verified)
+		VALUES ('default', 'default@localhost', '', '', 1)
+	`); err != nil {
+		return fmt.Errorf("初始化default用户失败: %w", err)
+	}
+
 	// 初始化AI模型（使用default用户）
 	// 注意：遷移到自增 ID 後，需要使用 model_id 而不是 id
 	aiModels := []struct {
@@ -356,11 +736,7 @@ func (d *Database) initDefaultData() error {
 	}
 
 	// 檢查表結構，判斷是否已遷移到自增ID結構
-	var hasModelIDColumn int
-	err := d.db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('ai_models')
-		WHERE name = 'model_id'
-	`).Scan(&hasModelIDColumn)
+	hasModelIDColumn, err := d.hasModelIDColumn()
 	if err != nil {
 		return fmt.Errorf("检查ai_models表结构失败: %w", err)
 	}
@@ -368,7 +744,7 @@ func (d *Database) initDefaultData() error {
 	for _, model := range aiModels {
 		var count int
 
-		if hasModelIDColumn > 0 {
+		if hasModelIDColumn {
 			// 新結構：使用 model_id
 			err = d.db.QueryRow(`
 				SELECT COUNT(*) FROM ai_models
@@ -432,11 +808,7 @@ func (d *Database) initDefaultData() error {
 	}
 
 	// 檢查表結構，判斷是否已遷移到自增ID結構
-	var hasExchangeIDColumn int
-	err = d.db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('exchanges')
-		WHERE name = 'exchange_id'
-	`).Scan(&hasExchangeIDColumn)
+	hasExchangeIDColumn, err := d.hasExchangeIDColumn()
 	if err != nil {
 		return fmt.Errorf("检查exchanges表结构失败: %w", err)
 	}
@@ -444,7 +816,7 @@ func (d *Database) initDefaultData() error {
 	for _, exchange := range exchanges {
 		var count int
 
-		if hasExchangeIDColumn > 0 {
+		if hasExchangeIDColumn {
 			// 新結構：使用 exchange_id
 			err = d.db.QueryRow(`
 				SELECT COUNT(*) FROM exchanges
@@ -487,17 +859,32 @@ func (d *Database) initDefaultData() error {
 
 	// 初始化系统配置 - 创建所有字段，设置默认值，后续由config.json同步更新
 	systemConfigs := map[string]string{
-		"beta_mode":            "false",                                                                               // 默认关闭内测模式
-		"api_server_port":      "8080",                                                                                // 默认API端口
-		"use_default_coins":    "true",                                                                                // 默认使用内置币种列表
-		"default_coins":        `["BTCUSDT","ETHUSDT","SOLUSDT","BNBUSDT","XRPUSDT","DOGEUSDT","ADAUSDT","HYPEUSDT"]`, // 默认币种列表（JSON格式）
-		"max_daily_loss":       "10.0",                                                                                // 最大日损失百分比
-		"max_drawdown":         "20.0",                                                                                // 最大回撤百分比
-		"stop_trading_minutes": "60",                                                                                  // 停止交易时间（分钟）
-		"btc_eth_leverage":     "5",                                                                                   // BTC/ETH杠杆倍数
-		"altcoin_leverage":     "5",                                                                                   // 山寨币杠杆倍数
-		"jwt_secret":           "",                                                                                    // JWT密钥，默认为空，由config.json或系统生成
-		"registration_enabled": "true",                                                                                // 默认允许注册
+		"beta_mode":                         "false",                                                                               // 默认关闭内测模式
+		"api_server_port":                   "8080",                                                                                // 默认API端口
+		"use_default_coins":                 "true",                                                                                // 默认使用内置币种列表
+		"default_coins":                     `["BTCUSDT","ETHUSDT","SOLUSDT","BNBUSDT","XRPUSDT","DOGEUSDT","ADAUSDT","HYPEUSDT"]`, // 默认币种列表（JSON格式）
+		"max_daily_loss":                    "10.0",                                                                                // 最大日损失百分比
+		"daily_reset_hour_utc":              "0",                                                                                   // 日盈亏重置时间（UTC小时，0-23）
+		"max_drawdown":                      "20.0",                                                                                // 最大回撤百分比
+		"stop_trading_minutes":              "60",                                                                                  // 停止交易时间（分钟）
+		"btc_eth_leverage":                  "5",                                                                                   // BTC/ETH杠杆倍数
+		"altcoin_leverage":                  "5",                                                                                   // 山寨币杠杆倍数
+		"jwt_secret":                        "",                                                                                    // JWT密钥，默认为空，由config.json或系统生成
+		"registration_enabled":              "true",                                                                                // 默认允许注册
+		"symbol_allowlist":                  "",                                                                                    // 系统级币种白名单（逗号分隔，为空表示不限制）
+		"symbol_denylist":                   "",                                                                                    // 系统级币种黑名单（逗号分隔，优先于白名单）
+		"ai_model_rpm":                      "0",                                                                                   // 每个AI模型配置每分钟最多请求次数的默认值（模型未设置 requests_per_minute 时使用），0表示不限制
+		"default_timeframes":                "4h",                                                                                  // 交易员未配置时间线时的默认值（逗号分隔）
+		"webhook_failure_keep":              "500",                                                                                 // 每个用户最多保留的webhook失败记录条数
+		"max_trader_symbols":                "30",                                                                                  // 单个交易员最多交易币种数（规范化去重后）
+		"sentiment_weights":                 "",                                                                                    // 情绪信号混合权重（JSON，例如 {"vix":0.3,"funding_rate":0.2}，为空使用默认）
+		"ai_max_concurrency":                "0",                                                                                   // 全局同时进行的AI请求上限，0表示不限制
+		"ai_slot_timeout_sec":               "30",                                                                                  // 等待全局AI并发名额的超时（秒），超时则延后到下个周期
+		"min_order_notional":                "0",                                                                                   // 开仓最小名义价值（USDT），与交易所规则取较大值，0表示只用交易所规则
+		"maintenance_window":                "",                                                                                    // 每日维护窗口（UTC，例如 02:00-02:30，逗号分隔多个），窗口内跳过定时周期，为空不启用
+		"min_available_margin":              "0",                                                                                   // 可用保证金下限（USDT），周期开始时低于该值自动停止交易员
+		"webhook_scale_in_window_minutes":   "0",                                                                                   // webhook 加仓窗口（分钟），窗口内同一币种的重复同向告警按加仓处理，0表示关闭
+		"webhook_scale_in_max_position_usd": "0",                                                                                   // webhook 加仓后单个币种单方向持仓名义价值上限（USDT），0表示关闭
 	}
 
 	for key, value := range systemConfigs {
@@ -515,6 +902,10 @@ func (d *Database) initDefaultData() error {
 
 // migrateExchangesTable 迁移exchanges表支持多用户
 func (d *Database) migrateExchangesTable() error {
+	if d.schemaAtLeast(schemaVersionMultiUserExchanges) {
+		return nil
+	}
+
 	// 检查表是否已经有 exchange_id 欄位（表示已經是新結構或已遷移）
 	var hasExchangeIDColumn int
 	err := d.db.QueryRow(`
@@ -620,6 +1011,10 @@ func (d *Database) migrateExchangesTable() error {
 
 // migrateToAutoIncrementID 迁移到自增ID结构（支持多配置）
 func (d *Database) migrateToAutoIncrementID() error {
+	if d.schemaAtLeast(schemaVersionAutoIncrementID) {
+		return nil
+	}
+
 	// 检查是否已经迁移过（通过检查 ai_models 表是否有 model_id 列）
 	var count int
 	err := d.db.QueryRow(`
@@ -638,6 +1033,11 @@ func (d *Database) migrateToAutoIncrementID() error {
 	log.Printf("🔄 开始迁移到自增ID结构（支持多配置）...")
 
 	// === 步骤0：创建自动备份 ===
+	// 磁盘已满时备份和迁移都可能失败，此时拒绝迁移，避免“迁移失败且没有备份”
+	if err := checkBackupDiskSpace(d.dbPath); err != nil {
+		log.Printf("❌ %v", err)
+		return err
+	}
 	backupPath, err := d.createDatabaseBackup("pre-autoincrement-migration")
 	if err != nil {
 		log.Printf("⚠️  创建备份失败: %v（继续迁移但风险較高）", err)
@@ -797,6 +1197,8 @@ func (d *Database) migrateAIModelsTable() error {
 			api_key TEXT DEFAULT '',
 			custom_api_url TEXT DEFAULT '',
 			custom_model_name TEXT DEFAULT '',
+			custom_headers TEXT DEFAULT '',
+			requests_per_minute INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -919,6 +1321,11 @@ func (d *Database) migrateExchangesTableToAutoIncrement() error {
 			aster_user TEXT DEFAULT '',
 			aster_signer TEXT DEFAULT '',
 			aster_private_key TEXT DEFAULT '',
+			-- 币安账户类型: standard / portfolio_margin
+			account_mode TEXT DEFAULT 'standard',
+			-- 密钥轮换暂存（测试通过后才替换 api_key/secret_key）
+			pending_api_key TEXT DEFAULT '',
+			pending_secret_key TEXT DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -1024,24 +1431,27 @@ type User struct {
 	PasswordHash string    `json:"-"` // 不返回到前端
 	OTPSecret    string    `json:"-"` // 不返回到前端
 	OTPVerified  bool      `json:"otp_verified"`
+	Disabled     bool      `json:"disabled"` // 已被禁用（保留数据，拒绝登录）
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // AIModelConfig AI模型配置
 type AIModelConfig struct {
-	ID              int       `json:"id"`       // 自增ID（主键）
-	ModelID         string    `json:"model_id"` // 模型类型ID（例如 "deepseek"）
-	UserID          string    `json:"user_id"`
-	DisplayName     string    `json:"display_name"` // 用户自定义显示名称
-	Name            string    `json:"name"`
-	Provider        string    `json:"provider"`
-	Enabled         bool      `json:"enabled"`
-	APIKey          string    `json:"apiKey"`
-	CustomAPIURL    string    `json:"customApiUrl"`
-	CustomModelName string    `json:"customModelName"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID                int       `json:"id"`       // 自增ID（主键）
+	ModelID           string    `json:"model_id"` // 模型类型ID（例如 "deepseek"）
+	UserID            string    `json:"user_id"`
+	DisplayName       string    `json:"display_name"` // 用户自定义显示名称
+	Name              string    `json:"name"`
+	Provider          string    `json:"provider"`
+	Enabled           bool      `json:"enabled"`
+	APIKey            string    `json:"apiKey"`
+	CustomAPIURL      string    `json:"customApiUrl"`
+	CustomModelName   string    `json:"customModelName"`
+	CustomHeaders     string    `json:"customHeaders"`     // 自定义HTTP请求头（JSON对象，键值均为字符串）
+	RequestsPerMinute int       `json:"requestsPerMinute"` // 该模型每分钟请求上限，0表示使用全局 ai_model_rpm
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // ExchangeConfig 交易所配置
@@ -1063,6 +1473,7 @@ type ExchangeConfig struct {
 	AsterUser       string    `json:"asterUser"`
 	AsterSigner     string    `json:"asterSigner"`
 	AsterPrivateKey string    `json:"asterPrivateKey"`
+	AccountMode     string    `json:"accountMode"` // 币安账户类型: standard / portfolio_margin
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
@@ -1076,6 +1487,7 @@ type TraderRecord struct {
 	ExchangeID           int       `json:"exchange_id"` // 外键：指向 exchanges.id
 	InitialBalance       float64   `json:"initial_balance"`
 	ScanIntervalMinutes  int       `json:"scan_interval_minutes"`
+	ScanIntervalSeconds  int       `json:"scan_interval_seconds"` // 扫描间隔（秒），优先于 ScanIntervalMinutes
 	IsRunning            bool      `json:"is_running"`
 	BTCETHLeverage       int       `json:"btc_eth_leverage"`       // BTC/ETH杠杆倍数
 	AltcoinLeverage      int       `json:"altcoin_leverage"`       // 山寨币杠杆倍数
@@ -1089,11 +1501,80 @@ type TraderRecord struct {
 	TakerFeeRate         float64   `json:"taker_fee_rate"`         // Taker fee rate, default 0.0004
 	MakerFeeRate         float64   `json:"maker_fee_rate"`         // Maker fee rate, default 0.0002
 	OrderStrategy        string    `json:"order_strategy"`         // Order strategy: "market_only", "conservative_hybrid", "limit_only"
+	BTCETHOrderStrategy  string    `json:"btc_eth_order_strategy"` // BTC/ETH订单策略（为空时使用 OrderStrategy）
+	AltcoinOrderStrategy string    `json:"altcoin_order_strategy"` // 山寨币订单策略（为空时使用 OrderStrategy）
 	LimitPriceOffset     float64   `json:"limit_price_offset"`     // Limit order price offset percentage (e.g., -0.03 for -0.03%)
 	LimitTimeoutSeconds  int       `json:"limit_timeout_seconds"`  // Timeout in seconds before converting to market order (default: 60)
 	Timeframes           string    `json:"timeframes"`             // 时间线选择 (逗号分隔，例如: "1m,4h,1d")
+	StopReason           string    `json:"stop_reason"`            // 最近一次停止的原因（运行中为空）
+	FallbackAIModelIDs   string    `json:"fallback_ai_model_ids"`  // 备用AI模型ID（JSON数组，例如 [3,5]）
+	LossStreakThreshold  int       `json:"loss_streak_threshold"`  // 连续亏损笔数阈值，达到后暂停交易（0表示关闭）
+	CooldownMinutes      int       `json:"cooldown_minutes"`       // 连续亏损后的冷却时长（分钟）
+	PromptTemplateName   string    `json:"prompt_template_name"`   // 引用的数据库提示词模板名称，为空表示不使用
+	MaxOrdersPerCycle    int       `json:"max_orders_per_cycle"`   // 单个决策周期最多执行的开仓动作数，平仓不计入（0表示不限制）
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
+
+	// 移动止损（按价格百分比计算，0表示关闭）
+	TrailingStopPercent float64 `json:"trailing_stop_percent"`       // 距最优价的回撤百分比
+	TrailingActivation  float64 `json:"trailing_activation_percent"` // 价格相对开仓价浮盈达到该百分比后激活
+
+	CandleLookback string `json:"candle_lookback"` // 各时间线传给AI的K线数量（JSON对象，例如 {"4h":30}），为空使用默认值
+	SymbolWeights  string `json:"symbol_weights"`  // 币种仓位权重（JSON对象，例如 {"BTCUSDT":2,"DOGEUSDT":0.5}），未列出的币种权重为1
+
+	// OCO 止盈止损（按开仓价百分比计算，0表示使用AI给出的止盈止损价）
+	TakeProfitPercent float64 `json:"tp_percent"`
+	StopLossPercent   float64 `json:"sl_percent"`
+
+	Timezone string `json:"timezone"` // 交易员时区（IANA 名称，例如 Asia/Shanghai），用于日内亏损熔断和按日统计，为空表示 UTC
+}
+
+// MinScanIntervalSeconds 扫描间隔下限（秒）
+const MinScanIntervalSeconds = 30
+
+// NormalizeScanInterval 根据秒/分钟配置计算扫描间隔（秒），并应用下限
+// seconds > 0 时优先使用秒；否则使用 minutes*60；两者都未设置时返回 defaultSeconds
+func NormalizeScanInterval(seconds, minutes, defaultSeconds int) int {
+	if seconds <= 0 {
+		seconds = minutes * 60
+	}
+	if seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	if seconds < MinScanIntervalSeconds {
+		seconds = MinScanIntervalSeconds
+	}
+	return seconds
+}
+
+// ErrInvalidMaxOrders 单周期最大下单数配置无效
+var ErrInvalidMaxOrders = errors.New("单周期最大下单数必须为正整数")
+
+// validateMaxOrdersPerCycle 校验单周期最大下单数：0 表示未设置（不限制），否则必须为正数
+func validateMaxOrdersPerCycle(n int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidMaxOrders, n)
+	}
+	return nil
+}
+
+// ErrInvalidTrailingStop 移动止损配置无效
+var ErrInvalidTrailingStop = errors.New("移动止损配置无效")
+
+// validateTrailingStop 校验移动止损百分比：均需在 [0, 100) 内，设置激活阈值时必须同时设置回撤百分比
+func validateTrailingStop(stopPercent, activationPercent float64) error {
+	if stopPercent < 0 || stopPercent >= 100 || activationPercent < 0 || activationPercent >= 100 {
+		return fmt.Errorf("%w: 百分比必须在 0-100 之间 (回撤 %.2f%%, 激活 %.2f%%)", ErrInvalidTrailingStop, stopPercent, activationPercent)
+	}
+	if activationPercent > 0 && stopPercent == 0 {
+		return fmt.Errorf("%w: 设置激活阈值时必须同时设置回撤百分比", ErrInvalidTrailingStop)
+	}
+	return nil
+}
+
+// ScanInterval 返回交易员的有效扫描间隔
+func (t *TraderRecord) ScanInterval() time.Duration {
+	return time.Duration(NormalizeScanInterval(t.ScanIntervalSeconds, t.ScanIntervalMinutes, 3*60)) * time.Second
 }
 
 // UserSignalSource 用户信号源配置
@@ -1155,11 +1636,11 @@ func (d *Database) EnsureAdminUser() error {
 func (d *Database) GetUserByEmail(email string) (*User, error) {
 	var user User
 	err := d.db.QueryRow(`
-		SELECT id, email, password_hash, otp_secret, otp_verified, created_at, updated_at
+		SELECT id, email, password_hash, otp_secret, otp_verified, COALESCE(disabled, 0), created_at, updated_at
 		FROM users WHERE email = ?
 	`, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.OTPSecret,
-		&user.OTPVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.OTPVerified, &user.Disabled, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -1171,11 +1652,11 @@ func (d *Database) GetUserByEmail(email string) (*User, error) {
 func (d *Database) GetUserByID(userID string) (*User, error) {
 	var user User
 	err := d.db.QueryRow(`
-		SELECT id, email, password_hash, otp_secret, otp_verified, created_at, updated_at
+		SELECT id, email, password_hash, otp_secret, otp_verified, COALESCE(disabled, 0), created_at, updated_at
 		FROM users WHERE id = ?
 	`, userID).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.OTPSecret,
-		&user.OTPVerified, &user.CreatedAt, &user.UpdatedAt,
+		&user.OTPVerified, &user.Disabled, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -1218,25 +1699,132 @@ func (d *Database) UpdateUserPassword(userID, passwordHash string) error {
 	return err
 }
 
+// StopReasonUserDisabled 用户被禁用时写入 traders.stop_reason
+const StopReasonUserDisabled = "user_disabled"
+
+// SetUserDisabled 禁用或启用用户（保留全部数据）
+// 禁用时在同一事务中停止该用户所有运行中的交易员（已暂停的交易员改记为禁用，不再能批量恢复）；启用时不会自动恢复交易员
+func (d *Database) SetUserDisabled(userID string, disabled bool) error {
+	if userID == "" || userID == "default" || userID == "admin" {
+		return fmt.Errorf("不能禁用系统用户: %q", userID)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE users SET disabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, disabled, userID)
+	if err != nil {
+		return fmt.Errorf("更新用户状态失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("用户不存在: %s", userID)
+	}
+
+	action := "enable"
+	if disabled {
+		action = "disable"
+		if _, err := tx.Exec(`
+			UPDATE traders SET is_running = 0, stop_reason = ?, leased_until = NULL, leased_by = ''
+			WHERE user_id = ? AND (is_running = 1 OR stop_reason = ?)
+		`, StopReasonUserDisabled, userID, StopReasonUserPaused); err != nil {
+			return fmt.Errorf("停止用户交易员失败: %w", err)
+		}
+	}
+	if err := recordAuditEvent(tx, userID, AuditEntityUser, userID, action, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// IsUserDisabled 查询用户是否已被禁用（用户不存在时返回 false）
+func (d *Database) IsUserDisabled(userID string) (bool, error) {
+	var disabled bool
+	err := d.db.QueryRow(`SELECT COALESCE(disabled, 0) FROM users WHERE id = ?`, userID).Scan(&disabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("查询用户状态失败: %w", err)
+	}
+	return disabled, nil
+}
+
+// deletedUserPlaceholder 删除用户后用于匿名化审计记录的占位符
+const deletedUserPlaceholder = "deleted_user"
+
+// DeleteUser 删除用户及其全部数据（AI模型、交易所、交易员、成交记录等依赖 ON DELETE CASCADE 一并删除）
+// 配置变更历史等审计记录不删除，只将操作人匿名化；调用前应先停止该用户在内存中运行的交易员
+func (d *Database) DeleteUser(userID string) error {
+	if userID == "" || userID == "default" {
+		return fmt.Errorf("不能删除系统用户: %q", userID)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var email string
+	err = tx.QueryRow(`SELECT email FROM users WHERE id = ?`, userID).Scan(&email)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("用户不存在: %s", userID)
+	}
+	if err != nil {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	// 先停止交易员，防止其他实例在删除过程中继续认领扫描
+	if _, err := tx.Exec(`UPDATE traders SET is_running = 0, leased_until = NULL, leased_by = '' WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("停止用户交易员失败: %w", err)
+	}
+
+	// 匿名化审计记录
+	if _, err := tx.Exec(`UPDATE config_history SET changed_by = ? WHERE changed_by IN (?, ?)`, deletedUserPlaceholder, userID, email); err != nil {
+		return fmt.Errorf("匿名化配置变更历史失败: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE beta_codes SET used_by = ? WHERE used_by = ?`, deletedUserPlaceholder, email); err != nil {
+		return fmt.Errorf("匿名化内测码记录失败: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE audit_log SET user_id = ? WHERE user_id = ?`, deletedUserPlaceholder, userID); err != nil {
+		return fmt.Errorf("匿名化审计日志失败: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM users WHERE id = ?`, userID); err != nil {
+		return fmt.Errorf("删除用户失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	log.Printf("🗑️ 已删除用户 %s 及其全部数据", userID)
+	return nil
+}
+
 // GetAIModels 获取用户的AI模型配置
 func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 	// 檢查表結構，判斷是否已遷移到自增ID結構
-	var hasModelIDColumn int
-	err := d.db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('ai_models')
-		WHERE name = 'model_id'
-	`).Scan(&hasModelIDColumn)
+	hasModelIDColumn, err := d.hasModelIDColumn()
 	if err != nil {
 		return nil, fmt.Errorf("检查ai_models表结构失败: %w", err)
 	}
 
 	var rows *sql.Rows
-	if hasModelIDColumn > 0 {
+	if hasModelIDColumn {
 		// 新結構：有 model_id 列
 		rows, err = d.db.Query(`
 			SELECT id, model_id, user_id, name, provider, enabled, api_key,
 			       COALESCE(custom_api_url, '') as custom_api_url,
 			       COALESCE(custom_model_name, '') as custom_model_name,
+			       COALESCE(custom_headers, '') as custom_headers,
+			       COALESCE(requests_per_minute, 0) as requests_per_minute,
 			       created_at, updated_at
 			FROM ai_models WHERE user_id = ? ORDER BY id
 		`, userID)
@@ -1246,6 +1834,8 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 			SELECT id, user_id, name, provider, enabled, api_key,
 			       COALESCE(custom_api_url, '') as custom_api_url,
 			       COALESCE(custom_model_name, '') as custom_model_name,
+			       COALESCE(custom_headers, '') as custom_headers,
+			       COALESCE(requests_per_minute, 0) as requests_per_minute,
 			       created_at, updated_at
 			FROM ai_models WHERE user_id = ? ORDER BY id
 		`, userID)
@@ -1259,20 +1849,20 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 	models := make([]*AIModelConfig, 0)
 	for rows.Next() {
 		var model AIModelConfig
-		if hasModelIDColumn > 0 {
+		if hasModelIDColumn {
 			// 新結構：掃描包含 model_id
 			err = rows.Scan(
 				&model.ID, &model.ModelID, &model.UserID, &model.Name, &model.Provider,
-				&model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName,
-				&model.CreatedAt, &model.UpdatedAt,
+				&model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName, &model.CustomHeaders,
+				&model.RequestsPerMinute, &model.CreatedAt, &model.UpdatedAt,
 			)
 		} else {
 			// 舊結構：id 直接映射到 ModelID（因為舊結構中 id 是業務邏輯 ID）
 			var idValue string
 			err = rows.Scan(
 				&idValue, &model.UserID, &model.Name, &model.Provider,
-				&model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName,
-				&model.CreatedAt, &model.UpdatedAt,
+				&model.Enabled, &model.APIKey, &model.CustomAPIURL, &model.CustomModelName, &model.CustomHeaders,
+				&model.RequestsPerMinute, &model.CreatedAt, &model.UpdatedAt,
 			)
 			// 舊結構中 id 是文本，直接用作業務邏輯 ID
 			model.ID = 0 // 舊結構沒有整數 ID
@@ -1281,8 +1871,9 @@ func (d *Database) GetAIModels(userID string) ([]*AIModelConfig, error) {
 		if err != nil {
 			return nil, err
 		}
-		// 解密API Key
+		// 解密API Key和自定义请求头
 		model.APIKey = d.decryptSensitiveData(model.APIKey)
+		model.CustomHeaders = d.decryptSensitiveData(model.CustomHeaders)
 		models = append(models, &model)
 	}
 
@@ -1295,23 +1886,19 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 		userID, id, enabled, len(apiKey), customAPIURL, customModelName)
 
 	// 檢查表結構，判斷是否已遷移到自增ID結構
-	var hasModelIDColumn int
-	err := d.db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('ai_models')
-		WHERE name = 'model_id'
-	`).Scan(&hasModelIDColumn)
+	hasModelIDColumn, err := d.hasModelIDColumn()
 	if err != nil {
 		log.Printf("❌ [AI Model] 檢查表結構失敗: %v", err)
 		return fmt.Errorf("检查ai_models表结构失败: %w", err)
 	}
-	log.Printf("   表結構檢查: hasModelIDColumn=%d (1=新結構, 0=舊結構)", hasModelIDColumn)
+	log.Printf("   表結構檢查: hasModelIDColumn=%v", hasModelIDColumn)
 
 	encryptedAPIKey := d.encryptSensitiveData(apiKey)
 	if apiKey != "" && encryptedAPIKey == "" {
 		log.Printf("⚠️  [AI Model] API Key 加密後為空！原始長度=%d", len(apiKey))
 	}
 
-	if hasModelIDColumn > 0 {
+	if hasModelIDColumn {
 		// ===== 新結構：有 model_id 列 =====
 		log.Printf("   使用新結構邏輯（有 model_id 列）")
 		// 先尝试精确匹配 model_id
@@ -1444,27 +2031,35 @@ func (d *Database) UpdateAIModel(userID, id string, enabled bool, apiKey, custom
 	}
 }
 
-// GetExchanges 获取用户的交易所配置
+// GetExchanges 获取用户的交易所配置（解密全部敏感字段，用于交易和连接测试）
 func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
+	return d.queryExchanges(userID, true)
+}
+
+// GetExchangesMetadata 获取用户的交易所配置但不解密敏感字段
+// api_key / secret_key / aster_private_key 置空，适用于只需要名称、ID、启用状态的场景（列表、ID映射等）
+func (d *Database) GetExchangesMetadata(userID string) ([]*ExchangeConfig, error) {
+	return d.queryExchanges(userID, false)
+}
+
+// queryExchanges 查询用户的交易所配置，decrypt=false 时敏感字段被清空而不解密
+func (d *Database) queryExchanges(userID string, decrypt bool) ([]*ExchangeConfig, error) {
 	// 檢查表結構，判斷是否已遷移到自增ID結構
-	var hasExchangeIDColumn int
-	err := d.db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('exchanges')
-		WHERE name = 'exchange_id'
-	`).Scan(&hasExchangeIDColumn)
+	hasExchangeIDColumn, err := d.hasExchangeIDColumn()
 	if err != nil {
 		return nil, fmt.Errorf("检查exchanges表结构失败: %w", err)
 	}
 
 	var rows *sql.Rows
-	if hasExchangeIDColumn > 0 {
+	if hasExchangeIDColumn {
 		// 新結構：有 exchange_id 列
 		rows, err = d.db.Query(`
-			SELECT id, exchange_id, user_id, name, type, enabled, api_key, secret_key, testnet,
+			SELECT id, exchange_id, user_id, COALESCE(display_name, '') as display_name, name, type, enabled, api_key, secret_key, testnet,
 			       COALESCE(hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
 			       COALESCE(aster_user, '') as aster_user,
 			       COALESCE(aster_signer, '') as aster_signer,
 			       COALESCE(aster_private_key, '') as aster_private_key,
+			       COALESCE(account_mode, 'standard') as account_mode,
 			       created_at, updated_at
 			FROM exchanges WHERE user_id = ? ORDER BY id
 		`, userID)
@@ -1476,6 +2071,7 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 			       COALESCE(aster_user, '') as aster_user,
 			       COALESCE(aster_signer, '') as aster_signer,
 			       COALESCE(aster_private_key, '') as aster_private_key,
+			       COALESCE(account_mode, 'standard') as account_mode,
 			       created_at, updated_at
 			FROM exchanges WHERE user_id = ? ORDER BY id
 		`, userID)
@@ -1489,13 +2085,13 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 	exchanges := make([]*ExchangeConfig, 0)
 	for rows.Next() {
 		var exchange ExchangeConfig
-		if hasExchangeIDColumn > 0 {
+		if hasExchangeIDColumn {
 			// 新結構：掃描包含 exchange_id
 			err = rows.Scan(
-				&exchange.ID, &exchange.ExchangeID, &exchange.UserID, &exchange.Name, &exchange.Type,
+				&exchange.ID, &exchange.ExchangeID, &exchange.UserID, &exchange.DisplayName, &exchange.Name, &exchange.Type,
 				&exchange.Enabled, &exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
 				&exchange.HyperliquidWalletAddr, &exchange.AsterUser,
-				&exchange.AsterSigner, &exchange.AsterPrivateKey,
+				&exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.AccountMode,
 				&exchange.CreatedAt, &exchange.UpdatedAt,
 			)
 		} else {
@@ -1505,7 +2101,7 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 				&idValue, &exchange.UserID, &exchange.Name, &exchange.Type,
 				&exchange.Enabled, &exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
 				&exchange.HyperliquidWalletAddr, &exchange.AsterUser,
-				&exchange.AsterSigner, &exchange.AsterPrivateKey,
+				&exchange.AsterSigner, &exchange.AsterPrivateKey, &exchange.AccountMode,
 				&exchange.CreatedAt, &exchange.UpdatedAt,
 			)
 			// 舊結構中 id 是文本，直接用作業務邏輯 ID
@@ -1516,10 +2112,14 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 			return nil, err
 		}
 
-		// 解密敏感字段
-		exchange.APIKey = d.decryptSensitiveData(exchange.APIKey)
-		exchange.SecretKey = d.decryptSensitiveData(exchange.SecretKey)
-		exchange.AsterPrivateKey = d.decryptSensitiveData(exchange.AsterPrivateKey)
+		if decrypt {
+			// 解密敏感字段
+			exchange.APIKey = d.decryptSensitiveData(exchange.APIKey)
+			exchange.SecretKey = d.decryptSensitiveData(exchange.SecretKey)
+			exchange.AsterPrivateKey = d.decryptSensitiveData(exchange.AsterPrivateKey)
+		} else {
+			exchange.APIKey, exchange.SecretKey, exchange.AsterPrivateKey = "", "", ""
+		}
 
 		exchanges = append(exchanges, &exchange)
 	}
@@ -1528,16 +2128,13 @@ func (d *Database) GetExchanges(userID string) ([]*ExchangeConfig, error) {
 }
 
 // UpdateExchange 更新交易所配置，如果不存在则创建用户特定配置
+// id 为数字时按 exchanges.id 更新指定账户（不存在时报错，不自动创建）；否则按交易所类型更新，同类型有多个账户时返回 ErrAmbiguousExchange
 // 🔒 安全特性：空值不会覆盖现有的敏感字段（api_key, secret_key, aster_private_key）
 func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error {
 	log.Printf("🔧 UpdateExchange: userID=%s, id=%s, enabled=%v", userID, id, enabled)
 
 	// 檢查表結構，判斷是否已遷移到自增ID結構
-	var hasExchangeIDColumn int
-	err := d.db.QueryRow(`
-		SELECT COUNT(*) FROM pragma_table_info('exchanges')
-		WHERE name = 'exchange_id'
-	`).Scan(&hasExchangeIDColumn)
+	hasExchangeIDColumn, err := d.hasExchangeIDColumn()
 	if err != nil {
 		return fmt.Errorf("检查exchanges表结构失败: %w", err)
 	}
@@ -1574,16 +2171,22 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 	}
 
 	// WHERE 条件：根據表結構選擇正確的列名
-	args = append(args, id, userID)
-
 	var query string
-	if hasExchangeIDColumn > 0 {
-		// 新結構：使用 exchange_id
+	byRowID := false
+	if hasExchangeIDColumn {
+		// 新結構：數字ID定位具體賬戶，否則使用 exchange_id
+		where, whereArgs, rowID, err := d.exchangeRowFilter(userID, id)
+		if err != nil {
+			return err
+		}
+		byRowID = rowID
+		args = append(args, whereArgs...)
 		query = fmt.Sprintf(`
 			UPDATE exchanges SET %s
-			WHERE exchange_id = ? AND user_id = ?
-		`, strings.Join(setClauses, ", "))
+			WHERE %s
+		`, strings.Join(setClauses, ", "), where)
 	} else {
+		args = append(args, id, userID)
 		// 舊結構：使用 id
 		query = fmt.Sprintf(`
 			UPDATE exchanges SET %s
@@ -1607,6 +2210,11 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 
 	log.Printf("📊 UpdateExchange: 影响行数 = %d", rowsAffected)
 
+	// 按数字ID更新时记录必须存在
+	if rowsAffected == 0 && byRowID {
+		return fmt.Errorf("交易所配置不存在: %s", id)
+	}
+
 	// 如果没有行被更新，说明用户没有这个交易所的配置，需要创建
 	if rowsAffected == 0 {
 		log.Printf("💡 UpdateExchange: 没有现有记录，创建新记录")
@@ -1635,7 +2243,7 @@ func (d *Database) UpdateExchange(userID, id string, enabled bool, apiKey, secre
 		encryptedSecretKey := d.encryptSensitiveData(secretKey)
 		encryptedAsterPrivateKey := d.encryptSensitiveData(asterPrivateKey)
 
-		if hasExchangeIDColumn > 0 {
+		if hasExchangeIDColumn {
 			// 新結構：使用 exchange_id 列
 			_, err = d.db.Exec(`
 				INSERT INTO exchanges (exchange_id, user_id, name, type, enabled, api_key, secret_key, testnet,
@@ -1672,33 +2280,70 @@ func (d *Database) CreateAIModel(userID, id, name, provider string, enabled bool
 	return err
 }
 
-// CreateExchange 创建交易所配置
+// CreateExchange 创建该交易所类型的默认账户（display_name 为空），已存在时返回 ErrExchangeAccountExists
+// 同类型的其他账户使用 CreateExchangeAccount 以不同的 display_name 创建
 func (d *Database) CreateExchange(userID, id, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) error {
-	// 加密敏感字段
-	encryptedAPIKey := d.encryptSensitiveData(apiKey)
-	encryptedSecretKey := d.encryptSensitiveData(secretKey)
-	encryptedAsterPrivateKey := d.encryptSensitiveData(asterPrivateKey)
-
-	_, err := d.db.Exec(`
-		INSERT OR IGNORE INTO exchanges (exchange_id, user_id, name, type, enabled, api_key, secret_key, testnet, hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, id, userID, name, typ, enabled, encryptedAPIKey, encryptedSecretKey, testnet, hyperliquidWalletAddr, asterUser, asterSigner, encryptedAsterPrivateKey)
+	_, err := d.CreateExchangeAccount(userID, id, "", name, typ, enabled, apiKey, secretKey, testnet, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey)
 	return err
 }
 
 // CreateTrader 创建交易员
 func (d *Database) CreateTrader(trader *TraderRecord) error {
-	_, err := d.db.Exec(`
-		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, taker_fee_rate, maker_fee_rate, order_strategy, limit_price_offset, limit_timeout_seconds, timeframes)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, trader.TakerFeeRate, trader.MakerFeeRate, trader.OrderStrategy, trader.LimitPriceOffset, trader.LimitTimeoutSeconds, trader.Timeframes)
-	return err
+	if trader.ScanIntervalSeconds <= 0 {
+		trader.ScanIntervalSeconds = trader.ScanIntervalMinutes * 60
+	}
+	if err := d.validateTraderSymbols(trader.TradingSymbols); err != nil {
+		return err
+	}
+	if err := d.validatePromptTemplateRef(trader.UserID, trader.PromptTemplateName); err != nil {
+		return err
+	}
+	if err := validateMaxOrdersPerCycle(trader.MaxOrdersPerCycle); err != nil {
+		return err
+	}
+	if err := validateTrailingStop(trader.TrailingStopPercent, trader.TrailingActivation); err != nil {
+		return err
+	}
+	if err := validateCandleLookback(trader.CandleLookback); err != nil {
+		return err
+	}
+	if err := validateSymbolWeights(trader.SymbolWeights); err != nil {
+		return err
+	}
+	if err := validateExitBracket(trader.TakeProfitPercent, trader.StopLossPercent); err != nil {
+		return err
+	}
+	if err := validateTimezone(trader.Timezone); err != nil {
+		return err
+	}
+	if err := d.validateTraderExchangeSymbols(trader.UserID, trader.ExchangeID, trader.TradingSymbols); err != nil {
+		return err
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO traders (id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, scan_interval_seconds, is_running, btc_eth_leverage, altcoin_leverage, trading_symbols, use_coin_pool, use_oi_top, custom_prompt, override_base_prompt, system_prompt_template, is_cross_margin, taker_fee_rate, maker_fee_rate, order_strategy, btc_eth_order_strategy, altcoin_order_strategy, limit_price_offset, limit_timeout_seconds, timeframes, fallback_ai_model_ids, loss_streak_threshold, cooldown_minutes, prompt_template_name, max_orders_per_cycle, trailing_stop_percent, trailing_activation_percent, candle_lookback, symbol_weights, tp_percent, sl_percent, timezone)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, trader.ID, trader.UserID, trader.Name, trader.AIModelID, trader.ExchangeID, trader.InitialBalance, trader.ScanIntervalMinutes, trader.ScanIntervalSeconds, trader.IsRunning, trader.BTCETHLeverage, trader.AltcoinLeverage, trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt, trader.SystemPromptTemplate, trader.IsCrossMargin, trader.TakerFeeRate, trader.MakerFeeRate, trader.OrderStrategy, trader.BTCETHOrderStrategy, trader.AltcoinOrderStrategy, trader.LimitPriceOffset, trader.LimitTimeoutSeconds, trader.Timeframes, trader.FallbackAIModelIDs, trader.LossStreakThreshold, trader.CooldownMinutes, trader.PromptTemplateName, trader.MaxOrdersPerCycle, trader.TrailingStopPercent, trader.TrailingActivation, trader.CandleLookback, trader.SymbolWeights, trader.TakeProfitPercent, trader.StopLossPercent, trader.Timezone)
+	if err != nil {
+		return err
+	}
+	if err := recordTraderConfigSnapshotTx(tx, trader.UserID, trader.ID); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(tx, trader.UserID, AuditEntityTrader, trader.ID, "create", trader.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetTraders 获取用户的交易员
-func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
-	rows, err := d.db.Query(`
-		SELECT id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes, is_running,
+// traderSelectColumns 查询交易员记录时使用的列（与 scanTraderRecord 的顺序保持一致）
+const traderSelectColumns = `id, user_id, name, ai_model_id, exchange_id, initial_balance, scan_interval_minutes,
+		       COALESCE(scan_interval_seconds, 0) as scan_interval_seconds, is_running,
 		       COALESCE(btc_eth_leverage, 5) as btc_eth_leverage, COALESCE(altcoin_leverage, 5) as altcoin_leverage,
 		       COALESCE(trading_symbols, '') as trading_symbols,
 		       COALESCE(use_coin_pool, 0) as use_coin_pool, COALESCE(use_oi_top, 0) as use_oi_top,
@@ -1707,12 +2352,53 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 		       COALESCE(is_cross_margin, 1) as is_cross_margin,
 		       COALESCE(taker_fee_rate, 0.0004) as taker_fee_rate, COALESCE(maker_fee_rate, 0.0002) as maker_fee_rate,
 		       COALESCE(order_strategy, 'conservative_hybrid') as order_strategy,
+		       COALESCE(btc_eth_order_strategy, '') as btc_eth_order_strategy,
+		       COALESCE(altcoin_order_strategy, '') as altcoin_order_strategy,
 		       COALESCE(limit_price_offset, -0.03) as limit_price_offset,
 		       COALESCE(limit_timeout_seconds, 60) as limit_timeout_seconds,
 		       COALESCE(timeframes, '4h') as timeframes,
-		       created_at, updated_at
-		FROM traders WHERE user_id = ? ORDER BY created_at DESC
-	`, userID)
+		       COALESCE(stop_reason, '') as stop_reason,
+		       COALESCE(fallback_ai_model_ids, '') as fallback_ai_model_ids,
+		       COALESCE(loss_streak_threshold, 0) as loss_streak_threshold,
+		       COALESCE(cooldown_minutes, 60) as cooldown_minutes,
+		       COALESCE(prompt_template_name, '') as prompt_template_name,
+		       COALESCE(max_orders_per_cycle, 0) as max_orders_per_cycle,
+		       COALESCE(trailing_stop_percent, 0) as trailing_stop_percent,
+		       COALESCE(trailing_activation_percent, 0) as trailing_activation_percent,
+		       COALESCE(candle_lookback, '') as candle_lookback,
+		       COALESCE(symbol_weights, '') as symbol_weights,
+		       COALESCE(tp_percent, 0) as tp_percent, COALESCE(sl_percent, 0) as sl_percent,
+		       COALESCE(timezone, '') as timezone,
+		       created_at, updated_at`
+
+// scanTraderRecord 扫描一行 traderSelectColumns 查询结果
+func scanTraderRecord(rows *sql.Rows) (*TraderRecord, error) {
+	var trader TraderRecord
+	err := rows.Scan(
+		&trader.ID, &trader.UserID, &trader.Name, &trader.AIModelID, &trader.ExchangeID,
+		&trader.InitialBalance, &trader.ScanIntervalMinutes, &trader.ScanIntervalSeconds, &trader.IsRunning,
+		&trader.BTCETHLeverage, &trader.AltcoinLeverage, &trader.TradingSymbols,
+		&trader.UseCoinPool, &trader.UseOITop,
+		&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
+		&trader.IsCrossMargin,
+		&trader.TakerFeeRate, &trader.MakerFeeRate,
+		&trader.OrderStrategy, &trader.BTCETHOrderStrategy, &trader.AltcoinOrderStrategy,
+		&trader.LimitPriceOffset, &trader.LimitTimeoutSeconds,
+		&trader.Timeframes, &trader.StopReason, &trader.FallbackAIModelIDs, &trader.LossStreakThreshold,
+		&trader.CooldownMinutes, &trader.PromptTemplateName, &trader.MaxOrdersPerCycle,
+		&trader.TrailingStopPercent, &trader.TrailingActivation, &trader.CandleLookback, &trader.SymbolWeights,
+		&trader.TakeProfitPercent, &trader.StopLossPercent, &trader.Timezone,
+		&trader.CreatedAt, &trader.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &trader, nil
+}
+
+// queryTraderRecords 执行交易员查询并扫描全部结果
+func (d *Database) queryTraderRecords(query string, args ...interface{}) ([]*TraderRecord, error) {
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -1720,52 +2406,213 @@ func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
 
 	var traders []*TraderRecord
 	for rows.Next() {
-		var trader TraderRecord
-		err := rows.Scan(
-			&trader.ID, &trader.UserID, &trader.Name, &trader.AIModelID, &trader.ExchangeID,
-			&trader.InitialBalance, &trader.ScanIntervalMinutes, &trader.IsRunning,
-			&trader.BTCETHLeverage, &trader.AltcoinLeverage, &trader.TradingSymbols,
-			&trader.UseCoinPool, &trader.UseOITop,
-			&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
-			&trader.IsCrossMargin,
-			&trader.TakerFeeRate, &trader.MakerFeeRate,
-			&trader.OrderStrategy, &trader.LimitPriceOffset, &trader.LimitTimeoutSeconds,
-			&trader.Timeframes,
-			&trader.CreatedAt, &trader.UpdatedAt,
-		)
+		trader, err := scanTraderRecord(rows)
 		if err != nil {
 			return nil, err
 		}
-		traders = append(traders, &trader)
+		traders = append(traders, trader)
+	}
+
+	return traders, rows.Err()
+}
+
+// GetTraders 获取用户的交易员
+func (d *Database) GetTraders(userID string) ([]*TraderRecord, error) {
+	return d.queryTraderRecords(`
+		SELECT `+traderSelectColumns+`
+		FROM traders WHERE user_id = ? ORDER BY created_at DESC
+	`, userID)
+}
+
+// GetAllRunningTraders 单条查询获取所有用户中处于运行状态的交易员（供调度使用，已禁用用户除外）
+// 按 user_id、created_at 排序，结果包含 user_id 便于调用方归属
+func (d *Database) GetAllRunningTraders() ([]*TraderRecord, error) {
+	return d.queryTraderRecords(`
+		SELECT ` + traderSelectColumns + `
+		FROM traders WHERE is_running = 1
+			AND user_id NOT IN (SELECT id FROM users WHERE disabled = 1)
+		ORDER BY user_id, created_at
+	`)
+}
+
+// TraderQueryOptions 交易员查询选项
+type TraderQueryOptions struct {
+	IsRunning    *bool  // 按运行状态过滤，nil表示不过滤
+	NameContains string // 名称包含（不区分大小写）
+	SortBy       string // 排序字段: created_at, updated_at, name, initial_balance, is_running（默认 created_at）
+	SortDesc     bool   // 是否降序
+}
+
+// ErrInvalidTraderSort 排序字段不在白名单内
+var ErrInvalidTraderSort = errors.New("不支持的排序字段")
+
+// traderSortColumns 允许排序的列白名单（防止SQL注入）
+var traderSortColumns = map[string]string{
+	"created_at":      "created_at",
+	"updated_at":      "updated_at",
+	"name":            "name COLLATE NOCASE",
+	"initial_balance": "initial_balance",
+	"is_running":      "is_running",
+}
+
+// GetTradersFiltered 按条件过滤和排序获取用户的交易员
+func (d *Database) GetTradersFiltered(userID string, opts TraderQueryOptions) ([]*TraderRecord, error) {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	sortColumn, ok := traderSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTraderSort, opts.SortBy)
+	}
+	direction := "ASC"
+	if opts.SortDesc {
+		direction = "DESC"
+	}
+
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{userID}
+	if opts.IsRunning != nil {
+		conditions = append(conditions, "is_running = ?")
+		args = append(args, *opts.IsRunning)
+	}
+	if name := strings.TrimSpace(opts.NameContains); name != "" {
+		// 转义 LIKE 通配符，按字面匹配
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(name)
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escaped+"%")
 	}
 
-	return traders, nil
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM traders WHERE %s ORDER BY %s %s, id ASC
+	`, traderSelectColumns, strings.Join(conditions, " AND "), sortColumn, direction)
+
+	return d.queryTraderRecords(query, args...)
+}
+
+// 交易员停止原因（写入 traders.stop_reason）
+const (
+	StopReasonUser  = "user"  // 用户手动停止
+	StopReasonError = "error" // 运行出错退出
+
+	StopReasonWebhook        = "webhook"         // 外部告警（webhook action=stop）停止
+	StopReasonExchangeOutage = "exchange_outage" // 交易所故障/维护时按交易所批量停止
+	StopReasonMarginFloor    = "margin_floor"    // 可用保证金低于下限（接近强平）时自动停止
+	StopReasonUserPaused     = "user_paused"     // 按用户批量暂停，可批量恢复
+)
+
+// GetTraderIDsByStopReason 获取已停止、且停止原因为 reason（或带详情的 "reason: ..."）的交易员ID
+func (d *Database) GetTraderIDsByStopReason(reason string) ([]string, error) {
+	rows, err := d.db.Query(`
+		SELECT id FROM traders
+		WHERE is_running = 0 AND (stop_reason = ? OR stop_reason LIKE ? || ':%')
+		ORDER BY id
+	`, reason, reason)
+	if err != nil {
+		return nil, fmt.Errorf("查询停止原因为 %s 的交易员失败: %w", reason, err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("读取交易员ID失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
 // UpdateTraderStatus 更新交易员状态
-func (d *Database) UpdateTraderStatus(userID, id string, isRunning bool) error {
-	_, err := d.db.Exec(`UPDATE traders SET is_running = ? WHERE id = ? AND user_id = ?`, isRunning, id, userID)
+// 停止时记录 reason（见 StopReason* 常量，可附带详情），启动时清空停止原因和连续失败计数
+func (d *Database) UpdateTraderStatus(userID, id string, isRunning bool, reason string) error {
+	if isRunning {
+		_, err := d.db.Exec(`UPDATE traders SET is_running = 1, stop_reason = '', consecutive_failures = 0 WHERE id = ? AND user_id = ?`, id, userID)
+		return err
+	}
+	_, err := d.db.Exec(`UPDATE traders SET is_running = 0, stop_reason = ? WHERE id = ? AND user_id = ?`, reason, id, userID)
 	return err
 }
 
 // UpdateTrader 更新交易员配置
 func (d *Database) UpdateTrader(trader *TraderRecord) error {
-	_, err := d.db.Exec(`
+	if err := d.validateTraderSymbols(trader.TradingSymbols); err != nil {
+		return err
+	}
+	if err := d.validatePromptTemplateRef(trader.UserID, trader.PromptTemplateName); err != nil {
+		return err
+	}
+	if err := validateMaxOrdersPerCycle(trader.MaxOrdersPerCycle); err != nil {
+		return err
+	}
+	if err := validateTrailingStop(trader.TrailingStopPercent, trader.TrailingActivation); err != nil {
+		return err
+	}
+	if err := validateCandleLookback(trader.CandleLookback); err != nil {
+		return err
+	}
+	if err := validateSymbolWeights(trader.SymbolWeights); err != nil {
+		return err
+	}
+	if err := validateExitBracket(trader.TakeProfitPercent, trader.StopLossPercent); err != nil {
+		return err
+	}
+	if err := validateTimezone(trader.Timezone); err != nil {
+		return err
+	}
+	if err := d.validateTraderExchangeSymbols(trader.UserID, trader.ExchangeID, trader.TradingSymbols); err != nil {
+		return err
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
 		UPDATE traders SET
 			name = ?, ai_model_id = ?, exchange_id = ?,
-			scan_interval_minutes = ?, btc_eth_leverage = ?, altcoin_leverage = ?,
+			scan_interval_minutes = ?, scan_interval_seconds = ?, btc_eth_leverage = ?, altcoin_leverage = ?,
 			trading_symbols = ?, use_coin_pool = ?, use_oi_top = ?, custom_prompt = ?, override_base_prompt = ?,
 			system_prompt_template = ?, is_cross_margin = ?, taker_fee_rate = ?, maker_fee_rate = ?,
-			order_strategy = ?, limit_price_offset = ?, limit_timeout_seconds = ?, timeframes = ?,
+			order_strategy = ?, btc_eth_order_strategy = ?, altcoin_order_strategy = ?,
+			limit_price_offset = ?, limit_timeout_seconds = ?, timeframes = ?,
+			fallback_ai_model_ids = ?,
+			loss_streak_threshold = ?,
+			cooldown_minutes = ?,
+			prompt_template_name = ?,
+			max_orders_per_cycle = ?,
+			trailing_stop_percent = ?, trailing_activation_percent = ?,
+			candle_lookback = ?,
+			symbol_weights = ?,
+			tp_percent = ?, sl_percent = ?,
+			timezone = ?,
 			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ? AND user_id = ?
 	`, trader.Name, trader.AIModelID, trader.ExchangeID,
-		trader.ScanIntervalMinutes, trader.BTCETHLeverage, trader.AltcoinLeverage,
+		trader.ScanIntervalMinutes, trader.ScanIntervalSeconds, trader.BTCETHLeverage, trader.AltcoinLeverage,
 		trader.TradingSymbols, trader.UseCoinPool, trader.UseOITop, trader.CustomPrompt, trader.OverrideBasePrompt,
 		trader.SystemPromptTemplate, trader.IsCrossMargin, trader.TakerFeeRate, trader.MakerFeeRate,
-		trader.OrderStrategy, trader.LimitPriceOffset, trader.LimitTimeoutSeconds, trader.Timeframes,
+		trader.OrderStrategy, trader.BTCETHOrderStrategy, trader.AltcoinOrderStrategy,
+		trader.LimitPriceOffset, trader.LimitTimeoutSeconds, trader.Timeframes, trader.FallbackAIModelIDs, trader.LossStreakThreshold, trader.CooldownMinutes, trader.PromptTemplateName, trader.MaxOrdersPerCycle,
+		trader.TrailingStopPercent, trader.TrailingActivation, trader.CandleLookback, trader.SymbolWeights,
+		trader.TakeProfitPercent, trader.StopLossPercent,
+		trader.Timezone,
 		trader.ID, trader.UserID)
-	return err
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		if err := recordTraderConfigSnapshotTx(tx, trader.UserID, trader.ID); err != nil {
+			return err
+		}
+		if err := recordAuditEvent(tx, trader.UserID, AuditEntityTrader, trader.ID, "update", trader.Name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 // UpdateTraderCustomPrompt 更新交易员自定义Prompt
@@ -1783,8 +2630,14 @@ func (d *Database) UpdateTraderInitialBalance(userID, id string, newBalance floa
 
 // DeleteTrader 删除交易员
 func (d *Database) DeleteTrader(userID, id string) error {
-	_, err := d.db.Exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, id, userID)
-	return err
+	result, err := d.db.Exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		d.RecordAuditEvent(userID, AuditEntityTrader, id, "delete", "")
+	}
+	return nil
 }
 
 // GetTraderConfig 获取交易员完整配置（包含AI模型和交易所信息）
@@ -1795,7 +2648,8 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 
 	err := d.db.QueryRow(`
 		SELECT
-			t.id, t.user_id, t.name, t.ai_model_id, t.exchange_id, t.initial_balance, t.scan_interval_minutes, t.is_running,
+			t.id, t.user_id, t.name, t.ai_model_id, t.exchange_id, t.initial_balance, t.scan_interval_minutes,
+			COALESCE(t.scan_interval_seconds, 0) as scan_interval_seconds, t.is_running,
 			COALESCE(t.btc_eth_leverage, 5) as btc_eth_leverage,
 			COALESCE(t.altcoin_leverage, 5) as altcoin_leverage,
 			COALESCE(t.trading_symbols, '') as trading_symbols,
@@ -1808,19 +2662,36 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 			COALESCE(t.taker_fee_rate, 0.0004) as taker_fee_rate,
 			COALESCE(t.maker_fee_rate, 0.0002) as maker_fee_rate,
 			COALESCE(t.order_strategy, 'conservative_hybrid') as order_strategy,
+			COALESCE(t.btc_eth_order_strategy, '') as btc_eth_order_strategy,
+			COALESCE(t.altcoin_order_strategy, '') as altcoin_order_strategy,
 			COALESCE(t.limit_price_offset, -0.03) as limit_price_offset,
 			COALESCE(t.limit_timeout_seconds, 60) as limit_timeout_seconds,
-			COALESCE(t.timeframes, '4h') as timeframes,
+			COALESCE(t.timeframes, '') as timeframes,
+			COALESCE(t.stop_reason, '') as stop_reason,
+			COALESCE(t.fallback_ai_model_ids, '') as fallback_ai_model_ids,
+			COALESCE(t.loss_streak_threshold, 0) as loss_streak_threshold,
+			COALESCE(t.cooldown_minutes, 60) as cooldown_minutes,
+			COALESCE(t.prompt_template_name, '') as prompt_template_name,
+			COALESCE(t.max_orders_per_cycle, 0) as max_orders_per_cycle,
+			COALESCE(t.trailing_stop_percent, 0) as trailing_stop_percent,
+			COALESCE(t.trailing_activation_percent, 0) as trailing_activation_percent,
+			COALESCE(t.candle_lookback, '') as candle_lookback,
+			COALESCE(t.symbol_weights, '') as symbol_weights,
+			COALESCE(t.tp_percent, 0) as tp_percent, COALESCE(t.sl_percent, 0) as sl_percent,
+			COALESCE(t.timezone, '') as timezone,
 			t.created_at, t.updated_at,
 			a.id, a.model_id, a.user_id, a.name, a.provider, a.enabled, a.api_key,
 			COALESCE(a.custom_api_url, '') as custom_api_url,
 			COALESCE(a.custom_model_name, '') as custom_model_name,
+			COALESCE(a.custom_headers, '') as custom_headers,
+			COALESCE(a.requests_per_minute, 0) as requests_per_minute,
 			a.created_at, a.updated_at,
 			e.id, e.exchange_id, e.user_id, e.name, e.type, e.enabled, e.api_key, e.secret_key, e.testnet,
 			COALESCE(e.hyperliquid_wallet_addr, '') as hyperliquid_wallet_addr,
 			COALESCE(e.aster_user, '') as aster_user,
 			COALESCE(e.aster_signer, '') as aster_signer,
 			COALESCE(e.aster_private_key, '') as aster_private_key,
+			COALESCE(e.account_mode, 'standard') as account_mode,
 			e.created_at, e.updated_at
 		FROM traders t
 		JOIN ai_models a ON t.ai_model_id = a.id
@@ -1828,21 +2699,26 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 		WHERE t.id = ? AND t.user_id = ?
 	`, traderID, userID).Scan(
 		&trader.ID, &trader.UserID, &trader.Name, &trader.AIModelID, &trader.ExchangeID,
-		&trader.InitialBalance, &trader.ScanIntervalMinutes, &trader.IsRunning,
+		&trader.InitialBalance, &trader.ScanIntervalMinutes, &trader.ScanIntervalSeconds, &trader.IsRunning,
 		&trader.BTCETHLeverage, &trader.AltcoinLeverage, &trader.TradingSymbols,
 		&trader.UseCoinPool, &trader.UseOITop,
 		&trader.CustomPrompt, &trader.OverrideBasePrompt, &trader.SystemPromptTemplate,
 		&trader.IsCrossMargin,
 		&trader.TakerFeeRate, &trader.MakerFeeRate,
-		&trader.OrderStrategy, &trader.LimitPriceOffset, &trader.LimitTimeoutSeconds,
-		&trader.Timeframes,
+		&trader.OrderStrategy, &trader.BTCETHOrderStrategy, &trader.AltcoinOrderStrategy,
+		&trader.LimitPriceOffset, &trader.LimitTimeoutSeconds,
+		&trader.Timeframes, &trader.StopReason, &trader.FallbackAIModelIDs, &trader.LossStreakThreshold,
+		&trader.CooldownMinutes, &trader.PromptTemplateName, &trader.MaxOrdersPerCycle,
+		&trader.TrailingStopPercent, &trader.TrailingActivation, &trader.CandleLookback, &trader.SymbolWeights,
+		&trader.TakeProfitPercent, &trader.StopLossPercent, &trader.Timezone,
 		&trader.CreatedAt, &trader.UpdatedAt,
 		&aiModel.ID, &aiModel.ModelID, &aiModel.UserID, &aiModel.Name, &aiModel.Provider, &aiModel.Enabled, &aiModel.APIKey,
-		&aiModel.CustomAPIURL, &aiModel.CustomModelName,
+		&aiModel.CustomAPIURL, &aiModel.CustomModelName, &aiModel.CustomHeaders, &aiModel.RequestsPerMinute,
 		&aiModel.CreatedAt, &aiModel.UpdatedAt,
 		&exchange.ID, &exchange.ExchangeID, &exchange.UserID, &exchange.Name, &exchange.Type, &exchange.Enabled,
 		&exchange.APIKey, &exchange.SecretKey, &exchange.Testnet,
 		&exchange.HyperliquidWalletAddr, &exchange.AsterUser, &exchange.AsterSigner, &exchange.AsterPrivateKey,
+		&exchange.AccountMode,
 		&exchange.CreatedAt, &exchange.UpdatedAt,
 	)
 
@@ -1852,10 +2728,16 @@ func (d *Database) GetTraderConfig(userID, traderID string) (*TraderRecord, *AIM
 
 	// 解密敏感数据
 	aiModel.APIKey = d.decryptSensitiveData(aiModel.APIKey)
+	aiModel.CustomHeaders = d.decryptSensitiveData(aiModel.CustomHeaders)
 	exchange.APIKey = d.decryptSensitiveData(exchange.APIKey)
 	exchange.SecretKey = d.decryptSensitiveData(exchange.SecretKey)
 	exchange.AsterPrivateKey = d.decryptSensitiveData(exchange.AsterPrivateKey)
 
+	// 未配置时间线时使用系统默认值
+	if strings.TrimSpace(trader.Timeframes) == "" {
+		trader.Timeframes = strings.Join(d.GetDefaultTimeframes(), ",")
+	}
+
 	return &trader, &aiModel, &exchange, nil
 }
 
@@ -1866,12 +2748,9 @@ func (d *Database) GetSystemConfig(key string) (string, error) {
 	return value, err
 }
 
-// SetSystemConfig 设置系统配置
+// SetSystemConfig 设置系统配置（值发生变化时记录到 config_history）
 func (d *Database) SetSystemConfig(key, value string) error {
-	_, err := d.db.Exec(`
-		INSERT OR REPLACE INTO system_config (key, value) VALUES (?, ?)
-	`, key, value)
-	return err
+	return d.SetSystemConfigBy(key, value, "")
 }
 
 // CreateUserSignalSource 创建用户信号源配置
@@ -1939,8 +2818,9 @@ func (d *Database) GetCustomCoins() []string {
 		}
 	}
 
+	filter := d.GetSymbolFilter()
 	if len(symbolSet) == 0 {
-		return d.getDefaultCoins()
+		return filter.Filter(d.getDefaultCoins(), "default_coins")
 	}
 
 	symbols := make([]string, 0, len(symbolSet))
@@ -1948,6 +2828,10 @@ func (d *Database) GetCustomCoins() []string {
 		symbols = append(symbols, s)
 	}
 	slices.Sort(symbols)
+	symbols = filter.Filter(symbols, "custom_coins")
+	if len(symbols) == 0 {
+		return filter.Filter(d.getDefaultCoins(), "default_coins")
+	}
 	return symbols
 }
 
@@ -1966,19 +2850,21 @@ func (d *Database) getDefaultCoins() []string {
 	for i, coin := range symbols {
 		symbols[i] = market.Normalize(coin)
 	}
-	return symbols
+	return d.filterDelistedCoins(symbols)
 }
 
 // GetAllTimeframes 获取所有交易员配置的时间线并集 / Get union of all trader timeframes
+// 未配置时间线的交易员按 GetDefaultTimeframes 计入
 func (d *Database) GetAllTimeframes() []string {
+	defaults := d.GetDefaultTimeframes()
 	rows, err := d.db.Query(`
-		SELECT DISTINCT timeframes
+		SELECT DISTINCT COALESCE(timeframes, '')
 		FROM traders
-		WHERE timeframes != '' AND is_running = 1
+		WHERE is_running = 1
 	`)
 	if err != nil {
 		log.Printf("查询 trader timeframes 失败: %v", err)
-		return []string{"4h"} // 默认返回 4h
+		return defaults
 	}
 	defer rows.Close()
 
@@ -1988,12 +2874,12 @@ func (d *Database) GetAllTimeframes() []string {
 		if err := rows.Scan(&timeframes); err != nil {
 			continue
 		}
-		// 解析逗号分隔的时间线
-		for _, tf := range strings.Split(timeframes, ",") {
-			tf = strings.TrimSpace(tf)
-			if tf != "" {
-				timeframeSet[tf] = true
-			}
+		parsed := parseTimeframes(timeframes, "trader timeframes")
+		if len(parsed) == 0 {
+			parsed = defaults
+		}
+		for _, tf := range parsed {
+			timeframeSet[tf] = true
 		}
 	}
 
@@ -2005,34 +2891,54 @@ func (d *Database) GetAllTimeframes() []string {
 
 	// 如果没有配置，返回默认值
 	if len(result) == 0 {
-		return []string{"15m", "1h", "4h"}
+		return defaults
 	}
 
 	log.Printf("📊 从数据库加载所有活跃 trader 的时间线: %v", result)
 	return result
 }
 
+// Ping 检查数据库连接是否可用
+func (d *Database) Ping(ctx context.Context) error {
+	var one int
+	return d.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// CheckIntegrity 执行 PRAGMA integrity_check 检查整个数据库文件，返回发现的问题（空切片表示正常）
+// 会读取全部页面，大库上耗时较长，适合在恢复备份前或异常关机后按需执行
+func (d *Database) CheckIntegrity() ([]string, error) {
+	rows, err := d.db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("执行完整性检查失败: %w", err)
+	}
+	defer rows.Close()
+
+	problems := make([]string, 0)
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("读取完整性检查结果失败: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取完整性检查结果失败: %w", err)
+	}
+	return problems, nil
+}
+
 // Close 关闭数据库连接
 func (d *Database) Close() error {
 	return d.db.Close()
 }
 
-// LoadBetaCodesFromFile 从文件加载内测码到数据库
+// LoadBetaCodesFromFile 从文件加载内测码到数据库，格式不合法（不匹配 betaCodePattern）的内测码会被跳过
 func (d *Database) LoadBetaCodesFromFile(filePath string) error {
-	// 读取文件内容
-	content, err := os.ReadFile(filePath)
+	codes, err := readBetaCodeFile(filePath)
 	if err != nil {
-		return fmt.Errorf("读取内测码文件失败: %w", err)
-	}
-
-	// 按行分割内测码
-	lines := strings.Split(string(content), "\n")
-	var codes []string
-	for _, line := range lines {
-		code := strings.TrimSpace(line)
-		if code != "" && !strings.HasPrefix(code, "#") {
-			codes = append(codes, code)
-		}
+		return err
 	}
 
 	// 批量插入内测码
@@ -2048,8 +2954,13 @@ func (d *Database) LoadBetaCodesFromFile(filePath string) error {
 	}
 	defer stmt.Close()
 
-	insertedCount := 0
+	insertedCount, invalidCount := 0, 0
 	for _, code := range codes {
+		if !betaCodePattern.MatchString(code) {
+			log.Printf("⚠️ 跳过格式不合法的内测码: %q", code)
+			invalidCount++
+			continue
+		}
 		result, err := stmt.Exec(code)
 		if err != nil {
 			log.Printf("插入内测码 %s 失败: %v", code, err)
@@ -2065,28 +2976,94 @@ func (d *Database) LoadBetaCodesFromFile(filePath string) error {
 		return fmt.Errorf("提交事务失败: %w", err)
 	}
 
-	log.Printf("✅ 成功加载 %d 个内测码到数据库 (总计 %d 个)", insertedCount, len(codes))
+	log.Printf("✅ 成功加载 %d 个内测码到数据库 (总计 %d 个，格式不合法 %d 个)", insertedCount, len(codes), invalidCount)
 	return nil
 }
 
-// ValidateBetaCode 验证内测码是否有效且未使用
+// readBetaCodeFile 读取内测码文件，忽略空行和 # 注释行
+func readBetaCodeFile(filePath string) ([]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取内测码文件失败: %w", err)
+	}
+
+	var codes []string
+	for _, line := range strings.Split(string(content), "\n") {
+		code := strings.TrimSpace(line)
+		if code != "" && !strings.HasPrefix(code, "#") {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// betaCodePattern 内测码格式（与 generate_beta_code.sh 及注册页输入一致：6位小写字母或数字）
+var betaCodePattern = regexp.MustCompile(`^[a-z0-9]{6}$`)
+
+// PreviewBetaCodeImport 预览内测码文件导入结果，仅分类不写入数据库
+// new: 可导入的新码；duplicate: 数据库中已存在或文件内重复；invalid: 格式不合法
+func (d *Database) PreviewBetaCodeImport(filePath string) (newCodes []string, duplicate []string, invalid []string, err error) {
+	codes, err := readBetaCodeFile(filePath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	existing := make(map[string]bool)
+	rows, err := d.db.Query(`SELECT code FROM beta_codes`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("查询内测码失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, nil, nil, fmt.Errorf("读取内测码失败: %w", err)
+		}
+		existing[code] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("读取内测码失败: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		switch {
+		case !betaCodePattern.MatchString(code):
+			invalid = append(invalid, code)
+		case existing[code] || seen[code]:
+			duplicate = append(duplicate, code)
+		default:
+			newCodes = append(newCodes, code)
+		}
+		seen[code] = true
+	}
+	return newCodes, duplicate, invalid, nil
+}
+
+// ValidateBetaCode 验证内测码是否有效且仍有剩余可用次数
 func (d *Database) ValidateBetaCode(code string) (bool, error) {
-	var used bool
-	err := d.db.QueryRow(`SELECT used FROM beta_codes WHERE code = ?`, code).Scan(&used)
+	var available bool
+	err := d.db.QueryRow(`
+		SELECT uses_count < uses_allowed FROM beta_codes WHERE code = ?
+	`, code).Scan(&available)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil // 内测码不存在
 		}
 		return false, err
 	}
-	return !used, nil // 内测码存在且未使用
+	return available, nil
 }
 
-// UseBetaCode 使用内测码（标记为已使用）
+// UseBetaCode 使用内测码（已使用次数 +1，达到 uses_allowed 后标记为已用完）
+// 计数与上限判断在同一条 UPDATE 中完成，并发注册时不会超额使用；used_by 记录最近一次使用者
 func (d *Database) UseBetaCode(code, userEmail string) error {
 	result, err := d.db.Exec(`
-		UPDATE beta_codes SET used = 1, used_by = ?, used_at = CURRENT_TIMESTAMP 
-		WHERE code = ? AND used = 0
+		UPDATE beta_codes SET
+			uses_count = uses_count + 1,
+			used = (uses_count + 1 >= uses_allowed),
+			used_by = ?, used_at = CURRENT_TIMESTAMP
+		WHERE code = ? AND uses_count < uses_allowed
 	`, userEmail, code)
 	if err != nil {
 		return err
@@ -2104,6 +3081,24 @@ func (d *Database) UseBetaCode(code, userEmail string) error {
 	return nil
 }
 
+// SetBetaCodeUsesAllowed 设置内测码可注册次数（用于邀请/推荐），不能小于 1
+// 上限调整到低于已使用次数时内测码即视为已用完
+func (d *Database) SetBetaCodeUsesAllowed(code string, usesAllowed int) error {
+	if usesAllowed < 1 {
+		return fmt.Errorf("内测码可使用次数必须大于0: %d", usesAllowed)
+	}
+	result, err := d.db.Exec(`
+		UPDATE beta_codes SET uses_allowed = ?, used = (uses_count >= ?) WHERE code = ?
+	`, usesAllowed, usesAllowed, code)
+	if err != nil {
+		return fmt.Errorf("更新内测码可使用次数失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("内测码不存在: %s", code)
+	}
+	return nil
+}
+
 // GetBetaCodeStats 获取内测码统计信息
 func (d *Database) GetBetaCodeStats() (total, used int, err error) {
 	err = d.db.QueryRow(`SELECT COUNT(*) FROM beta_codes`).Scan(&total)
@@ -2153,6 +3148,7 @@ func (d *Database) decryptSensitiveData(encrypted string) string {
 	decrypted, err := d.cryptoService.DecryptFromStorage(encrypted)
 	if err != nil {
 		log.Printf("⚠️ 解密失败: %v", err)
+		d.decryptMonitor.record(err)
 		return encrypted // 返回加密文本作为降级处理
 	}
 
@@ -2208,6 +3204,7 @@ func (d *Database) cleanupLegacyColumns() error {
 			exchange_id TEXT NOT NULL,
 			initial_balance REAL NOT NULL,
 			scan_interval_minutes INTEGER DEFAULT 3,
+			scan_interval_seconds INTEGER DEFAULT 0,
 			is_running BOOLEAN DEFAULT 0,
 			btc_eth_leverage INTEGER DEFAULT 5,
 			altcoin_leverage INTEGER DEFAULT 5,
@@ -2223,9 +3220,28 @@ func (d *Database) cleanupLegacyColumns() error {
 			taker_fee_rate REAL DEFAULT 0.0004,
 			maker_fee_rate REAL DEFAULT 0.0002,
 			order_strategy TEXT DEFAULT 'conservative_hybrid',
+			btc_eth_order_strategy TEXT DEFAULT '',
+			altcoin_order_strategy TEXT DEFAULT '',
 			limit_price_offset REAL DEFAULT -0.03,
 			limit_timeout_seconds INTEGER DEFAULT 60,
 			timeframes TEXT DEFAULT '4h',
+			last_scanned_at DATETIME,
+			leased_until DATETIME,
+			leased_by TEXT DEFAULT '',
+			stop_reason TEXT DEFAULT '',
+			fallback_ai_model_ids TEXT DEFAULT '',
+			loss_streak_threshold INTEGER DEFAULT 0,
+			cooldown_minutes INTEGER DEFAULT 60,
+			prompt_template_name TEXT DEFAULT '',
+			max_orders_per_cycle INTEGER DEFAULT 0,
+			trailing_stop_percent REAL DEFAULT 0,
+			trailing_activation_percent REAL DEFAULT 0,
+			candle_lookback TEXT DEFAULT '',
+			symbol_weights TEXT DEFAULT '',
+			tp_percent REAL DEFAULT 0,
+			sl_percent REAL DEFAULT 0,
+			timezone TEXT DEFAULT '',
+			consecutive_failures INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -2241,25 +3257,29 @@ func (d *Database) cleanupLegacyColumns() error {
 	_, err = tx.Exec(`
 		INSERT INTO traders_new (
 			id, user_id, name, ai_model_id, exchange_id,
-			initial_balance, scan_interval_minutes, is_running,
+			initial_balance, scan_interval_minutes, scan_interval_seconds, is_running,
 			btc_eth_leverage, altcoin_leverage, trading_symbols,
 			use_coin_pool, use_oi_top,
 			custom_prompt, override_base_prompt, system_prompt_template,
 			is_cross_margin, use_default_coins, custom_coins,
 			taker_fee_rate, maker_fee_rate, order_strategy,
+			btc_eth_order_strategy, altcoin_order_strategy,
 			limit_price_offset, limit_timeout_seconds, timeframes,
-			created_at, updated_at
+			stop_reason, fallback_ai_model_ids, loss_streak_threshold, cooldown_minutes, prompt_template_name, max_orders_per_cycle,
+			trailing_stop_percent, trailing_activation_percent, candle_lookback, symbol_weights, tp_percent, sl_percent, timezone, consecutive_failures, created_at, updated_at
 		)
 		SELECT
 			id, user_id, name, ai_model_id, exchange_id,
-			initial_balance, scan_interval_minutes, is_running,
+			initial_balance, scan_interval_minutes, COALESCE(scan_interval_seconds, 0), is_running,
 			btc_eth_leverage, altcoin_leverage, trading_symbols,
 			use_coin_pool, use_oi_top,
 			COALESCE(custom_prompt, ''), COALESCE(override_base_prompt, 0), COALESCE(system_prompt_template, 'default'),
 			COALESCE(is_cross_margin, 1), COALESCE(use_default_coins, 1), COALESCE(custom_coins, ''),
 			COALESCE(taker_fee_rate, 0.0004), COALESCE(maker_fee_rate, 0.0002), COALESCE(order_strategy, 'conservative_hybrid'),
+			COALESCE(btc_eth_order_strategy, ''), COALESCE(altcoin_order_strategy, ''),
 			COALESCE(limit_price_offset, -0.03), COALESCE(limit_timeout_seconds, 60), COALESCE(timeframes, '4h'),
-			created_at, updated_at
+			COALESCE(stop_reason, ''), COALESCE(fallback_ai_model_ids, ''), COALESCE(loss_streak_threshold, 0), COALESCE(cooldown_minutes, 60), COALESCE(prompt_template_name, ''), COALESCE(max_orders_per_cycle, 0),
+			COALESCE(trailing_stop_percent, 0), COALESCE(trailing_activation_percent, 0), COALESCE(candle_lookback, ''), COALESCE(symbol_weights, ''), COALESCE(tp_percent, 0), COALESCE(sl_percent, 0), COALESCE(timezone, ''), COALESCE(consecutive_failures, 0), created_at, updated_at
 		FROM traders
 	`)
 	if err != nil {
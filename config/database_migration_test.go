@@ -555,3 +555,28 @@ func TestDatabaseMigration_RollbackOnError(t *testing.T) {
 
 	assert.Contains(t, columns, "ai_model_id_old", "回滾後舊列應該仍存在")
 }
+
+// TestNewDatabaseWithOptions_SkipMigrations 測試跳過自動遷移後手動執行 RunMigrations
+func TestNewDatabaseWithOptions_SkipMigrations(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test_skip_migrations.db")
+
+	db, err := NewDatabaseWithOptions(dbPath, DatabaseOptions{SkipMigrations: true})
+	require.NoError(t, err)
+	defer db.Close()
+
+	var count int
+	err = db.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'traders'`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "跳過遷移時不應創建表")
+
+	require.NoError(t, db.RunMigrations())
+	err = db.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'traders'`).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "RunMigrations 後應創建表")
+
+	// 重複執行應該是冪等的
+	require.NoError(t, db.RunMigrations())
+	models, err := db.GetAIModels("default")
+	require.NoError(t, err)
+	assert.NotEmpty(t, models, "RunMigrations 應初始化默認數據")
+}
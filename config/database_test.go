@@ -556,7 +556,7 @@ func setupTestDB(t *testing.T) (*Database, func()) {
 	}
 
 	// 创建测试用户
-	testUsers := []string{"test-user-001", "test-user-002", "test-user-003", "test-user-004", "test-user-005", "test-user-006", "test-user-007", "test-user-008", "test-user-009"}
+	testUsers := []string{"test-user-001", "test-user-002", "test-user-003", "test-user-004", "test-user-005", "test-user-006", "test-user-007", "test-user-008", "test-user-009", "user1", "user2"}
 	for _, userID := range testUsers {
 		user := &User{
 			ID:           userID,
@@ -802,3 +802,56 @@ func TestConcurrentWritesWithWAL(t *testing.T) {
 		t.Errorf("并发写入失败次数过多: %d", errorCount)
 	}
 }
+
+// TestGetExchangesMetadata_DoesNotExposeSecrets 元数据查询不返回敏感字段
+func TestGetExchangesMetadata_DoesNotExposeSecrets(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	if err := db.CreateExchange(userID, "aster", "Aster", "dex", true, "api-key", "secret-key", false, "", "0xuser", "0xsigner", "private-key"); err != nil {
+		t.Fatalf("CreateExchange failed: %v", err)
+	}
+
+	full, err := db.GetExchanges(userID)
+	if err != nil || len(full) == 0 {
+		t.Fatalf("GetExchanges failed: %v", err)
+	}
+	meta, err := db.GetExchangesMetadata(userID)
+	if err != nil {
+		t.Fatalf("GetExchangesMetadata failed: %v", err)
+	}
+	if len(meta) != len(full) {
+		t.Fatalf("metadata rows = %d, want %d", len(meta), len(full))
+	}
+	for i, ex := range meta {
+		if ex.APIKey != "" || ex.SecretKey != "" || ex.AsterPrivateKey != "" {
+			t.Errorf("metadata for %s exposes secrets: %+v", ex.ExchangeID, ex)
+		}
+		if ex.ID != full[i].ID || ex.ExchangeID != full[i].ExchangeID || ex.AsterUser != full[i].AsterUser {
+			t.Errorf("metadata mismatch: %+v vs %+v", ex, full[i])
+		}
+		if ex.ExchangeID == "aster" && full[i].AsterPrivateKey != "private-key" {
+			t.Errorf("full query should decrypt private key, got %q", full[i].AsterPrivateKey)
+		}
+	}
+}
+
+// TestCheckIntegrity 完整性检查：正常数据库返回空列表
+func TestCheckIntegrity(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	problems, err := db.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("expected no problems, got %v", problems)
+	}
+
+	db.Close()
+	if _, err := db.CheckIntegrity(); err == nil {
+		t.Fatal("expected error on closed database")
+	}
+}
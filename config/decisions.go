@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Decision AI决策记录（每个周期每个币种一条）
+type Decision struct {
+	ID              int64     `json:"id"`
+	TraderID        string    `json:"trader_id"`
+	UserID          string    `json:"user_id"`
+	Symbol          string    `json:"symbol"`
+	Action          string    `json:"action"` // open_long / close_short / hold / wait 等
+	Leverage        int       `json:"leverage"`
+	PositionSizeUSD float64   `json:"position_size_usd"`
+	StopLoss        float64   `json:"stop_loss"`
+	TakeProfit      float64   `json:"take_profit"`
+	Confidence      int       `json:"confidence"`
+	Reasoning       string    `json:"reasoning"`
+	Success         bool      `json:"success"`                   // 是否执行成功
+	Error           string    `json:"error"`                     // 执行失败原因
+	ClientOrderID   string    `json:"client_order_id,omitempty"` // 幂等下单使用的 clientOrderId
+	CycleID         int64     `json:"cycle_id,omitempty"`        // 所属决策周期（见 RecordDecisionCycle）
+	CreatedAt       time.Time `json:"created_at"`
+
+	// 重放所需的输入，保存在所属决策周期中（仅 GetDecision 读取，列表查询不返回以免响应过大）
+	UserPrompt    string  `json:"user_prompt,omitempty"`    // 本周期发送给AI的输入prompt
+	AccountEquity float64 `json:"account_equity,omitempty"` // 决策时的账户净值（重建系统prompt使用）
+}
+
+// RecordDecisionCycle 记录一个决策周期的输入（每个周期一条，同一周期各币种的决策通过 cycle_id 引用），返回周期ID
+func (d *Database) RecordDecisionCycle(traderID, userID, userPrompt string, accountEquity float64) (int64, error) {
+	if traderID == "" {
+		return 0, fmt.Errorf("决策周期缺少交易员")
+	}
+	if userID == "" {
+		userID = "default"
+	}
+	result, err := d.db.Exec(`
+		INSERT INTO decision_cycles (trader_id, user_id, user_prompt, account_equity, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, traderID, userID, userPrompt, accountEquity, time.Now().UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return 0, fmt.Errorf("记录决策周期失败: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// RecordDecision 记录一条AI决策（UserPrompt / AccountEquity 不写入决策表，由 CycleID 指向的决策周期保存）
+func (d *Database) RecordDecision(decision *Decision) error {
+	if decision.TraderID == "" || decision.Symbol == "" {
+		return fmt.Errorf("决策记录缺少交易员或币种")
+	}
+	if decision.CreatedAt.IsZero() {
+		decision.CreatedAt = time.Now()
+	}
+	if decision.UserID == "" {
+		decision.UserID = "default"
+	}
+
+	result, err := d.db.Exec(`
+		INSERT INTO decisions (trader_id, user_id, symbol, action, leverage, position_size_usd,
+			stop_loss, take_profit, confidence, reasoning, success, error, client_order_id, cycle_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, decision.TraderID, decision.UserID, decision.Symbol, decision.Action, decision.Leverage, decision.PositionSizeUSD,
+		decision.StopLoss, decision.TakeProfit, decision.Confidence, decision.Reasoning, decision.Success, decision.Error,
+		decision.ClientOrderID, decision.CycleID, decision.CreatedAt.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return fmt.Errorf("记录决策失败: %w", err)
+	}
+	decision.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetLatestDecisions 获取交易员每个币种的最新一条决策（key 为币种）
+// 单条查询：按币种分区取 created_at 最大的记录，同一时间以 id 较大者为准
+func (d *Database) GetLatestDecisions(userID, traderID string) (map[string]*Decision, error) {
+	rows, err := d.db.Query(`
+		SELECT id, trader_id, user_id, symbol, action, leverage, position_size_usd,
+			stop_loss, take_profit, confidence, reasoning, success, error, COALESCE(client_order_id, ''), created_at
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY symbol ORDER BY created_at DESC, id DESC) AS rn
+			FROM decisions
+			WHERE trader_id = ? AND user_id = ?
+		)
+		WHERE rn = 1
+	`, traderID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询最新决策失败: %w", err)
+	}
+	defer rows.Close()
+
+	latest := make(map[string]*Decision)
+	for rows.Next() {
+		var decision Decision
+		if err := rows.Scan(&decision.ID, &decision.TraderID, &decision.UserID, &decision.Symbol, &decision.Action,
+			&decision.Leverage, &decision.PositionSizeUSD, &decision.StopLoss, &decision.TakeProfit,
+			&decision.Confidence, &decision.Reasoning, &decision.Success, &decision.Error, &decision.ClientOrderID, &decision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取决策记录失败: %w", err)
+		}
+		latest[decision.Symbol] = &decision
+	}
+	return latest, rows.Err()
+}
+
+// GetDecision 获取用户的一条决策记录（包含所属决策周期中重放所需的输入prompt）
+func (d *Database) GetDecision(userID string, id int64) (*Decision, error) {
+	var decision Decision
+	err := d.db.QueryRow(`
+		SELECT d.id, d.trader_id, d.user_id, d.symbol, d.action, d.leverage, d.position_size_usd,
+			d.stop_loss, d.take_profit, d.confidence, d.reasoning, d.success, d.error, COALESCE(d.client_order_id, ''),
+			COALESCE(d.cycle_id, 0), COALESCE(c.user_prompt, ''), COALESCE(c.account_equity, 0), d.created_at
+		FROM decisions d
+		LEFT JOIN decision_cycles c ON c.id = d.cycle_id
+		WHERE d.id = ? AND d.user_id = ?
+	`, id, userID).Scan(&decision.ID, &decision.TraderID, &decision.UserID, &decision.Symbol, &decision.Action,
+		&decision.Leverage, &decision.PositionSizeUSD, &decision.StopLoss, &decision.TakeProfit,
+		&decision.Confidence, &decision.Reasoning, &decision.Success, &decision.Error, &decision.ClientOrderID,
+		&decision.CycleID, &decision.UserPrompt, &decision.AccountEquity, &decision.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	return &decision, nil
+}
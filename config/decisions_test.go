@@ -0,0 +1,112 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetLatestDecisions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-decisions-1")
+	exID := ensureTestExchange(t, db, userID, "binance-decisions-1")
+	tr := &TraderRecord{
+		ID: "tr-decisions", UserID: userID, Name: "decisions", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	decisions := []Decision{
+		{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, CreatedAt: base},
+		{Symbol: "BTCUSDT", Action: "hold", CreatedAt: base.Add(3 * time.Minute)},
+		{Symbol: "ETHUSDT", Action: "open_short", CreatedAt: base.Add(3 * time.Minute)},
+		// 同一时间的多条决策以后写入的为准
+		{Symbol: "ETHUSDT", Action: "close_short", Success: true, CreatedAt: base.Add(3 * time.Minute)},
+		{Symbol: "SOLUSDT", Action: "wait", Error: "boom", CreatedAt: base.Add(-time.Hour)},
+	}
+	for i := range decisions {
+		decisions[i].TraderID = tr.ID
+		decisions[i].UserID = userID
+		if err := db.RecordDecision(&decisions[i]); err != nil {
+			t.Fatalf("RecordDecision failed: %v", err)
+		}
+	}
+
+	latest, err := db.GetLatestDecisions(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetLatestDecisions failed: %v", err)
+	}
+	if len(latest) != 3 {
+		t.Fatalf("expected 3 symbols, got %d", len(latest))
+	}
+	if got := latest["BTCUSDT"]; got.Action != "hold" || !got.CreatedAt.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("BTCUSDT: expected latest hold, got %+v", got)
+	}
+	if got := latest["ETHUSDT"]; got.Action != "close_short" || !got.Success {
+		t.Errorf("ETHUSDT: expected latest close_short, got %+v", got)
+	}
+	if got := latest["SOLUSDT"]; got.Action != "wait" || got.Error != "boom" {
+		t.Errorf("SOLUSDT: unexpected decision %+v", got)
+	}
+
+	// 其他用户无法读取
+	if other, err := db.GetLatestDecisions("test-user-002", tr.ID); err != nil || len(other) != 0 {
+		t.Fatalf("expected no decisions for other user, got %d (%v)", len(other), err)
+	}
+}
+
+func TestGetDecision(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-decisions-2")
+	exID := ensureTestExchange(t, db, userID, "binance-decisions-2")
+	tr := &TraderRecord{
+		ID: "tr-decision-get", UserID: userID, Name: "decision-get", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	prompt := "## 市场数据\nBTC 50000"
+	cycleID, err := db.RecordDecisionCycle(tr.ID, userID, prompt, 1234.5)
+	if err != nil {
+		t.Fatalf("RecordDecisionCycle failed: %v", err)
+	}
+	rec := &Decision{TraderID: tr.ID, UserID: userID, Symbol: "BTCUSDT", Action: "open_long", CycleID: cycleID}
+	if err := db.RecordDecision(rec); err != nil {
+		t.Fatalf("RecordDecision failed: %v", err)
+	}
+	other := &Decision{TraderID: tr.ID, UserID: userID, Symbol: "ETHUSDT", Action: "hold", CycleID: cycleID}
+	if err := db.RecordDecision(other); err != nil {
+		t.Fatalf("RecordDecision failed: %v", err)
+	}
+
+	got, err := db.GetDecision(userID, rec.ID)
+	if err != nil {
+		t.Fatalf("GetDecision failed: %v", err)
+	}
+	if got.Action != "open_long" || got.UserPrompt != prompt || got.AccountEquity != 1234.5 || got.CycleID != cycleID {
+		t.Fatalf("unexpected decision: %+v", got)
+	}
+	if got, err := db.GetDecision(userID, other.ID); err != nil || got.UserPrompt != prompt {
+		t.Fatalf("decisions of the same cycle should share the prompt, got %+v (%v)", got, err)
+	}
+
+	// 同一周期的输入prompt只保存一份
+	var cycles int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM decision_cycles WHERE trader_id = ?`, tr.ID).Scan(&cycles); err != nil || cycles != 1 {
+		t.Fatalf("expected the prompt to be stored once, got %d cycles (%v)", cycles, err)
+	}
+
+	if _, err := db.GetDecision("test-user-002", rec.ID); err == nil {
+		t.Fatal("other users must not read the decision")
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDecryptAlertThreshold 窗口期内解密失败达到该次数即触发告警
+	defaultDecryptAlertThreshold = 5
+	// defaultDecryptAlertWindow 解密失败统计窗口
+	defaultDecryptAlertWindow = 5 * time.Minute
+)
+
+// DecryptFailureAlertFunc 解密失败告警回调
+// count 为窗口期内的失败次数，lastErr 为最近一次失败的错误
+type DecryptFailureAlertFunc func(count int, window time.Duration, lastErr error)
+
+// DecryptFailureStats 解密失败统计（用于健康检查/监控指标）
+type DecryptFailureStats struct {
+	Total         int64     `json:"total"`           // 启动以来累计失败次数
+	InWindow      int       `json:"in_window"`       // 当前窗口期内失败次数
+	WindowSeconds int       `json:"window_seconds"`  // 统计窗口（秒）
+	Threshold     int       `json:"threshold"`       // 告警阈值
+	Alerts        int64     `json:"alerts"`          // 已触发告警次数
+	LastFailureAt time.Time `json:"last_failure_at"` // 最近一次失败时间
+}
+
+// decryptFailureMonitor 统计解密失败并在超过阈值时告警
+type decryptFailureMonitor struct {
+	mu            sync.Mutex
+	threshold     int
+	window        time.Duration
+	failures      []time.Time // 窗口期内的失败时间
+	total         int64
+	alerts        int64
+	lastFailureAt time.Time
+	alertedAt     time.Time // 上次告警时间，同一窗口内只告警一次
+	alertFunc     DecryptFailureAlertFunc
+	now           func() time.Time
+}
+
+func newDecryptFailureMonitor() *decryptFailureMonitor {
+	return &decryptFailureMonitor{
+		threshold: defaultDecryptAlertThreshold,
+		window:    defaultDecryptAlertWindow,
+		alertFunc: logDecryptFailureAlert,
+		now:       time.Now,
+	}
+}
+
+// logDecryptFailureAlert 默认告警：输出醒目日志
+func logDecryptFailureAlert(count int, window time.Duration, lastErr error) {
+	log.Printf("🚨 [告警] %v 内解密失败 %d 次，请检查 DATA_ENCRYPTION_KEY / RSA 密钥是否正确: %v", window, count, lastErr)
+}
+
+// record 记录一次解密失败，超过阈值时触发告警
+func (m *decryptFailureMonitor) record(err error) {
+	m.mu.Lock()
+	now := m.now()
+	m.total++
+	m.lastFailureAt = now
+	m.failures = append(m.pruneLocked(now), now)
+
+	count := len(m.failures)
+	shouldAlert := count >= m.threshold && (m.alertedAt.IsZero() || now.Sub(m.alertedAt) >= m.window)
+	if shouldAlert {
+		m.alertedAt = now
+		m.alerts++
+	}
+	alertFunc := m.alertFunc
+	window := m.window
+	m.mu.Unlock()
+
+	// 在锁外调用回调，避免回调阻塞统计
+	if shouldAlert && alertFunc != nil {
+		alertFunc(count, window, err)
+	}
+}
+
+// pruneLocked 移除窗口期外的失败记录（调用方需持有锁）
+func (m *decryptFailureMonitor) pruneLocked(now time.Time) []time.Time {
+	cutoff := now.Add(-m.window)
+	idx := 0
+	for idx < len(m.failures) && !m.failures[idx].After(cutoff) {
+		idx++
+	}
+	return m.failures[idx:]
+}
+
+// stats 返回当前统计快照
+func (m *decryptFailureMonitor) stats() DecryptFailureStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures = m.pruneLocked(m.now())
+	return DecryptFailureStats{
+		Total:         m.total,
+		InWindow:      len(m.failures),
+		WindowSeconds: int(m.window / time.Second),
+		Threshold:     m.threshold,
+		Alerts:        m.alerts,
+		LastFailureAt: m.lastFailureAt,
+	}
+}
+
+// SetDecryptFailureAlert 设置解密失败告警回调和阈值
+// threshold/window 小于等于0时保持默认值；alertFunc 为 nil 时使用默认日志告警
+func (d *Database) SetDecryptFailureAlert(threshold int, window time.Duration, alertFunc DecryptFailureAlertFunc) {
+	d.decryptMonitor.mu.Lock()
+	defer d.decryptMonitor.mu.Unlock()
+
+	if threshold > 0 {
+		d.decryptMonitor.threshold = threshold
+	}
+	if window > 0 {
+		d.decryptMonitor.window = window
+	}
+	if alertFunc == nil {
+		alertFunc = logDecryptFailureAlert
+	}
+	d.decryptMonitor.alertFunc = alertFunc
+}
+
+// GetDecryptFailureStats 获取解密失败统计
+func (d *Database) GetDecryptFailureStats() DecryptFailureStats {
+	return d.decryptMonitor.stats()
+}
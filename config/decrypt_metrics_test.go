@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestDecryptFailureMonitor_AlertOncePerWindow 测试超过阈值时每个窗口只告警一次
+func TestDecryptFailureMonitor_AlertOncePerWindow(t *testing.T) {
+	m := newDecryptFailureMonitor()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.threshold = 3
+	m.window = time.Minute
+
+	alerts := 0
+	m.alertFunc = func(count int, window time.Duration, lastErr error) {
+		alerts++
+		if count < 3 {
+			t.Errorf("告警时失败次数应 >= 3，实际 %d", count)
+		}
+	}
+
+	errDecrypt := errors.New("bad key")
+	for i := 0; i < 5; i++ {
+		m.record(errDecrypt)
+		now = now.Add(time.Second)
+	}
+	if alerts != 1 {
+		t.Fatalf("同一窗口内应只告警一次，实际 %d", alerts)
+	}
+
+	stats := m.stats()
+	if stats.Total != 5 || stats.InWindow != 5 || stats.Alerts != 1 {
+		t.Errorf("统计不正确: %+v", stats)
+	}
+
+	// 窗口过期后计数清零
+	now = now.Add(2 * time.Minute)
+	if got := m.stats().InWindow; got != 0 {
+		t.Errorf("窗口过期后 InWindow 应为 0，实际 %d", got)
+	}
+
+	// 新窗口内再次超过阈值会重新告警
+	for i := 0; i < 3; i++ {
+		m.record(errDecrypt)
+	}
+	if alerts != 2 {
+		t.Errorf("新窗口应再次告警，实际告警 %d 次", alerts)
+	}
+}
+
+// TestDecryptSensitiveData_CountsFailures 测试解密失败会被计数且仍返回原文
+func TestDecryptSensitiveData_CountsFailures(t *testing.T) {
+	t.Setenv("DATA_ENCRYPTION_KEY", "test-key-32-bytes-long-for-aes")
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if db.cryptoService == nil {
+		t.Skip("加密服务不可用，跳过")
+	}
+
+	bogus := "ENC:v1:invalid:payload"
+	var alerted int
+	db.SetDecryptFailureAlert(1, time.Minute, func(count int, window time.Duration, lastErr error) {
+		alerted = count
+	})
+
+	if got := db.decryptSensitiveData(bogus); got != bogus {
+		t.Errorf("解密失败时应返回原文，实际 %q", got)
+	}
+	if stats := db.GetDecryptFailureStats(); stats.Total != 1 {
+		t.Errorf("解密失败次数应为 1，实际 %d", stats.Total)
+	}
+	if alerted != 1 {
+		t.Errorf("达到阈值应触发告警，实际 %d", alerted)
+	}
+}
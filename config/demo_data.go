@@ -0,0 +1,175 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 演示数据使用的固定标识（重复执行 SeedDemoData 时按这些标识判断是否已存在）
+const (
+	DemoUserID    = "demo"
+	DemoUserEmail = "demo@nofx.local"
+)
+
+// ErrDatabaseNotEmpty 数据库已有真实用户数据，拒绝写入演示数据
+var ErrDatabaseNotEmpty = errors.New("数据库已有用户数据，拒绝写入演示数据")
+
+// DemoCredentials 新建演示用户时随机生成的登录凭据（只在创建时返回一次，不会写入日志）
+type DemoCredentials struct {
+	Email     string
+	Password  string
+	OTPSecret string
+}
+
+// newDemoCredentials 生成随机密码和 OTP 密钥（base32，可直接导入验证器）
+func newDemoCredentials() (*DemoCredentials, error) {
+	password := make([]byte, 12)
+	if _, err := rand.Read(password); err != nil {
+		return nil, fmt.Errorf("生成演示用户密码失败: %w", err)
+	}
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("生成演示用户OTP密钥失败: %w", err)
+	}
+	return &DemoCredentials{
+		Email:     DemoUserEmail,
+		Password:  base64.RawURLEncoding.EncodeToString(password),
+		OTPSecret: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret),
+	}, nil
+}
+
+// demoTraders 演示交易员（ID 固定，保证幂等）
+var demoTraders = []TraderRecord{
+	{
+		ID: "demo_trend_btc", Name: "Demo 趋势跟踪", InitialBalance: 1000, ScanIntervalMinutes: 5,
+		BTCETHLeverage: 5, AltcoinLeverage: 3, TradingSymbols: "BTCUSDT,ETHUSDT",
+		SystemPromptTemplate: "default", IsCrossMargin: true, OrderStrategy: "market_only", Timeframes: "4h",
+	},
+	{
+		ID: "demo_scalper_alt", Name: "Demo 山寨短线", InitialBalance: 500, ScanIntervalMinutes: 3,
+		BTCETHLeverage: 10, AltcoinLeverage: 5, TradingSymbols: "SOLUSDT,BNBUSDT,DOGEUSDT",
+		SystemPromptTemplate: "default", IsCrossMargin: false, OrderStrategy: "conservative_hybrid",
+		LimitPriceOffset: -0.03, LimitTimeoutSeconds: 60, Timeframes: "15m,1h",
+		LossStreakThreshold: 3, CooldownMinutes: 60,
+	},
+}
+
+// SeedDemoData 写入演示数据：演示用户、启用的（假）交易所和 AI 模型配置，以及两个设置各异的交易员
+// 仅在数据库中没有除系统用户和演示用户之外的用户时执行，有真实用户时一律拒绝
+// 可重复调用：已存在的数据不会重复创建；演示交易员均为停止状态，密钥为假值，不会真实下单
+// 新建演示用户时返回随机生成的密码和 OTP 密钥（调用方负责展示一次），演示用户已存在时返回 nil
+func (d *Database) SeedDemoData() (*DemoCredentials, error) {
+	var realUsers int
+	if err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM users WHERE id NOT IN ('default', 'admin', ?)
+	`, DemoUserID).Scan(&realUsers); err != nil {
+		return nil, fmt.Errorf("检查用户数据失败: %w", err)
+	}
+	if realUsers > 0 {
+		return nil, ErrDatabaseNotEmpty
+	}
+
+	var creds *DemoCredentials
+	if _, err := d.GetUserByID(DemoUserID); err != nil {
+		if creds, err = newDemoCredentials(); err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("生成演示用户密码失败: %w", err)
+		}
+		if err := d.CreateUser(&User{
+			ID: DemoUserID, Email: DemoUserEmail, PasswordHash: string(hash),
+			OTPSecret: creds.OTPSecret, OTPVerified: true,
+		}); err != nil {
+			return nil, fmt.Errorf("创建演示用户失败: %w", err)
+		}
+	}
+
+	aiModelID, err := d.ensureDemoAIModel()
+	if err != nil {
+		return nil, err
+	}
+	exchangeID, err := d.ensureDemoExchange()
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := d.GetTraders(DemoUserID)
+	if err != nil {
+		return nil, fmt.Errorf("查询演示交易员失败: %w", err)
+	}
+	have := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		have[t.ID] = true
+	}
+	created := 0
+	for _, tpl := range demoTraders {
+		if have[tpl.ID] {
+			continue
+		}
+		trader := tpl
+		trader.UserID = DemoUserID
+		trader.AIModelID = aiModelID
+		trader.ExchangeID = exchangeID
+		if err := d.CreateTrader(&trader); err != nil {
+			return nil, fmt.Errorf("创建演示交易员 %s 失败: %w", trader.ID, err)
+		}
+		created++
+	}
+
+	log.Printf("🌱 演示数据已就绪（用户 %s，新建 %d 个交易员）", DemoUserEmail, created)
+	return creds, nil
+}
+
+// ensureDemoAIModel 确保演示用户有一个启用的 DeepSeek 配置（假密钥），返回其自增ID
+func (d *Database) ensureDemoAIModel() (int, error) {
+	find := func() (int, error) {
+		models, err := d.GetAIModels(DemoUserID)
+		if err != nil {
+			return 0, fmt.Errorf("查询演示AI模型失败: %w", err)
+		}
+		for _, m := range models {
+			if m.ModelID == "deepseek" {
+				return m.ID, nil
+			}
+		}
+		return 0, nil
+	}
+	if id, err := find(); err != nil || id > 0 {
+		return id, err
+	}
+	if err := d.CreateAIModel(DemoUserID, "deepseek", "DeepSeek (Demo)", "deepseek", true, "sk-demo-not-a-real-key", ""); err != nil {
+		return 0, fmt.Errorf("创建演示AI模型失败: %w", err)
+	}
+	return find()
+}
+
+// ensureDemoExchange 确保演示用户有一个启用的 Binance 测试网配置（假密钥），返回其自增ID
+func (d *Database) ensureDemoExchange() (int, error) {
+	find := func() (int, error) {
+		exchanges, err := d.GetExchangesMetadata(DemoUserID)
+		if err != nil {
+			return 0, fmt.Errorf("查询演示交易所失败: %w", err)
+		}
+		for _, ex := range exchanges {
+			if ex.ExchangeID == "binance" {
+				return ex.ID, nil
+			}
+		}
+		return 0, nil
+	}
+	if id, err := find(); err != nil || id > 0 {
+		return id, err
+	}
+	if err := d.CreateExchange(DemoUserID, "binance", "Binance (Demo)", "cex", true, "demo-api-key", "demo-secret-key", true, "", "", "", ""); err != nil {
+		return 0, fmt.Errorf("创建演示交易所失败: %w", err)
+	}
+	return find()
+}
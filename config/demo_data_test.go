@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSeedDemoData(t *testing.T) {
+	t.Run("空库写入且可重复执行", func(t *testing.T) {
+		db, err := NewDatabase(t.TempDir() + "/demo.db")
+		require.NoError(t, err)
+		defer db.Close()
+
+		creds, err := db.SeedDemoData()
+		require.NoError(t, err)
+		require.NotNil(t, creds)
+		again, err := db.SeedDemoData()
+		require.NoError(t, err)
+		assert.Nil(t, again, "演示用户已存在时不再返回凭据")
+
+		user, err := db.GetUserByID(DemoUserID)
+		require.NoError(t, err)
+		assert.Equal(t, DemoUserEmail, user.Email)
+		assert.True(t, user.OTPVerified)
+		assert.Equal(t, creds.OTPSecret, user.OTPSecret)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password)))
+
+		models, err := db.GetAIModels(DemoUserID)
+		require.NoError(t, err)
+		assert.Len(t, models, 1)
+		assert.True(t, models[0].Enabled)
+
+		exchanges, err := db.GetExchangesMetadata(DemoUserID)
+		require.NoError(t, err)
+		assert.Len(t, exchanges, 1)
+		assert.True(t, exchanges[0].Enabled)
+
+		traders, err := db.GetTraders(DemoUserID)
+		require.NoError(t, err)
+		require.Len(t, traders, len(demoTraders))
+		for _, tr := range traders {
+			assert.False(t, tr.IsRunning)
+			assert.Equal(t, models[0].ID, tr.AIModelID)
+			assert.Equal(t, exchanges[0].ID, tr.ExchangeID)
+		}
+	})
+
+	t.Run("每次新建演示用户生成不同的凭据", func(t *testing.T) {
+		db1, err := NewDatabase(t.TempDir() + "/demo1.db")
+		require.NoError(t, err)
+		defer db1.Close()
+		db2, err := NewDatabase(t.TempDir() + "/demo2.db")
+		require.NoError(t, err)
+		defer db2.Close()
+
+		creds1, err := db1.SeedDemoData()
+		require.NoError(t, err)
+		creds2, err := db2.SeedDemoData()
+		require.NoError(t, err)
+		assert.NotEqual(t, creds1.Password, creds2.Password)
+		assert.NotEqual(t, creds1.OTPSecret, creds2.OTPSecret)
+		assert.GreaterOrEqual(t, len(creds1.Password), 12)
+	})
+
+	t.Run("已有用户数据时一律拒绝", func(t *testing.T) {
+		db, cleanup := setupTestDB(t)
+		defer cleanup()
+
+		_, err := db.SeedDemoData()
+		assert.True(t, errors.Is(err, ErrDatabaseNotEmpty))
+		_, err = db.GetUserByID(DemoUserID)
+		assert.Error(t, err)
+
+		// 旧版本的 allow_demo_seed 标记不再生效
+		require.NoError(t, db.SetSystemConfig("allow_demo_seed", "true"))
+		_, err = db.SeedDemoData()
+		assert.True(t, errors.Is(err, ErrDatabaseNotEmpty))
+		_, err = db.GetUserByID(DemoUserID)
+		assert.Error(t, err)
+	})
+}
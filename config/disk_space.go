@@ -0,0 +1,35 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrInsufficientDiskSpace 磁盘剩余空间不足以创建迁移前备份
+var ErrInsufficientDiskSpace = errors.New("磁盘空间不足，无法创建迁移前备份")
+
+// diskFreeBytes 获取目录所在磁盘的可用字节数，ok=false 表示当前平台无法获取（测试中可替换）
+var diskFreeBytes = freeDiskBytes
+
+// checkBackupDiskSpace 在备份前检查数据库所在磁盘的剩余空间
+// 备份需要与数据库（含 WAL）大致相同的空间，另留 10% 余量；无法获取剩余空间时放行（尽力而为）
+func checkBackupDiskSpace(dbPath string) error {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return nil
+	}
+	required := uint64(info.Size())
+	if wal, err := os.Stat(dbPath + "-wal"); err == nil {
+		required += uint64(wal.Size())
+	}
+	required += required / 10
+
+	free, ok := diskFreeBytes(filepath.Dir(dbPath))
+	if !ok || free >= required {
+		return nil
+	}
+	return fmt.Errorf("%w: 需要约 %.1f MB，剩余 %.1f MB，请释放 %s 所在磁盘的空间后重启",
+		ErrInsufficientDiskSpace, float64(required)/1024/1024, float64(free)/1024/1024, filepath.Dir(dbPath))
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package config
+
+// freeDiskBytes 当前平台不支持获取磁盘剩余空间
+func freeDiskBytes(dir string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func withDiskFree(t *testing.T, free uint64, ok bool) {
+	t.Helper()
+	old := diskFreeBytes
+	diskFreeBytes = func(string) (uint64, bool) { return free, ok }
+	t.Cleanup(func() { diskFreeBytes = old })
+}
+
+func TestCheckBackupDiskSpace(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "space.db")
+	if err := os.WriteFile(dbPath, make([]byte, 1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	withDiskFree(t, 500, true)
+	if err := checkBackupDiskSpace(dbPath); !errors.Is(err, ErrInsufficientDiskSpace) {
+		t.Fatalf("expected ErrInsufficientDiskSpace, got %v", err)
+	}
+
+	withDiskFree(t, 10000, true)
+	if err := checkBackupDiskSpace(dbPath); err != nil {
+		t.Fatalf("enough space should pass: %v", err)
+	}
+
+	// 无法获取剩余空间时放行
+	withDiskFree(t, 0, false)
+	if err := checkBackupDiskSpace(dbPath); err != nil {
+		t.Fatalf("unknown free space should pass: %v", err)
+	}
+
+	if free, ok := freeDiskBytes(t.TempDir()); ok && free == 0 {
+		t.Errorf("temp dir reported 0 free bytes")
+	}
+}
+
+func TestMigrationRefusedOnFullDisk(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "full.db")
+	sqlDB, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	if _, err := sqlDB.Exec(`CREATE TABLE ai_models (id TEXT PRIMARY KEY, user_id TEXT, name TEXT, provider TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	withDiskFree(t, 0, true)
+	d := &Database{db: sqlDB, dbPath: dbPath}
+	if err := d.migrateToAutoIncrementID(); !errors.Is(err, ErrInsufficientDiskSpace) {
+		t.Fatalf("expected migration to be refused, got %v", err)
+	}
+
+	var count int
+	sqlDB.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('ai_models') WHERE name = 'model_id'`).Scan(&count)
+	if count != 0 {
+		t.Fatal("ai_models must not be migrated without a backup")
+	}
+	if matches, _ := filepath.Glob(dbPath + ".backup.*"); len(matches) != 0 {
+		t.Fatalf("no backup should be attempted, found %v", matches)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd
+
+package config
+
+import "syscall"
+
+// freeDiskBytes 使用 statfs 获取非特权用户可用的字节数
+func freeDiskBytes(dir string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
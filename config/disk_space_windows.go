@@ -0,0 +1,24 @@
+//go:build windows
+
+package config
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// freeDiskBytes 使用 GetDiskFreeSpaceExW 获取调用者可用的字节数
+func freeDiskBytes(dir string) (uint64, bool) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, false
+	}
+	var freeBytesAvailable uint64
+	ret, _, _ := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&freeBytesAvailable)), 0, 0)
+	if ret == 0 {
+		return 0, false
+	}
+	return freeBytesAvailable, true
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidEnvTrader TRADER_* 环境变量配置无效
+var ErrInvalidEnvTrader = errors.New("环境变量交易员配置无效")
+
+// EnvTraderConfig 通过 TRADER_* 环境变量声明的交易员（无UI的单交易员容器部署）
+type EnvTraderConfig struct {
+	UserID    string
+	Trader    TraderRecord
+	AutoStart bool // TRADER_AUTO_START，默认 true：每次启动都标记为运行中
+
+	// TRADER_TAKER_FEE_RATE / TRADER_MAKER_FEE_RATE，nil 表示未设置：
+	// 新建时使用交易所默认费率，已存在时保留当前费率（可能由UI修改）
+	TakerFeeRate *float64
+	MakerFeeRate *float64
+
+	AIProvider      string // deepseek / qwen / custom
+	AIAPIKey        string
+	AICustomAPIURL  string
+	AICustomModel   string
+	ExchangeID      string // binance / hyperliquid / aster
+	ExchangeAPIKey  string
+	ExchangeSecret  string
+	ExchangeTestnet bool
+
+	HyperliquidWalletAddr string
+	AsterUser             string
+	AsterSigner           string
+	AsterPrivateKey       string
+}
+
+// ParseEnvTrader 从环境变量解析交易员配置；未设置 TRADER_ID 时返回 nil（不启用）
+// getenv 通常为 os.Getenv
+func ParseEnvTrader(getenv func(string) string) (*EnvTraderConfig, error) {
+	get := func(key string) string { return strings.TrimSpace(getenv(key)) }
+
+	traderID := get("TRADER_ID")
+	if traderID == "" {
+		return nil, nil
+	}
+
+	cfg := &EnvTraderConfig{
+		UserID:    get("TRADER_USER_ID"),
+		AutoStart: true,
+
+		AIProvider:     strings.ToLower(get("TRADER_AI_PROVIDER")),
+		AIAPIKey:       get("TRADER_AI_API_KEY"),
+		AICustomAPIURL: get("TRADER_AI_BASE_URL"),
+		AICustomModel:  get("TRADER_AI_MODEL"),
+		ExchangeID:     strings.ToLower(get("TRADER_EXCHANGE")),
+		ExchangeAPIKey: get("TRADER_EXCHANGE_API_KEY"),
+		ExchangeSecret: get("TRADER_EXCHANGE_SECRET_KEY"),
+
+		HyperliquidWalletAddr: get("TRADER_HYPERLIQUID_WALLET_ADDR"),
+		AsterUser:             get("TRADER_ASTER_USER"),
+		AsterSigner:           get("TRADER_ASTER_SIGNER"),
+		AsterPrivateKey:       get("TRADER_ASTER_PRIVATE_KEY"),
+	}
+	if cfg.UserID == "" {
+		cfg.UserID = "default"
+	}
+	if cfg.AIProvider == "" {
+		return nil, fmt.Errorf("%w: 必须设置 TRADER_AI_PROVIDER", ErrInvalidEnvTrader)
+	}
+	if cfg.ExchangeID == "" {
+		return nil, fmt.Errorf("%w: 必须设置 TRADER_EXCHANGE", ErrInvalidEnvTrader)
+	}
+
+	parseBool := func(key string, def bool) (bool, error) {
+		raw := get(key)
+		if raw == "" {
+			return def, nil
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return false, fmt.Errorf("%w: %s 必须为 true/false，当前为 %q", ErrInvalidEnvTrader, key, raw)
+		}
+		return v, nil
+	}
+	parseInt := func(key string, def int) (int, error) {
+		raw := get(key)
+		if raw == "" {
+			return def, nil
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			return 0, fmt.Errorf("%w: %s 必须为正整数，当前为 %q", ErrInvalidEnvTrader, key, raw)
+		}
+		return v, nil
+	}
+
+	var err error
+	if cfg.AutoStart, err = parseBool("TRADER_AUTO_START", true); err != nil {
+		return nil, err
+	}
+	if cfg.ExchangeTestnet, err = parseBool("TRADER_EXCHANGE_TESTNET", false); err != nil {
+		return nil, err
+	}
+
+	t := &cfg.Trader
+	t.ID = traderID
+	t.UserID = cfg.UserID
+	t.Name = get("TRADER_NAME")
+	if t.Name == "" {
+		t.Name = traderID
+	}
+	t.TradingSymbols = get("TRADER_SYMBOLS")
+	t.Timeframes = get("TRADER_TIMEFRAMES")
+	t.CustomPrompt = get("TRADER_CUSTOM_PROMPT")
+	t.Timezone = get("TRADER_TIMEZONE")
+	t.SystemPromptTemplate = get("TRADER_PROMPT_TEMPLATE")
+	if t.SystemPromptTemplate == "" {
+		t.SystemPromptTemplate = "default"
+	}
+	t.OrderStrategy = get("TRADER_ORDER_STRATEGY")
+	if t.OrderStrategy == "" {
+		t.OrderStrategy = "market_only"
+	}
+
+	// TRADER_LEVERAGE 同时设置 BTC/ETH 和山寨币杠杆，可分别用 TRADER_BTC_ETH_LEVERAGE / TRADER_ALTCOIN_LEVERAGE 覆盖
+	leverage, err := parseInt("TRADER_LEVERAGE", 5)
+	if err != nil {
+		return nil, err
+	}
+	if t.BTCETHLeverage, err = parseInt("TRADER_BTC_ETH_LEVERAGE", leverage); err != nil {
+		return nil, err
+	}
+	if t.AltcoinLeverage, err = parseInt("TRADER_ALTCOIN_LEVERAGE", leverage); err != nil {
+		return nil, err
+	}
+	if t.ScanIntervalMinutes, err = parseInt("TRADER_SCAN_INTERVAL_MINUTES", 3); err != nil {
+		return nil, err
+	}
+	if t.IsCrossMargin, err = parseBool("TRADER_CROSS_MARGIN", true); err != nil {
+		return nil, err
+	}
+	if t.UseCoinPool, err = parseBool("TRADER_USE_COIN_POOL", false); err != nil {
+		return nil, err
+	}
+	if t.UseOITop, err = parseBool("TRADER_USE_OI_TOP", false); err != nil {
+		return nil, err
+	}
+
+	parseFeeRate := func(key string) (*float64, error) {
+		raw := get(key)
+		if raw == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v >= 0.01 {
+			return nil, fmt.Errorf("%w: %s 必须为 0 到 0.01 之间的小数（例如 0.0004），当前为 %q", ErrInvalidEnvTrader, key, raw)
+		}
+		return &v, nil
+	}
+	if cfg.TakerFeeRate, err = parseFeeRate("TRADER_TAKER_FEE_RATE"); err != nil {
+		return nil, err
+	}
+	if cfg.MakerFeeRate, err = parseFeeRate("TRADER_MAKER_FEE_RATE"); err != nil {
+		return nil, err
+	}
+
+	raw := get("TRADER_INITIAL_BALANCE")
+	balance, err := strconv.ParseFloat(raw, 64)
+	if err != nil || balance <= 0 {
+		return nil, fmt.Errorf("%w: TRADER_INITIAL_BALANCE 必须为正数，当前为 %q", ErrInvalidEnvTrader, raw)
+	}
+	t.InitialBalance = balance
+
+	return cfg, nil
+}
+
+// ReconcileEnvTrader 按环境变量配置创建或覆盖交易员及其AI模型、交易所配置（每次启动执行，环境变量为唯一来源）
+// 已存在的交易员只覆盖环境变量声明的字段并保留创建时间；AutoStart 时标记为运行中，由启动流程自动恢复
+func (d *Database) ReconcileEnvTrader(cfg *EnvTraderConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if _, err := d.GetUserByID(cfg.UserID); err != nil {
+		return fmt.Errorf("环境变量交易员所属用户不存在: %s", cfg.UserID)
+	}
+
+	if err := d.UpdateAIModel(cfg.UserID, cfg.AIProvider, true, cfg.AIAPIKey, cfg.AICustomAPIURL, cfg.AICustomModel); err != nil {
+		return fmt.Errorf("同步AI模型配置失败: %w", err)
+	}
+	aiModelID, err := d.findAIModelID(cfg.UserID, cfg.AIProvider)
+	if err != nil {
+		return err
+	}
+
+	if err := d.UpdateExchange(cfg.UserID, cfg.ExchangeID, true, cfg.ExchangeAPIKey, cfg.ExchangeSecret, cfg.ExchangeTestnet,
+		cfg.HyperliquidWalletAddr, cfg.AsterUser, cfg.AsterSigner, cfg.AsterPrivateKey); err != nil {
+		return fmt.Errorf("同步交易所配置失败: %w", err)
+	}
+	exchangeID, err := d.findExchangeID(cfg.UserID, cfg.ExchangeID)
+	if err != nil {
+		return err
+	}
+
+	var existing *TraderRecord
+	traders, err := d.GetTraders(cfg.UserID)
+	if err != nil {
+		return fmt.Errorf("查询交易员失败: %w", err)
+	}
+	for _, t := range traders {
+		if t.ID == cfg.Trader.ID {
+			existing = t
+			break
+		}
+	}
+
+	var trader TraderRecord
+	if existing != nil {
+		// 只覆盖环境变量声明的字段，其余字段（UI 设置的止盈止损、冷却、权重等）保持不变
+		trader = *existing
+		cfg.applyTo(&trader)
+	} else {
+		trader = cfg.Trader
+		rates := d.GetExchangeFeeRates(cfg.ExchangeID)
+		trader.TakerFeeRate, trader.MakerFeeRate = rates.Taker, rates.Maker
+	}
+	trader.UserID = cfg.UserID
+	trader.AIModelID = aiModelID
+	trader.ExchangeID = exchangeID
+	if cfg.TakerFeeRate != nil {
+		trader.TakerFeeRate = *cfg.TakerFeeRate
+	}
+	if cfg.MakerFeeRate != nil {
+		trader.MakerFeeRate = *cfg.MakerFeeRate
+	}
+
+	exists := existing != nil
+	if exists {
+		if err := d.UpdateTrader(&trader); err != nil {
+			return fmt.Errorf("更新环境变量交易员失败: %w", err)
+		}
+	} else {
+		if err := d.CreateTrader(&trader); err != nil {
+			return fmt.Errorf("创建环境变量交易员失败: %w", err)
+		}
+	}
+
+	if cfg.AutoStart {
+		if err := d.UpdateTraderStatus(cfg.UserID, trader.ID, true, ""); err != nil {
+			return fmt.Errorf("标记环境变量交易员运行状态失败: %w", err)
+		}
+	}
+
+	action := "创建"
+	if exists {
+		action = "同步"
+	}
+	log.Printf("🧩 已按环境变量%s交易员 %s（%s / %s）", action, trader.ID, cfg.AIProvider, cfg.ExchangeID)
+	return nil
+}
+
+// applyTo 将环境变量声明的交易员字段覆盖到 t 上（扫描间隔按 TRADER_SCAN_INTERVAL_MINUTES 计算）
+func (cfg *EnvTraderConfig) applyTo(t *TraderRecord) {
+	env := cfg.Trader
+	t.Name = env.Name
+	t.TradingSymbols = env.TradingSymbols
+	t.Timeframes = env.Timeframes
+	t.CustomPrompt = env.CustomPrompt
+	t.Timezone = env.Timezone
+	t.SystemPromptTemplate = env.SystemPromptTemplate
+	t.OrderStrategy = env.OrderStrategy
+	t.BTCETHLeverage = env.BTCETHLeverage
+	t.AltcoinLeverage = env.AltcoinLeverage
+	t.ScanIntervalMinutes = env.ScanIntervalMinutes
+	t.ScanIntervalSeconds = env.ScanIntervalSeconds
+	t.IsCrossMargin = env.IsCrossMargin
+	t.UseCoinPool = env.UseCoinPool
+	t.UseOITop = env.UseOITop
+	t.InitialBalance = env.InitialBalance
+}
+
+// findAIModelID 按 model_id 查找用户的AI模型配置自增ID
+func (d *Database) findAIModelID(userID, modelID string) (int, error) {
+	models, err := d.GetAIModels(userID)
+	if err != nil {
+		return 0, fmt.Errorf("查询AI模型失败: %w", err)
+	}
+	for _, m := range models {
+		if m.ModelID == modelID {
+			return m.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("AI模型配置不存在: %s", modelID)
+}
+
+// findExchangeID 按 exchange_id 查找用户的交易所配置自增ID
+func (d *Database) findExchangeID(userID, exchangeID string) (int, error) {
+	exchanges, err := d.GetExchangesMetadata(userID)
+	if err != nil {
+		return 0, fmt.Errorf("查询交易所失败: %w", err)
+	}
+	for _, ex := range exchanges {
+		if ex.ExchangeID == exchangeID {
+			return ex.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("交易所配置不存在: %s", exchangeID)
+}
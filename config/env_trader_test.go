@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envLookup(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestParseEnvTrader(t *testing.T) {
+	cfg, err := ParseEnvTrader(envLookup(nil))
+	require.NoError(t, err)
+	assert.Nil(t, cfg, "未设置 TRADER_ID 时不启用")
+
+	env := map[string]string{
+		"TRADER_ID":               "env_btc",
+		"TRADER_SYMBOLS":          "BTCUSDT,ETHUSDT",
+		"TRADER_LEVERAGE":         "3",
+		"TRADER_ALTCOIN_LEVERAGE": "2",
+		"TRADER_INITIAL_BALANCE":  "500",
+		"TRADER_AI_PROVIDER":      "DeepSeek",
+		"TRADER_AI_API_KEY":       "sk-test",
+		"TRADER_EXCHANGE":         "binance",
+	}
+	cfg, err = ParseEnvTrader(envLookup(env))
+	require.NoError(t, err)
+	assert.Equal(t, "default", cfg.UserID)
+	assert.Equal(t, "env_btc", cfg.Trader.Name)
+	assert.Equal(t, 3, cfg.Trader.BTCETHLeverage)
+	assert.Equal(t, 2, cfg.Trader.AltcoinLeverage)
+	assert.Equal(t, 500.0, cfg.Trader.InitialBalance)
+	assert.Equal(t, "deepseek", cfg.AIProvider)
+	assert.True(t, cfg.AutoStart)
+	assert.Nil(t, cfg.TakerFeeRate, "未设置费率时由交易所默认费率决定")
+
+	env["TRADER_TAKER_FEE_RATE"] = "0.0005"
+	cfg, err = ParseEnvTrader(envLookup(env))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.TakerFeeRate)
+	assert.Equal(t, 0.0005, *cfg.TakerFeeRate)
+	assert.Nil(t, cfg.MakerFeeRate)
+	delete(env, "TRADER_TAKER_FEE_RATE")
+
+	for key, value := range map[string]string{
+		"TRADER_LEVERAGE":        "0",
+		"TRADER_INITIAL_BALANCE": "",
+		"TRADER_AUTO_START":      "yes please",
+		"TRADER_EXCHANGE":        "",
+		"TRADER_MAKER_FEE_RATE":  "2",
+	} {
+		bad := make(map[string]string, len(env))
+		for k, v := range env {
+			bad[k] = v
+		}
+		bad[key] = value
+		_, err := ParseEnvTrader(envLookup(bad))
+		assert.True(t, errors.Is(err, ErrInvalidEnvTrader), "%s=%q 应返回 ErrInvalidEnvTrader，实际 %v", key, value, err)
+	}
+}
+
+func TestReconcileEnvTrader(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	env := map[string]string{
+		"TRADER_ID":              "env_btc",
+		"TRADER_SYMBOLS":         "BTCUSDT",
+		"TRADER_LEVERAGE":        "3",
+		"TRADER_INITIAL_BALANCE": "500",
+		"TRADER_AI_PROVIDER":     "deepseek",
+		"TRADER_AI_API_KEY":      "sk-test",
+		"TRADER_EXCHANGE":        "binance",
+	}
+	cfg, err := ParseEnvTrader(envLookup(env))
+	require.NoError(t, err)
+	require.NoError(t, db.ReconcileEnvTrader(cfg))
+
+	traders, err := db.GetTraders("default")
+	require.NoError(t, err)
+	require.Len(t, traders, 1)
+	assert.Equal(t, "env_btc", traders[0].ID)
+	assert.Equal(t, 3, traders[0].BTCETHLeverage)
+	assert.True(t, traders[0].IsRunning)
+	assert.Equal(t, 0.0004, traders[0].TakerFeeRate, "新建时使用交易所默认费率")
+	assert.Equal(t, 0.0002, traders[0].MakerFeeRate)
+
+	// UI 修改了环境变量未声明的字段
+	ui := *traders[0]
+	ui.CooldownMinutes = 15
+	ui.TrailingStopPercent = 1.5
+	ui.MakerFeeRate = 0.0001
+	require.NoError(t, db.UpdateTrader(&ui))
+
+	// 重启时环境变量已修改：覆盖数据库中的配置，不重复创建
+	env["TRADER_LEVERAGE"] = "7"
+	env["TRADER_SYMBOLS"] = "BTCUSDT,ETHUSDT"
+	env["TRADER_AUTO_START"] = "false"
+	env["TRADER_TAKER_FEE_RATE"] = "0.0005"
+	require.NoError(t, db.UpdateTraderStatus("default", "env_btc", false, StopReasonUser))
+	cfg, err = ParseEnvTrader(envLookup(env))
+	require.NoError(t, err)
+	require.NoError(t, db.ReconcileEnvTrader(cfg))
+
+	traders, err = db.GetTraders("default")
+	require.NoError(t, err)
+	require.Len(t, traders, 1)
+	assert.Equal(t, 7, traders[0].BTCETHLeverage)
+	assert.Equal(t, 7, traders[0].AltcoinLeverage)
+	assert.Equal(t, "BTCUSDT,ETHUSDT", traders[0].TradingSymbols)
+	assert.False(t, traders[0].IsRunning)
+	assert.Equal(t, 0.0005, traders[0].TakerFeeRate, "显式设置的费率覆盖数据库")
+	assert.Equal(t, 0.0001, traders[0].MakerFeeRate, "未设置的费率保留UI修改")
+	assert.Equal(t, 15, traders[0].CooldownMinutes, "环境变量未声明的字段不应被覆盖")
+	assert.Equal(t, 1.5, traders[0].TrailingStopPercent)
+
+	models, err := db.GetAIModels("default")
+	require.NoError(t, err)
+	enabled := 0
+	for _, m := range models {
+		if m.ModelID == "deepseek" {
+			assert.True(t, m.Enabled)
+			assert.Equal(t, m.ID, traders[0].AIModelID)
+			enabled++
+		}
+	}
+	assert.Equal(t, 1, enabled)
+
+	cfg.UserID = "missing-user"
+	assert.Error(t, db.ReconcileEnvTrader(cfg))
+}
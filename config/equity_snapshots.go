@@ -0,0 +1,40 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// recordEquitySnapshotTx 在事务中写入一条净值快照
+func recordEquitySnapshotTx(tx *sql.Tx, traderID string, equity float64, now time.Time) error {
+	if _, err := tx.Exec(`
+		INSERT INTO equity_snapshots (trader_id, equity, created_at) VALUES (?, ?, ?)
+	`, traderID, equity, now.UTC().Format(sqliteTimeLayout)); err != nil {
+		return fmt.Errorf("记录净值快照失败: %w", err)
+	}
+	return nil
+}
+
+// GetTraderDrawdown 根据净值快照计算交易员回撤（百分比，与 max_drawdown 配置一致）
+// current 为最新净值相对历史峰值的回撤，max 为历史最大峰谷回撤；没有快照时均为 0
+// 峰值使用窗口函数在 SQL 中按时间累计取最大值
+func (d *Database) GetTraderDrawdown(userID, traderID string) (current, max float64, err error) {
+	err = d.db.QueryRow(`
+		WITH s AS (
+			SELECT e.equity,
+				MAX(e.equity) OVER (ORDER BY e.created_at, e.id ROWS UNBOUNDED PRECEDING) AS peak,
+				ROW_NUMBER() OVER (ORDER BY e.created_at DESC, e.id DESC) AS rn
+			FROM equity_snapshots e JOIN traders t ON t.id = e.trader_id
+			WHERE e.trader_id = ? AND t.user_id = ?
+		)
+		SELECT
+			COALESCE(MAX(CASE WHEN rn = 1 AND peak > 0 THEN (peak - equity) / peak * 100 END), 0),
+			COALESCE(MAX(CASE WHEN peak > 0 THEN (peak - equity) / peak * 100 END), 0)
+		FROM s
+	`, traderID, userID).Scan(&current, &max)
+	if err != nil {
+		return 0, 0, fmt.Errorf("计算回撤失败: %w", err)
+	}
+	return current, max, nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetTraderDrawdown(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-drawdown-1")
+	exID := ensureTestExchange(t, db, userID, "binance-drawdown-1")
+	tr := &TraderRecord{
+		ID: "tr-drawdown", UserID: userID, Name: "drawdown", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	if err := db.SetSystemConfig("max_daily_loss", "0"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	current, max, err := db.GetTraderDrawdown(userID, tr.ID)
+	if err != nil || current != 0 || max != 0 {
+		t.Fatalf("expected zero drawdown without snapshots, got %v/%v (%v)", current, max, err)
+	}
+
+	// 1000 → 1200（峰值）→ 900（-25%）→ 1100 → 1300（新峰值）→ 1170（-10%）
+	base := time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC)
+	for i, equity := range []float64{1000, 1200, 900, 1100, 1300, 1170} {
+		if _, err := db.recordDailyEquity(tr.ID, equity, base.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("recordDailyEquity failed: %v", err)
+		}
+	}
+
+	current, max, err = db.GetTraderDrawdown(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderDrawdown failed: %v", err)
+	}
+	if math.Abs(current-10) > 1e-9 || math.Abs(max-25) > 1e-9 {
+		t.Fatalf("expected current 10%% / max 25%%, got %v / %v", current, max)
+	}
+
+	if current, max, _ := db.GetTraderDrawdown("user1", tr.ID); current != 0 || max != 0 {
+		t.Fatalf("expected no drawdown for other user, got %v/%v", current, max)
+	}
+}
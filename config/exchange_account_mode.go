@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 币安账户类型
+const (
+	AccountModeStandard        = "standard"         // 标准U本位合约账户（/fapi）
+	AccountModePortfolioMargin = "portfolio_margin" // 统一账户（/papi）
+)
+
+// NormalizeAccountMode 规范化账户类型，空值视为标准账户
+func NormalizeAccountMode(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		return AccountModeStandard, nil
+	case AccountModeStandard, AccountModePortfolioMargin:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("不支持的账户类型: %s (可选: %s, %s)", mode, AccountModeStandard, AccountModePortfolioMargin)
+	}
+}
+
+// SetExchangeAccountMode 设置交易所的账户类型（目前仅币安支持统一账户）
+// exchangeID 可以是交易所类型（例如 "binance"）或 exchanges.id（同类型有多个账户时必须使用数字ID）
+func (d *Database) SetExchangeAccountMode(userID, exchangeID, mode string) error {
+	normalized, err := NormalizeAccountMode(mode)
+	if err != nil {
+		return err
+	}
+	where, args, _, err := d.exchangeRowFilter(userID, exchangeID)
+	if err != nil {
+		return err
+	}
+	var exchangeType string
+	if err := d.db.QueryRow(`SELECT exchange_id FROM exchanges WHERE `+where, args...).Scan(&exchangeType); err != nil {
+		return fmt.Errorf("交易所不存在: %s", exchangeID)
+	}
+	if normalized == AccountModePortfolioMargin && exchangeType != "binance" {
+		return fmt.Errorf("交易所 %s 不支持统一账户", exchangeType)
+	}
+
+	if _, err := d.db.Exec(`
+		UPDATE exchanges SET account_mode = ?, updated_at = datetime('now')
+		WHERE `+where, append([]interface{}{normalized}, args...)...); err != nil {
+		return fmt.Errorf("更新账户类型失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import "testing"
+
+func TestSetExchangeAccountMode(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	ensureTestExchange(t, db, userID, "binance")
+	ensureTestExchange(t, db, userID, "hyperliquid")
+
+	accountMode := func(exchangeID string) string {
+		exchanges, err := db.GetExchanges(userID)
+		if err != nil {
+			t.Fatalf("GetExchanges failed: %v", err)
+		}
+		for _, ex := range exchanges {
+			if ex.ExchangeID == exchangeID {
+				return ex.AccountMode
+			}
+		}
+		t.Fatalf("exchange %s not found", exchangeID)
+		return ""
+	}
+
+	if got := accountMode("binance"); got != AccountModeStandard {
+		t.Fatalf("expected default account mode %q, got %q", AccountModeStandard, got)
+	}
+
+	if err := db.SetExchangeAccountMode(userID, "binance", " Portfolio_Margin "); err != nil {
+		t.Fatalf("SetExchangeAccountMode failed: %v", err)
+	}
+	if got := accountMode("binance"); got != AccountModePortfolioMargin {
+		t.Fatalf("expected %q, got %q", AccountModePortfolioMargin, got)
+	}
+
+	if err := db.SetExchangeAccountMode(userID, "binance", "unified"); err == nil {
+		t.Fatal("expected error for unsupported account mode")
+	}
+	if err := db.SetExchangeAccountMode(userID, "hyperliquid", AccountModePortfolioMargin); err == nil {
+		t.Fatal("expected error for portfolio margin on non-binance exchange")
+	}
+	if err := db.SetExchangeAccountMode(userID, "aster", AccountModeStandard); err == nil {
+		t.Fatal("expected error for missing exchange")
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrAmbiguousExchange 用户有多个同类型交易所账户时，必须使用数字ID指定要操作的账户
+var ErrAmbiguousExchange = errors.New("存在多个同类型交易所账户，请使用数字ID指定")
+
+// ErrExchangeAccountExists 同一交易所类型下已有同名（display_name）账户
+var ErrExchangeAccountExists = errors.New("交易所账户已存在")
+
+// exchangeRowFilter 根据 id 构建定位交易所配置的 WHERE 条件（仅适用于自增ID结构）
+// id 为数字时按 exchanges.id 精确定位；否则按 exchange_id（交易所类型）定位，同类型有多个账户时返回 ErrAmbiguousExchange
+// byRowID 表示按数字ID定位，此时记录不存在不应自动创建
+func (d *Database) exchangeRowFilter(userID, id string) (clause string, args []interface{}, byRowID bool, err error) {
+	if rowID, convErr := strconv.Atoi(strings.TrimSpace(id)); convErr == nil && rowID > 0 {
+		return "id = ? AND user_id = ?", []interface{}{rowID, userID}, true, nil
+	}
+
+	var count int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM exchanges WHERE exchange_id = ? AND user_id = ?`, id, userID).Scan(&count); err != nil {
+		return "", nil, false, fmt.Errorf("查询交易所配置失败: %w", err)
+	}
+	if count > 1 {
+		return "", nil, false, fmt.Errorf("%w: %s", ErrAmbiguousExchange, id)
+	}
+	return "exchange_id = ? AND user_id = ?", []interface{}{id, userID}, false, nil
+}
+
+// CreateExchangeAccount 为用户新增一个交易所账户并返回其数字ID
+// 同一交易所类型可以有多个账户（例如两个币安账户），以 displayName 区分；同名账户已存在时返回错误
+func (d *Database) CreateExchangeAccount(userID, exchangeID, displayName, name, typ string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) (int, error) {
+	displayName = strings.TrimSpace(displayName)
+	if exchangeID == "" {
+		return 0, fmt.Errorf("交易所类型不能为空")
+	}
+
+	result, err := d.db.Exec(`
+		INSERT INTO exchanges (exchange_id, user_id, display_name, name, type, enabled, api_key, secret_key, testnet, hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, exchangeID, userID, displayName, name, typ, enabled, d.encryptSensitiveData(apiKey), d.encryptSensitiveData(secretKey),
+		testnet, hyperliquidWalletAddr, asterUser, asterSigner, d.encryptSensitiveData(asterPrivateKey))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return 0, fmt.Errorf("%w: %s (%s)", ErrExchangeAccountExists, exchangeID, displayName)
+		}
+		return 0, fmt.Errorf("创建交易所账户失败: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("获取交易所账户ID失败: %w", err)
+	}
+	return int(id), nil
+}
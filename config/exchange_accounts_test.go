@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestMultipleExchangeAccountsSameType(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	mainID := ensureTestExchange(t, db, userID, "binance")
+	secondID, err := db.CreateExchangeAccount(userID, "binance", "副账户", "Binance Futures", "cex", true, "key-2", "secret-2", false, "", "", "", "")
+	if err != nil {
+		t.Fatalf("CreateExchangeAccount failed: %v", err)
+	}
+	if secondID == mainID {
+		t.Fatal("second binance account must get its own id")
+	}
+	if _, err := db.CreateExchangeAccount(userID, "binance", "副账户", "Binance Futures", "cex", true, "", "", false, "", "", "", ""); err == nil {
+		t.Fatal("duplicate display name should be rejected")
+	}
+
+	// 按交易所类型更新已无法确定账户
+	if err := db.UpdateExchange(userID, "binance", true, "new-key", "", false, "", "", "", ""); !errors.Is(err, ErrAmbiguousExchange) {
+		t.Fatalf("expected ErrAmbiguousExchange, got %v", err)
+	}
+
+	// 按数字ID只更新指定账户
+	if err := db.UpdateExchange(userID, strconv.Itoa(secondID), true, "rotated-key", "", true, "", "", "", ""); err != nil {
+		t.Fatalf("UpdateExchange by id failed: %v", err)
+	}
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil {
+		t.Fatalf("GetExchanges failed: %v", err)
+	}
+	byID := make(map[int]*ExchangeConfig)
+	for _, ex := range exchanges {
+		byID[ex.ID] = ex
+	}
+	if len(byID) != 2 {
+		t.Fatalf("expected 2 exchange accounts, got %d", len(byID))
+	}
+	if ex := byID[secondID]; ex.APIKey != "rotated-key" || !ex.Testnet || ex.DisplayName != "副账户" {
+		t.Fatalf("second account not updated: %+v", ex)
+	}
+	if ex := byID[mainID]; ex.APIKey != "key" || ex.Testnet {
+		t.Fatalf("main account must stay untouched: %+v", ex)
+	}
+
+	if err := db.SetExchangeAccountMode(userID, strconv.Itoa(secondID), AccountModePortfolioMargin); err != nil {
+		t.Fatalf("SetExchangeAccountMode by id failed: %v", err)
+	}
+
+	// 不存在的数字ID不会自动创建记录
+	if err := db.UpdateExchange(userID, "99999", true, "", "", false, "", "", "", ""); err == nil {
+		t.Fatal("expected error for unknown exchange id")
+	}
+	if exchanges, _ := db.GetExchanges(userID); len(exchanges) != 2 {
+		t.Fatalf("unknown id must not create a row, got %d", len(exchanges))
+	}
+}
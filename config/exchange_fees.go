@@ -0,0 +1,56 @@
+package config
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+// exchangeFeeRatesKey system_config 中按交易所类型覆盖默认费率的配置
+// 格式：{"hyperliquid": {"taker": 0.00045, "maker": 0.00015}}
+const exchangeFeeRatesKey = "exchange_fee_rates"
+
+// ExchangeFeeRates 交易所默认手续费率（小数，例如 0.0004 表示 0.04%）
+type ExchangeFeeRates struct {
+	Taker float64 `json:"taker"`
+	Maker float64 `json:"maker"`
+}
+
+// fallbackFeeRates 未知交易所类型使用的默认费率（Binance 标准费率）
+var fallbackFeeRates = ExchangeFeeRates{Taker: 0.0004, Maker: 0.0002}
+
+// defaultExchangeFeeRates 各交易所基础档位的费率
+var defaultExchangeFeeRates = map[string]ExchangeFeeRates{
+	"binance":     {Taker: 0.0004, Maker: 0.0002},   // USDⓈ-M 合约 VIP0
+	"hyperliquid": {Taker: 0.00045, Maker: 0.00015}, // 永续合约基础档位
+	"aster":       {Taker: 0.00035, Maker: 0.0001},  // 永续合约基础档位
+}
+
+// GetExchangeFeeRates 获取交易所类型的默认费率，创建交易员未指定费率时使用
+// 优先读取 system_config.exchange_fee_rates 中的覆盖（只覆盖大于0的项），未知类型回退到 Binance 标准费率
+func (d *Database) GetExchangeFeeRates(exchangeType string) ExchangeFeeRates {
+	exchangeType = strings.ToLower(strings.TrimSpace(exchangeType))
+	rates, ok := defaultExchangeFeeRates[exchangeType]
+	if !ok {
+		rates = fallbackFeeRates
+	}
+
+	raw, _ := d.GetSystemConfig(exchangeFeeRatesKey)
+	if strings.TrimSpace(raw) == "" {
+		return rates
+	}
+	var overrides map[string]ExchangeFeeRates
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		log.Printf("⚠️ %s 不是有效的JSON，使用内置默认费率: %v", exchangeFeeRatesKey, err)
+		return rates
+	}
+	if override, ok := overrides[exchangeType]; ok {
+		if override.Taker > 0 {
+			rates.Taker = override.Taker
+		}
+		if override.Maker > 0 {
+			rates.Maker = override.Maker
+		}
+	}
+	return rates
+}
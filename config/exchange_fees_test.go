@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func TestGetExchangeFeeRates(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if got := db.GetExchangeFeeRates("hyperliquid"); got.Taker != 0.00045 || got.Maker != 0.00015 {
+		t.Fatalf("hyperliquid defaults: %+v", got)
+	}
+	if got := db.GetExchangeFeeRates(" Aster "); got.Taker != 0.00035 || got.Maker != 0.0001 {
+		t.Fatalf("aster defaults should be matched case-insensitively: %+v", got)
+	}
+	if got := db.GetExchangeFeeRates("unknown"); got != fallbackFeeRates {
+		t.Fatalf("unknown exchange should use fallback rates: %+v", got)
+	}
+
+	if err := db.SetSystemConfig(exchangeFeeRatesKey, `{"hyperliquid":{"taker":0.0003},"binance":{"taker":0.0005,"maker":0.00018}}`); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+	if got := db.GetExchangeFeeRates("hyperliquid"); got.Taker != 0.0003 || got.Maker != 0.00015 {
+		t.Fatalf("partial override should keep the built-in maker rate: %+v", got)
+	}
+	if got := db.GetExchangeFeeRates("binance"); got.Taker != 0.0005 || got.Maker != 0.00018 {
+		t.Fatalf("binance override: %+v", got)
+	}
+
+	if err := db.SetSystemConfig(exchangeFeeRatesKey, `not json`); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+	if got := db.GetExchangeFeeRates("aster"); got.Taker != 0.00035 {
+		t.Fatalf("invalid override should fall back to built-in rates: %+v", got)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ErrExchangeNotFound 交易所配置不存在（或不属于该用户）
+var ErrExchangeNotFound = errors.New("交易所配置不存在")
+
+// ErrExchangeKeyRejected 新密钥为空或未通过连接测试，原密钥保持不变
+var ErrExchangeKeyRejected = errors.New("新密钥不可用，已保留原密钥")
+
+// ExchangeKeyTester 使用给定的交易所配置执行一次需要鉴权的请求，返回 nil 表示密钥可用
+type ExchangeKeyTester func(exchange *ExchangeConfig) error
+
+// SetExchangeKeyTester 设置密钥轮换时使用的连接测试
+func (d *Database) SetExchangeKeyTester(tester ExchangeKeyTester) {
+	d.keyTester = tester
+}
+
+// RotateExchangeKeys 安全轮换交易所 API 密钥：新密钥先写入暂存列，连接测试通过后原子替换旧密钥，
+// 测试失败则清空暂存列、保留旧密钥，避免直接覆盖导致正在运行的交易员无法下单
+// newSecret 为空时保留原 Secret Key（Hyperliquid 等只需要私钥的交易所）
+func (d *Database) RotateExchangeKeys(userID string, exchangeID int, newAPIKey, newSecret string) error {
+	newAPIKey = strings.TrimSpace(newAPIKey)
+	newSecret = strings.TrimSpace(newSecret)
+	if newAPIKey == "" {
+		return fmt.Errorf("%w: 新的 API Key 不能为空", ErrExchangeKeyRejected)
+	}
+	if d.keyTester == nil {
+		return fmt.Errorf("未配置交易所连接测试，无法轮换密钥")
+	}
+
+	exchanges, err := d.GetExchanges(userID)
+	if err != nil {
+		return fmt.Errorf("获取交易所配置失败: %w", err)
+	}
+	var current *ExchangeConfig
+	for _, ex := range exchanges {
+		if ex.ID == exchangeID {
+			current = ex
+			break
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("%w: %d", ErrExchangeNotFound, exchangeID)
+	}
+
+	// 1. 写入暂存列（加密存储）
+	encryptedSecret := ""
+	if newSecret != "" {
+		encryptedSecret = d.encryptSensitiveData(newSecret)
+	}
+	if _, err := d.db.Exec(`
+		UPDATE exchanges SET pending_api_key = ?, pending_secret_key = COALESCE(NULLIF(?, ''), secret_key)
+		WHERE id = ? AND user_id = ?
+	`, d.encryptSensitiveData(newAPIKey), encryptedSecret, exchangeID, userID); err != nil {
+		return fmt.Errorf("暂存新密钥失败: %w", err)
+	}
+
+	// 2. 使用新密钥测试连接
+	candidate := *current
+	candidate.APIKey = newAPIKey
+	if newSecret != "" {
+		candidate.SecretKey = newSecret
+	}
+	if testErr := d.keyTester(&candidate); testErr != nil {
+		if _, err := d.db.Exec(`
+			UPDATE exchanges SET pending_api_key = '', pending_secret_key = ''
+			WHERE id = ? AND user_id = ?
+		`, exchangeID, userID); err != nil {
+			log.Printf("⚠️ 清理交易所 %d 暂存密钥失败: %v", exchangeID, err)
+		}
+		d.RecordAuditEvent(userID, AuditEntityExchange, strconv.Itoa(exchangeID), "rotate_keys_failed", testErr.Error())
+		return fmt.Errorf("%w: 连接测试失败: %v", ErrExchangeKeyRejected, testErr)
+	}
+
+	// 3. 原子替换：暂存列提升为正式密钥并清空
+	result, err := d.db.Exec(`
+		UPDATE exchanges SET api_key = pending_api_key, secret_key = pending_secret_key,
+			pending_api_key = '', pending_secret_key = '', updated_at = datetime('now')
+		WHERE id = ? AND user_id = ? AND pending_api_key != ''
+	`, exchangeID, userID)
+	if err != nil {
+		return fmt.Errorf("启用新密钥失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("暂存密钥已被并发修改，请重试")
+	}
+
+	d.RecordAuditEvent(userID, AuditEntityExchange, strconv.Itoa(exchangeID), "rotate_keys", "")
+	log.Printf("🔑 交易所 %d 密钥轮换成功 (用户 %s)", exchangeID, userID)
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRotateExchangeKeys(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	exID := ensureTestExchange(t, db, userID, "binance-rotate-1")
+
+	currentKeys := func() (apiKey, secret string) {
+		exchanges, err := db.GetExchanges(userID)
+		if err != nil {
+			t.Fatalf("GetExchanges failed: %v", err)
+		}
+		for _, ex := range exchanges {
+			if ex.ID == exID {
+				return ex.APIKey, ex.SecretKey
+			}
+		}
+		t.Fatalf("exchange %d not found", exID)
+		return "", ""
+	}
+	pendingKey := func() string {
+		var pending string
+		if err := db.db.QueryRow(`SELECT pending_api_key FROM exchanges WHERE id = ?`, exID).Scan(&pending); err != nil {
+			t.Fatalf("query pending key failed: %v", err)
+		}
+		return pending
+	}
+
+	if err := db.RotateExchangeKeys(userID, exID, "new-key", "new-secret"); err == nil {
+		t.Fatal("expected rotation to be refused without a key tester")
+	}
+
+	var tested []string
+	db.SetExchangeKeyTester(func(ex *ExchangeConfig) error {
+		tested = append(tested, ex.APIKey)
+		if ex.APIKey == "bad-key" {
+			return errors.New("invalid api key")
+		}
+		return nil
+	})
+
+	// 测试失败：保留旧密钥并清空暂存
+	err := db.RotateExchangeKeys(userID, exID, "bad-key", "bad-secret")
+	if err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Fatalf("expected tester error, got %v", err)
+	}
+	if key, secret := currentKeys(); key != "key" || secret != "secret" {
+		t.Fatalf("expected old keys to be kept, got %s/%s", key, secret)
+	}
+	if pending := pendingKey(); pending != "" {
+		t.Fatalf("expected staging columns to be cleared, got %q", pending)
+	}
+
+	if err := db.RotateExchangeKeys(userID, exID, "new-key", "new-secret"); err != nil {
+		t.Fatalf("RotateExchangeKeys failed: %v", err)
+	}
+	if key, secret := currentKeys(); key != "new-key" || secret != "new-secret" {
+		t.Fatalf("expected new keys to be promoted, got %s/%s", key, secret)
+	}
+
+	// Secret 为空时保留原 Secret
+	if err := db.RotateExchangeKeys(userID, exID, "newer-key", ""); err != nil {
+		t.Fatalf("RotateExchangeKeys failed: %v", err)
+	}
+	if key, secret := currentKeys(); key != "newer-key" || secret != "new-secret" {
+		t.Fatalf("expected secret to be kept, got %s/%s", key, secret)
+	}
+	if len(tested) != 3 || pendingKey() != "" {
+		t.Fatalf("unexpected tester calls %v / pending %q", tested, pendingKey())
+	}
+
+	if err := db.RotateExchangeKeys("user1", exID, "x", "y"); err == nil {
+		t.Fatal("expected error when rotating another user's exchange")
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxTakeProfitPercent 止盈百分比上限（相对开仓价）
+const maxTakeProfitPercent = 1000
+
+// ErrInvalidExitBracket 止盈止损（OCO）百分比配置无效
+var ErrInvalidExitBracket = errors.New("止盈止损百分比配置无效")
+
+// validateExitBracket 校验 OCO 止盈止损百分比：两者需同时设置或同时为0（关闭）
+// 止损需在 (0, 100) 内，止盈需在 (0, 1000] 内
+func validateExitBracket(tpPercent, slPercent float64) error {
+	if tpPercent == 0 && slPercent == 0 {
+		return nil
+	}
+	if tpPercent <= 0 || slPercent <= 0 {
+		return fmt.Errorf("%w: 止盈和止损百分比必须同时设置 (止盈 %.2f%%, 止损 %.2f%%)", ErrInvalidExitBracket, tpPercent, slPercent)
+	}
+	if slPercent >= 100 {
+		return fmt.Errorf("%w: 止损百分比必须小于 100 (当前 %.2f%%)", ErrInvalidExitBracket, slPercent)
+	}
+	if tpPercent > maxTakeProfitPercent {
+		return fmt.Errorf("%w: 止盈百分比不能超过 %d (当前 %.2f%%)", ErrInvalidExitBracket, maxTakeProfitPercent, tpPercent)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateExitBracket(t *testing.T) {
+	tests := []struct {
+		name    string
+		tp, sl  float64
+		wantErr bool
+	}{
+		{"disabled", 0, 0, false},
+		{"valid", 6, 2, false},
+		{"only take profit", 6, 0, true},
+		{"only stop loss", 0, 2, true},
+		{"negative", -1, 2, true},
+		{"stop loss 100%", 6, 100, true},
+		{"take profit too large", 1001, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExitBracket(tt.tp, tt.sl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateExitBracket(%v, %v) error = %v, wantErr %v", tt.tp, tt.sl, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidExitBracket) {
+				t.Fatalf("error should wrap ErrInvalidExitBracket: %v", err)
+			}
+		})
+	}
+}
+
+func TestTraderExitBracket(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-oco")
+	exID := ensureTestExchange(t, db, userID, "binance-oco")
+	tr := &TraderRecord{
+		ID: "tr-oco", UserID: userID, Name: "oco", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+		TakeProfitPercent: 6,
+	}
+	if err := db.CreateTrader(tr); !errors.Is(err, ErrInvalidExitBracket) {
+		t.Fatalf("expected ErrInvalidExitBracket, got %v", err)
+	}
+
+	tr.StopLossPercent = 2
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	got, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if got.TakeProfitPercent != 6 || got.StopLossPercent != 2 {
+		t.Fatalf("unexpected bracket: tp=%v sl=%v", got.TakeProfitPercent, got.StopLossPercent)
+	}
+
+	tr.StopLossPercent = 150
+	if err := db.UpdateTrader(tr); !errors.Is(err, ErrInvalidExitBracket) {
+		t.Fatalf("expected ErrInvalidExitBracket on update, got %v", err)
+	}
+	tr.TakeProfitPercent, tr.StopLossPercent = 0, 0
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	got, _, _, _ = db.GetTraderConfig(userID, tr.ID)
+	if got.TakeProfitPercent != 0 || got.StopLossPercent != 0 {
+		t.Fatalf("expected bracket disabled, got tp=%v sl=%v", got.TakeProfitPercent, got.StopLossPercent)
+	}
+}
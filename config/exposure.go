@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SymbolExposure 用户所有交易员在同一币种上的合计敞口（名义价值单位 USDT）
+type SymbolExposure struct {
+	Symbol        string   `json:"symbol"`
+	LongQuantity  float64  `json:"long_quantity"`  // 多头持仓数量合计
+	ShortQuantity float64  `json:"short_quantity"` // 空头持仓数量合计
+	NetQuantity   float64  `json:"net_quantity"`   // 多头 - 空头
+	LongNotional  float64  `json:"long_notional"`  // 多头名义价值合计
+	ShortNotional float64  `json:"short_notional"` // 空头名义价值合计
+	NetNotional   float64  `json:"net_notional"`   // 多头 - 空头（正数为净多，负数为净空）
+	TraderIDs     []string `json:"trader_ids"`     // 持有该币种的交易员
+}
+
+// TraderPosition 交易员在某币种某方向上的持仓（Notional 为名义价值，单位 USDT）
+type TraderPosition struct {
+	Symbol   string
+	Side     string // long / short
+	Quantity float64
+	Notional float64
+}
+
+// LivePositionFetcher 获取运行中交易员在交易所的实际持仓；交易员未加载或查询失败时 ok 返回 false
+type LivePositionFetcher func(traderID string) (positions []TraderPosition, ok bool)
+
+// GetAggregateExposure 按币种汇总用户所有交易员的未平仓敞口，用于提示多个交易员同向重仓同一币种的集中风险
+// 优先使用 live 返回的交易所实际持仓（按标记价格计价）；live 为 nil 或无法获取时，
+// 按成交记录回放推算该交易员的未平仓持仓（按开仓均价计价）。按净名义价值绝对值降序返回
+func (d *Database) GetAggregateExposure(userID string, live LivePositionFetcher) ([]*SymbolExposure, error) {
+	rows, err := d.db.Query(`SELECT id FROM traders WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询持仓敞口失败: %w", err)
+	}
+	var traderIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取持仓敞口失败: %w", err)
+		}
+		traderIDs = append(traderIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取持仓敞口失败: %w", err)
+	}
+
+	bySymbol := make(map[string]*SymbolExposure)
+	for _, traderID := range traderIDs {
+		var positions []TraderPosition
+		ok := false
+		if live != nil {
+			positions, ok = live(traderID)
+		}
+		if !ok {
+			estimated, err := d.getOpenPositions(userID, traderID)
+			if err != nil {
+				return nil, err
+			}
+			positions = make([]TraderPosition, 0, len(estimated))
+			for _, pos := range estimated {
+				positions = append(positions, TraderPosition{
+					Symbol:   pos.Symbol,
+					Side:     pos.Side,
+					Quantity: pos.Quantity,
+					Notional: pos.Quantity * pos.EntryPrice,
+				})
+			}
+		}
+
+		for _, pos := range positions {
+			if pos.Quantity <= 1e-12 || (pos.Side != "long" && pos.Side != "short") {
+				continue
+			}
+			exposure, exists := bySymbol[pos.Symbol]
+			if !exists {
+				exposure = &SymbolExposure{Symbol: pos.Symbol, TraderIDs: []string{}}
+				bySymbol[pos.Symbol] = exposure
+			}
+			if pos.Side == "long" {
+				exposure.LongQuantity += pos.Quantity
+				exposure.LongNotional += pos.Notional
+			} else {
+				exposure.ShortQuantity += pos.Quantity
+				exposure.ShortNotional += pos.Notional
+			}
+			if n := len(exposure.TraderIDs); n == 0 || exposure.TraderIDs[n-1] != traderID {
+				exposure.TraderIDs = append(exposure.TraderIDs, traderID)
+			}
+		}
+	}
+
+	result := make([]*SymbolExposure, 0, len(bySymbol))
+	for _, exposure := range bySymbol {
+		exposure.NetQuantity = exposure.LongQuantity - exposure.ShortQuantity
+		exposure.NetNotional = exposure.LongNotional - exposure.ShortNotional
+		result = append(result, exposure)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ai, aj := math.Abs(result[i].NetNotional), math.Abs(result[j].NetNotional)
+		if ai != aj {
+			return ai > aj
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+	return result, nil
+}
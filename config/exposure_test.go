@@ -0,0 +1,132 @@
+package config
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetAggregateExposure(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-exposure-1")
+	exID := ensureTestExchange(t, db, userID, "binance-exposure-1")
+	for _, id := range []string{"tr-exp-a", "tr-exp-b"} {
+		tr := &TraderRecord{
+			ID: id, UserID: userID, Name: id, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	trades := []TradeRecord{
+		// A 和 B 同时做多 SOL，A 部分平仓
+		{TraderID: "tr-exp-a", Symbol: "SOLUSDT", Side: "long", Action: "open", Quantity: 10, Price: 100},
+		{TraderID: "tr-exp-a", Symbol: "SOLUSDT", Side: "long", Action: "close", Quantity: 4, Price: 110},
+		{TraderID: "tr-exp-b", Symbol: "SOLUSDT", Side: "long", Action: "open", Quantity: 5, Price: 120},
+		// B 做空 BTC，A 做多 BTC，互相抵消一部分
+		{TraderID: "tr-exp-b", Symbol: "BTCUSDT", Side: "short", Action: "open", Quantity: 0.02, Price: 50000},
+		{TraderID: "tr-exp-a", Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 0.01, Price: 50000},
+		// 已全部平仓的 ETH 不计入
+		{TraderID: "tr-exp-a", Symbol: "ETHUSDT", Side: "long", Action: "open", Quantity: 1, Price: 3000},
+		{TraderID: "tr-exp-a", Symbol: "ETHUSDT", Side: "long", Action: "close", Quantity: 1, Price: 3100},
+	}
+	for i := range trades {
+		trades[i].UserID = userID
+		if err := db.RecordTrade(&trades[i]); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+
+	// 其他用户的持仓不计入
+	otherAI := ensureTestAIModel(t, db, "test-user-002", "model-exposure-2")
+	otherEx := ensureTestExchange(t, db, "test-user-002", "binance-exposure-2")
+	if err := db.CreateTrader(&TraderRecord{
+		ID: "tr-exp-other", UserID: "test-user-002", Name: "other", AIModelID: otherAI, ExchangeID: otherEx,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	if err := db.RecordTrade(&TradeRecord{TraderID: "tr-exp-other", UserID: "test-user-002", Symbol: "SOLUSDT", Side: "long", Action: "open", Quantity: 100, Price: 100}); err != nil {
+		t.Fatalf("RecordTrade failed: %v", err)
+	}
+
+	exposures, err := db.GetAggregateExposure(userID, nil)
+	if err != nil {
+		t.Fatalf("GetAggregateExposure failed: %v", err)
+	}
+	if len(exposures) != 2 {
+		t.Fatalf("expected 2 symbols, got %d", len(exposures))
+	}
+
+	sol := exposures[0]
+	if sol.Symbol != "SOLUSDT" {
+		t.Fatalf("expected SOLUSDT first (largest net notional), got %s", sol.Symbol)
+	}
+	if math.Abs(sol.NetQuantity-11) > 1e-9 || math.Abs(sol.NetNotional-1200) > 1e-9 {
+		t.Errorf("unexpected SOL exposure: qty=%v notional=%v", sol.NetQuantity, sol.NetNotional)
+	}
+	if len(sol.TraderIDs) != 2 {
+		t.Errorf("expected 2 traders on SOL, got %v", sol.TraderIDs)
+	}
+
+	btc := exposures[1]
+	if math.Abs(btc.NetQuantity+0.01) > 1e-9 || math.Abs(btc.NetNotional+500) > 1e-9 {
+		t.Errorf("unexpected BTC exposure: qty=%v notional=%v", btc.NetQuantity, btc.NetNotional)
+	}
+	if math.Abs(btc.LongNotional-500) > 1e-9 || math.Abs(btc.ShortNotional-1000) > 1e-9 {
+		t.Errorf("unexpected BTC legs: long=%v short=%v", btc.LongNotional, btc.ShortNotional)
+	}
+}
+
+// TestGetAggregateExposure_PrefersLivePositions 运行中交易员按交易所实际持仓计算，被止盈止损平掉的仓位不会残留为敞口
+func TestGetAggregateExposure_PrefersLivePositions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-exposure-live")
+	exID := ensureTestExchange(t, db, userID, "binance-exposure-live")
+	for _, id := range []string{"tr-live-a", "tr-live-b"} {
+		if err := db.CreateTrader(&TraderRecord{
+			ID: id, UserID: userID, Name: id, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+		}); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+	for _, trade := range []TradeRecord{
+		// A 的 BTC 多仓已在交易所被止损平掉，但成交记录里仍是开仓状态
+		{TraderID: "tr-live-a", Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 0.01, Price: 50000},
+		{TraderID: "tr-live-b", Symbol: "SOLUSDT", Side: "long", Action: "open", Quantity: 5, Price: 120},
+	} {
+		trade.UserID = userID
+		if err := db.RecordTrade(&trade); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+
+	live := func(traderID string) ([]TraderPosition, bool) {
+		if traderID != "tr-live-a" {
+			return nil, false // B 未加载，按成交记录估算
+		}
+		return []TraderPosition{{Symbol: "SOLUSDT", Side: "long", Quantity: 2, Notional: 220}}, true
+	}
+	exposures, err := db.GetAggregateExposure(userID, live)
+	if err != nil {
+		t.Fatalf("GetAggregateExposure failed: %v", err)
+	}
+	if len(exposures) != 1 || exposures[0].Symbol != "SOLUSDT" {
+		t.Fatalf("expected only SOLUSDT exposure, got %+v", exposures)
+	}
+	sol := exposures[0]
+	if math.Abs(sol.LongQuantity-7) > 1e-9 || math.Abs(sol.LongNotional-820) > 1e-9 {
+		t.Errorf("unexpected SOL exposure: qty=%v notional=%v", sol.LongQuantity, sol.LongNotional)
+	}
+	if len(sol.TraderIDs) != 2 {
+		t.Errorf("expected 2 traders on SOL, got %v", sol.TraderIDs)
+	}
+}
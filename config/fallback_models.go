@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ParseFallbackAIModelIDs 解析 traders.fallback_ai_model_ids（JSON整数数组，空字符串表示未配置）
+func ParseFallbackAIModelIDs(raw string) ([]int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var ids []int
+	if err := json.Unmarshal([]byte(raw), &ids); err != nil {
+		return nil, fmt.Errorf("备用AI模型列表必须是整数JSON数组: %w", err)
+	}
+	return ids, nil
+}
+
+// FallbackModelIDs 返回交易员的备用AI模型ID列表（未配置或配置无效时返回空列表）
+func (t *TraderRecord) FallbackModelIDs() []int {
+	ids, err := ParseFallbackAIModelIDs(t.FallbackAIModelIDs)
+	if err != nil || ids == nil {
+		return []int{}
+	}
+	return ids
+}
+
+// EncodeFallbackAIModelIDs 序列化备用AI模型ID列表（空列表返回空字符串）
+func EncodeFallbackAIModelIDs(ids []int) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(ids)
+	return string(data)
+}
+
+// ValidateFallbackAIModels 校验备用AI模型：必须属于该用户、已启用，且不能与主模型或彼此重复
+func (d *Database) ValidateFallbackAIModels(userID string, primaryID int, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	models, err := d.GetAIModels(userID)
+	if err != nil {
+		return fmt.Errorf("获取AI模型配置失败: %w", err)
+	}
+	byID := make(map[int]*AIModelConfig, len(models))
+	for _, model := range models {
+		byID[model.ID] = model
+	}
+
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id == primaryID {
+			return fmt.Errorf("备用AI模型 %d 与主模型相同", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("备用AI模型 %d 重复", id)
+		}
+		seen[id] = true
+
+		model, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("备用AI模型 %d 不存在", id)
+		}
+		if !model.Enabled {
+			return fmt.Errorf("备用AI模型 %d (%s) 未启用", id, model.Name)
+		}
+	}
+	return nil
+}
+
+// GetFallbackAIModels 按配置顺序获取可用的备用AI模型
+// 已删除或已禁用的模型会被跳过并记录警告，不影响交易员启动
+func (d *Database) GetFallbackAIModels(userID string, ids []int) ([]*AIModelConfig, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	models, err := d.GetAIModels(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取AI模型配置失败: %w", err)
+	}
+	byID := make(map[int]*AIModelConfig, len(models))
+	for _, model := range models {
+		byID[model.ID] = model
+	}
+
+	fallbacks := make([]*AIModelConfig, 0, len(ids))
+	for _, id := range ids {
+		model, ok := byID[id]
+		if !ok || !model.Enabled {
+			log.Printf("⚠️ 备用AI模型 %d 不存在或未启用，已跳过", id)
+			continue
+		}
+		fallbacks = append(fallbacks, model)
+	}
+	return fallbacks, nil
+}
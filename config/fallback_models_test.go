@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidateFallbackAIModels(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	primaryID := ensureTestAIModel(t, db, userID, "model-primary")
+	backupID := ensureTestAIModel(t, db, userID, "model-backup")
+	disabledID := ensureTestAIModel(t, db, userID, "model-disabled")
+	if err := db.UpdateAIModel(userID, "model-disabled", false, "", "", ""); err != nil {
+		t.Fatalf("UpdateAIModel failed: %v", err)
+	}
+
+	if err := db.ValidateFallbackAIModels(userID, primaryID, []int{backupID}); err != nil {
+		t.Fatalf("expected valid fallback chain, got %v", err)
+	}
+	if err := db.ValidateFallbackAIModels(userID, primaryID, nil); err != nil {
+		t.Fatalf("expected empty chain to be valid, got %v", err)
+	}
+
+	invalid := map[string][]int{
+		"disabled":  {disabledID},
+		"missing":   {99999},
+		"primary":   {primaryID},
+		"duplicate": {backupID, backupID},
+	}
+	for name, ids := range invalid {
+		if err := db.ValidateFallbackAIModels(userID, primaryID, ids); err == nil {
+			t.Errorf("%s: expected %v to be rejected", name, ids)
+		}
+	}
+
+	// 已禁用/已删除的模型在加载时被跳过，保持配置顺序
+	models, err := db.GetFallbackAIModels(userID, []int{disabledID, backupID, 99999})
+	if err != nil {
+		t.Fatalf("GetFallbackAIModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0].ID != backupID {
+		t.Fatalf("expected only backup model, got %v", models)
+	}
+}
+
+func TestFallbackAIModelIDsRoundTrip(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-fallback-rt")
+	exID := ensureTestExchange(t, db, userID, "binance-fallback-rt")
+
+	tr := &TraderRecord{
+		ID: "tr-fallback", UserID: userID, Name: "fallback", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+		FallbackAIModelIDs: EncodeFallbackAIModelIDs([]int{7, 3}),
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	stored, _, _, err := db.GetTraderConfig(userID, "tr-fallback")
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	ids := stored.FallbackModelIDs()
+	if len(ids) != 2 || ids[0] != 7 || ids[1] != 3 {
+		t.Fatalf("expected fallback ids [7 3], got %v", ids)
+	}
+
+	if _, err := ParseFallbackAIModelIDs(`["a"]`); err == nil {
+		t.Fatal("expected non-integer ids to be rejected")
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"nofx/market"
+	"time"
+)
+
+// RecordLongShortHistory 保存多空持仓人数比历史序列（market.FetchLongShortRatioHistory 的结果）
+// 以 (symbol, period, timestamp) 去重，重复数据点覆盖为最新值（最后一个周期的数据可能在收盘前变化）
+// 返回写入的数据点数
+func (d *Database) RecordLongShortHistory(symbol, period string, points []market.LongShortRatioPoint) (int, error) {
+	if len(points) == 0 {
+		return 0, nil
+	}
+	symbol = market.Normalize(symbol)
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO long_short_history (symbol, period, timestamp, long_short_ratio, long_account, short_account)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(symbol, period, timestamp) DO UPDATE SET
+			long_short_ratio = excluded.long_short_ratio,
+			long_account = excluded.long_account,
+			short_account = excluded.short_account
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("准备多空比写入失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range points {
+		if _, err := stmt.Exec(symbol, period, p.Timestamp.UTC().Format(sqliteTimeLayout),
+			p.LongShortRatio, p.LongAccount, p.ShortAccount); err != nil {
+			return 0, fmt.Errorf("写入多空比历史失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交多空比历史失败: %w", err)
+	}
+	return len(points), nil
+}
+
+// GetLongShortHistory 查询 since 之后（含）的多空比历史，按时间升序
+func (d *Database) GetLongShortHistory(symbol, period string, since time.Time) ([]market.LongShortRatioPoint, error) {
+	rows, err := d.db.Query(`
+		SELECT timestamp, long_short_ratio, long_account, short_account FROM long_short_history
+		WHERE symbol = ? AND period = ? AND timestamp >= ?
+		ORDER BY timestamp
+	`, market.Normalize(symbol), period, since.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return nil, fmt.Errorf("查询多空比历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]market.LongShortRatioPoint, 0)
+	for rows.Next() {
+		var p market.LongShortRatioPoint
+		if err := rows.Scan(&p.Timestamp, &p.LongShortRatio, &p.LongAccount, &p.ShortAccount); err != nil {
+			return nil, fmt.Errorf("读取多空比历史失败: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
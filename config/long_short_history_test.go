@@ -0,0 +1,42 @@
+package config
+
+import (
+	"nofx/market"
+	"testing"
+	"time"
+)
+
+func TestRecordLongShortHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []market.LongShortRatioPoint{
+		{Timestamp: base, LongShortRatio: 1.2, LongAccount: 0.545, ShortAccount: 0.455},
+		{Timestamp: base.Add(5 * time.Minute), LongShortRatio: 1.5, LongAccount: 0.6, ShortAccount: 0.4},
+	}
+	if n, err := db.RecordLongShortHistory("btc", "5m", points); err != nil || n != 2 {
+		t.Fatalf("RecordLongShortHistory = %d, %v", n, err)
+	}
+
+	// 重复写入时覆盖最后一个周期的值
+	points[1].LongShortRatio = 1.8
+	if _, err := db.RecordLongShortHistory("BTCUSDT", "5m", points[1:]); err != nil {
+		t.Fatalf("RecordLongShortHistory failed: %v", err)
+	}
+
+	got, err := db.GetLongShortHistory("BTCUSDT", "5m", base)
+	if err != nil {
+		t.Fatalf("GetLongShortHistory failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(got))
+	}
+	if !got[0].Timestamp.Equal(base) || got[1].LongShortRatio != 1.8 {
+		t.Fatalf("unexpected points: %+v", got)
+	}
+
+	if got, _ := db.GetLongShortHistory("BTCUSDT", "1h", base); len(got) != 0 {
+		t.Fatalf("other period should be empty, got %d", len(got))
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTraderMaxOrdersPerCycle(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-max-orders")
+	exID := ensureTestExchange(t, db, userID, "binance-max-orders")
+	tr := &TraderRecord{
+		ID: "tr-max-orders", UserID: userID, Name: "max-orders", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+		MaxOrdersPerCycle: -1,
+	}
+	if err := db.CreateTrader(tr); !errors.Is(err, ErrInvalidMaxOrders) {
+		t.Fatalf("expected ErrInvalidMaxOrders on create, got %v", err)
+	}
+
+	tr.MaxOrdersPerCycle = 3
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	got, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if got.MaxOrdersPerCycle != 3 {
+		t.Fatalf("MaxOrdersPerCycle = %d, want 3", got.MaxOrdersPerCycle)
+	}
+
+	tr.MaxOrdersPerCycle = -5
+	if err := db.UpdateTrader(tr); !errors.Is(err, ErrInvalidMaxOrders) {
+		t.Fatalf("expected ErrInvalidMaxOrders on update, got %v", err)
+	}
+	tr.MaxOrdersPerCycle = 1
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	traders, err := db.GetTraders(userID)
+	if err != nil || len(traders) != 1 || traders[0].MaxOrdersPerCycle != 1 {
+		t.Fatalf("expected updated MaxOrdersPerCycle=1, got %v (%v)", traders, err)
+	}
+}
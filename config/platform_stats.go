@@ -0,0 +1,53 @@
+package config
+
+import "fmt"
+
+// PlatformStats 全平台容量统计（运维看板使用）
+type PlatformStats struct {
+	Users             int            `json:"users"`               // 注册用户数（不含系统 default 用户）
+	Traders           int            `json:"traders"`             // 交易员总数
+	RunningTraders    int            `json:"running_traders"`     // 运行中的交易员数
+	TradersByExchange map[string]int `json:"traders_by_exchange"` // 按交易所（exchanges.exchange_id）统计的交易员数
+}
+
+// GetPlatformStats 统计全平台用户数、交易员数、运行中交易员数及各交易所交易员数
+// 计数在同一个只读事务中完成，保证各项数字来自同一时刻
+func (d *Database) GetPlatformStats() (*PlatformStats, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stats := &PlatformStats{TradersByExchange: make(map[string]int)}
+	if err := tx.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM users WHERE id != 'default'),
+			(SELECT COUNT(*) FROM traders),
+			(SELECT COUNT(*) FROM traders WHERE is_running = 1)
+	`).Scan(&stats.Users, &stats.Traders, &stats.RunningTraders); err != nil {
+		return nil, fmt.Errorf("统计平台数据失败: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT e.exchange_id, COUNT(*) FROM traders t
+		JOIN exchanges e ON e.id = t.exchange_id
+		GROUP BY e.exchange_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("按交易所统计交易员失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var exchangeID string
+		var count int
+		if err := rows.Scan(&exchangeID, &count); err != nil {
+			return nil, fmt.Errorf("读取交易所统计失败: %w", err)
+		}
+		stats.TradersByExchange[exchangeID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
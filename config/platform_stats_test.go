@@ -0,0 +1,46 @@
+package config
+
+import "testing"
+
+func TestGetPlatformStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	before, err := db.GetPlatformStats()
+	if err != nil {
+		t.Fatalf("GetPlatformStats failed: %v", err)
+	}
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-stats")
+	binanceID := ensureTestExchange(t, db, userID, "binance")
+	asterID := ensureTestExchange(t, db, userID, "aster")
+	for i, exID := range []int{binanceID, binanceID, asterID} {
+		tr := &TraderRecord{
+			ID: "tr-stats-" + string(rune('a'+i)), UserID: userID, Name: "stats", AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+	if err := db.UpdateTraderStatus(userID, "tr-stats-a", true, ""); err != nil {
+		t.Fatalf("UpdateTraderStatus failed: %v", err)
+	}
+
+	stats, err := db.GetPlatformStats()
+	if err != nil {
+		t.Fatalf("GetPlatformStats failed: %v", err)
+	}
+	if stats.Users != before.Users || before.Users == 0 {
+		t.Errorf("users = %d, before = %d", stats.Users, before.Users)
+	}
+	if stats.Traders-before.Traders != 3 || stats.RunningTraders-before.RunningTraders != 1 {
+		t.Errorf("traders = %d (+%d), running = %d (+%d)", stats.Traders, stats.Traders-before.Traders,
+			stats.RunningTraders, stats.RunningTraders-before.RunningTraders)
+	}
+	if stats.TradersByExchange["binance"]-before.TradersByExchange["binance"] != 2 ||
+		stats.TradersByExchange["aster"]-before.TradersByExchange["aster"] != 1 {
+		t.Errorf("unexpected per-exchange counts: %v", stats.TradersByExchange)
+	}
+}
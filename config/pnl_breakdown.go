@@ -0,0 +1,182 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+	"sort"
+)
+
+// MarkPriceFetcher 批量获取合约标记价格（币种 -> 价格，默认一次请求 Binance /fapi/v1/premiumIndex）
+type MarkPriceFetcher func(symbols []string) (map[string]float64, error)
+
+// SetMarkPriceFetcher 替换标记价格获取函数（测试或自定义数据源）
+func (d *Database) SetMarkPriceFetcher(fetcher MarkPriceFetcher) {
+	d.markPriceFetcher = fetcher
+}
+
+func fetchBinanceMarkPrices(symbols []string) (map[string]float64, error) {
+	return market.NewAPIClient().GetMarkPrices(symbols)
+}
+
+// openPosition 由成交记录推算的未平仓持仓（按当前这一轮持仓的开仓均价计价）
+type openPosition struct {
+	Symbol     string
+	Side       string
+	Quantity   float64
+	EntryPrice float64
+}
+
+// GetRealizedPnL 交易员全部平仓成交的已实现盈亏合计（未扣手续费，与 GetTraderStats 一致）
+func (d *Database) GetRealizedPnL(userID, traderID string) (float64, error) {
+	var realized float64
+	if err := d.db.QueryRow(`
+		SELECT COALESCE(SUM(realized_pnl), 0)
+		FROM trades
+		WHERE trader_id = ? AND user_id = ? AND action = 'close'
+	`, traderID, userID).Scan(&realized); err != nil {
+		return 0, fmt.Errorf("统计已实现盈亏失败: %w", err)
+	}
+	return realized, nil
+}
+
+// GetPnLBreakdown 拆分交易员的已实现盈亏与未实现盈亏（离线估算）
+// 运行中的交易员应直接使用交易所持仓的未实现盈亏，本方法用于交易员未加载时的兜底：
+// unrealized 按成交记录推算的未平仓持仓与当前标记价格计算，所有币种的标记价格一次批量获取；
+// 标记价格来自 Binance，非 Binance 交易员不估算未实现盈亏（返回 0），没有获取到标记价格的币种也不计入
+func (d *Database) GetPnLBreakdown(userID, traderID string) (realized, unrealized float64, err error) {
+	realized, err = d.GetRealizedPnL(userID, traderID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var exchangeID string
+	err = d.db.QueryRow(`
+		SELECT e.exchange_id FROM traders t
+		JOIN exchanges e ON t.exchange_id = e.id
+		WHERE t.id = ? AND t.user_id = ?
+	`, traderID, userID).Scan(&exchangeID)
+	if err == sql.ErrNoRows {
+		return realized, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("查询交易员交易所失败: %w", err)
+	}
+	if exchangeID != "binance" {
+		return realized, 0, nil
+	}
+
+	positions, err := d.getOpenPositions(userID, traderID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(positions) == 0 {
+		return realized, 0, nil
+	}
+
+	symbols := make([]string, 0, len(positions))
+	seen := make(map[string]bool, len(positions))
+	for _, pos := range positions {
+		if !seen[pos.Symbol] {
+			seen[pos.Symbol] = true
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+
+	fetcher := d.markPriceFetcher
+	if fetcher == nil {
+		fetcher = fetchBinanceMarkPrices
+	}
+	prices, err := fetcher(symbols)
+	if err != nil {
+		return 0, 0, fmt.Errorf("获取标记价格失败: %w", err)
+	}
+
+	for _, pos := range positions {
+		mark, ok := prices[pos.Symbol]
+		if !ok || mark <= 0 {
+			log.Printf("⚠️ 交易员 %s 的 %s 没有标记价格，未计入未实现盈亏", traderID, pos.Symbol)
+			continue
+		}
+		if pos.Side == "short" {
+			unrealized += (pos.EntryPrice - mark) * pos.Quantity
+		} else {
+			unrealized += (mark - pos.EntryPrice) * pos.Quantity
+		}
+	}
+	return realized, unrealized, nil
+}
+
+// getOpenPositions 按成交时间顺序回放成交记录，推算交易员每个币种、方向的未平仓持仓
+// 平仓按当前均价扣减成本；持仓归零后重新计算均价，之前已平掉的轮次不影响新持仓的开仓均价
+func (d *Database) getOpenPositions(userID, traderID string) ([]openPosition, error) {
+	rows, err := d.db.Query(`
+		SELECT symbol, side, action, quantity, price
+		FROM trades
+		WHERE trader_id = ? AND user_id = ? AND side IN ('long', 'short')
+		ORDER BY created_at, id
+	`, traderID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询未平仓持仓失败: %w", err)
+	}
+	defer rows.Close()
+
+	type holding struct {
+		quantity float64
+		cost     float64
+	}
+	holdings := make(map[[2]string]*holding)
+	for rows.Next() {
+		var symbol, side, action string
+		var quantity, price float64
+		if err := rows.Scan(&symbol, &side, &action, &quantity, &price); err != nil {
+			return nil, fmt.Errorf("读取未平仓持仓失败: %w", err)
+		}
+		key := [2]string{symbol, side}
+		h := holdings[key]
+		if h == nil {
+			h = &holding{}
+			holdings[key] = h
+		}
+		switch action {
+		case "open":
+			h.quantity += quantity
+			h.cost += quantity * price
+		case "close":
+			if h.quantity <= 0 {
+				continue
+			}
+			closed := math.Min(quantity, h.quantity)
+			h.cost -= h.cost / h.quantity * closed
+			h.quantity -= closed
+		}
+		if h.quantity <= 1e-12 {
+			h.quantity, h.cost = 0, 0
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取未平仓持仓失败: %w", err)
+	}
+
+	var positions []openPosition
+	for key, h := range holdings {
+		if h.quantity <= 0 {
+			continue
+		}
+		positions = append(positions, openPosition{
+			Symbol:     key[0],
+			Side:       key[1],
+			Quantity:   h.quantity,
+			EntryPrice: h.cost / h.quantity,
+		})
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Symbol != positions[j].Symbol {
+			return positions[i].Symbol < positions[j].Symbol
+		}
+		return positions[i].Side < positions[j].Side
+	})
+	return positions, nil
+}
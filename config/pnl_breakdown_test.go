@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetPnLBreakdown(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-pnl-1")
+	exID := ensureTestExchange(t, db, userID, "binance")
+	tr := &TraderRecord{
+		ID: "tr-pnl", UserID: userID, Name: "pnl", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	trades := []TradeRecord{
+		// BTC 多头：开 2 张（均价 100），平 1 张，剩余 1 张
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 1, Price: 90, CreatedAt: base},
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 1, Price: 110, CreatedAt: base.Add(time.Minute)},
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", Quantity: 1, Price: 120, RealizedPnL: 20, CreatedAt: base.Add(time.Hour)},
+		// ETH 空头：开 2 张（均价 50），未平仓
+		{Symbol: "ETHUSDT", Side: "short", Action: "open", Quantity: 2, Price: 50, CreatedAt: base},
+		// SOL 已全部平仓，不应请求标记价格
+		{Symbol: "SOLUSDT", Side: "long", Action: "open", Quantity: 3, Price: 10, CreatedAt: base},
+		{Symbol: "SOLUSDT", Side: "long", Action: "close", Quantity: 3, Price: 8, RealizedPnL: -6, CreatedAt: base.Add(time.Hour)},
+	}
+	for i := range trades {
+		trades[i].TraderID = tr.ID
+		trades[i].UserID = userID
+		if err := db.RecordTrade(&trades[i]); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+
+	calls := 0
+	db.SetMarkPriceFetcher(func(symbols []string) (map[string]float64, error) {
+		calls++
+		if len(symbols) != 2 || symbols[0] != "BTCUSDT" || symbols[1] != "ETHUSDT" {
+			t.Errorf("unexpected symbols: %v", symbols)
+		}
+		return map[string]float64{"BTCUSDT": 130, "ETHUSDT": 45}, nil
+	})
+
+	realized, unrealized, err := db.GetPnLBreakdown(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetPnLBreakdown failed: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("mark price fetcher called %d times, want 1 batch call", calls)
+	}
+	if realized != 14 {
+		t.Fatalf("realized = %v, want 14", realized)
+	}
+	// BTC 多头 (130-100)*1 = 30，ETH 空头 (50-45)*2 = 10
+	if math.Abs(unrealized-40) > 1e-9 {
+		t.Fatalf("unrealized = %v, want 40", unrealized)
+	}
+
+	// 其他用户看不到该交易员的盈亏
+	if realized, unrealized, err := db.GetPnLBreakdown("other-user", tr.ID); err != nil || realized != 0 || unrealized != 0 {
+		t.Fatalf("expected empty breakdown for other user, got %v %v %v", realized, unrealized, err)
+	}
+
+	// 缺少标记价格的币种不计入
+	db.SetMarkPriceFetcher(func(symbols []string) (map[string]float64, error) {
+		return map[string]float64{"BTCUSDT": 130}, nil
+	})
+	if _, unrealized, err := db.GetPnLBreakdown(userID, tr.ID); err != nil || math.Abs(unrealized-30) > 1e-9 {
+		t.Fatalf("expected unrealized 30 without ETH mark price, got %v %v", unrealized, err)
+	}
+
+	db.SetMarkPriceFetcher(func(symbols []string) (map[string]float64, error) {
+		return nil, errors.New("timeout")
+	})
+	if _, _, err := db.GetPnLBreakdown(userID, tr.ID); err == nil {
+		t.Fatal("expected error when mark prices are unavailable")
+	}
+}
+
+func TestGetPnLBreakdown_EntryResetsAfterFlat(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-pnl-2")
+	exID := ensureTestExchange(t, db, userID, "binance")
+	tr := &TraderRecord{
+		ID: "tr-pnl-cycle", UserID: userID, Name: "pnl", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	trades := []TradeRecord{
+		// 第一轮 50 开、全部平仓
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 2, Price: 50, CreatedAt: base},
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", Quantity: 2, Price: 60, RealizedPnL: 20, CreatedAt: base.Add(time.Hour)},
+		// 第二轮 100 开，尚未平仓：均价不应被第一轮拉低
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 1, Price: 100, CreatedAt: base.Add(2 * time.Hour)},
+	}
+	for i := range trades {
+		trades[i].TraderID = tr.ID
+		trades[i].UserID = userID
+		if err := db.RecordTrade(&trades[i]); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+	db.SetMarkPriceFetcher(func(symbols []string) (map[string]float64, error) {
+		return map[string]float64{"BTCUSDT": 110}, nil
+	})
+
+	_, unrealized, err := db.GetPnLBreakdown(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetPnLBreakdown failed: %v", err)
+	}
+	if math.Abs(unrealized-10) > 1e-9 {
+		t.Fatalf("unrealized = %v, want 10 from the current cycle's entry", unrealized)
+	}
+}
+
+func TestGetPnLBreakdown_NonBinanceSkipsMarkPrices(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-pnl-3")
+	exID := ensureTestExchange(t, db, userID, "hyperliquid")
+	tr := &TraderRecord{
+		ID: "tr-pnl-hl", UserID: userID, Name: "pnl", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	open := &TradeRecord{TraderID: tr.ID, UserID: userID, Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 1, Price: 100, CreatedAt: time.Now()}
+	if err := db.RecordTrade(open); err != nil {
+		t.Fatalf("RecordTrade failed: %v", err)
+	}
+	db.SetMarkPriceFetcher(func(symbols []string) (map[string]float64, error) {
+		t.Error("Binance mark prices must not be used for non-Binance traders")
+		return nil, nil
+	})
+
+	if _, unrealized, err := db.GetPnLBreakdown(userID, tr.ID); err != nil || unrealized != 0 {
+		t.Fatalf("expected no unrealized estimate, got %v %v", unrealized, err)
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrPromptTemplateNotFound 引用的提示词模板不存在
+var ErrPromptTemplateNotFound = errors.New("提示词模板不存在")
+
+// PromptTemplate 用户级提示词模板（多个交易员按名称引用，避免重复粘贴同一策略）
+// 模板内容支持 {{变量}} 占位符，在每个周期由交易员填充，例如 {{trader_name}}、{{equity}}
+type PromptTemplate struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// promptPlaceholderPattern 匹配 {{ name }} 形式的占位符
+var promptPlaceholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*\}\}`)
+
+// RenderPromptTemplate 用 vars 替换模板中的 {{变量}} 占位符，未提供的变量保持原样
+func RenderPromptTemplate(body string, vars map[string]string) string {
+	return promptPlaceholderPattern.ReplaceAllStringFunc(body, func(match string) string {
+		name := promptPlaceholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// CreatePromptTemplate 创建提示词模板（同一用户下名称唯一）
+func (d *Database) CreatePromptTemplate(tpl *PromptTemplate) error {
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	if tpl.UserID == "" || tpl.Name == "" || strings.TrimSpace(tpl.Body) == "" {
+		return fmt.Errorf("提示词模板名称和内容不能为空")
+	}
+	result, err := d.db.Exec(`
+		INSERT INTO prompt_templates (user_id, name, body) VALUES (?, ?, ?)
+	`, tpl.UserID, tpl.Name, tpl.Body)
+	if err != nil {
+		return fmt.Errorf("创建提示词模板失败: %w", err)
+	}
+	tpl.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetPromptTemplates 获取用户的全部提示词模板（按名称排序）
+func (d *Database) GetPromptTemplates(userID string) ([]*PromptTemplate, error) {
+	rows, err := d.db.Query(`
+		SELECT id, user_id, name, body, created_at, updated_at
+		FROM prompt_templates WHERE user_id = ? ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询提示词模板失败: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]*PromptTemplate, 0)
+	for rows.Next() {
+		var tpl PromptTemplate
+		if err := rows.Scan(&tpl.ID, &tpl.UserID, &tpl.Name, &tpl.Body, &tpl.CreatedAt, &tpl.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("读取提示词模板失败: %w", err)
+		}
+		templates = append(templates, &tpl)
+	}
+	return templates, rows.Err()
+}
+
+// GetPromptTemplateByName 按名称获取用户的提示词模板，不存在时返回 nil
+func (d *Database) GetPromptTemplateByName(userID, name string) (*PromptTemplate, error) {
+	var tpl PromptTemplate
+	err := d.db.QueryRow(`
+		SELECT id, user_id, name, body, created_at, updated_at
+		FROM prompt_templates WHERE user_id = ? AND name = ?
+	`, userID, strings.TrimSpace(name)).Scan(&tpl.ID, &tpl.UserID, &tpl.Name, &tpl.Body, &tpl.CreatedAt, &tpl.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询提示词模板失败: %w", err)
+	}
+	return &tpl, nil
+}
+
+// UpdatePromptTemplate 更新提示词模板内容，引用该模板的交易员在下个周期生效
+func (d *Database) UpdatePromptTemplate(userID, name, body string) error {
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("提示词模板内容不能为空")
+	}
+	result, err := d.db.Exec(`
+		UPDATE prompt_templates SET body = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND name = ?
+	`, body, userID, strings.TrimSpace(name))
+	if err != nil {
+		return fmt.Errorf("更新提示词模板失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: %s", ErrPromptTemplateNotFound, name)
+	}
+	return nil
+}
+
+// DeletePromptTemplate 删除提示词模板；仍被交易员引用时拒绝删除
+func (d *Database) DeletePromptTemplate(userID, name string) error {
+	name = strings.TrimSpace(name)
+	var inUse int
+	if err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM traders WHERE user_id = ? AND prompt_template_name = ?
+	`, userID, name).Scan(&inUse); err != nil {
+		return fmt.Errorf("查询模板引用失败: %w", err)
+	}
+	if inUse > 0 {
+		return fmt.Errorf("提示词模板 %s 仍被 %d 个交易员引用，无法删除", name, inUse)
+	}
+
+	result, err := d.db.Exec(`DELETE FROM prompt_templates WHERE user_id = ? AND name = ?`, userID, name)
+	if err != nil {
+		return fmt.Errorf("删除提示词模板失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("%w: %s", ErrPromptTemplateNotFound, name)
+	}
+	return nil
+}
+
+// validatePromptTemplateRef 校验交易员引用的提示词模板存在（为空表示不引用）
+func (d *Database) validatePromptTemplateRef(userID, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	tpl, err := d.GetPromptTemplateByName(userID, name)
+	if err != nil {
+		return err
+	}
+	if tpl == nil {
+		return fmt.Errorf("%w: %s", ErrPromptTemplateNotFound, name)
+	}
+	return nil
+}
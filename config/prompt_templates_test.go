@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRenderPromptTemplate(t *testing.T) {
+	got := RenderPromptTemplate("交易员 {{trader_name}} 净值 {{ equity }}，未知 {{missing}}", map[string]string{
+		"trader_name": "Alpha",
+		"equity":      "1000.00",
+	})
+	want := "交易员 Alpha 净值 1000.00，未知 {{missing}}"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPromptTemplateCRUDAndTraderReference(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	if err := db.CreatePromptTemplate(&PromptTemplate{UserID: userID, Name: "trend", Body: "只做 {{symbols}} 的趋势单"}); err != nil {
+		t.Fatalf("CreatePromptTemplate failed: %v", err)
+	}
+	if err := db.CreatePromptTemplate(&PromptTemplate{UserID: userID, Name: "trend", Body: "dup"}); err == nil {
+		t.Fatal("expected duplicate template name to be rejected")
+	}
+
+	aiID := ensureTestAIModel(t, db, userID, "model-tpl-1")
+	exID := ensureTestExchange(t, db, userID, "binance-tpl-1")
+	trader := &TraderRecord{
+		ID: "tr-tpl-1", UserID: userID, Name: "Tpl", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default", PromptTemplateName: "missing",
+	}
+	if err := db.CreateTrader(trader); !errors.Is(err, ErrPromptTemplateNotFound) {
+		t.Fatalf("expected ErrPromptTemplateNotFound, got %v", err)
+	}
+	trader.PromptTemplateName = "trend"
+	if err := db.CreateTrader(trader); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	// 其他用户的同名模板不可引用
+	if tpl, err := db.GetPromptTemplateByName("user1", "trend"); err != nil || tpl != nil {
+		t.Fatalf("expected template to be scoped per user, got %+v (%v)", tpl, err)
+	}
+
+	if err := db.UpdatePromptTemplate(userID, "trend", "新内容 {{equity}}"); err != nil {
+		t.Fatalf("UpdatePromptTemplate failed: %v", err)
+	}
+	tpl, err := db.GetPromptTemplateByName(userID, "trend")
+	if err != nil || tpl == nil || tpl.Body != "新内容 {{equity}}" {
+		t.Fatalf("unexpected template after update: %+v (%v)", tpl, err)
+	}
+	if err := db.UpdatePromptTemplate(userID, "nope", "x"); !errors.Is(err, ErrPromptTemplateNotFound) {
+		t.Fatalf("expected ErrPromptTemplateNotFound on update, got %v", err)
+	}
+
+	if err := db.DeletePromptTemplate(userID, "trend"); err == nil {
+		t.Fatal("expected delete to be refused while a trader references the template")
+	}
+	trader.PromptTemplateName = ""
+	if err := db.UpdateTrader(trader); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	if err := db.DeletePromptTemplate(userID, "trend"); err != nil {
+		t.Fatalf("DeletePromptTemplate failed: %v", err)
+	}
+	templates, err := db.GetPromptTemplates(userID)
+	if err != nil || len(templates) != 0 {
+		t.Fatalf("expected no templates after delete, got %d (%v)", len(templates), err)
+	}
+}
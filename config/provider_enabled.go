@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetProviderEnabled 批量启用/禁用用户某个AI提供商（如 deepseek）的全部模型配置，返回受影响的行数
+// 用于提供商故障时一次性切换，而不必逐个修改配置
+func (d *Database) SetProviderEnabled(userID, provider string, enabled bool) (int, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" {
+		return 0, fmt.Errorf("提供商不能为空")
+	}
+	result, err := d.db.Exec(`
+		UPDATE ai_models SET enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND LOWER(provider) = ?
+	`, enabled, userID, provider)
+	if err != nil {
+		return 0, fmt.Errorf("更新AI模型启用状态失败: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
+
+// SetExchangeTypeEnabled 批量启用/禁用用户某个交易所类型（如 binance）的全部配置，返回受影响的行数
+func (d *Database) SetExchangeTypeEnabled(userID, exchangeID string, enabled bool) (int, error) {
+	exchangeID = strings.ToLower(strings.TrimSpace(exchangeID))
+	if exchangeID == "" {
+		return 0, fmt.Errorf("交易所类型不能为空")
+	}
+	result, err := d.db.Exec(`
+		UPDATE exchanges SET enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND LOWER(exchange_id) = ?
+	`, enabled, userID, exchangeID)
+	if err != nil {
+		return 0, fmt.Errorf("更新交易所启用状态失败: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
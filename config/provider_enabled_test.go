@@ -0,0 +1,67 @@
+package config
+
+import "testing"
+
+func TestSetProviderEnabled(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	for _, m := range []struct{ id, provider string }{{"ds-1", "deepseek"}, {"ds-2", "deepseek"}, {"qw-1", "qwen"}} {
+		if err := db.CreateAIModel(userID, m.id, m.id, m.provider, true, "", ""); err != nil {
+			t.Fatalf("CreateAIModel failed: %v", err)
+		}
+	}
+	// 其他用户的配置不受影响
+	if err := db.CreateAIModel("test-user-002", "ds-other", "ds-other", "deepseek", true, "", ""); err != nil {
+		t.Fatalf("CreateAIModel failed: %v", err)
+	}
+
+	affected, err := db.SetProviderEnabled(userID, "DeepSeek", false)
+	if err != nil {
+		t.Fatalf("SetProviderEnabled failed: %v", err)
+	}
+	if affected != 2 {
+		t.Fatalf("affected = %d, want 2", affected)
+	}
+
+	models, _ := db.GetAIModels(userID)
+	for _, m := range models {
+		if want := m.Provider != "deepseek"; m.Enabled != want {
+			t.Errorf("model %s enabled = %v, want %v", m.ModelID, m.Enabled, want)
+		}
+	}
+	others, _ := db.GetAIModels("test-user-002")
+	for _, m := range others {
+		if m.ModelID == "ds-other" && !m.Enabled {
+			t.Error("other user's model should stay enabled")
+		}
+	}
+
+	if _, err := db.SetProviderEnabled(userID, " ", true); err == nil {
+		t.Error("expected error for empty provider")
+	}
+}
+
+func TestSetExchangeTypeEnabled(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	ensureTestExchange(t, db, userID, "binance")
+	ensureTestExchange(t, db, userID, "aster")
+
+	affected, err := db.SetExchangeTypeEnabled(userID, "binance", false)
+	if err != nil {
+		t.Fatalf("SetExchangeTypeEnabled failed: %v", err)
+	}
+	if affected != 1 {
+		t.Fatalf("affected = %d, want 1", affected)
+	}
+	exchanges, _ := db.GetExchanges(userID)
+	for _, e := range exchanges {
+		if want := e.ExchangeID != "binance"; e.Enabled != want {
+			t.Errorf("exchange %s enabled = %v, want %v", e.ExchangeID, e.Enabled, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import "fmt"
+
+// UsageCount 某个AI提供商或交易所被交易员使用的数量
+type UsageCount struct {
+	Name    string `json:"name"`
+	Traders int    `json:"traders"` // 使用该提供商/交易所的交易员数量
+	Running int    `json:"running"` // 其中正在运行的数量
+}
+
+// ProviderUsageStats 全平台（所有用户）交易员的AI提供商和交易所分布
+type ProviderUsageStats struct {
+	TotalTraders int          `json:"total_traders"`
+	AIProviders  []UsageCount `json:"ai_providers"` // 按 ai_models.provider 分组（deepseek / qwen / custom）
+	Exchanges    []UsageCount `json:"exchanges"`    // 按 exchanges.exchange_id 分组（binance / hyperliquid / aster）
+}
+
+// GetProviderUsageStats 统计所有用户的交易员按AI提供商和交易所类型的分布（用于容量规划）
+// 关联的模型或交易所已被删除的交易员计入 "unknown"；结果按交易员数量降序排列
+func (d *Database) GetProviderUsageStats() (*ProviderUsageStats, error) {
+	stats := &ProviderUsageStats{}
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM traders`).Scan(&stats.TotalTraders); err != nil {
+		return nil, fmt.Errorf("统计交易员数量失败: %w", err)
+	}
+
+	var err error
+	stats.AIProviders, err = d.queryUsageCounts(`
+		SELECT COALESCE(NULLIF(a.provider, ''), 'unknown') AS usage_name,
+		       COUNT(*), COALESCE(SUM(CASE WHEN t.is_running THEN 1 ELSE 0 END), 0)
+		FROM traders t LEFT JOIN ai_models a ON a.id = t.ai_model_id
+		GROUP BY usage_name ORDER BY COUNT(*) DESC, usage_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("统计AI提供商分布失败: %w", err)
+	}
+
+	stats.Exchanges, err = d.queryUsageCounts(`
+		SELECT COALESCE(NULLIF(e.exchange_id, ''), 'unknown') AS usage_name,
+		       COUNT(*), COALESCE(SUM(CASE WHEN t.is_running THEN 1 ELSE 0 END), 0)
+		FROM traders t LEFT JOIN exchanges e ON e.id = t.exchange_id
+		GROUP BY usage_name ORDER BY COUNT(*) DESC, usage_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("统计交易所分布失败: %w", err)
+	}
+	return stats, nil
+}
+
+func (d *Database) queryUsageCounts(query string) ([]UsageCount, error) {
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]UsageCount, 0)
+	for rows.Next() {
+		var c UsageCount
+		if err := rows.Scan(&c.Name, &c.Traders, &c.Running); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
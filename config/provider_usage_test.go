@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestGetProviderUsageStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	deepseekID := ensureTestAIModel(t, db, "test-user-001", "model-usage-ds")
+	binanceID := ensureTestExchange(t, db, "test-user-001", "binance")
+	if err := db.CreateAIModel("test-user-002", "model-usage-qw", "Qwen", "qwen", true, "", ""); err != nil {
+		t.Fatalf("CreateAIModel failed: %v", err)
+	}
+	if err := db.CreateExchange("test-user-002", "hyperliquid", "Hyperliquid", "dex", true, "key", "", false, "0xabc", "", "", ""); err != nil {
+		t.Fatalf("CreateExchange failed: %v", err)
+	}
+	models, _ := db.GetAIModels("test-user-002")
+	exchanges, _ := db.GetExchanges("test-user-002")
+	var qwenID, hlID int
+	for _, m := range models {
+		if m.ModelID == "model-usage-qw" {
+			qwenID = m.ID
+		}
+	}
+	for _, e := range exchanges {
+		if e.ExchangeID == "hyperliquid" {
+			hlID = e.ID
+		}
+	}
+
+	traders := []*TraderRecord{
+		{ID: "usage-1", UserID: "test-user-001", AIModelID: deepseekID, ExchangeID: binanceID, IsRunning: true},
+		{ID: "usage-2", UserID: "test-user-001", AIModelID: deepseekID, ExchangeID: binanceID},
+		{ID: "usage-3", UserID: "test-user-002", AIModelID: qwenID, ExchangeID: hlID, IsRunning: true},
+	}
+	for _, tr := range traders {
+		tr.Name = tr.ID
+		tr.InitialBalance = 1000
+		tr.ScanIntervalMinutes = 3
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	stats, err := db.GetProviderUsageStats()
+	if err != nil {
+		t.Fatalf("GetProviderUsageStats failed: %v", err)
+	}
+	if stats.TotalTraders != 3 {
+		t.Fatalf("TotalTraders = %d, want 3", stats.TotalTraders)
+	}
+	wantProviders := []UsageCount{{Name: "deepseek", Traders: 2, Running: 1}, {Name: "qwen", Traders: 1, Running: 1}}
+	if len(stats.AIProviders) != 2 || stats.AIProviders[0] != wantProviders[0] || stats.AIProviders[1] != wantProviders[1] {
+		t.Fatalf("AIProviders = %+v, want %+v", stats.AIProviders, wantProviders)
+	}
+	wantExchanges := []UsageCount{{Name: "binance", Traders: 2, Running: 1}, {Name: "hyperliquid", Traders: 1, Running: 1}}
+	if len(stats.Exchanges) != 2 || stats.Exchanges[0] != wantExchanges[0] || stats.Exchanges[1] != wantExchanges[1] {
+		t.Fatalf("Exchanges = %+v, want %+v", stats.Exchanges, wantExchanges)
+	}
+}
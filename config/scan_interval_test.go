@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeScanInterval(t *testing.T) {
+	tests := []struct {
+		name             string
+		seconds, minutes int
+		want             int
+	}{
+		{"seconds take precedence", 45, 5, 45},
+		{"fallback to minutes", 0, 5, 300},
+		{"clamped to floor", 5, 0, MinScanIntervalSeconds},
+		{"default when unset", 0, 0, 180},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeScanInterval(tt.seconds, tt.minutes, 180); got != tt.want {
+				t.Errorf("NormalizeScanInterval(%d, %d) = %d, want %d", tt.seconds, tt.minutes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanIntervalSecondsRoundTrip(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-scan-1")
+	exID := ensureTestExchange(t, db, userID, "binance-scan-1")
+
+	// 仅设置分钟：秒级字段自动换算
+	legacy := &TraderRecord{
+		ID: "tr-scan-legacy", UserID: userID, Name: "legacy", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 4, SystemPromptTemplate: "default",
+	}
+	fast := &TraderRecord{
+		ID: "tr-scan-fast", UserID: userID, Name: "fast", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 1, ScanIntervalSeconds: 30, SystemPromptTemplate: "default",
+	}
+	for _, tr := range []*TraderRecord{legacy, fast} {
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	got, _, _, err := db.GetTraderConfig(userID, legacy.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if got.ScanIntervalSeconds != 240 || got.ScanInterval() != 4*time.Minute {
+		t.Fatalf("legacy scan interval = %ds (%v), want 240s", got.ScanIntervalSeconds, got.ScanInterval())
+	}
+
+	got, _, _, err = db.GetTraderConfig(userID, fast.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if got.ScanInterval() != 30*time.Second {
+		t.Fatalf("fast scan interval = %v, want 30s", got.ScanInterval())
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 数据库结构版本，迁移完成后写入 schema_version 表
+// 热路径通过读取版本号判断结构，而不是每次都探测列是否存在
+const (
+	schemaVersionMultiUserExchanges = 1 // exchanges 表支持多用户（exchange_id 列）
+	schemaVersionAutoIncrementID    = 2 // ai_models / exchanges 使用自增ID（model_id 列）
+
+	currentSchemaVersion = schemaVersionAutoIncrementID
+)
+
+// SchemaVersion 读取当前已应用的结构版本，未记录时返回 0
+func (d *Database) SchemaVersion() (int, error) {
+	var version int
+	err := d.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("读取结构版本失败: %w", err)
+	}
+	return version, nil
+}
+
+// setSchemaVersion 记录已应用的结构版本（只升不降）
+func (d *Database) setSchemaVersion(version int) error {
+	_, err := d.db.Exec(`
+		INSERT INTO schema_version (id, version) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET version = excluded.version, updated_at = CURRENT_TIMESTAMP
+		WHERE excluded.version > schema_version.version
+	`, version)
+	if err != nil {
+		return fmt.Errorf("记录结构版本失败: %w", err)
+	}
+	return nil
+}
+
+// schemaAtLeast 结构版本是否不低于 version；读取失败（如跳过迁移的旧数据库）时返回 false
+func (d *Database) schemaAtLeast(version int) bool {
+	current, err := d.SchemaVersion()
+	return err == nil && current >= version
+}
+
+// columnExists 探测表中是否存在某列（仅在结构版本未知时使用）
+func (d *Database) columnExists(table, column string) (bool, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// schemaCheckCache 缓存表结构检查结果（迁移完成后运行期间不会变化）
+type schemaCheckCache struct {
+	mu      sync.Mutex
+	results map[string]bool
+}
+
+// cached 返回已缓存的结果，未缓存时执行 check 并缓存（出错时不缓存）
+func (c *schemaCheckCache) cached(key string, check func() (bool, error)) (bool, error) {
+	c.mu.Lock()
+	result, ok := c.results[key]
+	c.mu.Unlock()
+	if ok {
+		return result, nil
+	}
+
+	result, err := check()
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	if c.results == nil {
+		c.results = make(map[string]bool)
+	}
+	c.results[key] = result
+	c.mu.Unlock()
+	return result, nil
+}
+
+// reset 清空缓存（执行迁移后调用）
+func (c *schemaCheckCache) reset() {
+	c.mu.Lock()
+	c.results = nil
+	c.mu.Unlock()
+}
+
+// hasModelIDColumn ai_models 是否已迁移到自增ID结构（有 model_id 列）
+func (d *Database) hasModelIDColumn() (bool, error) {
+	return d.schemaChecks.cached("ai_models.model_id", func() (bool, error) {
+		if d.schemaAtLeast(schemaVersionAutoIncrementID) {
+			return true, nil
+		}
+		return d.columnExists("ai_models", "model_id")
+	})
+}
+
+// hasExchangeIDColumn exchanges 是否已迁移到多用户结构（有 exchange_id 列）
+func (d *Database) hasExchangeIDColumn() (bool, error) {
+	return d.schemaChecks.cached("exchanges.exchange_id", func() (bool, error) {
+		if d.schemaAtLeast(schemaVersionMultiUserExchanges) {
+			return true, nil
+		}
+		return d.columnExists("exchanges", "exchange_id")
+	})
+}
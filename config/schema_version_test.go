@@ -0,0 +1,79 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaVersion_RecordedAfterMigrations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != currentSchemaVersion {
+		t.Fatalf("version = %d, want %d", version, currentSchemaVersion)
+	}
+
+	// 版本只升不降
+	if err := db.setSchemaVersion(schemaVersionMultiUserExchanges); err != nil {
+		t.Fatalf("setSchemaVersion failed: %v", err)
+	}
+	if version, _ = db.SchemaVersion(); version != currentSchemaVersion {
+		t.Fatalf("version downgraded to %d", version)
+	}
+
+	hasModelID, err := db.hasModelIDColumn()
+	if err != nil || !hasModelID {
+		t.Fatalf("hasModelIDColumn = %v (%v), want true", hasModelID, err)
+	}
+}
+
+func TestSchemaVersion_FallsBackToProbeWithoutMigrations(t *testing.T) {
+	db, err := NewDatabaseWithOptions(filepath.Join(t.TempDir(), "probe.db"), DatabaseOptions{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("NewDatabaseWithOptions failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.SchemaVersion(); err == nil {
+		t.Fatal("expected error before schema_version table exists")
+	}
+	if _, err := db.db.Exec(`CREATE TABLE ai_models (id TEXT PRIMARY KEY, user_id TEXT)`); err != nil {
+		t.Fatalf("create legacy table failed: %v", err)
+	}
+	hasModelID, err := db.hasModelIDColumn()
+	if err != nil || hasModelID {
+		t.Fatalf("hasModelIDColumn = %v (%v), want false for legacy table", hasModelID, err)
+	}
+}
+
+func TestSchemaChecks_Memoized(t *testing.T) {
+	db, err := NewDatabaseWithOptions(filepath.Join(t.TempDir(), "memo.db"), DatabaseOptions{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("NewDatabaseWithOptions failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.db.Exec(`CREATE TABLE exchanges (id TEXT PRIMARY KEY, user_id TEXT)`); err != nil {
+		t.Fatalf("create legacy table failed: %v", err)
+	}
+	if has, _ := db.hasExchangeIDColumn(); has {
+		t.Fatal("legacy table should not have exchange_id")
+	}
+
+	// 结果已缓存：结构变化后不会重新探测
+	if _, err := db.db.Exec(`ALTER TABLE exchanges ADD COLUMN exchange_id TEXT`); err != nil {
+		t.Fatalf("alter table failed: %v", err)
+	}
+	if has, _ := db.hasExchangeIDColumn(); has {
+		t.Fatal("expected memoized result")
+	}
+
+	db.schemaChecks.reset()
+	if has, _ := db.hasExchangeIDColumn(); !has {
+		t.Fatal("expected fresh check after reset")
+	}
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"log"
+	"nofx/market"
+)
+
+// GetSentimentWeights 获取情绪信号混合权重（system_config: sentiment_weights，JSON）
+// 未配置或配置无效时使用 market.DefaultSentimentWeights
+func (d *Database) GetSentimentWeights() market.SentimentWeights {
+	raw, err := d.GetSystemConfig("sentiment_weights")
+	if err != nil {
+		return market.DefaultSentimentWeights
+	}
+	weights, err := market.ParseSentimentWeights(raw)
+	if err != nil {
+		log.Printf("⚠️ sentiment_weights 配置无效，使用默认权重: %v", err)
+	}
+	return weights
+}
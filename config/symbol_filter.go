@@ -0,0 +1,77 @@
+package config
+
+import (
+	"log"
+	"nofx/market"
+	"strings"
+)
+
+// SymbolFilter 系统级币种白名单/黑名单（system_config: symbol_allowlist / symbol_denylist）
+// 白名单为空表示不限制；黑名单优先于白名单
+type SymbolFilter struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// GetSymbolFilter 读取系统级币种过滤配置
+func (d *Database) GetSymbolFilter() *SymbolFilter {
+	if d == nil || d.db == nil {
+		return &SymbolFilter{}
+	}
+	allowRaw, _ := d.GetSystemConfig("symbol_allowlist")
+	denyRaw, _ := d.GetSystemConfig("symbol_denylist")
+	return NewSymbolFilter(allowRaw, denyRaw)
+}
+
+// NewSymbolFilter 根据逗号分隔的白名单/黑名单创建过滤器
+func NewSymbolFilter(allowList, denyList string) *SymbolFilter {
+	return &SymbolFilter{
+		allow: parseSymbolSet(allowList),
+		deny:  parseSymbolSet(denyList),
+	}
+}
+
+func parseSymbolSet(raw string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, token := range strings.Split(raw, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		set[market.Normalize(token)] = struct{}{}
+	}
+	return set
+}
+
+// Allowed 判断币种是否允许交易
+func (f *SymbolFilter) Allowed(symbol string) bool {
+	if f == nil {
+		return true
+	}
+	normalized := market.Normalize(symbol)
+	if _, denied := f.deny[normalized]; denied {
+		return false
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	_, allowed := f.allow[normalized]
+	return allowed
+}
+
+// Filter 过滤币种列表，被拒绝的币种会记录警告日志
+// source 用于日志说明来源（例如交易员名称）
+func (f *SymbolFilter) Filter(symbols []string, source string) []string {
+	if f == nil || (len(f.allow) == 0 && len(f.deny) == 0) {
+		return symbols
+	}
+	filtered := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if f.Allowed(symbol) {
+			filtered = append(filtered, symbol)
+			continue
+		}
+		log.Printf("⚠️ [%s] 币种 %s 被系统白名单/黑名单禁止，已移除", source, symbol)
+	}
+	return filtered
+}
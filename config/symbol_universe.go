@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// 币种状态（symbols 表 status 列）
+const (
+	SymbolStatusTrading  = "trading"  // 正常交易
+	SymbolStatusBreak    = "break"    // 暂停交易（维护、待上线等）
+	SymbolStatusDelisted = "delisted" // 已下架（交易所返回 CLOSE/SETTLING，或不再出现在 exchangeInfo 中）
+)
+
+// symbolUniverseExchange symbols 表目前只保存 Binance U本位合约
+const symbolUniverseExchange = "binance"
+
+// SymbolUniverseFetcher 获取交易所合约列表（默认请求 Binance /fapi/v1/exchangeInfo）
+type SymbolUniverseFetcher func() ([]market.SymbolInfo, error)
+
+// SymbolRecord symbols 表记录
+type SymbolRecord struct {
+	Symbol     string    `json:"symbol"`
+	Status     string    `json:"status"`
+	BaseAsset  string    `json:"base_asset"`
+	QuoteAsset string    `json:"quote_asset"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SetSymbolUniverseFetcher 替换合约列表获取函数（测试或自定义数据源）
+func (d *Database) SetSymbolUniverseFetcher(fetcher SymbolUniverseFetcher) {
+	d.universeFetcher = fetcher
+}
+
+func fetchBinanceSymbolUniverse() ([]market.SymbolInfo, error) {
+	info, err := market.NewAPIClient().GetExchangeInfo()
+	if err != nil {
+		return nil, err
+	}
+	return info.Symbols, nil
+}
+
+// binanceSymbolStatus 将 Binance 合约状态映射为 symbols 表状态
+func binanceSymbolStatus(status string) string {
+	switch strings.ToUpper(status) {
+	case "TRADING":
+		return SymbolStatusTrading
+	case "CLOSE", "SETTLING", "DELIVERING", "DELIVERED":
+		return SymbolStatusDelisted
+	default:
+		return SymbolStatusBreak
+	}
+}
+
+// RefreshSymbolUniverse 拉取 Binance 全部 USDT 永续合约并写入 symbols 表
+// 本次未返回的已知币种标记为 delisted；返回本次各状态的币种数
+func (d *Database) RefreshSymbolUniverse() (map[string]int, error) {
+	fetcher := d.universeFetcher
+	if fetcher == nil {
+		fetcher = fetchBinanceSymbolUniverse
+	}
+	listed, err := fetcher()
+	if err != nil {
+		return nil, fmt.Errorf("获取合约列表失败: %w", err)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	known := make(map[string]string)
+	rows, err := tx.Query(`SELECT symbol, status FROM symbols WHERE exchange = ?`, symbolUniverseExchange)
+	if err != nil {
+		return nil, fmt.Errorf("查询已知币种失败: %w", err)
+	}
+	for rows.Next() {
+		var symbol, status string
+		if err := rows.Scan(&symbol, &status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取已知币种失败: %w", err)
+		}
+		known[symbol] = status
+	}
+	rows.Close()
+
+	now := time.Now().UTC().Format(sqliteTimeLayout)
+	counts := map[string]int{SymbolStatusTrading: 0, SymbolStatusBreak: 0, SymbolStatusDelisted: 0}
+	for _, s := range listed {
+		if s.QuoteAsset != "USDT" || (s.ContractType != "" && s.ContractType != "PERPETUAL") {
+			continue
+		}
+		status := binanceSymbolStatus(s.Status)
+		if _, err := tx.Exec(`
+			INSERT INTO symbols (exchange, symbol, status, base_asset, quote_asset, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(exchange, symbol) DO UPDATE SET
+				status = excluded.status, base_asset = excluded.base_asset,
+				quote_asset = excluded.quote_asset, updated_at = excluded.updated_at
+		`, symbolUniverseExchange, s.Symbol, status, s.BaseAsset, s.QuoteAsset, now); err != nil {
+			return nil, fmt.Errorf("保存币种 %s 失败: %w", s.Symbol, err)
+		}
+		counts[status]++
+		delete(known, s.Symbol)
+	}
+
+	// 本次未返回的已知币种视为已下架
+	for symbol, status := range known {
+		if status == SymbolStatusDelisted {
+			continue
+		}
+		if _, err := tx.Exec(`
+			UPDATE symbols SET status = ?, updated_at = ? WHERE exchange = ? AND symbol = ?
+		`, SymbolStatusDelisted, now, symbolUniverseExchange, symbol); err != nil {
+			return nil, fmt.Errorf("标记下架币种 %s 失败: %w", symbol, err)
+		}
+		counts[SymbolStatusDelisted]++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交币种列表失败: %w", err)
+	}
+	log.Printf("📜 币种列表已更新: 可交易 %d / 暂停 %d / 本次下架 %d",
+		counts[SymbolStatusTrading], counts[SymbolStatusBreak], counts[SymbolStatusDelisted])
+	return counts, nil
+}
+
+// StartSymbolUniverseRefresh 后台定期刷新币种列表（启动时立即执行一次）
+func (d *Database) StartSymbolUniverseRefresh(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := d.RefreshSymbolUniverse(); err != nil {
+				log.Printf("⚠️ 刷新币种列表失败: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// GetSymbolUniverse 获取 symbols 表中的币种，status 为空时返回全部
+func (d *Database) GetSymbolUniverse(status string) ([]*SymbolRecord, error) {
+	rows, err := d.db.Query(`
+		SELECT symbol, status, base_asset, quote_asset, updated_at FROM symbols
+		WHERE exchange = ? AND (? = '' OR status = ?)
+		ORDER BY symbol
+	`, symbolUniverseExchange, status, status)
+	if err != nil {
+		return nil, fmt.Errorf("查询币种列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]*SymbolRecord, 0)
+	for rows.Next() {
+		var r SymbolRecord
+		if err := rows.Scan(&r.Symbol, &r.Status, &r.BaseAsset, &r.QuoteAsset, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("读取币种列表失败: %w", err)
+		}
+		records = append(records, &r)
+	}
+	return records, rows.Err()
+}
+
+// symbolStatuses 查询 symbols 表中已知币种的状态（未记录的币种不在返回结果中）
+func (d *Database) symbolStatuses(symbols []string) (map[string]string, error) {
+	statuses := make(map[string]string, len(symbols))
+	if len(symbols) == 0 {
+		return statuses, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(symbols)), ",")
+	args := []interface{}{symbolUniverseExchange}
+	for _, s := range symbols {
+		args = append(args, s)
+	}
+	rows, err := d.db.Query(`SELECT symbol, status FROM symbols WHERE exchange = ? AND symbol IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询币种状态失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var symbol, status string
+		if err := rows.Scan(&symbol, &status); err != nil {
+			return nil, fmt.Errorf("读取币种状态失败: %w", err)
+		}
+		statuses[symbol] = status
+	}
+	return statuses, rows.Err()
+}
+
+// unsupportedByUniverse 按 symbols 表判断 Binance 主网不可交易的币种
+// 表中没有数据（从未刷新）时返回 ok=false，由调用方跳过校验
+func (d *Database) unsupportedByUniverse(symbols []string) (unsupported []string, ok bool) {
+	var total int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM symbols WHERE exchange = ?`, symbolUniverseExchange).Scan(&total); err != nil || total == 0 {
+		return nil, false
+	}
+	normalized := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		normalized = append(normalized, market.Normalize(strings.TrimSpace(s)))
+	}
+	statuses, err := d.symbolStatuses(normalized)
+	if err != nil {
+		log.Printf("⚠️ %v", err)
+		return nil, false
+	}
+	for _, s := range normalized {
+		if statuses[s] != SymbolStatusTrading {
+			unsupported = append(unsupported, s)
+		}
+	}
+	return unsupported, true
+}
+
+// filterDelistedCoins 从默认币种中剔除 symbols 表标记为已下架的币种（表中未记录的保留，全部下架时原样返回）
+func (d *Database) filterDelistedCoins(symbols []string) []string {
+	statuses, err := d.symbolStatuses(symbols)
+	if err != nil || len(statuses) == 0 {
+		return symbols
+	}
+	kept := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		if statuses[s] == SymbolStatusDelisted {
+			log.Printf("⚠️ 默认币种 %s 已下架，已跳过", s)
+			continue
+		}
+		kept = append(kept, s)
+	}
+	if len(kept) == 0 {
+		return symbols
+	}
+	return kept
+}
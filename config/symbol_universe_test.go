@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"nofx/market"
+	"slices"
+	"testing"
+)
+
+func TestRefreshSymbolUniverse(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	listed := []market.SymbolInfo{
+		{Symbol: "BTCUSDT", Status: "TRADING", BaseAsset: "BTC", QuoteAsset: "USDT", ContractType: "PERPETUAL"},
+		{Symbol: "SOLUSDT", Status: "TRADING", BaseAsset: "SOL", QuoteAsset: "USDT", ContractType: "PERPETUAL"},
+		{Symbol: "NEWUSDT", Status: "PENDING_TRADING", BaseAsset: "NEW", QuoteAsset: "USDT", ContractType: "PERPETUAL"},
+		{Symbol: "OLDUSDT", Status: "SETTLING", BaseAsset: "OLD", QuoteAsset: "USDT", ContractType: "PERPETUAL"},
+		// 非 USDT 永续合约不入库
+		{Symbol: "BTCUSDC", Status: "TRADING", BaseAsset: "BTC", QuoteAsset: "USDC", ContractType: "PERPETUAL"},
+		{Symbol: "BTCUSDT_250627", Status: "TRADING", BaseAsset: "BTC", QuoteAsset: "USDT", ContractType: "CURRENT_QUARTER"},
+	}
+	db.SetSymbolUniverseFetcher(func() ([]market.SymbolInfo, error) { return listed, nil })
+
+	counts, err := db.RefreshSymbolUniverse()
+	if err != nil {
+		t.Fatalf("RefreshSymbolUniverse failed: %v", err)
+	}
+	if counts[SymbolStatusTrading] != 2 || counts[SymbolStatusBreak] != 1 || counts[SymbolStatusDelisted] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+
+	// 第二次刷新时 SOL 不再返回，应标记为下架
+	listed = listed[:1]
+	if _, err := db.RefreshSymbolUniverse(); err != nil {
+		t.Fatalf("RefreshSymbolUniverse failed: %v", err)
+	}
+	records, err := db.GetSymbolUniverse("")
+	if err != nil {
+		t.Fatalf("GetSymbolUniverse failed: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, r := range records {
+		statuses[r.Symbol] = r.Status
+	}
+	want := map[string]string{
+		"BTCUSDT": SymbolStatusTrading, "SOLUSDT": SymbolStatusDelisted,
+		"NEWUSDT": SymbolStatusDelisted, "OLDUSDT": SymbolStatusDelisted,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("unexpected symbols: %v", statuses)
+	}
+	for symbol, status := range want {
+		if statuses[symbol] != status {
+			t.Errorf("%s status = %q, want %q", symbol, statuses[symbol], status)
+		}
+	}
+
+	trading, err := db.GetSymbolUniverse(SymbolStatusTrading)
+	if err != nil || len(trading) != 1 || trading[0].Symbol != "BTCUSDT" {
+		t.Fatalf("expected only BTCUSDT trading, got %v (%v)", trading, err)
+	}
+
+	// 获取失败时保留原数据
+	db.SetSymbolUniverseFetcher(func() ([]market.SymbolInfo, error) { return nil, errors.New("timeout") })
+	if _, err := db.RefreshSymbolUniverse(); err == nil {
+		t.Fatal("expected fetch error")
+	}
+	if records, _ := db.GetSymbolUniverse(""); len(records) != len(want) {
+		t.Fatalf("failed refresh should keep existing rows, got %d", len(records))
+	}
+}
+
+func TestSymbolUniverseReferences(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	db.SetSymbolUniverseFetcher(func() ([]market.SymbolInfo, error) {
+		return []market.SymbolInfo{
+			{Symbol: "BTCUSDT", Status: "TRADING", QuoteAsset: "USDT", ContractType: "PERPETUAL"},
+			{Symbol: "ETHUSDT", Status: "TRADING", QuoteAsset: "USDT", ContractType: "PERPETUAL"},
+			{Symbol: "XRPUSDT", Status: "CLOSE", QuoteAsset: "USDT", ContractType: "PERPETUAL"},
+		}, nil
+	})
+	if _, err := db.RefreshSymbolUniverse(); err != nil {
+		t.Fatalf("RefreshSymbolUniverse failed: %v", err)
+	}
+
+	// 默认币种剔除已下架的，未记录的保留
+	if err := db.SetSystemConfig("default_coins", `["BTCUSDT","XRPUSDT","DOGEUSDT"]`); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+	if got := db.getDefaultCoins(); !slices.Equal(got, []string{"BTCUSDT", "DOGEUSDT"}) {
+		t.Fatalf("getDefaultCoins = %v", got)
+	}
+
+	// 未配置实时校验时，Binance 主网按 symbols 表校验
+	userID := "test-user-001"
+	exID := ensureTestExchange(t, db, userID, "binance")
+	err := db.validateTraderExchangeSymbols(userID, exID, "BTC,XRPUSDT,FOOUSDT")
+	if !errors.Is(err, ErrUnsupportedSymbols) {
+		t.Fatalf("expected ErrUnsupportedSymbols, got %v", err)
+	}
+	if err := db.validateTraderExchangeSymbols(userID, exID, "BTCUSDT,ETH"); err != nil {
+		t.Fatalf("listed symbols should pass: %v", err)
+	}
+}
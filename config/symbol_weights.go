@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"nofx/market"
+	"strings"
+)
+
+// ErrInvalidSymbolWeights 币种权重配置无效
+var ErrInvalidSymbolWeights = errors.New("币种权重配置无效")
+
+// parseSymbolWeights 解析 traders.symbol_weights（JSON 对象：币种 -> 权重，空字符串表示等权）
+// 币种统一为 USDT 交易对格式；权重必须为非负数
+func parseSymbolWeights(raw string) (map[string]float64, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var weights map[string]float64
+	if err := json.Unmarshal([]byte(raw), &weights); err != nil {
+		return nil, fmt.Errorf("必须是 币种 -> 权重 的JSON对象: %v", err)
+	}
+	normalized := make(map[string]float64, len(weights))
+	for symbol, weight := range weights {
+		symbol = strings.TrimSpace(symbol)
+		if symbol == "" {
+			return nil, fmt.Errorf("币种不能为空")
+		}
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("%s 的权重必须为非负数，当前为 %v", symbol, weight)
+		}
+		normalized[market.Normalize(symbol)] = weight
+	}
+	return normalized, nil
+}
+
+// validateSymbolWeights 校验 traders.symbol_weights
+func validateSymbolWeights(raw string) error {
+	if _, err := parseSymbolWeights(raw); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSymbolWeights, err)
+	}
+	return nil
+}
+
+// SymbolWeightsMap 返回交易员的币种权重（未配置或配置无效时返回 nil，所有币种等权）
+func (t *TraderRecord) SymbolWeightsMap() map[string]float64 {
+	weights, err := parseSymbolWeights(t.SymbolWeights)
+	if err != nil {
+		return nil
+	}
+	return weights
+}
+
+// EncodeSymbolWeights 序列化币种权重（空配置返回空字符串）
+func EncodeSymbolWeights(weights map[string]float64) string {
+	if len(weights) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(weights)
+	return string(data)
+}
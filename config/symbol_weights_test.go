@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTraderSymbolWeights(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-weights")
+	exID := ensureTestExchange(t, db, userID, "binance-weights")
+	tr := &TraderRecord{
+		ID: "tr-weights", UserID: userID, Name: "weights", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+	}
+
+	for _, raw := range []string{`{"BTCUSDT":-1}`, `["BTCUSDT"]`, `{"BTCUSDT":"2"}`, `{"":1}`} {
+		tr.SymbolWeights = raw
+		if err := db.CreateTrader(tr); !errors.Is(err, ErrInvalidSymbolWeights) {
+			t.Fatalf("%s: expected ErrInvalidSymbolWeights, got %v", raw, err)
+		}
+	}
+
+	tr.SymbolWeights = EncodeSymbolWeights(map[string]float64{"btc": 2, "ETHUSDT": 0})
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	got, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	weights := got.SymbolWeightsMap()
+	if w, ok := weights["ETHUSDT"]; len(weights) != 2 || weights["BTCUSDT"] != 2 || !ok || w != 0 {
+		t.Fatalf("unexpected weights: %v", weights)
+	}
+
+	tr.SymbolWeights = `{"BTCUSDT":-0.5}`
+	if err := db.UpdateTrader(tr); !errors.Is(err, ErrInvalidSymbolWeights) {
+		t.Fatalf("expected ErrInvalidSymbolWeights on update, got %v", err)
+	}
+	tr.SymbolWeights = EncodeSymbolWeights(nil)
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	got, _, _, _ = db.GetTraderConfig(userID, tr.ID)
+	if got.SymbolWeightsMap() != nil {
+		t.Fatalf("expected equal weights after reset, got %v", got.SymbolWeightsMap())
+	}
+}
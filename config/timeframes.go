@@ -0,0 +1,51 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+// fallbackDefaultTimeframes system_config.default_timeframes 缺失或无效时使用的默认时间线
+var fallbackDefaultTimeframes = []string{"4h"}
+
+// supportedTimeframes WSMonitor 支持订阅的K线时间线
+var supportedTimeframes = map[string]bool{
+	"1m": true, "3m": true, "5m": true, "15m": true, "1h": true, "4h": true, "1d": true,
+}
+
+// ValidTimeframe 判断时间线是否受支持
+func ValidTimeframe(tf string) bool {
+	return supportedTimeframes[strings.TrimSpace(tf)]
+}
+
+// parseTimeframes 解析逗号分隔的时间线列表（去重，忽略无效值）
+func parseTimeframes(raw, source string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tf := range strings.Split(raw, ",") {
+		tf = strings.TrimSpace(tf)
+		if tf == "" || seen[tf] {
+			continue
+		}
+		if !ValidTimeframe(tf) {
+			log.Printf("⚠️ %s 包含不支持的时间线 %q，已忽略", source, tf)
+			continue
+		}
+		seen[tf] = true
+		result = append(result, tf)
+	}
+	return result
+}
+
+// GetDefaultTimeframes 获取默认时间线（system_config: default_timeframes，逗号分隔）
+// 交易员未配置时间线时统一使用该默认值
+func (d *Database) GetDefaultTimeframes() []string {
+	if d == nil || d.db == nil {
+		return append([]string(nil), fallbackDefaultTimeframes...)
+	}
+	raw, _ := d.GetSystemConfig("default_timeframes")
+	if timeframes := parseTimeframes(raw, "default_timeframes"); len(timeframes) > 0 {
+		return timeframes
+	}
+	return append([]string(nil), fallbackDefaultTimeframes...)
+}
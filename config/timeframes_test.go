@@ -324,6 +324,65 @@ func TestTimeframes_EdgeCases(t *testing.T) {
 	}
 }
 
+// TestTimeframes_DefaultTimeframes 測試默認時間線配置
+func TestTimeframes_DefaultTimeframes(t *testing.T) {
+	db, cleanup := setupTestDBForTimeframes(t)
+	defer cleanup()
+
+	if got := db.GetDefaultTimeframes(); strings.Join(got, ",") != "4h" {
+		t.Errorf("預期默認 '4h', 實際 %v", got)
+	}
+	if got := db.GetAllTimeframes(); strings.Join(got, ",") != "4h" {
+		t.Errorf("無運行中 trader 時預期返回默認值 '4h', 實際 %v", got)
+	}
+
+	// 無效值被忽略
+	if err := db.SetSystemConfig("default_timeframes", "15m, 7m,1h,15m"); err != nil {
+		t.Fatalf("設置 default_timeframes 失敗: %v", err)
+	}
+	if got := db.GetDefaultTimeframes(); strings.Join(got, ",") != "15m,1h" {
+		t.Errorf("預期 '15m,1h', 實際 %v", got)
+	}
+
+	// 全部無效時回退到內置默認值
+	if err := db.SetSystemConfig("default_timeframes", "2w"); err != nil {
+		t.Fatalf("設置 default_timeframes 失敗: %v", err)
+	}
+	if got := db.GetDefaultTimeframes(); strings.Join(got, ",") != "4h" {
+		t.Errorf("預期回退 '4h', 實際 %v", got)
+	}
+
+	// GetTraderConfig 對未配置時間線的 trader 使用同一默認值
+	if err := db.SetSystemConfig("default_timeframes", "1h"); err != nil {
+		t.Fatalf("設置 default_timeframes 失敗: %v", err)
+	}
+	userID := "test-user-tf-005"
+	aiModelID, exchangeID := setupAIModelAndExchange(t, db, userID)
+	trader := &TraderRecord{
+		ID:                  "trader-no-tf",
+		UserID:              userID,
+		Name:                "No Timeframes",
+		AIModelID:           aiModelID,
+		ExchangeID:          exchangeID,
+		InitialBalance:      1000.0,
+		ScanIntervalMinutes: 60,
+		IsRunning:           true,
+	}
+	if err := db.CreateTrader(trader); err != nil {
+		t.Fatalf("創建失敗: %v", err)
+	}
+	traderRecord, _, _, err := db.GetTraderConfig(userID, trader.ID)
+	if err != nil {
+		t.Fatalf("獲取配置失敗: %v", err)
+	}
+	if traderRecord.Timeframes != "1h" {
+		t.Errorf("預期默認 '1h', 實際 '%s'", traderRecord.Timeframes)
+	}
+	if got := db.GetAllTimeframes(); strings.Join(got, ",") != "1h" {
+		t.Errorf("預期並集 '1h', 實際 %v", got)
+	}
+}
+
 // Helper functions
 
 func setupTestDBForTimeframes(t *testing.T) (*Database, func()) {
@@ -382,10 +441,16 @@ func setupTestDBForTimeframes(t *testing.T) (*Database, func()) {
 		t.Logf("警告：清理舊列失敗（可能不存在）: %v", err)
 	}
 
+	// 重建後補齊後續新增的列（ALTER TABLE 冪等）
+	if err := db.createTables(); err != nil {
+		t.Fatalf("補齊表結構失敗: %v", err)
+	}
+
 	// 創建測試用戶
 	testUsers := []string{
 		"test-user-tf-001", "test-user-tf-002",
 		"test-user-tf-003", "test-user-tf-004",
+		"test-user-tf-005",
 	}
 
 	for _, userID := range testUsers {
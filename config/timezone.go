@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTimezone 交易员时区配置无效
+var ErrInvalidTimezone = errors.New("时区无效，必须是 IANA 时区名称（例如 Asia/Shanghai）")
+
+// validateTimezone 校验 traders.timezone：为空表示 UTC，否则必须能被 time.LoadLocation 解析
+func validateTimezone(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(strings.TrimSpace(name)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTimezone, err)
+	}
+	return nil
+}
+
+// parseLocation 解析时区名称，为空或无效时返回 UTC
+func parseLocation(name string) *time.Location {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Location 返回交易员时区（未配置或配置无效时返回 UTC）
+func (t *TraderRecord) Location() *time.Location {
+	return parseLocation(t.Timezone)
+}
+
+// getTraderLocation 读取交易员时区，交易员不存在或未配置时返回 UTC
+func (d *Database) getTraderLocation(traderID string) *time.Location {
+	var name string
+	if err := d.db.QueryRow(`SELECT COALESCE(timezone, '') FROM traders WHERE id = ?`, traderID).Scan(&name); err != nil {
+		return time.UTC
+	}
+	return parseLocation(name)
+}
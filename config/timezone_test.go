@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTraderTimezone(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-tz")
+	exID := ensureTestExchange(t, db, userID, "binance-tz")
+	tr := &TraderRecord{
+		ID: "tr-tz", UserID: userID, Name: "tz", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+		Timezone: "Mars/Olympus_Mons",
+	}
+	if err := db.CreateTrader(tr); !errors.Is(err, ErrInvalidTimezone) {
+		t.Fatalf("expected ErrInvalidTimezone, got %v", err)
+	}
+
+	tr.Timezone = "America/New_York"
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	got, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if got.Timezone != "America/New_York" || got.Location().String() != "America/New_York" {
+		t.Fatalf("unexpected timezone: %q (%v)", got.Timezone, got.Location())
+	}
+	if loc := db.getTraderLocation(tr.ID); loc.String() != "America/New_York" {
+		t.Fatalf("getTraderLocation = %v", loc)
+	}
+
+	tr.Timezone = "UTC+8"
+	if err := db.UpdateTrader(tr); !errors.Is(err, ErrInvalidTimezone) {
+		t.Fatalf("expected ErrInvalidTimezone on update, got %v", err)
+	}
+	tr.Timezone = ""
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	if loc := db.getTraderLocation(tr.ID); loc != time.UTC {
+		t.Fatalf("empty timezone should fall back to UTC, got %v", loc)
+	}
+	if loc := db.getTraderLocation("missing"); loc != time.UTC {
+		t.Fatalf("missing trader should fall back to UTC, got %v", loc)
+	}
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// traderSnapshotExcludedFields 运行时状态字段，不属于配置，不计入快照
+var traderSnapshotExcludedFields = []string{"id", "user_id", "is_running", "stop_reason", "created_at", "updated_at"}
+
+// TraderConfigSnapshot 交易员配置快照
+type TraderConfigSnapshot struct {
+	ID        int64                  `json:"id"`
+	TraderID  string                 `json:"trader_id"`
+	UserID    string                 `json:"user_id"`
+	Config    map[string]interface{} `json:"config"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// TraderConfigChange 两个时间点之间发生变化的配置字段
+type TraderConfigChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"` // nil 表示该时间点尚无此字段（或尚无快照）
+	To    interface{} `json:"to"`
+}
+
+// recordTraderConfigSnapshotTx 在事务中读取交易员当前配置并写入快照
+func recordTraderConfigSnapshotTx(tx *sql.Tx, userID, traderID string) error {
+	rows, err := tx.Query(`SELECT `+traderSelectColumns+` FROM traders WHERE id = ? AND user_id = ?`, traderID, userID)
+	if err != nil {
+		return fmt.Errorf("读取交易员配置失败: %w", err)
+	}
+	if !rows.Next() {
+		rows.Close()
+		return fmt.Errorf("交易员不存在: %s", traderID)
+	}
+	trader, err := scanTraderRecord(rows)
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("读取交易员配置失败: %w", err)
+	}
+
+	snapshot, err := traderConfigSnapshot(trader)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("序列化配置快照失败: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO trader_config_history (trader_id, user_id, snapshot, created_at)
+		VALUES (?, ?, ?, ?)
+	`, traderID, userID, string(data), time.Now().UTC().Format(sqliteTimeLayout)); err != nil {
+		return fmt.Errorf("记录配置快照失败: %w", err)
+	}
+	return nil
+}
+
+// traderConfigSnapshot 将交易员记录转换为只含配置字段的 map
+func traderConfigSnapshot(trader *TraderRecord) (map[string]interface{}, error) {
+	data, err := json.Marshal(trader)
+	if err != nil {
+		return nil, fmt.Errorf("序列化配置快照失败: %w", err)
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("序列化配置快照失败: %w", err)
+	}
+	for _, field := range traderSnapshotExcludedFields {
+		delete(snapshot, field)
+	}
+	return snapshot, nil
+}
+
+// GetTraderConfigHistory 获取交易员的配置快照历史（最新的在前）
+func (d *Database) GetTraderConfigHistory(traderID string) ([]*TraderConfigSnapshot, error) {
+	rows, err := d.db.Query(`
+		SELECT id, trader_id, user_id, snapshot, created_at
+		FROM trader_config_history WHERE trader_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, traderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询配置历史失败: %w", err)
+	}
+	defer rows.Close()
+
+	history := make([]*TraderConfigSnapshot, 0)
+	for rows.Next() {
+		var snapshot TraderConfigSnapshot
+		var raw string
+		if err := rows.Scan(&snapshot.ID, &snapshot.TraderID, &snapshot.UserID, &raw, &snapshot.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取配置历史失败: %w", err)
+		}
+		if err := json.Unmarshal([]byte(raw), &snapshot.Config); err != nil {
+			return nil, fmt.Errorf("解析配置快照失败: %w", err)
+		}
+		history = append(history, &snapshot)
+	}
+	return history, rows.Err()
+}
+
+// traderConfigAt 获取某一时间点生效的配置快照（该时间点及之前最新的一条），不存在时返回 nil
+func (d *Database) traderConfigAt(traderID string, ts time.Time) (map[string]interface{}, error) {
+	var raw string
+	err := d.db.QueryRow(`
+		SELECT snapshot FROM trader_config_history
+		WHERE trader_id = ? AND created_at <= ?
+		ORDER BY created_at DESC, id DESC LIMIT 1
+	`, traderID, ts.UTC().Format(sqliteTimeLayout)).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询配置快照失败: %w", err)
+	}
+
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &snapshot); err != nil {
+		return nil, fmt.Errorf("解析配置快照失败: %w", err)
+	}
+	return snapshot, nil
+}
+
+// DiffTraderConfig 比较两个时间点生效的交易员配置，返回发生变化的字段（按字段名排序）
+func (d *Database) DiffTraderConfig(traderID string, fromTs, toTs time.Time) ([]TraderConfigChange, error) {
+	from, err := d.traderConfigAt(traderID, fromTs)
+	if err != nil {
+		return nil, err
+	}
+	to, err := d.traderConfigAt(traderID, toTs)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]struct{}, len(from)+len(to))
+	for field := range from {
+		fields[field] = struct{}{}
+	}
+	for field := range to {
+		fields[field] = struct{}{}
+	}
+
+	changes := make([]TraderConfigChange, 0)
+	for field := range fields {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes = append(changes, TraderConfigChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
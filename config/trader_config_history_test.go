@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraderConfigHistoryAndDiff(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-history-1")
+	exID := ensureTestExchange(t, db, userID, "binance-history-1")
+
+	tr := &TraderRecord{
+		ID:                   "tr-history",
+		UserID:               userID,
+		Name:                 "history",
+		AIModelID:            aiID,
+		ExchangeID:           exID,
+		InitialBalance:       1000,
+		ScanIntervalMinutes:  5,
+		BTCETHLeverage:       5,
+		AltcoinLeverage:      3,
+		TradingSymbols:       "BTCUSDT",
+		SystemPromptTemplate: "default",
+		Timeframes:           "4h",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	// 将创建时的快照移到过去，模拟两次修改之间的时间间隔
+	created := time.Now().Add(-2 * time.Hour).UTC()
+	if _, err := db.db.Exec(`UPDATE trader_config_history SET created_at = ? WHERE trader_id = ?`, created.Format(sqliteTimeLayout), tr.ID); err != nil {
+		t.Fatalf("backdate snapshot failed: %v", err)
+	}
+
+	tr.BTCETHLeverage = 10
+	tr.TradingSymbols = "BTCUSDT,ETHUSDT"
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+
+	history, err := db.GetTraderConfigHistory(tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfigHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(history))
+	}
+	if history[0].Config["trading_symbols"] != "BTCUSDT,ETHUSDT" {
+		t.Fatalf("expected newest snapshot first, got %v", history[0].Config["trading_symbols"])
+	}
+	if _, ok := history[0].Config["is_running"]; ok {
+		t.Fatal("runtime fields should not be part of the snapshot")
+	}
+
+	changes, err := db.DiffTraderConfig(tr.ID, created.Add(time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("DiffTraderConfig failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changed fields, got %+v", changes)
+	}
+	if changes[0].Field != "btc_eth_leverage" || changes[0].From != float64(5) || changes[0].To != float64(10) {
+		t.Fatalf("unexpected leverage change: %+v", changes[0])
+	}
+	if changes[1].Field != "trading_symbols" || changes[1].From != "BTCUSDT" {
+		t.Fatalf("unexpected symbols change: %+v", changes[1])
+	}
+
+	// 同一时间段内无变化
+	if changes, _ := db.DiffTraderConfig(tr.ID, time.Now().Add(time.Minute), time.Now().Add(time.Hour)); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
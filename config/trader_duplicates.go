@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// traderHistoryTables 合并交易员时需要改为指向保留交易员的历史表
+var traderHistoryTables = []string{
+	"trades", "decisions", "decision_cycles", "webhook_failures",
+	"equity_snapshots", "account_balance_history", "trader_logs",
+}
+
+// FindDuplicateTraders 查找用户名下名称、交易所、AI模型完全相同的交易员
+// 每组按创建时间升序（最早创建的在前），只返回包含两个及以上交易员的分组
+func (d *Database) FindDuplicateTraders(userID string) ([][]*TraderRecord, error) {
+	traders, err := d.queryTraderRecords(`
+		SELECT `+traderSelectColumns+`
+		FROM traders WHERE user_id = ? AND EXISTS (
+			SELECT 1 FROM traders dup
+			WHERE dup.user_id = traders.user_id AND dup.id != traders.id
+				AND dup.name = traders.name AND dup.exchange_id = traders.exchange_id AND dup.ai_model_id = traders.ai_model_id
+		)
+		ORDER BY name, exchange_id, ai_model_id, created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询重复交易员失败: %w", err)
+	}
+
+	groups := make([][]*TraderRecord, 0)
+	for _, trader := range traders {
+		if n := len(groups); n > 0 {
+			first := groups[n-1][0]
+			if first.Name == trader.Name && first.ExchangeID == trader.ExchangeID && first.AIModelID == trader.AIModelID {
+				groups[n-1] = append(groups[n-1], trader)
+				continue
+			}
+		}
+		groups = append(groups, []*TraderRecord{trader})
+	}
+	return groups, nil
+}
+
+// MergeTraders 在事务中将重复交易员合并到 keepID：成交、决策（含决策周期）、webhook 失败、净值快照、余额快照和日志改为指向保留的交易员，
+// 每日盈亏仅补充保留交易员缺失的日期，然后删除重复交易员（其配置快照随之级联删除），并在保留交易员下记录一条审计日志
+// 重复交易员必须属于同一用户且未在运行
+func (d *Database) MergeTraders(userID, keepID string, duplicateIDs []string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var keepCount int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM traders WHERE id = ? AND user_id = ?`, keepID, userID).Scan(&keepCount); err != nil {
+		return fmt.Errorf("查询交易员失败: %w", err)
+	}
+	if keepCount == 0 {
+		return fmt.Errorf("交易员不存在: %s", keepID)
+	}
+
+	var merged []string
+	for _, dupID := range duplicateIDs {
+		dupID = strings.TrimSpace(dupID)
+		if dupID == "" || dupID == keepID {
+			continue
+		}
+
+		var running bool
+		if err := tx.QueryRow(`SELECT is_running FROM traders WHERE id = ? AND user_id = ?`, dupID, userID).Scan(&running); err != nil {
+			return fmt.Errorf("交易员不存在: %s", dupID)
+		}
+		if running {
+			return fmt.Errorf("交易员 %s 正在运行，请先停止后再合并", dupID)
+		}
+
+		for _, table := range traderHistoryTables {
+			if _, err := tx.Exec(`UPDATE `+table+` SET trader_id = ? WHERE trader_id = ?`, keepID, dupID); err != nil {
+				return fmt.Errorf("迁移 %s 记录失败: %w", table, err)
+			}
+		}
+		if _, err := tx.Exec(`UPDATE OR IGNORE trader_daily_pnl SET trader_id = ? WHERE trader_id = ?`, keepID, dupID); err != nil {
+			return fmt.Errorf("迁移每日盈亏记录失败: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, dupID, userID); err != nil {
+			return fmt.Errorf("删除重复交易员失败: %w", err)
+		}
+		merged = append(merged, dupID)
+	}
+
+	if len(merged) > 0 {
+		if err := recordAuditEvent(tx, userID, AuditEntityTrader, keepID, "merge", strings.Join(merged, ",")); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindAndMergeDuplicateTraders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-dup-1")
+	exID := ensureTestExchange(t, db, userID, "binance-dup-1")
+	for _, tc := range []struct {
+		id, name string
+		running  bool
+	}{
+		{"tr-dup-a", "Alpha", true},
+		{"tr-dup-b", "Alpha", false},
+		{"tr-dup-c", "Alpha", false},
+		{"tr-unique", "Beta", false},
+	} {
+		tr := &TraderRecord{
+			ID: tc.id, UserID: userID, Name: tc.name, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 100, ScanIntervalMinutes: 5, IsRunning: tc.running, SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	groups, err := db.FindDuplicateTraders(userID)
+	if err != nil {
+		t.Fatalf("FindDuplicateTraders failed: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 3 {
+		t.Fatalf("expected one group of 3 duplicates, got %d groups", len(groups))
+	}
+
+	trade := &TradeRecord{TraderID: "tr-dup-b", UserID: userID, Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: 5, CreatedAt: time.Now()}
+	if err := db.RecordTrade(trade); err != nil {
+		t.Fatalf("RecordTrade failed: %v", err)
+	}
+	if err := db.RecordDecision(&Decision{TraderID: "tr-dup-c", UserID: userID, Symbol: "BTCUSDT", Action: "hold"}); err != nil {
+		t.Fatalf("RecordDecision failed: %v", err)
+	}
+	for _, id := range []string{"tr-dup-a", "tr-dup-b"} {
+		if _, err := db.db.Exec(`INSERT INTO trader_daily_pnl (trader_id, trading_day, start_equity, current_equity) VALUES (?, '2025-05-01', 100, 100)`, id); err != nil {
+			t.Fatalf("insert daily pnl failed: %v", err)
+		}
+	}
+
+	if _, err := db.db.Exec(`INSERT INTO equity_snapshots (trader_id, equity) VALUES ('tr-dup-b', 1050)`); err != nil {
+		t.Fatalf("record equity snapshot failed: %v", err)
+	}
+	if _, err := db.db.Exec(`INSERT INTO account_balance_history (trader_id, wallet_balance) VALUES ('tr-dup-c', 1000)`); err != nil {
+		t.Fatalf("insert balance history failed: %v", err)
+	}
+	if _, err := db.db.Exec(`INSERT INTO trader_logs (trader_id, level, message) VALUES ('tr-dup-b', 'error', 'boom')`); err != nil {
+		t.Fatalf("insert trader log failed: %v", err)
+	}
+
+	// 运行中的交易员不能被合并掉
+	if err := db.MergeTraders(userID, "tr-dup-b", []string{"tr-dup-a"}); err == nil {
+		t.Fatal("expected error when merging a running trader")
+	}
+	if err := db.MergeTraders("user1", "tr-dup-a", []string{"tr-dup-b"}); err == nil {
+		t.Fatal("expected error when merging traders of another user")
+	}
+
+	if err := db.MergeTraders(userID, "tr-dup-a", []string{"tr-dup-b", "tr-dup-c"}); err != nil {
+		t.Fatalf("MergeTraders failed: %v", err)
+	}
+
+	traders, err := db.GetTraders(userID)
+	if err != nil || len(traders) != 2 {
+		t.Fatalf("expected 2 traders after merge, got %v (%v)", traderIDs(traders), err)
+	}
+	stats, err := db.GetTraderStats(userID, "tr-dup-a", time.Time{})
+	if err != nil || stats.TotalTrades != 1 {
+		t.Fatalf("expected trade to be repointed, got %+v (%v)", stats, err)
+	}
+	latest, err := db.GetLatestDecisions(userID, "tr-dup-a")
+	if err != nil || latest["BTCUSDT"] == nil {
+		t.Fatalf("expected decision to be repointed, got %v (%v)", latest, err)
+	}
+	var dailyRows int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM trader_daily_pnl`).Scan(&dailyRows); err != nil || dailyRows != 1 {
+		t.Fatalf("expected conflicting daily pnl rows to be dropped, got %d (%v)", dailyRows, err)
+	}
+
+	for _, table := range []string{"equity_snapshots", "account_balance_history", "trader_logs"} {
+		var kept int
+		if err := db.db.QueryRow(`SELECT COUNT(*) FROM ` + table + ` WHERE trader_id = 'tr-dup-a'`).Scan(&kept); err != nil || kept != 1 {
+			t.Fatalf("expected %s row to be repointed to the survivor, got %d (%v)", table, kept, err)
+		}
+	}
+	var auditDetail string
+	if err := db.db.QueryRow(`SELECT detail FROM audit_log WHERE entity_type = ? AND entity_id = 'tr-dup-a' AND action = 'merge'`, AuditEntityTrader).Scan(&auditDetail); err != nil || auditDetail != "tr-dup-b,tr-dup-c" {
+		t.Fatalf("expected merge audit entry, got %q (%v)", auditDetail, err)
+	}
+
+	groups, err = db.FindDuplicateTraders(userID)
+	if err != nil || len(groups) != 0 {
+		t.Fatalf("expected no duplicates after merge, got %d (%v)", len(groups), err)
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// sqliteTimeLayout 与 SQLite datetime() 输出一致的时间格式（UTC），便于直接按字符串比较
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// scanIntervalSQL 有效扫描间隔（秒）的 SQL 表达式，与 NormalizeScanInterval 保持一致
+var scanIntervalSQL = fmt.Sprintf(`CASE WHEN COALESCE(scan_interval_seconds, 0) > 0
+			THEN MAX(scan_interval_seconds, %[1]d)
+			ELSE MAX(COALESCE(scan_interval_minutes, 3) * 60, %[1]d) END`, MinScanIntervalSeconds)
+
+// ClaimNextDueTrader 原子地认领下一个到期需要扫描的交易员
+// 条件: 正在运行、租约为空或已过期、距离上次扫描已超过扫描间隔
+// 认领成功后写入 last_scanned_at / leased_until / leased_by，其他实例会跳过该交易员
+// 没有到期的交易员时返回 (nil, nil)；扫描周期结束后应调用 ReleaseTraderLease 释放租约
+func (d *Database) ClaimNextDueTrader(instanceID string, leaseTTL time.Duration) (*TraderRecord, error) {
+	return d.claimNextDueTrader(instanceID, leaseTTL, time.Now())
+}
+
+func (d *Database) claimNextDueTrader(instanceID string, leaseTTL time.Duration, now time.Time) (*TraderRecord, error) {
+	if instanceID == "" {
+		return nil, fmt.Errorf("实例ID不能为空")
+	}
+	if leaseTTL <= 0 {
+		return nil, fmt.Errorf("租约时长必须大于0")
+	}
+
+	nowStr := now.UTC().Format(sqliteTimeLayout)
+	leasedUntil := now.Add(leaseTTL).UTC().Format(sqliteTimeLayout)
+
+	// 单条 UPDATE 语句完成选择与加锁，SQLite 写操作串行执行，保证同一交易员只会被一个实例认领
+	var traderID string
+	err := d.db.QueryRow(`
+		UPDATE traders SET last_scanned_at = ?, leased_until = ?, leased_by = ?
+		WHERE id = (
+			SELECT id FROM traders
+			WHERE is_running = 1
+			  AND (leased_until IS NULL OR leased_until <= ?)
+			  AND (last_scanned_at IS NULL
+			       OR datetime(last_scanned_at, '+' || (`+scanIntervalSQL+`) || ' seconds') <= ?)
+			ORDER BY COALESCE(last_scanned_at, '') ASC, id ASC
+			LIMIT 1
+		)
+		RETURNING id
+	`, nowStr, leasedUntil, instanceID, nowStr, nowStr).Scan(&traderID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("认领交易员失败: %w", err)
+	}
+
+	traders, err := d.queryTraderRecords(`
+		SELECT `+traderSelectColumns+`
+		FROM traders WHERE id = ?
+	`, traderID)
+	if err != nil {
+		return nil, fmt.Errorf("读取已认领交易员失败: %w", err)
+	}
+	if len(traders) == 0 {
+		return nil, fmt.Errorf("已认领的交易员不存在: %s", traderID)
+	}
+	return traders[0], nil
+}
+
+// ReleaseTraderLease 释放扫描租约（仅释放当前实例持有的租约）
+func (d *Database) ReleaseTraderLease(traderID, instanceID string) error {
+	_, err := d.db.Exec(`
+		UPDATE traders SET leased_until = NULL, leased_by = ''
+		WHERE id = ? AND leased_by = ?
+	`, traderID, instanceID)
+	return err
+}
+
+// TouchTraderScan 记录交易员完成一次扫描（更新 last_scanned_at）
+func (d *Database) TouchTraderScan(traderID string) error {
+	_, err := d.db.Exec(`UPDATE traders SET last_scanned_at = ? WHERE id = ?`,
+		time.Now().UTC().Format(sqliteTimeLayout), traderID)
+	return err
+}
+
+// GetStalledTraders 获取疑似卡死的交易员
+// 条件: is_running=1 且距离上次扫描超过「扫描间隔 + threshold」
+// 从未扫描过的交易员以 updated_at（启动时间）为准
+func (d *Database) GetStalledTraders(threshold time.Duration) ([]*TraderRecord, error) {
+	return d.getStalledTraders(threshold, time.Now())
+}
+
+func (d *Database) getStalledTraders(threshold time.Duration, now time.Time) ([]*TraderRecord, error) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	return d.queryTraderRecords(`
+		SELECT `+traderSelectColumns+`
+		FROM traders
+		WHERE is_running = 1
+		  AND datetime(COALESCE(last_scanned_at, updated_at),
+		               '+' || ((`+scanIntervalSQL+`) + ?) || ' seconds') < ?
+		ORDER BY COALESCE(last_scanned_at, updated_at) ASC
+	`, int(threshold/time.Second), now.UTC().Format(sqliteTimeLayout))
+}
+
+// GetDueTraders 获取到期需要扫描的交易员，按逾期时长降序排列（从未扫描过的最优先）
+// 逾期时长 = now - last_scanned_at - 扫描间隔；只返回正在运行且未被其他实例租用的交易员
+// 仅查询不认领，limit <= 0 表示不限制数量
+func (d *Database) GetDueTraders(limit int) ([]*TraderRecord, error) {
+	return d.getDueTraders(limit, time.Now())
+}
+
+func (d *Database) getDueTraders(limit int, now time.Time) ([]*TraderRecord, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite: LIMIT -1 表示不限制
+	}
+	nowStr := now.UTC().Format(sqliteTimeLayout)
+	return d.queryTraderRecords(`
+		SELECT `+traderSelectColumns+`
+		FROM traders
+		WHERE is_running = 1
+		  AND (leased_until IS NULL OR leased_until <= ?)
+		  AND (last_scanned_at IS NULL
+		       OR datetime(last_scanned_at, '+' || (`+scanIntervalSQL+`) || ' seconds') <= ?)
+		ORDER BY last_scanned_at IS NULL DESC,
+		         (julianday(?) - julianday(last_scanned_at)) * 86400 - (`+scanIntervalSQL+`) DESC,
+		         id ASC
+		LIMIT ?
+	`, nowStr, nowStr, nowStr, limit)
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimNextDueTrader(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-lease-1")
+	exID := ensureTestExchange(t, db, userID, "binance-lease-1")
+
+	for _, id := range []string{"tr-lease-a", "tr-lease-b"} {
+		tr := &TraderRecord{
+			ID: id, UserID: userID, Name: id, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 1000, ScanIntervalSeconds: 60, IsRunning: true, SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+	stopped := &TraderRecord{
+		ID: "tr-lease-stopped", UserID: userID, Name: "stopped", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalSeconds: 60, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(stopped); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	now := time.Now()
+	first, err := db.claimNextDueTrader("inst-1", time.Minute, now)
+	if err != nil || first == nil {
+		t.Fatalf("first claim failed: %v (%v)", err, first)
+	}
+	second, err := db.claimNextDueTrader("inst-2", time.Minute, now)
+	if err != nil || second == nil {
+		t.Fatalf("second claim failed: %v (%v)", err, second)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("trader %s claimed twice", first.ID)
+	}
+
+	// 两个运行中的交易员都已被认领，停止的交易员不参与
+	if got, err := db.claimNextDueTrader("inst-3", time.Minute, now); err != nil || got != nil {
+		t.Fatalf("expected no due trader, got %v (%v)", got, err)
+	}
+
+	// 释放租约后，未到扫描间隔仍不可认领
+	if err := db.ReleaseTraderLease(first.ID, "inst-1"); err != nil {
+		t.Fatalf("ReleaseTraderLease failed: %v", err)
+	}
+	if got, _ := db.claimNextDueTrader("inst-3", time.Minute, now.Add(30*time.Second)); got != nil {
+		t.Fatalf("trader %s claimed before interval elapsed", got.ID)
+	}
+
+	// 超过扫描间隔后可以再次认领
+	got, err := db.claimNextDueTrader("inst-3", time.Minute, now.Add(61*time.Second))
+	if err != nil || got == nil || got.ID != first.ID {
+		t.Fatalf("expected %s to be due again, got %v (%v)", first.ID, got, err)
+	}
+
+	// 其他实例无法释放不属于自己的租约；租约过期后则可被接管
+	if err := db.ReleaseTraderLease(second.ID, "inst-1"); err != nil {
+		t.Fatalf("ReleaseTraderLease failed: %v", err)
+	}
+	got, err = db.claimNextDueTrader("inst-4", time.Minute, now.Add(2*time.Minute))
+	if err != nil || got == nil || got.ID != second.ID {
+		t.Fatalf("expected expired lease on %s to be reclaimed, got %v (%v)", second.ID, got, err)
+	}
+}
+
+func TestGetStalledTraders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-stalled-1")
+	exID := ensureTestExchange(t, db, userID, "binance-stalled-1")
+
+	for _, id := range []string{"tr-healthy", "tr-stuck", "tr-stopped"} {
+		tr := &TraderRecord{
+			ID: id, UserID: userID, Name: id, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 1000, ScanIntervalMinutes: 3, IsRunning: id != "tr-stopped", SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	now := time.Now()
+	setScan := func(id string, at time.Time) {
+		if _, err := db.db.Exec(`UPDATE traders SET last_scanned_at = ? WHERE id = ?`, at.UTC().Format(sqliteTimeLayout), id); err != nil {
+			t.Fatalf("set last_scanned_at failed: %v", err)
+		}
+	}
+	setScan("tr-healthy", now.Add(-2*time.Minute))
+	setScan("tr-stuck", now.Add(-10*time.Minute))
+	setScan("tr-stopped", now.Add(-time.Hour))
+
+	stalled, err := db.getStalledTraders(5*time.Minute, now)
+	if err != nil {
+		t.Fatalf("GetStalledTraders failed: %v", err)
+	}
+	if len(stalled) != 1 || stalled[0].ID != "tr-stuck" {
+		t.Fatalf("expected only tr-stuck to be stalled, got %v", traderIDs(stalled))
+	}
+
+	// 阈值足够大时不应报告
+	if stalled, _ := db.getStalledTraders(10*time.Minute, now); len(stalled) != 0 {
+		t.Fatalf("expected no stalled traders with large threshold, got %v", traderIDs(stalled))
+	}
+
+	if err := db.TouchTraderScan("tr-stuck"); err != nil {
+		t.Fatalf("TouchTraderScan failed: %v", err)
+	}
+	if stalled, _ := db.GetStalledTraders(time.Minute); len(stalled) != 0 {
+		t.Fatalf("expected no stalled traders after scan, got %v", traderIDs(stalled))
+	}
+}
+
+func TestGetDueTraders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-due-1")
+	exID := ensureTestExchange(t, db, userID, "binance-due-1")
+
+	now := time.Now().UTC()
+	// 逾期时长: fresh 未到期, slow 逾期 60s, fast 逾期 240s, never 从未扫描, leased 被租用
+	traders := []struct {
+		id          string
+		interval    int
+		lastScanned time.Duration
+	}{
+		{"tr-due-fresh", 600, 5 * time.Minute},
+		{"tr-due-slow", 600, 11 * time.Minute},
+		{"tr-due-fast", 60, 5 * time.Minute},
+		{"tr-due-never", 60, 0},
+		{"tr-due-leased", 60, 10 * time.Minute},
+	}
+	for _, tc := range traders {
+		tr := &TraderRecord{
+			ID: tc.id, UserID: userID, Name: tc.id, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 1000, ScanIntervalSeconds: tc.interval, IsRunning: true, SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+		if tc.lastScanned > 0 {
+			db.db.Exec(`UPDATE traders SET last_scanned_at = ? WHERE id = ?`,
+				now.Add(-tc.lastScanned).Format(sqliteTimeLayout), tc.id)
+		}
+	}
+	db.db.Exec(`UPDATE traders SET leased_until = ?, leased_by = 'inst-1' WHERE id = 'tr-due-leased'`,
+		now.Add(time.Minute).Format(sqliteTimeLayout))
+
+	due, err := db.getDueTraders(0, now)
+	if err != nil {
+		t.Fatalf("GetDueTraders failed: %v", err)
+	}
+	var ids []string
+	for _, tr := range due {
+		ids = append(ids, tr.ID)
+	}
+	want := []string{"tr-due-never", "tr-due-fast", "tr-due-slow"}
+	if len(ids) != len(want) {
+		t.Fatalf("GetDueTraders = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("GetDueTraders = %v, want %v", ids, want)
+		}
+	}
+
+	if limited, _ := db.getDueTraders(1, now); len(limited) != 1 || limited[0].ID != "tr-due-never" {
+		t.Fatalf("limit=1 should return the most overdue trader, got %v", limited)
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// traderLogRetention 每个交易员最多保留的持久化日志条数，超出后删除最旧的记录
+const traderLogRetention = 500
+
+// TraderLog 持久化的交易员日志
+type TraderLog struct {
+	ID        int64     `json:"id"`
+	TraderID  string    `json:"trader_id"`
+	Level     string    `json:"level"` // "info", "warn" or "error"
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordTraderLog 持久化一条交易员日志，并清理超出保留条数的旧日志
+func (d *Database) RecordTraderLog(traderID, level, message string) error {
+	if traderID == "" {
+		return fmt.Errorf("日志缺少交易员ID")
+	}
+	if _, err := d.db.Exec(`
+		INSERT INTO trader_logs (trader_id, level, message, created_at) VALUES (?, ?, ?, ?)
+	`, traderID, level, message, time.Now().UTC().Format(sqliteTimeLayout)); err != nil {
+		return fmt.Errorf("记录交易员日志失败: %w", err)
+	}
+
+	if _, err := d.db.Exec(`
+		DELETE FROM trader_logs
+		WHERE trader_id = ? AND id <= (
+			SELECT id FROM trader_logs WHERE trader_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+		)
+	`, traderID, traderID, traderLogRetention); err != nil {
+		return fmt.Errorf("清理交易员日志失败: %w", err)
+	}
+	return nil
+}
+
+// GetTraderLogs 获取交易员最近 n 条持久化日志（按时间正序：从旧到新）
+func (d *Database) GetTraderLogs(traderID string, n int) ([]TraderLog, error) {
+	if n <= 0 || n > traderLogRetention {
+		n = traderLogRetention
+	}
+
+	rows, err := d.db.Query(`
+		SELECT id, trader_id, level, message, created_at FROM (
+			SELECT id, trader_id, level, message, created_at FROM trader_logs
+			WHERE trader_id = ?
+			ORDER BY id DESC
+			LIMIT ?
+		) ORDER BY id
+	`, traderID, n)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易员日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	logs := make([]TraderLog, 0)
+	for rows.Next() {
+		var l TraderLog
+		if err := rows.Scan(&l.ID, &l.TraderID, &l.Level, &l.Message, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取交易员日志失败: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTraderLogs_RecordAndRetention(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-logs-1")
+	exID := ensureTestExchange(t, db, userID, "binance-logs-1")
+	tr := &TraderRecord{
+		ID: "tr-logs", UserID: userID, Name: "logs", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	if err := db.RecordTraderLog("", "warn", "x"); err == nil {
+		t.Fatal("expected error without trader id")
+	}
+
+	total := traderLogRetention + 5
+	for i := 0; i < total; i++ {
+		if err := db.RecordTraderLog(tr.ID, "warn", fmt.Sprintf("line-%d", i)); err != nil {
+			t.Fatalf("RecordTraderLog failed: %v", err)
+		}
+	}
+
+	var stored int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM trader_logs WHERE trader_id = ?`, tr.ID).Scan(&stored); err != nil {
+		t.Fatalf("count logs: %v", err)
+	}
+	if stored != traderLogRetention {
+		t.Fatalf("expected %d retained logs, got %d", traderLogRetention, stored)
+	}
+
+	logs, err := db.GetTraderLogs(tr.ID, 3)
+	if err != nil {
+		t.Fatalf("GetTraderLogs failed: %v", err)
+	}
+	if len(logs) != 3 || logs[0].Message != fmt.Sprintf("line-%d", total-3) || logs[2].Message != fmt.Sprintf("line-%d", total-1) {
+		t.Fatalf("expected the last 3 logs oldest first, got %+v", logs)
+	}
+
+	other, err := db.GetTraderLogs("missing", 10)
+	if err != nil || len(other) != 0 {
+		t.Fatalf("expected no logs for unknown trader, got %+v (%v)", other, err)
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"nofx/market"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxTraderSymbols 单个交易员最多交易币种数（system_config: max_trader_symbols）
+const defaultMaxTraderSymbols = 30
+
+// ErrTooManySymbols 交易币种数量超过上限
+var ErrTooManySymbols = errors.New("交易币种数量超过上限")
+
+// ErrUnsupportedSymbols 交易币种在所选交易所不可交易
+var ErrUnsupportedSymbols = errors.New("交易币种在所选交易所不可交易")
+
+// SymbolChecker 校验币种是否能在交易所交易（market.SymbolCatalog 实现了该接口）
+type SymbolChecker interface {
+	UnsupportedSymbols(exchange string, testnet bool, symbols []string) ([]string, error)
+}
+
+// CountTraderSymbols 统计 trading_symbols 中的币种数（规范化并去重后）
+func CountTraderSymbols(tradingSymbols string) int {
+	seen := make(map[string]struct{})
+	for _, token := range strings.Split(tradingSymbols, ",") {
+		coin := strings.TrimSpace(token)
+		if coin == "" {
+			continue
+		}
+		seen[market.Normalize(coin)] = struct{}{}
+	}
+	return len(seen)
+}
+
+// maxTraderSymbols 读取单个交易员最多交易币种数
+func (d *Database) maxTraderSymbols() int {
+	if val, err := d.GetSystemConfig("max_trader_symbols"); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(val)); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxTraderSymbols
+}
+
+// validateTraderSymbols 校验交易币种数量，避免单个交易员每个周期发起过多 AI/交易所请求
+func (d *Database) validateTraderSymbols(tradingSymbols string) error {
+	limit := d.maxTraderSymbols()
+	if count := CountTraderSymbols(tradingSymbols); count > limit {
+		return fmt.Errorf("%w: 当前 %d 个，最多 %d 个", ErrTooManySymbols, count, limit)
+	}
+	return nil
+}
+
+// SetSymbolChecker 设置交易所币种校验器，nil 表示保存交易员时不校验币种是否可交易
+func (d *Database) SetSymbolChecker(checker SymbolChecker) {
+	d.symbolChecker = checker
+}
+
+// validateTraderExchangeSymbols 校验 trading_symbols 中的币种在交易员引用的交易所可交易
+// 在保存时发现配置错误，而不是让交易员每个周期都静默失败；无法获取币种列表时只记录警告并放行
+func (d *Database) validateTraderExchangeSymbols(userID string, exchangeID int, tradingSymbols string) error {
+	if strings.TrimSpace(tradingSymbols) == "" {
+		return nil
+	}
+
+	exchanges, err := d.GetExchangesMetadata(userID)
+	if err != nil {
+		return fmt.Errorf("查询交易所配置失败: %w", err)
+	}
+	var exchange *ExchangeConfig
+	for _, ex := range exchanges {
+		if ex.ID == exchangeID {
+			exchange = ex
+			break
+		}
+	}
+	if exchange == nil {
+		return nil
+	}
+
+	var symbols []string
+	for _, token := range strings.Split(tradingSymbols, ",") {
+		if coin := strings.TrimSpace(token); coin != "" {
+			symbols = append(symbols, coin)
+		}
+	}
+	var unsupported []string
+	checked := false
+	if d.symbolChecker != nil {
+		unsupported, err = d.symbolChecker.UnsupportedSymbols(exchange.ExchangeID, exchange.Testnet, symbols)
+		if err != nil {
+			log.Printf("⚠️ 无法校验交易币种是否可交易（%s）: %v", exchange.ExchangeID, err)
+		} else {
+			checked = true
+		}
+	}
+	// 实时校验不可用时，Binance 主网退回到 symbols 表（RefreshSymbolUniverse 每日刷新）
+	if !checked && exchange.ExchangeID == symbolUniverseExchange && !exchange.Testnet {
+		unsupported, checked = d.unsupportedByUniverse(symbols)
+	}
+	if !checked {
+		return nil
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%w: %s 不支持 %s", ErrUnsupportedSymbols, exchange.ExchangeID, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetTradersFiltered(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-filter-1")
+	exID := ensureTestExchange(t, db, userID, "binance-filter-1")
+
+	for _, tc := range []struct {
+		id, name string
+		balance  float64
+		running  bool
+	}{
+		{"tr-a", "Alpha BTC", 300, true},
+		{"tr-b", "beta_eth", 100, false},
+		{"tr-c", "Gamma BTC", 200, true},
+	} {
+		tr := &TraderRecord{
+			ID:                   tc.id,
+			UserID:               userID,
+			Name:                 tc.name,
+			AIModelID:            aiID,
+			ExchangeID:           exID,
+			InitialBalance:       tc.balance,
+			ScanIntervalMinutes:  5,
+			IsRunning:            tc.running,
+			SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	running := true
+	traders, err := db.GetTradersFiltered(userID, TraderQueryOptions{IsRunning: &running, SortBy: "name"})
+	if err != nil {
+		t.Fatalf("GetTradersFiltered failed: %v", err)
+	}
+	if len(traders) != 2 || traders[0].ID != "tr-a" || traders[1].ID != "tr-c" {
+		t.Fatalf("unexpected running traders: %v", traderIDs(traders))
+	}
+
+	traders, err = db.GetTradersFiltered(userID, TraderQueryOptions{NameContains: "btc", SortBy: "initial_balance", SortDesc: true})
+	if err != nil {
+		t.Fatalf("GetTradersFiltered failed: %v", err)
+	}
+	if len(traders) != 2 || traders[0].ID != "tr-a" || traders[1].ID != "tr-c" {
+		t.Fatalf("unexpected name filter result: %v", traderIDs(traders))
+	}
+
+	// 下划线按字面匹配，不作为通配符
+	traders, err = db.GetTradersFiltered(userID, TraderQueryOptions{NameContains: "a_e"})
+	if err != nil {
+		t.Fatalf("GetTradersFiltered failed: %v", err)
+	}
+	if len(traders) != 1 || traders[0].ID != "tr-b" {
+		t.Fatalf("unexpected literal match result: %v", traderIDs(traders))
+	}
+
+	if _, err := db.GetTradersFiltered(userID, TraderQueryOptions{SortBy: "name; DROP TABLE traders"}); !errors.Is(err, ErrInvalidTraderSort) {
+		t.Fatalf("expected ErrInvalidTraderSort for non-whitelisted sort field, got %v", err)
+	}
+}
+
+func TestGetAllRunningTraders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		id, userID string
+		running    bool
+	}{
+		{"tr-run-1", "test-user-002", true},
+		{"tr-run-2", "test-user-001", true},
+		{"tr-idle", "test-user-001", false},
+	} {
+		aiID := ensureTestAIModel(t, db, tc.userID, "model-running-"+tc.id)
+		exID := ensureTestExchange(t, db, tc.userID, "binance-running-"+tc.id)
+		tr := &TraderRecord{
+			ID: tc.id, UserID: tc.userID, Name: tc.id, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 100, ScanIntervalMinutes: 5, IsRunning: tc.running, SystemPromptTemplate: "default",
+		}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+
+	traders, err := db.GetAllRunningTraders()
+	if err != nil {
+		t.Fatalf("GetAllRunningTraders failed: %v", err)
+	}
+	if len(traders) != 2 || traders[0].ID != "tr-run-2" || traders[1].ID != "tr-run-1" {
+		t.Fatalf("unexpected running traders: %v", traderIDs(traders))
+	}
+	if traders[0].UserID != "test-user-001" || traders[1].UserID != "test-user-002" {
+		t.Fatalf("expected user_id on each trader, got %s / %s", traders[0].UserID, traders[1].UserID)
+	}
+}
+
+func traderIDs(traders []*TraderRecord) []string {
+	ids := make([]string, 0, len(traders))
+	for _, tr := range traders {
+		ids = append(ids, tr.ID)
+	}
+	return ids
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// TradeRecord 成交记录
+type TradeRecord struct {
+	ID            int64     `json:"id"`
+	TraderID      string    `json:"trader_id"`
+	UserID        string    `json:"user_id"`
+	Symbol        string    `json:"symbol"`
+	Side          string    `json:"side"`         // "long" or "short"
+	Action        string    `json:"action"`       // "open" or "close"
+	Quantity      float64   `json:"quantity"`     // 成交数量
+	Price         float64   `json:"price"`        // 成交价格
+	RealizedPnL   float64   `json:"realized_pnl"` // 已实现盈亏（仅平仓，未扣手续费）
+	Fee           float64   `json:"fee"`          // 手续费
+	OrderID       string    `json:"order_id"`
+	ClientOrderID string    `json:"client_order_id,omitempty"` // 幂等下单使用的 clientOrderId
+	Liquidity     string    `json:"liquidity,omitempty"`       // "maker" or "taker"（为空按 taker）
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TraderStats 交易员成交统计
+type TraderStats struct {
+	TotalTrades  int     `json:"total_trades"`  // 平仓笔数
+	Wins         int     `json:"wins"`          // 盈利笔数
+	Losses       int     `json:"losses"`        // 亏损笔数
+	WinRate      float64 `json:"win_rate"`      // 胜率（百分比）
+	AvgWin       float64 `json:"avg_win"`       // 平均盈利
+	AvgLoss      float64 `json:"avg_loss"`      // 平均亏损（负数）
+	ProfitFactor float64 `json:"profit_factor"` // 盈亏比：总盈利 / 总亏损绝对值（无亏损时为0）
+	Expectancy   float64 `json:"expectancy"`    // 每笔平仓期望收益
+	TotalFees    float64 `json:"total_fees"`    // 手续费合计（含开仓）
+	RealizedPnL  float64 `json:"realized_pnl"`  // 已实现盈亏合计（未扣手续费）
+	NetPnL       float64 `json:"net_pnl"`       // 扣除手续费后的净盈亏
+}
+
+// DailyPnL 按 UTC 自然日汇总的盈亏（用于图表）
+type DailyPnL struct {
+	Day         string  `json:"day"`          // 交易员时区（未配置时为 UTC）的日期，格式 2006-01-02
+	Trades      int     `json:"trades"`       // 当日平仓笔数
+	RealizedPnL float64 `json:"realized_pnl"` // 当日已实现盈亏（未扣手续费）
+	Fees        float64 `json:"fees"`         // 当日手续费合计（含开仓）
+	NetPnL      float64 `json:"net_pnl"`      // 扣除手续费后的净盈亏
+}
+
+// FeeBucket 某一成交方式下的成交笔数、成交额和手续费
+type FeeBucket struct {
+	Count  int     `json:"count"`  // 成交笔数
+	Volume float64 `json:"volume"` // 成交额（数量 × 价格）
+	Fees   float64 `json:"fees"`   // 手续费合计
+}
+
+// FeeBreakdown 交易员 Maker/Taker 手续费拆分，用于评估限价单策略节省的手续费
+type FeeBreakdown struct {
+	Taker      FeeBucket `json:"taker"`
+	Maker      FeeBucket `json:"maker"`
+	TotalFees  float64   `json:"total_fees"`  // 手续费合计
+	MakerRatio float64   `json:"maker_ratio"` // Maker 成交额占比（百分比）
+}
+
+// RecordTrade 记录一笔成交
+func (d *Database) RecordTrade(trade *TradeRecord) error {
+	if trade.TraderID == "" || trade.Symbol == "" {
+		return fmt.Errorf("成交记录缺少交易员或币种")
+	}
+	if trade.CreatedAt.IsZero() {
+		trade.CreatedAt = time.Now()
+	}
+	if trade.UserID == "" {
+		trade.UserID = "default"
+	}
+
+	result, err := d.db.Exec(`
+		INSERT INTO trades (trader_id, user_id, symbol, side, action, quantity, price, realized_pnl, fee, order_id, client_order_id, liquidity, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, trade.TraderID, trade.UserID, trade.Symbol, trade.Side, trade.Action, trade.Quantity, trade.Price,
+		trade.RealizedPnL, trade.Fee, trade.OrderID, trade.ClientOrderID, trade.Liquidity, trade.CreatedAt.UTC().Format(sqliteTimeLayout))
+	if err != nil {
+		return fmt.Errorf("记录成交失败: %w", err)
+	}
+	trade.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetTraderStats 统计交易员自 since 起的胜率、平均盈亏、盈亏比、手续费和已实现盈亏
+// since 为零值时统计全部成交记录
+func (d *Database) GetTraderStats(userID, traderID string, since time.Time) (*TraderStats, error) {
+	sinceStr := ""
+	if !since.IsZero() {
+		sinceStr = since.UTC().Format(sqliteTimeLayout)
+	}
+
+	var stats TraderStats
+	var grossWin, grossLoss float64
+	err := d.db.QueryRow(`
+		SELECT
+			COUNT(CASE WHEN action = 'close' THEN 1 END),
+			COUNT(CASE WHEN action = 'close' AND realized_pnl > 0 THEN 1 END),
+			COUNT(CASE WHEN action = 'close' AND realized_pnl < 0 THEN 1 END),
+			COALESCE(SUM(CASE WHEN action = 'close' AND realized_pnl > 0 THEN realized_pnl END), 0),
+			COALESCE(SUM(CASE WHEN action = 'close' AND realized_pnl < 0 THEN realized_pnl END), 0),
+			COALESCE(SUM(fee), 0),
+			COALESCE(SUM(CASE WHEN action = 'close' THEN realized_pnl END), 0)
+		FROM trades
+		WHERE trader_id = ? AND user_id = ? AND (? = '' OR created_at >= ?)
+	`, traderID, userID, sinceStr, sinceStr).Scan(
+		&stats.TotalTrades, &stats.Wins, &stats.Losses,
+		&grossWin, &grossLoss, &stats.TotalFees, &stats.RealizedPnL,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("统计成交记录失败: %w", err)
+	}
+
+	if stats.TotalTrades > 0 {
+		stats.WinRate = float64(stats.Wins) / float64(stats.TotalTrades) * 100
+		stats.Expectancy = stats.RealizedPnL / float64(stats.TotalTrades)
+	}
+	if stats.Wins > 0 {
+		stats.AvgWin = grossWin / float64(stats.Wins)
+	}
+	if stats.Losses > 0 {
+		stats.AvgLoss = grossLoss / float64(stats.Losses)
+	}
+	if grossLoss < 0 {
+		stats.ProfitFactor = grossWin / -grossLoss
+	}
+	stats.NetPnL = stats.RealizedPnL - stats.TotalFees
+	return &stats, nil
+}
+
+// GetLossStreak 统计交易员最近的连续亏损平仓笔数（遇到盈利或持平的平仓即停止），
+// 返回连续亏损笔数和最后一笔亏损的时间；since 非零值时只统计该时间之后的平仓
+func (d *Database) GetLossStreak(traderID string, since time.Time) (int, time.Time, error) {
+	sinceStr := ""
+	if !since.IsZero() {
+		sinceStr = since.UTC().Format(sqliteTimeLayout)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT realized_pnl, created_at FROM trades
+		WHERE trader_id = ? AND action = 'close' AND (? = '' OR created_at > ?)
+		ORDER BY created_at DESC, id DESC
+	`, traderID, sinceStr, sinceStr)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("查询成交记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	streak := 0
+	var lastLossAt time.Time
+	for rows.Next() {
+		var pnl float64
+		var createdAt time.Time
+		if err := rows.Scan(&pnl, &createdAt); err != nil {
+			return 0, time.Time{}, fmt.Errorf("读取成交记录失败: %w", err)
+		}
+		if pnl >= 0 {
+			break
+		}
+		if streak == 0 {
+			lastLossAt = createdAt
+		}
+		streak++
+	}
+	return streak, lastLossAt, rows.Err()
+}
+
+// GetDailyPnL 按交易员时区（未配置时为 UTC）的自然日汇总交易员自 since 起的已实现盈亏和手续费（按日期升序）
+// 只返回有成交的日期，没有成交的日期不补零，由调用方按需填充；since 为零值时统计全部成交记录
+func (d *Database) GetDailyPnL(userID, traderID string, since time.Time) ([]DailyPnL, error) {
+	sinceStr := ""
+	if !since.IsZero() {
+		sinceStr = since.UTC().Format(sqliteTimeLayout)
+	}
+	loc := d.getTraderLocation(traderID)
+
+	rows, err := d.db.Query(`
+		SELECT created_at, action, COALESCE(realized_pnl, 0), COALESCE(fee, 0)
+		FROM trades
+		WHERE trader_id = ? AND user_id = ? AND (? = '' OR created_at >= ?)
+		ORDER BY created_at
+	`, traderID, userID, sinceStr, sinceStr)
+	if err != nil {
+		return nil, fmt.Errorf("按日统计成交记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	days := make([]DailyPnL, 0)
+	index := make(map[string]int)
+	for rows.Next() {
+		var (
+			createdAt   time.Time
+			action      string
+			realizedPnL float64
+			fee         float64
+		)
+		if err := rows.Scan(&createdAt, &action, &realizedPnL, &fee); err != nil {
+			return nil, fmt.Errorf("读取按日统计失败: %w", err)
+		}
+		key := createdAt.In(loc).Format("2006-01-02")
+		i, ok := index[key]
+		if !ok {
+			i = len(days)
+			index[key] = i
+			days = append(days, DailyPnL{Day: key})
+		}
+		if action == "close" {
+			days[i].Trades++
+			days[i].RealizedPnL += realizedPnL
+		}
+		days[i].Fees += fee
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range days {
+		days[i].NetPnL = days[i].RealizedPnL - days[i].Fees
+	}
+	return days, nil
+}
+
+// GetFeeBreakdown 按 Maker/Taker 拆分交易员自 since 起的成交笔数、成交额和手续费
+// 未记录成交方式的旧成交按 Taker 统计；since 为零值时统计全部成交记录
+func (d *Database) GetFeeBreakdown(userID, traderID string, since time.Time) (*FeeBreakdown, error) {
+	sinceStr := ""
+	if !since.IsZero() {
+		sinceStr = since.UTC().Format(sqliteTimeLayout)
+	}
+
+	var fb FeeBreakdown
+	err := d.db.QueryRow(`
+		SELECT
+			COUNT(CASE WHEN liquidity = 'maker' THEN 1 END),
+			COALESCE(SUM(CASE WHEN liquidity = 'maker' THEN quantity * price END), 0),
+			COALESCE(SUM(CASE WHEN liquidity = 'maker' THEN fee END), 0),
+			COUNT(CASE WHEN COALESCE(liquidity, '') <> 'maker' THEN 1 END),
+			COALESCE(SUM(CASE WHEN COALESCE(liquidity, '') <> 'maker' THEN quantity * price END), 0),
+			COALESCE(SUM(CASE WHEN COALESCE(liquidity, '') <> 'maker' THEN fee END), 0)
+		FROM trades
+		WHERE trader_id = ? AND user_id = ? AND (? = '' OR created_at >= ?)
+	`, traderID, userID, sinceStr, sinceStr).Scan(
+		&fb.Maker.Count, &fb.Maker.Volume, &fb.Maker.Fees,
+		&fb.Taker.Count, &fb.Taker.Volume, &fb.Taker.Fees,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("统计手续费失败: %w", err)
+	}
+
+	fb.TotalFees = fb.Maker.Fees + fb.Taker.Fees
+	if total := fb.Maker.Volume + fb.Taker.Volume; total > 0 {
+		fb.MakerRatio = fb.Maker.Volume / total * 100
+	}
+	return &fb, nil
+}
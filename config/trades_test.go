@@ -0,0 +1,256 @@
+package config
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetTraderStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-stats-1")
+	exID := ensureTestExchange(t, db, userID, "binance-stats-1")
+	tr := &TraderRecord{
+		ID: "tr-stats", UserID: userID, Name: "stats", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	trades := []TradeRecord{
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Fee: 1, CreatedAt: base},
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: 30, Fee: 1, CreatedAt: base.Add(time.Hour)},
+		{Symbol: "ETHUSDT", Side: "short", Action: "close", RealizedPnL: -10, Fee: 1, CreatedAt: base.Add(2 * time.Hour)},
+		{Symbol: "SOLUSDT", Side: "long", Action: "close", RealizedPnL: 10, Fee: 1, CreatedAt: base.Add(3 * time.Hour)},
+		// 统计窗口之前的成交
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: -100, Fee: 5, CreatedAt: base.Add(-24 * time.Hour)},
+	}
+	for i := range trades {
+		trades[i].TraderID = tr.ID
+		trades[i].UserID = userID
+		if err := db.RecordTrade(&trades[i]); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+
+	stats, err := db.GetTraderStats(userID, tr.ID, base)
+	if err != nil {
+		t.Fatalf("GetTraderStats failed: %v", err)
+	}
+	approx := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if stats.TotalTrades != 3 || stats.Wins != 2 || stats.Losses != 1 {
+		t.Fatalf("unexpected counts: %+v", stats)
+	}
+	if !approx(stats.WinRate, 200.0/3) || !approx(stats.AvgWin, 20) || !approx(stats.AvgLoss, -10) {
+		t.Fatalf("unexpected averages: %+v", stats)
+	}
+	if !approx(stats.ProfitFactor, 4) || !approx(stats.RealizedPnL, 30) || !approx(stats.TotalFees, 4) || !approx(stats.NetPnL, 26) {
+		t.Fatalf("unexpected totals: %+v", stats)
+	}
+
+	all, err := db.GetTraderStats(userID, tr.ID, time.Time{})
+	if err != nil || all.TotalTrades != 4 {
+		t.Fatalf("expected 4 closed trades without since filter, got %+v (%v)", all, err)
+	}
+
+	// 其他用户无法读取该交易员的统计
+	other, err := db.GetTraderStats("user1", tr.ID, time.Time{})
+	if err != nil || other.TotalTrades != 0 {
+		t.Fatalf("expected no trades for other user, got %+v (%v)", other, err)
+	}
+}
+
+func TestGetLossStreak(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-streak-1")
+	exID := ensureTestExchange(t, db, userID, "binance-streak-1")
+	tr := &TraderRecord{
+		ID: "tr-streak", UserID: userID, Name: "streak", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+		LossStreakThreshold: 2, CooldownMinutes: 45,
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	trades := []TradeRecord{
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: -5, CreatedAt: base},
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: 20, CreatedAt: base.Add(time.Hour)},
+		{Symbol: "ETHUSDT", Side: "short", Action: "close", RealizedPnL: -10, CreatedAt: base.Add(2 * time.Hour)},
+		{Symbol: "SOLUSDT", Side: "long", Action: "open", CreatedAt: base.Add(3 * time.Hour)},
+		{Symbol: "SOLUSDT", Side: "long", Action: "close", RealizedPnL: -3, CreatedAt: base.Add(4 * time.Hour)},
+	}
+	for i := range trades {
+		trades[i].TraderID = tr.ID
+		trades[i].UserID = userID
+		if err := db.RecordTrade(&trades[i]); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+
+	streak, lastLossAt, err := db.GetLossStreak(tr.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("GetLossStreak failed: %v", err)
+	}
+	if streak != 2 || !lastLossAt.Equal(base.Add(4*time.Hour)) {
+		t.Fatalf("expected streak 2 ending at %v, got %d at %v", base.Add(4*time.Hour), streak, lastLossAt)
+	}
+
+	streak, _, err = db.GetLossStreak(tr.ID, base.Add(3*time.Hour))
+	if err != nil || streak != 1 {
+		t.Fatalf("expected streak 1 after since, got %d (%v)", streak, err)
+	}
+
+	// 盈利平仓重置连续亏损
+	win := TradeRecord{TraderID: tr.ID, UserID: userID, Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: 1, CreatedAt: base.Add(5 * time.Hour)}
+	if err := db.RecordTrade(&win); err != nil {
+		t.Fatalf("RecordTrade failed: %v", err)
+	}
+	if streak, _, _ = db.GetLossStreak(tr.ID, time.Time{}); streak != 0 {
+		t.Fatalf("expected streak reset after win, got %d", streak)
+	}
+
+	saved, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if saved.LossStreakThreshold != 2 || saved.CooldownMinutes != 45 {
+		t.Fatalf("cooldown config not persisted: %+v", saved)
+	}
+}
+
+func TestGetDailyPnL(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-daily-1")
+	exID := ensureTestExchange(t, db, userID, "binance-daily-1")
+	tr := &TraderRecord{
+		ID: "tr-daily", UserID: userID, Name: "daily", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	trades := []TradeRecord{
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Fee: 1, CreatedAt: base.Add(time.Hour)},
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: 30, Fee: 1, CreatedAt: base.Add(23 * time.Hour)},
+		// 5 月 2 日无成交，5 月 3 日一笔亏损
+		{Symbol: "ETHUSDT", Side: "short", Action: "close", RealizedPnL: -10, Fee: 2, CreatedAt: base.Add(50 * time.Hour)},
+		// 统计窗口之前的成交
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", RealizedPnL: -100, Fee: 5, CreatedAt: base.Add(-time.Hour)},
+	}
+	for i := range trades {
+		trades[i].TraderID = tr.ID
+		trades[i].UserID = userID
+		if err := db.RecordTrade(&trades[i]); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+
+	days, err := db.GetDailyPnL(userID, tr.ID, base)
+	if err != nil {
+		t.Fatalf("GetDailyPnL failed: %v", err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("expected 2 days (gaps not filled), got %+v", days)
+	}
+	if days[0].Day != "2025-05-01" || days[0].Trades != 1 || days[0].RealizedPnL != 30 || days[0].Fees != 2 || days[0].NetPnL != 28 {
+		t.Fatalf("unexpected first day: %+v", days[0])
+	}
+	if days[1].Day != "2025-05-03" || days[1].Trades != 1 || days[1].RealizedPnL != -10 || days[1].NetPnL != -12 {
+		t.Fatalf("unexpected second day: %+v", days[1])
+	}
+
+	all, err := db.GetDailyPnL(userID, tr.ID, time.Time{})
+	if err != nil || len(all) != 3 || all[0].Day != "2025-04-30" {
+		t.Fatalf("expected 3 days without since filter, got %+v (%v)", all, err)
+	}
+
+	other, err := db.GetDailyPnL("user1", tr.ID, time.Time{})
+	if err != nil || len(other) != 0 {
+		t.Fatalf("expected no days for other user, got %+v (%v)", other, err)
+	}
+
+	// 按交易员时区切分自然日：UTC 23:00 的平仓属于上海时间次日
+	tr.Timezone = "Asia/Shanghai"
+	if err := db.UpdateTrader(tr); err != nil {
+		t.Fatalf("UpdateTrader failed: %v", err)
+	}
+	local, err := db.GetDailyPnL(userID, tr.ID, base)
+	if err != nil || len(local) != 3 {
+		t.Fatalf("expected 3 local days, got %+v (%v)", local, err)
+	}
+	if local[0].Day != "2025-05-01" || local[0].Fees != 1 || local[1].Day != "2025-05-02" || local[1].RealizedPnL != 30 || local[2].Day != "2025-05-03" {
+		t.Fatalf("unexpected local days: %+v", local)
+	}
+}
+
+func TestGetFeeBreakdown(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-fee-1")
+	exID := ensureTestExchange(t, db, userID, "binance-fee-1")
+	tr := &TraderRecord{
+		ID: "tr-fee", UserID: userID, Name: "fee", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	trades := []TradeRecord{
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 1, Price: 300, Fee: 0.06, Liquidity: "maker", CreatedAt: base.Add(time.Hour)},
+		{Symbol: "BTCUSDT", Side: "long", Action: "close", Quantity: 1, Price: 400, Fee: 0.16, Liquidity: "taker", CreatedAt: base.Add(2 * time.Hour)},
+		// 未记录成交方式的旧记录按 taker 统计
+		{Symbol: "ETHUSDT", Side: "short", Action: "open", Quantity: 2, Price: 150, Fee: 0.12, CreatedAt: base.Add(3 * time.Hour)},
+		// 统计窗口之前的成交
+		{Symbol: "BTCUSDT", Side: "long", Action: "open", Quantity: 1, Price: 1000, Fee: 0.2, Liquidity: "maker", CreatedAt: base.Add(-time.Hour)},
+	}
+	for i := range trades {
+		trades[i].TraderID = tr.ID
+		trades[i].UserID = userID
+		if err := db.RecordTrade(&trades[i]); err != nil {
+			t.Fatalf("RecordTrade failed: %v", err)
+		}
+	}
+
+	fb, err := db.GetFeeBreakdown(userID, tr.ID, base)
+	if err != nil {
+		t.Fatalf("GetFeeBreakdown failed: %v", err)
+	}
+	if fb.Maker.Count != 1 || fb.Maker.Volume != 300 || math.Abs(fb.Maker.Fees-0.06) > 1e-9 {
+		t.Fatalf("unexpected maker bucket: %+v", fb.Maker)
+	}
+	if fb.Taker.Count != 2 || fb.Taker.Volume != 700 || math.Abs(fb.Taker.Fees-0.28) > 1e-9 {
+		t.Fatalf("unexpected taker bucket: %+v", fb.Taker)
+	}
+	if math.Abs(fb.TotalFees-0.34) > 1e-9 || fb.MakerRatio != 30 {
+		t.Fatalf("unexpected totals: %+v", fb)
+	}
+
+	all, err := db.GetFeeBreakdown(userID, tr.ID, time.Time{})
+	if err != nil || all.Maker.Count != 2 || all.Taker.Count != 2 {
+		t.Fatalf("expected all trades without since filter, got %+v (%v)", all, err)
+	}
+
+	other, err := db.GetFeeBreakdown("user1", tr.ID, time.Time{})
+	if err != nil || other.Maker.Count != 0 || other.Taker.Count != 0 || other.MakerRatio != 0 {
+		t.Fatalf("expected empty breakdown for other user, got %+v (%v)", other, err)
+	}
+}
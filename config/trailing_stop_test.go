@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTraderTrailingStop(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	aiID := ensureTestAIModel(t, db, userID, "model-trailing")
+	exID := ensureTestExchange(t, db, userID, "binance-trailing")
+	tr := &TraderRecord{
+		ID: "tr-trailing", UserID: userID, Name: "trailing", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 100, ScanIntervalMinutes: 5, SystemPromptTemplate: "default",
+	}
+
+	for _, c := range []struct{ stop, activation float64 }{{-1, 0}, {100, 0}, {2, 150}, {0, 3}} {
+		tr.TrailingStopPercent, tr.TrailingActivation = c.stop, c.activation
+		if err := db.CreateTrader(tr); !errors.Is(err, ErrInvalidTrailingStop) {
+			t.Fatalf("stop=%v activation=%v: expected ErrInvalidTrailingStop, got %v", c.stop, c.activation, err)
+		}
+	}
+
+	tr.TrailingStopPercent, tr.TrailingActivation = 1.5, 3
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	got, _, _, err := db.GetTraderConfig(userID, tr.ID)
+	if err != nil {
+		t.Fatalf("GetTraderConfig failed: %v", err)
+	}
+	if got.TrailingStopPercent != 1.5 || got.TrailingActivation != 3 {
+		t.Fatalf("unexpected trailing config: %v / %v", got.TrailingStopPercent, got.TrailingActivation)
+	}
+
+	tr.TrailingActivation = -1
+	if err := db.UpdateTrader(tr); !errors.Is(err, ErrInvalidTrailingStop) {
+		t.Fatalf("expected ErrInvalidTrailingStop on update, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestDeleteUser(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-009"
+	aiID := ensureTestAIModel(t, db, userID, "model-delete-1")
+	exID := ensureTestExchange(t, db, userID, "binance-delete-1")
+	tr := &TraderRecord{
+		ID: "tr-delete", UserID: userID, Name: "delete", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, IsRunning: true, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+	if err := db.RecordTrade(&TradeRecord{TraderID: "tr-delete", UserID: userID, Symbol: "BTCUSDT", Side: "long", Action: "open"}); err != nil {
+		t.Fatalf("RecordTrade failed: %v", err)
+	}
+	if err := db.SetSystemConfigBy("delete_test_key", "1", userID); err != nil {
+		t.Fatalf("SetSystemConfigBy failed: %v", err)
+	}
+	if _, err := db.db.Exec(`INSERT INTO beta_codes (code) VALUES ('DELETE01')`); err != nil {
+		t.Fatalf("insert beta code failed: %v", err)
+	}
+	if err := db.UseBetaCode("DELETE01", userID+"@test.com"); err != nil {
+		t.Fatalf("UseBetaCode failed: %v", err)
+	}
+
+	if err := db.DeleteUser(userID); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	if _, err := db.GetUserByID(userID); err == nil {
+		t.Fatal("expected user to be deleted")
+	}
+	for table, query := range map[string]string{
+		"ai_models": `SELECT COUNT(*) FROM ai_models WHERE user_id = ?`,
+		"exchanges": `SELECT COUNT(*) FROM exchanges WHERE user_id = ?`,
+		"traders":   `SELECT COUNT(*) FROM traders WHERE user_id = ?`,
+		"trades":    `SELECT COUNT(*) FROM trades WHERE user_id = ?`,
+	} {
+		var count int
+		if err := db.db.QueryRow(query, userID).Scan(&count); err != nil {
+			t.Fatalf("count %s failed: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("expected %s rows to be deleted, got %d", table, count)
+		}
+	}
+
+	// 审计记录保留但匿名化
+	history, err := db.GetSystemConfigHistory("delete_test_key")
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected history to be kept, got %v (%v)", history, err)
+	}
+	if history[0].ChangedBy != deletedUserPlaceholder {
+		t.Errorf("expected changed_by to be anonymized, got %q", history[0].ChangedBy)
+	}
+	var usedBy string
+	db.db.QueryRow(`SELECT used_by FROM beta_codes WHERE code = 'DELETE01'`).Scan(&usedBy)
+	if usedBy != deletedUserPlaceholder {
+		t.Errorf("expected beta code used_by to be anonymized, got %q", usedBy)
+	}
+
+	if err := db.DeleteUser(userID); err == nil {
+		t.Fatal("expected error when deleting a missing user")
+	}
+	if err := db.DeleteUser("default"); err == nil {
+		t.Fatal("expected error when deleting the default user")
+	}
+}
+
+func TestSetUserDisabled(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-008"
+	aiID := ensureTestAIModel(t, db, userID, "model-disable-1")
+	exID := ensureTestExchange(t, db, userID, "binance-disable-1")
+	tr := &TraderRecord{
+		ID: "tr-disable", UserID: userID, Name: "disable", AIModelID: aiID, ExchangeID: exID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, IsRunning: true, SystemPromptTemplate: "default",
+	}
+	if err := db.CreateTrader(tr); err != nil {
+		t.Fatalf("CreateTrader failed: %v", err)
+	}
+
+	if err := db.SetUserDisabled(userID, true); err != nil {
+		t.Fatalf("SetUserDisabled failed: %v", err)
+	}
+	user, err := db.GetUserByID(userID)
+	if err != nil || !user.Disabled {
+		t.Fatalf("expected user to be disabled, got %+v (%v)", user, err)
+	}
+	if disabled, err := db.IsUserDisabled(userID); err != nil || !disabled {
+		t.Fatalf("IsUserDisabled = %v (%v), want true", disabled, err)
+	}
+
+	// 交易员被停止但数据保留
+	traders, err := db.GetTraders(userID)
+	if err != nil || len(traders) != 1 {
+		t.Fatalf("expected trader to be kept, got %d (%v)", len(traders), err)
+	}
+	if traders[0].IsRunning || traders[0].StopReason != StopReasonUserDisabled {
+		t.Fatalf("expected trader stopped with reason %q, got running=%v reason=%q", StopReasonUserDisabled, traders[0].IsRunning, traders[0].StopReason)
+	}
+
+	// 即使交易员被重新标记为运行，调度也不会拉起已禁用用户的交易员
+	if err := db.UpdateTraderStatus(userID, "tr-disable", true, ""); err != nil {
+		t.Fatalf("UpdateTraderStatus failed: %v", err)
+	}
+	running, err := db.GetAllRunningTraders()
+	if err != nil {
+		t.Fatalf("GetAllRunningTraders failed: %v", err)
+	}
+	for _, r := range running {
+		if r.UserID == userID {
+			t.Fatal("expected disabled user's traders to be excluded from scheduling")
+		}
+	}
+
+	if err := db.SetUserDisabled(userID, false); err != nil {
+		t.Fatalf("SetUserDisabled(false) failed: %v", err)
+	}
+	if disabled, _ := db.IsUserDisabled(userID); disabled {
+		t.Fatal("expected user to be re-enabled")
+	}
+	if err := db.SetUserDisabled("no-such-user", true); err == nil {
+		t.Fatal("expected error for unknown user")
+	}
+	if err := db.SetUserDisabled("default", true); err == nil {
+		t.Fatal("expected error when disabling the system user")
+	}
+}
+
+func TestGetTraderIDsByStopReason(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-009"
+	aiID := ensureTestAIModel(t, db, userID, "model-stop-reason")
+	exID := ensureTestExchange(t, db, userID, "binance-stop-reason")
+	for _, id := range []string{"tr-outage-a", "tr-outage-b", "tr-user-stop", "tr-paused"} {
+		if err := db.CreateTrader(&TraderRecord{
+			ID: id, UserID: userID, Name: id, AIModelID: aiID, ExchangeID: exID,
+			InitialBalance: 1000, ScanIntervalMinutes: 3, IsRunning: true, SystemPromptTemplate: "default",
+		}); err != nil {
+			t.Fatalf("CreateTrader failed: %v", err)
+		}
+	}
+	db.UpdateTraderStatus(userID, "tr-outage-a", false, StopReasonExchangeOutage)
+	db.UpdateTraderStatus(userID, "tr-outage-b", false, StopReasonExchangeOutage+": maintenance")
+	db.UpdateTraderStatus(userID, "tr-user-stop", false, StopReasonUser)
+	db.UpdateTraderStatus(userID, "tr-paused", false, StopReasonUserPaused)
+
+	ids, err := db.GetTraderIDsByStopReason(StopReasonExchangeOutage)
+	if err != nil {
+		t.Fatalf("GetTraderIDsByStopReason failed: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "tr-outage-a" || ids[1] != "tr-outage-b" {
+		t.Fatalf("expected both outage traders, got %v", ids)
+	}
+
+	// 手动启动后清空停止原因，不再被批量恢复
+	db.UpdateTraderStatus(userID, "tr-outage-a", true, "")
+	if ids, _ := db.GetTraderIDsByStopReason(StopReasonExchangeOutage); len(ids) != 1 || ids[0] != "tr-outage-b" {
+		t.Fatalf("restarted trader should no longer be listed, got %v", ids)
+	}
+
+	// 禁用用户后暂停记录失效
+	if err := db.SetUserDisabled(userID, true); err != nil {
+		t.Fatalf("SetUserDisabled failed: %v", err)
+	}
+	if ids, _ := db.GetTraderIDsByStopReason(StopReasonUserPaused); len(ids) != 0 {
+		t.Fatalf("paused traders of a disabled user should not be resumable, got %v", ids)
+	}
+}
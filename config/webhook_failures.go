@@ -0,0 +1,120 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// defaultWebhookFailureKeep webhook 失败记录默认保留条数（system_config: webhook_failure_keep）
+const defaultWebhookFailureKeep = 500
+
+// WebhookFailure webhook 触发失败记录（死信日志）
+type WebhookFailure struct {
+	ID         int64     `json:"id"`
+	TraderID   string    `json:"trader_id"`
+	UserID     string    `json:"user_id"`
+	Payload    string    `json:"payload"` // 原始请求体
+	Error      string    `json:"error"`
+	RetryCount int       `json:"retry_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// RecordWebhookFailure 记录一次失败的 webhook 触发，并按保留条数清理该用户最旧的记录
+func (d *Database) RecordWebhookFailure(failure *WebhookFailure) error {
+	if failure.CreatedAt.IsZero() {
+		failure.CreatedAt = time.Now()
+	}
+	failure.UpdatedAt = failure.CreatedAt
+	ts := failure.CreatedAt.UTC().Format(sqliteTimeLayout)
+
+	result, err := d.db.Exec(`
+		INSERT INTO webhook_failures (trader_id, user_id, payload, error, retry_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, failure.TraderID, failure.UserID, failure.Payload, failure.Error, failure.RetryCount, ts, ts)
+	if err != nil {
+		return fmt.Errorf("记录webhook失败失败: %w", err)
+	}
+	failure.ID, _ = result.LastInsertId()
+
+	// 保留条数按用户计算，避免一个用户的大量失败挤掉其他用户的记录
+	if _, err := d.db.Exec(`
+		DELETE FROM webhook_failures
+		WHERE user_id = ? AND id NOT IN (
+			SELECT id FROM webhook_failures WHERE user_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`, failure.UserID, failure.UserID, d.webhookFailureKeep()); err != nil {
+		return fmt.Errorf("清理webhook失败记录失败: %w", err)
+	}
+	return nil
+}
+
+// webhookFailureKeep 读取 webhook 失败记录保留条数
+func (d *Database) webhookFailureKeep() int {
+	if val, err := d.GetSystemConfig("webhook_failure_keep"); err == nil {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultWebhookFailureKeep
+}
+
+// GetWebhookFailures 获取用户的 webhook 失败记录（最新的在前），limit<=0 时返回全部
+func (d *Database) GetWebhookFailures(userID string, limit int) ([]*WebhookFailure, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := d.db.Query(`
+		SELECT id, trader_id, user_id, payload, COALESCE(error, ''), retry_count, created_at, updated_at
+		FROM webhook_failures WHERE user_id = ?
+		ORDER BY id DESC LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询webhook失败记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	failures := make([]*WebhookFailure, 0)
+	for rows.Next() {
+		var f WebhookFailure
+		if err := rows.Scan(&f.ID, &f.TraderID, &f.UserID, &f.Payload, &f.Error, &f.RetryCount, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("读取webhook失败记录失败: %w", err)
+		}
+		failures = append(failures, &f)
+	}
+	return failures, rows.Err()
+}
+
+// GetWebhookFailure 获取单条 webhook 失败记录，不存在时返回 (nil, nil)
+func (d *Database) GetWebhookFailure(userID string, id int64) (*WebhookFailure, error) {
+	var f WebhookFailure
+	err := d.db.QueryRow(`
+		SELECT id, trader_id, user_id, payload, COALESCE(error, ''), retry_count, created_at, updated_at
+		FROM webhook_failures WHERE id = ? AND user_id = ?
+	`, id, userID).Scan(&f.ID, &f.TraderID, &f.UserID, &f.Payload, &f.Error, &f.RetryCount, &f.CreatedAt, &f.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询webhook失败记录失败: %w", err)
+	}
+	return &f, nil
+}
+
+// ResolveWebhookFailure 重试成功后删除失败记录
+func (d *Database) ResolveWebhookFailure(id int64) error {
+	_, err := d.db.Exec(`DELETE FROM webhook_failures WHERE id = ?`, id)
+	return err
+}
+
+// MarkWebhookFailureRetried 重试仍失败时更新错误信息并累加重试次数
+func (d *Database) MarkWebhookFailureRetried(id int64, errMsg string) error {
+	_, err := d.db.Exec(`
+		UPDATE webhook_failures
+		SET error = ?, retry_count = retry_count + 1, updated_at = ?
+		WHERE id = ?
+	`, errMsg, time.Now().UTC().Format(sqliteTimeLayout), id)
+	return err
+}
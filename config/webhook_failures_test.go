@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWebhookFailures(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSystemConfig("webhook_failure_keep", "3"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		failure := &WebhookFailure{
+			TraderID: "trader-1",
+			UserID:   "test-user-001",
+			Payload:  fmt.Sprintf("trader-1 breakout BTCUSDT 15m 1 2 0.5 1.5 %d", i),
+			Error:    "AI调用失败",
+		}
+		if err := db.RecordWebhookFailure(failure); err != nil {
+			t.Fatalf("RecordWebhookFailure failed: %v", err)
+		}
+	}
+	if err := db.RecordWebhookFailure(&WebhookFailure{TraderID: "trader-2", UserID: "test-user-002", Payload: "x", Error: "e"}); err != nil {
+		t.Fatalf("RecordWebhookFailure failed: %v", err)
+	}
+
+	// 每个用户各保留最新 3 条，user2 的记录不会挤掉 user1 的记录
+	failures, err := db.GetWebhookFailures("test-user-001", 0)
+	if err != nil {
+		t.Fatalf("GetWebhookFailures failed: %v", err)
+	}
+	if len(failures) != 3 {
+		t.Fatalf("expected 3 failures after retention, got %d", len(failures))
+	}
+	if others, err := db.GetWebhookFailures("test-user-002", 0); err != nil || len(others) != 1 {
+		t.Fatalf("expected other user's failure to be kept, got %d (%v)", len(others), err)
+	}
+	if failures[0].ID < failures[1].ID {
+		t.Fatal("expected newest failure first")
+	}
+
+	latest := failures[0]
+	if got, err := db.GetWebhookFailure("test-user-002", latest.ID); err != nil || got != nil {
+		t.Fatalf("expected other user's record to be hidden, got %v, %v", got, err)
+	}
+
+	if err := db.MarkWebhookFailureRetried(latest.ID, "仍然失败"); err != nil {
+		t.Fatalf("MarkWebhookFailureRetried failed: %v", err)
+	}
+	got, err := db.GetWebhookFailure("test-user-001", latest.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetWebhookFailure failed: %v", err)
+	}
+	if got.RetryCount != 1 || got.Error != "仍然失败" {
+		t.Fatalf("unexpected retried record: %+v", got)
+	}
+
+	if err := db.ResolveWebhookFailure(latest.ID); err != nil {
+		t.Fatalf("ResolveWebhookFailure failed: %v", err)
+	}
+	if got, _ := db.GetWebhookFailure("test-user-001", latest.ID); got != nil {
+		t.Fatal("expected resolved record to be deleted")
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultWebhookPromptKey system_config 中的默认告警 prompt 模板（未配置 TYPE_<type> 环境变量时使用）
+const DefaultWebhookPromptKey = "default_webhook_prompt"
+
+// ErrInvalidWebhookTemplate 告警 prompt 模板存在错误（缺少必需占位符或占位符写法错误）
+var ErrInvalidWebhookTemplate = errors.New("告警prompt模板无效")
+
+// WebhookPlaceholders 告警 prompt 模板的内置占位符（${名称}，区分大小写）
+var WebhookPlaceholders = []string{
+	"TraderID", "Type", "Symbol", "Side", "Interval",
+	"Open", "High", "Low", "Close", "Volume",
+	"Content", "Indicators",
+}
+
+// requiredWebhookPlaceholders 告警模板必须包含的占位符，缺少时 AI 拿不到需要操作的币种
+var requiredWebhookPlaceholders = []string{"Symbol"}
+
+var (
+	webhookPlaceholderPattern = regexp.MustCompile(`\$\{([^{}]*)\}`)
+	webhookPlaceholderName    = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// PromptTemplateIssue 告警 prompt 模板检查发现的问题
+type PromptTemplateIssue struct {
+	Placeholder string `json:"placeholder,omitempty"` // 相关占位符（例如 ${Symbol}）
+	Message     string `json:"message"`
+	Warning     bool   `json:"warning"` // 仅提示（未知占位符可能是告警携带的指标名），不阻止保存
+}
+
+func (i PromptTemplateIssue) String() string {
+	if i.Placeholder == "" {
+		return i.Message
+	}
+	return i.Placeholder + ": " + i.Message
+}
+
+// ValidatePromptTemplate 检查告警 prompt 模板（TYPE_<type> 环境变量或 default_webhook_prompt）的 ${...} 占位符
+// 缺少必需占位符、占位符写法错误（空名称、含空格、未闭合、大小写不符）为错误；
+// 其他未知占位符只作提示，因为告警的 indicators 字段也会按名称替换（例如 ${RSI}）
+func ValidatePromptTemplate(body string) []PromptTemplateIssue {
+	issues := make([]PromptTemplateIssue, 0)
+	if strings.TrimSpace(body) == "" {
+		return append(issues, PromptTemplateIssue{Message: "模板内容为空"})
+	}
+
+	known := make(map[string]bool, len(WebhookPlaceholders))
+	byLower := make(map[string]string, len(WebhookPlaceholders))
+	for _, name := range WebhookPlaceholders {
+		known[name] = true
+		byLower[strings.ToLower(name)] = name
+	}
+
+	used := make(map[string]bool)
+	reported := make(map[string]bool)
+	for _, match := range webhookPlaceholderPattern.FindAllStringSubmatch(body, -1) {
+		token, name := match[0], match[1]
+		if known[name] {
+			used[name] = true
+			continue
+		}
+		if reported[token] {
+			continue
+		}
+		reported[token] = true
+
+		switch trimmed := strings.TrimSpace(name); {
+		case trimmed == "":
+			issues = append(issues, PromptTemplateIssue{Placeholder: token, Message: "占位符名称为空"})
+		case !webhookPlaceholderName.MatchString(name):
+			issues = append(issues, PromptTemplateIssue{Placeholder: token, Message: "占位符名称只能包含字母、数字和下划线（不能有空格），不会被替换"})
+		case byLower[strings.ToLower(name)] != "":
+			issues = append(issues, PromptTemplateIssue{Placeholder: token, Message: fmt.Sprintf("占位符区分大小写，应为 ${%s}", byLower[strings.ToLower(name)])})
+		default:
+			issues = append(issues, PromptTemplateIssue{Placeholder: token, Message: "未知占位符，仅当告警 indicators 中包含同名指标时才会被替换", Warning: true})
+		}
+	}
+
+	// 去掉完整占位符后仍有 "${" 说明存在未闭合的占位符
+	if strings.Contains(webhookPlaceholderPattern.ReplaceAllString(body, ""), "${") {
+		issues = append(issues, PromptTemplateIssue{Placeholder: "${", Message: "占位符未闭合"})
+	}
+
+	for _, name := range requiredWebhookPlaceholders {
+		if !used[name] {
+			issues = append(issues, PromptTemplateIssue{Placeholder: "${" + name + "}", Message: "缺少必需的占位符"})
+		}
+	}
+	return issues
+}
+
+// webhookTemplateError 将模板检查结果中的错误合并为 ErrInvalidWebhookTemplate，只有提示时返回 nil
+func webhookTemplateError(issues []PromptTemplateIssue) error {
+	var problems []string
+	for _, issue := range issues {
+		if !issue.Warning {
+			problems = append(problems, issue.String())
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidWebhookTemplate, strings.Join(problems, "; "))
+}
+
+// validateSystemConfigValue 保存系统配置前的校验（目前只校验 default_webhook_prompt，为空表示不启用）
+func validateSystemConfigValue(key, value string) error {
+	if key == DefaultWebhookPromptKey && strings.TrimSpace(value) != "" {
+		return webhookTemplateError(ValidatePromptTemplate(value))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		errors   int
+		warnings int
+	}{
+		{"valid", "${Symbol} ${Interval} 收于 ${Close}", 0, 0},
+		{"empty", "  ", 1, 0},
+		{"missing symbol", "突破 ${Close}", 1, 0},
+		{"wrong case", "${symbol} ${Symbol}", 1, 0},
+		{"whitespace", "${ Symbol }", 2, 0},
+		{"empty name", "${Symbol} ${}", 1, 0},
+		{"unclosed", "${Symbol} 收于 ${Close", 1, 0},
+		{"indicator", "${Symbol} RSI=${RSI} ${RSI}", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs, warns int
+			issues := ValidatePromptTemplate(tt.body)
+			for _, issue := range issues {
+				if issue.Warning {
+					warns++
+				} else {
+					errs++
+				}
+			}
+			if errs != tt.errors || warns != tt.warnings {
+				t.Fatalf("ValidatePromptTemplate(%q) = %+v, want %d errors / %d warnings", tt.body, issues, tt.errors, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestSetSystemConfig_ValidatesWebhookTemplate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetSystemConfig(DefaultWebhookPromptKey, "突破 ${Close}"); !errors.Is(err, ErrInvalidWebhookTemplate) {
+		t.Fatalf("expected ErrInvalidWebhookTemplate, got %v", err)
+	}
+	if err := db.SetSystemConfig(DefaultWebhookPromptKey, "${Symbol} RSI=${RSI}"); err != nil {
+		t.Fatalf("warnings should not block saving: %v", err)
+	}
+	if err := db.SetSystemConfig(DefaultWebhookPromptKey, ""); err != nil {
+		t.Fatalf("clearing the template should be allowed: %v", err)
+	}
+}
@@ -106,6 +106,7 @@ type Context struct {
 	TakerFeeRate    float64                 `json:"-"` // Taker fee rate (from config, default 0.0004)
 	MakerFeeRate    float64                 `json:"-"` // Maker fee rate (from config, default 0.0002)
 	Timeframes      []string                `json:"-"` // K线时间线配置（从trader配置读取）
+	CandleLookback  map[string]int          `json:"-"` // 各时间线传给AI的K线数量（为空使用默认值）
 
 	// ⚡ 新增：全局市場情緒數據（VIX 恐慌指數 + 美股狀態）
 	GlobalSentiment *market.MarketSentiment `json:"-"` // 全局風險情緒（免費來源：Yahoo Finance + Alpha Vantage）
@@ -161,7 +162,7 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, custo
 
 	// 1.5. ⚡ 獲取全局市場情緒（VIX + 美股，免費來源）
 	alphaVantageKey := os.Getenv("ALPHA_VANTAGE_API_KEY") // 可選，用於美股數據（免費 500 calls/day）
-	sentiment, err := market.FetchMarketSentiment(alphaVantageKey)
+	sentiment, err := market.GetMarketSentiment(alphaVantageKey)
 	if err != nil {
 		// 非關鍵數據，失敗不阻塞主流程
 		log.Printf("⚠️  獲取全局市場情緒失敗（不影響交易）: %v", err)
@@ -202,6 +203,35 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, custo
 	return decision, nil
 }
 
+// ReplayFullDecision 使用当前的系统提示词配置重放一次历史输入prompt（不获取行情，不执行交易）
+// userPrompt 为历史周期发送给AI的输入，accountEquity 为当时的账户净值（用于重建系统提示词中的仓位规则）
+func ReplayFullDecision(userPrompt string, accountEquity float64, btcEthLeverage, altcoinLeverage int, mcpClient mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*FullDecision, error) {
+	if strings.TrimSpace(userPrompt) == "" {
+		return nil, fmt.Errorf("历史决策没有保存输入prompt，无法重放")
+	}
+
+	systemPrompt := buildSystemPromptWithCustom(accountEquity, btcEthLeverage, altcoinLeverage, customPrompt, overrideBase, templateName)
+
+	aiCallStart := time.Now()
+	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	aiCallDuration := time.Since(aiCallStart)
+	if err != nil {
+		return nil, fmt.Errorf("调用AI API失败: %w", err)
+	}
+
+	decision, err := parseFullDecisionResponse(aiResponse, accountEquity, btcEthLeverage, altcoinLeverage)
+	if decision != nil {
+		decision.Timestamp = time.Now()
+		decision.SystemPrompt = systemPrompt
+		decision.UserPrompt = userPrompt
+		decision.AIRequestDurationMs = aiCallDuration.Milliseconds()
+	}
+	if err != nil {
+		return decision, fmt.Errorf("解析AI响应失败: %w", err)
+	}
+	return decision, nil
+}
+
 // fetchMarketDataForContext 为上下文中的所有币种获取市场数据和OI数据
 func fetchMarketDataForContext(ctx *Context) error {
 	ctx.MarketDataMap = make(map[string]*market.Data)
@@ -245,7 +275,7 @@ func fetchMarketDataForContext(ctx *Context) error {
 		wg.Add(1)
 		go func(sym string) {
 			defer wg.Done()
-			data, err := market.Get(sym, ctx.Timeframes)
+			data, err := market.GetWithLookback(sym, ctx.Timeframes, ctx.CandleLookback)
 			resultChan <- marketDataResult{symbol: sym, data: data, err: err}
 		}(symbol)
 	}
@@ -352,6 +382,11 @@ func calculateMaxCandidates(ctx *Context) int {
 	return min(len(ctx.CandidateCoins), maxCandidates)
 }
 
+// PreviewSystemPrompt 按与决策周期相同的规则组装 System Prompt（基础模板 + 自定义prompt / 覆盖基础prompt），不调用AI
+func PreviewSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int, customPrompt string, overrideBase bool, templateName string) string {
+	return buildSystemPromptWithCustom(accountEquity, btcEthLeverage, altcoinLeverage, customPrompt, overrideBase, templateName)
+}
+
 // buildSystemPromptWithCustom 构建包含自定义内容的 System Prompt
 func buildSystemPromptWithCustom(accountEquity float64, btcEthLeverage, altcoinLeverage int, customPrompt string, overrideBase bool, templateName string) string {
 	// 如果覆盖基础prompt且有自定义prompt，只使用自定义prompt
@@ -529,6 +564,11 @@ func buildUserPrompt(ctx *Context) string {
 		sb.WriteString("\n")
 	}
 
+	// 综合情绪得分（VIX + BTC 多空比/大户多空比/资金费率，按 sentiment_weights 加权）
+	if score, label, ok := blendContextSentiment(ctx); ok {
+		sb.WriteString(fmt.Sprintf("综合情绪: %s (得分 %+.2f，范围 -1 偏空 ~ +1 偏多)\n\n", label, score))
+	}
+
 	// 账户
 	sb.WriteString(fmt.Sprintf("账户: 净值%.2f | 余额%.2f (%.1f%%) | 盈亏%+.2f%% | 保证金%.1f%% | 持仓%d个\n\n",
 		ctx.Account.TotalEquity,
@@ -932,6 +972,14 @@ func compactArrayOpen(s string) string {
 	return reArrayOpenSpace.ReplaceAllString(strings.TrimSpace(s), "[{")
 }
 
+// MaxPositionValue 单币种仓位价值上限：BTC/ETH 最多10倍账户净值，山寨币最多5倍
+func MaxPositionValue(symbol string, accountEquity float64) float64 {
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		return accountEquity * 10
+	}
+	return accountEquity * 5
+}
+
 // validateDecisions 验证所有决策（需要账户信息和杠杆配置）
 func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
 	for i, decision := range decisions {
@@ -1049,11 +1097,10 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 	// 开仓操作必须提供完整参数
 	if d.Action == "open_long" || d.Action == "open_short" {
 		// 根据币种使用配置的杠杆上限
-		maxLeverage := altcoinLeverage        // 山寨币使用配置的杠杆
-		maxPositionValue := accountEquity * 5 // 山寨币最多5倍账户净值
+		maxLeverage := altcoinLeverage // 山寨币使用配置的杠杆
+		maxPositionValue := MaxPositionValue(d.Symbol, accountEquity)
 		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC和ETH使用配置的杠杆
-			maxPositionValue = accountEquity * 10 // BTC/ETH最多10倍账户净值
+			maxLeverage = btcEthLeverage // BTC和ETH使用配置的杠杆
 		}
 
 		// ✅ Fallback 机制：杠杆超限时自动修正为上限值（而不是直接拒绝决策）
@@ -0,0 +1,64 @@
+package decision
+
+import (
+	"nofx/market"
+	"sync"
+)
+
+var (
+	sentimentWeightsMu sync.RWMutex
+	sentimentWeights   = market.DefaultSentimentWeights
+)
+
+// SetSentimentWeights 设置综合情绪得分的信号权重（system_config: sentiment_weights）
+func SetSentimentWeights(weights market.SentimentWeights) {
+	sentimentWeightsMu.Lock()
+	defer sentimentWeightsMu.Unlock()
+	sentimentWeights = weights
+}
+
+func getSentimentWeights() market.SentimentWeights {
+	sentimentWeightsMu.RLock()
+	defer sentimentWeightsMu.RUnlock()
+	return sentimentWeights
+}
+
+// sentimentInputsFromContext 从上下文收集可用的情绪信号：VIX 来自全局情绪，
+// 多空比、大户多空比和资金费率来自 BTCUSDT 的市场数据（目前没有恐惧贪婪指数来源）
+func sentimentInputsFromContext(ctx *Context) (market.SentimentInputs, bool) {
+	var inputs market.SentimentInputs
+	available := false
+	positive := func(v float64) *float64 {
+		if v <= 0 {
+			return nil
+		}
+		available = true
+		return &v
+	}
+
+	if ctx.GlobalSentiment != nil {
+		inputs.VIX = positive(ctx.GlobalSentiment.VIX)
+	}
+	if btcData, ok := ctx.MarketDataMap["BTCUSDT"]; ok && btcData != nil {
+		if oi := btcData.OpenInterest; oi != nil {
+			inputs.LongShortRatio = positive(oi.LongShortRatio)
+			inputs.TopTraderRatio = positive(oi.TopTraderLongShortRatio)
+		}
+		// 资金费率可以为负数，0 视为未获取到
+		if rate := btcData.FundingRate; rate != 0 {
+			inputs.FundingRate = &rate
+			available = true
+		}
+	}
+	return inputs, available
+}
+
+// blendContextSentiment 按配置的权重计算综合情绪得分，没有任何可用信号时返回 false
+func blendContextSentiment(ctx *Context) (score float64, label string, ok bool) {
+	inputs, ok := sentimentInputsFromContext(ctx)
+	if !ok {
+		return 0, "", false
+	}
+	score, label = market.BlendSentiment(inputs, getSentimentWeights())
+	return score, label, true
+}
@@ -0,0 +1,41 @@
+package decision
+
+import (
+	"nofx/market"
+	"strings"
+	"testing"
+)
+
+func TestBuildUserPrompt_IncludesBlendedSentiment(t *testing.T) {
+	defer SetSentimentWeights(market.DefaultSentimentWeights)
+
+	ctx := &Context{
+		Account: AccountInfo{TotalEquity: 1000, AvailableBalance: 1000},
+		MarketDataMap: map[string]*market.Data{
+			"BTCUSDT": {
+				CurrentPrice: 60000,
+				FundingRate:  0.001,
+				OpenInterest: &market.OIData{LongShortRatio: 1.5, TopTraderLongShortRatio: 1.5},
+			},
+		},
+		GlobalSentiment: &market.MarketSentiment{VIX: 35},
+	}
+
+	// 默认权重：VIX 偏空被多空比、资金费率偏多抵消后仍偏多
+	if prompt := buildUserPrompt(ctx); !strings.Contains(prompt, "综合情绪: bullish") {
+		t.Fatalf("prompt should include the blended sentiment, got:\n%s", prompt)
+	}
+
+	// 只看 VIX 时应偏空
+	SetSentimentWeights(market.SentimentWeights{VIX: 1})
+	if prompt := buildUserPrompt(ctx); !strings.Contains(prompt, "综合情绪: bearish (得分 -1.00") {
+		t.Fatalf("configured weights should drive the blended sentiment, got:\n%s", prompt)
+	}
+}
+
+func TestBuildUserPrompt_OmitsBlendedSentimentWithoutSignals(t *testing.T) {
+	ctx := &Context{Account: AccountInfo{TotalEquity: 1000}}
+	if prompt := buildUserPrompt(ctx); strings.Contains(prompt, "综合情绪") {
+		t.Fatalf("prompt should not include the blended sentiment without signals, got:\n%s", prompt)
+	}
+}
@@ -1,12 +1,22 @@
 package logger
 
 import (
+	"os"
+	"strings"
+
 	"github.com/sirupsen/logrus"
 )
 
+// 日志输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 // Config 日志配置（简化版）
 type Config struct {
 	Level    string          `json:"level"`    // 日志级别: debug, info, warn, error (默认: info)
+	Format   string          `json:"format"`   // 输出格式: text（默认，彩色文本）或 json（结构化，便于 Loki/ELK 采集），环境变量 LOG_FORMAT 可覆盖
 	Telegram *TelegramConfig `json:"telegram"` // Telegram推送配置（可选）
 }
 
@@ -23,6 +33,12 @@ func (c *Config) SetDefaults() {
 	if c.Level == "" {
 		c.Level = "info"
 	}
+	if format := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT"))); format != "" {
+		c.Format = format
+	}
+	if c.Format != FormatJSON {
+		c.Format = FormatText
+	}
 }
 
 // GetLogrusLevels 返回要推送到Telegram的日志级别
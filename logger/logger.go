@@ -3,6 +3,7 @@ package logger
 import (
 	"nofx/config"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -39,18 +40,27 @@ func Init(cfg *Config) error {
 	}
 	Log.SetLevel(level)
 
-	// 设置格式化器（固定使用彩色文本格式）
-	Log.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05",
-		ForceColors:     true,
-	})
+	// 设置格式化器（默认彩色文本，json 模式输出 level/msg/time 及字段）
+	if cfg.Format == FormatJSON {
+		Log.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	} else {
+		Log.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02 15:04:05",
+			ForceColors:     true,
+		})
+	}
 
 	// 设置输出目标（默认stdout）
 	Log.SetOutput(os.Stdout)
 
-	// 启用调用位置信息
-	Log.SetReportCaller(true)
+	// json 模式下把标准库 log.Printf 的输出也转为结构化日志（项目中大部分日志走标准库）
+	if cfg.Format == FormatJSON {
+		redirectStdLog(Log)
+	}
+
+	// 启用调用位置信息（json 模式下由标准库日志的 caller 字段提供，避免记录成转写器自身的位置）
+	Log.SetReportCaller(cfg.Format != FormatJSON)
 
 	// 添加Telegram Hook（可选）
 	if cfg.Telegram != nil && cfg.Telegram.Enabled {
@@ -100,7 +110,8 @@ func InitFromLogConfig(logConfig *config.LogConfig) error {
 	}
 
 	cfg := &Config{
-		Level: logConfig.Level,
+		Level:  logConfig.Level,
+		Format: logConfig.Format,
 	}
 
 	if cfg.Level == "" {
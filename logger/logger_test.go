@@ -2,7 +2,11 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
+	"log"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -347,3 +351,51 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// TestJSONFormat tests LOG_FORMAT=json switching to structured output, including stdlib log lines
+func TestJSONFormat(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "json")
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	if err := Init(nil); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	var buf bytes.Buffer
+	Log.SetOutput(&buf)
+
+	WithField("symbol", "BTCUSDT").Info("opened")
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not JSON: %v (%q)", err, buf.String())
+	}
+	if entry["level"] != "info" || entry["msg"] != "opened" || entry["symbol"] != "BTCUSDT" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+
+	buf.Reset()
+	log.Printf("⚠️ 余额不足: %d", 5)
+	entry = nil
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("stdlib output is not JSON: %v (%q)", err, buf.String())
+	}
+	if entry["level"] != "warning" || entry["msg"] != "⚠️ 余额不足: 5" {
+		t.Errorf("unexpected stdlib entry: %v", entry)
+	}
+	if caller, _ := entry["caller"].(string); !strings.HasPrefix(caller, "logger_test.go:") {
+		t.Errorf("expected caller field, got %v", entry["caller"])
+	}
+}
+
+// TestTextFormatDefault tests that the human-readable format stays the default
+func TestTextFormatDefault(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "")
+	if err := Init(nil); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, ok := Log.Formatter.(*logrus.TextFormatter); !ok {
+		t.Errorf("expected TextFormatter, got %T", Log.Formatter)
+	}
+}
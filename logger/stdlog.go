@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stdLogCallerPattern 匹配 log.Lshortfile 生成的 "file.go:123: " 前缀
+var stdLogCallerPattern = regexp.MustCompile(`^([\w.\-]+\.go:\d+): `)
+
+// stdLogWriter 将标准库 log 的每一行转写为 logrus 日志条目
+type stdLogWriter struct {
+	logger *logrus.Logger
+}
+
+// redirectStdLog 接管标准库 log 的输出：去掉标准库自带的时间戳，改用 Lshortfile 记录调用位置并拆成 caller 字段
+func redirectStdLog(l *logrus.Logger) {
+	log.SetFlags(log.Lshortfile)
+	log.SetPrefix("")
+	log.SetOutput(&stdLogWriter{logger: l})
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimRight(p, "\n"))
+	entry := logrus.NewEntry(w.logger)
+	if m := stdLogCallerPattern.FindStringSubmatch(msg); m != nil {
+		entry = entry.WithField("caller", m[1])
+		msg = msg[len(m[0]):]
+	}
+	entry.WithField("source", "stdlog").Log(stdLogLevel(msg), msg)
+	return len(p), nil
+}
+
+// stdLogLevel 根据日志行首的 emoji/关键字推断级别（标准库 log 没有级别概念）
+func stdLogLevel(msg string) logrus.Level {
+	msg = strings.TrimSpace(msg)
+	switch {
+	case strings.HasPrefix(msg, "❌"), strings.HasPrefix(msg, "🚨"), strings.HasPrefix(msg, "[ERROR]"):
+		return logrus.ErrorLevel
+	case strings.HasPrefix(msg, "⚠"), strings.HasPrefix(msg, "[WARN]"):
+		return logrus.WarnLevel
+	case strings.HasPrefix(msg, "🔍 [DEBUG]"), strings.HasPrefix(msg, "[DEBUG]"):
+		return logrus.DebugLevel
+	default:
+		return logrus.InfoLevel
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
@@ -10,9 +11,11 @@ import (
 	"nofx/auth"
 	"nofx/config"
 	"nofx/crypto"
+	"nofx/logger"
 	"nofx/manager"
 	"nofx/market"
 	"nofx/pool"
+	"nofx/trader"
 	"os"
 	"os/signal"
 	"strconv"
@@ -185,11 +188,19 @@ func main() {
 	// In Docker Compose, variables are injected by the runtime and this is harmless.
 	_ = godotenv.Load()
 
+	// 初始化日志（LOG_FORMAT=json 时输出结构化 JSON，默认保持可读文本）
+	if err := logger.Init(nil); err != nil {
+		log.Printf("⚠️  初始化日志失败: %v", err)
+	}
+
 	// 🔐 安全检查：验证必需的环境变量
 	if err := validateSecurityConfig(); err != nil {
 		log.Fatalf("❌ 安全配置检查失败: %v\n\n💡 请运行以下命令修复:\n   ./scripts/setup-env.sh\n", err)
 	}
 
+	// 检查环境变量中的告警 prompt 模板（TYPE_<type>），缺少 ${Symbol} 等问题在启动时提示
+	api.CheckEnvWebhookTemplates(os.Environ())
+
 	// 初始化数据库配置
 	dbPath := "config.db"
 	if len(os.Args) > 1 {
@@ -203,7 +214,9 @@ func main() {
 	}
 
 	log.Printf("📋 初始化配置数据库: %s", dbPath)
-	database, err := config.NewDatabase(dbPath)
+	// NOFX_SKIP_MIGRATIONS=true 时仅连接数据库，不自动执行迁移（生产环境可在备份后手动迁移）
+	skipMigrations := strings.EqualFold(strings.TrimSpace(os.Getenv("NOFX_SKIP_MIGRATIONS")), "true")
+	database, err := config.NewDatabaseWithOptions(dbPath, config.DatabaseOptions{SkipMigrations: skipMigrations})
 	if err != nil {
 		log.Fatalf("❌ 初始化数据库失败: %v", err)
 	}
@@ -218,11 +231,35 @@ func main() {
 	database.SetCryptoService(cryptoService)
 	log.Printf("✅ 加密服务初始化成功")
 
+	// NOFX_SEED_DEMO=true 时写入演示数据（仅空库生效，可重复执行）
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("NOFX_SEED_DEMO")), "true") {
+		creds, err := database.SeedDemoData()
+		if err != nil {
+			log.Printf("⚠️  写入演示数据失败: %v", err)
+		} else if creds != nil {
+			// 凭据随机生成且只在创建时展示这一次，直接输出到终端而不写入日志
+			fmt.Printf("🌱 演示账户已创建：邮箱 %s | 密码 %s | OTP 密钥 %s（请立即保存，之后不会再显示）\n",
+				creds.Email, creds.Password, creds.OTPSecret)
+		}
+	}
+
+	// 保存交易员时校验 trading_symbols 在所选交易所可交易（币种列表缓存1小时）
+	database.SetSymbolChecker(market.NewSymbolCatalog(time.Hour))
+	// 每日刷新 Binance 合约列表（symbols 表），发现新上架和已下架的币种
+	database.StartSymbolUniverseRefresh(24 * time.Hour)
+
 	// 同步config.json到数据库
 	if err := syncConfigToDatabase(database, configFile); err != nil {
 		log.Printf("⚠️  同步config.json到数据库失败: %v", err)
 	}
 
+	// TRADER_ID 等环境变量声明的交易员（无UI部署），每次启动按环境变量同步
+	if envTrader, err := config.ParseEnvTrader(os.Getenv); err != nil {
+		log.Printf("⚠️  解析环境变量交易员配置失败: %v", err)
+	} else if err := database.ReconcileEnvTrader(envTrader); err != nil {
+		log.Printf("⚠️  同步环境变量交易员失败: %v", err)
+	}
+
 	// 加载内测码到数据库
 	if err := loadBetaCodesToDatabase(database); err != nil {
 		log.Printf("⚠️  加载内测码到数据库失败: %v", err)
@@ -325,6 +362,26 @@ func main() {
 		log.Printf("✓ 已配置OI Top API")
 	}
 
+	// 多实例部署时通过 Redis 共享市场情绪快照、AI限流计数和API配额（优先级：环境变量 REDIS_URL > 数据库配置 redis_url）
+	redisURL := strings.TrimSpace(os.Getenv("REDIS_URL"))
+	if redisURL == "" {
+		redisURL, _ = database.GetSystemConfig("redis_url")
+	}
+	if redisURL != "" {
+		if redisClient, err := market.NewRedisClient(redisURL); err != nil {
+			log.Printf("⚠️  Redis 不可用，市场情绪快照、AI限流计数和API配额仅在本实例内生效: %v", err)
+		} else {
+			market.SetSentimentSnapshotStore(redisClient)
+			// AI 模型限流额度在实例间共享
+			trader.GetAIModelRateLimiter().SetCounter(redisClient)
+			// Alpha Vantage 配额在实例间共享（Redis 出错时自动退回本地限流）
+			market.SetAlphaVantageQuotaStore(redisClient)
+			// 非关键组件：Redis 故障不影响交易，健康汇总显示为 degraded
+			api.RegisterHealthCheck("redis", false, func(ctx context.Context) error { return redisClient.Healthy() })
+			log.Printf("✓ 已启用 Redis 共享市场情绪快照、AI限流计数和API配额")
+		}
+	}
+
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
 
@@ -400,6 +457,18 @@ func main() {
 		log.Printf("🔌 使用默认端口: %d", apiPort)
 	}
 
+	// 检查本地时钟偏差（签名请求对时间戳敏感），并注册到系统健康检查
+	for _, exchange := range []string{"binance", "hyperliquid"} {
+		api.RegisterHealthCheck("clock:"+exchange, false, api.ClockSkewHealthCheck(exchange))
+		go func(exchange string) {
+			if skew, err := market.CheckTimeSkew(exchange); err != nil {
+				log.Printf("⚠️  检查 %s 服务器时间失败: %v", exchange, err)
+			} else {
+				log.Printf("⏱ 本地时钟与 %s 服务器偏差: %v", exchange, skew.Round(time.Millisecond))
+			}
+		}(exchange)
+	}
+
 	// 创建并启动API服务器
 	apiServer := api.NewServer(traderManager, database, cryptoService, apiPort)
 	go func() {
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/notify"
 	"nofx/trader"
 	"sort"
 	"strconv"
@@ -21,11 +23,18 @@ type CompetitionCache struct {
 	mu        sync.RWMutex
 }
 
+// stoppedTraderLister 按停止原因查询已停止的交易员（由 config.Database 实现）
+type stoppedTraderLister interface {
+	GetTraderIDsByStopReason(reason string) ([]string, error)
+}
+
 // TraderManager 管理多个trader实例
 type TraderManager struct {
 	traders          map[string]*trader.AutoTrader // key: trader ID
 	competitionCache *CompetitionCache
-	mu               sync.RWMutex
+	// stoppedLister 批量暂停/交易所故障停止的记录保存在 traders.stop_reason 中，恢复时按停止原因查询（重启后仍可恢复）
+	stoppedLister stoppedTraderLister
+	mu            sync.RWMutex
 }
 
 // NewTraderManager 创建trader管理器
@@ -43,6 +52,8 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	tm.stoppedLister = database
+
 	// 获取所有用户
 	userIDs, err := database.GetAllUsers()
 	if err != nil {
@@ -65,6 +76,13 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 
 	log.Printf("📋 总共加载 %d 个交易员配置", len(allTraders))
 
+	configureAIConcurrency(database)
+	configureOrderMinimums(database)
+	configureMarginFloor(database)
+	configureWebhookScaleIn(database)
+	configureMaintenanceWindows(database)
+	configureSentimentWeights(database)
+
 	// 获取系统配置（不包含信号源，信号源现在为用户级别）
 	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
 	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
@@ -189,6 +207,11 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		}
 	}
 
+	// 应用系统级币种白名单/黑名单
+	symbolFilter := database.GetSymbolFilter()
+	tradingCoins = symbolFilter.Filter(tradingCoins, traderCfg.Name)
+	defaultCoins = symbolFilter.Filter(defaultCoins, traderCfg.Name)
+
 	// ✅ 不再混淆 tradingCoins 和 defaultCoins
 	// tradingCoins = 用戶自定義幣種（可能為空）
 	// defaultCoins = 系統默認幣種（將傳給 AutoTrader）
@@ -207,6 +230,8 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
 	}
 
+	fallbackAIModels := getFallbackAIModels(database, traderCfg)
+
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
 		ID:                    traderCfg.ID,
@@ -222,9 +247,13 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
-		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		CustomAPIURL:          aiModelCfg.CustomAPIURL,             // 自定义API URL
+		CustomModelName:       aiModelCfg.CustomModelName,          // 自定义模型名称
+		CustomHeaders:         aiModelCustomHeaders(aiModelCfg),    // 自定义请求头
+		FallbackAIModels:      fallbackAIModels,                    // 备用AI模型（故障转移）
+		AIModelID:             aiModelCfg.ID,                       // AI模型配置ID（用于限流）
+		AIRequestsPerMinute:   getAIModelRPM(database, aiModelCfg), // 每分钟AI请求上限
+		ScanInterval:          traderCfg.ScanInterval(),
 		InitialBalance:        traderCfg.InitialBalance,
 		BTCETHLeverage:        traderCfg.BTCETHLeverage,
 		AltcoinLeverage:       traderCfg.AltcoinLeverage,
@@ -240,14 +269,27 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		UseOITop:              traderCfg.UseOITop,             // OI Top 信号源配置
 		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
 		OrderStrategy:         traderCfg.OrderStrategy,        // 订单策略
+		BTCETHOrderStrategy:   traderCfg.BTCETHOrderStrategy,  // BTC/ETH订单策略
+		AltcoinOrderStrategy:  traderCfg.AltcoinOrderStrategy, // 山寨币订单策略
 		LimitPriceOffset:      traderCfg.LimitPriceOffset,     // 限价偏移
 		LimitTimeoutSeconds:   traderCfg.LimitTimeoutSeconds,  // 限价超时
+		LossStreakThreshold:   traderCfg.LossStreakThreshold,  // 连续亏损冷却阈值
+		LossCooldown:          time.Duration(traderCfg.CooldownMinutes) * time.Minute,
+		MaxOrdersPerCycle:     traderCfg.MaxOrdersPerCycle,
+		TrailingStopPercent:   traderCfg.TrailingStopPercent,
+		TrailingActivation:    traderCfg.TrailingActivation,
+		CandleLookback:        traderCfg.CandleLookbackMap(),
+		SymbolWeights:         traderCfg.SymbolWeightsMap(),
+		TakeProfitPercent:     traderCfg.TakeProfitPercent,
+		StopLossPercent:       traderCfg.StopLossPercent,
+		Timezone:              traderCfg.Location(),
 	}
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ExchangeID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceAccountMode = exchangeCfg.AccountMode
 	} else if exchangeCfg.ExchangeID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -270,10 +312,16 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
 
+	// 引用的提示词模板与自定义prompt相互独立：只引用模板、没有自定义prompt时也要设置
+	at.SetPromptTemplateName(traderCfg.PromptTemplateName)
+	at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
+	if traderCfg.PromptTemplateName != "" {
+		log.Printf("✓ 已引用提示词模板: %s", traderCfg.PromptTemplateName)
+	}
+
 	// 设置自定义prompt（如果有）
 	if traderCfg.CustomPrompt != "" {
 		at.SetCustomPrompt(traderCfg.CustomPrompt)
-		at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
 		if traderCfg.OverrideBasePrompt {
 			log.Printf("✓ 已设置自定义交易策略prompt (覆盖基础prompt)")
 		} else {
@@ -310,6 +358,11 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		}
 	}
 
+	// 应用系统级币种白名单/黑名单
+	symbolFilter := database.GetSymbolFilter()
+	tradingCoins = symbolFilter.Filter(tradingCoins, traderCfg.Name)
+	defaultCoins = symbolFilter.Filter(defaultCoins, traderCfg.Name)
+
 	// ✅ 不再混淆 tradingCoins 和 defaultCoins
 	// tradingCoins = 用戶自定義幣種（可能為空）
 	// defaultCoins = 系統默認幣種（將傳給 AutoTrader）
@@ -322,6 +375,8 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
 
+	fallbackAIModels := getFallbackAIModels(database, traderCfg)
+
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
 		ID:                    traderCfg.ID,
@@ -336,9 +391,13 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		UseQwen:               aiModelCfg.Provider == "qwen",
 		DeepSeekKey:           "",
 		QwenKey:               "",
-		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		CustomAPIURL:          aiModelCfg.CustomAPIURL,             // 自定义API URL
+		CustomModelName:       aiModelCfg.CustomModelName,          // 自定义模型名称
+		CustomHeaders:         aiModelCustomHeaders(aiModelCfg),    // 自定义请求头
+		FallbackAIModels:      fallbackAIModels,                    // 备用AI模型（故障转移）
+		AIModelID:             aiModelCfg.ID,                       // AI模型配置ID（用于限流）
+		AIRequestsPerMinute:   getAIModelRPM(database, aiModelCfg), // 每分钟AI请求上限
+		ScanInterval:          traderCfg.ScanInterval(),
 		InitialBalance:        traderCfg.InitialBalance,
 		BTCETHLeverage:        traderCfg.BTCETHLeverage,
 		AltcoinLeverage:       traderCfg.AltcoinLeverage,
@@ -354,14 +413,27 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		UseOITop:              traderCfg.UseOITop,             // OI Top 信号源配置
 		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
 		OrderStrategy:         traderCfg.OrderStrategy,        // 订单策略
+		BTCETHOrderStrategy:   traderCfg.BTCETHOrderStrategy,  // BTC/ETH订单策略
+		AltcoinOrderStrategy:  traderCfg.AltcoinOrderStrategy, // 山寨币订单策略
 		LimitPriceOffset:      traderCfg.LimitPriceOffset,     // 限价偏移
 		LimitTimeoutSeconds:   traderCfg.LimitTimeoutSeconds,  // 限价超时
+		LossStreakThreshold:   traderCfg.LossStreakThreshold,  // 连续亏损冷却阈值
+		LossCooldown:          time.Duration(traderCfg.CooldownMinutes) * time.Minute,
+		MaxOrdersPerCycle:     traderCfg.MaxOrdersPerCycle,
+		TrailingStopPercent:   traderCfg.TrailingStopPercent,
+		TrailingActivation:    traderCfg.TrailingActivation,
+		CandleLookback:        traderCfg.CandleLookbackMap(),
+		SymbolWeights:         traderCfg.SymbolWeightsMap(),
+		TakeProfitPercent:     traderCfg.TakeProfitPercent,
+		StopLossPercent:       traderCfg.StopLossPercent,
+		Timezone:              traderCfg.Location(),
 	}
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ExchangeID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceAccountMode = exchangeCfg.AccountMode
 	} else if exchangeCfg.ExchangeID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -384,10 +456,16 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		return fmt.Errorf("创建trader失败: %w", err)
 	}
 
+	// 引用的提示词模板与自定义prompt相互独立：只引用模板、没有自定义prompt时也要设置
+	at.SetPromptTemplateName(traderCfg.PromptTemplateName)
+	at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
+	if traderCfg.PromptTemplateName != "" {
+		log.Printf("✓ 已引用提示词模板: %s", traderCfg.PromptTemplateName)
+	}
+
 	// 设置自定义prompt（如果有）
 	if traderCfg.CustomPrompt != "" {
 		at.SetCustomPrompt(traderCfg.CustomPrompt)
-		at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
 		if traderCfg.OverrideBasePrompt {
 			log.Printf("✓ 已设置自定义交易策略prompt (覆盖基础prompt)")
 		} else {
@@ -412,6 +490,15 @@ func (tm *TraderManager) GetTrader(id string) (*trader.AutoTrader, error) {
 	return t, nil
 }
 
+// GetTraderLogs 获取指定trader最近 n 行周期日志（按时间正序）
+func (tm *TraderManager) GetTraderLogs(traderID string, n int) ([]trader.TraderLogLine, error) {
+	t, err := tm.GetTrader(traderID)
+	if err != nil {
+		return nil, err
+	}
+	return t.GetLogs(n), nil
+}
+
 // GetAllTraders 获取所有trader
 func (tm *TraderManager) GetAllTraders() map[string]*trader.AutoTrader {
 	tm.mu.RLock()
@@ -480,6 +567,99 @@ func (tm *TraderManager) RemoveTrader(traderID string) error {
 	return nil
 }
 
+// ReloadTradersByExchange 重新加载用户使用指定交易所账户（exchanges.id）的已加载交易员，使新的交易所配置（例如轮换后的密钥）立即生效
+// 运行中的交易员先停止，重新加载后恢复运行；返回重新加载的交易员数量
+func (tm *TraderManager) ReloadTradersByExchange(database *config.Database, userID string, exchangeID int) (int, error) {
+	traders, err := database.GetTraders(userID)
+	if err != nil {
+		return 0, fmt.Errorf("获取用户 %s 的交易员列表失败: %w", userID, err)
+	}
+
+	reloaded := 0
+	for _, traderCfg := range traders {
+		if traderCfg.ExchangeID != exchangeID {
+			continue
+		}
+		tm.mu.RLock()
+		old, loaded := tm.traders[traderCfg.ID]
+		tm.mu.RUnlock()
+		if !loaded {
+			continue
+		}
+		wasRunning := false
+		if old != nil {
+			wasRunning, _ = old.GetStatus()["is_running"].(bool)
+		}
+
+		if err := tm.RemoveTrader(traderCfg.ID); err != nil {
+			log.Printf("⚠️ 移除交易员 %s 失败: %v", traderCfg.ID, err)
+			continue
+		}
+		if err := tm.LoadTraderByID(database, userID, traderCfg.ID); err != nil {
+			log.Printf("⚠️ 重新加载交易员 %s 失败: %v", traderCfg.ID, err)
+			continue
+		}
+		reloaded++
+
+		if !wasRunning {
+			continue
+		}
+		at, err := tm.GetTrader(traderCfg.ID)
+		if err != nil {
+			continue
+		}
+		go func(at *trader.AutoTrader) {
+			log.Printf("▶️  重新启动交易员 %s", at.GetName())
+			if err := at.Run(); err != nil {
+				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+			}
+		}(at)
+	}
+
+	log.Printf("🔄 用户 %s 的交易所账户 %d: 已重新加载 %d 个交易员", userID, exchangeID, reloaded)
+	return reloaded, nil
+}
+
+// DeleteUser 停止并移除用户在内存中的所有交易员，然后删除该用户及其全部数据
+func (tm *TraderManager) DeleteUser(database *config.Database, userID string) error {
+	tm.mu.RLock()
+	var traderIDs []string
+	for id, t := range tm.traders {
+		if t != nil && t.GetUserID() == userID {
+			traderIDs = append(traderIDs, id)
+		}
+	}
+	tm.mu.RUnlock()
+
+	for _, id := range traderIDs {
+		if err := tm.RemoveTrader(id); err != nil {
+			log.Printf("⚠️ 移除用户 %s 的交易员 %s 失败: %v", userID, id, err)
+		}
+	}
+
+	return database.DeleteUser(userID)
+}
+
+// SetUserDisabled 禁用或启用用户；禁用时先停止该用户在内存中运行的交易员（保留在内存中以便查看数据）
+// 启用后交易员不会自动恢复，需要用户手动启动
+func (tm *TraderManager) SetUserDisabled(database *config.Database, userID string, disabled bool) error {
+	if disabled {
+		tm.mu.Lock()
+		for id, t := range tm.traders {
+			if t == nil || t.GetUserID() != userID {
+				continue
+			}
+			if isRunning, ok := t.GetStatus()["is_running"].(bool); ok && isRunning {
+				log.Printf("⏹  用户 %s 已被禁用，停止交易员 %s (%s)", userID, id, t.GetName())
+				t.Stop()
+			}
+		}
+		tm.mu.Unlock()
+	}
+
+	return database.SetUserDisabled(userID, disabled)
+}
+
 // StartAll 启动所有trader
 func (tm *TraderManager) StartAll() {
 	tm.mu.RLock()
@@ -501,25 +681,10 @@ func (tm *TraderManager) StartRunningTraders(database *config.Database) error {
 	tm.mu.RLock()
 	defer tm.mu.RUnlock()
 
-	// 获取所有用户
-	userIDs, err := database.GetAllUsers()
+	// 一次查询获取所有用户中应该启动的交易员
+	runningTraders, err := database.GetAllRunningTraders()
 	if err != nil {
-		return fmt.Errorf("获取用户列表失败: %w", err)
-	}
-
-	// 收集所有应该启动的交易员
-	var runningTraders []*config.TraderRecord
-	for _, userID := range userIDs {
-		traders, err := database.GetTraders(userID)
-		if err != nil {
-			log.Printf("⚠️ 获取用户 %s 的交易员失败: %v", userID, err)
-			continue
-		}
-		for _, trader := range traders {
-			if trader.IsRunning {
-				runningTraders = append(runningTraders, trader)
-			}
-		}
+		return fmt.Errorf("获取运行中的交易员失败: %w", err)
 	}
 
 	if len(runningTraders) == 0 {
@@ -555,6 +720,238 @@ func (tm *TraderManager) StopAll() {
 	}
 }
 
+// PauseUserTraders 暂停指定用户所有正在运行的trader
+// 暂停原因写入数据库（stop_reason = user_paused），服务重启后仍可由 ResumeUserTraders 恢复
+// 返回本次暂停的trader ID列表
+func (tm *TraderManager) PauseUserTraders(userID string) (paused []string, err error) {
+	if userID == "" {
+		return nil, fmt.Errorf("用户ID不能为空")
+	}
+
+	targets := tm.runningTraders(func(t *trader.AutoTrader) bool { return t.GetUserID() == userID })
+
+	// 在锁外停止：Stop 会等待正在执行的交易周期结束
+	paused = []string{}
+	for _, id := range sortedTraderIDs(targets) {
+		t := targets[id]
+		log.Printf("⏸  暂停交易员 %s (%s)", id, t.GetName())
+		t.Stop()
+		if err := t.PersistRunStatus(false, config.StopReasonUserPaused); err != nil {
+			log.Printf("⚠️ 更新交易员 %s 状态失败: %v", id, err)
+		}
+		paused = append(paused, id)
+	}
+
+	log.Printf("⏸  用户 %s 已暂停 %d 个交易员", userID, len(paused))
+	return paused, nil
+}
+
+// ResumeUserTraders 恢复指定用户之前被暂停的trader
+// ids 为空时恢复该用户所有停止原因为 user_paused 的trader；否则只恢复给定的ID
+func (tm *TraderManager) ResumeUserTraders(userID string, ids []string) error {
+	if userID == "" {
+		return fmt.Errorf("用户ID不能为空")
+	}
+
+	if len(ids) == 0 {
+		var err error
+		if ids, err = tm.GetPausedTraders(userID); err != nil {
+			return err
+		}
+	}
+
+	// 先校验全部ID，避免只恢复一部分
+	tm.mu.RLock()
+	targets := make(map[string]*trader.AutoTrader, len(ids))
+	for _, id := range ids {
+		t, exists := tm.traders[id]
+		if !exists || t == nil {
+			tm.mu.RUnlock()
+			return fmt.Errorf("trader ID '%s' 不存在", id)
+		}
+		if t.GetUserID() != userID {
+			tm.mu.RUnlock()
+			return fmt.Errorf("trader ID '%s' 不属于用户 %s", id, userID)
+		}
+		targets[id] = t
+	}
+	tm.mu.RUnlock()
+
+	resumed := 0
+	for _, id := range sortedTraderIDs(targets) {
+		t := targets[id]
+		if isRunning, ok := t.GetStatus()["is_running"].(bool); ok && isRunning {
+			continue
+		}
+		if err := t.PersistRunStatus(true, ""); err != nil {
+			log.Printf("⚠️ 更新交易员 %s 状态失败: %v", id, err)
+		}
+		go func(traderID string, at *trader.AutoTrader) {
+			log.Printf("▶️  恢复交易员 %s (%s)", traderID, at.GetName())
+			if err := at.Run(); err != nil {
+				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+			}
+		}(id, t)
+		resumed++
+	}
+
+	log.Printf("▶️  用户 %s 已恢复 %d 个交易员", userID, resumed)
+	return nil
+}
+
+// GetPausedTraders 获取指定用户当前被暂停（stop_reason = user_paused）且已加载的trader ID列表
+func (tm *TraderManager) GetPausedTraders(userID string) ([]string, error) {
+	return tm.stoppedTraders(config.StopReasonUserPaused, func(t *trader.AutoTrader) bool {
+		return t.GetUserID() == userID
+	})
+}
+
+// StopTradersByExchange 停止所有使用指定交易所且正在运行的trader（用于交易所故障或维护期间）
+// 停止原因写入数据库（stop_reason = exchange_outage），并只发送一条汇总通知；返回本次停止的trader数量
+// 之后由 ResumeTradersByExchange 按停止原因恢复（服务重启后同样有效）
+func (tm *TraderManager) StopTradersByExchange(exchangeType string, reason string) (int, error) {
+	exchangeType = strings.ToLower(strings.TrimSpace(exchangeType))
+	if exchangeType == "" {
+		return 0, fmt.Errorf("交易所类型不能为空")
+	}
+	stopReason := config.StopReasonExchangeOutage
+	if reason = strings.TrimSpace(reason); reason != "" {
+		stopReason = fmt.Sprintf("%s: %s", config.StopReasonExchangeOutage, reason)
+	}
+
+	targets := tm.runningTraders(func(t *trader.AutoTrader) bool { return strings.EqualFold(t.GetExchange(), exchangeType) })
+	if len(targets) == 0 {
+		log.Printf("📋 交易所 %s 没有正在运行的交易员", exchangeType)
+		return 0, nil
+	}
+
+	// 在锁外停止：Stop 会等待正在执行的交易周期结束
+	names := []string{}
+	for _, id := range sortedTraderIDs(targets) {
+		t := targets[id]
+		log.Printf("⏹  交易所 %s 故障，停止交易员 %s (%s)", exchangeType, id, t.GetName())
+		t.Stop()
+		if err := t.PersistRunStatus(false, stopReason); err != nil {
+			log.Printf("⚠️ 更新交易员 %s 状态失败: %v", id, err)
+		}
+		names = append(names, t.GetName())
+	}
+	sort.Strings(names)
+
+	message := fmt.Sprintf("🛑 交易所 %s 故障，已停止 %d 个交易员: %s", exchangeType, len(targets), strings.Join(names, ", "))
+	if reason != "" {
+		message += fmt.Sprintf("（%s）", reason)
+	}
+	notify.NotifyLevel(notify.LevelWarn, message)
+	return len(targets), nil
+}
+
+// ResumeTradersByExchange 恢复使用指定交易所、停止原因为 exchange_outage 的trader，返回本次恢复的数量
+// 已被手动启动的trader停止原因已清空，不会重复启动
+func (tm *TraderManager) ResumeTradersByExchange(exchangeType string) (int, error) {
+	exchangeType = strings.ToLower(strings.TrimSpace(exchangeType))
+	if exchangeType == "" {
+		return 0, fmt.Errorf("交易所类型不能为空")
+	}
+
+	ids, err := tm.GetExchangeStoppedTraders(exchangeType)
+	if err != nil {
+		return 0, err
+	}
+
+	tm.mu.RLock()
+	targets := make(map[string]*trader.AutoTrader, len(ids))
+	for _, id := range ids {
+		targets[id] = tm.traders[id]
+	}
+	tm.mu.RUnlock()
+
+	resumed := 0
+	for _, id := range sortedTraderIDs(targets) {
+		t := targets[id]
+		if isRunning, ok := t.GetStatus()["is_running"].(bool); ok && isRunning {
+			continue
+		}
+		if err := t.PersistRunStatus(true, ""); err != nil {
+			log.Printf("⚠️ 更新交易员 %s 状态失败: %v", id, err)
+		}
+		go func(traderID string, at *trader.AutoTrader) {
+			log.Printf("▶️  交易所 %s 已恢复，重新启动交易员 %s (%s)", exchangeType, traderID, at.GetName())
+			if err := at.Run(); err != nil {
+				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
+			}
+		}(id, t)
+		resumed++
+	}
+
+	if resumed > 0 {
+		notify.NotifyLevel(notify.LevelInfo, fmt.Sprintf("▶️ 交易所 %s 已恢复，重新启动 %d 个交易员", exchangeType, resumed))
+	}
+	return resumed, nil
+}
+
+// GetExchangeStoppedTraders 获取因交易所故障被批量停止、尚未恢复且已加载的trader ID列表
+func (tm *TraderManager) GetExchangeStoppedTraders(exchangeType string) ([]string, error) {
+	exchangeType = strings.ToLower(strings.TrimSpace(exchangeType))
+	return tm.stoppedTraders(config.StopReasonExchangeOutage, func(t *trader.AutoTrader) bool {
+		return strings.EqualFold(t.GetExchange(), exchangeType)
+	})
+}
+
+// runningTraders 返回满足条件且正在运行的trader（只在读锁内遍历，调用方在锁外停止）
+func (tm *TraderManager) runningTraders(match func(t *trader.AutoTrader) bool) map[string]*trader.AutoTrader {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	targets := make(map[string]*trader.AutoTrader)
+	for id, t := range tm.traders {
+		if t == nil || !match(t) {
+			continue
+		}
+		if isRunning, ok := t.GetStatus()["is_running"].(bool); ok && isRunning {
+			targets[id] = t
+		}
+	}
+	return targets
+}
+
+// stoppedTraders 按停止原因查询已停止的trader，只返回已加载到内存且满足条件的ID（已排序）
+// 未关联数据库时返回空列表
+func (tm *TraderManager) stoppedTraders(reason string, match func(t *trader.AutoTrader) bool) ([]string, error) {
+	tm.mu.RLock()
+	lister := tm.stoppedLister
+	tm.mu.RUnlock()
+	if lister == nil {
+		return []string{}, nil
+	}
+
+	ids, err := lister.GetTraderIDsByStopReason(reason)
+	if err != nil {
+		return nil, err
+	}
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	result := []string{}
+	for _, id := range ids {
+		if t, exists := tm.traders[id]; exists && t != nil && match(t) {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// sortedTraderIDs 返回 map 中的trader ID（已排序）
+func sortedTraderIDs(traders map[string]*trader.AutoTrader) []string {
+	ids := make([]string, 0, len(traders))
+	for id := range traders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // GetComparisonData 获取对比数据
 func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 	tm.mu.RLock()
@@ -784,6 +1181,158 @@ func (tm *TraderManager) GetTopTradersData() (map[string]interface{}, error) {
 	return result, nil
 }
 
+// aiModelCustomHeaders 解析AI模型的自定义请求头，配置无效时忽略并记录警告
+func aiModelCustomHeaders(aiModelCfg *config.AIModelConfig) map[string]string {
+	headers, err := config.ParseCustomHeaders(aiModelCfg.CustomHeaders)
+	if err != nil {
+		log.Printf("⚠️ AI模型 %s 的自定义请求头无效，已忽略: %v", aiModelCfg.ModelID, err)
+		return nil
+	}
+	return headers
+}
+
+// getFallbackAIModels 读取交易员配置的备用AI模型（按顺序故障转移）
+func getFallbackAIModels(database *config.Database, traderCfg *config.TraderRecord) []trader.FallbackAIModel {
+	if database == nil {
+		return nil
+	}
+	ids, err := config.ParseFallbackAIModelIDs(traderCfg.FallbackAIModelIDs)
+	if err != nil {
+		log.Printf("⚠️ 交易员 %s 的备用AI模型配置无效，已忽略: %v", traderCfg.Name, err)
+		return nil
+	}
+	models, err := database.GetFallbackAIModels(traderCfg.UserID, ids)
+	if err != nil {
+		log.Printf("⚠️ 获取交易员 %s 的备用AI模型失败: %v", traderCfg.Name, err)
+		return nil
+	}
+
+	fallbacks := make([]trader.FallbackAIModel, 0, len(models))
+	for _, model := range models {
+		if model.ID == traderCfg.AIModelID {
+			continue
+		}
+		fallbacks = append(fallbacks, trader.FallbackAIModel{
+			AIModelID:         model.ID,
+			Provider:          model.Provider,
+			APIKey:            model.APIKey,
+			CustomAPIURL:      model.CustomAPIURL,
+			CustomModelName:   model.CustomModelName,
+			CustomHeaders:     aiModelCustomHeaders(model),
+			RequestsPerMinute: getAIModelRPM(database, model),
+		})
+	}
+	return fallbacks
+}
+
+// getAIModelRPM 读取AI模型配置每分钟允许的请求数：优先使用模型自身的 requests_per_minute，
+// 未设置时使用全局默认值（system_config: ai_model_rpm，0表示不限制）
+func getAIModelRPM(database *config.Database, model *config.AIModelConfig) int {
+	if model != nil && model.RequestsPerMinute > 0 {
+		return model.RequestsPerMinute
+	}
+	if database == nil {
+		return 0
+	}
+	rpmStr, _ := database.GetSystemConfig("ai_model_rpm")
+	rpm, err := strconv.Atoi(strings.TrimSpace(rpmStr))
+	if err != nil || rpm < 0 {
+		return 0
+	}
+	return rpm
+}
+
+// configureAIConcurrency 按系统配置设置全局AI并发上限（system_config: ai_max_concurrency、ai_slot_timeout_sec）
+func configureAIConcurrency(database *config.Database) {
+	if database == nil {
+		return
+	}
+	limitStr, _ := database.GetSystemConfig("ai_max_concurrency")
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit < 0 {
+		limit = 0
+	}
+	timeoutStr, _ := database.GetSystemConfig("ai_slot_timeout_sec")
+	timeoutSec, err := strconv.Atoi(strings.TrimSpace(timeoutStr))
+	if err != nil || timeoutSec <= 0 {
+		timeoutSec = 30
+	}
+	trader.GetAIConcurrencyLimiter().Configure(limit, time.Duration(timeoutSec)*time.Second)
+	if limit > 0 {
+		log.Printf("🚦 全局AI并发上限: %d（等待超时 %d 秒）", limit, timeoutSec)
+	}
+}
+
+// configureOrderMinimums 按系统配置设置开仓最小名义价值（system_config: min_order_notional）
+func configureOrderMinimums(database *config.Database) {
+	if database == nil {
+		return
+	}
+	valStr, _ := database.GetSystemConfig("min_order_notional")
+	minNotional, err := strconv.ParseFloat(strings.TrimSpace(valStr), 64)
+	if err != nil || minNotional < 0 {
+		minNotional = 0
+	}
+	trader.SetMinOrderNotional(minNotional)
+}
+
+// configureMarginFloor 按系统配置设置可用保证金下限（system_config: min_available_margin，默认 0）
+func configureMarginFloor(database *config.Database) {
+	if database == nil {
+		return
+	}
+	valStr, _ := database.GetSystemConfig("min_available_margin")
+	floor, err := strconv.ParseFloat(strings.TrimSpace(valStr), 64)
+	if err != nil {
+		floor = 0
+	}
+	trader.SetMarginFloor(floor)
+}
+
+// configureWebhookScaleIn 按系统配置设置 webhook 加仓窗口和持仓上限
+// （system_config: webhook_scale_in_window_minutes / webhook_scale_in_max_position_usd，默认 0 即关闭）
+func configureWebhookScaleIn(database *config.Database) {
+	if database == nil {
+		return
+	}
+	windowStr, _ := database.GetSystemConfig("webhook_scale_in_window_minutes")
+	minutes, err := strconv.Atoi(strings.TrimSpace(windowStr))
+	if err != nil || minutes < 0 {
+		minutes = 0
+	}
+	maxStr, _ := database.GetSystemConfig("webhook_scale_in_max_position_usd")
+	maxUSD, err := strconv.ParseFloat(strings.TrimSpace(maxStr), 64)
+	if err != nil {
+		maxUSD = 0
+	}
+	trader.SetWebhookScaleIn(time.Duration(minutes)*time.Minute, maxUSD)
+}
+
+// configureMaintenanceWindows 按系统配置设置全局维护窗口（system_config: maintenance_window）
+func configureMaintenanceWindows(database *config.Database) {
+	if database == nil {
+		return
+	}
+	spec, _ := database.GetSystemConfig("maintenance_window")
+	windows, err := trader.ParseMaintenanceWindows(spec)
+	if err != nil {
+		log.Printf("⚠️ 维护窗口配置无效，已忽略: %v", err)
+		windows = nil
+	}
+	trader.SetMaintenanceWindows(windows)
+	for _, w := range windows {
+		log.Printf("🛠️ 维护窗口: %s", w)
+	}
+}
+
+// configureSentimentWeights 按系统配置设置综合情绪得分的信号权重（system_config: sentiment_weights）
+func configureSentimentWeights(database *config.Database) {
+	if database == nil {
+		return
+	}
+	decision.SetSentimentWeights(database.GetSentimentWeights())
+}
+
 // isUserTrader 检查trader是否属于指定用户
 func isUserTrader(traderID, userID string) bool {
 	// trader ID格式: userID_traderName 或 randomUUID_modelName
@@ -1112,6 +1661,11 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		}
 	}
 
+	// 应用系统级币种白名单/黑名单
+	symbolFilter := database.GetSymbolFilter()
+	tradingCoins = symbolFilter.Filter(tradingCoins, traderCfg.Name)
+	defaultCoins = symbolFilter.Filter(defaultCoins, traderCfg.Name)
+
 	// ✅ 不再混淆 tradingCoins 和 defaultCoins
 	// tradingCoins = 用戶自定義幣種（可能為空）
 	// defaultCoins = 系統默認幣種（將傳給 AutoTrader）
@@ -1143,7 +1697,13 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		}
 		log.Printf("✓ 交易员 %s 配置时间线: %v", traderCfg.Name, timeframes)
 	}
-	// 如果为空，将使用 NewAutoTrader 中的默认值 ["15m", "1h", "4h"]
+	// 如果为空，使用系统默认时间线（system_config: default_timeframes）
+	if len(timeframes) == 0 {
+		timeframes = database.GetDefaultTimeframes()
+	}
+
+	fallbackAIModels := getFallbackAIModels(database, traderCfg)
+
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
 		ID:                   traderCfg.ID,
@@ -1155,11 +1715,15 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		AltcoinLeverage:      traderCfg.AltcoinLeverage,
 		TakerFeeRate:         traderCfg.TakerFeeRate, // Taker fee rate from config
 		MakerFeeRate:         traderCfg.MakerFeeRate, // Maker fee rate from config
-		ScanInterval:         time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		ScanInterval:         traderCfg.ScanInterval(),
 		CoinPoolAPIURL:       effectiveCoinPoolURL,
 		OITopAPIURL:          effectiveOITopURL,
-		CustomAPIURL:         aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:      aiModelCfg.CustomModelName, // 自定义模型名称
+		CustomAPIURL:         aiModelCfg.CustomAPIURL,             // 自定义API URL
+		CustomModelName:      aiModelCfg.CustomModelName,          // 自定义模型名称
+		CustomHeaders:        aiModelCustomHeaders(aiModelCfg),    // 自定义请求头
+		FallbackAIModels:     fallbackAIModels,                    // 备用AI模型（故障转移）
+		AIModelID:            aiModelCfg.ID,                       // AI模型配置ID（用于限流）
+		AIRequestsPerMinute:  getAIModelRPM(database, aiModelCfg), // 每分钟AI请求上限
 		UseQwen:              aiModelCfg.Provider == "qwen",
 		MaxDailyLoss:         maxDailyLoss,
 		MaxDrawdown:          maxDrawdown,
@@ -1169,16 +1733,29 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		TradingCoins:         tradingCoins,
 		SystemPromptTemplate: traderCfg.SystemPromptTemplate, // 系统提示词模板
 		OrderStrategy:        traderCfg.OrderStrategy,        // 订单策略
+		BTCETHOrderStrategy:  traderCfg.BTCETHOrderStrategy,  // BTC/ETH订单策略
+		AltcoinOrderStrategy: traderCfg.AltcoinOrderStrategy, // 山寨币订单策略
 		LimitPriceOffset:     traderCfg.LimitPriceOffset,     // 限价偏移
 		LimitTimeoutSeconds:  traderCfg.LimitTimeoutSeconds,  // 限价超时
 		HyperliquidTestnet:   exchangeCfg.Testnet,            // Hyperliquid测试网
 		Timeframes:           timeframes,                     // K线时间线配置
+		LossStreakThreshold:  traderCfg.LossStreakThreshold,  // 连续亏损冷却阈值
+		LossCooldown:         time.Duration(traderCfg.CooldownMinutes) * time.Minute,
+		MaxOrdersPerCycle:    traderCfg.MaxOrdersPerCycle,
+		TrailingStopPercent:  traderCfg.TrailingStopPercent,
+		TrailingActivation:   traderCfg.TrailingActivation,
+		CandleLookback:       traderCfg.CandleLookbackMap(),
+		SymbolWeights:        traderCfg.SymbolWeightsMap(),
+		TakeProfitPercent:    traderCfg.TakeProfitPercent,
+		StopLossPercent:      traderCfg.StopLossPercent,
+		Timezone:             traderCfg.Location(),
 	}
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ExchangeID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceAccountMode = exchangeCfg.AccountMode
 	} else if exchangeCfg.ExchangeID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -1201,10 +1778,16 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		return nil, fmt.Errorf("创建trader失败: %w", err)
 	}
 
+	// 引用的提示词模板与自定义prompt相互独立：只引用模板、没有自定义prompt时也要设置
+	at.SetPromptTemplateName(traderCfg.PromptTemplateName)
+	at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
+	if traderCfg.PromptTemplateName != "" {
+		log.Printf("✓ 已引用提示词模板: %s", traderCfg.PromptTemplateName)
+	}
+
 	// 设置自定义prompt（如果有）
 	if traderCfg.CustomPrompt != "" {
 		at.SetCustomPrompt(traderCfg.CustomPrompt)
-		at.SetOverrideBasePrompt(traderCfg.OverrideBasePrompt)
 		if traderCfg.OverrideBasePrompt {
 			log.Printf("✓ 已设置自定义交易策略prompt (覆盖基础prompt)")
 		} else {
@@ -1,7 +1,9 @@
 package manager
 
 import (
+	"nofx/config"
 	"nofx/trader"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -774,3 +776,404 @@ func TestGetTopTradersData(t *testing.T) {
 
 	t.Logf("✅ GetTopTradersData returned valid data structure")
 }
+
+// fakeStatusStore 模拟 traders 表的运行状态与停止原因（实现 UpdateTraderStatus / GetTraderIDsByStopReason）
+type fakeStatusStore struct {
+	mu      sync.Mutex
+	reasons map[string]string // key: trader ID，value: 停止原因（运行中不在表中）
+}
+
+func newFakeStatusStore() *fakeStatusStore {
+	return &fakeStatusStore{reasons: make(map[string]string)}
+}
+
+func (f *fakeStatusStore) UpdateTraderStatus(userID, id string, isRunning bool, reason string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if isRunning {
+		delete(f.reasons, id)
+	} else {
+		f.reasons[id] = reason
+	}
+	return nil
+}
+
+func (f *fakeStatusStore) GetTraderIDsByStopReason(reason string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []string
+	for id, r := range f.reasons {
+		if r == reason || strings.HasPrefix(r, reason+":") {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeStatusStore) reason(id string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.reasons[id]
+	return r, ok
+}
+
+func newTestTrader(t *testing.T, id, userID string, database interface{}) *trader.AutoTrader {
+	t.Helper()
+	at, err := trader.NewAutoTrader(trader.AutoTraderConfig{
+		ID:             id,
+		Name:           id,
+		AIModel:        "deepseek",
+		Exchange:       "binance",
+		InitialBalance: 1000.0,
+		ScanInterval:   5 * time.Minute,
+	}, database, userID)
+	if err != nil {
+		t.Fatalf("Failed to create trader %s: %v", id, err)
+	}
+	return at
+}
+
+func isTraderRunning(at *trader.AutoTrader) bool {
+	running, _ := at.GetStatus()["is_running"].(bool)
+	return running
+}
+
+// skipCyclesForTest 设置覆盖当前时间的维护窗口，trader 运行时跳过交易周期（不访问交易所）
+func skipCyclesForTest(t *testing.T) {
+	t.Helper()
+	now := time.Now().UTC()
+	minute := now.Hour()*60 + now.Minute()
+	trader.SetMaintenanceWindows([]trader.MaintenanceWindow{{Start: (minute + 1435) % 1440, End: (minute + 5) % 1440}})
+	t.Cleanup(func() { trader.SetMaintenanceWindows(nil) })
+}
+
+// startTestTrader 在维护窗口内启动trader，等待其进入运行状态
+func startTestTrader(t *testing.T, at *trader.AutoTrader) {
+	t.Helper()
+	skipCyclesForTest(t)
+	t.Cleanup(at.Stop)
+
+	go at.Run()
+	waitForRunning(t, at, true)
+}
+
+func waitForRunning(t *testing.T, at *trader.AutoTrader, want bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for isTraderRunning(at) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("trader %s is_running 未变为 %v", at.GetID(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPauseUserTraders_OnlyRecordsRunning 测试暂停只处理正在运行的trader
+func TestPauseUserTraders_OnlyRecordsRunning(t *testing.T) {
+	tm := NewTraderManager()
+	store := newFakeStatusStore()
+	tm.stoppedLister = store
+	tm.traders["pause-trader-1"] = newTestTrader(t, "pause-trader-1", "user-a", store)
+	tm.traders["pause-trader-2"] = newTestTrader(t, "pause-trader-2", "user-b", store)
+
+	paused, err := tm.PauseUserTraders("user-a")
+	if err != nil {
+		t.Fatalf("PauseUserTraders failed: %v", err)
+	}
+	if len(paused) != 0 {
+		t.Errorf("未运行的trader不应被记录为暂停，got %v", paused)
+	}
+	if got, _ := tm.GetPausedTraders("user-a"); len(got) != 0 {
+		t.Errorf("暂停集合应为空，got %v", got)
+	}
+
+	if _, err := tm.PauseUserTraders(""); err == nil {
+		t.Error("空用户ID应该返回错误")
+	}
+}
+
+// TestPauseUserTraders_PersistsAcrossRestart 测试暂停正在运行的trader并写入停止原因，重启后仍可恢复
+func TestPauseUserTraders_PersistsAcrossRestart(t *testing.T) {
+	store := newFakeStatusStore()
+	tm := NewTraderManager()
+	tm.stoppedLister = store
+	running := newTestTrader(t, "pause-running", "user-a", store)
+	other := newTestTrader(t, "pause-other-user", "user-b", store)
+	tm.traders["pause-running"] = running
+	tm.traders["pause-other-user"] = other
+	startTestTrader(t, running)
+	startTestTrader(t, other)
+
+	paused, err := tm.PauseUserTraders("user-a")
+	if err != nil {
+		t.Fatalf("PauseUserTraders failed: %v", err)
+	}
+	if len(paused) != 1 || paused[0] != "pause-running" {
+		t.Fatalf("expected pause-running to be paused, got %v", paused)
+	}
+	if isTraderRunning(running) {
+		t.Error("暂停后trader应已停止")
+	}
+	if !isTraderRunning(other) {
+		t.Error("其他用户的trader不应被暂停")
+	}
+	if reason, _ := store.reason("pause-running"); reason != config.StopReasonUserPaused {
+		t.Errorf("停止原因应为 %s，got %q", config.StopReasonUserPaused, reason)
+	}
+
+	// 模拟重启：新的管理器从数据库重新加载trader（未运行），按停止原因恢复
+	restarted := NewTraderManager()
+	restarted.stoppedLister = store
+	reloaded := newTestTrader(t, "pause-running", "user-a", store)
+	restarted.traders["pause-running"] = reloaded
+	if got, _ := restarted.GetPausedTraders("user-a"); len(got) != 1 {
+		t.Fatalf("重启后应仍能查到暂停记录，got %v", got)
+	}
+	skipCyclesForTest(t)
+	t.Cleanup(reloaded.Stop)
+
+	if err := restarted.ResumeUserTraders("user-a", nil); err != nil {
+		t.Fatalf("ResumeUserTraders failed: %v", err)
+	}
+	waitForRunning(t, reloaded, true)
+	if _, stopped := store.reason("pause-running"); stopped {
+		t.Error("恢复后应清除停止原因")
+	}
+}
+
+// TestResumeUserTraders_ValidatesOwnership 测试恢复时校验trader归属
+func TestResumeUserTraders_ValidatesOwnership(t *testing.T) {
+	tm := NewTraderManager()
+	store := newFakeStatusStore()
+	tm.stoppedLister = store
+	tm.traders["resume-trader-1"] = newTestTrader(t, "resume-trader-1", "user-b", store)
+	store.UpdateTraderStatus("user-b", "resume-trader-1", false, config.StopReasonUserPaused)
+
+	if err := tm.ResumeUserTraders("user-a", []string{"resume-trader-1"}); err == nil {
+		t.Error("恢复其他用户的trader应该返回错误")
+	}
+	if err := tm.ResumeUserTraders("user-a", []string{"non-existent"}); err == nil {
+		t.Error("恢复不存在的trader应该返回错误")
+	}
+	// 其他用户的暂停记录不会被当前用户恢复
+	if got, _ := tm.GetPausedTraders("user-a"); len(got) != 0 {
+		t.Errorf("不应查到其他用户的暂停记录，got %v", got)
+	}
+
+	// 校验失败时不应清除暂停记录
+	if got, _ := tm.GetPausedTraders("user-b"); len(got) != 1 {
+		t.Errorf("暂停记录不应被清除，got %v", got)
+	}
+
+	// 移除trader后不再出现在暂停列表中
+	if err := tm.RemoveTrader("resume-trader-1"); err != nil {
+		t.Fatalf("RemoveTrader failed: %v", err)
+	}
+	if got, _ := tm.GetPausedTraders("user-b"); len(got) != 0 {
+		t.Errorf("移除trader后暂停记录应被清理，got %v", got)
+	}
+}
+
+// TestStopTradersByExchange_OnlyRunning 测试按交易所批量停止只处理正在运行的trader
+func TestStopTradersByExchange_OnlyRunning(t *testing.T) {
+	tm := NewTraderManager()
+	store := newFakeStatusStore()
+	tm.stoppedLister = store
+	tm.traders["outage-trader-1"] = newTestTrader(t, "outage-trader-1", "user-a", store)
+
+	stopped, err := tm.StopTradersByExchange("Binance", "maintenance")
+	if err != nil {
+		t.Fatalf("StopTradersByExchange failed: %v", err)
+	}
+	if stopped != 0 {
+		t.Errorf("未运行的trader不应被停止，got %d", stopped)
+	}
+	if got, _ := tm.GetExchangeStoppedTraders("binance"); len(got) != 0 {
+		t.Errorf("停止记录应为空，got %v", got)
+	}
+
+	if _, err := tm.StopTradersByExchange(" ", ""); err == nil {
+		t.Error("空交易所类型应该返回错误")
+	}
+	if _, err := tm.ResumeTradersByExchange(""); err == nil {
+		t.Error("空交易所类型应该返回错误")
+	}
+}
+
+// TestStopTradersByExchange_StopsRunningAndResumesAfterRestart 测试停止正在运行的trader，并在重启后按停止原因恢复
+func TestStopTradersByExchange_StopsRunningAndResumesAfterRestart(t *testing.T) {
+	store := newFakeStatusStore()
+	tm := NewTraderManager()
+	tm.stoppedLister = store
+	running := newTestTrader(t, "outage-running", "user-a", store)
+	tm.traders["outage-running"] = running
+	startTestTrader(t, running)
+
+	stopped, err := tm.StopTradersByExchange("BINANCE", "maintenance")
+	if err != nil {
+		t.Fatalf("StopTradersByExchange failed: %v", err)
+	}
+	if stopped != 1 {
+		t.Fatalf("expected 1 stopped trader, got %d", stopped)
+	}
+	if isTraderRunning(running) {
+		t.Error("trader 应已停止")
+	}
+	if reason, _ := store.reason("outage-running"); reason != config.StopReasonExchangeOutage+": maintenance" {
+		t.Errorf("停止原因未写入，got %q", reason)
+	}
+
+	// 模拟重启：内存中没有任何停止记录，只能依靠 stop_reason 恢复
+	restarted := NewTraderManager()
+	restarted.stoppedLister = store
+	reloaded := newTestTrader(t, "outage-running", "user-a", store)
+	restarted.traders["outage-running"] = reloaded
+	if got, _ := restarted.GetExchangeStoppedTraders("hyperliquid"); len(got) != 0 {
+		t.Errorf("其他交易所不应查到停止记录，got %v", got)
+	}
+	skipCyclesForTest(t)
+	t.Cleanup(reloaded.Stop)
+
+	resumed, err := restarted.ResumeTradersByExchange("binance")
+	if err != nil {
+		t.Fatalf("ResumeTradersByExchange failed: %v", err)
+	}
+	if resumed != 1 {
+		t.Fatalf("expected 1 resumed trader, got %d", resumed)
+	}
+	waitForRunning(t, reloaded, true)
+	if got, _ := restarted.GetExchangeStoppedTraders("binance"); len(got) != 0 {
+		t.Errorf("恢复后停止记录应被清除，got %v", got)
+	}
+}
+
+// TestResumeTradersByExchange_SkipsUnloadedTraders 测试不会恢复未加载到内存的trader
+func TestResumeTradersByExchange_SkipsUnloadedTraders(t *testing.T) {
+	tm := NewTraderManager()
+	store := newFakeStatusStore()
+	tm.stoppedLister = store
+	store.UpdateTraderStatus("user-a", "removed-trader", false, config.StopReasonExchangeOutage)
+
+	resumed, err := tm.ResumeTradersByExchange("BINANCE")
+	if err != nil {
+		t.Fatalf("ResumeTradersByExchange failed: %v", err)
+	}
+	if resumed != 0 {
+		t.Errorf("已移除的trader不应被恢复，got %d", resumed)
+	}
+}
+
+func TestGetAIModelRPM_PrefersModelLimit(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+	if err := db.SetSystemConfig("ai_model_rpm", "30"); err != nil {
+		t.Fatalf("SetSystemConfig failed: %v", err)
+	}
+
+	if got := getAIModelRPM(db, &config.AIModelConfig{RequestsPerMinute: 5}); got != 5 {
+		t.Errorf("model limit should win, got %d", got)
+	}
+	if got := getAIModelRPM(db, &config.AIModelConfig{}); got != 30 {
+		t.Errorf("unset model limit should fall back to ai_model_rpm, got %d", got)
+	}
+	if got := getAIModelRPM(nil, nil); got != 0 {
+		t.Errorf("no config should mean unlimited, got %d", got)
+	}
+}
+
+func TestReloadTradersByExchange(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	user := &config.User{ID: "reload-user", Email: "reload@example.com", PasswordHash: "x", OTPSecret: "JBSWY3DPEHPK3PXP", OTPVerified: true}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := db.CreateAIModel(user.ID, "test-model", "Test Model", "openai", true, "test-key", "http://test"); err != nil {
+		t.Fatalf("Failed to create AI model: %v", err)
+	}
+	if err := db.CreateExchange(user.ID, "binance", "Binance", "cex", true, "test-key", "test-secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("Failed to create exchange: %v", err)
+	}
+	aiModels, _ := db.GetAIModels(user.ID)
+	exchanges, _ := db.GetExchanges(user.ID)
+	exchangeID := exchanges[0].ID
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID: "reload-trader", UserID: user.ID, Name: "Reload Trader",
+		AIModelID: aiModels[0].ID, ExchangeID: exchangeID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, BTCETHLeverage: 5, AltcoinLeverage: 5, TradingSymbols: "BTCUSDT",
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+
+	tm := NewTraderManager()
+	if err := tm.LoadUserTraders(db, user.ID); err != nil {
+		t.Fatalf("Failed to load traders: %v", err)
+	}
+	before, err := tm.GetTrader("reload-trader")
+	if err != nil {
+		t.Fatalf("trader should be loaded: %v", err)
+	}
+
+	if n, err := tm.ReloadTradersByExchange(db, user.ID, exchangeID+1); err != nil || n != 0 {
+		t.Fatalf("other exchange accounts should not be reloaded, got %d %v", n, err)
+	}
+	n, err := tm.ReloadTradersByExchange(db, user.ID, exchangeID)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 reloaded trader, got %d %v", n, err)
+	}
+	after, err := tm.GetTrader("reload-trader")
+	if err != nil {
+		t.Fatalf("trader should be loaded after reload: %v", err)
+	}
+	if after == before {
+		t.Error("reload should replace the trader instance")
+	}
+}
+
+func TestLoadUserTraders_PromptTemplateWithoutCustomPrompt(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	user := &config.User{ID: "template-user", Email: "template@example.com", PasswordHash: "x", OTPSecret: "JBSWY3DPEHPK3PXP", OTPVerified: true}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := db.CreateAIModel(user.ID, "test-model", "Test Model", "openai", true, "test-key", "http://test"); err != nil {
+		t.Fatalf("Failed to create AI model: %v", err)
+	}
+	if err := db.CreateExchange(user.ID, "binance", "Binance", "cex", true, "test-key", "test-secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("Failed to create exchange: %v", err)
+	}
+	if err := db.CreatePromptTemplate(&config.PromptTemplate{UserID: user.ID, Name: "shared-strategy", Body: "共享策略 for {{trader_name}}"}); err != nil {
+		t.Fatalf("Failed to create prompt template: %v", err)
+	}
+	aiModels, _ := db.GetAIModels(user.ID)
+	exchanges, _ := db.GetExchanges(user.ID)
+
+	if err := db.CreateTrader(&config.TraderRecord{
+		ID: "template-trader", UserID: user.ID, Name: "Template Trader",
+		AIModelID: aiModels[0].ID, ExchangeID: exchanges[0].ID,
+		InitialBalance: 1000, ScanIntervalMinutes: 3, BTCETHLeverage: 5, AltcoinLeverage: 5, TradingSymbols: "BTCUSDT",
+		PromptTemplateName: "shared-strategy", OverrideBasePrompt: true, // 覆盖基础prompt，测试不依赖 prompts 目录
+	}); err != nil {
+		t.Fatalf("Failed to create trader: %v", err)
+	}
+
+	tm := NewTraderManager()
+	if err := tm.LoadUserTraders(db, user.ID); err != nil {
+		t.Fatalf("Failed to load traders: %v", err)
+	}
+	at, err := tm.GetTrader("template-trader")
+	if err != nil {
+		t.Fatalf("trader should be loaded: %v", err)
+	}
+	prompt, err := at.EffectivePrompt()
+	if err != nil {
+		t.Fatalf("EffectivePrompt failed: %v", err)
+	}
+	if !strings.Contains(prompt, "共享策略 for Template Trader") {
+		t.Errorf("rendered template missing from prompt:\n%s", prompt)
+	}
+}
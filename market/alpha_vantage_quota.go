@@ -0,0 +1,143 @@
+package market
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// Alpha Vantage 免費 Key 的限額：每分鐘 5 次（同一 Key 在所有實例間共享）
+const (
+	alphaVantageBurst   = 5
+	alphaVantageRefill  = 12 * time.Second // 每 12 秒補充一個令牌
+	alphaVantageMaxWait = 15 * time.Second // 無緩存可用時最多等待的時間
+)
+
+// ErrQuotaExhausted 共享配額已用完
+var ErrQuotaExhausted = errors.New("API 配額已用完")
+
+// QuotaStore 令牌桶狀態存儲
+// 默認為進程內實現；多實例部署時替換為 RedisClient（Lua 腳本原子扣減），使所有實例共享同一配額
+type QuotaStore interface {
+	// Take 嘗試從 key 對應的令牌桶取出一個令牌（容量 capacity，每 refill 補充一個）
+	// 取不到時 ok=false，wait 為下一個令牌可用前的等待時間
+	Take(key string, capacity int, refill time.Duration) (ok bool, wait time.Duration, err error)
+}
+
+type tokenBucket struct {
+	tokens   float64
+	updateAt time.Time
+}
+
+// memoryQuotaStore 進程內令牌桶
+type memoryQuotaStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+// NewMemoryQuotaStore 創建進程內令牌桶存儲
+func NewMemoryQuotaStore() QuotaStore {
+	return &memoryQuotaStore{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+func (m *memoryQuotaStore) Take(key string, capacity int, refill time.Duration) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(capacity), updateAt: now}
+		m.buckets[key] = bucket
+	}
+	if elapsed := now.Sub(bucket.updateAt); elapsed > 0 {
+		bucket.tokens = math.Min(float64(capacity), bucket.tokens+float64(elapsed)/float64(refill))
+		bucket.updateAt = now
+	}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - bucket.tokens) * float64(refill)), nil
+}
+
+// QuotaLimiter 按 API Key 協調請求配額；共享存儲不可用時退回進程內限流
+type QuotaLimiter struct {
+	mu       sync.RWMutex
+	store    QuotaStore
+	fallback QuotaStore
+	name     string
+	capacity int
+	refill   time.Duration
+	sleep    func(time.Duration)
+}
+
+// NewQuotaLimiter 創建配額限流器，store 為 nil 時只使用進程內限流
+func NewQuotaLimiter(name string, capacity int, refill time.Duration, store QuotaStore) *QuotaLimiter {
+	fallback := NewMemoryQuotaStore()
+	if store == nil {
+		store = fallback
+	}
+	return &QuotaLimiter{store: store, fallback: fallback, name: name, capacity: capacity, refill: refill, sleep: time.Sleep}
+}
+
+// SetStore 替換共享配額存儲（例如 Redis 實現）
+func (l *QuotaLimiter) SetStore(store QuotaStore) {
+	if store == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.store = store
+}
+
+// quotaKey 以 API Key 的哈希作為令牌桶 key，避免明文 Key 寫入共享存儲
+func (l *QuotaLimiter) quotaKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return fmt.Sprintf("quota:%s:%s", l.name, hex.EncodeToString(sum[:8]))
+}
+
+// take 嘗試取一個令牌，共享存儲出錯時使用進程內令牌桶
+func (l *QuotaLimiter) take(key string) (bool, time.Duration) {
+	l.mu.RLock()
+	store := l.store
+	l.mu.RUnlock()
+
+	ok, wait, err := store.Take(key, l.capacity, l.refill)
+	if err != nil {
+		log.Printf("⚠️ %s 共享配額不可用，改用本地限流: %v", l.name, err)
+		ok, wait, _ = l.fallback.Take(key, l.capacity, l.refill)
+	}
+	return ok, wait
+}
+
+// Acquire 為 apiKey 占用一次請求配額；配額不足時最多等待 maxWait，仍不足返回 ErrQuotaExhausted
+func (l *QuotaLimiter) Acquire(apiKey string, maxWait time.Duration) error {
+	key := l.quotaKey(apiKey)
+	ok, wait := l.take(key)
+	if ok {
+		return nil
+	}
+	if wait > maxWait {
+		return fmt.Errorf("%w: %s（需等待 %v）", ErrQuotaExhausted, l.name, wait.Round(time.Second))
+	}
+	l.sleep(wait)
+	if ok, wait = l.take(key); !ok {
+		return fmt.Errorf("%w: %s（需等待 %v）", ErrQuotaExhausted, l.name, wait.Round(time.Second))
+	}
+	return nil
+}
+
+// alphaVantageLimiter Alpha Vantage 請求配額（同一 Key 每分鐘 5 次）
+var alphaVantageLimiter = NewQuotaLimiter("alphavantage", alphaVantageBurst, alphaVantageRefill, nil)
+
+// SetAlphaVantageQuotaStore 替換 Alpha Vantage 配額存儲（例如多實例共享的 Redis）
+func SetAlphaVantageQuotaStore(store QuotaStore) {
+	alphaVantageLimiter.SetStore(store)
+}
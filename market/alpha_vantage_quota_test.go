@@ -0,0 +1,82 @@
+package market
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingQuotaStore struct{ calls int }
+
+func (f *failingQuotaStore) Take(string, int, time.Duration) (bool, time.Duration, error) {
+	f.calls++
+	return false, 0, errors.New("redis: connection refused")
+}
+
+func TestMemoryQuotaStoreTokenBucket(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &memoryQuotaStore{buckets: make(map[string]*tokenBucket), now: func() time.Time { return now }}
+
+	for i := 0; i < 5; i++ {
+		if ok, _, _ := store.Take("k", 5, 12*time.Second); !ok {
+			t.Fatalf("token %d should be available", i+1)
+		}
+	}
+	ok, wait, _ := store.Take("k", 5, 12*time.Second)
+	if ok || wait != 12*time.Second {
+		t.Fatalf("expected bucket exhausted with 12s wait, got ok=%v wait=%v", ok, wait)
+	}
+
+	now = now.Add(6 * time.Second)
+	if ok, wait, _ := store.Take("k", 5, 12*time.Second); ok || wait != 6*time.Second {
+		t.Fatalf("expected half refilled token, got ok=%v wait=%v", ok, wait)
+	}
+	now = now.Add(6 * time.Second)
+	if ok, _, _ := store.Take("k", 5, 12*time.Second); !ok {
+		t.Fatal("token should be refilled after 12s")
+	}
+
+	// 不同 key 互不影响
+	if ok, _, _ := store.Take("other", 5, 12*time.Second); !ok {
+		t.Fatal("separate key should have its own bucket")
+	}
+}
+
+func TestQuotaLimiterAcquire(t *testing.T) {
+	limiter := NewQuotaLimiter("test", 1, 10*time.Second, nil)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.fallback.(*memoryQuotaStore).now = func() time.Time { return now }
+	var slept time.Duration
+	limiter.sleep = func(d time.Duration) { slept += d; now = now.Add(d) }
+
+	if err := limiter.Acquire("key", 0); err != nil {
+		t.Fatalf("first acquire should succeed: %v", err)
+	}
+	if err := limiter.Acquire("key", 0); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected ErrQuotaExhausted without waiting, got %v", err)
+	}
+	if err := limiter.Acquire("key", 15*time.Second); err != nil {
+		t.Fatalf("acquire should succeed after waiting: %v", err)
+	}
+	if slept != 10*time.Second {
+		t.Fatalf("expected to wait 10s, waited %v", slept)
+	}
+}
+
+func TestQuotaLimiterFallsBackWhenStoreFails(t *testing.T) {
+	store := &failingQuotaStore{}
+	limiter := NewQuotaLimiter("test", 2, time.Minute, store)
+	limiter.sleep = func(time.Duration) {}
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Acquire("key", 0); err != nil {
+			t.Fatalf("local fallback should grant token %d: %v", i+1, err)
+		}
+	}
+	if err := limiter.Acquire("key", 0); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("local fallback should still enforce the limit, got %v", err)
+	}
+	if store.calls != 3 {
+		t.Fatalf("shared store should be consulted on every acquire, got %d calls", store.calls)
+	}
+}
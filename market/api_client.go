@@ -3,7 +3,6 @@ package market
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"nofx/hook"
@@ -39,13 +38,13 @@ func NewAPIClient() *APIClient {
 
 func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
 	url := fmt.Sprintf("%s/fapi/v1/exchangeInfo", baseURL)
-	resp, err := c.client.Get(url)
+	resp, err := httpGet(c.client, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return nil, err
 	}
@@ -104,13 +103,13 @@ func (c *APIClient) getKlinesAttempt(symbol, interval string, limit int, attempt
 	q.Add("limit", strconv.Itoa(limit))
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := c.client.Do(req)
+	resp, err := doRequest(c.client, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("read response body failed: %w", err)
 	}
@@ -205,13 +204,13 @@ func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
 	q.Add("symbol", symbol)
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := c.client.Do(req)
+	resp, err := doRequest(c.client, req)
 	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return 0, err
 	}
@@ -230,17 +229,61 @@ func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
 	return price, nil
 }
 
+// GetMarkPrices 批量获取合约标记价格：一次请求 premiumIndex 返回全部交易对，再按 symbols 筛选
+// symbols 为空时返回全部；交易所未返回的币种不会出现在结果中
+func (c *APIClient) GetMarkPrices(symbols []string) (map[string]float64, error) {
+	url := fmt.Sprintf("%s/fapi/v1/premiumIndex", baseURL)
+	resp, err := httpGet(c.client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取标记价格失败 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var items []struct {
+		Symbol    string `json:"symbol"`
+		MarkPrice string `json:"markPrice"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[Normalize(symbol)] = true
+	}
+	prices := make(map[string]float64, len(symbols))
+	for _, item := range items {
+		if len(wanted) > 0 && !wanted[item.Symbol] {
+			continue
+		}
+		price, err := strconv.ParseFloat(item.MarkPrice, 64)
+		if err != nil || price <= 0 {
+			continue
+		}
+		prices[item.Symbol] = price
+	}
+	return prices, nil
+}
+
 // GetOpenInterest 获取持仓量（P0修复：用于OI历史数据采集）
 func (c *APIClient) GetOpenInterest(symbol string) (*OIData, error) {
 	url := fmt.Sprintf("%s/fapi/v1/openInterest?symbol=%s", baseURL, symbol)
 
-	resp, err := c.client.Get(url)
+	resp, err := httpGet(c.client, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return nil, err
 	}
@@ -302,13 +345,13 @@ func (c *APIClient) getOpenInterestHistoryAttempt(symbol string, period string,
 	q.Add("limit", strconv.Itoa(limit))
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := c.client.Do(req)
+	resp, err := doRequest(c.client, req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("read response body failed: %w", err)
 	}
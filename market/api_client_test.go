@@ -147,6 +147,36 @@ func BenchmarkGetKlines(b *testing.B) {
 	}
 }
 
+// TestGetMarkPrices 一次请求获取全部标记价格并按币种筛选
+func TestGetMarkPrices(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/fapi/v1/premiumIndex" || r.URL.Query().Get("symbol") != "" {
+			t.Errorf("unexpected request: %s", r.URL.String())
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]string{
+			{"symbol": "BTCUSDT", "markPrice": "65000.5"},
+			{"symbol": "ETHUSDT", "markPrice": "3200.25"},
+			{"symbol": "SOLUSDT", "markPrice": "150"},
+		})
+	}))
+	defer server.Close()
+	setBaseURLForTesting(server.URL)
+	defer setBaseURLForTesting(defaultBaseURL)
+
+	prices, err := NewAPIClient().GetMarkPrices([]string{"btc", "ETHUSDT", "DOGEUSDT"})
+	if err != nil {
+		t.Fatalf("GetMarkPrices failed: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1", requests)
+	}
+	if len(prices) != 2 || prices["BTCUSDT"] != 65000.5 || prices["ETHUSDT"] != 3200.25 {
+		t.Fatalf("unexpected prices: %v", prices)
+	}
+}
+
 type handlerRoundTripper struct {
 	handler http.Handler
 }
@@ -0,0 +1,87 @@
+package market
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// atrCacheMaxTTL ATR 缓存的最长有效期（大周期K线的 ATR 也至少每小时刷新一次）
+const atrCacheMaxTTL = 1 * time.Hour
+
+// atrCacheEntry ATR 缓存项
+type atrCacheEntry struct {
+	ATR       float64
+	UpdatedAt time.Time
+	TTL       time.Duration
+}
+
+// atrCache key: symbol|interval|period
+var atrCache sync.Map
+
+// FetchATR 获取币种在指定K线周期上的 ATR（Wilder 平滑），用于按波动率调整仓位
+// 结果按K线周期缓存（最长 1 小时），同一根K线内重复调用不会再次请求交易所
+func FetchATR(symbol, interval string, period int) (float64, error) {
+	if period <= 0 {
+		return 0, fmt.Errorf("ATR 周期必须大于 0: %d", period)
+	}
+	ttl, err := klineIntervalDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+	if ttl > atrCacheMaxTTL {
+		ttl = atrCacheMaxTTL
+	}
+
+	symbol = Normalize(symbol)
+	key := fmt.Sprintf("%s|%s|%d", symbol, interval, period)
+	if cached, ok := atrCache.Load(key); ok {
+		entry := cached.(*atrCacheEntry)
+		if time.Since(entry.UpdatedAt) < entry.TTL {
+			return entry.ATR, nil
+		}
+	}
+
+	// 取 period 的数倍数据，让 Wilder 平滑收敛
+	limit := period*3 + 1
+	if limit > 1500 {
+		limit = 1500
+	}
+	klines, err := NewAPIClient().GetKlines(symbol, interval, limit)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s %s K线失败: %w", symbol, interval, err)
+	}
+	if len(klines) <= period {
+		return 0, fmt.Errorf("%s %s K线数量不足: %d (需要 > %d)", symbol, interval, len(klines), period)
+	}
+
+	atr := calculateATR(klines, period)
+	atrCache.Store(key, &atrCacheEntry{ATR: atr, UpdatedAt: time.Now(), TTL: ttl})
+	return atr, nil
+}
+
+// klineIntervalDuration 解析币安K线周期（例如 "3m"、"4h"、"1d"、"1w"、"1M"）
+// "1M" 为月线（区分大小写，"m" 为分钟），按 30 天计算，只用于确定缓存有效期
+func klineIntervalDuration(interval string) (time.Duration, error) {
+	interval = strings.TrimSpace(interval)
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("无效的K线周期: %q", interval)
+	}
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的K线周期: %q", interval)
+	}
+	unit := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+		'M': 30 * 24 * time.Hour,
+	}[interval[len(interval)-1]]
+	if unit == 0 {
+		return 0, fmt.Errorf("无效的K线周期: %q", interval)
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,69 @@
+package market
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchATR(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// 每根K线振幅恒为 10，ATR 应为 10
+		rows := make([][]any, 0, 20)
+		for i := 0; i < 20; i++ {
+			open := float64(i*1000) + 1609459200000
+			rows = append(rows, []any{open, "100", "105", "95", "100", "1", open + 59999, "100", float64(1), "0", "0"})
+		}
+		_ = json.NewEncoder(w).Encode(rows)
+	}))
+	defer server.Close()
+	setBaseURLForTesting(server.URL)
+	defer setBaseURLForTesting(defaultBaseURL)
+	atrCache.Delete("ATRTESTUSDT|1h|5")
+
+	atr, err := FetchATR("atrtest", "1h", 5)
+	if err != nil {
+		t.Fatalf("FetchATR failed: %v", err)
+	}
+	if math.Abs(atr-10) > 1e-9 {
+		t.Fatalf("ATR = %v, want 10", atr)
+	}
+
+	// 同一根K线内命中缓存
+	if _, err := FetchATR("ATRTESTUSDT", "1h", 5); err != nil {
+		t.Fatalf("cached FetchATR failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected 1 request, got %d", got)
+	}
+
+	if _, err := FetchATR("ATRTESTUSDT", "1h", 0); err == nil {
+		t.Fatal("expected error for non-positive period")
+	}
+	if _, err := FetchATR("ATRTESTUSDT", "xx", 5); err == nil {
+		t.Fatal("expected error for invalid interval")
+	}
+	if _, err := FetchATR("ATRTESTUSDT", "1h", 50); err == nil {
+		t.Fatal("expected error when klines are insufficient")
+	}
+}
+
+func TestKlineIntervalDuration(t *testing.T) {
+	cases := map[string]time.Duration{"3m": 3 * time.Minute, "4h": 4 * time.Hour, "1d": 24 * time.Hour, "1w": 7 * 24 * time.Hour, "1M": 30 * 24 * time.Hour}
+	for interval, want := range cases {
+		if got, err := klineIntervalDuration(interval); err != nil || got != want {
+			t.Errorf("%s = %v (%v), want %v", interval, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "m", "0h", "5x", "0M"} {
+		if _, err := klineIntervalDuration(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyedDataSource 按 key 读取的上游数据源（多空比、市场情绪等）
+// 与 K 线数据源 DataSource 不同，这里只关心 Fetch(key) -> value，便于统一加缓存和在测试中替换
+type KeyedDataSource[T any] interface {
+	Fetch(key string) (T, error)
+}
+
+// DataSourceFunc 将普通函数适配为 KeyedDataSource
+type DataSourceFunc[T any] func(key string) (T, error)
+
+// Fetch 调用函数本身
+func (f DataSourceFunc[T]) Fetch(key string) (T, error) { return f(key) }
+
+// Cache 缓存后端，值以 JSON 存储，便于替换为 Redis 等跨实例共享的实现
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+type memoryCacheItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache 进程内缓存（默认后端）
+type MemoryCache struct {
+	mu    sync.RWMutex
+	items map[string]memoryCacheItem
+	now   func() time.Time
+}
+
+// NewMemoryCache 创建进程内缓存
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryCacheItem), now: time.Now}
+}
+
+// Get 读取未过期的缓存值
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(item.expiresAt) {
+		c.mu.Lock()
+		delete(c.items, key)
+		c.mu.Unlock()
+		return nil, false
+	}
+	return item.value, true
+}
+
+// Set 写入缓存值
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	c.items[key] = memoryCacheItem{value: value, expiresAt: c.now().Add(ttl)}
+	c.mu.Unlock()
+}
+
+// CacheStats 缓存命中统计
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Errors  int64 `json:"errors"`  // 上游请求失败次数（重试后仍失败）
+	Retries int64 `json:"retries"` // 上游失败后的重试次数
+}
+
+// CachingDataSource 读穿缓存装饰器：命中直接返回，未命中请求上游并写回缓存
+// 上游失败时按 WithRetry 的配置有限次重试，仍失败则不写缓存，下次调用会重新请求
+type CachingDataSource[T any] struct {
+	name   string
+	source KeyedDataSource[T]
+	ttl    time.Duration
+
+	mu    sync.RWMutex
+	cache Cache
+
+	keyFunc     func(key string) string
+	maxAttempts int
+	retryDelay  time.Duration
+	sleep       func(time.Duration)
+
+	hits    atomic.Int64
+	misses  atomic.Int64
+	errors  atomic.Int64
+	retries atomic.Int64
+}
+
+// NewCachingDataSource 创建读穿缓存数据源，name 用作缓存 key 前缀
+func NewCachingDataSource[T any](name string, source KeyedDataSource[T], cache Cache, ttl time.Duration) *CachingDataSource[T] {
+	if cache == nil {
+		cache = NewMemoryCache()
+	}
+	return &CachingDataSource[T]{name: name, source: source, cache: cache, ttl: ttl, maxAttempts: 1, sleep: time.Sleep}
+}
+
+// WithCacheKey 设置由 Fetch 的 key 生成缓存 key 的方式（默认直接使用 key）
+// key 为 API Key 等凭证时应按数据提供方生成缓存 key，避免凭证留在缓存中、更换凭证后缓存失效
+func (s *CachingDataSource[T]) WithCacheKey(fn func(key string) string) *CachingDataSource[T] {
+	s.keyFunc = fn
+	return s
+}
+
+// WithRetry 上游失败时最多请求 attempts 次（含首次），第 n 次重试前等待 base*n（±50% 随机抖动）
+func (s *CachingDataSource[T]) WithRetry(attempts int, base time.Duration) *CachingDataSource[T] {
+	if attempts < 1 {
+		attempts = 1
+	}
+	s.maxAttempts = attempts
+	s.retryDelay = base
+	return s
+}
+
+// SetCache 替换缓存后端
+func (s *CachingDataSource[T]) SetCache(cache Cache) {
+	s.mu.Lock()
+	s.cache = cache
+	s.mu.Unlock()
+}
+
+// Fetch 读取数据（优先缓存）
+func (s *CachingDataSource[T]) Fetch(key string) (T, error) {
+	s.mu.RLock()
+	cache := s.cache
+	s.mu.RUnlock()
+
+	cacheKey := key
+	if s.keyFunc != nil {
+		cacheKey = s.keyFunc(key)
+	}
+	cacheKey = s.name + ":" + cacheKey
+	if raw, ok := cache.Get(cacheKey); ok {
+		var value T
+		if err := json.Unmarshal(raw, &value); err == nil {
+			s.hits.Add(1)
+			return value, nil
+		}
+		log.Printf("⚠️ [%s] 缓存数据解析失败，回源请求: %s", s.name, cacheKey)
+	}
+	s.misses.Add(1)
+
+	value, err := s.fetchWithRetry(key)
+	if err != nil {
+		s.errors.Add(1)
+		return value, err
+	}
+	if raw, err := json.Marshal(value); err == nil {
+		cache.Set(cacheKey, raw, s.ttl)
+	}
+	return value, nil
+}
+
+// fetchWithRetry 请求上游，失败时按退避有限次重试
+func (s *CachingDataSource[T]) fetchWithRetry(key string) (T, error) {
+	var value T
+	var err error
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		value, err = s.source.Fetch(key)
+		if err == nil || attempt == s.maxAttempts {
+			break
+		}
+		s.retries.Add(1)
+		backoff := jitteredBackoff(s.retryDelay, attempt)
+		log.Printf("⚠️ [%s] 上游请求失败（第 %d/%d 次）: %v，%v 后重试", s.name, attempt, s.maxAttempts, err, backoff)
+		s.sleep(backoff)
+	}
+	return value, err
+}
+
+// Stats 返回缓存命中统计
+func (s *CachingDataSource[T]) Stats() CacheStats {
+	return CacheStats{Hits: s.hits.Load(), Misses: s.misses.Load(), Errors: s.errors.Load(), Retries: s.retries.Load()}
+}
+
+// String 便于日志输出
+func (s *CachingDataSource[T]) String() string {
+	st := s.Stats()
+	return fmt.Sprintf("%s(hits=%d misses=%d errors=%d retries=%d)", s.name, st.Hits, st.Misses, st.Errors, st.Retries)
+}
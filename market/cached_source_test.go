@@ -0,0 +1,115 @@
+package market
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingDataSource_ReadThrough(t *testing.T) {
+	calls := map[string]int{}
+	source := DataSourceFunc[float64](func(key string) (float64, error) {
+		calls[key]++
+		if key == "FAIL" {
+			return 0, errors.New("upstream down")
+		}
+		return 1.25, nil
+	})
+
+	cache := NewMemoryCache()
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+	cached := NewCachingDataSource[float64]("ratio", source, cache, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		v, err := cached.Fetch("BTCUSDT")
+		require.NoError(t, err)
+		assert.Equal(t, 1.25, v)
+	}
+	assert.Equal(t, 1, calls["BTCUSDT"], "命中缓存时不应请求上游")
+
+	// 失败结果不缓存
+	_, err := cached.Fetch("FAIL")
+	require.Error(t, err)
+	_, err = cached.Fetch("FAIL")
+	require.Error(t, err)
+	assert.Equal(t, 2, calls["FAIL"])
+
+	// 过期后回源
+	now = now.Add(2 * time.Minute)
+	_, err = cached.Fetch("BTCUSDT")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls["BTCUSDT"])
+
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 4, Errors: 2}, cached.Stats())
+}
+
+func TestCachingDataSource_PointerValues(t *testing.T) {
+	calls := 0
+	source := DataSourceFunc[*MarketSentiment](func(key string) (*MarketSentiment, error) {
+		calls++
+		return &MarketSentiment{VIX: 18.5, FearLevel: "moderate", USMarket: &USMarketStatus{IsOpen: true}}, nil
+	})
+	cached := NewCachingDataSource[*MarketSentiment]("sentiment", source, nil, time.Minute)
+
+	first, err := cached.Fetch("")
+	require.NoError(t, err)
+	second, err := cached.Fetch("")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, first.VIX, second.VIX)
+	assert.True(t, second.USMarket.IsOpen)
+	assert.NotSame(t, first, second, "缓存返回副本，调用方修改不影响缓存内容")
+}
+
+func TestCachingDataSource_CacheKeyFunc(t *testing.T) {
+	calls := 0
+	source := DataSourceFunc[*MarketSentiment](func(key string) (*MarketSentiment, error) {
+		calls++
+		return &MarketSentiment{VIX: 20}, nil
+	})
+	cache := NewMemoryCache()
+	cached := NewCachingDataSource[*MarketSentiment]("sentiment", source, cache, time.Minute).WithCacheKey(sentimentProviderKey)
+
+	_, err := cached.Fetch("av-key-old")
+	require.NoError(t, err)
+	_, err = cached.Fetch("av-key-new")
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "更换 API Key 后仍命中同一提供方的缓存")
+
+	for key := range cache.items {
+		assert.NotContains(t, key, "av-key", "缓存 key 不应包含 API Key")
+	}
+}
+
+func TestCachingDataSource_Retry(t *testing.T) {
+	calls := 0
+	source := DataSourceFunc[float64](func(key string) (float64, error) {
+		calls++
+		if key == "FAIL" || calls < 3 {
+			return 0, errors.New("upstream down")
+		}
+		return 1.5, nil
+	})
+	var slept []time.Duration
+	cached := NewCachingDataSource[float64]("ratio", source, nil, time.Minute).WithRetry(3, 100*time.Millisecond)
+	cached.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	v, err := cached.Fetch("BTCUSDT")
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, v)
+	assert.Equal(t, 3, calls)
+	require.Len(t, slept, 2)
+	assert.InDelta(t, float64(200*time.Millisecond), float64(slept[1]), float64(100*time.Millisecond), "第 2 次重试等待 base*2（±50%）")
+
+	// 重试次数有上限，仍失败时返回错误且不写缓存
+	calls = 0
+	_, err = cached.Fetch("FAIL")
+	require.Error(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, CacheStats{Misses: 2, Errors: 1, Retries: 4}, cached.Stats())
+}
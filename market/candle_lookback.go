@@ -0,0 +1,69 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KlineCacheSize 每个时间线缓存的K线数量（WSMonitor 初始化、动态订阅和 API 回退都按该数量请求）
+// Binance 单次最多返回 1500 根，但喂给 AI 的序列只能取自缓存，因此回看数量以缓存大小为上限
+const KlineCacheSize = 100
+
+// DefaultCandleLookback 各时间线默认传给 AI 的K线数量
+// 日内/中期/4h 序列取最近 10 根，日线取全部缓存
+var DefaultCandleLookback = map[string]int{
+	"1m":  10,
+	"3m":  10,
+	"5m":  10,
+	"15m": 10,
+	"1h":  10,
+	"4h":  10,
+	"1d":  KlineCacheSize,
+}
+
+// candleLookbackFor 返回指定时间线的回看数量，未配置时使用默认值
+func candleLookbackFor(lookback map[string]int, timeframe string) int {
+	if n, ok := lookback[timeframe]; ok && n > 0 {
+		return n
+	}
+	if n, ok := DefaultCandleLookback[timeframe]; ok {
+		return n
+	}
+	return 10
+}
+
+// ValidateCandleLookback 校验回看配置：时间线必须受支持，数量在 1 到 KlineCacheSize 之间
+func ValidateCandleLookback(lookback map[string]int) error {
+	timeframes := make([]string, 0, len(lookback))
+	for tf := range lookback {
+		timeframes = append(timeframes, tf)
+	}
+	sort.Strings(timeframes)
+	for _, tf := range timeframes {
+		if _, ok := DefaultCandleLookback[tf]; !ok {
+			return fmt.Errorf("不支持的时间线: %s", tf)
+		}
+		if n := lookback[tf]; n < 1 || n > KlineCacheSize {
+			return fmt.Errorf("%s K线回看数量必须在 1-%d 之间: %d", tf, KlineCacheSize, n)
+		}
+	}
+	return nil
+}
+
+// ParseCandleLookback 解析 JSON 格式的回看配置（例如 {"4h":30,"1m":5}），空字符串返回 nil
+func ParseCandleLookback(raw string) (map[string]int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var lookback map[string]int
+	if err := json.Unmarshal([]byte(raw), &lookback); err != nil {
+		return nil, fmt.Errorf("K线回看配置格式错误: %w", err)
+	}
+	if err := ValidateCandleLookback(lookback); err != nil {
+		return nil, err
+	}
+	return lookback, nil
+}
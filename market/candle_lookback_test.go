@@ -0,0 +1,42 @@
+package market
+
+import "testing"
+
+func TestCandleLookbackFor(t *testing.T) {
+	lookback := map[string]int{"4h": 30}
+	if got := candleLookbackFor(lookback, "4h"); got != 30 {
+		t.Fatalf("configured 4h = %d, want 30", got)
+	}
+	if got := candleLookbackFor(lookback, "3m"); got != 10 {
+		t.Fatalf("default 3m = %d, want 10", got)
+	}
+	if got := candleLookbackFor(nil, "1d"); got != KlineCacheSize {
+		t.Fatalf("default 1d = %d, want %d", got, KlineCacheSize)
+	}
+}
+
+func TestParseCandleLookback(t *testing.T) {
+	if lookback, err := ParseCandleLookback(""); err != nil || lookback != nil {
+		t.Fatalf("empty config should be nil, got %v (%v)", lookback, err)
+	}
+	lookback, err := ParseCandleLookback(`{"1h":20,"1d":60}`)
+	if err != nil || lookback["1h"] != 20 || lookback["1d"] != 60 {
+		t.Fatalf("unexpected result: %v (%v)", lookback, err)
+	}
+	for _, raw := range []string{`{"2h":10}`, `{"1m":0}`, `{"1m":101}`, `[1]`} {
+		if _, err := ParseCandleLookback(raw); err == nil {
+			t.Errorf("%s: expected error", raw)
+		}
+	}
+}
+
+func TestCalculateSeriesHonorsLookback(t *testing.T) {
+	klines := make([]Kline, 50)
+	for i := range klines {
+		p := float64(100 + i)
+		klines[i] = Kline{Open: p, High: p + 1, Low: p - 1, Close: p, Volume: 1000}
+	}
+	if got := calculateIntradaySeries(klines, 25); len(got.MidPrices) != 25 || got.MidPrices[24] != 149 {
+		t.Fatalf("expected last 25 closes, got %d (%v)", len(got.MidPrices), got.MidPrices)
+	}
+}
@@ -39,7 +39,7 @@ func (c *CombinedStreamsClient) Connect() error {
 	}
 
 	// 组合流使用不同的端点
-	conn, _, err := dialer.Dial("wss://fstream.binance.com/stream", nil)
+	conn, _, err := dialer.Dial("wss://fstream.binance.com/stream", marketHeaders())
 	if err != nil {
 		return fmt.Errorf("组合流WebSocket连接失败: %v", err)
 	}
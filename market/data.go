@@ -3,7 +3,6 @@ package market
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math"
 	"net/http"
@@ -28,7 +27,17 @@ var (
 // Get 获取指定代币的市场数据（支持动态时间线选择）
 // timeframes: 可选参数，指定需要获取的时间线列表，如 []string{"1m", "15m", "1h", "4h"}
 // 如果为空或nil，默认使用 ["15m", "1h", "4h"]
+//
+// 保持不内联：测试通过 gomonkey 替换 Get，内联后补丁不会生效
+//
+//go:noinline
 func Get(symbol string, timeframes []string) (*Data, error) {
+	return GetWithLookback(symbol, timeframes, nil)
+}
+
+// GetWithLookback 与 Get 相同，但按 lookback（时间线 -> K线数量）控制各时间线序列的长度
+// lookback 中未配置的时间线使用 DefaultCandleLookback
+func GetWithLookback(symbol string, timeframes []string, lookback map[string]int) (*Data, error) {
 	var klines1m, klines3m, klines5m, klines15m, klines1h, klines4h, klines1d []Kline
 	var err error
 	// 标准化symbol
@@ -191,31 +200,31 @@ func Get(symbol string, timeframes []string) (*Data, error) {
 
 	// 计算日内系列数据 (1m/3m/5m)
 	if len(klines1m) > 0 {
-		intradayData = calculateIntradaySeries(klines1m)
+		intradayData = calculateIntradaySeries(klines1m, candleLookbackFor(lookback, "1m"))
 	} else if len(klines3m) > 0 {
-		intradayData = calculateIntradaySeries(klines3m)
+		intradayData = calculateIntradaySeries(klines3m, candleLookbackFor(lookback, "3m"))
 	} else if len(klines5m) > 0 {
-		intradayData = calculateIntradaySeries(klines5m)
+		intradayData = calculateIntradaySeries(klines5m, candleLookbackFor(lookback, "5m"))
 	}
 
 	// 计算15分钟系列数据（如果用户选择了15m）
 	if len(klines15m) > 0 {
-		midTermData15m = calculateMidTermSeries15m(klines15m)
+		midTermData15m = calculateMidTermSeries15m(klines15m, candleLookbackFor(lookback, "15m"))
 	}
 
 	// 计算1小时系列数据（如果用户选择了1h）
 	if len(klines1h) > 0 {
-		midTermData1h = calculateMidTermSeries1h(klines1h)
+		midTermData1h = calculateMidTermSeries1h(klines1h, candleLookbackFor(lookback, "1h"))
 	}
 
 	// 计算长期数据 (4小时，如果用户选择了4h)
 	if len(klines4h) > 0 {
-		longerTermData = calculateLongerTermData(klines4h)
+		longerTermData = calculateLongerTermData(klines4h, candleLookbackFor(lookback, "4h"))
 	}
 
 	// 计算日线数据（如果用户选择了1d）
 	if len(klines1d) > 0 {
-		dailyData = calculateDailyData(klines1d)
+		dailyData = calculateDailyData(klines1d, candleLookbackFor(lookback, "1d"))
 	}
 
 	return &Data{
@@ -351,18 +360,18 @@ func calculateATR(klines []Kline, period int) float64 {
 }
 
 // calculateIntradaySeries 计算日内系列数据
-func calculateIntradaySeries(klines []Kline) *IntradayData {
+func calculateIntradaySeries(klines []Kline, points int) *IntradayData {
 	data := &IntradayData{
-		MidPrices:   make([]float64, 0, 10),
-		EMA20Values: make([]float64, 0, 10),
-		MACDValues:  make([]float64, 0, 10),
-		RSI7Values:  make([]float64, 0, 10),
-		RSI14Values: make([]float64, 0, 10),
-		Volume:      make([]float64, 0, 10),
+		MidPrices:   make([]float64, 0, points),
+		EMA20Values: make([]float64, 0, points),
+		MACDValues:  make([]float64, 0, points),
+		RSI7Values:  make([]float64, 0, points),
+		RSI14Values: make([]float64, 0, points),
+		Volume:      make([]float64, 0, points),
 	}
 
-	// 获取最近10个数据点
-	start := len(klines) - 10
+	// 获取最近 points 个数据点
+	start := len(klines) - points
 	if start < 0 {
 		start = 0
 	}
@@ -401,17 +410,17 @@ func calculateIntradaySeries(klines []Kline) *IntradayData {
 }
 
 // calculateMidTermSeries15m 计算15分钟系列数据
-func calculateMidTermSeries15m(klines []Kline) *MidTermData15m {
+func calculateMidTermSeries15m(klines []Kline, points int) *MidTermData15m {
 	data := &MidTermData15m{
-		MidPrices:   make([]float64, 0, 10),
-		EMA20Values: make([]float64, 0, 10),
-		MACDValues:  make([]float64, 0, 10),
-		RSI7Values:  make([]float64, 0, 10),
-		RSI14Values: make([]float64, 0, 10),
+		MidPrices:   make([]float64, 0, points),
+		EMA20Values: make([]float64, 0, points),
+		MACDValues:  make([]float64, 0, points),
+		RSI7Values:  make([]float64, 0, points),
+		RSI14Values: make([]float64, 0, points),
 	}
 
-	// 获取最近10个数据点
-	start := len(klines) - 10
+	// 获取最近 points 个数据点
+	start := len(klines) - points
 	if start < 0 {
 		start = 0
 	}
@@ -446,17 +455,17 @@ func calculateMidTermSeries15m(klines []Kline) *MidTermData15m {
 }
 
 // calculateMidTermSeries1h 计算1小时系列数据
-func calculateMidTermSeries1h(klines []Kline) *MidTermData1h {
+func calculateMidTermSeries1h(klines []Kline, points int) *MidTermData1h {
 	data := &MidTermData1h{
-		MidPrices:   make([]float64, 0, 10),
-		EMA20Values: make([]float64, 0, 10),
-		MACDValues:  make([]float64, 0, 10),
-		RSI7Values:  make([]float64, 0, 10),
-		RSI14Values: make([]float64, 0, 10),
+		MidPrices:   make([]float64, 0, points),
+		EMA20Values: make([]float64, 0, points),
+		MACDValues:  make([]float64, 0, points),
+		RSI7Values:  make([]float64, 0, points),
+		RSI14Values: make([]float64, 0, points),
 	}
 
-	// 获取最近10个数据点
-	start := len(klines) - 10
+	// 获取最近 points 个数据点
+	start := len(klines) - points
 	if start < 0 {
 		start = 0
 	}
@@ -491,10 +500,10 @@ func calculateMidTermSeries1h(klines []Kline) *MidTermData1h {
 }
 
 // calculateLongerTermData 计算长期数据
-func calculateLongerTermData(klines []Kline) *LongerTermData {
+func calculateLongerTermData(klines []Kline, points int) *LongerTermData {
 	data := &LongerTermData{
-		MACDValues:  make([]float64, 0, 10),
-		RSI14Values: make([]float64, 0, 10),
+		MACDValues:  make([]float64, 0, points),
+		RSI14Values: make([]float64, 0, points),
 	}
 
 	// 计算EMA
@@ -516,8 +525,8 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 		data.AverageVolume = sum / float64(len(klines))
 	}
 
-	// 计算MACD和RSI序列
-	start := len(klines) - 10
+	// 计算MACD和RSI序列（最近 points 个数据点）
+	start := len(klines) - points
 	if start < 0 {
 		start = 0
 	}
@@ -537,19 +546,23 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 }
 
 // calculateDailyData 计算日线数据
-func calculateDailyData(klines []Kline) *DailyData {
+func calculateDailyData(klines []Kline, points int) *DailyData {
 	data := &DailyData{
-		MidPrices:   make([]float64, 0, 90),
-		EMA20Values: make([]float64, 0, 90),
-		EMA50Values: make([]float64, 0, 90),
-		MACDValues:  make([]float64, 0, 90),
-		RSI14Values: make([]float64, 0, 90),
-		ATR14Values: make([]float64, 0, 90),
-		Volume:      make([]float64, 0, 90),
+		MidPrices:   make([]float64, 0, points),
+		EMA20Values: make([]float64, 0, points),
+		EMA50Values: make([]float64, 0, points),
+		MACDValues:  make([]float64, 0, points),
+		RSI14Values: make([]float64, 0, points),
+		ATR14Values: make([]float64, 0, points),
+		Volume:      make([]float64, 0, points),
 	}
 
-	// 获取全部数据点（最多90个）
-	for i := 0; i < len(klines); i++ {
+	// 获取最近 points 个数据点
+	start := len(klines) - points
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(klines); i++ {
 		data.MidPrices = append(data.MidPrices, klines[i].Close)
 		data.Volume = append(data.Volume, klines[i].Volume)
 
@@ -623,13 +636,13 @@ func getOpenInterestData(symbol string) (*OIData, error) {
 	// ⚠️ 降级：缓存不存在时才调用 API（仅冷启动或缓存失效）
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/openInterest?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := httpGet(http.DefaultClient, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return nil, err
 	}
@@ -688,13 +701,13 @@ func getFundingRate(symbol string) (float64, error) {
 	// ⚠️ 缓存过期或不存在，调用 API
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := httpGet(http.DefaultClient, url)
 	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return 0, err
 	}
@@ -22,6 +22,7 @@ type DataSourceStatus struct {
 	Healthy       bool          // 是否健康
 	Latency       time.Duration // 延迟
 	LastCheckTime time.Time     // 最后检查时间
+	LastSuccess   time.Time     // 最后一次成功（健康检查或数据请求）时间
 	FailureCount  int           // 连续失败次数
 	SuccessCount  int           // 总成功次数
 	TotalRequests int           // 总请求次数
@@ -117,6 +118,7 @@ func (dsm *DataSourceManager) performHealthCheck() {
 			status.FailureCount = 0
 			status.Latency = latency
 			status.SuccessCount++
+			status.LastSuccess = status.LastCheckTime
 			log.Printf("✅ 数据源 %s 健康检查成功 (延迟: %v)",
 				source.GetName(), latency)
 		}
@@ -190,6 +192,9 @@ func (dsm *DataSourceManager) GetKlinesWithFallback(symbol, interval string, lim
 
 		dsm.mu.Lock()
 		status.TotalRequests++
+		if err == nil && len(klines) > 0 {
+			status.LastSuccess = time.Now()
+		}
 		dsm.mu.Unlock()
 
 		if err == nil && len(klines) > 0 {
@@ -230,6 +235,9 @@ func (dsm *DataSourceManager) GetTickerWithFallback(symbol string) (*Ticker, err
 
 		dsm.mu.Lock()
 		status.TotalRequests++
+		if err == nil && ticker != nil {
+			status.LastSuccess = time.Now()
+		}
 		dsm.mu.Unlock()
 
 		if err == nil && ticker != nil {
@@ -255,6 +263,7 @@ func (dsm *DataSourceManager) GetStatus() map[string]*DataSourceStatus {
 			Healthy:       status.Healthy,
 			Latency:       status.Latency,
 			LastCheckTime: status.LastCheckTime,
+			LastSuccess:   status.LastSuccess,
 			FailureCount:  status.FailureCount,
 			SuccessCount:  status.SuccessCount,
 			TotalRequests: status.TotalRequests,
@@ -0,0 +1,126 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sonirico/go-hyperliquid"
+)
+
+// defaultSymbolCatalogTTL 交易所可交易币种列表的缓存时长
+const defaultSymbolCatalogTTL = time.Hour
+
+// SymbolFetcher 获取交易所可交易币种列表（统一为 Binance 格式，例如 BTCUSDT）
+type SymbolFetcher func(testnet bool) (map[string]struct{}, error)
+
+type symbolCatalogEntry struct {
+	symbols   map[string]struct{}
+	fetchedAt time.Time
+}
+
+// SymbolCatalog 按交易所缓存可交易币种列表，用于保存交易员配置时校验 trading_symbols
+// 没有注册获取函数的交易所不做校验
+type SymbolCatalog struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	fetchers map[string]SymbolFetcher
+	entries  map[string]symbolCatalogEntry
+	now      func() time.Time
+}
+
+// NewSymbolCatalog 创建币种目录，默认注册 Binance 和 Hyperliquid
+func NewSymbolCatalog(ttl time.Duration) *SymbolCatalog {
+	if ttl <= 0 {
+		ttl = defaultSymbolCatalogTTL
+	}
+	c := &SymbolCatalog{
+		ttl:      ttl,
+		fetchers: make(map[string]SymbolFetcher),
+		entries:  make(map[string]symbolCatalogEntry),
+		now:      time.Now,
+	}
+	c.Register("binance", fetchBinanceSymbols)
+	c.Register("hyperliquid", fetchHyperliquidSymbols)
+	return c
+}
+
+// Register 注册（或替换）交易所的币种获取函数
+func (c *SymbolCatalog) Register(exchange string, fetcher SymbolFetcher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetchers[strings.ToLower(exchange)] = fetcher
+}
+
+// UnsupportedSymbols 返回 symbols 中在该交易所不可交易的币种（规范化为 Binance 格式）
+// 交易所未注册获取函数时返回 nil；获取币种列表失败时返回错误，由调用方决定是否放行
+func (c *SymbolCatalog) UnsupportedSymbols(exchange string, testnet bool, symbols []string) ([]string, error) {
+	exchange = strings.ToLower(exchange)
+	c.mu.Lock()
+	fetcher, ok := c.fetchers[exchange]
+	key := fmt.Sprintf("%s:%t", exchange, testnet)
+	entry, cached := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	if !cached || c.now().Sub(entry.fetchedAt) > c.ttl {
+		listed, err := fetcher(testnet)
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 可交易币种失败: %w", exchange, err)
+		}
+		entry = symbolCatalogEntry{symbols: listed, fetchedAt: c.now()}
+		c.mu.Lock()
+		c.entries[key] = entry
+		c.mu.Unlock()
+	}
+
+	var unsupported []string
+	for _, symbol := range symbols {
+		symbol = Normalize(strings.TrimSpace(symbol))
+		if _, ok := entry.symbols[symbol]; !ok {
+			unsupported = append(unsupported, symbol)
+		}
+	}
+	return unsupported, nil
+}
+
+// fetchBinanceSymbols 获取 Binance U本位合约中状态为 TRADING 的币种
+func fetchBinanceSymbols(testnet bool) (map[string]struct{}, error) {
+	info, err := NewAPIClient().GetExchangeInfo()
+	if err != nil {
+		return nil, err
+	}
+	symbols := make(map[string]struct{}, len(info.Symbols))
+	for _, s := range info.Symbols {
+		if s.Status == "TRADING" {
+			symbols[s.Symbol] = struct{}{}
+		}
+	}
+	return symbols, nil
+}
+
+// fetchHyperliquidSymbols 获取 Hyperliquid 永续合约币种（BTC -> BTCUSDT，已下架的除外）
+func fetchHyperliquidSymbols(testnet bool) (map[string]struct{}, error) {
+	baseURL := hyperliquid.MainnetAPIURL
+	if testnet {
+		baseURL = hyperliquid.TestnetAPIURL
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	meta, err := hyperliquid.NewInfo(ctx, baseURL, true, nil, nil).Meta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make(map[string]struct{}, len(meta.Universe))
+	for _, asset := range meta.Universe {
+		if !asset.IsDelisted {
+			symbols[Normalize(asset.Name)] = struct{}{}
+		}
+	}
+	return symbols, nil
+}
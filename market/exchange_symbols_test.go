@@ -0,0 +1,47 @@
+package market
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSymbolCatalog_CachesAndExpires(t *testing.T) {
+	catalog := NewSymbolCatalog(time.Minute)
+	now := time.Unix(1700000000, 0)
+	catalog.now = func() time.Time { return now }
+
+	calls := 0
+	var fetchErr error
+	catalog.Register("hyperliquid", func(testnet bool) (map[string]struct{}, error) {
+		calls++
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return map[string]struct{}{"BTCUSDT": {}}, nil
+	})
+
+	unsupported, err := catalog.UnsupportedSymbols("Hyperliquid", false, []string{"btc", " ETHUSDT"})
+	if err != nil || len(unsupported) != 1 || unsupported[0] != "ETHUSDT" {
+		t.Fatalf("unexpected result: %v (%v)", unsupported, err)
+	}
+	catalog.UnsupportedSymbols("hyperliquid", false, []string{"BTCUSDT"})
+	if calls != 1 {
+		t.Fatalf("expected cached list to be reused, got %d fetches", calls)
+	}
+	// testnet 单独缓存
+	catalog.UnsupportedSymbols("hyperliquid", true, []string{"BTCUSDT"})
+	if calls != 2 {
+		t.Fatalf("expected separate fetch for testnet, got %d fetches", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	fetchErr = errors.New("timeout")
+	if _, err := catalog.UnsupportedSymbols("hyperliquid", false, []string{"BTCUSDT"}); err == nil {
+		t.Fatal("expected fetch error after cache expiry")
+	}
+
+	if unsupported, err := catalog.UnsupportedSymbols("aster", false, []string{"ANYUSDT"}); err != nil || unsupported != nil {
+		t.Fatalf("expected unregistered exchange to be skipped, got %v (%v)", unsupported, err)
+	}
+}
@@ -0,0 +1,86 @@
+package market
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding 市场数据请求声明支持的压缩格式
+// 注意：显式设置 Accept-Encoding 后 net/http 不再自动解压，由 readResponseBody 负责
+const acceptEncoding = "gzip, deflate"
+
+// httpGet 发送带 Accept-Encoding 的 GET 请求
+func httpGet(client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return doRequest(client, req)
+}
+
+// doRequest 设置 Accept-Encoding 和通用请求头（User-Agent 等）后发送请求
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	applyMarketHeaders(req)
+	return client.Do(req)
+}
+
+// readResponseBody 读取响应体，按 Content-Encoding 透明解压 gzip/deflate
+// 某些代理会强制压缩但不设置响应头，因此未声明编码时也会嗅探 gzip 魔数
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	br := bufio.NewReader(resp.Body)
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		return readGzip(br)
+	case "deflate":
+		return readDeflate(br)
+	case "", "identity":
+		if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+			return readGzip(br)
+		}
+		return io.ReadAll(br)
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding: %s", encoding)
+	}
+}
+
+func readGzip(r io.Reader) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip decode failed: %w", err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// readDeflate 解压 deflate 响应
+// HTTP 规范要求 zlib 封装，但不少服务端直接发送原始 deflate 流，两者都支持
+func readDeflate(r io.Reader) ([]byte, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if zr, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+		defer zr.Close()
+		return io.ReadAll(zr)
+	}
+	fr := flate.NewReader(bytes.NewReader(raw))
+	defer fr.Close()
+	body, err := io.ReadAll(fr)
+	if err != nil {
+		return nil, fmt.Errorf("deflate decode failed: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,76 @@
+package market
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func compressBody(t *testing.T, encoding string, payload []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	switch encoding {
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		w.Write(payload)
+		w.Close()
+	case "zlib":
+		w := zlib.NewWriter(&buf)
+		w.Write(payload)
+		w.Close()
+	case "flate":
+		w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+		w.Write(payload)
+		w.Close()
+	default:
+		buf.Write(payload)
+	}
+	return buf.Bytes()
+}
+
+// TestGetCurrentPriceCompressedBodies 验证压缩响应在解析前被透明解压
+func TestGetCurrentPriceCompressedBodies(t *testing.T) {
+	payload := []byte(`{"symbol":"BTCUSDT","price":"65000.5"}`)
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		compression     string
+	}{
+		{"plain", "", ""},
+		{"gzip", "gzip", "gzip"},
+		{"deflate zlib-wrapped", "deflate", "zlib"},
+		{"deflate raw", "deflate", "flate"},
+		{"gzip without header", "", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != acceptEncoding {
+					t.Errorf("Accept-Encoding = %q, want %q", got, acceptEncoding)
+				}
+				if tt.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", tt.contentEncoding)
+				}
+				w.Write(compressBody(t, tt.compression, payload))
+			}))
+			defer server.Close()
+
+			setBaseURLForTesting(server.URL)
+			defer setBaseURLForTesting(defaultBaseURL)
+
+			price, err := NewAPIClient().GetCurrentPrice("BTCUSDT")
+			if err != nil {
+				t.Fatalf("GetCurrentPrice failed: %v", err)
+			}
+			if price != 65000.5 {
+				t.Fatalf("price = %v, want 65000.5", price)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package market
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// defaultMarketUserAgent 默认 User-Agent
+// 部分 CDN/WAF 会拦截 Go 默认的 "Go-http-client/1.1"，因此使用浏览器兼容格式
+const defaultMarketUserAgent = "Mozilla/5.0 (compatible; nofx-market/1.0)"
+
+// marketUserAgent 市场数据请求使用的 User-Agent（环境变量 MARKET_USER_AGENT 可覆盖）
+func marketUserAgent() string {
+	if ua := strings.TrimSpace(os.Getenv("MARKET_USER_AGENT")); ua != "" {
+		return ua
+	}
+	return defaultMarketUserAgent
+}
+
+// marketHeaders 所有市场数据请求（HTTP 与 WebSocket 握手）共用的请求头
+// 新增通用请求头时只需修改这里
+func marketHeaders() http.Header {
+	header := http.Header{}
+	header.Set("User-Agent", marketUserAgent())
+	return header
+}
+
+// applyMarketHeaders 为请求设置通用请求头，调用方已显式设置的同名请求头保持不变
+func applyMarketHeaders(req *http.Request) {
+	for name, values := range marketHeaders() {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+}
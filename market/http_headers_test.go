@@ -0,0 +1,46 @@
+package market
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMarketRequestsSendUserAgent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	resp, err := httpGet(nil, server.URL)
+	if err != nil {
+		t.Fatalf("httpGet failed: %v", err)
+	}
+	resp.Body.Close()
+	if got != defaultMarketUserAgent {
+		t.Fatalf("default User-Agent = %q, want %q", got, defaultMarketUserAgent)
+	}
+
+	t.Setenv("MARKET_USER_AGENT", "custom-agent/2.0")
+	resp, err = httpGet(nil, server.URL)
+	if err != nil {
+		t.Fatalf("httpGet failed: %v", err)
+	}
+	resp.Body.Close()
+	if got != "custom-agent/2.0" {
+		t.Fatalf("User-Agent = %q, want custom-agent/2.0", got)
+	}
+
+	// 调用方显式设置的请求头不被覆盖
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("User-Agent", "explicit")
+	resp, err = doRequest(nil, req)
+	if err != nil {
+		t.Fatalf("doRequest failed: %v", err)
+	}
+	resp.Body.Close()
+	if got != "explicit" {
+		t.Fatalf("explicit User-Agent overwritten: %q", got)
+	}
+}
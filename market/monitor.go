@@ -175,7 +175,7 @@ func (m *WSMonitor) initializeHistoricalData() error {
 				}
 
 				for retry := 0; retry < maxRetries; retry++ {
-					klines, err = apiClient.GetKlines(s, tf, 100)
+					klines, err = apiClient.GetKlines(s, tf, KlineCacheSize)
 					if err == nil && len(klines) > 0 {
 						break
 					}
@@ -378,7 +378,7 @@ func (m *WSMonitor) processKlineUpdate(symbol string, wsData KlineWSData, _time
 			klines = append(klines, kline)
 
 			// 保持数据长度
-			if len(klines) > 100 {
+			if len(klines) > KlineCacheSize {
 				klines = klines[1:]
 			}
 		}
@@ -400,7 +400,7 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 	if !exists {
 		// 如果Ws数据未初始化完成时,单独使用api获取 - 兼容性代码 (防止在未初始化完成是,已经有交易员运行)
 		apiClient := NewAPIClient()
-		klines, err := apiClient.GetKlines(symbol, duration, 100)
+		klines, err := apiClient.GetKlines(symbol, duration, KlineCacheSize)
 		if err != nil {
 			return nil, fmt.Errorf("获取%v分钟K线失败: %v", duration, err)
 		}
@@ -444,7 +444,7 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 
 		// 🔧 P0修复：數據過期時，嘗試 API fallback（避免 AI 用過期數據決策）
 		apiClient := NewAPIClient()
-		freshKlines, err := apiClient.GetKlines(symbol, duration, 100)
+		freshKlines, err := apiClient.GetKlines(symbol, duration, KlineCacheSize)
 		if err != nil {
 			return nil, fmt.Errorf("%s 的 %s K线数据已过期且 API fallback 失败: %v", symbol, duration, err)
 		}
@@ -0,0 +1,112 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// 幣種池情緒批量獲取參數
+var (
+	defaultPoolSentimentConcurrency = 5
+	poolSentimentCallTimeout        = 5 * time.Second  // 單個幣種的超時時間
+	poolSentimentDeadline           = 15 * time.Second // 整批的截止時間
+	poolSentimentEnhancer           = EnhanceOIData
+)
+
+// FetchPoolSentiment 並發獲取幣種池中所有幣種的多空情緒（基於 EnhanceOIData）
+// 同時進行的請求數不超過 maxConc（<=0 時使用默認值 5）；單個幣種超時或失敗會被跳過，
+// 整批在截止時間到達時立即返回已完成的部分結果。只有全部幣種都失敗時才返回錯誤
+func FetchPoolSentiment(symbols []string, maxConc int) (map[string]*OIData, error) {
+	results := make(map[string]*OIData, len(symbols))
+	if len(symbols) == 0 {
+		return results, nil
+	}
+	if maxConc <= 0 {
+		maxConc = defaultPoolSentimentConcurrency
+	}
+
+	seen := make(map[string]bool, len(symbols))
+	unique := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = Normalize(symbol)
+		if !seen[symbol] {
+			seen[symbol] = true
+			unique = append(unique, symbol)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), poolSentimentDeadline)
+	defer cancel()
+
+	type result struct {
+		symbol string
+		oi     *OIData
+		err    error
+	}
+	// 緩衝區足夠容納所有結果，截止後返回時不會阻塞仍在運行的 goroutine
+	resultCh := make(chan result, len(unique))
+	sem := make(chan struct{}, maxConc)
+
+	go func() {
+		for _, symbol := range unique {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(symbol string) {
+				done := make(chan result, 1)
+				go func() {
+					// 請求真正結束後才釋放並發名額，超時的請求不會讓並發數超過上限
+					defer func() { <-sem }()
+					oi := &OIData{}
+					err := poolSentimentEnhancer(symbol, oi)
+					if err == nil && oi.LongShortRatio <= 0 && oi.TopTraderLongShortRatio <= 0 {
+						err = fmt.Errorf("no sentiment data")
+					}
+					done <- result{symbol: symbol, oi: oi, err: err}
+				}()
+
+				timer := time.NewTimer(poolSentimentCallTimeout)
+				defer timer.Stop()
+				select {
+				case r := <-done:
+					resultCh <- r
+				case <-timer.C:
+					resultCh <- result{symbol: symbol, err: fmt.Errorf("timeout after %v", poolSentimentCallTimeout)}
+				case <-ctx.Done():
+				}
+			}(symbol)
+		}
+	}()
+
+	var errs []error
+collect:
+	for received := 0; received < len(unique); received++ {
+		select {
+		case r := <-resultCh:
+			if r.err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.symbol, r.err))
+				continue
+			}
+			results[r.symbol] = r.oi
+		case <-ctx.Done():
+			log.Printf("⚠️ FetchPoolSentiment: deadline %v reached, returning %d/%d symbols", poolSentimentDeadline, len(results), len(unique))
+			break collect
+		}
+	}
+
+	if len(results) == 0 {
+		if len(errs) == 0 {
+			errs = append(errs, ctx.Err())
+		}
+		return results, fmt.Errorf("failed to fetch pool sentiment: %w", errors.Join(errs...))
+	}
+	if len(errs) > 0 {
+		log.Printf("⚠️ FetchPoolSentiment: %d/%d symbols failed: %v", len(errs), len(unique), errors.Join(errs...))
+	}
+	return results, nil
+}
@@ -3,8 +3,11 @@ package market
 import (
 	"encoding/json"
 	"fmt"
-	"io"
+	"log"
+	"math/rand"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -15,13 +18,13 @@ import (
 func FetchLongShortRatio(symbol string) (float64, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/futures/data/globalLongShortAccountRatio?symbol=%s&period=5m&limit=1", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := httpGet(http.DefaultClient, url)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch long/short ratio: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return 0, err
 	}
@@ -47,17 +50,81 @@ func FetchLongShortRatio(symbol string) (float64, error) {
 	return ratio, nil
 }
 
+// LongShortRatioPoint 多空持倉人數比的單個數據點
+type LongShortRatioPoint struct {
+	Timestamp      time.Time `json:"timestamp"`
+	LongShortRatio float64   `json:"long_short_ratio"`
+	LongAccount    float64   `json:"long_account"`  // 多頭賬戶佔比（0-1）
+	ShortAccount   float64   `json:"short_account"` // 空頭賬戶佔比（0-1）
+}
+
+// longShortRatioHistoryURL 多空比歷史接口（測試時可替換）
+var longShortRatioHistoryURL = defaultBaseURL + "/futures/data/globalLongShortAccountRatio"
+
+// maxLongShortRatioLimit 接口單次最多返回的數據點數
+const maxLongShortRatioLimit = 500
+
+// longShortRatioPeriods 接口支持的統計週期
+var longShortRatioPeriods = map[string]bool{
+	"5m": true, "15m": true, "30m": true, "1h": true, "2h": true,
+	"4h": true, "6h": true, "12h": true, "1d": true,
+}
+
+// FetchLongShortRatioHistory 獲取多空持倉人數比的歷史序列（按時間升序），用於入庫與回測
+// period 為統計週期（5m/15m/30m/1h/2h/4h/6h/12h/1d），limit 最大 500；Binance 只保留最近 30 天的數據
+func FetchLongShortRatioHistory(symbol, period string, limit int) ([]LongShortRatioPoint, error) {
+	if !longShortRatioPeriods[period] {
+		return nil, fmt.Errorf("不支持的多空比週期: %s", period)
+	}
+	if limit <= 0 || limit > maxLongShortRatioLimit {
+		limit = maxLongShortRatioLimit
+	}
+	url := fmt.Sprintf("%s?symbol=%s&period=%s&limit=%d", longShortRatioHistoryURL, Normalize(symbol), period, limit)
+
+	resp, err := httpGet(http.DefaultClient, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch long/short ratio history: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []struct {
+		LongShortRatio string `json:"longShortRatio"`
+		LongAccount    string `json:"longAccount"`
+		ShortAccount   string `json:"shortAccount"`
+		Timestamp      int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+
+	points := make([]LongShortRatioPoint, 0, len(data))
+	for _, item := range data {
+		point := LongShortRatioPoint{Timestamp: time.UnixMilli(item.Timestamp).UTC()}
+		fmt.Sscanf(item.LongShortRatio, "%f", &point.LongShortRatio)
+		fmt.Sscanf(item.LongAccount, "%f", &point.LongAccount)
+		fmt.Sscanf(item.ShortAccount, "%f", &point.ShortAccount)
+		points = append(points, point)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+	return points, nil
+}
+
 // FetchTopTraderLongShortRatio 獲取大戶多空持倉量比
 func FetchTopTraderLongShortRatio(symbol string) (float64, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/futures/data/topLongShortPositionRatio?symbol=%s&period=5m&limit=1", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := httpGet(http.DefaultClient, url)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch top trader ratio: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return 0, err
 	}
@@ -102,22 +169,63 @@ func AnalyzeSentiment(longShortRatio, topTraderRatio float64) string {
 
 // ========== VIX 恐慌指數（Yahoo Finance - 免費）==========
 
+var (
+	// vixURL Yahoo Finance API（非官方但穩定）
+	vixURL = "https://query1.finance.yahoo.com/v8/finance/chart/%5EVIX?interval=1m&range=1d"
+	// vixMaxAttempts VIX 最大請求次數（含首次）
+	vixMaxAttempts = 3
+	// vixRetryBaseDelay 重試基礎延遲，第 n 次重試等待 base*n（±50% 隨機抖動）
+	vixRetryBaseDelay = 5 * time.Second
+	vixSleep          = time.Sleep
+)
+
+// SetVIXRetryBaseDelay 設置 FetchVIX 重試基礎延遲（<=0 時忽略）
+func SetVIXRetryBaseDelay(d time.Duration) {
+	if d > 0 {
+		vixRetryBaseDelay = d
+	}
+}
+
+// jitteredBackoff 計算帶 ±50% 隨機抖動的退避時間
+// 多實例同時重啟時避免同步重試，持續觸發上游限流
+func jitteredBackoff(base time.Duration, attempt int) time.Duration {
+	delay := base * time.Duration(attempt)
+	return time.Duration(float64(delay) * (0.5 + rand.Float64()))
+}
+
 // FetchVIX 獲取 VIX 恐慌指數
-// 使用 Yahoo Finance API（免費，但有限流）
+// 使用 Yahoo Finance API（免費，但有限流），失敗時以帶抖動的退避重試
 func FetchVIX() (float64, error) {
-	// Yahoo Finance API（非官方但穩定）
-	url := "https://query1.finance.yahoo.com/v8/finance/chart/%5EVIX?interval=1m&range=1d"
+	var lastErr error
+	for attempt := 1; attempt <= vixMaxAttempts; attempt++ {
+		vix, err := fetchVIXOnce()
+		if err == nil {
+			return vix, nil
+		}
+		lastErr = err
+		if attempt < vixMaxAttempts {
+			backoff := jitteredBackoff(vixRetryBaseDelay, attempt)
+			log.Printf("⚠️ FetchVIX attempt %d/%d failed: %v, retrying in %v...", attempt, vixMaxAttempts, err, backoff)
+			vixSleep(backoff)
+		}
+	}
+	return 0, fmt.Errorf("failed after %d attempts: %w", vixMaxAttempts, lastErr)
+}
 
-	resp, err := http.Get(url)
+func fetchVIXOnce() (float64, error) {
+	resp, err := httpGet(http.DefaultClient, vixURL)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch VIX: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return 0, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body[:min(200, len(body))]))
+	}
 
 	var data struct {
 		Chart struct {
@@ -182,16 +290,29 @@ func FetchSPXStatus(apiKey string) (*USMarketStatus, error) {
 		}, nil
 	}
 
+	// 配額不足時優先返回上次成功的數據，沒有緩存才等待配額
+	cached := lastSPXStatus.get()
+	maxWait := alphaVantageMaxWait
+	if cached != nil {
+		maxWait = 0
+	}
+	if err := alphaVantageLimiter.Acquire(apiKey, maxWait); err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+
 	// 獲取 S&P 500 數據（使用 Alpha Vantage 免費 API）
 	url := fmt.Sprintf("https://www.alphavantage.co/query?function=GLOBAL_QUOTE&symbol=SPY&apikey=%s", apiKey)
 
-	resp, err := http.Get(url)
+	resp, err := httpGet(http.DefaultClient, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch SPX: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readResponseBody(resp)
 	if err != nil {
 		return nil, err
 	}
@@ -227,19 +348,86 @@ func FetchSPXStatus(apiKey string) (*USMarketStatus, error) {
 		warning = fmt.Sprintf("🔥 S&P 500 大漲 %.2f%%，市場風險偏好上升", changePercent)
 	}
 
-	return &USMarketStatus{
+	status := &USMarketStatus{
 		IsOpen:      true,
 		SPXTrend:    trend,
 		SPXChange1h: changePercent,
 		Warning:     warning,
-	}, nil
+	}
+	lastSPXStatus.set(status)
+	return status, nil
+}
+
+// spxStatusCache 保存最近一次成功獲取的 S&P 500 狀態（配額用完時返回）
+// 數據與使用哪個 API Key 無關，只保存一份，更換 Key 後仍可使用
+type spxStatusCache struct {
+	mu     sync.RWMutex
+	status *USMarketStatus
+}
+
+var lastSPXStatus = &spxStatusCache{}
+
+func (c *spxStatusCache) get() *USMarketStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+func (c *spxStatusCache) set(status *USMarketStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+}
+
+// ========== 緩存數據源 ==========
+
+// 情緒類數據的緩存與重試參數
+const (
+	sentimentCacheTTL       = 5 * time.Minute        // 緩存時間（多空比以 5 分鐘為週期更新）
+	sentimentFetchAttempts  = 3                      // 多空比上游最多請求次數（含首次）
+	sentimentRetryBaseDelay = 500 * time.Millisecond // 重試基礎延遲，第 n 次重試等待 base*n（±50% 隨機抖動）
+)
+
+var (
+	longShortRatioSource = NewCachingDataSource[float64]("long_short_ratio", DataSourceFunc[float64](FetchLongShortRatio), nil, sentimentCacheTTL).WithRetry(sentimentFetchAttempts, sentimentRetryBaseDelay)
+	topTraderRatioSource = NewCachingDataSource[float64]("top_trader_ratio", DataSourceFunc[float64](FetchTopTraderLongShortRatio), nil, sentimentCacheTTL).WithRetry(sentimentFetchAttempts, sentimentRetryBaseDelay)
+	// 市場情緒按數據提供方緩存（FetchVIX 自帶重試），Fetch 的 key 是 Alpha Vantage API Key
+	marketSentimentSource  = NewCachingDataSource[*MarketSentiment]("market_sentiment", DataSourceFunc[*MarketSentiment](FetchMarketSentiment), nil, sentimentCacheTTL).WithCacheKey(sentimentProviderKey)
+	sentimentCachedSources = []interface{ SetCache(Cache) }{longShortRatioSource, topTraderRatioSource, marketSentimentSource}
+)
+
+// sentimentProviderKey 按數據提供方區分市場情緒數據（僅 VIX，或 VIX + Alpha Vantage 美股數據）
+// 不使用 API Key 本身，避免 Key 留在緩存中，也避免更換 Key 後緩存失效
+func sentimentProviderKey(alphaVantageKey string) string {
+	if alphaVantageKey == "" {
+		return "vix"
+	}
+	return "vix+alphavantage"
+}
+
+// SetSentimentCache 替換情緒/OI 增強數據的緩存後端（例如多實例共享的 Redis）
+func SetSentimentCache(cache Cache) {
+	for _, source := range sentimentCachedSources {
+		source.SetCache(cache)
+	}
+}
+
+// GetMarketSentiment 帶緩存的 FetchMarketSentiment
+func GetMarketSentiment(alphaVantageKey string) (*MarketSentiment, error) {
+	return marketSentimentSource.Fetch(alphaVantageKey)
 }
 
 // ========== 整合函數 ==========
 
 // FetchMarketSentiment 獲取完整的市場情緒數據（免費版本）
 // alphaVantageKey: 可選，用於獲取美股數據（免費 500 calls/day）
+// 結果寫入共享快照（見 SetSentimentSnapshotStore），多實例部署時每個週期只有一個實例請求上游
 func FetchMarketSentiment(alphaVantageKey string) (*MarketSentiment, error) {
+	return fetchSharedSentiment(getSentimentSnapshotStore(), alphaVantageKey, fetchMarketSentimentDirect)
+}
+
+// fetchMarketSentimentDirect 直接請求上游獲取市場情緒（不經過共享快照）
+func fetchMarketSentimentDirect(alphaVantageKey string) (*MarketSentiment, error) {
 	sentiment := &MarketSentiment{
 		UpdatedAt: time.Now(),
 	}
@@ -270,13 +458,13 @@ func EnhanceOIData(symbol string, oi *OIData) error {
 	}
 
 	// 獲取多空比（完全免費）
-	longShortRatio, err := FetchLongShortRatio(symbol)
+	longShortRatio, err := longShortRatioSource.Fetch(symbol)
 	if err == nil {
 		oi.LongShortRatio = longShortRatio
 	}
 
 	// 獲取大戶多空比（完全免費）
-	topTraderRatio, err := FetchTopTraderLongShortRatio(symbol)
+	topTraderRatio, err := topTraderRatioSource.Fetch(symbol)
 	if err == nil {
 		oi.TopTraderLongShortRatio = topTraderRatio
 	}
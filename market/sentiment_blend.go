@@ -0,0 +1,97 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// SentimentInputs 各情緒信號的原始值，nil 表示該信號不可用（不參與加權）
+type SentimentInputs struct {
+	VIX            *float64 // VIX 恐慌指數，20 附近為中性，越低越偏多
+	FearGreed      *float64 // 加密貨幣恐懼貪婪指數 0-100，50 為中性
+	LongShortRatio *float64 // 全市場多空人數比，1 為中性
+	TopTraderRatio *float64 // 大戶多空持倉比，1 為中性
+	FundingRate    *float64 // 資金費率（例如 0.0001 = 0.01%），正值表示多頭付費
+}
+
+// SentimentWeights 各信號權重（無需歸一化，按可用信號的權重和重新分配）
+type SentimentWeights struct {
+	VIX            float64 `json:"vix"`
+	FearGreed      float64 `json:"fear_greed"`
+	LongShortRatio float64 `json:"long_short_ratio"`
+	TopTraderRatio float64 `json:"top_trader_ratio"`
+	FundingRate    float64 `json:"funding_rate"`
+}
+
+// DefaultSentimentWeights 默認權重
+var DefaultSentimentWeights = SentimentWeights{
+	VIX:            0.25,
+	FearGreed:      0.25,
+	LongShortRatio: 0.2,
+	TopTraderRatio: 0.2,
+	FundingRate:    0.1,
+}
+
+// 綜合得分的標籤閾值（與 AnalyzeSentiment 的標籤一致）
+const sentimentLabelThreshold = 0.2
+
+// ParseSentimentWeights 解析 JSON 格式的權重配置，未提供的字段使用默認值
+func ParseSentimentWeights(raw string) (SentimentWeights, error) {
+	weights := DefaultSentimentWeights
+	if raw == "" {
+		return weights, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &weights); err != nil {
+		return DefaultSentimentWeights, fmt.Errorf("解析情緒權重失敗: %w", err)
+	}
+	for _, w := range []float64{weights.VIX, weights.FearGreed, weights.LongShortRatio, weights.TopTraderRatio, weights.FundingRate} {
+		if w < 0 || math.IsNaN(w) {
+			return DefaultSentimentWeights, fmt.Errorf("情緒權重不能為負數")
+		}
+	}
+	return weights, nil
+}
+
+func clampUnit(v float64) float64 {
+	return math.Max(-1, math.Min(1, v))
+}
+
+// 各信號歸一化到 [-1,1]，正值偏多、負值偏空
+func normalizeVIX(vix float64) float64          { return clampUnit((20 - vix) / 15) }   // 5 → +1，35 → -1
+func normalizeFearGreed(fg float64) float64     { return clampUnit((fg - 50) / 50) }    // 0 → -1，100 → +1
+func normalizeRatio(ratio float64) float64      { return clampUnit((ratio - 1) / 0.5) } // 0.5 → -1，1.5 → +1
+func normalizeFundingRate(rate float64) float64 { return clampUnit(rate / 0.001) }      // ±0.1% 為極值
+
+// BlendSentiment 按權重綜合多個情緒信號，返回 [-1,1] 的得分及標籤（bullish/neutral/bearish）
+// 不可用的信號不參與計算，其餘信號按權重和重新歸一化；沒有任何可用信號時返回 0 和 neutral
+func BlendSentiment(inputs SentimentInputs, weights SentimentWeights) (score float64, label string) {
+	var sum, totalWeight float64
+	add := func(value *float64, weight float64, normalize func(float64) float64) {
+		if value == nil || weight <= 0 || math.IsNaN(*value) {
+			return
+		}
+		sum += normalize(*value) * weight
+		totalWeight += weight
+	}
+
+	add(inputs.VIX, weights.VIX, normalizeVIX)
+	add(inputs.FearGreed, weights.FearGreed, normalizeFearGreed)
+	add(inputs.LongShortRatio, weights.LongShortRatio, normalizeRatio)
+	add(inputs.TopTraderRatio, weights.TopTraderRatio, normalizeRatio)
+	add(inputs.FundingRate, weights.FundingRate, normalizeFundingRate)
+
+	if totalWeight > 0 {
+		score = sum / totalWeight
+	}
+
+	switch {
+	case score > sentimentLabelThreshold:
+		label = "bullish"
+	case score < -sentimentLabelThreshold:
+		label = "bearish"
+	default:
+		label = "neutral"
+	}
+	return score, label
+}
@@ -0,0 +1,72 @@
+package market
+
+import (
+	"math"
+	"testing"
+)
+
+func f64(v float64) *float64 { return &v }
+
+func TestBlendSentiment(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputs    SentimentInputs
+		weights   SentimentWeights
+		wantScore float64
+		wantLabel string
+	}{
+		{
+			name:      "无可用信号",
+			weights:   DefaultSentimentWeights,
+			wantScore: 0,
+			wantLabel: "neutral",
+		},
+		{
+			name:      "极度恐慌",
+			inputs:    SentimentInputs{VIX: f64(40), FearGreed: f64(0)},
+			weights:   DefaultSentimentWeights,
+			wantScore: -1,
+			wantLabel: "bearish",
+		},
+		{
+			name:      "缺失信号按剩余权重归一化",
+			inputs:    SentimentInputs{LongShortRatio: f64(1.25), FundingRate: f64(0.0005)},
+			weights:   SentimentWeights{LongShortRatio: 1, FundingRate: 1},
+			wantScore: 0.5,
+			wantLabel: "bullish",
+		},
+		{
+			name:      "权重为0的信号被忽略",
+			inputs:    SentimentInputs{VIX: f64(5), TopTraderRatio: f64(0.9)},
+			weights:   SentimentWeights{TopTraderRatio: 1},
+			wantScore: -0.2,
+			wantLabel: "neutral",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, label := BlendSentiment(tt.inputs, tt.weights)
+			if math.Abs(score-tt.wantScore) > 1e-9 || label != tt.wantLabel {
+				t.Errorf("BlendSentiment() = (%v, %q), want (%v, %q)", score, label, tt.wantScore, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestParseSentimentWeights(t *testing.T) {
+	weights, err := ParseSentimentWeights(`{"vix":0.5}`)
+	if err != nil {
+		t.Fatalf("ParseSentimentWeights failed: %v", err)
+	}
+	if weights.VIX != 0.5 || weights.FearGreed != DefaultSentimentWeights.FearGreed {
+		t.Errorf("unexpected weights: %+v", weights)
+	}
+
+	if _, err := ParseSentimentWeights(`{"vix":-1}`); err == nil {
+		t.Error("expected error for negative weight")
+	}
+	if w, err := ParseSentimentWeights(""); err != nil || w != DefaultSentimentWeights {
+		t.Errorf("expected defaults for empty config, got %+v, %v", w, err)
+	}
+}
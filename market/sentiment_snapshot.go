@@ -0,0 +1,183 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 跨實例共享市場情緒快照的參數
+const (
+	sentimentSnapshotTTL  = sentimentCacheTTL      // 快照有效期，過期後由任一實例重新獲取
+	sentimentLockTTL      = 30 * time.Second       // 獲取鎖的最長持有時間（持有者崩潰時自動釋放）
+	sentimentLockWait     = 10 * time.Second       // 未搶到鎖時等待其他實例寫入快照的最長時間
+	sentimentPollInterval = 200 * time.Millisecond // 等待期間輪詢快照的間隔
+)
+
+// SnapshotStore 跨實例共享的快照存儲（語義與 Redis GET / SET PX / SET NX PX / 比對 owner 後 DEL 一致）
+// 默認為進程內實現；多實例部署時替換為 Redis 實現，使整個集群每個週期只有一個實例請求上游
+// 任一方法返回錯誤視為共享存儲不可用，調用方會退回本實例直接獲取
+type SnapshotStore interface {
+	Get(key string) (value []byte, ok bool, err error)
+	Set(key string, value []byte, ttl time.Duration) error
+	// TryLock 嘗試以 owner 身份獲取 key 對應的鎖，ttl 後自動過期
+	TryLock(key, owner string, ttl time.Duration) (bool, error)
+	// Unlock 僅當鎖仍由 owner 持有時釋放
+	Unlock(key, owner string) error
+}
+
+type memorySnapshotItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// memorySnapshotStore 進程內快照存儲（單實例部署時的默認實現）
+type memorySnapshotStore struct {
+	mu    sync.Mutex
+	items map[string]memorySnapshotItem
+	now   func() time.Time
+}
+
+// NewMemorySnapshotStore 創建進程內快照存儲
+func NewMemorySnapshotStore() SnapshotStore {
+	return &memorySnapshotStore{items: make(map[string]memorySnapshotItem), now: time.Now}
+}
+
+func (m *memorySnapshotStore) Get(key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok || m.now().After(item.expiresAt) {
+		delete(m.items, key)
+		return nil, false, nil
+	}
+	return item.value, true, nil
+}
+
+func (m *memorySnapshotStore) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = memorySnapshotItem{value: value, expiresAt: m.now().Add(ttl)}
+	return nil
+}
+
+func (m *memorySnapshotStore) TryLock(key, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if item, ok := m.items[key]; ok && !m.now().After(item.expiresAt) {
+		return false, nil
+	}
+	m.items[key] = memorySnapshotItem{value: []byte(owner), expiresAt: m.now().Add(ttl)}
+	return true, nil
+}
+
+func (m *memorySnapshotStore) Unlock(key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if item, ok := m.items[key]; ok && string(item.value) == owner {
+		delete(m.items, key)
+	}
+	return nil
+}
+
+var (
+	sentimentSnapshotMu    sync.RWMutex
+	sentimentSnapshotStore = NewMemorySnapshotStore()
+
+	// sentimentLockOwner 本實例的鎖持有者前綴（主機名 + 進程號）
+	sentimentLockOwner = func() string {
+		host, _ := os.Hostname()
+		return fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+	}()
+	sentimentLockSeq atomic.Int64
+)
+
+// SetSentimentSnapshotStore 替換市場情緒快照存儲（例如多實例共享的 Redis），nil 時恢復進程內存儲
+func SetSentimentSnapshotStore(store SnapshotStore) {
+	if store == nil {
+		store = NewMemorySnapshotStore()
+	}
+	sentimentSnapshotMu.Lock()
+	defer sentimentSnapshotMu.Unlock()
+	sentimentSnapshotStore = store
+}
+
+func getSentimentSnapshotStore() SnapshotStore {
+	sentimentSnapshotMu.RLock()
+	defer sentimentSnapshotMu.RUnlock()
+	return sentimentSnapshotStore
+}
+
+// sentimentSnapshotKey 按數據提供方區分快照（有無美股數據不同），API Key 不寫入共享存儲
+func sentimentSnapshotKey(alphaVantageKey string) string {
+	return "sentiment:snapshot:" + sentimentProviderKey(alphaVantageKey)
+}
+
+// readSentimentSnapshot 讀取共享快照；ok=false 表示沒有可用快照，err 非空表示共享存儲不可用
+func readSentimentSnapshot(store SnapshotStore, key string) (*MarketSentiment, bool, error) {
+	data, ok, err := store.Get(key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var sentiment MarketSentiment
+	if err := json.Unmarshal(data, &sentiment); err != nil {
+		return nil, false, nil
+	}
+	return &sentiment, true, nil
+}
+
+// fetchSharedSentiment 通過共享快照獲取市場情緒：快照新鮮時直接使用；
+// 否則只有搶到鎖的實例請求上游並寫入快照，其他實例等待快照出現（跨實例 single-flight）
+// 共享存儲不可用或等待超時時退回本實例直接獲取
+func fetchSharedSentiment(store SnapshotStore, alphaVantageKey string, fetch func(string) (*MarketSentiment, error)) (*MarketSentiment, error) {
+	key := sentimentSnapshotKey(alphaVantageKey)
+	sentiment, ok, err := readSentimentSnapshot(store, key)
+	if err != nil {
+		log.Printf("⚠️ 共享情緒快照不可用，改為本實例獲取: %v", err)
+		return fetch(alphaVantageKey)
+	}
+	if ok {
+		return sentiment, nil
+	}
+
+	lockKey := key + ":lock"
+	owner := fmt.Sprintf("%s-%d", sentimentLockOwner, sentimentLockSeq.Add(1))
+	locked, err := store.TryLock(lockKey, owner, sentimentLockTTL)
+	if err != nil {
+		log.Printf("⚠️ 共享情緒快照鎖不可用，改為本實例獲取: %v", err)
+		return fetch(alphaVantageKey)
+	}
+
+	if locked {
+		defer store.Unlock(lockKey, owner)
+		sentiment, err := fetch(alphaVantageKey)
+		if err != nil {
+			return nil, err
+		}
+		if data, err := json.Marshal(sentiment); err == nil {
+			if err := store.Set(key, data, sentimentSnapshotTTL); err != nil {
+				log.Printf("⚠️ 寫入共享情緒快照失敗: %v", err)
+			}
+		}
+		return sentiment, nil
+	}
+
+	// 其他實例正在獲取：等待其寫入快照
+	deadline := time.Now().Add(sentimentLockWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(sentimentPollInterval)
+		sentiment, ok, err := readSentimentSnapshot(store, key)
+		if err != nil {
+			break
+		}
+		if ok {
+			return sentiment, nil
+		}
+	}
+	log.Printf("⚠️ 等待共享情緒快照超時，改為本實例獲取")
+	return fetch(alphaVantageKey)
+}
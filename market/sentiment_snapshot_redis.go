@@ -0,0 +1,297 @@
+package market
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout 單條 Redis 命令（含建立連接）的超時時間
+const redisTimeout = 3 * time.Second
+
+// redisUnlockScript 僅當鎖的值仍為 owner 時刪除（比對與刪除在 Redis 端原子執行）
+const redisUnlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+
+// redisTokenBucketScript 令牌桶原子取令牌（QuotaStore.Take 的 Redis 實現），以 Redis 服務器時間計算補充量，避免實例間時鐘偏差
+// KEYS[1] 令牌桶 key，ARGV[1] 容量，ARGV[2] 每補充一個令牌的毫秒數；返回 0 表示取到令牌，否則為需要等待的毫秒數
+const redisTokenBucketScript = `local capacity = tonumber(ARGV[1])
+local refill = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+if now > ts then
+  tokens = math.min(capacity, tokens + (now - ts) / refill)
+  ts = now
+end
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+else
+  wait = math.ceil((1 - tokens) * refill)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(ts))
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity * refill))
+return wait`
+
+// errRedisNil Redis 返回空值（key 不存在或 SET NX 未成功）
+var errRedisNil = errors.New("redis: nil")
+
+// RedisClient 極簡 Redis 客戶端（多實例共享快照、限流計數和 API 配額），只實現用到的少量命令
+// 實現 SnapshotStore、QuotaStore 與 AI 限流的計數器接口；使用單條長連接，命令串行執行；連接出錯時關閉，下一條命令重新連接
+type RedisClient struct {
+	addr     string
+	username string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	lost   bool // 連接因網絡錯誤中斷、尚未恢復（只在狀態變化時打印日誌）
+}
+
+// NewRedisSnapshotStore 按 Redis URL 創建快照存儲
+func NewRedisSnapshotStore(rawURL string) (SnapshotStore, error) {
+	client, err := NewRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewRedisClient 按 Redis URL（redis://[user:password@]host:port/db）創建客戶端，創建時 PING 一次確認可用
+func NewRedisClient(rawURL string) (*RedisClient, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("解析 Redis 地址失敗: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("不支持的 Redis 地址協議: %q", u.Scheme)
+	}
+	store := &RedisClient{addr: u.Host}
+	if u.Port() == "" {
+		store.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		store.password, _ = u.User.Password()
+		if store.password == "" {
+			// redis://password@host 形式
+			store.password = u.User.Username()
+		} else {
+			store.username = u.User.Username()
+		}
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if store.db, err = strconv.Atoi(path); err != nil || store.db < 0 {
+			return nil, fmt.Errorf("無效的 Redis 數據庫編號: %q", path)
+		}
+	}
+	if _, err := store.do("PING"); err != nil {
+		return nil, fmt.Errorf("連接 Redis 失敗: %w", err)
+	}
+	return store, nil
+}
+
+func (r *RedisClient) Get(key string) ([]byte, bool, error) {
+	reply, err := r.do("GET", key)
+	if err == errRedisNil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis GET 返回了意外的類型 %T", reply)
+	}
+	return value, true, nil
+}
+
+func (r *RedisClient) Set(key string, value []byte, ttl time.Duration) error {
+	_, err := r.do("SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (r *RedisClient) TryLock(key, owner string, ttl time.Duration) (bool, error) {
+	_, err := r.do("SET", key, owner, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err == errRedisNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *RedisClient) Unlock(key, owner string) error {
+	_, err := r.do("EVAL", redisUnlockScript, "1", key, owner)
+	return err
+}
+
+// Healthy PING 一次檢查 Redis 是否可用（供健康檢查使用）
+func (r *RedisClient) Healthy() error {
+	_, err := r.do("PING")
+	return err
+}
+
+// Take 用 Lua 腳本在 Redis 端原子地從令牌桶取一個令牌（實現 QuotaStore，所有實例共享同一配額）
+func (r *RedisClient) Take(key string, capacity int, refill time.Duration) (bool, time.Duration, error) {
+	if capacity <= 0 || refill < time.Millisecond {
+		return false, 0, fmt.Errorf("無效的令牌桶參數: capacity=%d refill=%v", capacity, refill)
+	}
+	reply, err := r.do("EVAL", redisTokenBucketScript, "1", key, strconv.Itoa(capacity), strconv.FormatInt(refill.Milliseconds(), 10))
+	if err != nil {
+		return false, 0, err
+	}
+	waitMs, ok := reply.(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("redis 令牌桶腳本返回了意外的類型 %T", reply)
+	}
+	if waitMs <= 0 {
+		return true, 0, nil
+	}
+	return false, time.Duration(waitMs) * time.Millisecond, nil
+}
+
+// Incr 將 key 的計數加1並返回新值，首次創建（計數為1）時用 PEXPIRE 設置 ttl 過期
+func (r *RedisClient) Incr(key string, ttl time.Duration) (int64, error) {
+	reply, err := r.do("INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis INCR 返回了意外的類型 %T", reply)
+	}
+	if count == 1 {
+		if _, err := r.do("PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10)); err != nil {
+			return 0, fmt.Errorf("redis PEXPIRE 失敗: %w", err)
+		}
+	}
+	return count, nil
+}
+
+// do 執行一條命令並返回回覆（簡單字符串為 string，整數為 int64，批量字符串為 []byte）
+func (r *RedisClient) do(args ...string) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connect(); err != nil {
+			return nil, err
+		}
+		if r.lost {
+			r.lost = false
+			log.Printf("✓ Redis 連接已恢復: %s", r.addr)
+		}
+	}
+	reply, err := r.roundTrip(args...)
+	if err != nil && err != errRedisNil {
+		var redisErr redisError
+		if !errors.As(err, &redisErr) {
+			// 網絡或協議錯誤：丟棄連接，下次重新連接
+			r.conn.Close()
+			r.conn, r.reader = nil, nil
+			if !r.lost {
+				r.lost = true
+				log.Printf("⚠️ Redis 連接中斷，下次命令時重連: %v", err)
+			}
+		}
+	}
+	return reply, err
+}
+
+// connect 建立連接並完成認證和選庫（調用方持有 r.mu）
+func (r *RedisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", r.addr, redisTimeout)
+	if err != nil {
+		return err
+	}
+	r.conn, r.reader = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if r.password != "" {
+		if r.username != "" {
+			setup = append(setup, []string{"AUTH", r.username, r.password})
+		} else {
+			setup = append(setup, []string{"AUTH", r.password})
+		}
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, args := range setup {
+		if _, err := r.roundTrip(args...); err != nil {
+			conn.Close()
+			r.conn, r.reader = nil, nil
+			return fmt.Errorf("redis %s 失敗: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+func (r *RedisClient) roundTrip(args ...string) (interface{}, error) {
+	if err := r.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(r.reader)
+}
+
+// redisError Redis 返回的錯誤回覆（連接本身仍可用）
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readRedisReply 讀取一條 RESP 回覆（不支持嵌套數組，SnapshotStore 用到的命令不會返回數組）
+func readRedisReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: 空回覆")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: 無效的批量回覆長度 %q", line)
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	default:
+		return nil, fmt.Errorf("redis: 不支持的回覆類型 %q", line)
+	}
+}
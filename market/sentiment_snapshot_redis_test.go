@@ -0,0 +1,293 @@
+package market
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedisServer 只實現 RedisClient 用到的命令，數據存放在進程內快照存儲中
+type fakeRedisServer struct {
+	listener net.Listener
+	password string
+	store    SnapshotStore
+
+	mu       sync.Mutex
+	counters map[string]int64
+	ttls     map[string]int64 // key -> PEXPIRE 設置的毫秒數
+	pexpires int
+	quota    QuotaStore // 模擬令牌桶腳本
+}
+
+func newFakeRedisServer(t *testing.T, password string) *fakeRedisServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	s := &fakeRedisServer{listener: listener, password: password, store: NewMemorySnapshotStore(),
+		counters: make(map[string]int64), ttls: make(map[string]int64), quota: NewMemoryQuotaStore()}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		args, err := readFakeRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		switch cmd {
+		case "AUTH":
+			if args[len(args)-1] != s.password {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			io.WriteString(conn, "+OK\r\n")
+		case "PING":
+			io.WriteString(conn, "+PONG\r\n")
+		case "SELECT":
+			io.WriteString(conn, "+OK\r\n")
+		case "GET":
+			value, ok, _ := s.store.Get(args[1])
+			if !ok {
+				io.WriteString(conn, "$-1\r\n")
+				continue
+			}
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+		case "SET":
+			ms, _ := strconv.Atoi(args[len(args)-1])
+			ttl := time.Duration(ms) * time.Millisecond
+			if strings.ToUpper(args[3]) == "NX" {
+				if ok, _ := s.store.TryLock(args[1], args[2], ttl); !ok {
+					io.WriteString(conn, "$-1\r\n")
+					continue
+				}
+			} else {
+				s.store.Set(args[1], []byte(args[2]), ttl)
+			}
+			io.WriteString(conn, "+OK\r\n")
+		case "INCR":
+			s.mu.Lock()
+			s.counters[args[1]]++
+			count := s.counters[args[1]]
+			s.mu.Unlock()
+			fmt.Fprintf(conn, ":%d\r\n", count)
+		case "PEXPIRE":
+			ms, _ := strconv.ParseInt(args[2], 10, 64)
+			s.mu.Lock()
+			s.ttls[args[1]] = ms
+			s.pexpires++
+			s.mu.Unlock()
+			io.WriteString(conn, ":1\r\n")
+		case "EVAL":
+			switch args[1] {
+			case redisUnlockScript:
+				s.store.Unlock(args[3], args[4])
+				io.WriteString(conn, ":1\r\n")
+			case redisTokenBucketScript:
+				capacity, _ := strconv.Atoi(args[4])
+				refillMs, _ := strconv.Atoi(args[5])
+				_, wait, _ := s.quota.Take(args[3], capacity, time.Duration(refillMs)*time.Millisecond)
+				fmt.Fprintf(conn, ":%d\r\n", int64(math.Ceil(float64(wait)/float64(time.Millisecond))))
+			default:
+				io.WriteString(conn, "-NOSCRIPT unknown script\r\n")
+			}
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", cmd)
+		}
+	}
+}
+
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		reply, err := readRedisReply(reader)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, string(reply.([]byte)))
+	}
+	return args, nil
+}
+
+func TestRedisSnapshotStore(t *testing.T) {
+	server := newFakeRedisServer(t, "secret")
+	store, err := NewRedisSnapshotStore("redis://:secret@" + server.listener.Addr().String() + "/2")
+	if err != nil {
+		t.Fatalf("NewRedisSnapshotStore failed: %v", err)
+	}
+
+	if _, ok, err := store.Get("missing"); ok || err != nil {
+		t.Fatalf("missing key should be a clean miss, got ok=%v err=%v", ok, err)
+	}
+	if err := store.Set("snap", []byte(`{"a":1}`), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, ok, err := store.Get("snap"); !ok || err != nil || string(value) != `{"a":1}` {
+		t.Fatalf("Get returned %q ok=%v err=%v", value, ok, err)
+	}
+
+	if ok, err := store.TryLock("lock", "a", time.Minute); !ok || err != nil {
+		t.Fatalf("first TryLock should succeed, got %v %v", ok, err)
+	}
+	if ok, err := store.TryLock("lock", "b", time.Minute); ok || err != nil {
+		t.Fatalf("second TryLock should fail cleanly, got %v %v", ok, err)
+	}
+	if err := store.Unlock("lock", "b"); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	if ok, _ := store.TryLock("lock", "b", time.Minute); ok {
+		t.Fatal("unlock by a non-owner must not release the lock")
+	}
+	store.Unlock("lock", "a")
+	if ok, _ := store.TryLock("lock", "b", time.Minute); !ok {
+		t.Fatal("lock should be free after the owner unlocks it")
+	}
+}
+
+func TestNewRedisSnapshotStore_RejectsBadConfig(t *testing.T) {
+	server := newFakeRedisServer(t, "secret")
+	if _, err := NewRedisSnapshotStore("redis://:wrong@" + server.listener.Addr().String()); err == nil {
+		t.Error("wrong password should fail at startup")
+	}
+	if _, err := NewRedisSnapshotStore("http://" + server.listener.Addr().String()); err == nil {
+		t.Error("non-redis scheme should be rejected")
+	}
+}
+
+func TestRedisClient_Incr(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+	client, err := NewRedisClient("redis://" + server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		count, err := client.Incr("ai_rate:model:1", time.Minute)
+		if err != nil || count != want {
+			t.Fatalf("Incr returned %d err=%v, want %d", count, err, want)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if ttl := server.ttls["ai_rate:model:1"]; ttl != time.Minute.Milliseconds() || server.pexpires != 1 {
+		t.Fatalf("ttl should be set once on the first hit, got %dms after %d PEXPIRE calls", ttl, server.pexpires)
+	}
+}
+
+func TestRedisClient_TakeTokenBucket(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+	client, err := NewRedisClient("redis://" + server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if ok, wait, err := client.Take("quota:test", 2, time.Minute); !ok || wait != 0 || err != nil {
+			t.Fatalf("token %d should be available, got ok=%v wait=%v err=%v", i, ok, wait, err)
+		}
+	}
+	ok, wait, err := client.Take("quota:test", 2, time.Minute)
+	if ok || err != nil || wait <= 0 || wait > time.Minute {
+		t.Fatalf("empty bucket should report a wait, got ok=%v wait=%v err=%v", ok, wait, err)
+	}
+	if _, _, err := client.Take("quota:test", 0, time.Minute); err == nil {
+		t.Fatal("invalid capacity should be rejected")
+	}
+}
+
+func TestQuotaLimiter_RedisStoreFallsBackWhenUnavailable(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+	client, err := NewRedisClient("redis://" + server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+	limiter := NewQuotaLimiter("test", 1, time.Minute, client)
+	if err := limiter.Acquire("key", 0); err != nil {
+		t.Fatalf("first request should use the shared quota: %v", err)
+	}
+	if err := limiter.Acquire("key", 0); err == nil {
+		t.Fatal("shared quota should be exhausted")
+	}
+
+	// Redis 不可用時退回本地令牌桶
+	server.listener.Close()
+	client.mu.Lock()
+	client.conn.Close()
+	client.mu.Unlock()
+	if err := limiter.Acquire("key", 0); err != nil {
+		t.Fatalf("limiter should fall back to the local bucket: %v", err)
+	}
+}
+
+func TestRedisClient_HealthyAndReconnect(t *testing.T) {
+	server := newFakeRedisServer(t, "")
+	client, err := NewRedisClient("redis://" + server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("NewRedisClient failed: %v", err)
+	}
+	if err := client.Healthy(); err != nil {
+		t.Fatalf("Healthy failed: %v", err)
+	}
+
+	// 模擬網絡中斷：本次命令失敗並標記連接中斷，下一次命令重新連接
+	client.mu.Lock()
+	client.conn.Close()
+	client.mu.Unlock()
+	if err := client.Healthy(); err == nil {
+		t.Fatal("Healthy should fail on a broken connection")
+	}
+	if !client.lost {
+		t.Fatal("connection loss should be recorded")
+	}
+	if err := client.Healthy(); err != nil {
+		t.Fatalf("Healthy should succeed after reconnecting: %v", err)
+	}
+	if client.lost {
+		t.Fatal("recovery should clear the lost flag")
+	}
+
+	server.listener.Close()
+	client.mu.Lock()
+	client.conn.Close()
+	client.mu.Unlock()
+	client.Healthy()
+	if err := client.Healthy(); err == nil {
+		t.Fatal("Healthy should fail while Redis is unreachable")
+	}
+}
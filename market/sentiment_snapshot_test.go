@@ -0,0 +1,100 @@
+package market
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingSnapshotStore 模擬共享存儲（Redis）不可用
+type failingSnapshotStore struct{}
+
+func (failingSnapshotStore) Get(string) ([]byte, bool, error) { return nil, false, errors.New("down") }
+func (failingSnapshotStore) Set(string, []byte, time.Duration) error {
+	return errors.New("down")
+}
+func (failingSnapshotStore) TryLock(string, string, time.Duration) (bool, error) {
+	return false, errors.New("down")
+}
+func (failingSnapshotStore) Unlock(string, string) error { return errors.New("down") }
+
+func TestFetchSharedSentiment_SingleFlightAcrossInstances(t *testing.T) {
+	store := NewMemorySnapshotStore()
+	var calls atomic.Int32
+	fetch := func(string) (*MarketSentiment, error) {
+		calls.Add(1)
+		time.Sleep(300 * time.Millisecond) // 模擬上游延遲，讓其他“實例”在此期間等待鎖
+		return &MarketSentiment{VIX: 18.5, FearLevel: "moderate", UpdatedAt: time.Now()}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*MarketSentiment, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s, err := fetchSharedSentiment(store, "key", fetch)
+			if err != nil {
+				t.Errorf("fetchSharedSentiment failed: %v", err)
+				return
+			}
+			results[i] = s
+		}(i)
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("upstream should be fetched once across instances, got %d", got)
+	}
+	for i, s := range results {
+		if s == nil || s.VIX != 18.5 || s.FearLevel != "moderate" {
+			t.Fatalf("instance %d got unexpected sentiment: %+v", i, s)
+		}
+	}
+
+	// 快照新鮮時不再請求上游；更換 API Key 仍使用同一提供方的快照，沒有 Key（僅 VIX）使用獨立快照
+	if _, err := fetchSharedSentiment(store, "key", fetch); err != nil || calls.Load() != 1 {
+		t.Fatalf("fresh snapshot should be reused (calls=%d, err=%v)", calls.Load(), err)
+	}
+	if _, err := fetchSharedSentiment(store, "rotated-key", fetch); err != nil || calls.Load() != 1 {
+		t.Fatalf("rotated key should reuse the provider snapshot (calls=%d, err=%v)", calls.Load(), err)
+	}
+	if _, err := fetchSharedSentiment(store, "", fetch); err != nil || calls.Load() != 2 {
+		t.Fatalf("VIX-only sentiment should fetch its own snapshot (calls=%d, err=%v)", calls.Load(), err)
+	}
+}
+
+func TestFetchSharedSentiment_DegradesWhenStoreDown(t *testing.T) {
+	var calls int
+	fetch := func(string) (*MarketSentiment, error) {
+		calls++
+		return &MarketSentiment{VIX: 25}, nil
+	}
+	for i := 0; i < 2; i++ {
+		s, err := fetchSharedSentiment(failingSnapshotStore{}, "key", fetch)
+		if err != nil || s.VIX != 25 {
+			t.Fatalf("should fall back to direct fetch: %+v (%v)", s, err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("each call should fetch directly when the store is down, got %d", calls)
+	}
+}
+
+func TestFetchSharedSentiment_UpstreamErrorReleasesLock(t *testing.T) {
+	store := NewMemorySnapshotStore()
+	if _, err := fetchSharedSentiment(store, "key", func(string) (*MarketSentiment, error) {
+		return nil, errors.New("upstream down")
+	}); err == nil {
+		t.Fatal("expected upstream error")
+	}
+	// 鎖已釋放，下一次調用可立即重新獲取
+	s, err := fetchSharedSentiment(store, "key", func(string) (*MarketSentiment, error) {
+		return &MarketSentiment{VIX: 12}, nil
+	})
+	if err != nil || s.VIX != 12 {
+		t.Fatalf("lock should be released after a failed fetch: %+v (%v)", s, err)
+	}
+}
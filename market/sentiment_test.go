@@ -0,0 +1,154 @@
+package market
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJitteredBackoffWithinRange(t *testing.T) {
+	base := 2 * time.Second
+	for attempt := 1; attempt <= 3; attempt++ {
+		nominal := base * time.Duration(attempt)
+		for i := 0; i < 100; i++ {
+			d := jitteredBackoff(base, attempt)
+			if d < nominal/2 || d > nominal*3/2 {
+				t.Fatalf("attempt %d: backoff %v outside [%v, %v]", attempt, d, nominal/2, nominal*3/2)
+			}
+		}
+	}
+}
+
+func TestFetchVIXRetriesWithBackoff(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`Too Many Requests`))
+			return
+		}
+		w.Write([]byte(`{"chart":{"result":[{"meta":{"regularMarketPrice":18.5}}]}}`))
+	}))
+	defer server.Close()
+
+	origURL, origBase, origSleep := vixURL, vixRetryBaseDelay, vixSleep
+	defer func() { vixURL, vixRetryBaseDelay, vixSleep = origURL, origBase, origSleep }()
+
+	var sleeps []time.Duration
+	vixURL = server.URL
+	vixSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	SetVIXRetryBaseDelay(100 * time.Millisecond)
+
+	vix, err := FetchVIX()
+	if err != nil {
+		t.Fatalf("FetchVIX failed: %v", err)
+	}
+	if vix != 18.5 {
+		t.Fatalf("vix = %v, want 18.5", vix)
+	}
+	if len(sleeps) != 2 {
+		t.Fatalf("expected 2 backoff sleeps, got %d", len(sleeps))
+	}
+	if sleeps[0] < 50*time.Millisecond || sleeps[0] > 150*time.Millisecond {
+		t.Errorf("first backoff %v outside jitter range", sleeps[0])
+	}
+	if sleeps[1] < 100*time.Millisecond || sleeps[1] > 300*time.Millisecond {
+		t.Errorf("second backoff %v outside jitter range", sleeps[1])
+	}
+}
+
+func TestFetchPoolSentimentBoundedAndPartial(t *testing.T) {
+	origEnhancer, origCall, origDeadline := poolSentimentEnhancer, poolSentimentCallTimeout, poolSentimentDeadline
+	defer func() {
+		poolSentimentEnhancer, poolSentimentCallTimeout, poolSentimentDeadline = origEnhancer, origCall, origDeadline
+	}()
+	poolSentimentCallTimeout = 100 * time.Millisecond
+	poolSentimentDeadline = time.Second
+
+	var inFlight, maxInFlight int32
+	release := make(chan struct{})
+	defer close(release)
+	poolSentimentEnhancer = func(symbol string, oi *OIData) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		switch symbol {
+		case "SLOWUSDT":
+			<-release // 模擬卡住的請求
+		case "EMPTYUSDT":
+			return nil
+		default:
+			time.Sleep(10 * time.Millisecond)
+			oi.LongShortRatio, oi.TopTraderLongShortRatio = 1.2, 1.3
+		}
+		return nil
+	}
+
+	symbols := []string{"BTCUSDT", "ETHUSDT", "SLOWUSDT", "SOLUSDT", "EMPTYUSDT", "BNBUSDT", "btc"}
+	start := time.Now()
+	results, err := FetchPoolSentiment(symbols, 2)
+	if err != nil {
+		t.Fatalf("FetchPoolSentiment failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > poolSentimentDeadline {
+		t.Fatalf("batch took %v, longer than deadline", elapsed)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 successful symbols, got %d: %v", len(results), results)
+	}
+	if _, ok := results["SLOWUSDT"]; ok {
+		t.Fatal("slow symbol should be skipped")
+	}
+	if results["BTCUSDT"].LongShortRatio != 1.2 {
+		t.Fatalf("unexpected BTCUSDT data: %+v", results["BTCUSDT"])
+	}
+	if m := atomic.LoadInt32(&maxInFlight); m > 2 {
+		t.Fatalf("max concurrency %d exceeded limit 2", m)
+	}
+
+	poolSentimentEnhancer = func(symbol string, oi *OIData) error { return nil }
+	if _, err := FetchPoolSentiment([]string{"BTCUSDT"}, 1); err == nil {
+		t.Fatal("expected error when all symbols fail")
+	}
+}
+
+func TestFetchLongShortRatioHistory(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`[
+			{"symbol":"BTCUSDT","longShortRatio":"1.5","longAccount":"0.6","shortAccount":"0.4","timestamp":1735689900000},
+			{"symbol":"BTCUSDT","longShortRatio":"1.2","longAccount":"0.5454","shortAccount":"0.4546","timestamp":1735689600000}
+		]`))
+	}))
+	defer server.Close()
+
+	orig := longShortRatioHistoryURL
+	defer func() { longShortRatioHistoryURL = orig }()
+	longShortRatioHistoryURL = server.URL
+
+	points, err := FetchLongShortRatioHistory("btc", "5m", 1000)
+	if err != nil {
+		t.Fatalf("FetchLongShortRatioHistory failed: %v", err)
+	}
+	if query != "symbol=BTCUSDT&period=5m&limit=500" {
+		t.Fatalf("unexpected query: %s", query)
+	}
+	if len(points) != 2 || !points[0].Timestamp.Before(points[1].Timestamp) {
+		t.Fatalf("expected 2 points in ascending order, got %+v", points)
+	}
+	if points[0].LongShortRatio != 1.2 || points[1].LongAccount != 0.6 {
+		t.Fatalf("unexpected values: %+v", points)
+	}
+
+	if _, err := FetchLongShortRatioHistory("BTCUSDT", "3m", 10); err == nil {
+		t.Fatal("expected error for unsupported period")
+	}
+}
@@ -0,0 +1,100 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TimeSkewThreshold 本地时钟与交易所服务器时间的最大允许偏差
+// Binance 默认 recvWindow 为 5 秒，Hyperliquid 的 nonce 也基于毫秒时间戳，偏差过大会导致签名请求被拒
+const TimeSkewThreshold = 2 * time.Second
+
+// timeSkewEndpoints 各交易所的服务器时间接口
+var timeSkewEndpoints = map[string]string{
+	"binance":     defaultBaseURL + "/fapi/v1/time",
+	"aster":       "https://fapi.asterdex.com/fapi/v1/time",
+	"hyperliquid": "https://api.hyperliquid.xyz/info",
+}
+
+var timeSkewClient = &http.Client{Timeout: 10 * time.Second}
+
+// CheckTimeSkew 比较本地时间与交易所服务器时间，返回偏差（正数表示本地时钟快于服务器）
+// Binance/Aster 使用 /fapi/v1/time 的毫秒时间戳；Hyperliquid 没有时间接口，使用响应的 Date 头（秒级精度）
+// 偏差超过 TimeSkewThreshold 时输出警告
+func CheckTimeSkew(exchangeType string) (time.Duration, error) {
+	exchangeType = strings.ToLower(strings.TrimSpace(exchangeType))
+	endpoint, ok := timeSkewEndpoints[exchangeType]
+	if !ok {
+		return 0, fmt.Errorf("不支持检查 %s 的服务器时间", exchangeType)
+	}
+
+	var req *http.Request
+	var err error
+	if exchangeType == "hyperliquid" {
+		req, err = http.NewRequest(http.MethodPost, endpoint, bytes.NewReader([]byte(`{"type":"exchangeStatus"}`)))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	} else {
+		req, err = http.NewRequest(http.MethodGet, endpoint, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	resp, err := doRequest(timeSkewClient, req)
+	if err != nil {
+		return 0, fmt.Errorf("获取 %s 服务器时间失败: %w", exchangeType, err)
+	}
+	defer resp.Body.Close()
+	rtt := time.Since(start)
+	// 以请求往返的中点作为服务器生成时间戳时的本地时间
+	localAt := start.Add(rtt / 2)
+
+	serverAt, err := parseServerTime(resp)
+	if err != nil {
+		return 0, fmt.Errorf("解析 %s 服务器时间失败: %w", exchangeType, err)
+	}
+
+	skew := localAt.Sub(serverAt)
+	if exceedsTimeSkew(skew) {
+		log.Printf("⚠️ 本地时钟与 %s 服务器相差 %v（超过 %v），签名请求可能失败，请同步系统时间（例如启用 NTP）",
+			exchangeType, skew.Round(time.Millisecond), TimeSkewThreshold)
+	}
+	return skew, nil
+}
+
+// exceedsTimeSkew 判断偏差是否超过阈值
+func exceedsTimeSkew(skew time.Duration) bool {
+	return skew > TimeSkewThreshold || skew < -TimeSkewThreshold
+}
+
+// parseServerTime 优先读取响应体中的 serverTime（毫秒），否则使用 Date 头
+func parseServerTime(resp *http.Response) (time.Time, error) {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		body, err := readResponseBody(resp)
+		if err == nil {
+			var data struct {
+				ServerTime int64 `json:"serverTime"`
+			}
+			if json.Unmarshal(body, &data) == nil && data.ServerTime > 0 {
+				return time.UnixMilli(data.ServerTime), nil
+			}
+		}
+	}
+	if date := resp.Header.Get("Date"); date != "" {
+		t, err := http.ParseTime(date)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// Date 头只精确到秒，取该秒的中点减小截断误差
+		return t.Add(500 * time.Millisecond), nil
+	}
+	return time.Time{}, fmt.Errorf("响应中没有服务器时间")
+}
@@ -0,0 +1,68 @@
+package market
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func withTimeSkewEndpoint(t *testing.T, exchange, url string) {
+	t.Helper()
+	old, existed := timeSkewEndpoints[exchange]
+	timeSkewEndpoints[exchange] = url
+	t.Cleanup(func() {
+		if existed {
+			timeSkewEndpoints[exchange] = old
+		} else {
+			delete(timeSkewEndpoints, exchange)
+		}
+	})
+}
+
+func TestCheckTimeSkew_ServerTimeBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 服务器时间比本地慢 5 秒
+		fmt.Fprintf(w, `{"serverTime":%d}`, time.Now().Add(-5*time.Second).UnixMilli())
+	}))
+	defer server.Close()
+	withTimeSkewEndpoint(t, "binance", server.URL)
+
+	skew, err := CheckTimeSkew("Binance")
+	if err != nil {
+		t.Fatalf("CheckTimeSkew failed: %v", err)
+	}
+	if skew < 4900*time.Millisecond || skew > 5100*time.Millisecond {
+		t.Fatalf("expected ~5s skew, got %v", skew)
+	}
+	if !exceedsTimeSkew(skew) {
+		t.Fatal("5s skew should exceed threshold")
+	}
+}
+
+func TestCheckTimeSkew_DateHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		w.Header().Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	withTimeSkewEndpoint(t, "hyperliquid", server.URL)
+
+	skew, err := CheckTimeSkew("hyperliquid")
+	if err != nil {
+		t.Fatalf("CheckTimeSkew failed: %v", err)
+	}
+	if exceedsTimeSkew(skew) {
+		t.Fatalf("in-sync clock reported as skewed: %v", skew)
+	}
+}
+
+func TestCheckTimeSkew_UnsupportedExchange(t *testing.T) {
+	if _, err := CheckTimeSkew("unknown"); err == nil {
+		t.Fatal("expected error for unsupported exchange")
+	}
+}
@@ -31,6 +31,8 @@ type Client struct {
 	Timeout    time.Duration
 	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
 	MaxTokens  int  // AI响应的最大token数
+
+	CustomHeaders map[string]string // 自定义请求头（例如 LiteLLM / Azure 网关需要的额外头）
 }
 
 func New() AIClient {
@@ -73,6 +75,19 @@ func (client *Client) SetAPIKey(apiKey, apiURL, customModel string) {
 	client.Timeout = 120 * time.Second
 }
 
+// SetCustomHeaders 设置每次请求附带的自定义HTTP请求头
+// 自定义请求头在认证头之后写入，同名时覆盖默认值
+func (client *Client) SetCustomHeaders(headers map[string]string) {
+	if len(headers) == 0 {
+		client.CustomHeaders = nil
+		return
+	}
+	client.CustomHeaders = make(map[string]string, len(headers))
+	for name, value := range headers {
+		client.CustomHeaders[name] = value
+	}
+}
+
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
 func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
 	if client.APIKey == "" {
@@ -184,6 +199,9 @@ func (client *Client) callOnce(systemPrompt, userPrompt string) (string, error)
 	req.Header.Set("Content-Type", "application/json")
 
 	client.setAuthHeader(req.Header)
+	for name, value := range client.CustomHeaders {
+		req.Header.Set(name, value)
+	}
 
 	// 发送请求
 	httpClient := &http.Client{Timeout: client.Timeout}
@@ -678,6 +678,44 @@ func TestCallWithMessages_EmptyPrompts(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// Test 14: Custom Headers
+// =============================================================================
+
+func TestCallWithMessages_CustomHeaders(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("api-key"); got != "azure-key" {
+			t.Errorf("expected api-key header azure-key, got %q", got)
+		}
+		if got := r.Header.Get("X-LiteLLM-Tag"); got != "nofx" {
+			t.Errorf("expected X-LiteLLM-Tag header nofx, got %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key-1234567890" {
+			t.Errorf("Authorization header should be preserved, got %q", got)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"content": "ok"}},
+			},
+		})
+	}))
+	defer mockServer.Close()
+
+	aiClient := New()
+	aiClient.SetAPIKey("test-key-1234567890", mockServer.URL, "test-model")
+	headers := map[string]string{"api-key": "azure-key", "X-LiteLLM-Tag": "nofx"}
+	aiClient.SetCustomHeaders(headers)
+
+	// 调用方后续修改 map 不应影响客户端
+	headers["X-LiteLLM-Tag"] = "changed"
+
+	if _, err := aiClient.CallWithMessages("system prompt", "user prompt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 // =============================================================================
 // Benchmark Tests
 // =============================================================================
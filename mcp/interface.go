@@ -5,6 +5,8 @@ import "net/http"
 // AIClient AI客户端接口
 type AIClient interface {
 	SetAPIKey(apiKey string, customURL string, customModel string)
+	// SetCustomHeaders 设置每次请求附带的自定义HTTP请求头
+	SetCustomHeaders(headers map[string]string)
 	// CallWithMessages 使用 system + user prompt 调用AI API
 	CallWithMessages(systemPrompt, userPrompt string) (string, error)
 
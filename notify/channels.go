@@ -0,0 +1,218 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const channelTimeout = 10 * time.Second
+
+// postJSON 发送 JSON 请求，非 2xx 响应转换为 *HTTPError
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respBody, &HTTPError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Body:       strings.TrimSpace(string(respBody)),
+		}
+	}
+	return respBody, nil
+}
+
+// TelegramChannel 通过 Bot API 发送消息
+type TelegramChannel struct {
+	name   string
+	apiURL string
+	chatID string
+	client *http.Client
+}
+
+// NewTelegramChannel 创建 Telegram 渠道
+func NewTelegramChannel(botToken, chatID string) *TelegramChannel {
+	return &TelegramChannel{
+		name:   "telegram",
+		apiURL: "https://api.telegram.org/bot" + botToken + "/sendMessage",
+		chatID: chatID,
+		client: &http.Client{Timeout: channelTimeout},
+	}
+}
+
+// NewTelegramChannels 为每个目标创建一个 Telegram 渠道
+// 单个目标时渠道名为 "telegram"；多个目标时为 "telegram:<chat_id>"，各目标独立退避
+func NewTelegramChannels(botToken string, chatIDs []string) []Channel {
+	channels := make([]Channel, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		ch := NewTelegramChannel(botToken, chatID)
+		if len(chatIDs) > 1 {
+			ch.name = "telegram:" + chatID
+		}
+		channels = append(channels, ch)
+	}
+	return channels
+}
+
+func (c *TelegramChannel) Name() string { return c.name }
+
+func (c *TelegramChannel) Send(ctx context.Context, message string) error {
+	respBody, err := postJSON(ctx, c.client, c.apiURL, map[string]string{"chat_id": c.chatID, "text": message})
+	if httpErr, ok := err.(*HTTPError); ok && httpErr.RetryAfter == 0 {
+		// Telegram 限流时在响应体 parameters.retry_after 中给出等待秒数
+		var tgResp struct {
+			Parameters struct {
+				RetryAfter int `json:"retry_after"`
+			} `json:"parameters"`
+		}
+		if json.Unmarshal(respBody, &tgResp) == nil && tgResp.Parameters.RetryAfter > 0 {
+			httpErr.RetryAfter = time.Duration(tgResp.Parameters.RetryAfter) * time.Second
+		}
+	}
+	return err
+}
+
+// DiscordChannel 通过 Discord Webhook 发送消息
+type DiscordChannel struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewDiscordChannel 创建 Discord 渠道
+func NewDiscordChannel(webhookURL string) *DiscordChannel {
+	return &DiscordChannel{webhookURL: webhookURL, client: &http.Client{Timeout: channelTimeout}}
+}
+
+func (c *DiscordChannel) Name() string { return "discord" }
+
+func (c *DiscordChannel) Send(ctx context.Context, message string) error {
+	respBody, err := postJSON(ctx, c.client, c.webhookURL, map[string]string{"content": message})
+	if httpErr, ok := err.(*HTTPError); ok && httpErr.RetryAfter == 0 {
+		// Discord 限流时在响应体 retry_after 中给出等待秒数（可带小数）
+		var dcResp struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(respBody, &dcResp) == nil && dcResp.RetryAfter > 0 {
+			httpErr.RetryAfter = time.Duration(dcResp.RetryAfter * float64(time.Second))
+		}
+	}
+	return err
+}
+
+// parseTargets 解析逗号分隔的目标列表（去除空白和重复项）
+func parseTargets(raw string) []string {
+	seen := make(map[string]bool)
+	var targets []string
+	for _, target := range strings.Split(raw, ",") {
+		target = strings.TrimSpace(target)
+		if target != "" && !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// NewDispatcherFromEnv 根据环境变量创建分发器
+// TG_BOT_TOKEN + TG_TARGET_ID 启用 Telegram（TG_TARGET_ID 可用逗号分隔多个目标），DISCORD_WEBHOOK_URL 启用 Discord
+// NOTIFY_FALLBACK_CHANNEL=telegram|discord 时该渠道只作为其余渠道的备用，主渠道重试耗尽后才发送
+func NewDispatcherFromEnv() (*Dispatcher, error) {
+	var telegram, discord []Channel
+	if token := strings.TrimSpace(os.Getenv("TG_BOT_TOKEN")); token != "" {
+		targets := parseTargets(os.Getenv("TG_TARGET_ID"))
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("已配置 TG_BOT_TOKEN 但缺少 TG_TARGET_ID")
+		}
+		telegram = NewTelegramChannels(token, targets)
+	}
+	if url := strings.TrimSpace(os.Getenv("DISCORD_WEBHOOK_URL")); url != "" {
+		discord = []Channel{NewDiscordChannel(url)}
+	}
+
+	var primaries, backups []Channel
+	switch fallback := strings.ToLower(strings.TrimSpace(os.Getenv("NOTIFY_FALLBACK_CHANNEL"))); fallback {
+	case "":
+		primaries = append(telegram, discord...)
+	case "telegram":
+		primaries, backups = discord, telegram
+	case "discord":
+		primaries, backups = telegram, discord
+	default:
+		return nil, fmt.Errorf("NOTIFY_FALLBACK_CHANNEL 无效: %s（可选 telegram、discord）", fallback)
+	}
+	if len(backups) == 0 || len(primaries) == 0 {
+		// 备用渠道未配置，或除备用渠道外没有其他渠道：全部作为主渠道
+		return NewDispatcher(append(primaries, backups...)...), nil
+	}
+
+	d := NewDispatcher(primaries...)
+	for _, ch := range primaries {
+		d.SetFallback(ch.Name(), backups...)
+	}
+	return d, nil
+}
+
+var (
+	defaultOnce       sync.Once
+	defaultDispatcher *Dispatcher
+)
+
+// Default 返回按环境变量初始化的全局分发器（首次调用时创建）
+func Default() *Dispatcher {
+	defaultOnce.Do(func() {
+		d, err := NewDispatcherFromEnv()
+		if err != nil {
+			log.Printf("⚠️ [Notify] 通知渠道配置无效，已禁用通知: %v", err)
+			d = NewDispatcher()
+		}
+		defaultDispatcher = d
+	})
+	return defaultDispatcher
+}
+
+// Notify 以 Info 级别发送通知，未配置任何渠道时直接忽略
+func Notify(message string) {
+	NotifyLevel(LevelInfo, message)
+}
+
+// NotifyLevel 按级别发送通知：启用汇总模式（NOTIFY_DIGEST_WINDOW）时非 Error 级别会先缓存再合并发送
+func NotifyLevel(level Level, message string) {
+	if len(Default().channels) == 0 {
+		return
+	}
+	DefaultDigest().Add(level, message)
+}
+
+// dispatchAsync 通过分发器异步发送消息
+func dispatchAsync(d *Dispatcher, message string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := d.Dispatch(ctx, message); err != nil {
+			log.Printf("⚠️ [Notify] 通知发送失败: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDigestEntries 单个汇总最多缓存的通知条数，超过时立即发送，避免消息过长
+const maxDigestEntries = 50
+
+// Level 通知级别
+type Level int
+
+const (
+	LevelInfo Level = iota
+	LevelWarn
+	LevelError // Error 级别不等待汇总窗口，立即发送
+)
+
+type digestEntry struct {
+	level   Level
+	message string
+	at      time.Time
+}
+
+// Digest 通知汇总：在窗口期内缓存通知，到期后合并为一条消息发送
+// 高频交易员每笔成交都会产生通知，汇总模式可以显著降低打扰，Error 级别仍然立即送达
+type Digest struct {
+	mu      sync.Mutex
+	window  time.Duration
+	send    func(message string)
+	pending []digestEntry
+	timer   *time.Timer
+	now     func() time.Time
+}
+
+// NewDigest 创建通知汇总器，window <= 0 时不缓存，每条通知直接发送
+func NewDigest(window time.Duration, send func(message string)) *Digest {
+	return &Digest{window: window, send: send, now: time.Now}
+}
+
+// Add 加入一条通知；Error 级别会连同已缓存的通知立即发送
+func (g *Digest) Add(level Level, message string) {
+	if g.window <= 0 {
+		g.send(message)
+		return
+	}
+
+	g.mu.Lock()
+	g.pending = append(g.pending, digestEntry{level: level, message: message, at: g.now()})
+	if level < LevelError && len(g.pending) < maxDigestEntries {
+		if g.timer == nil {
+			g.timer = time.AfterFunc(g.window, g.Flush)
+		}
+		g.mu.Unlock()
+		return
+	}
+	g.mu.Unlock()
+	g.Flush()
+}
+
+// Flush 立即发送已缓存的通知（没有缓存时不发送）
+func (g *Digest) Flush() {
+	g.mu.Lock()
+	entries := g.pending
+	g.pending = nil
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	g.mu.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+	g.send(formatDigest(entries))
+}
+
+// Pending 当前缓存的通知条数
+func (g *Digest) Pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.pending)
+}
+
+// formatDigest 合并多条通知；只有一条时原样发送
+func formatDigest(entries []digestEntry) string {
+	if len(entries) == 1 {
+		return entries[0].message
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📬 通知汇总（%d 条，%s - %s）", len(entries),
+		entries[0].at.Format("15:04:05"), entries[len(entries)-1].at.Format("15:04:05"))
+	for _, entry := range entries {
+		prefix := "•"
+		switch entry.level {
+		case LevelWarn:
+			prefix = "⚠️"
+		case LevelError:
+			prefix = "🚨"
+		}
+		fmt.Fprintf(&b, "\n%s [%s] %s", prefix, entry.at.Format("15:04:05"), entry.message)
+	}
+	return b.String()
+}
+
+var (
+	digestOnce    sync.Once
+	defaultDigest *Digest
+)
+
+// digestWindowFromEnv 读取 NOTIFY_DIGEST_WINDOW（如 "5m"、"300s"），未配置或无效时关闭汇总
+func digestWindowFromEnv() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("NOTIFY_DIGEST_WINDOW"))
+	if raw == "" {
+		return 0
+	}
+	window, err := time.ParseDuration(raw)
+	if err != nil || window < 0 {
+		log.Printf("⚠️ [Notify] NOTIFY_DIGEST_WINDOW 无效（%q），已关闭汇总模式", raw)
+		return 0
+	}
+	return window
+}
+
+// DefaultDigest 返回全局通知汇总器（首次调用时按环境变量创建，汇总内容经全局分发器发送）
+func DefaultDigest() *Digest {
+	digestOnce.Do(func() {
+		window := digestWindowFromEnv()
+		if window > 0 {
+			log.Printf("📬 [Notify] 已启用通知汇总模式，窗口 %s", window)
+		}
+		defaultDigest = NewDigest(window, func(message string) { dispatchAsync(Default(), message) })
+	})
+	return defaultDigest
+}
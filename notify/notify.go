@@ -0,0 +1,246 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认退避参数
+const (
+	defaultMaxAttempts = 3
+	defaultBaseBackoff = 1 * time.Second
+	defaultMaxBackoff  = 60 * time.Second
+	// defaultMaxRetryAfter Retry-After 的上限（默认扫描间隔 3 分钟），避免异常的上游让通知长时间挂起
+	defaultMaxRetryAfter = 3 * time.Minute
+)
+
+// Channel 通知渠道（Telegram、Discord 等）
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, message string) error
+}
+
+// HTTPError 渠道返回的 HTTP 错误，RetryAfter 来自 Retry-After 头或渠道自身的限流字段
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("HTTP %d (retry after %s): %s", e.StatusCode, e.RetryAfter, e.Body)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable 4xx 中仅 429 值得重试，其余视为配置错误
+func (e *HTTPError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// parseRetryAfter 解析 Retry-After 头（秒数或 HTTP 日期）
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// channelState 单个渠道的退避状态
+type channelState struct {
+	mu       sync.Mutex
+	failures int
+	until    time.Time // 在此之前不再向该渠道发送
+}
+
+// Dispatcher 将消息并发分发到所有渠道
+// 每个渠道独立维护退避状态：一个渠道被限流只会推迟它自己，不影响其他渠道
+type Dispatcher struct {
+	channels      []Channel
+	fallbacks     map[string][]Channel // 主渠道名 -> 备用渠道（主渠道重试耗尽后才使用）
+	states        map[string]*channelState
+	maxAttempts   int
+	baseBackoff   time.Duration
+	maxBackoff    time.Duration
+	maxRetryAfter time.Duration
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+	rand  func() float64
+}
+
+// NewDispatcher 创建通知分发器
+func NewDispatcher(channels ...Channel) *Dispatcher {
+	d := &Dispatcher{
+		channels:      channels,
+		fallbacks:     make(map[string][]Channel),
+		states:        make(map[string]*channelState, len(channels)),
+		maxAttempts:   defaultMaxAttempts,
+		baseBackoff:   defaultBaseBackoff,
+		maxBackoff:    defaultMaxBackoff,
+		maxRetryAfter: defaultMaxRetryAfter,
+		now:           time.Now,
+		sleep:         sleepContext,
+		rand:          rand.Float64,
+	}
+	for _, ch := range channels {
+		d.states[ch.Name()] = &channelState{}
+	}
+	return d
+}
+
+// Channels 返回已注册的渠道名称
+func (d *Dispatcher) Channels() []string {
+	names := make([]string, 0, len(d.channels))
+	for _, ch := range d.channels {
+		names = append(names, ch.Name())
+	}
+	return names
+}
+
+// SetMaxRetryAfter 设置渠道 Retry-After 的上限，超过上限时按上限等待（<= 0 时恢复默认值）
+func (d *Dispatcher) SetMaxRetryAfter(limit time.Duration) {
+	if limit <= 0 {
+		limit = defaultMaxRetryAfter
+	}
+	d.maxRetryAfter = limit
+}
+
+// SetFallback 为主渠道设置备用渠道：主渠道重试耗尽（或返回不可重试错误）后依次尝试备用渠道，
+// 任一备用渠道发送成功即视为送达。备用渠道有独立的退避状态，平时不接收消息
+func (d *Dispatcher) SetFallback(primary string, backups ...Channel) {
+	d.fallbacks[primary] = backups
+	for _, ch := range backups {
+		if _, ok := d.states[ch.Name()]; !ok {
+			d.states[ch.Name()] = &channelState{}
+		}
+	}
+}
+
+// Dispatch 并发发送到所有渠道，返回各渠道最终失败的聚合错误
+func (d *Dispatcher) Dispatch(ctx context.Context, message string) error {
+	errs := make([]error, len(d.channels))
+	var wg sync.WaitGroup
+	for i, ch := range d.channels {
+		wg.Add(1)
+		go func(i int, ch Channel) {
+			defer wg.Done()
+			if err := d.sendWithBackoff(ctx, ch, message); err != nil {
+				if fbErr := d.sendFallback(ctx, ch.Name(), message, err); fbErr != nil {
+					errs[i] = fmt.Errorf("%s: %w", ch.Name(), fbErr)
+				}
+			}
+		}(i, ch)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// sendFallback 主渠道发送失败后依次尝试其备用渠道；全部失败时返回包含主渠道错误的聚合错误
+func (d *Dispatcher) sendFallback(ctx context.Context, primary, message string, primaryErr error) error {
+	backups := d.fallbacks[primary]
+	if len(backups) == 0 || ctx.Err() != nil {
+		return primaryErr
+	}
+
+	errs := []error{primaryErr}
+	for _, backup := range backups {
+		log.Printf("🔀 [Notify] %s 发送失败，改用备用渠道 %s", primary, backup.Name())
+		err := d.sendWithBackoff(ctx, backup, message)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("备用渠道 %s: %w", backup.Name(), err))
+	}
+	return errors.Join(errs...)
+}
+
+// sendWithBackoff 按渠道退避状态发送，失败时指数退避+抖动重试
+func (d *Dispatcher) sendWithBackoff(ctx context.Context, ch Channel, message string) error {
+	state := d.states[ch.Name()]
+
+	var lastErr error
+	for attempt := 0; attempt < d.maxAttempts; attempt++ {
+		state.mu.Lock()
+		wait := state.until.Sub(d.now())
+		state.mu.Unlock()
+		if wait > 0 {
+			if err := d.sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+
+		lastErr = ch.Send(ctx, message)
+		if lastErr == nil {
+			state.mu.Lock()
+			state.failures = 0
+			state.until = time.Time{}
+			state.mu.Unlock()
+			return nil
+		}
+
+		var httpErr *HTTPError
+		if errors.As(lastErr, &httpErr) && !httpErr.retryable() {
+			return lastErr
+		}
+
+		delay := d.recordFailure(state, lastErr)
+		log.Printf("⚠️ [Notify] %s 发送失败（第%d次），%s 后重试: %v", ch.Name(), attempt+1, delay.Round(time.Millisecond), lastErr)
+	}
+	return lastErr
+}
+
+// recordFailure 累加失败次数并计算下一次可发送时间，优先采用渠道给出的 Retry-After（不超过 maxRetryAfter）
+func (d *Dispatcher) recordFailure(state *channelState, err error) time.Duration {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.failures++
+	backoff := d.baseBackoff << (state.failures - 1)
+	if backoff <= 0 || backoff > d.maxBackoff {
+		backoff = d.maxBackoff
+	}
+	// 抖动：在 [backoff/2, backoff] 之间取值，避免多个实例同时重试
+	delay := backoff/2 + time.Duration(d.rand()*float64(backoff/2))
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > delay {
+		delay = httpErr.RetryAfter
+		if d.maxRetryAfter > 0 && delay > d.maxRetryAfter {
+			log.Printf("⚠️ [Notify] Retry-After %s 超过上限，按 %s 等待", delay, d.maxRetryAfter)
+			delay = d.maxRetryAfter
+		}
+	}
+
+	if until := d.now().Add(delay); until.After(state.until) {
+		state.until = until
+	}
+	return delay
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
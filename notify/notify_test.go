@@ -0,0 +1,287 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChannel struct {
+	name  string
+	mu    sync.Mutex
+	errs  []error // 按调用顺序返回，用尽后返回 nil
+	calls int
+}
+
+func (c *fakeChannel) Name() string { return c.name }
+
+func (c *fakeChannel) Send(ctx context.Context, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+// newTestDispatcher 使用虚拟时钟，sleep 只推进时间并记录等待时长
+func newTestDispatcher(channels ...Channel) (*Dispatcher, func() []time.Duration) {
+	d := NewDispatcher(channels...)
+	var mu sync.Mutex
+	now := time.Unix(1700000000, 0)
+	var sleeps []time.Duration
+	d.now = func() time.Time { mu.Lock(); defer mu.Unlock(); return now }
+	d.sleep = func(ctx context.Context, dur time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sleeps = append(sleeps, dur)
+		now = now.Add(dur)
+		return nil
+	}
+	d.rand = func() float64 { return 0 }
+	return d, func() []time.Duration { mu.Lock(); defer mu.Unlock(); return append([]time.Duration(nil), sleeps...) }
+}
+
+func TestDispatcher_RateLimitedChannelDoesNotDelayOthers(t *testing.T) {
+	discord := &fakeChannel{name: "discord", errs: []error{&HTTPError{StatusCode: 429, RetryAfter: 7 * time.Second}}}
+	telegram := &fakeChannel{name: "telegram"}
+	d, sleeps := newTestDispatcher(discord, telegram)
+
+	require.NoError(t, d.Dispatch(context.Background(), "hello"))
+	assert.Equal(t, 2, discord.calls)
+	assert.Equal(t, 1, telegram.calls)
+	// 只有 discord 等待，且遵循 Retry-After
+	assert.Equal(t, []time.Duration{7 * time.Second}, sleeps())
+}
+
+func TestDispatcher_CapsRetryAfter(t *testing.T) {
+	ch := &fakeChannel{name: "discord", errs: []error{&HTTPError{StatusCode: 429, RetryAfter: 6 * time.Hour}}}
+	d, sleeps := newTestDispatcher(ch)
+
+	require.NoError(t, d.Dispatch(context.Background(), "hello"))
+	assert.Equal(t, []time.Duration{defaultMaxRetryAfter}, sleeps())
+
+	d.SetMaxRetryAfter(10 * time.Second)
+	ch.errs = []error{&HTTPError{StatusCode: 429, RetryAfter: time.Hour}}
+	require.NoError(t, d.Dispatch(context.Background(), "hello"))
+	assert.Equal(t, 10*time.Second, sleeps()[1])
+}
+
+func TestDispatcher_ExponentialBackoffAndGiveUp(t *testing.T) {
+	boom := &HTTPError{StatusCode: 503}
+	ch := &fakeChannel{name: "discord", errs: []error{boom, boom, boom}}
+	d, sleeps := newTestDispatcher(ch)
+
+	err := d.Dispatch(context.Background(), "hello")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "discord")
+	assert.Equal(t, 3, ch.calls)
+	// rand=0 时取区间下限 backoff/2：1s→0.5s，2s→1s
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, sleeps())
+
+	// 失败后该渠道仍处于退避期，下一次发送先等待
+	d.Dispatch(context.Background(), "again")
+	assert.Equal(t, 2*time.Second, sleeps()[2])
+}
+
+func TestDispatcher_NonRetryableErrorStopsImmediately(t *testing.T) {
+	ch := &fakeChannel{name: "telegram", errs: []error{&HTTPError{StatusCode: 400}}}
+	d, sleeps := newTestDispatcher(ch)
+
+	require.Error(t, d.Dispatch(context.Background(), "hello"))
+	assert.Equal(t, 1, ch.calls)
+	assert.Empty(t, sleeps())
+}
+
+func TestDispatcher_FallbackOnlyAfterPrimaryRetriesExhausted(t *testing.T) {
+	boom := &HTTPError{StatusCode: 503}
+	primary := &fakeChannel{name: "telegram", errs: []error{boom, boom}}
+	backup := &fakeChannel{name: "discord"}
+	d, _ := newTestDispatcher(primary)
+	d.SetFallback("telegram", backup)
+
+	// 主渠道重试成功时不使用备用渠道
+	require.NoError(t, d.Dispatch(context.Background(), "hello"))
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, 0, backup.calls)
+
+	// 主渠道重试耗尽后改用备用渠道，送达即不返回错误
+	primary.errs = []error{boom, boom, boom}
+	primary.calls = 0
+	require.NoError(t, d.Dispatch(context.Background(), "down"))
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, 1, backup.calls)
+
+	// 备用渠道也失败时返回两个渠道的错误
+	primary.errs = []error{&HTTPError{StatusCode: 401}}
+	backup.errs = []error{&HTTPError{StatusCode: 403}}
+	err := d.Dispatch(context.Background(), "both down")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 401")
+	assert.Contains(t, err.Error(), "备用渠道 discord")
+}
+
+func TestNewDispatcherFromEnv_FallbackChannel(t *testing.T) {
+	t.Setenv("TG_BOT_TOKEN", "token")
+	t.Setenv("TG_TARGET_ID", "123")
+	t.Setenv("DISCORD_WEBHOOK_URL", "https://discord.example/webhook")
+
+	t.Setenv("NOTIFY_FALLBACK_CHANNEL", "discord")
+	d, err := NewDispatcherFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"telegram"}, d.Channels())
+	require.Len(t, d.fallbacks["telegram"], 1)
+	assert.Equal(t, "discord", d.fallbacks["telegram"][0].Name())
+
+	// 只配置了备用渠道时仍作为主渠道使用
+	t.Setenv("TG_BOT_TOKEN", "")
+	d, err = NewDispatcherFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"discord"}, d.Channels())
+	assert.Empty(t, d.fallbacks)
+
+	t.Setenv("NOTIFY_FALLBACK_CHANNEL", "pigeon")
+	_, err = NewDispatcherFromEnv()
+	assert.Error(t, err)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 1500*time.Millisecond, parseRetryAfter("1.5", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+}
+
+func TestTelegramChannel_RetryAfterFromBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"ok":false,"error_code":429,"parameters":{"retry_after":12}}`))
+	}))
+	defer server.Close()
+
+	ch := NewTelegramChannel("token", "123")
+	ch.apiURL = server.URL
+	err := ch.Send(context.Background(), "hello")
+
+	httpErr, ok := err.(*HTTPError)
+	require.True(t, ok, "expected *HTTPError, got %v", err)
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+	assert.Equal(t, 12*time.Second, httpErr.RetryAfter)
+}
+
+func TestNewDispatcherFromEnv_MultipleTelegramTargets(t *testing.T) {
+	t.Setenv("TG_BOT_TOKEN", "token")
+	t.Setenv("DISCORD_WEBHOOK_URL", "")
+
+	t.Setenv("TG_TARGET_ID", "123")
+	d, err := NewDispatcherFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"telegram"}, d.Channels(), "单目标保持原渠道名")
+
+	t.Setenv("TG_TARGET_ID", " 123, -100456 ,123,")
+	d, err = NewDispatcherFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"telegram:123", "telegram:-100456"}, d.Channels())
+
+	t.Setenv("TG_TARGET_ID", " , ")
+	_, err = NewDispatcherFromEnv()
+	assert.Error(t, err)
+}
+
+func TestDispatcher_AggregatesTelegramTargetErrors(t *testing.T) {
+	var mu sync.Mutex
+	var chatIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ChatID string `json:"chat_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		chatIDs = append(chatIDs, body.ChatID)
+		mu.Unlock()
+		if body.ChatID == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"chat not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	channels := NewTelegramChannels("token", []string{"good", "bad"})
+	for _, ch := range channels {
+		ch.(*TelegramChannel).apiURL = server.URL
+	}
+	d, _ := newTestDispatcher(channels...)
+
+	err := d.Dispatch(context.Background(), "hello")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "telegram:bad")
+	assert.NotContains(t, err.Error(), "telegram:good")
+	assert.ElementsMatch(t, []string{"good", "bad"}, chatIDs)
+}
+
+func TestDigest_BuffersUntilFlushAndErrorFlushesImmediately(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	g := NewDigest(time.Hour, func(message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, message)
+	})
+
+	g.Add(LevelInfo, "开多 BTCUSDT")
+	g.Add(LevelWarn, "滑点过大")
+	assert.Empty(t, sent)
+	assert.Equal(t, 2, g.Pending())
+
+	g.Add(LevelError, "下单失败")
+	require.Len(t, sent, 1)
+	assert.Contains(t, sent[0], "通知汇总（3 条")
+	assert.Contains(t, sent[0], "开多 BTCUSDT")
+	assert.Contains(t, sent[0], "🚨")
+	assert.Zero(t, g.Pending())
+
+	// 只有一条时原样发送
+	g.Add(LevelInfo, "平仓 ETHUSDT")
+	g.Flush()
+	require.Len(t, sent, 2)
+	assert.Equal(t, "平仓 ETHUSDT", sent[1])
+
+	g.Flush()
+	assert.Len(t, sent, 2, "没有缓存时不发送")
+}
+
+func TestDigest_WindowExpiryAndDisabled(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	record := func(message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, message)
+	}
+
+	g := NewDigest(20*time.Millisecond, record)
+	g.Add(LevelInfo, "a")
+	g.Add(LevelInfo, "b")
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	NewDigest(0, record).Add(LevelInfo, "direct")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "direct", sent[len(sent)-1])
+}
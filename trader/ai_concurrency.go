@@ -0,0 +1,83 @@
+package trader
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultAISlotTimeout 等待全局 AI 并发名额的默认超时时间
+const defaultAISlotTimeout = 30 * time.Second
+
+// AIConcurrencyLimiter 全局 AI 并发限制（所有交易员共享）
+// 行情剧烈波动时大量交易员同时触发，用于限制同时进行的 AI 请求总数，平滑成本峰值并避免上游限流
+type AIConcurrencyLimiter struct {
+	mu      sync.Mutex
+	sem     chan struct{} // nil 表示不限制
+	timeout time.Duration
+}
+
+var (
+	aiConcurrencyLimiter     *AIConcurrencyLimiter
+	aiConcurrencyLimiterOnce sync.Once
+)
+
+// GetAIConcurrencyLimiter 获取全局 AI 并发限制器（单例，默认不限制）
+func GetAIConcurrencyLimiter() *AIConcurrencyLimiter {
+	aiConcurrencyLimiterOnce.Do(func() {
+		aiConcurrencyLimiter = NewAIConcurrencyLimiter(0, defaultAISlotTimeout)
+	})
+	return aiConcurrencyLimiter
+}
+
+// NewAIConcurrencyLimiter 创建并发限制器，limit <= 0 表示不限制
+func NewAIConcurrencyLimiter(limit int, timeout time.Duration) *AIConcurrencyLimiter {
+	l := &AIConcurrencyLimiter{}
+	l.Configure(limit, timeout)
+	return l
+}
+
+// Configure 更新并发上限和等待超时；正在进行的请求仍归还到旧的名额池，不受影响
+func (l *AIConcurrencyLimiter) Configure(limit int, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultAISlotTimeout
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timeout = timeout
+	if limit <= 0 {
+		l.sem = nil
+		return
+	}
+	if l.sem == nil || cap(l.sem) != limit {
+		l.sem = make(chan struct{}, limit)
+	}
+}
+
+// Acquire 在超时时间内等待一个并发名额，成功时返回释放函数
+// 返回 ok=false 表示超时未获得名额，调用方应延后到下个周期
+func (l *AIConcurrencyLimiter) Acquire() (release func(), ok bool) {
+	l.mu.Lock()
+	sem, timeout := l.sem, l.timeout
+	l.mu.Unlock()
+
+	if sem == nil {
+		return func() {}, true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case sem <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-sem }) }, true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// InFlight 当前正在进行的 AI 请求数（不限制时为 0）
+func (l *AIConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.sem)
+}
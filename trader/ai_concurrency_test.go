@@ -0,0 +1,58 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAIConcurrencyLimiter(t *testing.T) {
+	unlimited := NewAIConcurrencyLimiter(0, time.Second)
+	for i := 0; i < 5; i++ {
+		if _, ok := unlimited.Acquire(); !ok {
+			t.Fatal("unlimited limiter should always grant a slot")
+		}
+	}
+
+	l := NewAIConcurrencyLimiter(2, 20*time.Millisecond)
+	release1, ok1 := l.Acquire()
+	release2, ok2 := l.Acquire()
+	if !ok1 || !ok2 || l.InFlight() != 2 {
+		t.Fatalf("expected two slots, in-flight %d", l.InFlight())
+	}
+
+	start := time.Now()
+	if _, ok := l.Acquire(); ok {
+		t.Fatal("expected third acquire to time out")
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("acquire should wait for the timeout before giving up")
+	}
+
+	release1()
+	release1() // 重复释放不应多归还名额
+	if l.InFlight() != 1 {
+		t.Fatalf("expected 1 in-flight after release, got %d", l.InFlight())
+	}
+	release3, ok := l.Acquire()
+	if !ok {
+		t.Fatal("expected slot after release")
+	}
+	release2()
+	release3()
+
+	// 等待中的请求在名额释放后立即获得名额
+	held, _ := l.Acquire()
+	held2, _ := l.Acquire()
+	l.Configure(2, time.Second)
+	done := make(chan bool)
+	go func() {
+		_, ok := l.Acquire()
+		done <- ok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	held()
+	if !<-done {
+		t.Fatal("waiting acquire should succeed once a slot is released")
+	}
+	held2()
+}
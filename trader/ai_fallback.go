@@ -0,0 +1,81 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+
+	"nofx/decision"
+	"nofx/mcp"
+)
+
+// FallbackAIModel 备用AI模型配置（主模型调用失败时按顺序尝试）
+type FallbackAIModel struct {
+	AIModelID         int // ai_models.id
+	Provider          string
+	APIKey            string
+	CustomAPIURL      string
+	CustomModelName   string
+	CustomHeaders     map[string]string
+	RequestsPerMinute int // 该模型每分钟最多请求次数，0表示不限制
+}
+
+// fallbackAIClient 已初始化的备用AI客户端
+type fallbackAIClient struct {
+	model  FallbackAIModel
+	client mcp.AIClient
+}
+
+// newFallbackAIClients 为备用模型创建AI客户端（与 NewAutoTrader 中主模型的初始化方式一致）
+func newFallbackAIClients(models []FallbackAIModel) []fallbackAIClient {
+	clients := make([]fallbackAIClient, 0, len(models))
+	for _, model := range models {
+		var client mcp.AIClient
+		switch model.Provider {
+		case "qwen":
+			client = mcp.NewQwenClient()
+		case "deepseek":
+			client = mcp.NewDeepSeekClient()
+		default:
+			client = mcp.New()
+		}
+		client.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
+		if len(model.CustomHeaders) > 0 {
+			client.SetCustomHeaders(model.CustomHeaders)
+		}
+		clients = append(clients, fallbackAIClient{model: model, client: client})
+	}
+	return clients
+}
+
+// getDecisionWithFallback 请求AI决策，主模型失败时按顺序切换到备用模型
+// 返回最后一次尝试的决策（即使失败也保留思维链用于调试）
+func (at *AutoTrader) getDecisionWithFallback(ctx *decision.Context) (*decision.FullDecision, error) {
+	prompt := at.cyclePrompt(ctx)
+	fullDecision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, prompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	if err == nil || len(at.fallbackClients) == 0 {
+		return fullDecision, err
+	}
+
+	for _, fallback := range at.fallbackClients {
+		modelKey := fmt.Sprintf("%d", fallback.model.AIModelID)
+		if fallback.model.RequestsPerMinute > 0 {
+			if allowed, _ := GetAIModelRateLimiter().Allow(modelKey, fallback.model.RequestsPerMinute); !allowed {
+				log.Printf("⏳ [%s] 备用AI模型 %s 已达到每分钟请求上限，跳过", at.name, modelKey)
+				continue
+			}
+		}
+
+		log.Printf("🔁 [%s] AI决策失败 (%v)，切换到备用模型 %s (%s)", at.name, err, modelKey, fallback.model.Provider)
+		candidate, candidateErr := decision.GetFullDecisionWithCustomPrompt(ctx, fallback.client, prompt, at.overrideBasePrompt, at.systemPromptTemplate)
+		if candidate != nil {
+			fullDecision = candidate
+		}
+		if candidateErr == nil {
+			log.Printf("✅ [%s] 备用AI模型 %s (%s) 返回有效决策", at.name, modelKey, fallback.model.Provider)
+			return fullDecision, nil
+		}
+		err = candidateErr
+	}
+
+	return fullDecision, fmt.Errorf("主模型及 %d 个备用模型均失败: %w", len(at.fallbackClients), err)
+}
@@ -0,0 +1,117 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// RateCounter 带过期时间的计数器（语义与 Redis INCR + EXPIRE 一致）
+// 部署多实例时可替换为基于 Redis 的实现，使限流在实例间共享
+type RateCounter interface {
+	// Incr 将 key 的计数加1并返回新值，key 首次创建时设置 ttl 过期
+	Incr(key string, ttl time.Duration) (int64, error)
+}
+
+// memoryRateCounter 进程内计数器（默认实现）
+type memoryRateCounter struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounterEntry
+	now      func() time.Time
+}
+
+type memoryCounterEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// NewMemoryRateCounter 创建进程内计数器
+func NewMemoryRateCounter() RateCounter {
+	return &memoryRateCounter{
+		counters: make(map[string]*memoryCounterEntry),
+		now:      time.Now,
+	}
+}
+
+func (m *memoryRateCounter) Incr(key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	entry, exists := m.counters[key]
+	if !exists || !now.Before(entry.expiresAt) {
+		// 顺便清理已过期的 key，避免无限增长
+		for k, e := range m.counters {
+			if !now.Before(e.expiresAt) {
+				delete(m.counters, k)
+			}
+		}
+		entry = &memoryCounterEntry{expiresAt: now.Add(ttl)}
+		m.counters[key] = entry
+	}
+	entry.count++
+	return entry.count, nil
+}
+
+// AIModelRateLimiter 按 AI 模型配置（ai_model_id）限制每分钟请求数
+// 多个交易员共用同一个模型配置时共享额度，避免触发上游 429
+type AIModelRateLimiter struct {
+	counter RateCounter
+	now     func() time.Time
+}
+
+var (
+	aiModelRateLimiter     *AIModelRateLimiter
+	aiModelRateLimiterOnce sync.Once
+)
+
+// GetAIModelRateLimiter 获取全局 AI 模型限流器（单例）
+func GetAIModelRateLimiter() *AIModelRateLimiter {
+	aiModelRateLimiterOnce.Do(func() {
+		aiModelRateLimiter = NewAIModelRateLimiter(NewMemoryRateCounter())
+	})
+	return aiModelRateLimiter
+}
+
+// NewAIModelRateLimiter 创建 AI 模型限流器
+func NewAIModelRateLimiter(counter RateCounter) *AIModelRateLimiter {
+	if counter == nil {
+		counter = NewMemoryRateCounter()
+	}
+	return &AIModelRateLimiter{
+		counter: counter,
+		now:     time.Now,
+	}
+}
+
+// SetCounter 替换底层计数器（例如切换为 Redis 实现）
+func (l *AIModelRateLimiter) SetCounter(counter RateCounter) {
+	if counter != nil {
+		l.counter = counter
+	}
+}
+
+// Allow 尝试占用一次请求额度
+// rpm <= 0 表示不限流；返回 false 时 retryAfter 为距离下一个窗口的时间
+func (l *AIModelRateLimiter) Allow(modelKey string, rpm int) (allowed bool, retryAfter time.Duration) {
+	if rpm <= 0 || modelKey == "" {
+		return true, 0
+	}
+
+	now := l.now()
+	window := now.Truncate(time.Minute)
+	key := fmt.Sprintf("ai_rate:%s:%d", modelKey, window.Unix())
+
+	count, err := l.counter.Incr(key, time.Minute)
+	if err != nil {
+		// 计数器不可用时放行，避免限流组件故障导致交易停摆
+		log.Printf("⚠️ AI限流计数失败，放行本次请求: %v", err)
+		return true, 0
+	}
+
+	if count > int64(rpm) {
+		return false, window.Add(time.Minute).Sub(now)
+	}
+	return true, 0
+}
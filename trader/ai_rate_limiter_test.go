@@ -0,0 +1,62 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingRateCounter struct{}
+
+func (failingRateCounter) Incr(key string, ttl time.Duration) (int64, error) {
+	return 0, errors.New("counter unavailable")
+}
+
+// TestAIModelRateLimiter_PerModelWindow 测试按模型计数且窗口结束后恢复额度
+func TestAIModelRateLimiter_PerModelWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 10, 0, time.UTC)
+	counter := NewMemoryRateCounter().(*memoryRateCounter)
+	counter.now = func() time.Time { return now }
+	limiter := NewAIModelRateLimiter(counter)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("1", 2); !ok {
+			t.Fatalf("第 %d 次请求应被放行", i+1)
+		}
+	}
+
+	ok, retryAfter := limiter.Allow("1", 2)
+	if ok {
+		t.Fatal("超过每分钟额度的请求应被限流")
+	}
+	if retryAfter != 50*time.Second {
+		t.Errorf("retryAfter 应为 50s，实际 %v", retryAfter)
+	}
+
+	// 不同模型互不影响
+	if ok, _ := limiter.Allow("2", 2); !ok {
+		t.Error("其他模型的请求不应受影响")
+	}
+
+	// 下一个窗口恢复额度
+	now = now.Add(time.Minute)
+	if ok, _ := limiter.Allow("1", 2); !ok {
+		t.Error("新窗口内请求应被放行")
+	}
+
+	// rpm <= 0 不限流
+	for i := 0; i < 10; i++ {
+		if ok, _ := limiter.Allow("1", 0); !ok {
+			t.Fatal("rpm=0 时不应限流")
+		}
+	}
+}
+
+// TestAIModelRateLimiter_CounterFailureAllows 测试计数器故障时放行
+func TestAIModelRateLimiter_CounterFailureAllows(t *testing.T) {
+	limiter := NewAIModelRateLimiter(failingRateCounter{})
+	if ok, _ := limiter.Allow("1", 1); !ok {
+		t.Error("计数器故障时应放行请求")
+	}
+}
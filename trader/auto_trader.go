@@ -2,6 +2,7 @@ package trader
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -10,7 +11,9 @@ import (
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/notify"
 	"nofx/pool"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,8 +30,9 @@ type AutoTraderConfig struct {
 	Exchange string // "binance", "hyperliquid" 或 "aster"
 
 	// 币安API配置
-	BinanceAPIKey    string
-	BinanceSecretKey string
+	BinanceAPIKey      string
+	BinanceSecretKey   string
+	BinanceAccountMode string // standard / portfolio_margin
 
 	// Hyperliquid配置
 	HyperliquidPrivateKey string
@@ -52,6 +56,14 @@ type AutoTraderConfig struct {
 	CustomAPIURL    string
 	CustomAPIKey    string
 	CustomModelName string
+	CustomHeaders   map[string]string // 自定义HTTP请求头（ai_models.custom_headers）
+
+	// 备用AI模型（主模型失败时按顺序故障转移）
+	FallbackAIModels []FallbackAIModel
+
+	// AI限流配置（按 ai_model_id 共享额度）
+	AIModelID           int // AI模型配置ID（ai_models.id）
+	AIRequestsPerMinute int // 该模型每分钟最多请求次数，0表示不限制
 
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
@@ -87,12 +99,38 @@ type AutoTraderConfig struct {
 	SystemPromptTemplate string // 系统提示词模板名称（如 "default", "aggressive"）
 
 	// 订单策略配置
-	OrderStrategy       string  // Order strategy: "market_only", "conservative_hybrid", "limit_only"
-	LimitPriceOffset    float64 // Limit order price offset percentage (e.g., -0.03 for -0.03%)
-	LimitTimeoutSeconds int     // Timeout in seconds before converting to market order
+	OrderStrategy        string  // Order strategy: "market_only", "conservative_hybrid", "limit_only"
+	BTCETHOrderStrategy  string  // BTC/ETH订单策略（为空时使用 OrderStrategy）
+	AltcoinOrderStrategy string  // 山寨币订单策略（为空时使用 OrderStrategy）
+	LimitPriceOffset     float64 // Limit order price offset percentage (e.g., -0.03 for -0.03%)
+	LimitTimeoutSeconds  int     // Timeout in seconds before converting to market order
 
 	// K线时间线配置
 	Timeframes []string // K线时间线选择，例如: ["1m", "15m", "1h", "4h"]
+
+	// 连续亏损冷却
+	LossStreakThreshold int           // 连续亏损N笔后暂停交易，0表示关闭
+	LossCooldown        time.Duration // 冷却时长（从最后一笔亏损起算，默认60分钟）
+
+	// 单周期下单上限
+	MaxOrdersPerCycle int // 单个决策周期最多执行的开仓动作数（平仓不计入），0表示不限制
+
+	// 移动止损（按价格百分比，0表示关闭）
+	TrailingStopPercent float64 // 止损价距持仓期间最优价的回撤百分比
+	TrailingActivation  float64 // 价格相对开仓价浮盈达到该百分比后开始移动止损
+
+	// 各时间线传给AI的K线数量（时间线 -> 数量），未配置的时间线使用 market.DefaultCandleLookback
+	CandleLookback map[string]int
+
+	// 币种仓位权重（币种 -> 权重），未列出的币种权重为1，0表示不开仓
+	SymbolWeights map[string]float64
+
+	// OCO 止盈止损（按开仓价百分比，0表示使用AI给出的止盈止损价）
+	TakeProfitPercent float64
+	StopLossPercent   float64
+
+	// 交易员时区（用于切分交易日），nil 表示 UTC
+	Timezone *time.Location
 }
 
 // AutoTrader 自动交易器
@@ -104,12 +142,15 @@ type AutoTrader struct {
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
 	mcpClient             mcp.AIClient
+	fallbackClients       []fallbackAIClient     // 备用AI客户端（按顺序故障转移）
 	decisionLogger        logger.IDecisionLogger // 决策日志记录器
 	initialBalance        float64
 	dailyPnL              float64
 	dailyPnLBase          float64
 	needsDailyBaseline    bool
 	customPrompt          string   // 自定义交易策略prompt
+	promptTemplateName    string   // 引用的数据库提示词模板名称（每个周期解析，优先于 customPrompt）
+	extraPrompt           string   // 本周期附加的prompt（例如webhook告警内容），周期结束后清空
 	overrideBasePrompt    bool     // 是否覆盖基础prompt
 	systemPromptTemplate  string   // 系统提示词模板名称
 	timeframes            []string // K线时间线配置
@@ -121,21 +162,36 @@ type AutoTrader struct {
 	oiTopAPIURL           string
 	lastResetTime         time.Time
 	stopUntil             time.Time
-	isRunning             bool
+	lossCooldownUntil     time.Time                        // 连续亏损冷却结束时间
+	lossStreakSince       time.Time                        // 只统计此时间之后的连续亏损（上一次冷却结束后重新计数）
+	isRunning             bool                             // 主循环是否运行（由 runMu 保护）
+	runMu                 sync.Mutex                       // 保护 isRunning 与 stopMonitorCh：Stop 与周期内停止可能并发
 	startTime             time.Time                        // 系统启动时间
 	callCount             int                              // AI调用次数
 	positionFirstSeenTime map[string]int64                 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
 	lastPositions         map[string]decision.PositionInfo // 上一次周期的持仓快照 (用于检测被动平仓)
+	lastPositionsAt       time.Time                        // 持仓快照时间（查询被动平仓成交的起点）
 	positionStopLoss      map[string]float64               // 持仓止损价格 (symbol_side -> stop_loss_price)
 	positionTakeProfit    map[string]float64               // 持仓止盈价格 (symbol_side -> take_profit_price)
 	stopMonitorCh         chan struct{}                    // 用于停止监控goroutine
 	monitorWg             sync.WaitGroup                   // 用于等待监控goroutine结束
+	cycleMu               sync.Mutex                       // 串行化交易周期（定时周期与webhook触发的周期）
 	peakPnLCache          map[string]float64               // 最高收益缓存 (symbol -> 峰值盈亏百分比)
 	peakPnLCacheMutex     sync.RWMutex                     // 缓存读写锁
+	trailingStops         map[string]float64               // 已挂出的移动止损价 (symbol_side -> stop_price)
+	trailingStopsMutex    sync.Mutex                       // 移动止损缓存锁
+	exitBrackets          map[string]string                // 模拟 OCO 的止盈止损 (symbol_side -> symbol)，持仓平掉后撤销剩余挂单
+	exitBracketsMutex     sync.Mutex                       // 模拟 OCO 记录锁
 	peakEquity            float64                          // 账户峰值净值，用于回撤计算
 	lastBalanceSyncTime   time.Time                        // 上次余额同步时间
+	lastBalanceSnapshot   time.Time                        // 上次记录交易所余额快照的时间
+	cycleBalanceCache     map[string]interface{}           // 本周期已读取的账户余额（周期结束后清空）
+	webhookAlerts         map[string]time.Time             // 最近一次带方向的 webhook 告警 (symbol_side -> 时间)
+	webhookScaleIns       map[string]bool                  // 本次告警允许加仓的持仓 (symbol_side)
+	webhookScaleInMutex   sync.Mutex                       // webhook 加仓记录锁
 	database              interface{}                      // 数据库引用（用于自动更新余额）
 	userID                string                           // 用户ID
+	logTail               traderLogBuffer                  // 最近的周期日志（内存环形缓冲区）
 }
 
 // NewAutoTrader 创建自动交易器
@@ -181,6 +237,10 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 			log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
 		}
 	}
+	if len(config.CustomHeaders) > 0 {
+		mcpClient.SetCustomHeaders(config.CustomHeaders)
+		log.Printf("🤖 [%s] 已设置 %d 个自定义AI请求头", config.Name, len(config.CustomHeaders))
+	}
 
 	// 设置默认交易平台
 	if config.Exchange == "" {
@@ -200,8 +260,13 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 
 	switch config.Exchange {
 	case "binance":
+		if isPortfolioMarginMode(config.BinanceAccountMode) {
+			log.Printf("🏦 [%s] 使用币安统一账户交易（仅市价单，固定全仓）", config.Name)
+			trader = NewPortfolioMarginTrader(config.BinanceAPIKey, config.BinanceSecretKey)
+			break
+		}
 		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
-		trader = NewFuturesTrader(
+		futuresTrader := NewFuturesTrader(
 			config.BinanceAPIKey,
 			config.BinanceSecretKey,
 			userID,
@@ -209,6 +274,8 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 			config.LimitPriceOffset,
 			config.LimitTimeoutSeconds,
 		)
+		futuresTrader.SetCategoryOrderStrategies(config.BTCETHOrderStrategy, config.AltcoinOrderStrategy)
+		trader = futuresTrader
 	case "hyperliquid":
 		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
 		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
@@ -241,7 +308,7 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		systemPromptTemplate = "adaptive"
 	}
 
-	return &AutoTrader{
+	at := &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
 		aiModel:               config.AIModel,
@@ -249,6 +316,7 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		config:                config,
 		trader:                trader,
 		mcpClient:             mcpClient,
+		fallbackClients:       newFallbackAIClients(config.FallbackAIModels),
 		decisionLogger:        decisionLogger,
 		initialBalance:        config.InitialBalance,
 		systemPromptTemplate:  systemPromptTemplate,
@@ -272,18 +340,24 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		monitorWg:             sync.WaitGroup{},
 		peakPnLCache:          make(map[string]float64),
 		peakPnLCacheMutex:     sync.RWMutex{},
+		trailingStops:         make(map[string]float64),
 		lastBalanceSyncTime:   time.Now(), // 初始化为当前时间
 		database:              database,
 		userID:                userID,
 		coinPoolAPIURL:        strings.TrimSpace(config.CoinPoolAPIURL),
 		oiTopAPIURL:           strings.TrimSpace(config.OITopAPIURL),
-	}, nil
+	}
+	at.restorePersistedLogs()
+	return at, nil
 }
 
 // Run 运行自动交易主循环
 func (at *AutoTrader) Run() error {
+	at.runMu.Lock()
 	at.isRunning = true
 	at.stopMonitorCh = make(chan struct{})
+	stopCh := at.stopMonitorCh
+	at.runMu.Unlock()
 	at.startTime = time.Now()
 
 	log.Println("🚀 AI驱动自动交易系统启动")
@@ -294,23 +368,19 @@ func (at *AutoTrader) Run() error {
 	defer at.monitorWg.Done()
 
 	// 启动回撤监控
-	at.startDrawdownMonitor()
+	at.startDrawdownMonitor(stopCh)
 
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
 
 	// 首次立即执行
-	if err := at.runCycle(); err != nil {
-		log.Printf("❌ 执行失败: %v", err)
-	}
+	at.runScheduledCycle()
 
-	for at.isRunning {
+	for at.running() {
 		select {
 		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
-				log.Printf("❌ 执行失败: %v", err)
-			}
-		case <-at.stopMonitorCh:
+			at.runScheduledCycle()
+		case <-stopCh:
 			log.Printf("[%s] ⏹ 收到停止信号，退出自动交易主循环", at.name)
 			return nil
 		}
@@ -319,23 +389,105 @@ func (at *AutoTrader) Run() error {
 	return nil
 }
 
+// runScheduledCycle 执行一次定时周期，处于维护窗口内时跳过
+func (at *AutoTrader) runScheduledCycle() {
+	if inMaintenanceWindow(time.Now()) {
+		// 维护窗口内交易员仍在正常调度，更新扫描时间避免被卡死检测误报
+		at.touchScan()
+		at.logf(LogLevelInfo, "[%s] 🛠️ 维护窗口内，跳过本周期", at.name)
+		return
+	}
+	if err := at.RunCycle(""); err != nil {
+		at.logf(LogLevelError, "❌ 执行失败: %v", err)
+	}
+}
+
+// running 主循环是否在运行
+func (at *AutoTrader) running() bool {
+	at.runMu.Lock()
+	defer at.runMu.Unlock()
+	return at.isRunning
+}
+
+// signalStop 唯一的停止入口：标记停止并关闭 stopMonitorCh（通知主循环和监控goroutine退出）
+// 在锁内检查并关闭，Stop 与周期内停止并发调用时只有一方生效，返回本次调用是否执行了停止
+func (at *AutoTrader) signalStop() bool {
+	at.runMu.Lock()
+	defer at.runMu.Unlock()
+	if !at.isRunning {
+		return false
+	}
+	at.isRunning = false
+	if at.stopMonitorCh != nil {
+		close(at.stopMonitorCh)
+	}
+	return true
+}
+
 // Stop 停止自动交易
 func (at *AutoTrader) Stop() {
-	if !at.isRunning {
+	if !at.signalStop() {
 		return
 	}
-	at.isRunning = false
-	close(at.stopMonitorCh) // 通知监控goroutine停止
-	at.monitorWg.Wait()     // 等待监控goroutine结束
+	at.monitorWg.Wait() // 等待监控goroutine结束
 	log.Println("⏹ 自动交易系统停止")
 }
 
+// traderStatusUpdater 交易员运行状态持久化（由 config.Database 实现）
+type traderStatusUpdater interface {
+	UpdateTraderStatus(userID, id string, isRunning bool, reason string) error
+}
+
+// PersistRunStatus 将运行状态及停止原因写入数据库，未配置数据库时忽略
+func (at *AutoTrader) PersistRunStatus(isRunning bool, reason string) error {
+	updater, ok := at.database.(traderStatusUpdater)
+	if !ok {
+		return nil
+	}
+	return updater.UpdateTraderStatus(at.userID, at.id, isRunning, reason)
+}
+
+// RunCycle 立即运行一个交易周期，与定时周期串行执行
+// extraPrompt 非空时附加到本周期的自定义策略之后（例如webhook告警内容）
+func (at *AutoTrader) RunCycle(extraPrompt string) error {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	at.extraPrompt = extraPrompt
+	defer func() {
+		at.extraPrompt = ""
+		at.cycleBalanceCache = nil
+	}()
+	err := at.runCycle()
+	at.trackCycleResult(err)
+	return err
+}
+
+// ErrCycleInProgress 交易周期正在执行，无法进行需要空闲状态的操作
+var ErrCycleInProgress = errors.New("交易周期执行中，请稍后再试")
+
+// WithCycleIdle 在没有交易周期执行时运行 fn，并在 fn 执行期间阻止新周期开始
+// 周期正在执行时不等待，直接返回 ErrCycleInProgress
+func (at *AutoTrader) WithCycleIdle(fn func() error) error {
+	if !at.cycleMu.TryLock() {
+		return ErrCycleInProgress
+	}
+	defer at.cycleMu.Unlock()
+	return fn()
+}
+
+// SetInitialBalance 更新初始余额基准（用于同步交易所余额后刷新内存中的PNL计算）
+func (at *AutoTrader) SetInitialBalance(balance float64) {
+	at.initialBalance = balance
+}
+
 // runCycle 运行一个交易周期（使用AI全权决策）
 func (at *AutoTrader) runCycle() error {
 	at.callCount++
+	at.touchScan()
 
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
-	log.Printf("⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
+	at.logf(LogLevelInfo, "⏰ %s - AI决策周期 #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
 	log.Println(strings.Repeat("=", 70))
 
 	// 创建决策记录
@@ -348,16 +500,44 @@ func (at *AutoTrader) runCycle() error {
 	// 1. 检查是否需要停止交易
 	if time.Now().Before(at.stopUntil) {
 		remaining := at.stopUntil.Sub(time.Now())
-		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
+		at.logf(LogLevelWarn, "⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
 		at.decisionLogger.LogDecision(record)
 		return nil
 	}
 
+	// 连续亏损冷却：冷却期间跳过本周期
+	if reason, cooling := at.checkLossCooldown(time.Now()); cooling {
+		at.logf(LogLevelWarn, "🧊 [%s] %s", at.name, reason)
+		record.Success = false
+		record.ErrorMessage = reason
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
+
+	// 可用保证金低于下限（过度杠杆亏损）：立即停止交易员
+	if reason, triggered := at.checkMarginFloor(); triggered {
+		record.Success = false
+		record.ErrorMessage = reason
+		at.decisionLogger.LogDecision(record)
+		at.logf(LogLevelError, "🚨 [%s] %s，交易员已停止", at.name, reason)
+		return nil
+	}
+
 	// 2. 重置日盈亏基线（每天一次）
 	at.maybeResetDailyMetrics()
 
+	// 3. AI模型限流：超出每分钟额度时延后到下个周期，而不是报错
+	if at.config.AIRequestsPerMinute > 0 {
+		modelKey := fmt.Sprintf("%d", at.config.AIModelID)
+		if allowed, retryAfter := GetAIModelRateLimiter().Allow(modelKey, at.config.AIRequestsPerMinute); !allowed {
+			at.logf(LogLevelWarn, "⏳ [%s] AI模型 %s 已达到每分钟 %d 次请求上限，本周期延后（%.0f 秒后恢复额度）",
+				at.name, modelKey, at.config.AIRequestsPerMinute, retryAfter.Seconds())
+			return nil
+		}
+	}
+
 	// 4. 收集交易上下文
 	ctx, err := at.buildTradingContext()
 	if err != nil {
@@ -391,12 +571,21 @@ func (at *AutoTrader) runCycle() error {
 		})
 	}
 
+	// 日内最大亏损熔断（数据库层记录）：触发后停止交易员
+	if reason, triggered := at.checkDailyLossBreaker(ctx.Account.TotalEquity); triggered {
+		record.Success = false
+		record.ErrorMessage = reason
+		at.decisionLogger.LogDecision(record)
+		at.logf(LogLevelError, "⛔ [%s] %s，交易员已停止", at.name, reason)
+		return nil
+	}
+
 	// 更新盈亏指标并执行账户级风控
 	if reason, triggered := at.enforceRiskLimits(ctx.Account.TotalEquity); triggered {
 		record.Success = false
 		record.ErrorMessage = reason
 		at.decisionLogger.LogDecision(record)
-		log.Printf("⛔ 风险控制触发，暂停交易：%s | 恢复时间: %s", reason, at.stopUntil.Format(time.RFC3339))
+		at.logf(LogLevelError, "⛔ 风险控制触发，暂停交易：%s | 恢复时间: %s", reason, at.stopUntil.Format(time.RFC3339))
 		return nil
 	}
 
@@ -404,15 +593,15 @@ func (at *AutoTrader) runCycle() error {
 	closedPositions := at.detectClosedPositions(ctx.Positions)
 	if len(closedPositions) > 0 {
 		autoCloseActions := at.generateAutoCloseActions(closedPositions)
-		record.Decisions = append(record.Decisions, autoCloseActions...)
-		log.Printf("🔔 检测到 %d 个被动平仓", len(closedPositions))
+		at.logf(LogLevelInfo, "🔔 检测到 %d 个被动平仓", len(closedPositions))
 		for i, closed := range closedPositions {
-			action := autoCloseActions[i]
-			pnl := closed.Quantity * (closed.MarkPrice - closed.EntryPrice)
-			if closed.Side == "short" {
-				pnl = -pnl
+			action := &autoCloseActions[i]
+			// 写入成交记录；有交易所成交明细时 action 的价格和数量回填为实际成交
+			pnl := at.recordPassiveClose(closed, action)
+			pnlPct := 0.0
+			if margin := closed.EntryPrice * action.Quantity; margin > 0 {
+				pnlPct = pnl / margin * 100 * float64(closed.Leverage)
 			}
-			pnlPct := pnl / (closed.EntryPrice * closed.Quantity) * 100 * float64(closed.Leverage)
 
 			// 平仓原因中文映射
 			reasonMap := map[string]string{
@@ -426,14 +615,24 @@ func (at *AutoTrader) runCycle() error {
 				reasonCN = action.Error
 			}
 
-			log.Printf("   └─ %s %s | 开仓: %.4f → 平仓: %.4f | 盈亏: %+.2f%% | 原因: %s",
+			at.logf(LogLevelInfo, "   └─ %s %s | 开仓: %.4f → 平仓: %.4f | 盈亏: %+.2f%% | 原因: %s",
 				closed.Symbol,
 				closed.Side,
 				closed.EntryPrice,
-				action.Price, // 使用推断的平仓价格
+				action.Price,
 				pnlPct,
 				reasonCN)
 		}
+		record.Decisions = append(record.Decisions, autoCloseActions...)
+
+		// 被动平仓（止损/强平）同样计入连续亏损：本周期因此达到阈值时不再请求AI开新仓
+		if reason, cooling := at.checkLossCooldown(time.Now()); cooling {
+			at.logf(LogLevelWarn, "🧊 [%s] %s", at.name, reason)
+			record.Success = false
+			record.ErrorMessage = reason
+			at.decisionLogger.LogDecision(record)
+			return nil
+		}
 	}
 
 	log.Print(strings.Repeat("=", 70))
@@ -441,16 +640,22 @@ func (at *AutoTrader) runCycle() error {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
 	}
 
-	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
+	at.logf(LogLevelInfo, "📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
-	// 5. 调用AI获取完整决策
-	log.Printf("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
-	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	// 5. 调用AI获取完整决策（先占用全局AI并发名额，超时未获得则延后到下个周期）
+	releaseAISlot, ok := GetAIConcurrencyLimiter().Acquire()
+	if !ok {
+		at.logf(LogLevelWarn, "⏳ [%s] 全局AI并发已达上限，等待超时，本周期延后", at.name)
+		return nil
+	}
+	at.logf(LogLevelInfo, "🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
+	decision, err := at.getDecisionWithFallback(ctx)
+	releaseAISlot()
 
 	if decision != nil && decision.AIRequestDurationMs > 0 {
 		record.AIRequestDurationMs = decision.AIRequestDurationMs
-		log.Printf("⏱️ AI调用耗时: %.2f 秒", float64(record.AIRequestDurationMs)/1000)
+		at.logf(LogLevelInfo, "⏱️ AI调用耗时: %.2f 秒", float64(record.AIRequestDurationMs)/1000)
 		record.ExecutionLog = append(record.ExecutionLog,
 			fmt.Sprintf("AI调用耗时: %d ms", record.AIRequestDurationMs))
 	}
@@ -521,14 +726,29 @@ func (at *AutoTrader) runCycle() error {
 
 	// 8. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
+	sortedDecisions, dropped := capOrdersPerCycle(sortedDecisions, at.config.MaxOrdersPerCycle)
+	for _, d := range dropped {
+		at.logf(LogLevelWarn, "⚠️ 超过单周期最大开仓数 %d，丢弃决策: %s %s", at.config.MaxOrdersPerCycle, d.Symbol, d.Action)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %s %s 超过单周期最大开仓数 %d，已丢弃", d.Symbol, d.Action, at.config.MaxOrdersPerCycle))
+	}
+	sortedDecisions, zeroWeighted := applySymbolWeights(sortedDecisions, at.config.SymbolWeights, ctx.Account.TotalEquity)
+	for _, d := range zeroWeighted {
+		at.logf(LogLevelInfo, "⏭️  币种权重为0，跳过开仓: %s %s", d.Symbol, d.Action)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏭️ %s %s 币种权重为0，已跳过", d.Symbol, d.Action))
+	}
 
 	log.Println("🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
-		log.Printf("  [%d] %s %s", i+1, d.Symbol, d.Action)
+		at.logf(LogLevelInfo, "  [%d] %s %s", i+1, d.Symbol, d.Action)
 	}
 	log.Println()
 
 	// 执行决策并记录结果
+	var cycleID int64
+	if len(sortedDecisions) > 0 {
+		cycleID = at.recordDecisionCycle(decision.UserPrompt, ctx.Account.TotalEquity)
+	}
+	var closedKeys []string // 本周期主动平掉的持仓，不再作为被动平仓检测
 	for _, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
@@ -540,26 +760,39 @@ func (at *AutoTrader) runCycle() error {
 			Success:   false,
 		}
 
-		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
-			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+		if err := at.executeDecisionWithRecord(&d, &actionRecord); errors.Is(err, ErrBelowMinOrder) {
+			at.logf(LogLevelInfo, "⏭️  跳过决策 (%s %s): %v", d.Symbol, d.Action, err)
+			actionRecord.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏭️ %s %s 跳过: %v", d.Symbol, d.Action, err))
+		} else if err != nil {
+			at.logf(LogLevelError, "❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
 		} else {
 			actionRecord.Success = true
+			at.logf(LogLevelInfo, "✓ 执行决策成功 (%s %s)", d.Symbol, d.Action)
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			at.recordTrade(&actionRecord, ctx.Positions)
+			if d.Action == "close_long" || d.Action == "close_short" {
+				closedKeys = append(closedKeys, d.Symbol+"_"+strings.TrimPrefix(d.Action, "close_"))
+			}
 			// 成功执行后短暂延迟
 			time.Sleep(1 * time.Second)
 		}
 
 		record.Decisions = append(record.Decisions, actionRecord)
+		at.recordDecision(&d, &actionRecord, cycleID)
 	}
 
 	// 9. 更新持仓快照（用于下一周期检测被动平仓）
 	at.updatePositionSnapshot(ctx.Positions)
+	for _, key := range closedKeys {
+		delete(at.lastPositions, key)
+	}
 
 	// 10. 保存决策记录
 	if err := at.decisionLogger.LogDecision(record); err != nil {
-		log.Printf("⚠ 保存决策记录失败: %v", err)
+		at.logf(LogLevelError, "⚠ 保存决策记录失败: %v", err)
 	}
 
 	return nil
@@ -567,8 +800,8 @@ func (at *AutoTrader) runCycle() error {
 
 // 每日重置盈亏基线
 func (at *AutoTrader) maybeResetDailyMetrics() {
-	now := time.Now()
-	if at.lastResetTime.IsZero() || !sameDay(at.lastResetTime, now) {
+	now := time.Now().In(at.location())
+	if at.lastResetTime.IsZero() || !sameDay(at.lastResetTime.In(now.Location()), now) {
 		at.dailyPnL = 0
 		at.dailyPnLBase = 0
 		at.needsDailyBaseline = true
@@ -601,6 +834,294 @@ func (at *AutoTrader) enforceRiskLimits(currentEquity float64) (string, bool) {
 	return "", false
 }
 
+// dailyEquityRecorder 每日净值记录器（由 config.Database 实现）
+type dailyEquityRecorder interface {
+	RecordDailyEquity(traderID string, equity float64) (*config.DailyLossStatus, error)
+}
+
+// checkDailyLossBreaker 记录当日净值，当日已突破 max_daily_loss 时停止主循环（熔断后当天重新启动也会再次停止）
+// 数据库层已将 is_running 置为0并写入 stop_reason，且只在首次触发时通知
+func (at *AutoTrader) checkDailyLossBreaker(currentEquity float64) (string, bool) {
+	recorder, ok := at.database.(dailyEquityRecorder)
+	if !ok || currentEquity <= 0 {
+		return "", false
+	}
+
+	status, err := recorder.RecordDailyEquity(at.id, currentEquity)
+	if err != nil {
+		log.Printf("⚠️ [%s] 记录每日净值失败: %v", at.name, err)
+		return "", false
+	}
+	if !status.Breached {
+		return "", false
+	}
+
+	at.haltRunLoop()
+	return fmt.Sprintf("触发日内最大亏损熔断 %.2f%% (当日盈亏 %.2f%%)", status.LimitPct, status.PnLPct), true
+}
+
+// cycleResultRecorder 交易周期连续失败统计（由 config.Database 实现）
+type cycleResultRecorder interface {
+	RecordCycleResult(traderID string, cycleErr error) (*config.CycleFailureStatus, error)
+}
+
+// trackCycleResult 记录周期结果，连续失败达到上限时停止主循环并发送一次通知
+// 数据库层已将 is_running 置为0并写入 stop_reason，避免交易员在失败中无限重试
+func (at *AutoTrader) trackCycleResult(cycleErr error) {
+	recorder, ok := at.database.(cycleResultRecorder)
+	if !ok {
+		return
+	}
+	status, err := recorder.RecordCycleResult(at.id, cycleErr)
+	if err != nil {
+		log.Printf("⚠️ [%s] 记录周期结果失败: %v", at.name, err)
+		return
+	}
+	if !status.JustDisabled {
+		return
+	}
+
+	at.haltRunLoop()
+	at.logf(LogLevelError, "⛔ [%s] 连续 %d 个周期失败，交易员已自动停用: %v", at.name, status.Failures, cycleErr)
+	notify.NotifyLevel(notify.LevelError, fmt.Sprintf("⛔ 交易员 %s 连续 %d 个周期失败，已自动停用: %v", at.name, status.Failures, cycleErr))
+}
+
+// defaultLossCooldown 未配置冷却时长时的默认值
+const defaultLossCooldown = 60 * time.Minute
+
+// lossStreakReader 连续亏损统计（由 config.Database 实现）
+type lossStreakReader interface {
+	GetLossStreak(traderID string, since time.Time) (int, time.Time, error)
+}
+
+// checkLossCooldown 检查连续亏损冷却状态，返回 (原因, 是否处于冷却中)
+// 最近连续亏损笔数达到阈值时，从最后一笔亏损起暂停 LossCooldown；
+// 出现盈利平仓即重置计数，冷却结束后也从结束时间起重新计数
+func (at *AutoTrader) checkLossCooldown(now time.Time) (string, bool) {
+	threshold := at.config.LossStreakThreshold
+	if threshold <= 0 {
+		return "", false
+	}
+	if now.Before(at.lossCooldownUntil) {
+		return fmt.Sprintf("连续亏损冷却中，剩余 %.0f 分钟", at.lossCooldownUntil.Sub(now).Minutes()), true
+	}
+	if !at.lossCooldownUntil.IsZero() {
+		log.Printf("▶️ [%s] 连续亏损冷却结束，恢复交易", at.name)
+		at.lossStreakSince = at.lossCooldownUntil
+		at.lossCooldownUntil = time.Time{}
+	}
+
+	reader, ok := at.database.(lossStreakReader)
+	if !ok {
+		return "", false
+	}
+	streak, lastLossAt, err := reader.GetLossStreak(at.id, at.lossStreakSince)
+	if err != nil {
+		log.Printf("⚠️ [%s] 统计连续亏损失败: %v", at.name, err)
+		return "", false
+	}
+	if streak < threshold {
+		return "", false
+	}
+
+	cooldown := at.config.LossCooldown
+	if cooldown <= 0 {
+		cooldown = defaultLossCooldown
+	}
+	until := lastLossAt.Add(cooldown)
+	if !now.Before(until) {
+		// 冷却期已过（例如重启前触发的冷却），从冷却结束时间起重新计数
+		at.lossStreakSince = until
+		return "", false
+	}
+
+	at.lossCooldownUntil = until
+	notify.NotifyLevel(notify.LevelWarn, fmt.Sprintf("🧊 交易员 %s 连续亏损 %d 笔，暂停交易至 %s",
+		at.name, streak, until.Format(time.RFC3339)))
+	return fmt.Sprintf("连续亏损 %d 笔（阈值 %d），冷却至 %s", streak, threshold, until.Format(time.RFC3339)), true
+}
+
+// scanRecorder 扫描心跳记录器（由 config.Database 实现，用于卡死检测）
+type scanRecorder interface {
+	TouchTraderScan(traderID string) error
+}
+
+// touchScan 记录本次扫描时间（last_scanned_at）
+func (at *AutoTrader) touchScan() {
+	recorder, ok := at.database.(scanRecorder)
+	if !ok {
+		return
+	}
+	if err := recorder.TouchTraderScan(at.id); err != nil {
+		log.Printf("⚠️ [%s] 更新扫描时间失败: %v", at.name, err)
+	}
+}
+
+// balanceSnapshotInterval 交易所余额快照的最小记录间隔
+const balanceSnapshotInterval = 15 * time.Minute
+
+// balanceSnapshotRecorder 余额快照记录器（由 config.Database 实现）
+type balanceSnapshotRecorder interface {
+	RecordBalanceSnapshot(snapshot *config.BalanceSnapshot) error
+}
+
+// snapshotBalance 按间隔记录交易所返回的账户余额，用于与交易所账单对账
+func (at *AutoTrader) snapshotBalance(walletBalance, availableBalance, unrealizedPnL float64) {
+	recorder, ok := at.database.(balanceSnapshotRecorder)
+	if !ok || time.Since(at.lastBalanceSnapshot) < balanceSnapshotInterval {
+		return
+	}
+	if err := recorder.RecordBalanceSnapshot(&config.BalanceSnapshot{
+		TraderID:         at.id,
+		WalletBalance:    walletBalance,
+		AvailableBalance: availableBalance,
+		UnrealizedPnL:    unrealizedPnL,
+	}); err != nil {
+		log.Printf("⚠️ [%s] 记录余额快照失败: %v", at.name, err)
+		return
+	}
+	at.lastBalanceSnapshot = time.Now()
+}
+
+// tradeRecorder 成交记录器（由 config.Database 实现）
+type tradeRecorder interface {
+	RecordTrade(trade *config.TradeRecord) error
+}
+
+// recordTrade 将成功执行的开仓/平仓写入成交记录
+// 平仓优先按交易所成交明细记录成交价和已实现盈亏，交易器不支持时按执行前持仓的开仓价估算；手续费按 Taker 费率估算
+func (at *AutoTrader) recordTrade(action *logger.DecisionAction, positions []decision.PositionInfo) {
+	recorder, ok := at.database.(tradeRecorder)
+	if !ok {
+		return
+	}
+
+	trade := &config.TradeRecord{
+		TraderID:  at.id,
+		UserID:    at.userID,
+		Symbol:    action.Symbol,
+		Quantity:  action.Quantity,
+		Price:     action.Price,
+		CreatedAt: action.Timestamp,
+	}
+	if action.OrderID != 0 {
+		trade.OrderID = fmt.Sprintf("%d", action.OrderID)
+	}
+	trade.ClientOrderID = action.ClientOrderID
+
+	switch action.Action {
+	case "open_long", "open_short":
+		trade.Action = "open"
+		trade.Side = strings.TrimPrefix(action.Action, "open_")
+	case "close_long", "close_short", "partial_close":
+		trade.Action = "close"
+		trade.Side = strings.TrimPrefix(action.Action, "close_")
+		var pos *decision.PositionInfo
+		for i := range positions {
+			if positions[i].Symbol == action.Symbol && (action.Action == "partial_close" || positions[i].Side == trade.Side) {
+				pos = &positions[i]
+				break
+			}
+		}
+		if pos == nil {
+			return
+		}
+		trade.Side = pos.Side
+		if trade.Quantity <= 0 {
+			trade.Quantity = pos.Quantity
+		}
+		if trade.Side == "long" {
+			trade.RealizedPnL = (trade.Price - pos.EntryPrice) * trade.Quantity
+		} else {
+			trade.RealizedPnL = (pos.EntryPrice - trade.Price) * trade.Quantity
+		}
+	default:
+		return
+	}
+
+	// 交易器能查到成交明细时按实际成交记录数量、均价、成交方式和手续费
+	if summary, ok := at.orderFills(action.Symbol, action.OrderID, action.Timestamp); ok {
+		trade.Quantity = summary.Quantity
+		trade.Price = summary.Price
+		if trade.Action == "close" {
+			trade.RealizedPnL = summary.RealizedPnL
+		}
+		trade.Liquidity = summary.liquidity()
+		trade.Fee = summary.fee(at.config.TakerFeeRate, at.config.MakerFeeRate)
+	} else {
+		// 没有成交明细时，仅开仓且限价单挂单成交时按 Maker 费率计算，其余（市价单、平仓、未知）均按 Taker
+		trade.Liquidity = LiquidityTaker
+		if trade.Action == "open" && action.Liquidity == LiquidityMaker {
+			trade.Liquidity = LiquidityMaker
+		}
+		feeRate := at.config.TakerFeeRate
+		if trade.Liquidity == LiquidityMaker {
+			feeRate = at.config.MakerFeeRate
+		}
+		trade.Fee = trade.Quantity * trade.Price * feeRate
+	}
+
+	if err := recorder.RecordTrade(trade); err != nil {
+		log.Printf("⚠️ [%s] 记录成交失败: %v", at.name, err)
+	}
+}
+
+// decisionRecorder 决策记录器（由 config.Database 实现）
+type decisionRecorder interface {
+	RecordDecisionCycle(traderID, userID, userPrompt string, accountEquity float64) (int64, error)
+	RecordDecision(decision *config.Decision) error
+}
+
+// recordDecisionCycle 保存本周期发送给AI的输入和账户净值（每个周期一条，用于之后重放决策），返回周期ID
+// 未配置数据库或写入失败时返回 0，决策仍会记录但无法重放
+func (at *AutoTrader) recordDecisionCycle(userPrompt string, accountEquity float64) int64 {
+	recorder, ok := at.database.(decisionRecorder)
+	if !ok {
+		return 0
+	}
+	cycleID, err := recorder.RecordDecisionCycle(at.id, at.userID, userPrompt, accountEquity)
+	if err != nil {
+		log.Printf("⚠️ [%s] 记录决策周期失败: %v", at.name, err)
+		return 0
+	}
+	return cycleID
+}
+
+// recordDecision 将本周期的决策及执行结果写入决策表（包括 hold/wait），cycleID 指向保存输入prompt的决策周期
+func (at *AutoTrader) recordDecision(d *decision.Decision, action *logger.DecisionAction, cycleID int64) {
+	recorder, ok := at.database.(decisionRecorder)
+	if !ok {
+		return
+	}
+
+	err := recorder.RecordDecision(&config.Decision{
+		TraderID:        at.id,
+		UserID:          at.userID,
+		Symbol:          d.Symbol,
+		Action:          d.Action,
+		Leverage:        d.Leverage,
+		PositionSizeUSD: d.PositionSizeUSD,
+		StopLoss:        d.StopLoss,
+		TakeProfit:      d.TakeProfit,
+		Confidence:      d.Confidence,
+		Reasoning:       d.Reasoning,
+		Success:         action.Success,
+		Error:           action.Error,
+		ClientOrderID:   action.ClientOrderID,
+		CycleID:         cycleID,
+		CreatedAt:       action.Timestamp,
+	})
+	if err != nil {
+		log.Printf("⚠️ [%s] 记录决策失败: %v", at.name, err)
+	}
+}
+
+// haltRunLoop 在交易周期内部停止主循环（与 Stop 共用 signalStop）
+// 不能直接调用 Stop()：Run 本身计入 monitorWg，在周期内等待会死锁
+func (at *AutoTrader) haltRunLoop() {
+	at.signalStop()
+}
+
 func (at *AutoTrader) updatePnLMetrics(currentEquity float64) {
 	if at.dailyPnLBase == 0 || at.needsDailyBaseline {
 		at.dailyPnLBase = currentEquity
@@ -627,8 +1148,8 @@ func (at *AutoTrader) activateRiskStop() {
 
 // buildTradingContext 构建交易上下文
 func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
-	// 1. 获取账户信息
-	balance, err := at.trader.GetBalance()
+	// 1. 获取账户信息（本周期已读取时复用）
+	balance, err := at.cycleBalance()
 	if err != nil {
 		return nil, fmt.Errorf("获取账户余额失败: %w", err)
 	}
@@ -650,6 +1171,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 	// Total Equity = 钱包余额 + 未实现盈亏
 	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	at.snapshotBalance(totalWalletBalance, availableBalance, totalUnrealizedProfit)
 
 	// 2. 获取持仓信息
 	positions, err := at.trader.GetPositions()
@@ -784,6 +1306,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		TakerFeeRate:    at.config.TakerFeeRate,    // Use configured taker fee rate
 		MakerFeeRate:    at.config.MakerFeeRate,    // Use configured maker fee rate
 		Timeframes:      at.timeframes,             // K线时间线配置
+		CandleLookback:  at.config.CandleLookback,  // K线回看数量
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -833,11 +1356,21 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	log.Printf("  📈 开多仓: %s", decision.Symbol)
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
+	// webhook 窗口内的重复同向告警允许加仓一次（金字塔），按上限压缩开仓金额
 	positions, err := at.trader.GetPositions()
+	existingQty := 0.0
+	var exitOrdersBefore exitOrderSnapshot
 	if err == nil {
 		for _, pos := range positions {
 			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
-				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
+				if !at.consumeWebhookScaleIn(decision.Symbol, "long") {
+					return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
+				}
+				if existingQty, err = capWebhookScaleIn(decision, pos); err != nil {
+					return err
+				}
+				exitOrdersBefore = at.snapshotOpenOrders(decision.Symbol)
+				break
 			}
 		}
 	}
@@ -870,6 +1403,11 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
+	// 低于交易所最小下单限制时直接跳过，避免提交后被拒
+	if err := checkOrderMinimums(at.trader, decision.Symbol, quantity, marketData.CurrentPrice); err != nil {
+		return err
+	}
+
 	// ⚠️ 保证金验证：防止保证金不足错误（code=-2019）
 	requiredMargin := decision.PositionSizeUSD / float64(decision.Leverage)
 
@@ -882,8 +1420,8 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		availableBalance = avail
 	}
 
-	// 手续费估算（Taker费率 0.04%）
-	estimatedFee := decision.PositionSizeUSD * 0.0004
+	// 手续费估算（按配置的 Taker 费率）
+	estimatedFee := decision.PositionSizeUSD * at.config.TakerFeeRate
 	totalRequired := requiredMargin + estimatedFee
 
 	if totalRequired > availableBalance {
@@ -891,6 +1429,9 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 			totalRequired, requiredMargin, estimatedFee, availableBalance)
 	}
 
+	// 配置了 OCO 百分比时按当前价计算止盈止损
+	at.applyExitBracket(decision, "long", marketData.CurrentPrice)
+
 	// ⚡ 严格验证止损/止盈价格（防止开仓后无法设置保护，导致仓位风险）
 	// 修复 Issue: 开仓成功但止损/止盈设置失败，仓位失去保护
 	if decision.StopLoss <= 0 || decision.TakeProfit <= 0 {
@@ -920,8 +1461,8 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		// 继续执行，不影响交易
 	}
 
-	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
+	// 开仓（支持 clientOrderId 的交易器使用幂等下单）
+	order, err := at.placeOpenOrder("long", decision.Symbol, quantity, decision.Leverage, actionRecord)
 	if err != nil {
 		return err
 	}
@@ -930,24 +1471,24 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	if orderID, ok := order["orderId"].(int64); ok {
 		actionRecord.OrderID = orderID
 	}
+	if liquidity, ok := order["liquidity"].(string); ok {
+		actionRecord.Liquidity = liquidity
+	}
 
 	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
 
-	// 记录开仓时间
+	// 记录开仓时间（加仓保留首次开仓时间）
 	posKey := decision.Symbol + "_long"
+	if existingQty > 0 {
+		// 加仓后只撤销该方向的原止盈止损，按合计数量重新设置
+		at.rescaleExitOrders(decision.Symbol, "long", exitOrdersBefore, quantity+existingQty,
+			positionQuantity(positions, decision.Symbol, "short"), decision.StopLoss, decision.TakeProfit)
+		return nil
+	}
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	} else {
-		at.positionStopLoss[posKey] = decision.StopLoss // 记录止损价格
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
-	} else {
-		at.positionTakeProfit[posKey] = decision.TakeProfit // 记录止盈价格
-	}
+	// 设置止损止盈（配置了 OCO 百分比时挂出关联的止盈止损）
+	at.placeExitOrders(decision.Symbol, "long", quantity, decision.StopLoss, decision.TakeProfit)
 
 	return nil
 }
@@ -957,11 +1498,21 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	log.Printf("  📉 开空仓: %s", decision.Symbol)
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
+	// webhook 窗口内的重复同向告警允许加仓一次（金字塔），按上限压缩开仓金额
 	positions, err := at.trader.GetPositions()
+	existingQty := 0.0
+	var exitOrdersBefore exitOrderSnapshot
 	if err == nil {
 		for _, pos := range positions {
 			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
-				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
+				if !at.consumeWebhookScaleIn(decision.Symbol, "short") {
+					return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
+				}
+				if existingQty, err = capWebhookScaleIn(decision, pos); err != nil {
+					return err
+				}
+				exitOrdersBefore = at.snapshotOpenOrders(decision.Symbol)
+				break
 			}
 		}
 	}
@@ -994,6 +1545,11 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
+	// 低于交易所最小下单限制时直接跳过，避免提交后被拒
+	if err := checkOrderMinimums(at.trader, decision.Symbol, quantity, marketData.CurrentPrice); err != nil {
+		return err
+	}
+
 	// ⚠️ 保证金验证：防止保证金不足错误（code=-2019）
 	requiredMargin := decision.PositionSizeUSD / float64(decision.Leverage)
 
@@ -1006,8 +1562,8 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		availableBalance = avail
 	}
 
-	// 手续费估算（Taker费率 0.04%）
-	estimatedFee := decision.PositionSizeUSD * 0.0004
+	// 手续费估算（按配置的 Taker 费率）
+	estimatedFee := decision.PositionSizeUSD * at.config.TakerFeeRate
 	totalRequired := requiredMargin + estimatedFee
 
 	if totalRequired > availableBalance {
@@ -1015,6 +1571,9 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 			totalRequired, requiredMargin, estimatedFee, availableBalance)
 	}
 
+	// 配置了 OCO 百分比时按当前价计算止盈止损
+	at.applyExitBracket(decision, "short", marketData.CurrentPrice)
+
 	// ⚡ 严格验证止损/止盈价格（防止开仓后无法设置保护，导致仓位风险）
 	// 修复 Issue: 开仓成功但止损/止盈设置失败，仓位失去保护
 	if decision.StopLoss <= 0 || decision.TakeProfit <= 0 {
@@ -1044,8 +1603,8 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		// 继续执行，不影响交易
 	}
 
-	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
+	// 开仓（支持 clientOrderId 的交易器使用幂等下单）
+	order, err := at.placeOpenOrder("short", decision.Symbol, quantity, decision.Leverage, actionRecord)
 	if err != nil {
 		return err
 	}
@@ -1054,28 +1613,49 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	if orderID, ok := order["orderId"].(int64); ok {
 		actionRecord.OrderID = orderID
 	}
+	if liquidity, ok := order["liquidity"].(string); ok {
+		actionRecord.Liquidity = liquidity
+	}
 
 	log.Printf("  ✓ 开仓成功，订单ID: %v, 数量: %.4f", order["orderId"], quantity)
 
-	// 记录开仓时间
+	// 记录开仓时间（加仓保留首次开仓时间）
 	posKey := decision.Symbol + "_short"
+	if existingQty > 0 {
+		// 加仓后只撤销该方向的原止盈止损，按合计数量重新设置
+		at.rescaleExitOrders(decision.Symbol, "short", exitOrdersBefore, quantity+existingQty,
+			positionQuantity(positions, decision.Symbol, "long"), decision.StopLoss, decision.TakeProfit)
+		return nil
+	}
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	} else {
-		at.positionStopLoss[posKey] = decision.StopLoss // 记录止损价格
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
-	} else {
-		at.positionTakeProfit[posKey] = decision.TakeProfit // 记录止盈价格
-	}
+	// 设置止损止盈（配置了 OCO 百分比时挂出关联的止盈止损）
+	at.placeExitOrders(decision.Symbol, "short", quantity, decision.StopLoss, decision.TakeProfit)
 
 	return nil
 }
 
+// placeOpenOrder 下开仓单：交易器实现 ClientOrderTrader 时使用确定性 clientOrderId 幂等下单，
+// 并将 clientOrderId 写入执行记录；否则直接调用 OpenLong/OpenShort
+func (at *AutoTrader) placeOpenOrder(side, symbol string, quantity float64, leverage int, actionRecord *logger.DecisionAction) (map[string]interface{}, error) {
+	ct, ok := at.trader.(ClientOrderTrader)
+	if !ok {
+		if side == "long" {
+			return at.trader.OpenLong(symbol, quantity, leverage)
+		}
+		return at.trader.OpenShort(symbol, quantity, leverage)
+	}
+
+	clientOrderID := clientOrderIDFor(at.id, symbol, actionRecord.Action, actionRecord.Timestamp)
+	actionRecord.ClientOrderID = clientOrderID
+	return placeOrderIdempotent(ct, symbol, clientOrderID, func(clientOrderID string) (map[string]interface{}, error) {
+		if side == "long" {
+			return ct.OpenLongWithClientID(symbol, quantity, leverage, clientOrderID)
+		}
+		return ct.OpenShortWithClientID(symbol, quantity, leverage, clientOrderID)
+	})
+}
+
 // executeCloseLongWithRecord 执行平多仓并记录详细信息
 func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	log.Printf("  🔄 平多仓: %s", decision.Symbol)
@@ -1541,6 +2121,11 @@ func (at *AutoTrader) GetID() string {
 	return at.id
 }
 
+// GetUserID 获取trader所属用户ID
+func (at *AutoTrader) GetUserID() string {
+	return at.userID
+}
+
 // GetName 获取trader名称
 func (at *AutoTrader) GetName() string {
 	return at.name
@@ -1561,6 +2146,113 @@ func (at *AutoTrader) SetCustomPrompt(prompt string) {
 	at.customPrompt = prompt
 }
 
+// SetPromptTemplateName 设置引用的数据库提示词模板名称（为空表示使用 customPrompt）
+func (at *AutoTrader) SetPromptTemplateName(name string) {
+	at.promptTemplateName = name
+}
+
+// promptTemplateReader 提示词模板读取器（由 config.Database 实现）
+type promptTemplateReader interface {
+	GetPromptTemplateByName(userID, name string) (*config.PromptTemplate, error)
+}
+
+// resolvePromptTemplate 读取引用的提示词模板并用本周期上下文填充占位符
+// 每个周期重新读取，修改模板后无需重启；模板不可用时返回 false，回退到 customPrompt
+func (at *AutoTrader) resolvePromptTemplate(ctx *decision.Context) (string, bool) {
+	if at.promptTemplateName == "" {
+		return "", false
+	}
+	reader, ok := at.database.(promptTemplateReader)
+	if !ok {
+		return "", false
+	}
+	tpl, err := reader.GetPromptTemplateByName(at.userID, at.promptTemplateName)
+	if err != nil || tpl == nil {
+		log.Printf("⚠️ [%s] 提示词模板 %s 不可用，使用自定义prompt: %v", at.name, at.promptTemplateName, err)
+		return "", false
+	}
+
+	vars := map[string]string{
+		"trader_id":   at.id,
+		"trader_name": at.name,
+		"date":        at.localDate(),
+	}
+	if ctx != nil {
+		symbols := make([]string, 0, len(ctx.CandidateCoins))
+		for _, coin := range ctx.CandidateCoins {
+			symbols = append(symbols, coin.Symbol)
+		}
+		vars["equity"] = fmt.Sprintf("%.2f", ctx.Account.TotalEquity)
+		vars["available_balance"] = fmt.Sprintf("%.2f", ctx.Account.AvailableBalance)
+		vars["position_count"] = strconv.Itoa(ctx.Account.PositionCount)
+		vars["btc_eth_leverage"] = strconv.Itoa(ctx.BTCETHLeverage)
+		vars["altcoin_leverage"] = strconv.Itoa(ctx.AltcoinLeverage)
+		vars["symbols"] = strings.Join(symbols, ",")
+	}
+	return config.RenderPromptTemplate(tpl.Body, vars), true
+}
+
+// strategyPrompt 交易员配置的自定义策略prompt：引用了可用的提示词模板时使用渲染后的模板，否则使用 customPrompt
+func (at *AutoTrader) strategyPrompt(ctx *decision.Context) string {
+	if rendered, ok := at.resolvePromptTemplate(ctx); ok {
+		return rendered
+	}
+	return at.customPrompt
+}
+
+// cyclePrompt 本周期使用的自定义prompt（自定义策略或引用模板 + 附加prompt）
+func (at *AutoTrader) cyclePrompt(ctx *decision.Context) string {
+	base := at.strategyPrompt(ctx)
+	if at.extraPrompt == "" {
+		return base
+	}
+	if base == "" {
+		return at.extraPrompt
+	}
+	return base + "\n\n" + at.extraPrompt
+}
+
+// EffectivePrompt 组装交易员实际发送给AI的系统提示词（基础模板、自定义策略或引用模板、覆盖基础prompt），用于预览和排查prompt配置
+// 不获取行情、不调用AI、不下单：每个周期实时生成的行情输入（用户prompt）和webhook附加prompt不包含在内，净值使用初始余额
+// 引用的提示词模板或系统提示词模板不存在时返回错误（实际周期中会静默回退）
+func (at *AutoTrader) EffectivePrompt() (string, error) {
+	symbols := at.tradingCoins
+	if len(symbols) == 0 {
+		symbols = at.defaultCoins
+	}
+	ctx := &decision.Context{
+		Account:         decision.AccountInfo{TotalEquity: at.initialBalance, AvailableBalance: at.initialBalance},
+		BTCETHLeverage:  at.config.BTCETHLeverage,
+		AltcoinLeverage: at.config.AltcoinLeverage,
+	}
+	for _, symbol := range symbols {
+		ctx.CandidateCoins = append(ctx.CandidateCoins, decision.CandidateCoin{Symbol: symbol})
+	}
+
+	if at.promptTemplateName != "" {
+		reader, ok := at.database.(promptTemplateReader)
+		if !ok {
+			return "", fmt.Errorf("提示词模板不可用")
+		}
+		tpl, err := reader.GetPromptTemplateByName(at.userID, at.promptTemplateName)
+		if err != nil {
+			return "", fmt.Errorf("读取提示词模板 %s 失败: %w", at.promptTemplateName, err)
+		}
+		if tpl == nil {
+			return "", fmt.Errorf("引用的提示词模板 %s 不存在", at.promptTemplateName)
+		}
+	}
+	customPrompt := at.strategyPrompt(ctx)
+
+	if !at.overrideBasePrompt || customPrompt == "" {
+		if _, err := decision.GetPromptTemplate(at.systemPromptTemplate); err != nil {
+			return "", fmt.Errorf("系统提示词模板 %s 不存在: %w", at.systemPromptTemplate, err)
+		}
+	}
+	return decision.PreviewSystemPrompt(at.initialBalance, ctx.BTCETHLeverage, ctx.AltcoinLeverage,
+		customPrompt, at.overrideBasePrompt, at.systemPromptTemplate), nil
+}
+
 // SetOverrideBasePrompt 设置是否覆盖基础prompt
 func (at *AutoTrader) SetOverrideBasePrompt(override bool) {
 	at.overrideBasePrompt = override
@@ -1593,7 +2285,7 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"trader_name":     at.name,
 		"ai_model":        at.aiModel,
 		"exchange":        at.exchange,
-		"is_running":      at.isRunning,
+		"is_running":      at.running(),
 		"start_time":      at.startTime.Format(time.RFC3339),
 		"runtime_minutes": int(time.Since(at.startTime).Minutes()),
 		"call_count":      at.callCount,
@@ -1695,6 +2387,19 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	}, nil
 }
 
+// location 交易员时区，未配置时为 UTC
+func (at *AutoTrader) location() *time.Location {
+	if at.config.Timezone == nil {
+		return time.UTC
+	}
+	return at.config.Timezone
+}
+
+// localDate 交易员时区的当前日期
+func (at *AutoTrader) localDate() string {
+	return time.Now().In(at.location()).Format("2006-01-02")
+}
+
 func sameDay(a, b time.Time) bool {
 	ay, am, ad := a.Date()
 	by, bm, bd := b.Date()
@@ -1797,6 +2502,29 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	return sorted
 }
 
+// capOrdersPerCycle 限制单周期执行的开仓动作数（open_long/open_short），超出的开仓按顺序丢弃
+// 平仓、部分平仓、调整止盈止损等降低风险的动作不计入上限，也不会被丢弃；max<=0 表示不限制
+func capOrdersPerCycle(decisions []decision.Decision, max int) (kept, dropped []decision.Decision) {
+	if max <= 0 {
+		return decisions, nil
+	}
+	kept = make([]decision.Decision, 0, len(decisions))
+	opens := 0
+	for _, d := range decisions {
+		if d.Action != "open_long" && d.Action != "open_short" {
+			kept = append(kept, d)
+			continue
+		}
+		if opens >= max {
+			dropped = append(dropped, d)
+			continue
+		}
+		opens++
+		kept = append(kept, d)
+	}
+	return kept, dropped
+}
+
 // getCandidateCoins 获取交易员的候选币种列表
 func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 	// 优先级 1: 自定义币种列表（最高优先级）
@@ -1947,7 +2675,7 @@ func normalizeSymbol(symbol string) string {
 }
 
 // 启动回撤监控
-func (at *AutoTrader) startDrawdownMonitor() {
+func (at *AutoTrader) startDrawdownMonitor(stopCh <-chan struct{}) {
 	at.monitorWg.Add(1)
 	go func() {
 		defer at.monitorWg.Done()
@@ -1961,7 +2689,7 @@ func (at *AutoTrader) startDrawdownMonitor() {
 			select {
 			case <-ticker.C:
 				at.checkPositionDrawdown()
-			case <-at.stopMonitorCh:
+			case <-stopCh:
 				log.Println("⏹ 停止持仓回撤监控")
 				return
 			}
@@ -1978,6 +2706,10 @@ func (at *AutoTrader) checkPositionDrawdown() {
 		return
 	}
 
+	activeKeys := make(map[string]bool, len(positions))
+	defer at.pruneTrailingStops(activeKeys)
+	defer at.settleExitBrackets(activeKeys)
+
 	for _, pos := range positions {
 		symbol := pos["symbol"].(string)
 		side := pos["side"].(string)
@@ -1987,6 +2719,10 @@ func (at *AutoTrader) checkPositionDrawdown() {
 		if quantity < 0 {
 			quantity = -quantity // 空仓数量为负，转为正数
 		}
+		activeKeys[symbol+"_"+side] = true
+
+		// 移动止损：浮盈达到激活阈值后让止损跟随最优价移动
+		at.applyTrailingStop(symbol, side, entryPrice, markPrice, quantity)
 
 		// 计算当前盈亏百分比
 		leverage := 10 // 默认值
@@ -2232,6 +2968,7 @@ func (at *AutoTrader) updatePositionSnapshot(currentPositions []decision.Positio
 		key := pos.Symbol + "_" + pos.Side
 		at.lastPositions[key] = pos
 	}
+	at.lastPositionsAt = time.Now()
 }
 
 // ReloadAIModelConfig 重新加载AI模型配置（热更新）
@@ -2246,6 +2983,11 @@ func (at *AutoTrader) ReloadAIModelConfig(modelConfig *config.AIModelConfig) err
 	// 更新AI模型相关配置
 	at.config.CustomModelName = modelConfig.CustomModelName
 	at.config.CustomAPIURL = modelConfig.CustomAPIURL
+	customHeaders, err := config.ParseCustomHeaders(modelConfig.CustomHeaders)
+	if err != nil {
+		return fmt.Errorf("自定义请求头无效: %w", err)
+	}
+	at.config.CustomHeaders = customHeaders
 
 	// 根据不同的AI provider更新对应的API Key
 	switch modelConfig.Provider {
@@ -2299,6 +3041,7 @@ func (at *AutoTrader) reinitializeMCPClient() error {
 
 	// 使用统一的 SetAPIKey 方法重新初始化
 	at.mcpClient.SetAPIKey(apiKey, at.config.CustomAPIURL, at.config.CustomModelName)
+	at.mcpClient.SetCustomHeaders(at.config.CustomHeaders)
 
 	log.Printf("🔧 [MCP] AI模型配置已重新初始化: Model=%s, Provider=%s, CustomURL=%s",
 		at.config.CustomModelName, at.config.AIModel, at.config.CustomAPIURL)
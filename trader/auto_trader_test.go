@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sync"
 	"testing"
 	"time"
 
+	"nofx/config"
 	"nofx/decision"
 	"nofx/logger"
 	"nofx/market"
+	"nofx/mcp"
 	"nofx/pool"
 
 	"github.com/agiledragon/gomonkey/v2"
@@ -177,6 +180,43 @@ func (s *AutoTraderTestSuite) TestSortDecisionsByPriority() {
 	}
 }
 
+func (s *AutoTraderTestSuite) TestCapOrdersPerCycle() {
+	decisions := sortDecisionsByPriority([]decision.Decision{
+		{Action: "open_long", Symbol: "BTCUSDT"},
+		{Action: "hold", Symbol: "BNBUSDT"},
+		{Action: "close_short", Symbol: "ETHUSDT"},
+		{Action: "open_short", Symbol: "SOLUSDT"},
+		{Action: "close_long", Symbol: "XRPUSDT"},
+		{Action: "update_stop_loss", Symbol: "DOGEUSDT"},
+	})
+
+	kept, dropped := capOrdersPerCycle(decisions, 0)
+	s.Len(kept, 6, "0 表示不限制")
+	s.Empty(dropped)
+
+	kept, dropped = capOrdersPerCycle(decisions, 1)
+	s.Len(kept, 5, "保留全部平仓、调整和 hold，开仓只保留1个")
+	s.Require().Len(dropped, 1)
+	s.Contains([]string{"open_long", "open_short"}, dropped[0].Action, "超出部分为开仓")
+	opens := 0
+	for _, d := range kept {
+		if d.Action == "open_long" || d.Action == "open_short" {
+			opens++
+		}
+	}
+	s.Equal(1, opens)
+
+	// 平仓数量超过上限时也不会被丢弃
+	closes := []decision.Decision{
+		{Action: "close_long", Symbol: "BTCUSDT"},
+		{Action: "close_short", Symbol: "ETHUSDT"},
+		{Action: "partial_close", Symbol: "SOLUSDT"},
+	}
+	kept, dropped = capOrdersPerCycle(closes, 1)
+	s.Len(kept, 3, "平仓不计入上限")
+	s.Empty(dropped)
+}
+
 func (s *AutoTraderTestSuite) TestNormalizeSymbol() {
 	tests := []struct {
 		name     string
@@ -419,6 +459,7 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 		expectedOrder int64
 		existingSide  string
 		availBalance  float64
+		takerFeeRate  float64
 		expectedErr   string
 		executeFn     func(*decision.Decision, *logger.DecisionAction) error
 	}{
@@ -458,6 +499,16 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 				return s.autoTrader.executeOpenShortWithRecord(d, a)
 			},
 		},
+		{
+			name:         "多仓_按配置费率估算手续费后保证金不足",
+			action:       "open_long",
+			availBalance: 100.5, // 保证金 100 + 手续费 1000*0.001
+			takerFeeRate: 0.001,
+			expectedErr:  "手续费 1.00",
+			executeFn: func(d *decision.Decision, a *logger.DecisionAction) error {
+				return s.autoTrader.executeOpenLongWithRecord(d, a)
+			},
+		},
 		{
 			name:         "多仓_已有同方向持仓",
 			action:       "open_long",
@@ -488,6 +539,7 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 			})
 
 			s.mockTrader.balance["availableBalance"] = tt.availBalance
+			s.autoTrader.config.TakerFeeRate = tt.takerFeeRate
 			if tt.existingSide != "" {
 				s.mockTrader.positions = []map[string]interface{}{{"symbol": "BTCUSDT", "side": tt.existingSide}}
 			} else {
@@ -1452,3 +1504,365 @@ func (s *AutoTraderTestSuite) TestEnforceRiskLimits_BaselineSync() {
 	s.Equal(0.0, at.dailyPnL, "同步基准后日盈亏应为0")
 	s.Equal("", reason)
 }
+
+// fakeTradeRecorder 记录写入的成交
+type fakeTradeRecorder struct {
+	trades []*config.TradeRecord
+}
+
+func (f *fakeTradeRecorder) RecordTrade(trade *config.TradeRecord) error {
+	f.trades = append(f.trades, trade)
+	return nil
+}
+
+func TestRecordTrade_ComputesRealizedPnL(t *testing.T) {
+	recorder := &fakeTradeRecorder{}
+	at := &AutoTrader{
+		id:       "trader-1",
+		userID:   "user-1",
+		name:     "test",
+		config:   AutoTraderConfig{TakerFeeRate: 0.001},
+		database: recorder,
+	}
+	positions := []decision.PositionInfo{
+		{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, Quantity: 2},
+		{Symbol: "ETHUSDT", Side: "short", EntryPrice: 50, Quantity: 4},
+	}
+
+	at.recordTrade(&logger.DecisionAction{Action: "close_long", Symbol: "BTCUSDT", Price: 110, OrderID: 42}, positions)
+	at.recordTrade(&logger.DecisionAction{Action: "partial_close", Symbol: "ETHUSDT", Price: 45, Quantity: 1}, positions)
+	at.recordTrade(&logger.DecisionAction{Action: "open_short", Symbol: "SOLUSDT", Price: 20, Quantity: 5}, positions)
+	at.recordTrade(&logger.DecisionAction{Action: "hold", Symbol: "SOLUSDT"}, positions)
+
+	if len(recorder.trades) != 3 {
+		t.Fatalf("expected 3 trades, got %d", len(recorder.trades))
+	}
+	if tr := recorder.trades[0]; tr.Action != "close" || tr.Side != "long" || tr.Quantity != 2 || math.Abs(tr.RealizedPnL-20) > 1e-9 || tr.OrderID != "42" {
+		t.Errorf("unexpected close_long trade: %+v", tr)
+	}
+	if tr := recorder.trades[1]; tr.Side != "short" || math.Abs(tr.RealizedPnL-5) > 1e-9 || math.Abs(tr.Fee-0.045) > 1e-9 {
+		t.Errorf("unexpected partial_close trade: %+v", tr)
+	}
+	if tr := recorder.trades[2]; tr.Action != "open" || tr.Side != "short" || tr.RealizedPnL != 0 {
+		t.Errorf("unexpected open_short trade: %+v", tr)
+	}
+}
+
+func TestRecordTrade_MakerFeeRate(t *testing.T) {
+	recorder := &fakeTradeRecorder{}
+	at := &AutoTrader{
+		id:       "trader-1",
+		userID:   "user-1",
+		name:     "test",
+		config:   AutoTraderConfig{TakerFeeRate: 0.0004, MakerFeeRate: 0.0002},
+		database: recorder,
+	}
+	positions := []decision.PositionInfo{{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, Quantity: 1}}
+
+	at.recordTrade(&logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT", Price: 100, Quantity: 10, Liquidity: LiquidityMaker}, positions)
+	at.recordTrade(&logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT", Price: 100, Quantity: 10}, positions)
+	// 平仓均为市价单，即使标记为 maker 也按 taker 计费
+	at.recordTrade(&logger.DecisionAction{Action: "close_long", Symbol: "BTCUSDT", Price: 100, Liquidity: LiquidityMaker}, positions)
+
+	if len(recorder.trades) != 3 {
+		t.Fatalf("expected 3 trades, got %d", len(recorder.trades))
+	}
+	if tr := recorder.trades[0]; tr.Liquidity != LiquidityMaker || math.Abs(tr.Fee-0.2) > 1e-9 {
+		t.Errorf("maker open should use maker fee rate: %+v", tr)
+	}
+	if tr := recorder.trades[1]; tr.Liquidity != LiquidityTaker || math.Abs(tr.Fee-0.4) > 1e-9 {
+		t.Errorf("open without liquidity should default to taker: %+v", tr)
+	}
+	if tr := recorder.trades[2]; tr.Liquidity != LiquidityTaker || math.Abs(tr.Fee-0.04) > 1e-9 {
+		t.Errorf("close should be charged as taker: %+v", tr)
+	}
+}
+
+func (s *AutoTraderTestSuite) TestGetDecisionWithFallback_FailsOver() {
+	primary := mcp.New()
+	backup := mcp.New()
+	s.autoTrader.mcpClient = primary
+	s.autoTrader.fallbackClients = []fallbackAIClient{
+		{model: FallbackAIModel{AIModelID: 2, Provider: "custom"}, client: backup},
+	}
+
+	var called []mcp.AIClient
+	s.patches.ApplyFunc(decision.GetFullDecisionWithCustomPrompt, func(ctx *decision.Context, client mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*decision.FullDecision, error) {
+		called = append(called, client)
+		if client == primary {
+			return nil, errors.New("provider outage")
+		}
+		return &decision.FullDecision{CoTTrace: "backup"}, nil
+	})
+
+	result, err := s.autoTrader.getDecisionWithFallback(&decision.Context{})
+	s.Require().NoError(err)
+	s.Equal("backup", result.CoTTrace)
+	s.Len(called, 2)
+
+	// 所有模型都失败时返回错误
+	s.patches.Reset()
+	s.patches.ApplyFunc(decision.GetFullDecisionWithCustomPrompt, func(ctx *decision.Context, client mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*decision.FullDecision, error) {
+		return nil, errors.New("provider outage")
+	})
+	_, err = s.autoTrader.getDecisionWithFallback(&decision.Context{})
+	s.Error(err)
+}
+
+func TestWithCycleIdle_RejectsDuringCycle(t *testing.T) {
+	at := &AutoTrader{initialBalance: 1000}
+
+	at.cycleMu.Lock()
+	err := at.WithCycleIdle(func() error { return nil })
+	at.cycleMu.Unlock()
+	if !errors.Is(err, ErrCycleInProgress) {
+		t.Fatalf("expected ErrCycleInProgress, got %v", err)
+	}
+
+	err = at.WithCycleIdle(func() error {
+		at.SetInitialBalance(1500)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithCycleIdle failed: %v", err)
+	}
+	if at.initialBalance != 1500 {
+		t.Errorf("initialBalance = %.2f, want 1500", at.initialBalance)
+	}
+	if !at.cycleMu.TryLock() {
+		t.Fatal("cycle lock should be released after WithCycleIdle")
+	}
+}
+
+type fakeLossStreakReader struct {
+	streak     int
+	lastLossAt time.Time
+	since      []time.Time
+}
+
+func (f *fakeLossStreakReader) GetLossStreak(traderID string, since time.Time) (int, time.Time, error) {
+	f.since = append(f.since, since)
+	return f.streak, f.lastLossAt, nil
+}
+
+func TestCheckLossCooldown(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	reader := &fakeLossStreakReader{streak: 3, lastLossAt: now.Add(-10 * time.Minute)}
+	at := &AutoTrader{
+		name:     "cooldown",
+		config:   AutoTraderConfig{LossStreakThreshold: 3, LossCooldown: 30 * time.Minute},
+		database: reader,
+	}
+
+	if _, cooling := at.checkLossCooldown(now); !cooling {
+		t.Fatal("expected cooldown after 3 consecutive losses")
+	}
+	if want := now.Add(20 * time.Minute); !at.lossCooldownUntil.Equal(want) {
+		t.Fatalf("lossCooldownUntil = %v, want %v", at.lossCooldownUntil, want)
+	}
+
+	// 冷却期间不再查询数据库
+	if _, cooling := at.checkLossCooldown(now.Add(10 * time.Minute)); !cooling {
+		t.Fatal("expected still cooling")
+	}
+	if len(reader.since) != 1 {
+		t.Fatalf("expected 1 streak query during cooldown, got %d", len(reader.since))
+	}
+
+	// 冷却结束后从结束时间起重新计数
+	reader.streak = 0
+	if _, cooling := at.checkLossCooldown(now.Add(25 * time.Minute)); cooling {
+		t.Fatal("expected cooldown to end")
+	}
+	if got := reader.since[len(reader.since)-1]; !got.Equal(now.Add(20 * time.Minute)) {
+		t.Fatalf("streak should be counted from cooldown end, got since=%v", got)
+	}
+
+	// 阈值为0时关闭
+	at = &AutoTrader{database: &fakeLossStreakReader{streak: 10, lastLossAt: now}}
+	if _, cooling := at.checkLossCooldown(now); cooling {
+		t.Fatal("cooldown should be disabled when threshold is 0")
+	}
+}
+
+// fakeCycleResultRecorder 返回预设的周期失败状态
+type fakeCycleResultRecorder struct {
+	status *config.CycleFailureStatus
+	errs   []error
+}
+
+func (f *fakeCycleResultRecorder) RecordCycleResult(traderID string, cycleErr error) (*config.CycleFailureStatus, error) {
+	f.errs = append(f.errs, cycleErr)
+	return f.status, nil
+}
+
+func TestTrackCycleResult_HaltsWhenDisabled(t *testing.T) {
+	recorder := &fakeCycleResultRecorder{status: &config.CycleFailureStatus{Failures: 2, Threshold: 5}}
+	at := &AutoTrader{id: "trader-1", name: "test", database: recorder, isRunning: true, stopMonitorCh: make(chan struct{})}
+
+	at.trackCycleResult(errors.New("boom"))
+	if !at.isRunning {
+		t.Fatal("trader should keep running below the failure threshold")
+	}
+
+	recorder.status = &config.CycleFailureStatus{Failures: 5, Threshold: 5, JustDisabled: true}
+	at.trackCycleResult(errors.New("boom"))
+	if at.isRunning {
+		t.Fatal("trader should halt once the failure threshold is reached")
+	}
+	select {
+	case <-at.stopMonitorCh:
+	default:
+		t.Fatal("stopMonitorCh should be closed")
+	}
+	if len(recorder.errs) != 2 {
+		t.Fatalf("expected 2 recorded results, got %d", len(recorder.errs))
+	}
+}
+
+type fakeBalanceSnapshotRecorder struct {
+	snapshots []config.BalanceSnapshot
+}
+
+func (f *fakeBalanceSnapshotRecorder) RecordBalanceSnapshot(snapshot *config.BalanceSnapshot) error {
+	f.snapshots = append(f.snapshots, *snapshot)
+	return nil
+}
+
+func TestSnapshotBalance_Throttled(t *testing.T) {
+	recorder := &fakeBalanceSnapshotRecorder{}
+	at := &AutoTrader{id: "trader-1", name: "test", database: recorder}
+
+	at.snapshotBalance(1000, 800, 12.5)
+	at.snapshotBalance(1001, 801, 13)
+	if len(recorder.snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot within the interval, got %d", len(recorder.snapshots))
+	}
+	got := recorder.snapshots[0]
+	if got.TraderID != "trader-1" || got.WalletBalance != 1000 || got.AvailableBalance != 800 || got.UnrealizedPnL != 12.5 {
+		t.Fatalf("unexpected snapshot: %+v", got)
+	}
+
+	at.lastBalanceSnapshot = time.Now().Add(-balanceSnapshotInterval)
+	at.snapshotBalance(1002, 802, 14)
+	if len(recorder.snapshots) != 2 {
+		t.Fatalf("expected a new snapshot after the interval, got %d", len(recorder.snapshots))
+	}
+}
+
+func TestMaybeResetDailyMetrics_UsesTraderTimezone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	now := time.Now().In(shanghai)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, shanghai)
+
+	at := &AutoTrader{config: AutoTraderConfig{Timezone: shanghai}, dailyPnL: 12, lastResetTime: startOfDay.UTC()}
+	at.maybeResetDailyMetrics()
+	if at.dailyPnL != 12 || at.needsDailyBaseline {
+		t.Fatal("same local day should not reset, even when the UTC date differs")
+	}
+
+	at.lastResetTime = startOfDay.Add(-time.Minute)
+	at.maybeResetDailyMetrics()
+	if at.dailyPnL != 0 || !at.needsDailyBaseline {
+		t.Fatal("a new local day should reset daily metrics")
+	}
+}
+
+// fakeDailyEquityRecorder 返回固定的每日熔断状态
+type fakeDailyEquityRecorder struct {
+	status *config.DailyLossStatus
+}
+
+func (f *fakeDailyEquityRecorder) RecordDailyEquity(traderID string, equity float64) (*config.DailyLossStatus, error) {
+	return f.status, nil
+}
+
+func TestCheckDailyLossBreaker_StopsWhileDayBreached(t *testing.T) {
+	// 当天早些时候已熔断（JustBreached=false），重新启动后仍应停止
+	at := &AutoTrader{
+		name:      "test",
+		isRunning: true,
+		database:  &fakeDailyEquityRecorder{status: &config.DailyLossStatus{Breached: true, LimitPct: 5, PnLPct: -6}},
+	}
+	if _, halted := at.checkDailyLossBreaker(940); !halted || at.isRunning {
+		t.Fatalf("trader should be halted while the day is breached, halted=%v running=%v", halted, at.isRunning)
+	}
+
+	at = &AutoTrader{
+		name:      "test",
+		isRunning: true,
+		database:  &fakeDailyEquityRecorder{status: &config.DailyLossStatus{LimitPct: 5, PnLPct: -2}},
+	}
+	if _, halted := at.checkDailyLossBreaker(980); halted || !at.isRunning {
+		t.Fatal("trader should keep running below the daily loss limit")
+	}
+}
+
+// raceStop 并发调用 Stop 与周期内停止触发器，验证只关闭一次 stopMonitorCh（配合 -race 运行）
+func raceStop(t *testing.T, newTrader func() *AutoTrader, trigger func(at *AutoTrader)) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		at := newTrader()
+		at.isRunning = true
+		at.stopMonitorCh = make(chan struct{})
+
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			at.Stop()
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			trigger(at)
+		}()
+		close(start)
+		wg.Wait()
+
+		if at.running() {
+			t.Fatal("trader should be stopped")
+		}
+		select {
+		case <-at.stopMonitorCh:
+		default:
+			t.Fatal("stopMonitorCh should be closed")
+		}
+	}
+}
+
+func TestStop_ConcurrentWithHaltRunLoop(t *testing.T) {
+	raceStop(t, func() *AutoTrader {
+		return &AutoTrader{name: "test"}
+	}, func(at *AutoTrader) {
+		at.haltRunLoop()
+	})
+}
+
+func TestStop_ConcurrentWithDailyLossBreaker(t *testing.T) {
+	raceStop(t, func() *AutoTrader {
+		return &AutoTrader{
+			name:     "test",
+			database: &fakeDailyEquityRecorder{status: &config.DailyLossStatus{Breached: true, LimitPct: 5, PnLPct: -6}},
+		}
+	}, func(at *AutoTrader) {
+		at.checkDailyLossBreaker(940)
+	})
+}
+
+func TestStop_ConcurrentWithCycleFailureDisable(t *testing.T) {
+	raceStop(t, func() *AutoTrader {
+		return &AutoTrader{
+			id:       "trader-1",
+			name:     "test",
+			database: &fakeCycleResultRecorder{status: &config.CycleFailureStatus{Failures: 5, Threshold: 5, JustDisabled: true}},
+		}
+	}, func(at *AutoTrader) {
+		at.trackCycleResult(errors.New("boom"))
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"nofx/decision"
@@ -13,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/adshao/go-binance/v2/common"
 	"github.com/adshao/go-binance/v2/futures"
 )
 
@@ -62,9 +64,59 @@ type FuturesTrader struct {
 	cacheDuration time.Duration
 
 	// 订单策略配置
-	orderStrategy       string  // Order strategy: "market_only", "conservative_hybrid", "limit_only"
-	limitPriceOffset    float64 // Limit order price offset percentage (e.g., -0.03 for -0.03%)
-	limitTimeoutSeconds int     // Timeout in seconds before converting to market order
+	orderStrategy        string  // Order strategy: "market_only", "conservative_hybrid", "limit_only"
+	btcEthOrderStrategy  string  // BTC/ETH订单策略覆盖（为空时使用 orderStrategy）
+	altcoinOrderStrategy string  // 山寨币订单策略覆盖（为空时使用 orderStrategy）
+	limitPriceOffset     float64 // Limit order price offset percentage (e.g., -0.03 for -0.03%)
+	limitTimeoutSeconds  int     // Timeout in seconds before converting to market order
+
+	// 交易规则缓存（最小下单量/最小名义价值）
+	orderFilters     map[string]SymbolOrderFilter
+	orderFilterTime  time.Time
+	orderFilterMutex sync.RWMutex
+}
+
+// validOrderStrategies 支持的订单策略
+var validOrderStrategies = map[string]bool{
+	"market_only":         true,
+	"conservative_hybrid": true,
+	"limit_only":          true,
+}
+
+// IsValidOrderStrategy 检查订单策略是否有效
+func IsValidOrderStrategy(strategy string) bool {
+	return validOrderStrategies[strategy]
+}
+
+// IsMajorSymbol 判断是否为主流币（BTC/ETH），与杠杆配置的划分保持一致
+func IsMajorSymbol(symbol string) bool {
+	return symbol == "BTCUSDT" || symbol == "ETHUSDT"
+}
+
+// SetCategoryOrderStrategies 按币种类别覆盖订单策略（空字符串或无效值表示沿用全局策略）
+func (t *FuturesTrader) SetCategoryOrderStrategies(btcEthStrategy, altcoinStrategy string) {
+	if btcEthStrategy != "" && !IsValidOrderStrategy(btcEthStrategy) {
+		log.Printf("⚠️ 无效的BTC/ETH订单策略 %s，沿用全局策略 %s", btcEthStrategy, t.orderStrategy)
+		btcEthStrategy = ""
+	}
+	if altcoinStrategy != "" && !IsValidOrderStrategy(altcoinStrategy) {
+		log.Printf("⚠️ 无效的山寨币订单策略 %s，沿用全局策略 %s", altcoinStrategy, t.orderStrategy)
+		altcoinStrategy = ""
+	}
+	t.btcEthOrderStrategy = btcEthStrategy
+	t.altcoinOrderStrategy = altcoinStrategy
+}
+
+// orderStrategyFor 根据币种类别选择订单策略
+func (t *FuturesTrader) orderStrategyFor(symbol string) string {
+	if IsMajorSymbol(symbol) {
+		if t.btcEthOrderStrategy != "" {
+			return t.btcEthOrderStrategy
+		}
+	} else if t.altcoinOrderStrategy != "" {
+		return t.altcoinOrderStrategy
+	}
+	return t.orderStrategy
 }
 
 // NewFuturesTrader 创建合约交易器
@@ -366,43 +418,27 @@ func (t *FuturesTrader) GetCurrentPrice(symbol string) (float64, error) {
 	return price, nil
 }
 
-// FormatPrice 格式化价格到交易所要求的精度
+// FormatPrice 将价格对齐到交易对的 tickSize 并格式化（交易规则来自缓存，见 GetOrderFilter）
+// 限价偏移等计算出的价格小数位可能多于 tickSize 允许的位数，直接提交会被交易所拒绝
 func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error) {
-	// 获取交易对信息
-	info, err := t.client.NewExchangeInfoService().Do(context.Background())
+	filter, err := t.GetOrderFilter(symbol)
 	if err != nil {
-		return "", fmt.Errorf("获取交易对信息失败: %w", err)
-	}
-
-	// 查找对应的symbol信息
-	for _, s := range info.Symbols {
-		if s.Symbol == symbol {
-			// 找到价格精度过滤器
-			for _, filter := range s.Filters {
-				if filter["filterType"] == "PRICE_FILTER" {
-					tickSizeStr := filter["tickSize"].(string)
-					tickSize, err := strconv.ParseFloat(tickSizeStr, 64)
-					if err != nil {
-						return "", fmt.Errorf("解析tickSize失败: %w", err)
-					}
-
-					// 计算精度
-					precision := 0
-					temp := tickSize
-					for temp < 1 {
-						temp *= 10
-						precision++
-					}
-
-					// 格式化价格
-					format := fmt.Sprintf("%%.%df", precision)
-					return fmt.Sprintf(format, price), nil
-				}
-			}
-		}
+		return "", fmt.Errorf("获取 %s 价格精度失败: %w", symbol, err)
+	}
+	if filter.TickSize <= 0 {
+		return "", fmt.Errorf("未找到 %s 的价格精度信息", symbol)
 	}
+	return formatPriceWithFilter(filter, price), nil
+}
 
-	return "", fmt.Errorf("未找到 %s 的价格精度信息", symbol)
+// formatTriggerPrice 格式化止盈止损触发价，取不到价格精度时退回8位小数
+func (t *FuturesTrader) formatTriggerPrice(symbol string, price float64) string {
+	priceStr, err := t.FormatPrice(symbol, price)
+	if err != nil {
+		log.Printf("  ⚠ %v，触发价使用默认精度", err)
+		return fmt.Sprintf("%.8f", price)
+	}
+	return priceStr
 }
 
 // QueryOrderStatus 查询订单状态
@@ -417,6 +453,54 @@ func (t *FuturesTrader) QueryOrderStatus(symbol string, orderID int64) (string,
 	return string(order.Status), nil
 }
 
+// QueryOrderByClientID 按 clientOrderId 查询订单，不存在时返回 ErrOrderNotFound
+func (t *FuturesTrader) QueryOrderByClientID(symbol string, clientOrderID string) (map[string]interface{}, error) {
+	order, err := t.client.NewGetOrderService().
+		Symbol(symbol).
+		OrigClientOrderID(clientOrderID).
+		Do(context.Background())
+	if err != nil {
+		var apiErr *common.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == -2013 {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	if order.Status == futures.OrderStatusTypeFilled || order.Status == futures.OrderStatusTypePartiallyFilled {
+		t.InvalidateAllCaches()
+	}
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = order.Status
+	result["clientOrderId"] = order.ClientOrderID
+	result["type"] = order.Type
+	result["side"] = order.Side
+	result["positionSide"] = order.PositionSide
+	result["origQty"] = order.OrigQuantity
+	return result, nil
+}
+
+// MonitorPendingOrder 跟进下单超时后查到的未成交订单
+// conservative_hybrid 策略的限价单交给限价单监控（超时撤单转市价）；其他情况（limit_only 挂单、尚未撮合的市价单）原样返回
+func (t *FuturesTrader) MonitorPendingOrder(symbol string, order map[string]interface{}) (map[string]interface{}, error) {
+	orderType, _ := order["type"].(futures.OrderType)
+	orderID, _ := order["orderId"].(int64)
+	if orderType != futures.OrderTypeLimit || t.orderStrategyFor(symbol) != "conservative_hybrid" || orderID == 0 {
+		return order, nil
+	}
+	side, _ := order["side"].(futures.SideType)
+	positionSide, _ := order["positionSide"].(futures.PositionSideType)
+	quantityStr, _ := order["origQty"].(string)
+	result, _, err := t.monitorAndConvertLimitOrder(symbol, orderID, side, positionSide, quantityStr)
+	if err != nil {
+		return nil, fmt.Errorf("监控限价单失败: %w", err)
+	}
+	t.InvalidateAllCaches()
+	return result, nil
+}
+
 // CancelOrder 取消订单
 func (t *FuturesTrader) CancelOrder(symbol string, orderID int64) error {
 	_, err := t.client.NewCancelOrderService().
@@ -429,6 +513,21 @@ func (t *FuturesTrader) CancelOrder(symbol string, orderID int64) error {
 	return nil
 }
 
+// 成交方式（写入订单结果的 "liquidity" 字段，用于按 Maker/Taker 费率统计手续费）
+const (
+	LiquidityMaker = "maker"
+	LiquidityTaker = "taker"
+)
+
+// orderLiquidity 按订单类型推断成交方式：限价单（挂单）视为 Maker，其余视为 Taker
+// 仅在查不到成交明细时使用，能查到时以 userTrades 的 maker 字段和实际手续费为准（见 AutoTrader.recordTrade）
+func orderLiquidity(orderType futures.OrderType) string {
+	if orderType == futures.OrderTypeLimit {
+		return LiquidityMaker
+	}
+	return LiquidityTaker
+}
+
 // monitorAndConvertLimitOrder 监控限价单并在超时时转换为市价单
 // 返回值：最终订单结果, 是否发生了降级, error
 func (t *FuturesTrader) monitorAndConvertLimitOrder(
@@ -464,6 +563,7 @@ func (t *FuturesTrader) monitorAndConvertLimitOrder(
 				result["symbol"] = symbol
 				result["status"] = status
 				result["converted"] = false
+				result["liquidity"] = LiquidityMaker
 				return result, false, nil
 			}
 
@@ -500,6 +600,7 @@ func (t *FuturesTrader) monitorAndConvertLimitOrder(
 				result["symbol"] = marketOrder.Symbol
 				result["status"] = marketOrder.Status
 				result["converted"] = true
+				result["liquidity"] = LiquidityTaker
 				result["originalOrderId"] = orderID
 				return result, true, nil
 			}
@@ -515,6 +616,16 @@ func (t *FuturesTrader) monitorAndConvertLimitOrder(
 
 // OpenLong 开多仓
 func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openLong(symbol, quantity, leverage, "")
+}
+
+// OpenLongWithClientID 使用指定 clientOrderId 开多仓（幂等下单）
+func (t *FuturesTrader) OpenLongWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.openLong(symbol, quantity, leverage, clientOrderID)
+}
+
+// openLong 开多仓，clientOrderID 为空时自动生成
+func (t *FuturesTrader) openLong(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -544,9 +655,15 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 		return nil, err
 	}
 
-	// 根据订单策略创建订单
+	orderClientID := clientOrderID
+	if orderClientID == "" {
+		orderClientID = getBrOrderID()
+	}
+
+	// 根据订单策略创建订单（按币种类别选择策略）
+	orderStrategy := t.orderStrategyFor(symbol)
 	var order *futures.CreateOrderResponse
-	if t.orderStrategy == "market_only" {
+	if orderStrategy == "market_only" {
 		// 纯市价单策略
 		log.Printf("📋 [%s] 使用市价单策略", symbol)
 		order, err = t.client.NewCreateOrderService().
@@ -555,7 +672,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 			PositionSide(futures.PositionSideTypeLong).
 			Type(futures.OrderTypeMarket).
 			Quantity(quantityStr).
-			NewClientOrderID(getBrOrderID()).
+			NewClientOrderID(orderClientID).
 			Do(context.Background())
 	} else {
 		// 限价单策略（conservative_hybrid 或 limit_only）
@@ -583,13 +700,14 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 			Quantity(quantityStr).
 			Price(limitPriceStr).
 			TimeInForce(futures.TimeInForceTypeGTC). // Good Till Cancel
-			NewClientOrderID(getBrOrderID()).
+			NewClientOrderID(orderClientID).
 			Do(context.Background())
 
 		if err != nil {
 			log.Printf("⚠️ 限价单创建失败: %v", err)
 			// 如果是 conservative_hybrid 策略，失败后可以降级到市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			// 幂等下单时超时无法确认限价单是否已提交，交由调用方按 clientOrderId 确认，避免重复下单
+			if orderStrategy == "conservative_hybrid" && !(clientOrderID != "" && isTimeoutError(err)) {
 				log.Printf("📋 [%s] 限价单失败，降级为市价单", symbol)
 				order, err = t.client.NewCreateOrderService().
 					Symbol(symbol).
@@ -605,7 +723,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 			log.Printf("✓ 限价单创建成功: %s OrderID=%d", symbol, order.OrderID)
 
 			// 如果是 conservative_hybrid 策略，启动监控并在超时时转换为市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			if orderStrategy == "conservative_hybrid" {
 				result, converted, monitorErr := t.monitorAndConvertLimitOrder(
 					symbol,
 					order.OrderID,
@@ -646,11 +764,22 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["liquidity"] = orderLiquidity(order.Type)
 	return result, nil
 }
 
 // OpenShort 开空仓
 func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openShort(symbol, quantity, leverage, "")
+}
+
+// OpenShortWithClientID 使用指定 clientOrderId 开空仓（幂等下单）
+func (t *FuturesTrader) OpenShortWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.openShort(symbol, quantity, leverage, clientOrderID)
+}
+
+// openShort 开空仓，clientOrderID 为空时自动生成
+func (t *FuturesTrader) openShort(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
 	if err := t.CancelAllOrders(symbol); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
@@ -680,9 +809,15 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 		return nil, err
 	}
 
-	// 根据订单策略创建订单
+	orderClientID := clientOrderID
+	if orderClientID == "" {
+		orderClientID = getBrOrderID()
+	}
+
+	// 根据订单策略创建订单（按币种类别选择策略）
+	orderStrategy := t.orderStrategyFor(symbol)
 	var order *futures.CreateOrderResponse
-	if t.orderStrategy == "market_only" {
+	if orderStrategy == "market_only" {
 		// 纯市价单策略
 		log.Printf("📋 [%s] 使用市价单策略", symbol)
 		order, err = t.client.NewCreateOrderService().
@@ -691,7 +826,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 			PositionSide(futures.PositionSideTypeShort).
 			Type(futures.OrderTypeMarket).
 			Quantity(quantityStr).
-			NewClientOrderID(getBrOrderID()).
+			NewClientOrderID(orderClientID).
 			Do(context.Background())
 	} else {
 		// 限价单策略（conservative_hybrid 或 limit_only）
@@ -719,13 +854,14 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 			Quantity(quantityStr).
 			Price(limitPriceStr).
 			TimeInForce(futures.TimeInForceTypeGTC). // Good Till Cancel
-			NewClientOrderID(getBrOrderID()).
+			NewClientOrderID(orderClientID).
 			Do(context.Background())
 
 		if err != nil {
 			log.Printf("⚠️ 限价单创建失败: %v", err)
 			// 如果是 conservative_hybrid 策略，失败后可以降级到市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			// 幂等下单时超时无法确认限价单是否已提交，交由调用方按 clientOrderId 确认，避免重复下单
+			if orderStrategy == "conservative_hybrid" && !(clientOrderID != "" && isTimeoutError(err)) {
 				log.Printf("📋 [%s] 限价单失败，降级为市价单", symbol)
 				order, err = t.client.NewCreateOrderService().
 					Symbol(symbol).
@@ -741,7 +877,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 			log.Printf("✓ 限价单创建成功: %s OrderID=%d", symbol, order.OrderID)
 
 			// 如果是 conservative_hybrid 策略，启动监控并在超时时转换为市价单
-			if t.orderStrategy == "conservative_hybrid" {
+			if orderStrategy == "conservative_hybrid" {
 				result, converted, monitorErr := t.monitorAndConvertLimitOrder(
 					symbol,
 					order.OrderID,
@@ -782,6 +918,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	result["orderId"] = order.OrderID
 	result["symbol"] = order.Symbol
 	result["status"] = order.Status
+	result["liquidity"] = orderLiquidity(order.Type)
 	return result, nil
 }
 
@@ -1113,7 +1250,7 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		Side(side).
 		PositionSide(posSide).
 		Type(futures.OrderTypeStopMarket).
-		StopPrice(fmt.Sprintf("%.8f", stopPrice)).
+		StopPrice(t.formatTriggerPrice(symbol, stopPrice)).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
@@ -1154,7 +1291,7 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		Side(side).
 		PositionSide(posSide).
 		Type(futures.OrderTypeTakeProfitMarket).
-		StopPrice(fmt.Sprintf("%.8f", takeProfitPrice)).
+		StopPrice(t.formatTriggerPrice(symbol, takeProfitPrice)).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
@@ -1171,9 +1308,69 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	return nil
 }
 
-// GetMinNotional 获取最小名义价值（Binance要求）
+// PlaceOCOBracket 通过批量下单一次挂出关联的止损和止盈（实现 OCOBracketPlacer）
+// 两腿均为 closePosition 条件单：任一方触发平仓后另一方随持仓归零失效
+// 任一腿下单失败时撤销已挂出的条件单并返回错误，避免只剩单边保护
+func (t *FuturesTrader) PlaceOCOBracket(symbol, positionSide string, quantity, stopPrice, takeProfitPrice float64) error {
+	side := futures.SideTypeBuy
+	posSide := futures.PositionSideTypeShort
+	if positionSide == "LONG" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeLong
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	leg := func(orderType futures.OrderType, triggerPrice float64) *futures.CreateOrderService {
+		return t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(side).
+			PositionSide(posSide).
+			Type(orderType).
+			StopPrice(t.formatTriggerPrice(symbol, triggerPrice)).
+			Quantity(quantityStr).
+			WorkingType(futures.WorkingTypeContractPrice).
+			ClosePosition(true)
+	}
+	legNames := []string{"止损", "止盈"}
+	res, err := t.client.NewCreateBatchOrdersService().
+		OrderList([]*futures.CreateOrderService{
+			leg(futures.OrderTypeStopMarket, stopPrice),
+			leg(futures.OrderTypeTakeProfitMarket, takeProfitPrice),
+		}).
+		Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("挂出OCO止盈止损失败: %w", err)
+	}
+	for i, legErr := range res.Errors {
+		if legErr == nil {
+			continue
+		}
+		// 只撤销本次挂出成功的另一腿，不影响同币种反向持仓（双向持仓）的保护单
+		for _, order := range res.Orders {
+			if _, cancelErr := t.client.NewCancelOrderService().
+				Symbol(symbol).
+				OrderID(order.OrderID).
+				Do(context.Background()); cancelErr != nil {
+				log.Printf("  ⚠ 撤销OCO残留条件单 %d 失败: %v", order.OrderID, cancelErr)
+			}
+		}
+		return fmt.Errorf("挂出OCO%s单失败: %w", legNames[i], legErr)
+	}
+
+	t.InvalidatePositionsCache()
+	log.Printf("  OCO止盈止损设置: 止损 %.4f / 止盈 %.4f", stopPrice, takeProfitPrice)
+	return nil
+}
+
+// GetMinNotional 获取最小名义价值（Binance要求），取不到交易规则时使用保守的默认值 10 USDT
 func (t *FuturesTrader) GetMinNotional(symbol string) float64 {
-	// 使用保守的默认值 10 USDT，确保订单能够通过交易所验证
+	if filter, err := t.GetOrderFilter(symbol); err == nil && filter.MinNotional > 0 {
+		return filter.MinNotional
+	}
 	return 10.0
 }
 
@@ -1199,27 +1396,15 @@ func (t *FuturesTrader) CheckMinNotional(symbol string, quantity float64) error
 
 // GetSymbolPrecision 获取交易对的数量精度
 func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
-	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	filter, err := t.GetOrderFilter(symbol)
 	if err != nil {
-		return 0, fmt.Errorf("获取交易规则失败: %w", err)
-	}
-
-	for _, s := range exchangeInfo.Symbols {
-		if s.Symbol == symbol {
-			// 从LOT_SIZE filter获取精度
-			for _, filter := range s.Filters {
-				if filter["filterType"] == "LOT_SIZE" {
-					stepSize := filter["stepSize"].(string)
-					precision := calculatePrecision(stepSize)
-					log.Printf("  %s 数量精度: %d (stepSize: %s)", symbol, precision, stepSize)
-					return precision, nil
-				}
-			}
-		}
+		return 0, err
 	}
-
-	log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
-	return 3, nil // 默认精度为3
+	if filter.StepSize <= 0 {
+		log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
+		return 3, nil // 默认精度为3
+	}
+	return filter.QtyPrecision, nil
 }
 
 // calculatePrecision 从stepSize计算精度
@@ -1265,16 +1450,14 @@ func trimTrailingZeros(s string) string {
 	return s
 }
 
-// FormatQuantity 格式化数量到正确的精度
+// FormatQuantity 将数量向下对齐到交易对的 stepSize 并格式化（交易规则来自缓存，见 GetOrderFilter）
 func (t *FuturesTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	precision, err := t.GetSymbolPrecision(symbol)
-	if err != nil {
+	filter, err := t.GetOrderFilter(symbol)
+	if err != nil || filter.StepSize <= 0 {
 		// 如果获取失败，使用默认格式
 		return fmt.Sprintf("%.3f", quantity), nil
 	}
-
-	format := fmt.Sprintf("%%.%df", precision)
-	return fmt.Sprintf(format, quantity), nil
+	return formatQuantityWithFilter(filter, quantity), nil
 }
 
 // GetOpenOrders retrieves open orders for AI decision context
@@ -1329,6 +1512,40 @@ func (t *FuturesTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo,
 	return result, nil
 }
 
+// GetFills 获取 since 之后的成交明细（/fapi/v1/userTrades，最多 1000 条）
+func (t *FuturesTrader) GetFills(symbol string, since time.Time) ([]TradeFill, error) {
+	trades, err := t.client.NewListAccountTradeService().
+		Symbol(symbol).
+		StartTime(since.UnixMilli()).
+		Limit(1000).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取成交明细失败: %w", err)
+	}
+
+	fills := make([]TradeFill, 0, len(trades))
+	for _, trade := range trades {
+		price, _ := strconv.ParseFloat(trade.Price, 64)
+		quantity, _ := strconv.ParseFloat(trade.Quantity, 64)
+		realizedPnL, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+		commission, _ := strconv.ParseFloat(trade.Commission, 64)
+		fills = append(fills, TradeFill{
+			OrderID:         trade.OrderID,
+			Symbol:          trade.Symbol,
+			Side:            string(trade.Side),
+			PositionSide:    string(trade.PositionSide),
+			Price:           price,
+			Quantity:        quantity,
+			RealizedPnL:     realizedPnL,
+			Commission:      commission,
+			CommissionAsset: trade.CommissionAsset,
+			Maker:           trade.Maker,
+			Time:            time.UnixMilli(trade.Time),
+		})
+	}
+	return fills, nil
+}
+
 // 辅助函数
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && stringContains(s, substr)
@@ -3,14 +3,18 @@ package trader
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/adshao/go-binance/v2/futures"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // ============================================================
@@ -200,6 +204,25 @@ func NewBinanceFuturesTestSuite(t *testing.T) *BinanceFuturesTestSuite {
 				"workingType":   r.FormValue("workingType"),
 			}
 
+		// Mock CreateBatchOrders - /fapi/v1/batchOrders
+		case path == "/fapi/v1/batchOrders":
+			var orders []map[string]interface{}
+			json.Unmarshal([]byte(r.FormValue("batchOrders")), &orders)
+			placed := make([]map[string]interface{}, 0, len(orders))
+			for i, o := range orders {
+				placed = append(placed, map[string]interface{}{
+					"orderId":       123457 + i,
+					"symbol":        o["symbol"],
+					"status":        "NEW",
+					"type":          o["type"],
+					"side":          o["side"],
+					"positionSide":  o["positionSide"],
+					"stopPrice":     o["stopPrice"],
+					"closePosition": o["closePosition"] == "true",
+				})
+			}
+			respBody = placed
+
 		// Mock CancelOrder - /fapi/v1/order (DELETE)
 		case path == "/fapi/v1/order" && r.Method == "DELETE":
 			respBody = map[string]interface{}{
@@ -786,3 +809,85 @@ func TestGetOpenOrders_ParseFloatValues(t *testing.T) {
 		assert.True(t, hasValidPrice, "价格或止损价至少有一个应该大于0")
 	}
 }
+
+// TestPlaceOCOBracket 测试一次性批量挂出止损 + 止盈条件单
+func TestPlaceOCOBracket(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		batches     [][]map[string]interface{}
+		cancelled   []string
+		bulkCancels int
+		failTP      bool
+	)
+	mockServer := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var respBody interface{} = map[string]interface{}{}
+		switch {
+		case r.URL.Path == "/fapi/v1/batchOrders":
+			var orders []map[string]interface{}
+			json.Unmarshal([]byte(r.FormValue("batchOrders")), &orders)
+			batches = append(batches, orders)
+			placed := make([]interface{}, 0, len(orders))
+			for i, o := range orders {
+				if failTP && o["type"] == "TAKE_PROFIT_MARKET" {
+					placed = append(placed, map[string]interface{}{"code": -2021, "msg": "Order would immediately trigger."})
+					continue
+				}
+				placed = append(placed, map[string]interface{}{
+					"orderId": 200 + i, "symbol": o["symbol"], "status": "NEW", "type": o["type"],
+					"side": o["side"], "positionSide": o["positionSide"], "stopPrice": o["stopPrice"],
+				})
+			}
+			respBody = placed
+		case r.URL.Path == "/fapi/v1/order" && r.Method == http.MethodDelete:
+			// DELETE 请求的参数可能在请求体中，FormValue 不会解析
+			raw, _ := io.ReadAll(r.Body)
+			params, _ := url.ParseQuery(string(raw))
+			orderID := params.Get("orderId")
+			if orderID == "" {
+				orderID = r.URL.Query().Get("orderId")
+			}
+			cancelled = append(cancelled, orderID)
+			respBody = map[string]interface{}{"orderId": 200, "symbol": r.FormValue("symbol"), "status": "CANCELED"}
+		case r.URL.Path == "/fapi/v1/allOpenOrders" || r.URL.Path == "/fapi/v1/openOrders":
+			bulkCancels++
+			respBody = []interface{}{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(respBody)
+	}))
+	defer mockServer.Close()
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = mockServer.URL
+	client.HTTPClient = mockServer.Client()
+	trader := &FuturesTrader{client: client}
+
+	var _ OCOBracketPlacer = trader
+	require.NoError(t, trader.PlaceOCOBracket("BTCUSDT", "LONG", 0.01, 49000, 53000))
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 2)
+	for i, wantType := range []string{"STOP_MARKET", "TAKE_PROFIT_MARKET"} {
+		leg := batches[0][i]
+		assert.Equal(t, wantType, leg["type"])
+		assert.Equal(t, "SELL", leg["side"])
+		assert.Equal(t, "LONG", leg["positionSide"])
+		assert.Equal(t, "true", leg["closePosition"])
+		assert.Equal(t, "CONTRACT_PRICE", leg["workingType"])
+	}
+	assert.Equal(t, "49000.00000000", batches[0][0]["stopPrice"])
+	assert.Equal(t, "53000.00000000", batches[0][1]["stopPrice"])
+	assert.Empty(t, cancelled)
+
+	// 止盈腿失败：只撤销本次挂出的止损单，不批量撤销该币种的条件单
+	failTP = true
+	err := trader.PlaceOCOBracket("BTCUSDT", "SHORT", 0.01, 53000, 49000)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "止盈")
+	require.Len(t, batches, 2)
+	assert.Equal(t, "BUY", batches[1][0]["side"])
+	assert.Equal(t, "SHORT", batches[1][0]["positionSide"])
+	assert.Equal(t, []string{"200"}, cancelled)
+	assert.Zero(t, bulkCancels, "rollback must not touch the opposite side's orders")
+}
@@ -0,0 +1,531 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/decision"
+	"strconv"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/adshao/go-binance/v2/portfolio"
+)
+
+// PortfolioMarginTrader 币安统一账户（Portfolio Margin）U本位合约交易器
+// 账户、下单、持仓走 /papi 接口；行情、精度等公共数据复用 FuturesTrader 的 /fapi 接口
+// 统一账户的U本位合约固定为全仓，且暂仅支持市价单
+type PortfolioMarginTrader struct {
+	*FuturesTrader
+	pm *portfolio.Client
+}
+
+// isPortfolioMarginMode 判断交易所配置的账户类型是否为统一账户
+func isPortfolioMarginMode(accountMode string) bool {
+	return accountMode == config.AccountModePortfolioMargin
+}
+
+// NewPortfolioMarginTrader 创建统一账户交易器
+func NewPortfolioMarginTrader(apiKey, secretKey string) *PortfolioMarginTrader {
+	return newPortfolioMarginTraderWithClients(futures.NewClient(apiKey, secretKey), portfolio.NewClient(apiKey, secretKey))
+}
+
+// newPortfolioMarginTraderWithClients creates a trader with pre-configured clients (for testing)
+func newPortfolioMarginTraderWithClients(client *futures.Client, pm *portfolio.Client) *PortfolioMarginTrader {
+	syncBinanceServerTime(client)
+	pm.TimeOffset = client.TimeOffset
+
+	t := &PortfolioMarginTrader{
+		FuturesTrader: &FuturesTrader{
+			client:        client,
+			cacheDuration: 15 * time.Second,
+			orderStrategy: "market_only",
+		},
+		pm: pm,
+	}
+
+	// 与标准合约一致，使用双向持仓模式（LONG/SHORT）
+	if _, err := pm.NewChangeUMPositionModeService().DualSidePosition(true).Do(context.Background()); err != nil {
+		log.Printf("⚠️ 设置统一账户双向持仓模式失败: %v (如果已是双向模式则忽略此警告)", err)
+	}
+	return t
+}
+
+// GetBalance 获取统一账户余额（带缓存）
+// 统一账户以 USD 计价的账户权益为准，未实现盈亏取各资产的U本位未实现盈亏之和
+func (t *PortfolioMarginTrader) GetBalance() (map[string]interface{}, error) {
+	t.balanceCacheMutex.RLock()
+	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
+		t.balanceCacheMutex.RUnlock()
+		return t.cachedBalance, nil
+	}
+	t.balanceCacheMutex.RUnlock()
+
+	account, err := t.pm.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取统一账户信息失败: %w", err)
+	}
+	balances, err := t.pm.NewGetBalanceService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取统一账户余额失败: %w", err)
+	}
+
+	equity, _ := strconv.ParseFloat(account.AccountEquity, 64)
+	available, _ := strconv.ParseFloat(account.TotalAvailableBalance, 64)
+	unrealized := 0.0
+	for _, b := range balances {
+		pnl, _ := strconv.ParseFloat(b.UMUnrealizedPNL, 64)
+		unrealized += pnl
+	}
+
+	result := map[string]interface{}{
+		"totalWalletBalance":    equity - unrealized,
+		"availableBalance":      available,
+		"totalUnrealizedProfit": unrealized,
+	}
+	log.Printf("✓ 统一账户API返回: 账户权益=%s, 可用=%s, 未实现盈亏=%.4f",
+		account.AccountEquity, account.TotalAvailableBalance, unrealized)
+
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = result
+	t.balanceCacheTime = time.Now()
+	t.balanceCacheMutex.Unlock()
+	return result, nil
+}
+
+// GetPositions 获取统一账户U本位持仓（带缓存）
+func (t *PortfolioMarginTrader) GetPositions() ([]map[string]interface{}, error) {
+	t.positionsCacheMutex.RLock()
+	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
+		t.positionsCacheMutex.RUnlock()
+		return t.cachedPositions, nil
+	}
+	t.positionsCacheMutex.RUnlock()
+
+	positions, err := t.pm.NewGetUMPositionRiskService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取统一账户持仓失败: %w", err)
+	}
+
+	var result []map[string]interface{}
+	for _, pos := range positions {
+		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
+		if posAmt == 0 {
+			continue
+		}
+
+		posMap := make(map[string]interface{})
+		posMap["symbol"] = pos.Symbol
+		posMap["positionAmt"] = posAmt
+		posMap["entryPrice"], _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnrealizedProfit, 64)
+		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
+		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
+		if posAmt > 0 {
+			posMap["side"] = "long"
+		} else {
+			posMap["side"] = "short"
+		}
+		result = append(result, posMap)
+	}
+
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = result
+	t.positionsCacheTime = time.Now()
+	t.positionsCacheMutex.Unlock()
+	return result, nil
+}
+
+// SetMarginMode 统一账户U本位合约固定为全仓，逐仓配置会被忽略
+func (t *PortfolioMarginTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	if !isCrossMargin {
+		log.Printf("  ⚠️ %s 统一账户不支持逐仓模式，继续使用全仓", symbol)
+	}
+	return nil
+}
+
+// SetLeverage 设置U本位合约杠杆
+func (t *PortfolioMarginTrader) SetLeverage(symbol string, leverage int) error {
+	positions, err := t.GetPositions()
+	if err == nil {
+		for _, pos := range positions {
+			if pos["symbol"] == symbol {
+				if lev, ok := pos["leverage"].(float64); ok && int(lev) == leverage {
+					log.Printf("  ✓ %s 杠杆已是 %dx，无需切换", symbol, leverage)
+					return nil
+				}
+				break
+			}
+		}
+	}
+
+	if _, err := t.pm.NewChangeUMInitialLeverageService().Symbol(symbol).Leverage(leverage).Do(context.Background()); err != nil {
+		if contains(err.Error(), "No need to change") {
+			return nil
+		}
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// OpenLong 开多仓
+func (t *PortfolioMarginTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, quantity, leverage, portfolio.SideTypeBuy, portfolio.PositionSideTypeLong, "")
+}
+
+// OpenShort 开空仓
+func (t *PortfolioMarginTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, quantity, leverage, portfolio.SideTypeSell, portfolio.PositionSideTypeShort, "")
+}
+
+// OpenLongWithClientID 使用指定 clientOrderId 开多仓（幂等下单）
+func (t *PortfolioMarginTrader) OpenLongWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.openPosition(symbol, quantity, leverage, portfolio.SideTypeBuy, portfolio.PositionSideTypeLong, clientOrderID)
+}
+
+// OpenShortWithClientID 使用指定 clientOrderId 开空仓（幂等下单）
+func (t *PortfolioMarginTrader) OpenShortWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return t.openPosition(symbol, quantity, leverage, portfolio.SideTypeSell, portfolio.PositionSideTypeShort, clientOrderID)
+}
+
+func (t *PortfolioMarginTrader) openPosition(symbol string, quantity float64, leverage int, side portfolio.SideType, positionSide portfolio.PositionSideType, clientOrderID string) (map[string]interface{}, error) {
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
+	if parseErr != nil || quantityFloat <= 0 {
+		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)。建议增加开仓金额或选择价格更低的币种", quantity, quantityStr)
+	}
+	if err := t.CheckMinNotional(symbol, quantityFloat); err != nil {
+		return nil, err
+	}
+
+	order, err := t.placeMarketOrder(symbol, side, positionSide, quantityStr, clientOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("开仓失败: %w", err)
+	}
+	log.Printf("✓ 统一账户开仓成功: %s %s 数量: %s 订单ID: %d", symbol, positionSide, quantityStr, order.OrderID)
+	return portfolioOrderResult(order), nil
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *PortfolioMarginTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, quantity, "long", portfolio.SideTypeSell, portfolio.PositionSideTypeLong)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *PortfolioMarginTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, quantity, "short", portfolio.SideTypeBuy, portfolio.PositionSideTypeShort)
+}
+
+func (t *PortfolioMarginTrader) closePosition(symbol string, quantity float64, sideName string, side portfolio.SideType, positionSide portfolio.PositionSideType) (map[string]interface{}, error) {
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			if pos["symbol"] == symbol && pos["side"] == sideName {
+				quantity = pos["positionAmt"].(float64)
+				if quantity < 0 {
+					quantity = -quantity
+				}
+				break
+			}
+		}
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的%s持仓", symbol, sideName)
+		}
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := t.placeMarketOrder(symbol, side, positionSide, quantityStr, "")
+	if err != nil {
+		return nil, fmt.Errorf("平仓失败: %w", err)
+	}
+	log.Printf("✓ 统一账户平仓成功: %s %s 数量: %s", symbol, positionSide, quantityStr)
+
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+	return portfolioOrderResult(order), nil
+}
+
+// placeMarketOrder 下市价单，clientOrderID 为空时自动生成
+func (t *PortfolioMarginTrader) placeMarketOrder(symbol string, side portfolio.SideType, positionSide portfolio.PositionSideType, quantity, clientOrderID string) (*portfolio.UMOrder, error) {
+	if clientOrderID == "" {
+		clientOrderID = getBrOrderID()
+	}
+	order, err := t.pm.NewUMOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(positionSide).
+		Type(portfolio.OrderTypeMarket).
+		Quantity(quantity).
+		NewClientOrderID(clientOrderID).
+		Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	t.InvalidateAllCaches()
+	return order, nil
+}
+
+func portfolioOrderResult(order *portfolio.UMOrder) map[string]interface{} {
+	return map[string]interface{}{
+		"orderId": order.OrderID,
+		"symbol":  order.Symbol,
+		"status":  order.Status,
+	}
+}
+
+// SetStopLoss 设置止损单（统一账户条件单）
+func (t *PortfolioMarginTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.placeConditionalOrder(symbol, positionSide, "STOP_MARKET", quantity, stopPrice); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单（统一账户条件单）
+func (t *PortfolioMarginTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.placeConditionalOrder(symbol, positionSide, "TAKE_PROFIT_MARKET", quantity, takeProfitPrice); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+func (t *PortfolioMarginTrader) placeConditionalOrder(symbol, positionSide, strategyType string, quantity, stopPrice float64) error {
+	side := portfolio.SideTypeBuy
+	posSide := portfolio.PositionSideTypeShort
+	if positionSide == "LONG" {
+		side = portfolio.SideTypeSell
+		posSide = portfolio.PositionSideTypeLong
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.pm.NewUMConditionalOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		StrategyType(strategyType).
+		StopPrice(fmt.Sprintf("%.8f", stopPrice)).
+		Quantity(quantityStr).
+		WorkingType("CONTRACT_PRICE").
+		Do(context.Background())
+	if err != nil {
+		return err
+	}
+	t.InvalidatePositionsCache()
+	return nil
+}
+
+// CancelStopLossOrders 仅取消止损条件单
+func (t *PortfolioMarginTrader) CancelStopLossOrders(symbol string) error {
+	return t.cancelConditionalOrders(symbol, "STOP_MARKET", "STOP")
+}
+
+// CancelTakeProfitOrders 仅取消止盈条件单
+func (t *PortfolioMarginTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.cancelConditionalOrders(symbol, "TAKE_PROFIT_MARKET", "TAKE_PROFIT")
+}
+
+// CancelStopOrders 取消该币种的止盈/止损条件单
+func (t *PortfolioMarginTrader) CancelStopOrders(symbol string) error {
+	return t.cancelConditionalOrders(symbol, "STOP_MARKET", "STOP", "TAKE_PROFIT_MARKET", "TAKE_PROFIT")
+}
+
+func (t *PortfolioMarginTrader) cancelConditionalOrders(symbol string, strategyTypes ...string) error {
+	orders, err := t.pm.NewUMOpenConditionalOrdersService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return fmt.Errorf("获取未完成条件单失败: %w", err)
+	}
+
+	canceledCount := 0
+	var cancelErrors []error
+	for _, order := range orders {
+		matched := false
+		for _, st := range strategyTypes {
+			if order.StrategyType == st {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if _, err := t.pm.NewUMCancelConditionalOrderService().Symbol(symbol).StrategyID(order.StrategyID).Do(context.Background()); err != nil {
+			cancelErrors = append(cancelErrors, fmt.Errorf("条件单ID %d: %w", order.StrategyID, err))
+			continue
+		}
+		canceledCount++
+	}
+
+	if canceledCount > 0 {
+		log.Printf("  ✓ 已取消 %s 的 %d 个条件单", symbol, canceledCount)
+	}
+	if len(cancelErrors) > 0 && canceledCount == 0 {
+		return fmt.Errorf("取消条件单失败: %v", cancelErrors)
+	}
+	return nil
+}
+
+// CancelAllOrders 取消该币种的所有普通挂单和条件单
+func (t *PortfolioMarginTrader) CancelAllOrders(symbol string) error {
+	if _, err := t.pm.NewUMCancelAllOrdersService().Symbol(symbol).Do(context.Background()); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	if _, err := t.pm.NewUMCancelAllConditionalOrdersService().Symbol(symbol).Do(context.Background()); err != nil {
+		return fmt.Errorf("取消条件单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// QueryOrderStatus 查询订单状态
+func (t *PortfolioMarginTrader) QueryOrderStatus(symbol string, orderID int64) (string, error) {
+	order, err := t.pm.NewUMQueryOrderService().Symbol(symbol).OrderID(orderID).Do(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("查询订单状态失败: %w", err)
+	}
+	return order.Status, nil
+}
+
+// QueryOrderByClientID 按 clientOrderId 查询订单，不存在时返回 ErrOrderNotFound
+func (t *PortfolioMarginTrader) QueryOrderByClientID(symbol string, clientOrderID string) (map[string]interface{}, error) {
+	order, err := t.pm.NewUMQueryOrderService().Symbol(symbol).OrigClientOrderID(clientOrderID).Do(context.Background())
+	if err != nil {
+		var apiErr *portfolio.Error
+		if errors.As(err, &apiErr) && apiErr.Code == -2013 {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	t.InvalidateAllCaches()
+	return map[string]interface{}{
+		"orderId":       order.OrderID,
+		"symbol":        order.Symbol,
+		"status":        order.Status,
+		"clientOrderId": order.ClientOrderID,
+	}, nil
+}
+
+// MonitorPendingOrder 统一账户只下市价单，已提交的 NEW 订单会自行成交，原样返回
+// （覆盖 FuturesTrader 的实现，避免走 /fapi 的限价单监控）
+func (t *PortfolioMarginTrader) MonitorPendingOrder(symbol string, order map[string]interface{}) (map[string]interface{}, error) {
+	return order, nil
+}
+
+// CancelOrder 取消订单
+func (t *PortfolioMarginTrader) CancelOrder(symbol string, orderID int64) error {
+	if _, err := t.pm.NewUMCancelOrderService().Symbol(symbol).OrderID(orderID).Do(context.Background()); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	return nil
+}
+
+// GetFills 获取 since 之后的 UM 成交明细（统一账户走 /papi/v1/um/userTrades）
+func (t *PortfolioMarginTrader) GetFills(symbol string, since time.Time) ([]TradeFill, error) {
+	trades, err := t.pm.NewUMAccountTradeService().
+		Symbol(symbol).
+		StartTime(since.UnixMilli()).
+		Limit(1000).
+		Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取成交明细失败: %w", err)
+	}
+
+	fills := make([]TradeFill, 0, len(trades))
+	for _, trade := range trades {
+		price, _ := strconv.ParseFloat(trade.Price, 64)
+		quantity, _ := strconv.ParseFloat(trade.Qty, 64)
+		realizedPnL, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
+		commission, _ := strconv.ParseFloat(trade.Commission, 64)
+		fills = append(fills, TradeFill{
+			OrderID:         trade.OrderID,
+			Symbol:          trade.Symbol,
+			Side:            trade.Side,
+			PositionSide:    trade.PositionSide,
+			Price:           price,
+			Quantity:        quantity,
+			RealizedPnL:     realizedPnL,
+			Commission:      commission,
+			CommissionAsset: trade.CommissionAsset,
+			Maker:           trade.Maker,
+			Time:            time.UnixMilli(trade.Time),
+		})
+	}
+	return fills, nil
+}
+
+// GetOpenOrders 获取未成交订单（普通挂单 + 止盈止损条件单）
+func (t *PortfolioMarginTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	orderService := t.pm.NewUMOpenOrdersService()
+	conditionalService := t.pm.NewUMOpenConditionalOrdersService()
+	if symbol != "" {
+		orderService = orderService.Symbol(symbol)
+		conditionalService = conditionalService.Symbol(symbol)
+	}
+
+	orders, err := orderService.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取未成交订单失败: %w", err)
+	}
+	conditionalOrders, err := conditionalService.Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取未完成条件单失败: %w", err)
+	}
+
+	result := make([]decision.OpenOrderInfo, 0, len(orders)+len(conditionalOrders))
+	for _, order := range orders {
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQty, 64)
+		result = append(result, decision.OpenOrderInfo{
+			Symbol:       order.Symbol,
+			OrderID:      order.OrderID,
+			Type:         order.Type,
+			Side:         order.Side,
+			PositionSide: order.PositionSide,
+			Quantity:     quantity,
+			Price:        price,
+		})
+	}
+	for _, order := range conditionalOrders {
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		quantity, _ := strconv.ParseFloat(order.OrigQty, 64)
+		result = append(result, decision.OpenOrderInfo{
+			Symbol:       order.Symbol,
+			OrderID:      order.StrategyID,
+			Type:         order.StrategyType,
+			Side:         order.Side,
+			PositionSide: order.PositionSide,
+			Quantity:     quantity,
+			Price:        price,
+			StopPrice:    stopPrice,
+		})
+	}
+	return result, nil
+}
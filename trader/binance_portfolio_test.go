@@ -0,0 +1,101 @@
+package trader
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/adshao/go-binance/v2/portfolio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioMarginTrader_InterfaceCompliance(t *testing.T) {
+	var _ Trader = (*PortfolioMarginTrader)(nil)
+}
+
+func newTestPortfolioMarginTrader(t *testing.T) (*PortfolioMarginTrader, func() []string) {
+	var mu sync.Mutex
+	var orders []string
+
+	server := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var respBody interface{}
+		switch r.URL.Path {
+		case "/papi/v1/account":
+			respBody = map[string]interface{}{
+				"accountEquity":         "10100.00",
+				"totalAvailableBalance": "8000.00",
+			}
+		case "/papi/v1/balance":
+			respBody = []map[string]interface{}{
+				{"asset": "USDT", "umUnrealizedPNL": "80.00"},
+				{"asset": "USDC", "umUnrealizedPNL": "20.00"},
+			}
+		case "/papi/v1/um/positionRisk":
+			respBody = []map[string]interface{}{
+				{"symbol": "BTCUSDT", "positionAmt": "0.5", "entryPrice": "50000", "markPrice": "50200",
+					"unrealizedProfit": "100", "leverage": "10", "liquidationPrice": "45000", "positionSide": "LONG"},
+				{"symbol": "ETHUSDT", "positionAmt": "0", "leverage": "5", "positionSide": "LONG"},
+			}
+		case "/papi/v1/um/order":
+			mu.Lock()
+			orders = append(orders, r.URL.Query().Get("side")+" "+r.URL.Query().Get("positionSide")+" "+r.URL.Query().Get("quantity"))
+			mu.Unlock()
+			respBody = map[string]interface{}{"orderId": 42, "symbol": "BTCUSDT", "status": "FILLED"}
+		case "/fapi/v1/time":
+			respBody = map[string]interface{}{"serverTime": 1234567890000}
+		default:
+			respBody = map[string]interface{}{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(respBody)
+	}))
+	t.Cleanup(server.Close)
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	pm := portfolio.NewClient("test_api_key", "test_secret_key")
+	pm.BaseURL = server.URL
+	pm.HTTPClient = server.Client()
+
+	trader := newPortfolioMarginTraderWithClients(client, pm)
+	trader.cacheDuration = 0
+	return trader, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), orders...)
+	}
+}
+
+func TestPortfolioMarginTrader_BalanceAndPositions(t *testing.T) {
+	trader, _ := newTestPortfolioMarginTrader(t)
+
+	balance, err := trader.GetBalance()
+	require.NoError(t, err)
+	assert.InDelta(t, 10000.0, balance["totalWalletBalance"], 1e-9)
+	assert.InDelta(t, 8000.0, balance["availableBalance"], 1e-9)
+	assert.InDelta(t, 100.0, balance["totalUnrealizedProfit"], 1e-9)
+
+	positions, err := trader.GetPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1, "空仓位应被过滤")
+	assert.Equal(t, "BTCUSDT", positions[0]["symbol"])
+	assert.Equal(t, "long", positions[0]["side"])
+	assert.InDelta(t, 100.0, positions[0]["unRealizedProfit"], 1e-9)
+	assert.InDelta(t, 10.0, positions[0]["leverage"], 1e-9)
+
+	// 统一账户固定全仓，逐仓配置不应报错
+	assert.NoError(t, trader.SetMarginMode("BTCUSDT", false))
+}
+
+func TestPortfolioMarginTrader_CloseUsesPapiOrder(t *testing.T) {
+	trader, orders := newTestPortfolioMarginTrader(t)
+
+	result, err := trader.CloseLong("BTCUSDT", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), result["orderId"])
+	assert.Equal(t, []string{"SELL LONG 0.500"}, orders())
+}
@@ -0,0 +1,89 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"nofx/config"
+	"nofx/decision"
+)
+
+// decisionReader 决策记录读取器（由 config.Database 实现）
+type decisionReader interface {
+	GetDecision(userID string, id int64) (*config.Decision, error)
+}
+
+// DecisionReplay 历史决策的重放结果
+type DecisionReplay struct {
+	Original      *config.Decision       `json:"original"`              // 历史决策记录
+	Replay        *decision.FullDecision `json:"replay"`                // 使用当前配置重新请求AI的完整结果
+	Decision      *decision.Decision     `json:"decision,omitempty"`    // 重放结果中与历史决策同币种的决策
+	ActionChanged bool                   `json:"action_changed"`        // 同币种的动作是否与历史决策不同
+	ParseError    string                 `json:"parse_error,omitempty"` // AI 响应解析失败原因（仍返回思维链用于调试）
+}
+
+// ReplayDecision 将历史决策的输入prompt交给当前AI配置（模型、模板、自定义prompt）重新决策，用于对比prompt调整前后的行为
+// 只调用AI，不获取账户、不下单：重放结果不会进入执行流程
+func (at *AutoTrader) ReplayDecision(userID, decisionID string) (*DecisionReplay, error) {
+	if userID != at.userID {
+		return nil, fmt.Errorf("决策不存在或无访问权限")
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(decisionID), 10, 64)
+	if err != nil || id <= 0 {
+		return nil, fmt.Errorf("无效的决策ID: %s", decisionID)
+	}
+	reader, ok := at.database.(decisionReader)
+	if !ok {
+		return nil, fmt.Errorf("决策记录不可用")
+	}
+	original, err := reader.GetDecision(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.TraderID != at.id {
+		return nil, fmt.Errorf("决策不属于交易员 %s", at.id)
+	}
+	if at.mcpClient == nil {
+		return nil, fmt.Errorf("交易员 %s 未配置AI模型", at.id)
+	}
+
+	releaseAISlot, ok := GetAIConcurrencyLimiter().Acquire()
+	if !ok {
+		return nil, fmt.Errorf("全局AI并发已达上限，请稍后重试")
+	}
+	defer releaseAISlot()
+
+	// 用历史周期的净值和当前杠杆配置填充模板变量，保证重放只改变prompt配置
+	ctx := &decision.Context{
+		Account:         decision.AccountInfo{TotalEquity: original.AccountEquity},
+		CandidateCoins:  []decision.CandidateCoin{{Symbol: original.Symbol}},
+		BTCETHLeverage:  at.config.BTCETHLeverage,
+		AltcoinLeverage: at.config.AltcoinLeverage,
+	}
+	log.Printf("🔁 [%s] 重放决策 #%d (%s %s) [模板: %s]", at.name, original.ID, original.Symbol, original.Action, at.systemPromptTemplate)
+	full, err := decision.ReplayFullDecision(original.UserPrompt, original.AccountEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage,
+		at.mcpClient, at.cyclePrompt(ctx), at.overrideBasePrompt, at.systemPromptTemplate)
+	if full == nil {
+		return nil, err
+	}
+
+	result := &DecisionReplay{Original: original, Replay: full}
+	if err != nil {
+		result.ParseError = err.Error()
+	}
+	for i := range full.Decisions {
+		if full.Decisions[i].Symbol == original.Symbol {
+			result.Decision = &full.Decisions[i]
+			break
+		}
+	}
+	// 重放结果未提及该币种时视为 wait
+	replayedAction := "wait"
+	if result.Decision != nil {
+		replayedAction = result.Decision.Action
+	}
+	result.ActionChanged = replayedAction != original.Action
+	return result, nil
+}
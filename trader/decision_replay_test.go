@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/config"
+	"nofx/mcp"
+)
+
+type fakeDecisionReader struct {
+	decisions map[int64]*config.Decision
+}
+
+func (f *fakeDecisionReader) GetDecision(userID string, id int64) (*config.Decision, error) {
+	d, ok := f.decisions[id]
+	if !ok || d.UserID != userID {
+		return nil, errors.New("not found")
+	}
+	return d, nil
+}
+
+func TestReplayDecision(t *testing.T) {
+	var gotUserPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, m := range body.Messages {
+			if m.Role == "user" {
+				gotUserPrompt = m.Content
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{
+					"content": "思考\n```json\n[{\"symbol\":\"BTCUSDT\",\"action\":\"hold\",\"reasoning\":\"重放\"}]\n```",
+				}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := mcp.New()
+	client.SetAPIKey("test-key", server.URL+"#", "test-model")
+
+	// trader 为空：重放一旦触及交易接口就会 panic
+	at := &AutoTrader{
+		id:                   "tr-replay",
+		userID:               "user-1",
+		name:                 "replay",
+		mcpClient:            client,
+		systemPromptTemplate: "default",
+		config:               AutoTraderConfig{BTCETHLeverage: 5, AltcoinLeverage: 3},
+		database: &fakeDecisionReader{decisions: map[int64]*config.Decision{
+			7: {ID: 7, TraderID: "tr-replay", UserID: "user-1", Symbol: "BTCUSDT", Action: "open_long",
+				UserPrompt: "## 历史输入\nBTC 50000", AccountEquity: 1000},
+			8: {ID: 8, TraderID: "tr-other", UserID: "user-1", Symbol: "ETHUSDT", Action: "hold", UserPrompt: "x"},
+			9: {ID: 9, TraderID: "tr-replay", UserID: "user-1", Symbol: "ETHUSDT", Action: "hold"},
+		}},
+	}
+
+	result, err := at.ReplayDecision("user-1", "7")
+	if err != nil {
+		t.Fatalf("ReplayDecision failed: %v", err)
+	}
+	if gotUserPrompt != "## 历史输入\nBTC 50000" {
+		t.Fatalf("stored user prompt should be replayed verbatim, got %q", gotUserPrompt)
+	}
+	if result.Decision == nil || result.Decision.Action != "hold" || !result.ActionChanged {
+		t.Fatalf("unexpected replay result: %+v", result)
+	}
+	if !strings.Contains(result.Replay.SystemPrompt, "1000") {
+		t.Errorf("system prompt should be rebuilt with the recorded equity")
+	}
+
+	for _, c := range []struct{ user, id string }{{"user-2", "7"}, {"user-1", "abc"}, {"user-1", "8"}, {"user-1", "9"}, {"user-1", "404"}} {
+		if _, err := at.ReplayDecision(c.user, c.id); err == nil {
+			t.Errorf("user=%s id=%s: expected error", c.user, c.id)
+		}
+	}
+}
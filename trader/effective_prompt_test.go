@@ -0,0 +1,68 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/config"
+)
+
+// fakePromptTemplateReader 内存中的提示词模板
+type fakePromptTemplateReader struct {
+	templates map[string]string
+}
+
+func (f *fakePromptTemplateReader) GetPromptTemplateByName(userID, name string) (*config.PromptTemplate, error) {
+	body, ok := f.templates[name]
+	if !ok {
+		return nil, nil
+	}
+	return &config.PromptTemplate{UserID: userID, Name: name, Body: body}, nil
+}
+
+func TestEffectivePrompt(t *testing.T) {
+	// trader 与 mcpClient 为空：预览一旦触及交易或AI接口就会 panic
+	at := &AutoTrader{
+		id:                   "tr-prompt",
+		userID:               "user-1",
+		name:                 "preview",
+		initialBalance:       1000,
+		systemPromptTemplate: "default",
+		customPrompt:         "只做BTC趋势单",
+		tradingCoins:         []string{"BTCUSDT", "ETHUSDT"},
+		config:               AutoTraderConfig{BTCETHLeverage: 5, AltcoinLeverage: 3},
+		database: &fakePromptTemplateReader{templates: map[string]string{
+			"swing": "{{trader_name}} 只交易 {{symbols}}，杠杆 {{btc_eth_leverage}}x",
+		}},
+	}
+
+	prompt, err := at.EffectivePrompt()
+	if err != nil {
+		t.Fatalf("EffectivePrompt failed: %v", err)
+	}
+	if !strings.Contains(prompt, "个性化交易策略") || !strings.Contains(prompt, "只做BTC趋势单") || len(prompt) <= len("只做BTC趋势单") {
+		t.Fatalf("custom prompt should be appended to the base template, got %q", prompt)
+	}
+
+	at.overrideBasePrompt = true
+	if prompt, err := at.EffectivePrompt(); err != nil || prompt != "只做BTC趋势单" {
+		t.Fatalf("override should send the custom prompt only, got %q (%v)", prompt, err)
+	}
+
+	at.promptTemplateName = "swing"
+	if prompt, err := at.EffectivePrompt(); err != nil || prompt != "preview 只交易 BTCUSDT,ETHUSDT，杠杆 5x" {
+		t.Fatalf("referenced template should be rendered, got %q (%v)", prompt, err)
+	}
+
+	at.promptTemplateName = "missing"
+	if _, err := at.EffectivePrompt(); err == nil {
+		t.Fatal("expected error for a missing referenced template")
+	}
+
+	at.promptTemplateName = ""
+	at.overrideBasePrompt = false
+	at.systemPromptTemplate = "no-such-template"
+	if _, err := at.EffectivePrompt(); err == nil {
+		t.Fatal("expected error for a missing system prompt template")
+	}
+}
@@ -0,0 +1,116 @@
+package trader
+
+import (
+	"log"
+	"strings"
+
+	"nofx/decision"
+)
+
+// OCOBracketPlacer 可原生挂出关联止盈止损（OCO：一方触发后另一方失效）的交易器（可选接口）
+type OCOBracketPlacer interface {
+	PlaceOCOBracket(symbol, positionSide string, quantity, stopPrice, takeProfitPrice float64) error
+}
+
+// nativeOCOPlacer 返回可原生挂出 OCO 的交易器
+// 统一账户交易器嵌入 FuturesTrader 会继承 PlaceOCOBracket，但其批量下单走 /fapi，统一账户必须走 /papi，
+// 因此排除在外，改为分别挂单并由持仓监控模拟 OCO
+func nativeOCOPlacer(t Trader) (OCOBracketPlacer, bool) {
+	if _, ok := t.(*PortfolioMarginTrader); ok {
+		return nil, false
+	}
+	placer, ok := t.(OCOBracketPlacer)
+	return placer, ok
+}
+
+// exitBracketPrices 按开仓价百分比计算 OCO 止损/止盈价（按价格百分比，不含杠杆）；未配置时返回 false
+func exitBracketPrices(side string, entryPrice, tpPct, slPct float64) (stopLoss, takeProfit float64, ok bool) {
+	if tpPct <= 0 || slPct <= 0 || entryPrice <= 0 {
+		return 0, 0, false
+	}
+	switch side {
+	case "long":
+		return entryPrice * (1 - slPct/100), entryPrice * (1 + tpPct/100), true
+	case "short":
+		return entryPrice * (1 + slPct/100), entryPrice * (1 - tpPct/100), true
+	default:
+		return 0, 0, false
+	}
+}
+
+// applyExitBracket 配置了 tp_percent/sl_percent 时，用按当前价计算的止盈止损覆盖AI给出的价格
+func (at *AutoTrader) applyExitBracket(d *decision.Decision, side string, entryPrice float64) {
+	stopLoss, takeProfit, ok := exitBracketPrices(side, entryPrice, at.config.TakeProfitPercent, at.config.StopLossPercent)
+	if !ok {
+		return
+	}
+	log.Printf("  🎯 OCO止盈止损: 止损 %.4f (-%.2f%%) / 止盈 %.4f (+%.2f%%)，替代AI给出的 %.4f / %.4f",
+		stopLoss, at.config.StopLossPercent, takeProfit, at.config.TakeProfitPercent, d.StopLoss, d.TakeProfit)
+	d.StopLoss = stopLoss
+	d.TakeProfit = takeProfit
+}
+
+// placeExitOrders 开仓后挂出止盈止损，并记录到 positionStopLoss/positionTakeProfit，返回止损、止盈是否挂单成功
+// 配置了 OCO 百分比时：交易器支持原生 OCO（币安）则一次挂出关联的两腿；否则（Hyperliquid 等，或原生 OCO 失败）分别挂单，
+// 并登记到 exitBrackets，由持仓监控在持仓平掉后撤销剩余的一方，模拟 OCO
+func (at *AutoTrader) placeExitOrders(symbol, side string, quantity, stopLoss, takeProfit float64) (stopLossPlaced, takeProfitPlaced bool) {
+	posKey := symbol + "_" + side
+	positionSide := strings.ToUpper(side)
+	bracket := at.config.TakeProfitPercent > 0 && at.config.StopLossPercent > 0
+
+	if placer, ok := nativeOCOPlacer(at.trader); ok && bracket {
+		err := placer.PlaceOCOBracket(symbol, positionSide, quantity, stopLoss, takeProfit)
+		if err == nil {
+			at.positionStopLoss[posKey] = stopLoss
+			at.positionTakeProfit[posKey] = takeProfit
+			return true, true
+		}
+		// 原生 OCO 失败（已回滚本次挂出的单）时改为分别挂单，避免新仓位没有任何保护
+		log.Printf("  ⚠ 设置OCO止盈止损失败，改为分别挂单: %v", err)
+	}
+
+	if err := at.trader.SetStopLoss(symbol, positionSide, quantity, stopLoss); err != nil {
+		log.Printf("  ⚠ 设置止损失败: %v", err)
+	} else {
+		at.positionStopLoss[posKey] = stopLoss // 记录止损价格
+		stopLossPlaced = true
+	}
+	if err := at.trader.SetTakeProfit(symbol, positionSide, quantity, takeProfit); err != nil {
+		log.Printf("  ⚠ 设置止盈失败: %v", err)
+	} else {
+		at.positionTakeProfit[posKey] = takeProfit // 记录止盈价格
+		takeProfitPlaced = true
+	}
+
+	if bracket {
+		at.exitBracketsMutex.Lock()
+		if at.exitBrackets == nil {
+			at.exitBrackets = make(map[string]string)
+		}
+		at.exitBrackets[posKey] = symbol
+		at.exitBracketsMutex.Unlock()
+	}
+	return stopLossPlaced, takeProfitPlaced
+}
+
+// settleExitBrackets 模拟 OCO：持仓已平（止盈或止损一方触发）后撤销该币种剩余的条件单
+// 同一币种仍有反向持仓时只清除记录，避免误撤反向持仓的保护单
+func (at *AutoTrader) settleExitBrackets(activeKeys map[string]bool) {
+	at.exitBracketsMutex.Lock()
+	defer at.exitBracketsMutex.Unlock()
+	for key, symbol := range at.exitBrackets {
+		if activeKeys[key] {
+			continue
+		}
+		delete(at.exitBrackets, key)
+		if activeKeys[symbol+"_long"] || activeKeys[symbol+"_short"] {
+			log.Printf("⚠️ OCO：%s 已平仓，但该币种仍有反向持仓，保留其余条件单", key)
+			continue
+		}
+		if err := at.trader.CancelStopOrders(symbol); err != nil {
+			log.Printf("❌ OCO：撤销 %s 剩余止盈止损单失败: %v", key, err)
+			continue
+		}
+		log.Printf("🔗 OCO：%s 已平仓，已撤销剩余的止盈止损单", key)
+	}
+}
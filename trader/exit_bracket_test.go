@@ -0,0 +1,161 @@
+package trader
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"nofx/decision"
+)
+
+// bracketRecordingTrader 记录止盈止损挂单与撤单（不支持原生 OCO）
+type bracketRecordingTrader struct {
+	MockTrader
+	stops, takeProfits []float64
+	cancelled          []string
+}
+
+func (t *bracketRecordingTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	t.stops = append(t.stops, stopPrice)
+	return nil
+}
+
+func (t *bracketRecordingTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	t.takeProfits = append(t.takeProfits, takeProfitPrice)
+	return nil
+}
+
+func (t *bracketRecordingTrader) CancelStopOrders(symbol string) error {
+	t.cancelled = append(t.cancelled, symbol)
+	return nil
+}
+
+// ocoRecordingTrader 支持原生 OCO 的交易器
+type ocoRecordingTrader struct {
+	bracketRecordingTrader
+	brackets int
+	ocoErr   error
+}
+
+func (t *ocoRecordingTrader) PlaceOCOBracket(symbol, positionSide string, quantity, stopPrice, takeProfitPrice float64) error {
+	t.brackets++
+	return t.ocoErr
+}
+
+func TestExitBracketPrices(t *testing.T) {
+	sl, tp, ok := exitBracketPrices("long", 100, 6, 2)
+	if !ok || math.Abs(sl-98) > 1e-9 || math.Abs(tp-106) > 1e-9 {
+		t.Fatalf("long bracket = %v / %v, %v", sl, tp, ok)
+	}
+	sl, tp, ok = exitBracketPrices("short", 100, 6, 2)
+	if !ok || math.Abs(sl-102) > 1e-9 || math.Abs(tp-94) > 1e-9 {
+		t.Fatalf("short bracket = %v / %v, %v", sl, tp, ok)
+	}
+	if _, _, ok := exitBracketPrices("long", 100, 0, 2); ok {
+		t.Fatal("bracket disabled when tp percent is 0")
+	}
+
+	at := &AutoTrader{config: AutoTraderConfig{TakeProfitPercent: 6, StopLossPercent: 2}}
+	d := &decision.Decision{StopLoss: 90, TakeProfit: 150}
+	at.applyExitBracket(d, "long", 100)
+	if math.Abs(d.StopLoss-98) > 1e-9 || math.Abs(d.TakeProfit-106) > 1e-9 {
+		t.Fatalf("decision prices not overridden: %+v", d)
+	}
+}
+
+func TestPlaceExitOrders_NativeOCO(t *testing.T) {
+	mock := &ocoRecordingTrader{}
+	at := &AutoTrader{
+		trader:             mock,
+		config:             AutoTraderConfig{TakeProfitPercent: 6, StopLossPercent: 2},
+		positionStopLoss:   map[string]float64{},
+		positionTakeProfit: map[string]float64{},
+	}
+
+	at.placeExitOrders("BTCUSDT", "long", 1, 98, 106)
+	if mock.brackets != 1 || len(mock.stops) != 0 || len(mock.takeProfits) != 0 {
+		t.Fatalf("expected one native OCO bracket, got brackets=%d stops=%v tps=%v", mock.brackets, mock.stops, mock.takeProfits)
+	}
+	if at.positionStopLoss["BTCUSDT_long"] != 98 || at.positionTakeProfit["BTCUSDT_long"] != 106 {
+		t.Fatalf("bracket prices not recorded: %v %v", at.positionStopLoss, at.positionTakeProfit)
+	}
+	if len(at.exitBrackets) != 0 {
+		t.Fatalf("native OCO should not be emulated: %v", at.exitBrackets)
+	}
+}
+
+// TestPlaceExitOrders_NativeOCOFailureFallsBack 原生 OCO 失败时改为分别挂止损/止盈
+func TestPlaceExitOrders_NativeOCOFailureFallsBack(t *testing.T) {
+	mock := &ocoRecordingTrader{ocoErr: errors.New("batch rejected")}
+	at := &AutoTrader{
+		trader:             mock,
+		config:             AutoTraderConfig{TakeProfitPercent: 6, StopLossPercent: 2},
+		positionStopLoss:   map[string]float64{},
+		positionTakeProfit: map[string]float64{},
+	}
+
+	at.placeExitOrders("BTCUSDT", "long", 1, 98, 106)
+	if mock.brackets != 1 || len(mock.stops) != 1 || len(mock.takeProfits) != 1 {
+		t.Fatalf("expected fallback to separate orders, got brackets=%d stops=%v tps=%v", mock.brackets, mock.stops, mock.takeProfits)
+	}
+	if at.positionStopLoss["BTCUSDT_long"] != 98 || at.positionTakeProfit["BTCUSDT_long"] != 106 {
+		t.Fatalf("fallback prices not recorded: %v %v", at.positionStopLoss, at.positionTakeProfit)
+	}
+	if at.exitBrackets["BTCUSDT_long"] != "BTCUSDT" {
+		t.Fatalf("fallback orders should be tracked as an emulated bracket: %v", at.exitBrackets)
+	}
+}
+
+func TestPlaceExitOrders_EmulatedOCO(t *testing.T) {
+	mock := &bracketRecordingTrader{}
+	at := &AutoTrader{
+		trader:             mock,
+		config:             AutoTraderConfig{TakeProfitPercent: 6, StopLossPercent: 2},
+		positionStopLoss:   map[string]float64{},
+		positionTakeProfit: map[string]float64{},
+	}
+
+	at.placeExitOrders("ETHUSDT", "short", 1, 102, 94)
+	at.placeExitOrders("SOLUSDT", "long", 1, 98, 106)
+	if len(mock.stops) != 2 || len(mock.takeProfits) != 2 {
+		t.Fatalf("expected separate stop/take-profit orders, got %v %v", mock.stops, mock.takeProfits)
+	}
+
+	// ETH 空单仍持有：不撤单
+	at.settleExitBrackets(map[string]bool{"ETHUSDT_short": true, "SOLUSDT_long": true})
+	if len(mock.cancelled) != 0 {
+		t.Fatalf("no orders should be cancelled while positions are open: %v", mock.cancelled)
+	}
+
+	// SOL 多单被止盈/止损平掉：撤销剩余挂单
+	at.settleExitBrackets(map[string]bool{"ETHUSDT_short": true})
+	if len(mock.cancelled) != 1 || mock.cancelled[0] != "SOLUSDT" {
+		t.Fatalf("expected remaining SOLUSDT orders to be cancelled, got %v", mock.cancelled)
+	}
+	if _, exists := at.exitBrackets["SOLUSDT_long"]; exists {
+		t.Fatal("settled bracket should be removed")
+	}
+
+	// ETH 平仓但同币种仍有反向持仓：只清除记录
+	at.settleExitBrackets(map[string]bool{"ETHUSDT_long": true})
+	if len(mock.cancelled) != 1 || len(at.exitBrackets) != 0 {
+		t.Fatalf("orders of the opposite position must be kept: cancelled=%v brackets=%v", mock.cancelled, at.exitBrackets)
+	}
+
+	// 未配置 OCO 时不登记
+	at.config = AutoTraderConfig{}
+	at.placeExitOrders("BNBUSDT", "long", 1, 98, 106)
+	if len(at.exitBrackets) != 0 {
+		t.Fatalf("brackets should only be tracked when OCO is configured: %v", at.exitBrackets)
+	}
+}
+
+// TestNativeOCOPlacer_PortfolioMargin 统一账户不使用 /fapi 批量下单的原生 OCO
+func TestNativeOCOPlacer_PortfolioMargin(t *testing.T) {
+	if _, ok := nativeOCOPlacer(&FuturesTrader{}); !ok {
+		t.Fatal("futures trader should place native OCO brackets")
+	}
+	if _, ok := nativeOCOPlacer(&PortfolioMarginTrader{FuturesTrader: &FuturesTrader{}}); ok {
+		t.Fatal("portfolio margin trader must fall back to emulated OCO")
+	}
+}
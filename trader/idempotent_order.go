@@ -0,0 +1,115 @@
+package trader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrOrderNotFound 按 clientOrderId 查询时交易所不存在该订单
+var ErrOrderNotFound = errors.New("订单不存在")
+
+// ClientOrderTrader 支持按 clientOrderId 幂等下单的交易器（可选接口，目前由币安合约实现）
+// 开仓时传入确定性的 clientOrderId，超时后可据此向交易所确认订单是否已提交
+type ClientOrderTrader interface {
+	// OpenLongWithClientID 使用指定 clientOrderId 开多仓
+	OpenLongWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error)
+	// OpenShortWithClientID 使用指定 clientOrderId 开空仓
+	OpenShortWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error)
+	// QueryOrderByClientID 按 clientOrderId 查询订单，不存在时返回 ErrOrderNotFound
+	QueryOrderByClientID(symbol string, clientOrderID string) (map[string]interface{}, error)
+}
+
+// idempotentOrderRetries 下单超时后的最大重试次数（每次重试前先查询订单是否已提交）
+const idempotentOrderRetries = 2
+
+// idempotentRetryDelay 重试前的等待时间（测试中可缩短）
+var idempotentRetryDelay = time.Second
+
+// clientOrderIDFor 根据交易员、决策周期、币种和动作生成确定性的 clientOrderId
+// 同一决策的重试使用相同 ID；保留币安 broker 前缀，总长度不超过 32 字符
+func clientOrderIDFor(traderID, symbol, action string, cycleTime time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", traderID, symbol, action, cycleTime.UnixNano())))
+	return "x-KzrpZaP9" + hex.EncodeToString(sum[:])[:21]
+}
+
+// isTimeoutError 判断下单错误是否为超时（无法确定订单是否已提交）
+func isTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded")
+}
+
+// PendingOrderMonitor 可继续跟进已提交未成交订单的交易器（可选接口）
+// 下单超时后按 clientOrderId 查到 NEW 状态的订单时调用，按下单策略监控成交（如限价单超时转市价），返回最终订单结果
+type PendingOrderMonitor interface {
+	MonitorPendingOrder(symbol string, order map[string]interface{}) (map[string]interface{}, error)
+}
+
+// orderStatusOf 读取订单结果中的状态（币安返回 futures.OrderStatusType，统一转为大写字符串）
+func orderStatusOf(order map[string]interface{}) string {
+	status, ok := order["status"]
+	if !ok || status == nil {
+		return ""
+	}
+	return strings.ToUpper(fmt.Sprint(status))
+}
+
+// placeOrderIdempotent 使用确定性 clientOrderId 下单
+// 超时时先按 clientOrderId 查询交易所，按订单状态处理：
+// FILLED/PARTIALLY_FILLED 视为成功；NEW（已挂单未成交）交给限价单监控；CANCELED/EXPIRED/REJECTED 与确认不存在一样，
+// 用同一 ID 重试。无法确认订单状态时不重试，避免重复下单
+func placeOrderIdempotent(t ClientOrderTrader, symbol, clientOrderID string, place func(clientOrderID string) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		order, err := place(clientOrderID)
+		if err == nil {
+			return order, nil
+		}
+		if !isTimeoutError(err) {
+			return nil, err
+		}
+		log.Printf("  ⚠️ [%s] 下单超时 (clientOrderId=%s): %v", symbol, clientOrderID, err)
+
+		time.Sleep(idempotentRetryDelay)
+		order, queryErr := t.QueryOrderByClientID(symbol, clientOrderID)
+		switch {
+		case errors.Is(queryErr, ErrOrderNotFound):
+		case queryErr != nil:
+			// 无法确认订单状态时不能重试，否则可能重复下单
+			return nil, fmt.Errorf("下单超时且无法确认订单状态 (clientOrderId=%s): %w", clientOrderID, queryErr)
+		default:
+			switch status := orderStatusOf(order); status {
+			case "CANCELED", "EXPIRED", "REJECTED":
+				log.Printf("  ⚠️ [%s] 下单超时，订单已提交但状态为 %s (clientOrderId=%s)", symbol, status, clientOrderID)
+			case "NEW":
+				log.Printf("  ⏳ [%s] 下单超时，订单已挂出未成交 (clientOrderId=%s)，继续监控", symbol, clientOrderID)
+				if monitor, ok := t.(PendingOrderMonitor); ok {
+					return monitor.MonitorPendingOrder(symbol, order)
+				}
+				return order, nil
+			default:
+				log.Printf("  ✓ [%s] 下单超时但订单已提交 (clientOrderId=%s, 状态=%s)，不再重试", symbol, clientOrderID, status)
+				return order, nil
+			}
+		}
+		if attempt >= idempotentOrderRetries {
+			return nil, fmt.Errorf("下单多次超时 (clientOrderId=%s): %w", clientOrderID, err)
+		}
+		log.Printf("  🔁 [%s] 确认订单未成交，使用同一 clientOrderId 重试 (%d/%d)", symbol, attempt+1, idempotentOrderRetries)
+	}
+}
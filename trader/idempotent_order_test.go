@@ -0,0 +1,136 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClientOrderTrader 记录下单次数，并按 clientOrderId 保存"交易所"中的订单
+type fakeClientOrderTrader struct {
+	placeErrs []error         // 每次下单依次返回的错误（nil 表示成功）
+	placed    map[string]bool // 交易所实际收到的订单
+	queryErr  error           // 查询订单时返回的错误
+	statuses  []string        // 每次查询依次返回的订单状态（为空表示 FILLED）
+	queries   int
+	calls     int
+	monitored int
+}
+
+func (f *fakeClientOrderTrader) place(clientOrderID string) (map[string]interface{}, error) {
+	var err error
+	if f.calls < len(f.placeErrs) {
+		err = f.placeErrs[f.calls]
+	}
+	f.calls++
+	// 超时的请求也可能已经到达交易所
+	if err == nil || strings.Contains(err.Error(), "placed") {
+		f.placed[clientOrderID] = true
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"orderId": int64(f.calls)}, nil
+}
+
+func (f *fakeClientOrderTrader) OpenLongWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return f.place(clientOrderID)
+}
+
+func (f *fakeClientOrderTrader) OpenShortWithClientID(symbol string, quantity float64, leverage int, clientOrderID string) (map[string]interface{}, error) {
+	return f.place(clientOrderID)
+}
+
+func (f *fakeClientOrderTrader) QueryOrderByClientID(symbol string, clientOrderID string) (map[string]interface{}, error) {
+	if f.queryErr != nil {
+		return nil, f.queryErr
+	}
+	if !f.placed[clientOrderID] {
+		return nil, ErrOrderNotFound
+	}
+	status := "FILLED"
+	if f.queries < len(f.statuses) {
+		status = f.statuses[f.queries]
+	}
+	f.queries++
+	if status == "CANCELED" || status == "EXPIRED" || status == "REJECTED" {
+		// 订单已终结，之后用同一 ID 重试
+		delete(f.placed, clientOrderID)
+	}
+	return map[string]interface{}{"orderId": int64(99), "clientOrderId": clientOrderID, "status": status}, nil
+}
+
+func (f *fakeClientOrderTrader) MonitorPendingOrder(symbol string, order map[string]interface{}) (map[string]interface{}, error) {
+	f.monitored++
+	return map[string]interface{}{"orderId": int64(100), "status": "FILLED"}, nil
+}
+
+func TestClientOrderIDFor(t *testing.T) {
+	ts := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	id := clientOrderIDFor("trader-1", "BTCUSDT", "open_long", ts)
+	if id != clientOrderIDFor("trader-1", "BTCUSDT", "open_long", ts) {
+		t.Fatal("clientOrderId should be deterministic")
+	}
+	if !strings.HasPrefix(id, "x-KzrpZaP9") || len(id) > 32 {
+		t.Fatalf("unexpected clientOrderId format: %q (len %d)", id, len(id))
+	}
+	if id == clientOrderIDFor("trader-1", "BTCUSDT", "open_short", ts) ||
+		id == clientOrderIDFor("trader-1", "BTCUSDT", "open_long", ts.Add(time.Second)) {
+		t.Fatal("different decisions should produce different clientOrderIds")
+	}
+}
+
+func TestPlaceOrderIdempotent(t *testing.T) {
+	oldDelay := idempotentRetryDelay
+	idempotentRetryDelay = 0
+	defer func() { idempotentRetryDelay = oldDelay }()
+
+	timeoutPlaced := fmt.Errorf("request timeout (placed)")
+	timeoutLost := fmt.Errorf("request timeout")
+
+	tests := []struct {
+		name      string
+		placeErrs []error
+		queryErr  error
+		statuses  []string
+		wantErr   bool
+		wantCalls int
+		wantID    int64
+		monitored int
+	}{
+		{name: "success", wantCalls: 1, wantID: 1},
+		{name: "timeout but placed", placeErrs: []error{timeoutPlaced}, wantCalls: 1, wantID: 99},
+		{name: "timeout not placed then retry", placeErrs: []error{timeoutLost, nil}, wantCalls: 2, wantID: 2},
+		{name: "non-timeout error", placeErrs: []error{errors.New("insufficient margin")}, wantErr: true, wantCalls: 1},
+		{name: "query failure stops retry", placeErrs: []error{timeoutLost}, queryErr: errors.New("network down"), wantErr: true, wantCalls: 1},
+		{name: "always timeout", placeErrs: []error{timeoutLost, timeoutLost, timeoutLost}, wantErr: true, wantCalls: idempotentOrderRetries + 1},
+		{name: "timeout partially filled", placeErrs: []error{timeoutPlaced}, statuses: []string{"PARTIALLY_FILLED"}, wantCalls: 1, wantID: 99},
+		{name: "timeout new goes to monitor", placeErrs: []error{timeoutPlaced}, statuses: []string{"NEW"}, wantCalls: 1, wantID: 100, monitored: 1},
+		{name: "timeout canceled then retry", placeErrs: []error{timeoutPlaced, nil}, statuses: []string{"CANCELED"}, wantCalls: 2, wantID: 2},
+		{name: "timeout rejected then retry", placeErrs: []error{timeoutPlaced, nil}, statuses: []string{"REJECTED"}, wantCalls: 2, wantID: 2},
+		{name: "expired every time", placeErrs: []error{timeoutPlaced, timeoutPlaced, timeoutPlaced}, statuses: []string{"EXPIRED", "EXPIRED", "EXPIRED"}, wantErr: true, wantCalls: idempotentOrderRetries + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeClientOrderTrader{placeErrs: tt.placeErrs, placed: map[string]bool{}, queryErr: tt.queryErr, statuses: tt.statuses}
+			order, err := placeOrderIdempotent(f, "BTCUSDT", "cid-1", func(id string) (map[string]interface{}, error) {
+				return f.OpenLongWithClientID("BTCUSDT", 1, 5, id)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f.calls != tt.wantCalls {
+				t.Fatalf("expected %d place calls, got %d", tt.wantCalls, f.calls)
+			}
+			if !tt.wantErr && order["orderId"] != tt.wantID {
+				t.Fatalf("expected orderId %d, got %v", tt.wantID, order["orderId"])
+			}
+			if f.monitored != tt.monitored {
+				t.Fatalf("expected %d monitor calls, got %d", tt.monitored, f.monitored)
+			}
+		})
+	}
+}
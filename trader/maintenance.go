@@ -0,0 +1,117 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/notify"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow 每日维护窗口（UTC，[Start, End)，单位为当日分钟数；End < Start 表示跨越午夜）
+type MaintenanceWindow struct {
+	Start int
+	End   int
+}
+
+// Contains 判断 t（按 UTC）是否处于维护窗口内
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+func (w MaintenanceWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d UTC", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// ParseMaintenanceWindows 解析维护窗口配置（system_config: maintenance_window）
+// 格式为逗号分隔的 UTC 时间段，例如 "02:00-02:30" 或 "23:30-00:15,12:00-12:10"；空字符串表示不启用
+func ParseMaintenanceWindows(spec string) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		startStr, endStr, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("维护窗口格式错误（应为 HH:MM-HH:MM）: %s", part)
+		}
+		start, err := parseClockMinutes(startStr)
+		if err != nil {
+			return nil, fmt.Errorf("维护窗口开始时间无效: %w", err)
+		}
+		end, err := parseClockMinutes(endStr)
+		if err != nil {
+			return nil, fmt.Errorf("维护窗口结束时间无效: %w", err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("维护窗口开始和结束时间相同: %s", part)
+		}
+		windows = append(windows, MaintenanceWindow{Start: start, End: end})
+	}
+	return windows, nil
+}
+
+// parseClockMinutes 解析 HH:MM 为当日分钟数
+func parseClockMinutes(s string) (int, error) {
+	hStr, mStr, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("%q 不是 HH:MM 格式", s)
+	}
+	h, err := strconv.Atoi(hStr)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("%q 小时无效", s)
+	}
+	m, err := strconv.Atoi(mStr)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("%q 分钟无效", s)
+	}
+	return h*60 + m, nil
+}
+
+var (
+	maintenanceMu      sync.Mutex
+	maintenanceWindows []MaintenanceWindow
+	maintenanceActive  bool
+)
+
+// SetMaintenanceWindows 设置全局维护窗口（为空表示不启用），所有交易员在窗口内跳过定时周期
+func SetMaintenanceWindows(windows []MaintenanceWindow) {
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+	maintenanceWindows = windows
+}
+
+// inMaintenanceWindow 判断当前是否处于维护窗口；进入和离开窗口时各发送一次通知
+// 由各交易员的扫描循环调用，边界通知在窗口开始/结束后的首个扫描周期发出
+func inMaintenanceWindow(now time.Time) bool {
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+
+	active := false
+	var current MaintenanceWindow
+	for _, w := range maintenanceWindows {
+		if w.Contains(now) {
+			active, current = true, w
+			break
+		}
+	}
+	if active != maintenanceActive {
+		maintenanceActive = active
+		if active {
+			log.Printf("🛠️ 进入维护窗口 %s，暂停所有交易员的定时周期", current)
+			notify.NotifyLevel(notify.LevelWarn, fmt.Sprintf("🛠️ 进入维护窗口 %s，交易已自动暂停", current))
+		} else {
+			log.Printf("✅ 维护窗口结束，恢复交易员定时周期")
+			notify.NotifyLevel(notify.LevelWarn, "✅ 维护窗口结束，交易已自动恢复")
+		}
+	}
+	return active
+}
@@ -0,0 +1,106 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	windows, err := ParseMaintenanceWindows(" 02:00-02:30, 23:30-00:15 ")
+	if err != nil {
+		t.Fatalf("ParseMaintenanceWindows failed: %v", err)
+	}
+	if len(windows) != 2 || windows[0] != (MaintenanceWindow{Start: 120, End: 150}) || windows[1] != (MaintenanceWindow{Start: 1410, End: 15}) {
+		t.Fatalf("unexpected windows: %+v", windows)
+	}
+
+	if windows, err := ParseMaintenanceWindows(""); err != nil || len(windows) != 0 {
+		t.Fatalf("empty spec should disable windows, got %+v, %v", windows, err)
+	}
+	for _, bad := range []string{"02:00", "25:00-26:00", "02:00-02:00", "ab:cd-01:00"} {
+		if _, err := ParseMaintenanceWindows(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	normal := MaintenanceWindow{Start: 120, End: 150}
+	wrap := MaintenanceWindow{Start: 1410, End: 15}
+
+	cases := []struct {
+		w    MaintenanceWindow
+		at   time.Duration
+		want bool
+	}{
+		{normal, 2 * time.Hour, true},
+		{normal, 2*time.Hour + 29*time.Minute, true},
+		{normal, 2*time.Hour + 30*time.Minute, false},
+		{normal, time.Hour, false},
+		{wrap, 23*time.Hour + 45*time.Minute, true},
+		{wrap, 10 * time.Minute, true},
+		{wrap, 15 * time.Minute, false},
+	}
+	for _, c := range cases {
+		if got := c.w.Contains(day.Add(c.at)); got != c.want {
+			t.Errorf("%s contains %v = %v, want %v", c.w, c.at, got, c.want)
+		}
+	}
+
+	// 非 UTC 时间按 UTC 判断
+	cst := time.FixedZone("CST", 8*3600)
+	if !normal.Contains(time.Date(2025, 6, 1, 10, 10, 0, 0, cst)) {
+		t.Error("10:10 CST (02:10 UTC) should be inside the window")
+	}
+}
+
+func TestInMaintenanceWindowTransitions(t *testing.T) {
+	SetMaintenanceWindows([]MaintenanceWindow{{Start: 120, End: 150}})
+	defer func() {
+		SetMaintenanceWindows(nil)
+		inMaintenanceWindow(time.Now())
+	}()
+
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	if inMaintenanceWindow(day.Add(time.Hour)) {
+		t.Fatal("01:00 should not be in maintenance")
+	}
+	if !inMaintenanceWindow(day.Add(2*time.Hour + 5*time.Minute)) {
+		t.Fatal("02:05 should be in maintenance")
+	}
+	if !maintenanceActive {
+		t.Fatal("expected maintenance state to be active after entering window")
+	}
+	if inMaintenanceWindow(day.Add(3 * time.Hour)) {
+		t.Fatal("03:00 should not be in maintenance")
+	}
+	if maintenanceActive {
+		t.Fatal("expected maintenance state to be cleared after leaving window")
+	}
+}
+
+// scanCounter 记录 TouchTraderScan 调用次数
+type scanCounter struct{ touched int }
+
+func (s *scanCounter) TouchTraderScan(traderID string) error {
+	s.touched++
+	return nil
+}
+
+func TestRunScheduledCycle_TouchesScanDuringMaintenance(t *testing.T) {
+	now := time.Now().UTC()
+	start := (now.Hour()*60 + now.Minute() + 1439) % 1440
+	SetMaintenanceWindows([]MaintenanceWindow{{Start: start, End: (start + 10) % 1440}})
+	defer func() {
+		SetMaintenanceWindows(nil)
+		inMaintenanceWindow(time.Now())
+	}()
+
+	recorder := &scanCounter{}
+	at := &AutoTrader{name: "maintenance-trader", database: recorder}
+	at.runScheduledCycle()
+	if recorder.touched != 1 {
+		t.Fatalf("expected the skipped cycle to record a scan, got %d", recorder.touched)
+	}
+}
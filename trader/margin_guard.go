@@ -0,0 +1,71 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/config"
+	"nofx/notify"
+	"sync"
+)
+
+var (
+	marginFloorMu sync.RWMutex
+	marginFloor   float64
+)
+
+// SetMarginFloor 设置可用保证金下限（USDT，system_config: min_available_margin）
+// 周期开始时可用保证金低于该值即停止交易员；默认 0，即可用保证金为负时停止
+func SetMarginFloor(usd float64) {
+	if math.IsNaN(usd) || math.IsInf(usd, 0) {
+		usd = 0
+	}
+	marginFloorMu.Lock()
+	defer marginFloorMu.Unlock()
+	marginFloor = usd
+}
+
+func getMarginFloor() float64 {
+	marginFloorMu.RLock()
+	defer marginFloorMu.RUnlock()
+	return marginFloor
+}
+
+// cycleBalance 返回本周期的账户余额，同一周期内只向交易所查询一次
+func (at *AutoTrader) cycleBalance() (map[string]interface{}, error) {
+	if at.cycleBalanceCache != nil {
+		return at.cycleBalanceCache, nil
+	}
+	balance, err := at.trader.GetBalance()
+	if err != nil {
+		return nil, err
+	}
+	at.cycleBalanceCache = balance
+	return balance, nil
+}
+
+// checkMarginFloor 周期开始时检查可用保证金，低于下限时停止交易员、记录 stop_reason 并发送通知
+// 这是防止爆仓的最后一道保护；读取余额失败时不拦截（由后续构建上下文时报错）
+func (at *AutoTrader) checkMarginFloor() (string, bool) {
+	balance, err := at.cycleBalance()
+	if err != nil {
+		return "", false
+	}
+	available, ok := balance["availableBalance"].(float64)
+	if !ok {
+		return "", false
+	}
+	floor := getMarginFloor()
+	if available >= floor {
+		return "", false
+	}
+
+	reason := fmt.Sprintf("可用保证金 %.2f USDT 低于下限 %.2f USDT", available, floor)
+	// 与 Stop 共用同一个加锁的停止入口，并发调用时 stopMonitorCh 只关闭一次
+	at.signalStop()
+	if err := at.PersistRunStatus(false, fmt.Sprintf("%s: %s", config.StopReasonMarginFloor, reason)); err != nil {
+		log.Printf("⚠️ [%s] 保存停止状态失败: %v", at.name, err)
+	}
+	notify.NotifyLevel(notify.LevelError, fmt.Sprintf("🚨 交易员 %s %s，已自动停止", at.name, reason))
+	return reason, true
+}
@@ -0,0 +1,97 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/config"
+)
+
+// balanceCountingTrader 统计余额查询次数
+type balanceCountingTrader struct {
+	MockTrader
+	calls int
+}
+
+func (t *balanceCountingTrader) GetBalance() (map[string]interface{}, error) {
+	t.calls++
+	return t.MockTrader.GetBalance()
+}
+
+type fakeStatusUpdater struct {
+	running bool
+	reason  string
+	updates int
+}
+
+func (f *fakeStatusUpdater) UpdateTraderStatus(userID, id string, isRunning bool, reason string) error {
+	f.running, f.reason = isRunning, reason
+	f.updates++
+	return nil
+}
+
+func TestCheckMarginFloor(t *testing.T) {
+	defer SetMarginFloor(0)
+
+	mock := &balanceCountingTrader{MockTrader: MockTrader{balance: map[string]interface{}{
+		"totalWalletBalance":    1000.0,
+		"availableBalance":      50.0,
+		"totalUnrealizedProfit": -200.0,
+	}}}
+	db := &fakeStatusUpdater{}
+	at := &AutoTrader{id: "trader-1", name: "test", trader: mock, database: db, isRunning: true, stopMonitorCh: make(chan struct{})}
+
+	if _, triggered := at.checkMarginFloor(); triggered {
+		t.Fatal("positive margin should pass with the default floor")
+	}
+	if _, err := at.cycleBalance(); err != nil {
+		t.Fatalf("cycleBalance failed: %v", err)
+	}
+	if mock.calls != 1 {
+		t.Fatalf("balance should be read once per cycle, got %d calls", mock.calls)
+	}
+
+	at.cycleBalanceCache = nil
+	SetMarginFloor(100)
+	reason, triggered := at.checkMarginFloor()
+	if !triggered {
+		t.Fatal("margin below the floor should stop the trader")
+	}
+	if at.isRunning {
+		t.Fatal("run loop should be halted")
+	}
+	if db.updates != 1 || db.running || !strings.HasPrefix(db.reason, config.StopReasonMarginFloor) || !strings.Contains(db.reason, reason) {
+		t.Fatalf("unexpected persisted status: %+v", db)
+	}
+}
+
+func TestCheckMarginFloor_NegativeMargin(t *testing.T) {
+	mock := &MockTrader{balance: map[string]interface{}{
+		"totalWalletBalance":    1000.0,
+		"availableBalance":      -5.0,
+		"totalUnrealizedProfit": -1100.0,
+	}}
+	at := &AutoTrader{name: "test", trader: mock, isRunning: true, stopMonitorCh: make(chan struct{})}
+
+	if _, triggered := at.checkMarginFloor(); !triggered {
+		t.Fatal("negative available margin should stop the trader")
+	}
+}
+
+func TestStop_ConcurrentWithMarginFloor(t *testing.T) {
+	defer SetMarginFloor(0)
+	SetMarginFloor(100)
+
+	raceStop(t, func() *AutoTrader {
+		mock := &MockTrader{balance: map[string]interface{}{
+			"totalWalletBalance":    1000.0,
+			"availableBalance":      50.0,
+			"totalUnrealizedProfit": -200.0,
+		}}
+		return &AutoTrader{id: "trader-1", name: "test", trader: mock, database: &fakeStatusUpdater{}}
+	}, func(at *AutoTrader) {
+		if _, triggered := at.checkMarginFloor(); !triggered {
+			t.Error("margin below the floor should stop the trader")
+		}
+	})
+}
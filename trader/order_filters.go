@@ -0,0 +1,161 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// orderFilterCacheTTL 交易规则（最小下单量/最小名义价值/价格和数量精度）缓存时长
+const orderFilterCacheTTL = time.Hour
+
+// ErrBelowMinOrder 订单低于交易所最小下单要求（跳过下单，不视为执行失败）
+var ErrBelowMinOrder = errors.New("订单低于交易所最小下单要求")
+
+// SymbolOrderFilter 交易对的下单限制
+type SymbolOrderFilter struct {
+	MinNotional float64 // 最小名义价值（USDT）
+	MinQty      float64 // 最小下单数量
+
+	TickSize       float64 // 价格最小变动单位（PRICE_FILTER.tickSize），0 表示未知
+	StepSize       float64 // 数量最小变动单位（LOT_SIZE.stepSize），0 表示未知
+	PricePrecision int     // 价格小数位数（由 tickSize 推算）
+	QtyPrecision   int     // 数量小数位数（由 stepSize 推算）
+}
+
+// OrderFilterProvider 可查询交易对最小下单限制的交易器（可选接口）
+type OrderFilterProvider interface {
+	GetOrderFilter(symbol string) (*SymbolOrderFilter, error)
+}
+
+var (
+	minOrderNotionalMu    sync.RWMutex
+	minOrderNotionalFloor float64
+)
+
+// SetMinOrderNotional 设置全局最小下单名义价值（USDT，system_config: min_order_notional）
+// 与交易所规则取较大值；<= 0 表示只使用交易所规则
+func SetMinOrderNotional(usd float64) {
+	minOrderNotionalMu.Lock()
+	defer minOrderNotionalMu.Unlock()
+	minOrderNotionalFloor = math.Max(usd, 0)
+}
+
+func getMinOrderNotional() float64 {
+	minOrderNotionalMu.RLock()
+	defer minOrderNotionalMu.RUnlock()
+	return minOrderNotionalFloor
+}
+
+// checkOrderMinimums 下单前校验数量和名义价值，低于限制时返回 ErrBelowMinOrder
+// 交易规则获取失败时只记录警告并放行，由交易所做最终校验
+func checkOrderMinimums(t Trader, symbol string, quantity, price float64) error {
+	filter := &SymbolOrderFilter{}
+	if provider, ok := t.(OrderFilterProvider); ok {
+		f, err := provider.GetOrderFilter(symbol)
+		if err != nil {
+			log.Printf("  ⚠️ 获取 %s 交易规则失败，跳过最小下单检查: %v", symbol, err)
+		} else {
+			filter = f
+		}
+	}
+	minNotional := math.Max(filter.MinNotional, getMinOrderNotional())
+
+	if filter.MinQty > 0 && quantity < filter.MinQty {
+		return fmt.Errorf("%w: %s 数量 %.6f 低于最小下单量 %.6f", ErrBelowMinOrder, symbol, quantity, filter.MinQty)
+	}
+	if notional := quantity * price; minNotional > 0 && notional < minNotional {
+		return fmt.Errorf("%w: %s 名义价值 %.2f USDT 低于最小要求 %.2f USDT", ErrBelowMinOrder, symbol, notional, minNotional)
+	}
+	return nil
+}
+
+// GetOrderFilter 获取交易对的下单限制（整份交易规则缓存1小时，避免每笔订单请求 exchangeInfo）
+func (t *FuturesTrader) GetOrderFilter(symbol string) (*SymbolOrderFilter, error) {
+	t.orderFilterMutex.RLock()
+	filter, ok := t.orderFilters[symbol]
+	fresh := time.Since(t.orderFilterTime) < orderFilterCacheTTL
+	t.orderFilterMutex.RUnlock()
+	if ok && fresh {
+		return &filter, nil
+	}
+
+	info, err := t.client.NewExchangeInfoService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	filters := make(map[string]SymbolOrderFilter, len(info.Symbols))
+	for _, s := range info.Symbols {
+		var f SymbolOrderFilter
+		for _, raw := range s.Filters {
+			switch raw["filterType"] {
+			case "MIN_NOTIONAL":
+				f.MinNotional = parseFilterValue(raw["notional"])
+			case "PRICE_FILTER":
+				f.TickSize = parseFilterValue(raw["tickSize"])
+				if s, ok := raw["tickSize"].(string); ok {
+					f.PricePrecision = calculatePrecision(s)
+				}
+			case "LOT_SIZE", "MARKET_LOT_SIZE":
+				f.MinQty = math.Max(f.MinQty, parseFilterValue(raw["minQty"]))
+				// 数量精度以限价单的 LOT_SIZE 为准
+				if raw["filterType"] == "LOT_SIZE" {
+					f.StepSize = parseFilterValue(raw["stepSize"])
+					if s, ok := raw["stepSize"].(string); ok {
+						f.QtyPrecision = calculatePrecision(s)
+					}
+				}
+			}
+		}
+		filters[s.Symbol] = f
+	}
+
+	t.orderFilterMutex.Lock()
+	t.orderFilters = filters
+	t.orderFilterTime = time.Now()
+	t.orderFilterMutex.Unlock()
+
+	filter, ok = filters[symbol]
+	if !ok {
+		return nil, fmt.Errorf("未找到 %s 的交易规则", symbol)
+	}
+	return &filter, nil
+}
+
+// floorToStep 将数量向下取整到 stepSize 的整数倍，避免取整后超出可用保证金或持仓数量（stepSize <= 0 时原样返回）
+// 加上微小容差，防止 0.3/0.1 这类浮点误差被多舍掉一个步长
+func floorToStep(quantity, stepSize float64) float64 {
+	if stepSize <= 0 {
+		return quantity
+	}
+	return math.Floor(quantity/stepSize+1e-9) * stepSize
+}
+
+// formatPriceWithFilter 按交易规则对齐并格式化价格
+func formatPriceWithFilter(filter *SymbolOrderFilter, price float64) string {
+	return strconv.FormatFloat(roundToTickSize(price, filter.TickSize), 'f', filter.PricePrecision, 64)
+}
+
+// formatQuantityWithFilter 按交易规则对齐并格式化数量
+func formatQuantityWithFilter(filter *SymbolOrderFilter, quantity float64) string {
+	return strconv.FormatFloat(floorToStep(quantity, filter.StepSize), 'f', filter.QtyPrecision, 64)
+}
+
+// parseFilterValue 解析 exchangeInfo filter 中的字符串数值
+func parseFilterValue(v interface{}) float64 {
+	s, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,88 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuturesTrader_GetOrderFilter(t *testing.T) {
+	suite := NewBinanceFuturesTestSuite(t)
+	defer suite.Cleanup()
+
+	trader := suite.Trader.(*FuturesTrader)
+	filter, err := trader.GetOrderFilter("BTCUSDT")
+	require.NoError(t, err)
+	assert.Equal(t, 0.001, filter.MinQty)
+	assert.Equal(t, 0.01, filter.TickSize)
+	assert.Equal(t, 2, filter.PricePrecision)
+	assert.Equal(t, 0.001, filter.StepSize)
+	assert.Equal(t, 3, filter.QtyPrecision)
+	assert.False(t, trader.orderFilterTime.IsZero(), "交易规则应被缓存")
+	assert.Len(t, trader.orderFilters, 2, "一次请求缓存全部交易对")
+
+	_, err = trader.GetOrderFilter("UNKNOWNUSDT")
+	assert.Error(t, err)
+}
+
+func TestCheckOrderMinimums(t *testing.T) {
+	suite := NewBinanceFuturesTestSuite(t)
+	defer suite.Cleanup()
+	trader := suite.Trader.(*FuturesTrader)
+	trader.orderFilters = map[string]SymbolOrderFilter{"BTCUSDT": {MinNotional: 100, MinQty: 0.001}}
+	trader.orderFilterTime = time.Now()
+
+	assert.NoError(t, checkOrderMinimums(trader, "BTCUSDT", 0.01, 50000))
+
+	err := checkOrderMinimums(trader, "BTCUSDT", 0.0005, 50000)
+	assert.True(t, errors.Is(err, ErrBelowMinOrder), "数量低于 minQty: %v", err)
+
+	err = checkOrderMinimums(trader, "BTCUSDT", 0.001, 50000)
+	assert.True(t, errors.Is(err, ErrBelowMinOrder), "名义价值低于 minNotional: %v", err)
+
+	// 全局下限与交易所规则取较大值
+	SetMinOrderNotional(1000)
+	defer SetMinOrderNotional(0)
+	err = checkOrderMinimums(trader, "BTCUSDT", 0.01, 50000)
+	assert.True(t, errors.Is(err, ErrBelowMinOrder), "低于全局最小名义价值: %v", err)
+
+	// 不提供交易规则的交易器只使用全局下限
+	assert.NoError(t, checkOrderMinimums(&MockTrader{}, "BTCUSDT", 1, 2000))
+	assert.Error(t, checkOrderMinimums(&MockTrader{}, "BTCUSDT", 0.01, 2000))
+}
+
+func TestFuturesTrader_FormatPriceAndQuantity(t *testing.T) {
+	suite := NewBinanceFuturesTestSuite(t)
+	defer suite.Cleanup()
+	trader := suite.Trader.(*FuturesTrader)
+	trader.orderFilters = map[string]SymbolOrderFilter{
+		"DOGEUSDT": {TickSize: 0.00005, StepSize: 1, PricePrecision: 5, QtyPrecision: 0},
+		"BTCUSDT":  {TickSize: 0.1, StepSize: 0.001, PricePrecision: 1, QtyPrecision: 3},
+	}
+	trader.orderFilterTime = time.Now()
+
+	// 限价偏移算出的价格对齐到 tickSize 的整数倍
+	price, err := trader.FormatPrice("DOGEUSDT", 0.123456789)
+	require.NoError(t, err)
+	assert.Equal(t, "0.12345", price)
+	price, err = trader.FormatPrice("BTCUSDT", 65432.17)
+	require.NoError(t, err)
+	assert.Equal(t, "65432.2", price)
+
+	// 数量向下对齐到 stepSize，不会超出原始数量
+	qty, err := trader.FormatQuantity("DOGEUSDT", 1234.99)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", qty)
+	qty, err = trader.FormatQuantity("BTCUSDT", 0.3)
+	require.NoError(t, err)
+	assert.Equal(t, "0.300", qty, "浮点误差不应多舍掉一个步长")
+
+	// 使用缓存的交易规则，不重复请求 exchangeInfo
+	assert.Len(t, trader.orderFilters, 2)
+
+	_, err = trader.FormatPrice("UNKNOWNUSDT", 1)
+	assert.Error(t, err)
+}
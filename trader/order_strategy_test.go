@@ -981,3 +981,35 @@ func BenchmarkOrderStrategy_MarketOrder(b *testing.B) {
 		trader.OpenLong("BTCUSDT", 100.0, 10)
 	}
 }
+
+// TestOrderStrategy_PerCategoryOverride 測試按幣種類別覆蓋訂單策略
+func TestOrderStrategy_PerCategoryOverride(t *testing.T) {
+	ft := &FuturesTrader{orderStrategy: "conservative_hybrid"}
+
+	// 未設置覆蓋時使用全局策略
+	if got := ft.orderStrategyFor("BTCUSDT"); got != "conservative_hybrid" {
+		t.Errorf("未覆蓋時 BTCUSDT 應使用全局策略，實際 %s", got)
+	}
+
+	ft.SetCategoryOrderStrategies("limit_only", "market_only")
+	cases := map[string]string{
+		"BTCUSDT":  "limit_only",
+		"ETHUSDT":  "limit_only",
+		"SOLUSDT":  "market_only",
+		"DOGEUSDT": "market_only",
+	}
+	for symbol, expected := range cases {
+		if got := ft.orderStrategyFor(symbol); got != expected {
+			t.Errorf("%s 期望策略 %s，實際 %s", symbol, expected, got)
+		}
+	}
+
+	// 無效值被忽略，回退到全局策略
+	ft.SetCategoryOrderStrategies("invalid", "")
+	if got := ft.orderStrategyFor("BTCUSDT"); got != "conservative_hybrid" {
+		t.Errorf("無效覆蓋應回退到全局策略，實際 %s", got)
+	}
+	if got := ft.orderStrategyFor("SOLUSDT"); got != "conservative_hybrid" {
+		t.Errorf("空覆蓋應回退到全局策略，實際 %s", got)
+	}
+}
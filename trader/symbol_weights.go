@@ -0,0 +1,66 @@
+package trader
+
+import (
+	"log"
+
+	"nofx/decision"
+	"nofx/market"
+)
+
+// symbolWeight 返回币种的仓位权重，未配置的币种权重为1
+func symbolWeight(weights map[string]float64, symbol string) float64 {
+	if w, ok := weights[market.Normalize(symbol)]; ok {
+		return w
+	}
+	return 1
+}
+
+// isWeightedOpen 是否为参与权重分配的开仓决策
+func isWeightedOpen(d decision.Decision) bool {
+	return (d.Action == "open_long" || d.Action == "open_short") && d.PositionSizeUSD > 0
+}
+
+// applySymbolWeights 按币种权重重新分配本周期开仓决策的仓位
+// 预算为AI给出的开仓仓位总和；各开仓按 仓位×权重 占比分配预算，分配后总和仍等于预算
+// 权重为0的开仓被移出决策列表并返回给调用方记录；平仓、hold 等其他决策保持不变
+// AI 决策的仓位上限校验在加权之前，因此加权后再按单币种上限（BTC/ETH 10倍、山寨币 5倍账户净值）截断，截掉的部分不再分给其他币种
+func applySymbolWeights(decisions []decision.Decision, weights map[string]float64, accountEquity float64) (weighted, skipped []decision.Decision) {
+	if len(weights) == 0 {
+		return decisions, nil
+	}
+
+	budget, weightedTotal := 0.0, 0.0
+	for _, d := range decisions {
+		if isWeightedOpen(d) {
+			budget += d.PositionSizeUSD
+			weightedTotal += d.PositionSizeUSD * symbolWeight(weights, d.Symbol)
+		}
+	}
+	if budget <= 0 {
+		return decisions, nil
+	}
+
+	weighted = make([]decision.Decision, 0, len(decisions))
+	for _, d := range decisions {
+		if !isWeightedOpen(d) {
+			weighted = append(weighted, d)
+			continue
+		}
+		w := symbolWeight(weights, d.Symbol)
+		if w == 0 {
+			skipped = append(skipped, d)
+			continue
+		}
+		size := budget * d.PositionSizeUSD * w / weightedTotal
+		if maxSize := decision.MaxPositionValue(market.Normalize(d.Symbol), accountEquity); accountEquity > 0 && size > maxSize {
+			log.Printf("⚖️ %s 加权后仓位 %.2f USDT 超过单币种上限，截断为 %.2f USDT", d.Symbol, size, maxSize)
+			size = maxSize
+		}
+		if size != d.PositionSizeUSD {
+			log.Printf("⚖️ %s 按权重 %.2f 调整仓位: %.2f → %.2f USDT", d.Symbol, w, d.PositionSizeUSD, size)
+			d.PositionSizeUSD = size
+		}
+		weighted = append(weighted, d)
+	}
+	return weighted, skipped
+}
@@ -0,0 +1,68 @@
+package trader
+
+import (
+	"testing"
+
+	"nofx/decision"
+)
+
+func TestApplySymbolWeights(t *testing.T) {
+	decisions := []decision.Decision{
+		{Action: "close_long", Symbol: "ETHUSDT"},
+		{Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 100},
+		{Action: "open_short", Symbol: "SOLUSDT", PositionSizeUSD: 100},
+		{Action: "open_long", Symbol: "DOGEUSDT", PositionSizeUSD: 100},
+		{Action: "hold", Symbol: "BNBUSDT"},
+	}
+
+	kept, skipped := applySymbolWeights(decisions, nil, 1000)
+	if len(kept) != len(decisions) || skipped != nil {
+		t.Fatalf("未配置权重时应原样返回")
+	}
+
+	// BTC 权重3，SOL 未配置（权重1），DOGE 权重0
+	kept, skipped = applySymbolWeights(decisions, map[string]float64{"BTCUSDT": 3, "DOGEUSDT": 0}, 1000)
+	if len(skipped) != 1 || skipped[0].Symbol != "DOGEUSDT" {
+		t.Fatalf("权重为0的开仓应被跳过: %+v", skipped)
+	}
+	if len(kept) != 4 {
+		t.Fatalf("保留决策数 = %d, want 4", len(kept))
+	}
+
+	sizes := map[string]float64{}
+	total := 0.0
+	for _, d := range kept {
+		sizes[d.Symbol] = d.PositionSizeUSD
+		total += d.PositionSizeUSD
+	}
+	if total != 300 {
+		t.Errorf("分配后仓位总和 = %.2f, want 300（等于原预算）", total)
+	}
+	if sizes["BTCUSDT"] != 225 || sizes["SOLUSDT"] != 75 {
+		t.Errorf("按权重分配错误: BTC=%.2f SOL=%.2f, want 225/75", sizes["BTCUSDT"], sizes["SOLUSDT"])
+	}
+	if decisions[1].PositionSizeUSD != 100 {
+		t.Errorf("不应修改传入的决策")
+	}
+}
+
+func TestApplySymbolWeights_CapsAtMaxPositionValue(t *testing.T) {
+	// 净值 100：山寨币上限 500 USDT，AI 给出的 400 USDT 已通过校验
+	decisions := []decision.Decision{
+		{Action: "open_long", Symbol: "SOLUSDT", PositionSizeUSD: 400},
+		{Action: "open_long", Symbol: "DOGEUSDT", PositionSizeUSD: 400},
+	}
+
+	kept, _ := applySymbolWeights(decisions, map[string]float64{"SOLUSDT": 4}, 100)
+	sizes := map[string]float64{}
+	for _, d := range kept {
+		sizes[d.Symbol] = d.PositionSizeUSD
+	}
+	// 加权后 SOL 分到 640 USDT，超过 5 倍净值上限，截断为 500
+	if sizes["SOLUSDT"] != 500 {
+		t.Errorf("SOL = %.2f, want 500（截断到单币种上限）", sizes["SOLUSDT"])
+	}
+	if sizes["DOGEUSDT"] != 160 {
+		t.Errorf("DOGE = %.2f, want 160", sizes["DOGEUSDT"])
+	}
+}
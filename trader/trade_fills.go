@@ -0,0 +1,192 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// TradeFill 交易所成交明细（Binance userTrades）
+type TradeFill struct {
+	OrderID      int64
+	Symbol       string
+	Side         string // BUY / SELL
+	PositionSide string // LONG / SHORT / BOTH
+	Price        float64
+	Quantity     float64
+	RealizedPnL  float64 // 交易所计算的已实现盈亏（未扣手续费）
+	Commission   float64
+	// CommissionAsset 手续费币种（如 USDT；开启 BNB 抵扣时为 BNB）
+	CommissionAsset string
+	Maker           bool
+	Time            time.Time
+}
+
+// FillReporter 可查询成交明细的交易器（可选接口）
+type FillReporter interface {
+	GetFills(symbol string, since time.Time) ([]TradeFill, error)
+}
+
+// fillSummary 一组成交的合计（均价按成交量加权）
+// 全部成交都是挂单成交时 Maker 为 true；手续费都以计价币种收取时 FeeKnown 为 true，Fee 为实际手续费合计
+type fillSummary struct {
+	OrderID     int64
+	Quantity    float64
+	Price       float64
+	RealizedPnL float64
+	Fee         float64
+	FeeKnown    bool
+	Maker       bool
+	Time        time.Time
+}
+
+func summarizeFills(fills []TradeFill) (fillSummary, bool) {
+	summary := fillSummary{FeeKnown: true, Maker: true}
+	var notional float64
+	for _, fill := range fills {
+		summary.Quantity += fill.Quantity
+		notional += fill.Price * fill.Quantity
+		summary.RealizedPnL += fill.RealizedPnL
+		summary.Fee += fill.Commission
+		if fill.CommissionAsset == "" || !strings.HasSuffix(fill.Symbol, fill.CommissionAsset) {
+			summary.FeeKnown = false
+		}
+		summary.Maker = summary.Maker && fill.Maker
+		if !fill.Time.Before(summary.Time) {
+			summary.Time = fill.Time
+			summary.OrderID = fill.OrderID
+		}
+	}
+	if summary.Quantity <= 0 {
+		return fillSummary{}, false
+	}
+	summary.Price = notional / summary.Quantity
+	return summary, true
+}
+
+// closingFills 挑出平掉 side 方向持仓的成交：多仓由 SELL 平仓，空仓由 BUY 平仓
+// 双向持仓模式要求 positionSide 一致；单向持仓模式（BOTH）只统计有已实现盈亏的成交，排除反手开仓部分
+func closingFills(fills []TradeFill, side string) []TradeFill {
+	closeSide := "SELL"
+	if side == "short" {
+		closeSide = "BUY"
+	}
+	var result []TradeFill
+	for _, fill := range fills {
+		if strings.ToUpper(fill.Side) != closeSide {
+			continue
+		}
+		switch strings.ToUpper(fill.PositionSide) {
+		case strings.ToUpper(side):
+		case "BOTH", "":
+			if fill.RealizedPnL == 0 {
+				continue
+			}
+		default:
+			continue
+		}
+		result = append(result, fill)
+	}
+	return result
+}
+
+// liquidity 成交合计对应的成交方式（部分吃单即按 Taker 记录）
+func (s fillSummary) liquidity() string {
+	if s.Maker {
+		return LiquidityMaker
+	}
+	return LiquidityTaker
+}
+
+// fee 实际手续费；手续费以其他币种（如 BNB）收取时按成交方式对应的费率估算
+func (s fillSummary) fee(takerFeeRate, makerFeeRate float64) float64 {
+	if s.FeeKnown {
+		return s.Fee
+	}
+	rate := takerFeeRate
+	if s.Maker {
+		rate = makerFeeRate
+	}
+	return s.Quantity * s.Price * rate
+}
+
+// orderFills 查询某个订单的成交合计，交易器不支持或尚未查到成交时返回 false
+func (at *AutoTrader) orderFills(symbol string, orderID int64, placedAt time.Time) (fillSummary, bool) {
+	reporter, ok := at.trader.(FillReporter)
+	if !ok || orderID == 0 {
+		return fillSummary{}, false
+	}
+	fills, err := reporter.GetFills(symbol, placedAt.Add(-time.Minute))
+	if err != nil {
+		log.Printf("⚠️ [%s] 查询 %s 订单 %d 成交明细失败，按估算记录: %v", at.name, symbol, orderID, err)
+		return fillSummary{}, false
+	}
+	var orderFills []TradeFill
+	for _, fill := range fills {
+		if fill.OrderID == orderID {
+			orderFills = append(orderFills, fill)
+		}
+	}
+	return summarizeFills(orderFills)
+}
+
+// recordPassiveClose 将被动平仓（止损/止盈/强平/移动止损/OCO 触发）写入成交记录，返回已实现盈亏
+// 交易器支持成交明细时按上一次持仓快照之后的平仓成交记录数量、均价和已实现盈亏，并回填到 action；
+// 否则按推断的平仓价估算
+func (at *AutoTrader) recordPassiveClose(closed decision.PositionInfo, action *logger.DecisionAction) float64 {
+	trade := &config.TradeRecord{
+		TraderID:  at.id,
+		UserID:    at.userID,
+		Symbol:    closed.Symbol,
+		Side:      closed.Side,
+		Action:    "close",
+		Quantity:  closed.Quantity,
+		Price:     action.Price,
+		Liquidity: LiquidityTaker,
+		CreatedAt: action.Timestamp,
+	}
+	if closed.Side == "short" {
+		trade.RealizedPnL = (closed.EntryPrice - trade.Price) * trade.Quantity
+	} else {
+		trade.RealizedPnL = (trade.Price - closed.EntryPrice) * trade.Quantity
+	}
+
+	filled := false
+	if reporter, ok := at.trader.(FillReporter); ok {
+		since := at.lastPositionsAt
+		if since.IsZero() {
+			since = time.Now().Add(-time.Hour)
+		}
+		fills, err := reporter.GetFills(closed.Symbol, since)
+		if err != nil {
+			log.Printf("⚠️ [%s] 查询 %s 被动平仓成交明细失败，按推断价格记录: %v", at.name, closed.Symbol, err)
+		} else if summary, ok := summarizeFills(closingFills(fills, closed.Side)); ok {
+			trade.Quantity = summary.Quantity
+			trade.Price = summary.Price
+			trade.RealizedPnL = summary.RealizedPnL
+			trade.OrderID = fmt.Sprintf("%d", summary.OrderID)
+			trade.CreatedAt = summary.Time
+			trade.Liquidity = summary.liquidity()
+			trade.Fee = summary.fee(at.config.TakerFeeRate, at.config.MakerFeeRate)
+			filled = true
+			action.Quantity = summary.Quantity
+			action.Price = summary.Price
+			action.OrderID = summary.OrderID
+		}
+	}
+	if !filled {
+		trade.Fee = trade.Quantity * trade.Price * at.config.TakerFeeRate
+	}
+
+	if recorder, ok := at.database.(tradeRecorder); ok {
+		if err := recorder.RecordTrade(trade); err != nil {
+			log.Printf("⚠️ [%s] 记录被动平仓成交失败: %v", at.name, err)
+		}
+	}
+	return trade.RealizedPnL
+}
@@ -0,0 +1,228 @@
+package trader
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// fillReportingTrader 返回固定成交明细的交易器
+type fillReportingTrader struct {
+	MockTrader
+	fills []TradeFill
+	since time.Time
+}
+
+func (t *fillReportingTrader) GetFills(symbol string, since time.Time) ([]TradeFill, error) {
+	t.since = since
+	return t.fills, nil
+}
+
+func TestClosingFills(t *testing.T) {
+	fills := []TradeFill{
+		{OrderID: 1, Side: "SELL", PositionSide: "LONG", Quantity: 1},
+		{OrderID: 2, Side: "BUY", PositionSide: "SHORT", Quantity: 1},
+		{OrderID: 3, Side: "SELL", PositionSide: "SHORT", Quantity: 1},                 // 开空，不是平多
+		{OrderID: 4, Side: "SELL", PositionSide: "BOTH", Quantity: 1, RealizedPnL: -2}, // 单向模式平多
+		{OrderID: 5, Side: "SELL", PositionSide: "BOTH", Quantity: 1},                  // 单向模式反手开空
+	}
+	got := closingFills(fills, "long")
+	if len(got) != 2 || got[0].OrderID != 1 || got[1].OrderID != 4 {
+		t.Fatalf("unexpected long closing fills: %+v", got)
+	}
+	got = closingFills(fills, "short")
+	if len(got) != 1 || got[0].OrderID != 2 {
+		t.Fatalf("unexpected short closing fills: %+v", got)
+	}
+}
+
+func TestRecordPassiveClose_UsesExchangeFills(t *testing.T) {
+	snapshotAt := time.Now().Add(-3 * time.Minute)
+	filledAt := time.Now().Add(-time.Minute)
+	mock := &fillReportingTrader{fills: []TradeFill{
+		{OrderID: 7, Symbol: "BTCUSDT", Side: "SELL", PositionSide: "LONG", Price: 94, Quantity: 0.5, RealizedPnL: -3, Time: filledAt},
+		{OrderID: 7, Symbol: "BTCUSDT", Side: "SELL", PositionSide: "LONG", Price: 96, Quantity: 0.5, RealizedPnL: -2, Time: filledAt},
+	}}
+	recorder := &fakeTradeRecorder{}
+	at := &AutoTrader{
+		id:              "trader-1",
+		userID:          "user-1",
+		name:            "test",
+		trader:          mock,
+		config:          AutoTraderConfig{TakerFeeRate: 0.001},
+		database:        recorder,
+		lastPositionsAt: snapshotAt,
+	}
+	closed := decision.PositionInfo{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, Quantity: 1, MarkPrice: 97}
+	action := &logger.DecisionAction{Action: "auto_close_long", Symbol: "BTCUSDT", Quantity: 1, Price: 95.5, Error: "stop_loss"}
+
+	pnl := at.recordPassiveClose(closed, action)
+
+	if !mock.since.Equal(snapshotAt) {
+		t.Fatalf("fills should be queried from the snapshot time, got %v", mock.since)
+	}
+	if math.Abs(pnl-(-5)) > 1e-9 {
+		t.Fatalf("pnl = %v, want -5 from exchange fills", pnl)
+	}
+	if len(recorder.trades) != 1 {
+		t.Fatalf("expected 1 trade, got %d", len(recorder.trades))
+	}
+	tr := recorder.trades[0]
+	if tr.Action != "close" || tr.Side != "long" || tr.Quantity != 1 || math.Abs(tr.Price-95) > 1e-9 ||
+		math.Abs(tr.RealizedPnL-(-5)) > 1e-9 || tr.OrderID != "7" || !tr.CreatedAt.Equal(filledAt) {
+		t.Errorf("unexpected passive close trade: %+v", tr)
+	}
+	if action.Price != 95 || action.OrderID != 7 {
+		t.Errorf("action should be backfilled with the actual fill: %+v", action)
+	}
+}
+
+func TestRecordPassiveClose_FallsBackToInferredPrice(t *testing.T) {
+	recorder := &fakeTradeRecorder{}
+	at := &AutoTrader{
+		id:       "trader-1",
+		userID:   "user-1",
+		name:     "test",
+		trader:   &MockTrader{},
+		database: recorder,
+	}
+	closed := decision.PositionInfo{Symbol: "ETHUSDT", Side: "short", EntryPrice: 50, Quantity: 2}
+	action := &logger.DecisionAction{Action: "auto_close_short", Symbol: "ETHUSDT", Quantity: 2, Price: 55, Error: "stop_loss"}
+
+	if pnl := at.recordPassiveClose(closed, action); math.Abs(pnl-(-10)) > 1e-9 {
+		t.Fatalf("pnl = %v, want -10", pnl)
+	}
+	if len(recorder.trades) != 1 || recorder.trades[0].Price != 55 || recorder.trades[0].Side != "short" {
+		t.Fatalf("unexpected trades: %+v", recorder.trades)
+	}
+}
+
+func TestRecordTrade_CloseUsesOrderFills(t *testing.T) {
+	placedAt := time.Now()
+	recorder := &fakeTradeRecorder{}
+	at := &AutoTrader{
+		id:     "trader-1",
+		userID: "user-1",
+		name:   "test",
+		trader: &fillReportingTrader{fills: []TradeFill{
+			{OrderID: 41, Side: "SELL", PositionSide: "LONG", Price: 120, Quantity: 2, RealizedPnL: 40},
+			{OrderID: 42, Side: "SELL", PositionSide: "LONG", Price: 108, Quantity: 2, RealizedPnL: 16, Time: placedAt},
+		}},
+		config:   AutoTraderConfig{TakerFeeRate: 0.001},
+		database: recorder,
+	}
+	positions := []decision.PositionInfo{{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, Quantity: 2}}
+
+	// 执行前价格 110，实际成交 108
+	at.recordTrade(&logger.DecisionAction{Action: "close_long", Symbol: "BTCUSDT", Price: 110, OrderID: 42, Timestamp: placedAt}, positions)
+
+	if len(recorder.trades) != 1 {
+		t.Fatalf("expected 1 trade, got %d", len(recorder.trades))
+	}
+	if tr := recorder.trades[0]; tr.Price != 108 || math.Abs(tr.RealizedPnL-16) > 1e-9 {
+		t.Errorf("close should be recorded from the actual fill: %+v", tr)
+	}
+}
+
+func TestRecordTrade_UsesFillLiquidityAndCommission(t *testing.T) {
+	placedAt := time.Now()
+	recorder := &fakeTradeRecorder{}
+	at := &AutoTrader{
+		id:     "trader-1",
+		userID: "user-1",
+		name:   "test",
+		trader: &fillReportingTrader{fills: []TradeFill{
+			{OrderID: 51, Symbol: "BTCUSDT", Side: "BUY", PositionSide: "LONG", Price: 100, Quantity: 1, Commission: 0.02, CommissionAsset: "USDT", Maker: true},
+			{OrderID: 51, Symbol: "BTCUSDT", Side: "BUY", PositionSide: "LONG", Price: 102, Quantity: 1, Commission: 0.0408, CommissionAsset: "USDT", Time: placedAt},
+		}},
+		config:   AutoTraderConfig{TakerFeeRate: 0.0004, MakerFeeRate: 0.0002},
+		database: recorder,
+	}
+
+	// 限价单部分挂单成交、部分吃单成交：按 Taker 记录，手续费取交易所实际收取的金额
+	at.recordTrade(&logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT", Price: 100, Quantity: 2, OrderID: 51, Liquidity: LiquidityMaker, Timestamp: placedAt}, nil)
+
+	if len(recorder.trades) != 1 {
+		t.Fatalf("expected 1 trade, got %d", len(recorder.trades))
+	}
+	tr := recorder.trades[0]
+	if tr.Liquidity != LiquidityTaker {
+		t.Errorf("partially taker fill should be recorded as taker, got %q", tr.Liquidity)
+	}
+	if math.Abs(tr.Fee-0.0608) > 1e-9 || math.Abs(tr.Price-101) > 1e-9 {
+		t.Errorf("expected actual commission 0.0608 at avg price 101, got fee %v price %v", tr.Fee, tr.Price)
+	}
+}
+
+func TestSummarizeFills_EstimatesFeeWhenPaidInOtherAsset(t *testing.T) {
+	summary, ok := summarizeFills([]TradeFill{
+		{OrderID: 1, Symbol: "BTCUSDT", Price: 100, Quantity: 1, Commission: 0.00005, CommissionAsset: "BNB", Maker: true},
+	})
+	if !ok {
+		t.Fatal("expected a summary")
+	}
+	if summary.liquidity() != LiquidityMaker {
+		t.Errorf("all-maker fills should be recorded as maker, got %q", summary.liquidity())
+	}
+	if fee := summary.fee(0.0004, 0.0002); math.Abs(fee-0.02) > 1e-9 {
+		t.Errorf("BNB commission should fall back to the maker rate estimate, got %v", fee)
+	}
+}
+
+// passiveCloseStore 记录成交并按记录的平仓计算连续亏损（模拟 config.Database）
+type passiveCloseStore struct {
+	fakeTradeRecorder
+}
+
+func (s *passiveCloseStore) GetLossStreak(traderID string, since time.Time) (int, time.Time, error) {
+	streak := 0
+	var lastLossAt time.Time
+	for i := len(s.trades) - 1; i >= 0; i-- {
+		trade := s.trades[i]
+		if trade.Action != "close" || !trade.CreatedAt.After(since) {
+			continue
+		}
+		if trade.RealizedPnL >= 0 {
+			break
+		}
+		if streak == 0 {
+			lastLossAt = trade.CreatedAt
+		}
+		streak++
+	}
+	return streak, lastLossAt, nil
+}
+
+func TestRecordPassiveClose_CountsTowardLossStreak(t *testing.T) {
+	now := time.Now()
+	store := &passiveCloseStore{}
+	store.trades = []*config.TradeRecord{
+		{Symbol: "ETHUSDT", Side: "long", Action: "close", RealizedPnL: -4, CreatedAt: now.Add(-10 * time.Minute)},
+	}
+	at := &AutoTrader{
+		id:       "trader-1",
+		name:     "test",
+		trader:   &MockTrader{},
+		config:   AutoTraderConfig{TakerFeeRate: 0.0004, LossStreakThreshold: 2, LossCooldown: 30 * time.Minute},
+		database: store,
+	}
+	if _, cooling := at.checkLossCooldown(now); cooling {
+		t.Fatal("one loss should not trigger the cooldown")
+	}
+
+	// 止损单在交易所成交，由持仓对比检测到被动平仓
+	closed := decision.PositionInfo{Symbol: "BTCUSDT", Side: "long", EntryPrice: 100, Quantity: 1}
+	action := &logger.DecisionAction{Action: "auto_close_long", Symbol: "BTCUSDT", Quantity: 1, Price: 95, Error: "stop_loss", Timestamp: now.Add(-time.Minute)}
+	at.recordPassiveClose(closed, action)
+
+	if _, cooling := at.checkLossCooldown(now); !cooling {
+		t.Fatal("a stop-loss close should count toward the loss streak")
+	}
+	if want := now.Add(29 * time.Minute); !at.lossCooldownUntil.Equal(want) {
+		t.Errorf("lossCooldownUntil = %v, want %v", at.lossCooldownUntil, want)
+	}
+}
@@ -0,0 +1,124 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"nofx/config"
+)
+
+// 交易员日志级别
+const (
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+const (
+	// traderLogBufferSize 每个交易员内存中保留的日志行数
+	traderLogBufferSize = 200
+	// maxTraderLogLineBytes 单行日志最大字节数，超出部分截断（与行数上限一起严格限制内存占用）
+	maxTraderLogLineBytes = 512
+)
+
+// TraderLogLine 交易员的一行周期日志
+type TraderLogLine struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// traderLogBuffer 固定容量的日志环形缓冲区，零值可直接使用
+type traderLogBuffer struct {
+	mu    sync.Mutex
+	lines []TraderLogLine
+	next  int // 下一次写入位置（缓冲区写满后循环覆盖最旧的行）
+}
+
+// add 追加一行日志，写满后覆盖最旧的行
+func (b *traderLogBuffer) add(line TraderLogLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lines) < traderLogBufferSize {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % traderLogBufferSize
+}
+
+// tail 返回最近 n 行日志（按时间正序），n <= 0 时返回全部
+func (b *traderLogBuffer) tail(n int) []TraderLogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total := len(b.lines)
+	if n <= 0 || n > total {
+		n = total
+	}
+	result := make([]TraderLogLine, 0, n)
+	for i := total - n; i < total; i++ {
+		result = append(result, b.lines[(b.next+i)%total])
+	}
+	return result
+}
+
+// truncateLogLine 按字节上限截断日志行，不截断多字节字符
+func truncateLogLine(s string) string {
+	if len(s) <= maxTraderLogLineBytes {
+		return s
+	}
+	cut := maxTraderLogLineBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// traderLogStore 交易员日志持久化（由 config.Database 实现）
+type traderLogStore interface {
+	RecordTraderLog(traderID, level, message string) error
+	GetTraderLogs(traderID string, n int) ([]config.TraderLog, error)
+}
+
+// logf 输出日志到标准输出，同时写入交易员的内存日志缓冲区
+// 警告和错误级别的日志会持久化到数据库，重启后仍可查看
+func (at *AutoTrader) logf(level, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Print(message)
+
+	message = truncateLogLine(message)
+	at.logTail.add(TraderLogLine{Time: time.Now(), Level: level, Message: message})
+
+	if level == LogLevelInfo {
+		return
+	}
+	if store, ok := at.database.(traderLogStore); ok {
+		if err := store.RecordTraderLog(at.id, level, message); err != nil {
+			log.Printf("⚠️ [%s] 持久化日志失败: %v", at.name, err)
+		}
+	}
+}
+
+// restorePersistedLogs 用数据库中持久化的日志预填充内存缓冲区（用于重启后查看最近的警告/错误）
+func (at *AutoTrader) restorePersistedLogs() {
+	store, ok := at.database.(traderLogStore)
+	if !ok {
+		return
+	}
+	logs, err := store.GetTraderLogs(at.id, traderLogBufferSize)
+	if err != nil {
+		log.Printf("⚠️ [%s] 加载历史日志失败: %v", at.name, err)
+		return
+	}
+	for _, l := range logs {
+		at.logTail.add(TraderLogLine{Time: l.CreatedAt, Level: l.Level, Message: l.Message})
+	}
+}
+
+// GetLogs 获取交易员最近 n 行周期日志（按时间正序），n <= 0 时返回缓冲区内全部日志
+func (at *AutoTrader) GetLogs(n int) []TraderLogLine {
+	return at.logTail.tail(n)
+}
@@ -0,0 +1,86 @@
+package trader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"nofx/config"
+)
+
+func TestTraderLogBuffer_Wraparound(t *testing.T) {
+	var b traderLogBuffer
+	if got := b.tail(10); len(got) != 0 {
+		t.Fatalf("empty buffer should return no lines, got %d", len(got))
+	}
+
+	for i := 0; i < traderLogBufferSize+5; i++ {
+		b.add(TraderLogLine{Level: LogLevelInfo, Message: fmt.Sprintf("line-%d", i)})
+	}
+	if len(b.lines) != traderLogBufferSize {
+		t.Fatalf("buffer should be bounded to %d lines, got %d", traderLogBufferSize, len(b.lines))
+	}
+
+	all := b.tail(0)
+	if len(all) != traderLogBufferSize || all[0].Message != "line-5" || all[len(all)-1].Message != fmt.Sprintf("line-%d", traderLogBufferSize+4) {
+		t.Fatalf("unexpected buffer order: first=%s last=%s", all[0].Message, all[len(all)-1].Message)
+	}
+
+	last := b.tail(3)
+	want := []string{fmt.Sprintf("line-%d", traderLogBufferSize+2), fmt.Sprintf("line-%d", traderLogBufferSize+3), fmt.Sprintf("line-%d", traderLogBufferSize+4)}
+	for i := range want {
+		if last[i].Message != want[i] {
+			t.Fatalf("tail(3)[%d] = %s, want %s", i, last[i].Message, want[i])
+		}
+	}
+}
+
+func TestTruncateLogLine(t *testing.T) {
+	if got := truncateLogLine("short"); got != "short" {
+		t.Fatalf("short line should be unchanged, got %q", got)
+	}
+	long := strings.Repeat("中", maxTraderLogLineBytes)
+	got := truncateLogLine(long)
+	if len(got) > maxTraderLogLineBytes+len("…") || !utf8.ValidString(got) {
+		t.Fatalf("truncated line should be bounded and valid UTF-8, got %d bytes", len(got))
+	}
+}
+
+// fakeTraderLogStore 内存中的日志持久化
+type fakeTraderLogStore struct {
+	logs []config.TraderLog
+}
+
+func (f *fakeTraderLogStore) RecordTraderLog(traderID, level, message string) error {
+	f.logs = append(f.logs, config.TraderLog{TraderID: traderID, Level: level, Message: message, CreatedAt: time.Now()})
+	return nil
+}
+
+func (f *fakeTraderLogStore) GetTraderLogs(traderID string, n int) ([]config.TraderLog, error) {
+	return f.logs, nil
+}
+
+func TestAutoTraderLogf_PersistsImportantLines(t *testing.T) {
+	store := &fakeTraderLogStore{}
+	at := &AutoTrader{id: "tr-logs", name: "logs", database: store}
+
+	at.logf(LogLevelInfo, "📊 账户净值: %.2f", 1000.0)
+	at.logf(LogLevelError, "❌ 执行决策失败 (%s)", "BTCUSDT")
+
+	lines := at.GetLogs(10)
+	if len(lines) != 2 || lines[0].Message != "📊 账户净值: 1000.00" || lines[1].Level != LogLevelError {
+		t.Fatalf("unexpected log tail: %+v", lines)
+	}
+	if len(store.logs) != 1 || store.logs[0].Message != "❌ 执行决策失败 (BTCUSDT)" {
+		t.Fatalf("only warn/error lines should be persisted, got %+v", store.logs)
+	}
+
+	// 重启后从持久化日志恢复
+	restarted := &AutoTrader{id: "tr-logs", name: "logs", database: store}
+	restarted.restorePersistedLogs()
+	if got := restarted.GetLogs(10); len(got) != 1 || got[0].Level != LogLevelError {
+		t.Fatalf("persisted lines should be restored, got %+v", got)
+	}
+}
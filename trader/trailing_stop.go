@@ -0,0 +1,172 @@
+package trader
+
+import (
+	"log"
+	"math"
+	"strings"
+
+	"nofx/decision"
+)
+
+// trailingStopPrice 计算移动止损价（按价格百分比，不含杠杆）
+// 价格相对开仓价的浮盈未达到 activationPct 时返回 false；达到后多单止损为 mark*(1-trail%)，空单为 mark*(1+trail%)
+func trailingStopPrice(side string, entryPrice, markPrice, trailPct, activationPct float64) (float64, bool) {
+	if trailPct <= 0 || entryPrice <= 0 || markPrice <= 0 {
+		return 0, false
+	}
+	switch side {
+	case "long":
+		if (markPrice-entryPrice)/entryPrice*100 < activationPct {
+			return 0, false
+		}
+		return markPrice * (1 - trailPct/100), true
+	case "short":
+		if (entryPrice-markPrice)/entryPrice*100 < activationPct {
+			return 0, false
+		}
+		return markPrice * (1 + trailPct/100), true
+	default:
+		return 0, false
+	}
+}
+
+// orderCanceller 可按订单 ID 撤单的交易器（可选接口）
+type orderCanceller interface {
+	CancelOrder(symbol string, orderID int64) error
+}
+
+// stopOrderCanceller 返回可按订单 ID 撤销止损单的交易器
+// 统一账户的止损单是 /papi 条件单（ID 为 strategyId），CancelOrder 只能撤普通挂单，因此排除在外
+func stopOrderCanceller(t Trader) (orderCanceller, bool) {
+	if _, ok := t.(*PortfolioMarginTrader); ok {
+		return nil, false
+	}
+	canceller, ok := t.(orderCanceller)
+	return canceller, ok
+}
+
+// exitOrderPositionSide 返回止盈止损单保护的持仓方向（long/short）
+// 单向持仓模式下 positionSide 为 BOTH，按下单方向推断：SELL 平多仓，BUY 平空仓
+func exitOrderPositionSide(order decision.OpenOrderInfo) string {
+	switch strings.ToUpper(order.PositionSide) {
+	case "LONG":
+		return "long"
+	case "SHORT":
+		return "short"
+	}
+	if strings.ToUpper(order.Side) == "BUY" {
+		return "short"
+	}
+	return "long"
+}
+
+// splitStopLossOrders 从挂单中挑出止损单，按是否属于 side 方向拆分
+func splitStopLossOrders(orders []decision.OpenOrderInfo, side string) (own, others []decision.OpenOrderInfo) {
+	for _, order := range orders {
+		typ := strings.ToUpper(order.Type)
+		if (typ != "STOP_MARKET" && typ != "STOP") || order.StopPrice <= 0 {
+			continue
+		}
+		if exitOrderPositionSide(order) == side {
+			own = append(own, order)
+		} else {
+			others = append(others, order)
+		}
+	}
+	return own, others
+}
+
+// tighterStop 返回两个止损价中对持仓更有利（更紧）的一个，0 表示未知
+func tighterStop(side string, a, b float64) float64 {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	if side == "short" {
+		return math.Min(a, b)
+	}
+	return math.Max(a, b)
+}
+
+// applyTrailingStop 按当前标记价格挂出或上移移动止损
+// 止损只会朝有利方向移动（多单只升不降、空单只降不升），因此每次按标记价计算即可跟随持仓期间的最优价
+// 比较基准取交易所当前止损单、AI 设置的止损和本地记录中最紧的一个：首次激活或重启后也不会放宽 AI 的止损
+// 只撤销该方向的止损单；新止损挂单失败时按原价格重新挂回旧止损，避免持仓失去保护
+func (at *AutoTrader) applyTrailingStop(symbol, side string, entryPrice, markPrice, quantity float64) {
+	stopPrice, ok := trailingStopPrice(side, entryPrice, markPrice, at.config.TrailingStopPercent, at.config.TrailingActivation)
+	if !ok || quantity <= 0 {
+		return
+	}
+
+	posKey := symbol + "_" + side
+	at.trailingStopsMutex.Lock()
+	defer at.trailingStopsMutex.Unlock()
+	if at.trailingStops == nil {
+		at.trailingStops = make(map[string]float64)
+	}
+
+	orders, err := at.trader.GetOpenOrders(symbol)
+	if err != nil {
+		log.Printf("❌ 移动止损：查询当前止损单失败 (%s %s): %v", symbol, side, err)
+		return
+	}
+	own, others := splitStopLossOrders(orders, side)
+
+	current := tighterStop(side, at.trailingStops[posKey], at.positionStopLoss[posKey])
+	for _, order := range own {
+		current = tighterStop(side, current, order.StopPrice)
+	}
+	if current > 0 && ((side == "long" && stopPrice <= current) || (side == "short" && stopPrice >= current)) {
+		return
+	}
+
+	// 与 update_stop_loss 一致：必须先取消旧止损单，避免重复挂单（Binance 同方向不允许两张 closePosition 止损）
+	if canceller, ok := stopOrderCanceller(at.trader); ok && len(own) > 0 {
+		for _, order := range own {
+			if err := canceller.CancelOrder(symbol, order.OrderID); err != nil {
+				log.Printf("❌ 移动止损：取消旧止损单 %d 失败 (%s %s): %v", order.OrderID, symbol, side, err)
+				return
+			}
+		}
+	} else {
+		// 交易器只能按币种撤销全部止损单：撤销后把另一方向的止损按原价格挂回
+		if err := at.trader.CancelStopLossOrders(symbol); err != nil {
+			log.Printf("❌ 移动止损：取消旧止损单失败 (%s %s): %v", symbol, side, err)
+			return
+		}
+		for _, order := range others {
+			otherSide := strings.ToUpper(exitOrderPositionSide(order))
+			if err := at.trader.SetStopLoss(symbol, otherSide, order.Quantity, order.StopPrice); err != nil {
+				log.Printf("❌ 移动止损：恢复 %s %s 止损 @ %.4f 失败: %v", symbol, otherSide, order.StopPrice, err)
+			}
+		}
+	}
+
+	if err := at.trader.SetStopLoss(symbol, strings.ToUpper(side), quantity, stopPrice); err != nil {
+		log.Printf("❌ 移动止损：设置止损失败 (%s %s @ %.4f): %v", symbol, side, stopPrice, err)
+		if current > 0 {
+			if err := at.trader.SetStopLoss(symbol, strings.ToUpper(side), quantity, current); err != nil {
+				log.Printf("❌ 移动止损：恢复旧止损 @ %.4f 失败，%s %s 当前没有止损: %v", current, symbol, side, err)
+			} else {
+				log.Printf("  ↩ 移动止损：已恢复旧止损 %s %s @ %.4f", symbol, side, current)
+			}
+		}
+		return
+	}
+	at.trailingStops[posKey] = stopPrice
+	log.Printf("📈 移动止损已更新: %s %s → %.4f (标记价 %.4f, 开仓价 %.4f, 回撤 %.2f%%)",
+		symbol, side, stopPrice, markPrice, entryPrice, at.config.TrailingStopPercent)
+}
+
+// pruneTrailingStops 清理已平仓持仓的移动止损记录，避免重新开仓后沿用旧止损价
+func (at *AutoTrader) pruneTrailingStops(activeKeys map[string]bool) {
+	at.trailingStopsMutex.Lock()
+	defer at.trailingStopsMutex.Unlock()
+	for key := range at.trailingStops {
+		if !activeKeys[key] {
+			delete(at.trailingStops, key)
+		}
+	}
+}
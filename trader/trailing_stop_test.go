@@ -0,0 +1,200 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"nofx/decision"
+)
+
+// stopRecordingTrader 记录 SetStopLoss 调用
+type stopRecordingTrader struct {
+	MockTrader
+	stops []float64
+}
+
+func (t *stopRecordingTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	t.stops = append(t.stops, stopPrice)
+	return nil
+}
+
+func TestTrailingStopPrice(t *testing.T) {
+	if _, ok := trailingStopPrice("long", 100, 101, 2, 3); ok {
+		t.Fatal("long should not activate below threshold")
+	}
+	if stop, ok := trailingStopPrice("long", 100, 110, 2, 3); !ok || math.Abs(stop-107.8) > 1e-9 {
+		t.Fatalf("long stop = %v, %v", stop, ok)
+	}
+	if stop, ok := trailingStopPrice("short", 100, 90, 2, 3); !ok || math.Abs(stop-91.8) > 1e-9 {
+		t.Fatalf("short stop = %v, %v", stop, ok)
+	}
+	if _, ok := trailingStopPrice("long", 100, 110, 0, 3); ok {
+		t.Fatal("trailing disabled when percent is 0")
+	}
+}
+
+func TestApplyTrailingStopOnlyTightens(t *testing.T) {
+	mock := &stopRecordingTrader{}
+	at := &AutoTrader{
+		trader: mock,
+		config: AutoTraderConfig{TrailingStopPercent: 2, TrailingActivation: 3},
+	}
+
+	at.applyTrailingStop("BTCUSDT", "long", 100, 102, 1) // 未激活
+	at.applyTrailingStop("BTCUSDT", "long", 100, 110, 1) // 激活，挂 107.8
+	at.applyTrailingStop("BTCUSDT", "long", 100, 108, 1) // 价格回落，不下移
+	at.applyTrailingStop("BTCUSDT", "long", 100, 115, 1) // 新高，上移
+	if len(mock.stops) != 2 || mock.stops[1] <= mock.stops[0] {
+		t.Fatalf("expected two increasing stops, got %v", mock.stops)
+	}
+
+	// 平仓后清理记录，重新开仓从头计算
+	at.pruneTrailingStops(map[string]bool{})
+	at.applyTrailingStop("BTCUSDT", "long", 100, 110, 1)
+	if len(mock.stops) != 3 {
+		t.Fatalf("expected stop to be placed again after prune, got %v", mock.stops)
+	}
+}
+
+// trailingOrdersTrader 带挂单查询与按 ID 撤单的记录交易器
+type trailingOrdersTrader struct {
+	MockTrader
+	orders     []decision.OpenOrderInfo
+	stops      []string
+	cancelled  []int64
+	bulkCancel int
+	failStop   float64
+}
+
+func (t *trailingOrdersTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	return t.orders, nil
+}
+
+func (t *trailingOrdersTrader) CancelOrder(symbol string, orderID int64) error {
+	t.cancelled = append(t.cancelled, orderID)
+	return nil
+}
+
+func (t *trailingOrdersTrader) CancelStopLossOrders(symbol string) error {
+	t.bulkCancel++
+	return nil
+}
+
+func (t *trailingOrdersTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if t.failStop > 0 && math.Abs(stopPrice-t.failStop) < 1e-9 {
+		return errors.New("rejected")
+	}
+	t.stops = append(t.stops, fmt.Sprintf("%s@%.1f", positionSide, stopPrice))
+	return nil
+}
+
+// bulkCancelTrader 只能按币种撤销全部止损单的交易器
+type bulkCancelTrader struct {
+	MockTrader
+	orders     []decision.OpenOrderInfo
+	stops      []string
+	bulkCancel int
+}
+
+func (t *bulkCancelTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	return t.orders, nil
+}
+
+func (t *bulkCancelTrader) CancelStopLossOrders(symbol string) error {
+	t.bulkCancel++
+	return nil
+}
+
+func (t *bulkCancelTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	t.stops = append(t.stops, fmt.Sprintf("%s@%.1f", positionSide, stopPrice))
+	return nil
+}
+
+func TestApplyTrailingStopDoesNotLoosenLiveStop(t *testing.T) {
+	mock := &trailingOrdersTrader{orders: []decision.OpenOrderInfo{
+		{OrderID: 1, Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 108},
+	}}
+	at := &AutoTrader{
+		trader: mock,
+		config: AutoTraderConfig{TrailingStopPercent: 2, TrailingActivation: 3},
+	}
+
+	// 重启后缓存为空：交易所已有 108 的止损，107.8 不能放宽它
+	at.applyTrailingStop("BTCUSDT", "long", 100, 110, 1)
+	if len(mock.stops) != 0 || len(mock.cancelled) != 0 {
+		t.Fatalf("live stop must not be loosened, stops=%v cancelled=%v", mock.stops, mock.cancelled)
+	}
+
+	// AI 设置的止损同样作为基准
+	at = &AutoTrader{
+		trader:           &trailingOrdersTrader{},
+		config:           AutoTraderConfig{TrailingStopPercent: 2, TrailingActivation: 3},
+		positionStopLoss: map[string]float64{"BTCUSDT_long": 109},
+	}
+	at.applyTrailingStop("BTCUSDT", "long", 100, 110, 1)
+	if stops := at.trader.(*trailingOrdersTrader).stops; len(stops) != 0 {
+		t.Fatalf("AI stop must not be loosened, got %v", stops)
+	}
+}
+
+func TestApplyTrailingStopCancelsOnlyOwnSide(t *testing.T) {
+	mock := &trailingOrdersTrader{orders: []decision.OpenOrderInfo{
+		{OrderID: 1, Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 95},
+		{OrderID: 2, Type: "STOP_MARKET", Side: "BUY", PositionSide: "SHORT", StopPrice: 120},
+		{OrderID: 3, Type: "TAKE_PROFIT_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 130},
+	}}
+	at := &AutoTrader{
+		trader: mock,
+		config: AutoTraderConfig{TrailingStopPercent: 2, TrailingActivation: 3},
+	}
+
+	at.applyTrailingStop("BTCUSDT", "long", 100, 110, 1)
+	if len(mock.cancelled) != 1 || mock.cancelled[0] != 1 || mock.bulkCancel != 0 {
+		t.Fatalf("expected only long stop cancelled, cancelled=%v bulk=%d", mock.cancelled, mock.bulkCancel)
+	}
+	if len(mock.stops) != 1 || mock.stops[0] != "LONG@107.8" {
+		t.Fatalf("unexpected stops %v", mock.stops)
+	}
+}
+
+func TestApplyTrailingStopRestoresOldStopOnFailure(t *testing.T) {
+	mock := &trailingOrdersTrader{
+		orders: []decision.OpenOrderInfo{
+			{OrderID: 1, Type: "STOP_MARKET", Side: "SELL", PositionSide: "BOTH", StopPrice: 95},
+		},
+		failStop: 107.8,
+	}
+	at := &AutoTrader{
+		trader: mock,
+		config: AutoTraderConfig{TrailingStopPercent: 2, TrailingActivation: 3},
+	}
+
+	at.applyTrailingStop("BTCUSDT", "long", 100, 110, 1)
+	if len(mock.stops) != 1 || mock.stops[0] != "LONG@95.0" {
+		t.Fatalf("expected old stop restored, got %v", mock.stops)
+	}
+	if _, ok := at.trailingStops["BTCUSDT_long"]; ok {
+		t.Fatal("failed trailing stop must not be recorded")
+	}
+}
+
+func TestApplyTrailingStopBulkCancelRestoresOtherSide(t *testing.T) {
+	mock := &bulkCancelTrader{orders: []decision.OpenOrderInfo{
+		{OrderID: 1, Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 95, Quantity: 1},
+		{OrderID: 2, Type: "STOP_MARKET", Side: "BUY", PositionSide: "SHORT", StopPrice: 120, Quantity: 2},
+	}}
+	at := &AutoTrader{
+		trader: mock,
+		config: AutoTraderConfig{TrailingStopPercent: 2, TrailingActivation: 3},
+	}
+
+	at.applyTrailingStop("BTCUSDT", "long", 100, 110, 1)
+	if mock.bulkCancel != 1 {
+		t.Fatalf("expected bulk cancel, got %d", mock.bulkCancel)
+	}
+	if len(mock.stops) != 2 || mock.stops[0] != "SHORT@120.0" || mock.stops[1] != "LONG@107.8" {
+		t.Fatalf("expected short stop restored then long stop placed, got %v", mock.stops)
+	}
+}
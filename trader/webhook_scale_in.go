@@ -0,0 +1,237 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	webhookScaleInMu     sync.RWMutex
+	webhookScaleInWindow time.Duration
+	webhookScaleInMaxUSD float64
+)
+
+// SetWebhookScaleIn 设置 webhook 加仓（金字塔）参数
+// （system_config: webhook_scale_in_window_minutes / webhook_scale_in_max_position_usd）
+// 窗口内同一交易员同一币种的重复同向告警按加仓处理，加仓后该方向持仓名义价值不超过 maxPositionUSD
+// 任一参数 <= 0 时关闭，已有同向持仓时仍拒绝开仓
+func SetWebhookScaleIn(window time.Duration, maxPositionUSD float64) {
+	if window < 0 {
+		window = 0
+	}
+	if math.IsNaN(maxPositionUSD) || math.IsInf(maxPositionUSD, 0) || maxPositionUSD < 0 {
+		maxPositionUSD = 0
+	}
+	webhookScaleInMu.Lock()
+	defer webhookScaleInMu.Unlock()
+	webhookScaleInWindow = window
+	webhookScaleInMaxUSD = maxPositionUSD
+}
+
+func getWebhookScaleIn() (time.Duration, float64) {
+	webhookScaleInMu.RLock()
+	defer webhookScaleInMu.RUnlock()
+	return webhookScaleInWindow, webhookScaleInMaxUSD
+}
+
+// NoteWebhookAlert 记录带方向（long/short）的 webhook 告警，返回本次告警是否按加仓处理
+// 距上一次同向告警不超过窗口时允许本次告警触发的周期加仓一次；反向告警会清除该币种另一方向的计时
+// 告警计时只保存在内存中：交易员重启（或服务重启）后重新计时，重启后的第一次告警不会按加仓处理
+func (at *AutoTrader) NoteWebhookAlert(symbol, side string) bool {
+	side = strings.ToLower(side)
+	if side != "long" && side != "short" {
+		return false
+	}
+	symbol = market.Normalize(symbol)
+	opposite := "short"
+	if side == "short" {
+		opposite = "long"
+	}
+	window, maxUSD := getWebhookScaleIn()
+	now := time.Now()
+
+	at.webhookScaleInMutex.Lock()
+	defer at.webhookScaleInMutex.Unlock()
+	if at.webhookAlerts == nil {
+		at.webhookAlerts = make(map[string]time.Time)
+		at.webhookScaleIns = make(map[string]bool)
+	}
+	delete(at.webhookAlerts, symbol+"_"+opposite)
+	delete(at.webhookScaleIns, symbol+"_"+opposite)
+
+	key := symbol + "_" + side
+	last, seen := at.webhookAlerts[key]
+	at.webhookAlerts[key] = now
+	scaleIn := window > 0 && maxUSD > 0 && seen && now.Sub(last) <= window
+	if scaleIn {
+		at.webhookScaleIns[key] = true
+	} else {
+		delete(at.webhookScaleIns, key)
+	}
+	return scaleIn
+}
+
+// consumeWebhookScaleIn 取出加仓许可（每次告警最多加仓一次）
+func (at *AutoTrader) consumeWebhookScaleIn(symbol, side string) bool {
+	at.webhookScaleInMutex.Lock()
+	defer at.webhookScaleInMutex.Unlock()
+	key := symbol + "_" + side
+	if !at.webhookScaleIns[key] {
+		return false
+	}
+	delete(at.webhookScaleIns, key)
+	return true
+}
+
+// capWebhookScaleIn 按加仓上限压缩本次开仓金额，返回已有持仓数量（用于按合计数量重设止盈止损）
+// 已有持仓按标记价格（缺失时用开仓均价）计算名义价值，已达上限时拒绝加仓
+func capWebhookScaleIn(d *decision.Decision, pos map[string]interface{}) (float64, error) {
+	_, maxUSD := getWebhookScaleIn()
+	qty, _ := pos["positionAmt"].(float64)
+	qty = math.Abs(qty)
+	price, _ := pos["markPrice"].(float64)
+	if price <= 0 {
+		price, _ = pos["entryPrice"].(float64)
+	}
+	existing := qty * price
+
+	remaining := maxUSD - existing
+	if remaining <= 0 {
+		return 0, fmt.Errorf("❌ %s 加仓已达上限：当前持仓 %.2f USDT，上限 %.2f USDT", d.Symbol, existing, maxUSD)
+	}
+	if d.PositionSizeUSD > remaining {
+		log.Printf("  📐 %s 加仓金额 %.2f USDT 超过剩余额度，压缩为 %.2f USDT（上限 %.2f USDT）",
+			d.Symbol, d.PositionSizeUSD, remaining, maxUSD)
+		d.PositionSizeUSD = remaining
+	}
+	log.Printf("  📈 webhook 加仓: %s 已有 %.4f（%.2f USDT），本次加仓 %.2f USDT", d.Symbol, qty, existing, d.PositionSizeUSD)
+	return qty, nil
+}
+
+// WebhookScaleInPrompt 加仓告警附加到 prompt 的说明，告诉 AI 可以对已有持仓同向开仓
+func WebhookScaleInPrompt(symbol, side string) string {
+	window, maxUSD := getWebhookScaleIn()
+	action := "open_long"
+	direction := "多"
+	if strings.ToLower(side) == "short" {
+		action = "open_short"
+		direction = "空"
+	}
+	return fmt.Sprintf("\n\n📈 加仓信号：%s 在 %d 分钟内再次触发做%s告警。如趋势仍然成立，可对已有%s仓给出 %s 决策加仓（无需先平仓），"+
+		"该方向持仓名义价值合计不超过 %.2f USDT，超出部分会被自动压缩。",
+		market.Normalize(symbol), int(window/time.Minute), direction, direction, action, maxUSD)
+}
+
+// exitOrderSnapshot 加仓前该币种的挂单快照（用于加仓后只替换同方向的止盈止损，并恢复被误撤的挂单）
+type exitOrderSnapshot []decision.OpenOrderInfo
+
+// snapshotOpenOrders 读取加仓前的挂单，查询失败时返回 nil（之后无法恢复旧挂单）
+func (at *AutoTrader) snapshotOpenOrders(symbol string) exitOrderSnapshot {
+	orders, err := at.trader.GetOpenOrders(symbol)
+	if err != nil {
+		log.Printf("  ⚠ 查询 %s 加仓前挂单失败: %v", symbol, err)
+		return nil
+	}
+	return orders
+}
+
+// positionQuantity 返回持仓列表中某币种某方向的持仓数量（绝对值），没有时返回 0
+func positionQuantity(positions []map[string]interface{}, symbol, side string) float64 {
+	for _, pos := range positions {
+		if pos["symbol"] == symbol && pos["side"] == side {
+			qty, _ := pos["positionAmt"].(float64)
+			return math.Abs(qty)
+		}
+	}
+	return 0
+}
+
+func isTakeProfitOrder(order decision.OpenOrderInfo) bool {
+	typ := strings.ToUpper(order.Type)
+	return typ == "TAKE_PROFIT_MARKET" || typ == "TAKE_PROFIT"
+}
+
+func isExitOrder(order decision.OpenOrderInfo) bool {
+	typ := strings.ToUpper(order.Type)
+	return (typ == "STOP_MARKET" || typ == "STOP" || isTakeProfitOrder(order)) && order.StopPrice > 0
+}
+
+// rescaleExitOrders 加仓成交后按合计数量重设 side 方向的止盈止损
+// 只撤销该方向的旧止盈止损；开仓时被交易器撤掉（Binance 开仓会先撤销该币种全部委托）或只能按币种撤单时一并撤掉的
+// 另一方向止盈止损按原价格挂回（otherQuantity 为另一方向持仓数量）。新止盈止损挂单失败时按原价格恢复该方向的旧挂单
+func (at *AutoTrader) rescaleExitOrders(symbol, side string, before exitOrderSnapshot, quantity, otherQuantity, stopLoss, takeProfit float64) {
+	current, err := at.trader.GetOpenOrders(symbol)
+	if err != nil {
+		log.Printf("  ⚠ 查询 %s 当前挂单失败，按加仓前挂单处理: %v", symbol, err)
+		current = before
+	}
+	remaining := make(map[int64]bool, len(current))
+	var own []decision.OpenOrderInfo
+	for _, order := range current {
+		remaining[order.OrderID] = true
+		if isExitOrder(order) && exitOrderPositionSide(order) == side {
+			own = append(own, order)
+		}
+	}
+
+	bulkCancelled := false
+	if canceller, ok := stopOrderCanceller(at.trader); ok {
+		for _, order := range own {
+			if err := canceller.CancelOrder(symbol, order.OrderID); err != nil {
+				log.Printf("  ⚠ 撤销原止盈止损 %d 失败: %v", order.OrderID, err)
+			}
+		}
+	} else {
+		// 只能按币种撤单（挂单列表可能不含条件单，因此不依赖 own 判断是否需要撤单）
+		if err := at.trader.CancelStopOrders(symbol); err != nil {
+			log.Printf("  ⚠ 撤销原止盈止损失败: %v", err)
+		} else {
+			bulkCancelled = true
+		}
+	}
+
+	for _, order := range before {
+		if !isExitOrder(order) || exitOrderPositionSide(order) == side {
+			continue
+		}
+		if remaining[order.OrderID] && !bulkCancelled {
+			continue
+		}
+		at.restoreExitOrder(symbol, order, otherQuantity)
+	}
+
+	stopLossPlaced, takeProfitPlaced := at.placeExitOrders(symbol, side, quantity, stopLoss, takeProfit)
+	for _, order := range before {
+		if !isExitOrder(order) || exitOrderPositionSide(order) != side {
+			continue
+		}
+		if (isTakeProfitOrder(order) && !takeProfitPlaced) || (!isTakeProfitOrder(order) && !stopLossPlaced) {
+			at.restoreExitOrder(symbol, order, quantity)
+		}
+	}
+}
+
+// restoreExitOrder 按原触发价重新挂出止盈或止损单；挂单自身没有数量（closePosition）时使用 quantity
+func (at *AutoTrader) restoreExitOrder(symbol string, order decision.OpenOrderInfo, quantity float64) {
+	if order.Quantity > 0 {
+		quantity = order.Quantity
+	}
+	positionSide := strings.ToUpper(exitOrderPositionSide(order))
+	var err error
+	if isTakeProfitOrder(order) {
+		err = at.trader.SetTakeProfit(symbol, positionSide, quantity, order.StopPrice)
+	} else {
+		err = at.trader.SetStopLoss(symbol, positionSide, quantity, order.StopPrice)
+	}
+	if err != nil {
+		log.Printf("  ❌ 恢复 %s %s %s @ %.4f 失败: %v", symbol, positionSide, order.Type, order.StopPrice, err)
+		return
+	}
+	log.Printf("  ↩ 已恢复 %s %s %s @ %.4f", symbol, positionSide, order.Type, order.StopPrice)
+}
@@ -0,0 +1,171 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+func TestNoteWebhookAlert(t *testing.T) {
+	SetWebhookScaleIn(10*time.Minute, 3000)
+	defer SetWebhookScaleIn(0, 0)
+
+	at := &AutoTrader{}
+	if at.NoteWebhookAlert("BTC", "long") {
+		t.Fatal("first alert should open normally")
+	}
+	if !at.NoteWebhookAlert("BTCUSDT", "long") {
+		t.Fatal("repeated same-direction alert within window should scale in")
+	}
+	if !at.consumeWebhookScaleIn("BTCUSDT", "long") || at.consumeWebhookScaleIn("BTCUSDT", "long") {
+		t.Fatal("scale-in permit should be consumed exactly once")
+	}
+
+	// 反向告警重新开始计时
+	if at.NoteWebhookAlert("BTCUSDT", "short") {
+		t.Fatal("opposite alert should not scale in")
+	}
+	if at.NoteWebhookAlert("BTCUSDT", "long") {
+		t.Fatal("long timer should reset after a short alert")
+	}
+
+	// 超出窗口
+	at.webhookAlerts["BTCUSDT_long"] = time.Now().Add(-11 * time.Minute)
+	if at.NoteWebhookAlert("BTCUSDT", "long") {
+		t.Fatal("alert outside window should not scale in")
+	}
+
+	if at.NoteWebhookAlert("BTCUSDT", "flat") {
+		t.Fatal("unknown side should be ignored")
+	}
+
+	SetWebhookScaleIn(10*time.Minute, 0)
+	at.NoteWebhookAlert("ETHUSDT", "long")
+	if at.NoteWebhookAlert("ETHUSDT", "long") {
+		t.Fatal("scale-in disabled without a max position size")
+	}
+}
+
+// TestExecuteOpenPosition_WebhookScaleIn 加仓窗口内的同向开仓按上限压缩金额，并按合计数量重设止盈止损
+func (s *AutoTraderTestSuite) TestExecuteOpenPosition_WebhookScaleIn() {
+	SetWebhookScaleIn(10*time.Minute, 3000)
+	defer SetWebhookScaleIn(0, 0)
+
+	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
+	})
+	newDecision := func() *decision.Decision {
+		return &decision.Decision{
+			Action: "open_long", Symbol: "BTCUSDT", PositionSizeUSD: 1000.0,
+			Leverage: 10, StopLoss: 48000.0, TakeProfit: 52000.0,
+		}
+	}
+
+	// 已有 0.05 BTC（2500 USDT），剩余额度 500 USDT
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long", "positionAmt": 0.05, "markPrice": 50000.0},
+	}
+	defer func() { s.mockTrader.positions = []map[string]interface{}{} }()
+
+	s.autoTrader.NoteWebhookAlert("BTCUSDT", "long")
+	s.True(s.autoTrader.NoteWebhookAlert("BTCUSDT", "long"))
+	d := newDecision()
+	record := &logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT"}
+	s.Require().NoError(s.autoTrader.executeOpenLongWithRecord(d, record))
+	s.InDelta(500.0, d.PositionSizeUSD, 1e-9)
+	s.True(math.Abs(record.Quantity-0.01) < 1e-9)
+
+	// 许可已用完：再次开仓按原逻辑拒绝
+	err := s.autoTrader.executeOpenLongWithRecord(newDecision(), &logger.DecisionAction{})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "已有多仓")
+
+	// 已达上限
+	s.mockTrader.positions[0]["positionAmt"] = 0.06
+	s.True(s.autoTrader.NoteWebhookAlert("BTCUSDT", "long"))
+	err = s.autoTrader.executeOpenLongWithRecord(newDecision(), &logger.DecisionAction{})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "加仓已达上限")
+}
+
+// exitRecordingTrader 记录撤单和止盈止损挂单的交易器
+type exitRecordingTrader struct {
+	MockTrader
+	orders    []decision.OpenOrderInfo
+	cancelled []int64
+	placed    []string
+	failStop  bool
+}
+
+func (t *exitRecordingTrader) GetOpenOrders(symbol string) ([]decision.OpenOrderInfo, error) {
+	return t.orders, nil
+}
+
+func (t *exitRecordingTrader) CancelOrder(symbol string, orderID int64) error {
+	t.cancelled = append(t.cancelled, orderID)
+	return nil
+}
+
+func (t *exitRecordingTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if t.failStop && stopPrice == 48000 {
+		return errors.New("rejected")
+	}
+	t.placed = append(t.placed, fmt.Sprintf("SL %s %.2f@%.0f", positionSide, quantity, stopPrice))
+	return nil
+}
+
+func (t *exitRecordingTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	t.placed = append(t.placed, fmt.Sprintf("TP %s %.2f@%.0f", positionSide, quantity, takeProfitPrice))
+	return nil
+}
+
+func TestRescaleExitOrders_OnlyReplacesSameSide(t *testing.T) {
+	before := exitOrderSnapshot{
+		{OrderID: 1, Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 47000},
+		{OrderID: 2, Type: "TAKE_PROFIT_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 51000},
+		{OrderID: 3, Type: "STOP_MARKET", Side: "BUY", PositionSide: "SHORT", StopPrice: 55000},
+	}
+	// 开仓时交易器撤销了该币种全部挂单，空仓止损 3 已不在
+	mock := &exitRecordingTrader{orders: before[:2]}
+	at := &AutoTrader{
+		trader:             mock,
+		positionStopLoss:   map[string]float64{},
+		positionTakeProfit: map[string]float64{},
+	}
+
+	at.rescaleExitOrders("BTCUSDT", "long", before, 0.06, 0.02, 48000, 52000)
+
+	if len(mock.cancelled) != 2 || mock.cancelled[0] != 1 || mock.cancelled[1] != 2 {
+		t.Fatalf("only the long exit orders should be cancelled, got %v", mock.cancelled)
+	}
+	want := []string{"SL SHORT 0.02@55000", "SL LONG 0.06@48000", "TP LONG 0.06@52000"}
+	if fmt.Sprint(mock.placed) != fmt.Sprint(want) {
+		t.Fatalf("placed = %v, want %v", mock.placed, want)
+	}
+}
+
+func TestRescaleExitOrders_RestoresOldStopOnFailure(t *testing.T) {
+	before := exitOrderSnapshot{
+		{OrderID: 1, Type: "STOP_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 47000},
+		{OrderID: 2, Type: "TAKE_PROFIT_MARKET", Side: "SELL", PositionSide: "LONG", StopPrice: 51000},
+	}
+	mock := &exitRecordingTrader{orders: before, failStop: true}
+	at := &AutoTrader{
+		trader:             mock,
+		positionStopLoss:   map[string]float64{},
+		positionTakeProfit: map[string]float64{},
+	}
+
+	at.rescaleExitOrders("BTCUSDT", "long", before, 0.06, 0, 48000, 52000)
+
+	want := []string{"TP LONG 0.06@52000", "SL LONG 0.06@47000"}
+	if fmt.Sprint(mock.placed) != fmt.Sprint(want) {
+		t.Fatalf("placed = %v, want %v", mock.placed, want)
+	}
+}